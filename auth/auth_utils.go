@@ -13,6 +13,7 @@ var (
 	ErrInvalidDriver  = errors.New("invalid driver type in context")
 	ErrUserNotFound   = errors.New("user not found in context")
 	ErrInvalidUser    = errors.New("invalid user type in context")
+	ErrAccessNotFound = errors.New("user access not found in context")
 )
 
 func GetDriverFromContext(ctx context.Context) (*model.DriverInfo, error) {
@@ -43,6 +44,15 @@ func GetUserFromContext(ctx context.Context) (*model.User, error) {
 	return user, nil
 }
 
+// GetUserAccessFromContext 取得用戶認證中間件解析出的生效權限與車隊檢視範圍
+func GetUserAccessFromContext(ctx context.Context) (*model.UserAccess, error) {
+	access, ok := ctx.Value("user_access").(*model.UserAccess)
+	if !ok || access == nil {
+		return nil, ErrAccessNotFound
+	}
+	return access, nil
+}
+
 // JWT 驗證相關的通用錯誤
 var (
 	ErrInvalidToken            = errors.New("invalid token")
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHashCost bcrypt 雜湊強度，既有雜湊低於此強度時於下次登入成功後自動升級
+const PasswordHashCost = 12
+
+// ErrPasswordTooLong bcrypt 只接受 72 位元組以內的密碼
+var ErrPasswordTooLong = errors.New("密碼長度不可超過 72 位元組")
+
+// HashPassword 以 bcrypt 雜湊密碼
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), PasswordHashCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", ErrPasswordTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// IsPasswordHash 判斷儲存的密碼是否已是 bcrypt 雜湊（未遷移的舊資料為明文）
+func IsPasswordHash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// VerifyPassword 比對輸入密碼與儲存的密碼，回傳是否正確，以及是否需要重新雜湊（舊資料為明文或雜湊強度低於目前設定）
+func VerifyPassword(stored, password string) (ok bool, needsRehash bool) {
+	if stored == "" {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(stored))
+	if err != nil {
+		// 尚未遷移的明文密碼
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(password)) != 1 {
+			return false, false
+		}
+		return true, true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+		return false, false
+	}
+	return true, cost < PasswordHashCost
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func bcryptHash(t *testing.T, password string, cost int) string {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		t.Fatalf("產生測試雜湊失敗: %v", err)
+	}
+	return string(hashed)
+}
+
+func TestVerifyPassword(t *testing.T) {
+	current, err := HashPassword("secret123")
+	if err != nil {
+		t.Fatalf("雜湊密碼失敗: %v", err)
+	}
+	weak := bcryptHash(t, "secret123", bcrypt.MinCost)
+
+	testCases := []struct {
+		name            string
+		stored          string
+		password        string
+		wantOK          bool
+		wantNeedsRehash bool
+	}{
+		{name: "目前強度的雜湊密碼正確", stored: current, password: "secret123", wantOK: true},
+		{name: "目前強度的雜湊密碼錯誤", stored: current, password: "wrong"},
+		{name: "低強度雜湊密碼正確需升級", stored: weak, password: "secret123", wantOK: true, wantNeedsRehash: true},
+		{name: "低強度雜湊密碼錯誤不升級", stored: weak, password: "wrong"},
+		{name: "明文密碼正確需遷移", stored: "secret123", password: "secret123", wantOK: true, wantNeedsRehash: true},
+		{name: "明文密碼錯誤不遷移", stored: "secret123", password: "secret12"},
+		{name: "儲存的密碼為空", stored: "", password: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, needsRehash := VerifyPassword(tc.stored, tc.password)
+			if ok != tc.wantOK || needsRehash != tc.wantNeedsRehash {
+				t.Fatalf("預期 (ok=%v, needsRehash=%v)，實際為 (ok=%v, needsRehash=%v)", tc.wantOK, tc.wantNeedsRehash, ok, needsRehash)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hashed, err := HashPassword("secret123")
+	if err != nil {
+		t.Fatalf("雜湊密碼失敗: %v", err)
+	}
+	if !IsPasswordHash(hashed) || IsPasswordHash("secret123") {
+		t.Fatal("預期能區分 bcrypt 雜湊與明文密碼")
+	}
+	if cost, _ := bcrypt.Cost([]byte(hashed)); cost != PasswordHashCost {
+		t.Fatalf("預期雜湊強度 %d，實際為 %d", PasswordHashCost, cost)
+	}
+
+	if _, err := HashPassword(strings.Repeat("a", 73)); err != ErrPasswordTooLong {
+		t.Fatalf("預期超過 72 位元組的密碼回傳 ErrPasswordTooLong，實際為 %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+package background
+
+import (
+	"context"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// broadcastOutcome 搶單模式下單一司機的結果
+type broadcastOutcome string
+
+const (
+	broadcastOutcomePending  broadcastOutcome = "pending"  // 尚未回應
+	broadcastOutcomeAccepted broadcastOutcome = "accepted" // 搶單成功
+	broadcastOutcomeRejected broadcastOutcome = "rejected" // 明確拒單
+	broadcastOutcomeSkipped  broadcastOutcome = "skipped"  // 已轉為非閒置，不再等待
+)
+
+// sendFcmBroadcast 搶單模式：同時推送訂單給前 N 位司機，由 RedisEventManager.AtomicAcceptOrder 決定先接單者
+func (d *Dispatcher) sendFcmBroadcast(ctx context.Context, order *model.Order, candidates []*model.DriverInfo, distances []string, durationMins []int, crawlerCompletedAt time.Time) (bool, error) {
+	// 搶單需要 Redis 決定得標者，無事件管理器時降級為依序呼叫
+	if d.EventManager == nil {
+		d.logger.Warn().Str("short_id", order.ShortID).Msg("事件管理器未初始化，搶單模式降級為依序呼叫")
+		return d.sendFcm(ctx, order, candidates, distances, durationMins, crawlerCompletedAt)
+	}
+
+	policy := d.policyFor(order.Fleet)
+	callTimeout := policy.SequentialCallTimeout()
+	broadcastCount := policy.BroadcastCount
+	if broadcastCount <= 0 || broadcastCount > len(candidates) {
+		broadcastCount = len(candidates)
+	}
+
+	fcmCtx, fcmSpan := infra.StartSpan(ctx, "dispatcher_send_fcm_broadcast",
+		infra.AttrOperation("send_fcm_broadcast"),
+		infra.AttrOrderID(order.ID.Hex()),
+		infra.AttrString("order.short_id", order.ShortID),
+		infra.AttrInt("candidates_count", len(candidates)),
+		infra.AttrInt("broadcast_count", broadcastCount),
+	)
+	defer fcmSpan.End()
+
+	orderID := order.ID.Hex()
+
+	// 1. 建立基礎訂單資訊並檢查訂單狀態
+	baseOrderInfo, err := d.buildOrderInfo(fcmCtx, order)
+	if err != nil {
+		infra.RecordError(fcmSpan, err, "Build order info failed")
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("無法建立訂單資訊")
+		return false, err
+	}
+
+	currentOrder, err := d.OrderSvc.GetOrderByID(fcmCtx, orderID)
+	if err != nil {
+		infra.RecordError(fcmSpan, err, "Initial order status check failed")
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度中心推送前檢查訂單狀態失敗")
+		return false, err
+	}
+	if currentOrder.Status != model.OrderStatusWaiting {
+		d.logger.Info().
+			Str("short_id", order.ShortID).
+			Str("status", string(currentOrder.Status)).
+			Msg("調度中心訂單在搶單推送開始前已被處理")
+		return currentOrder.Status != model.OrderStatusCancelled, nil
+	}
+
+	// 2. 獲取調度鎖，並在推送前先訂閱事件，避免遺漏最快的接單回應
+	lockTTL := callTimeout + 10*time.Second
+	lockAcquired, lockValue, releaseLock, err := d.EventManager.AcquireDispatchLock(ctx, orderID, d.dispatcherID, lockTTL)
+	if err != nil {
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度鎖獲取失敗")
+		return false, err
+	}
+	if !lockAcquired {
+		d.logger.Info().Str("short_id", order.ShortID).Msg("調度鎖已被其他流程持有，訂單可能已被處理")
+		return false, nil
+	}
+	defer releaseLock()
+
+	orderResponseSub := d.EventManager.SubscribeOrderResponses(ctx, orderID)
+	defer func() {
+		if err := orderResponseSub.Close(); err != nil {
+			d.logger.Error().Err(err).Msg("關閉orderResponseSub失敗")
+		}
+	}()
+
+	driverStatusSub := d.EventManager.SubscribeDriverStatusChanges(ctx)
+	defer func() {
+		if err := driverStatusSub.Close(); err != nil {
+			d.logger.Error().Err(err).Msg("關閉driverStatusSub失敗")
+		}
+	}()
+
+	// 3. 同時推送給前 N 位可用司機
+	notified := make([]*model.DriverInfo, 0, broadcastCount)
+	outcomes := make(map[string]broadcastOutcome, broadcastCount)
+	for i, driver := range candidates {
+		if len(notified) >= broadcastCount {
+			break
+		}
+		if driver.FcmToken == "" {
+			d.logger.Debug().Str("short_id", order.ShortID).Int("rank", i+1).Str("car_plate", driver.CarPlate).Msg("調度中心司機沒有FCM Token，跳過")
+			continue
+		}
+
+		success, reason, atomicErr := d.EventManager.AtomicNotifyDriver(ctx, driver.ID.Hex(), orderID, d.dispatcherID, lockTTL)
+		if atomicErr != nil || !success {
+			d.logger.Debug().Err(atomicErr).
+				Str("short_id", order.ShortID).
+				Int("rank", i+1).
+				Str("car_plate", driver.CarPlate).
+				Str("reason", reason).
+				Msg("🔒 司機或訂單不可用，跳過")
+			continue
+		}
+
+		if pushErr := d.pushOrderToDriver(fcmCtx, fcmSpan, order, baseOrderInfo, driver, i+1, distances[i], durationMins[i], crawlerCompletedAt, callTimeout); pushErr != nil {
+			d.EventManager.ReleaseDriverNotification(ctx, driver.ID.Hex(), orderID, d.dispatcherID)
+			continue
+		}
+
+		notified = append(notified, driver)
+		outcomes[driver.ID.Hex()] = broadcastOutcomePending
+	}
+
+	defer func() {
+		for _, driver := range notified {
+			d.EventManager.ReleaseDriverNotification(ctx, driver.ID.Hex(), orderID, d.dispatcherID)
+		}
+	}()
+
+	if len(notified) == 0 {
+		infra.SetAttributes(fcmSpan, infra.AttrString("fcm.result", "no_driver_notified"))
+		return false, nil
+	}
+
+	d.logger.Info().
+		Str("short_id", order.ShortID).
+		Str("ori_text", order.OriText).
+		Int("notified_count", len(notified)).
+		Dur("timeout", callTimeout).
+		Msg("[調度中心-{short_id}]: ({ori_text}) 搶單推送完成，同時通知 {notified_count} 位司機")
+
+	// 4. 等待第一位接單者、全部拒單、超時或訂單狀態變更
+	winnerID, orderStatus := d.waitForBroadcastResponses(ctx, order, outcomes, lockValue, lockTTL, callTimeout, orderResponseSub.Channel(), driverStatusSub.Channel())
+
+	// 5. 記錄每位司機的結果
+	d.settleBroadcastOutcomes(ctx, order, notified, outcomes, winnerID, orderStatus)
+
+	if winnerID != "" || (orderStatus != model.OrderStatusWaiting && orderStatus != model.OrderStatusCancelled && orderStatus != model.OrderStatusFailed) {
+		infra.MarkSuccess(fcmSpan,
+			infra.AttrString("fcm.result", "driver_accepted"),
+			infra.AttrDriverID(winnerID),
+		)
+		d.logger.Info().
+			Str("short_id", order.ShortID).
+			Str("ori_text", order.OriText).
+			Str("winner_id", winnerID).
+			Msg("[調度中心-{short_id}]: ({ori_text}) 🎉 搶單成功，調度完成")
+		return true, nil
+	}
+
+	infra.SetAttributes(fcmSpan,
+		infra.AttrString("fcm.result", "no_driver_accepted"),
+		infra.AttrString("order.status", string(orderStatus)),
+	)
+	return false, nil
+}
+
+// waitForBroadcastResponses 等待搶單結果，回傳得標司機ID（可能為空）與最後觀察到的訂單狀態
+func (d *Dispatcher) waitForBroadcastResponses(ctx context.Context, order *model.Order, outcomes map[string]broadcastOutcome, lockValue string, lockTTL, callTimeout time.Duration, responses, driverStatuses <-chan *redis.Message) (string, model.OrderStatus) {
+	orderID := order.ID.Hex()
+
+	statusCheckTicker := time.NewTicker(1 * time.Second)
+	defer statusCheckTicker.Stop()
+
+	timeoutTimer := time.NewTimer(callTimeout)
+	defer timeoutTimer.Stop()
+
+	lockExtendTicker := time.NewTicker(5 * time.Second)
+	defer lockExtendTicker.Stop()
+
+	allResponded := func() bool {
+		for _, outcome := range outcomes {
+			if outcome == broadcastOutcomePending {
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-timeoutTimer.C:
+			d.logger.Info().Str("short_id", order.ShortID).Msg("搶單等待超時")
+			return d.latestOrderResult(ctx, orderID)
+
+		case msg := <-responses:
+			response, parseErr := infra.ParseDriverResponse(msg.Payload)
+			if parseErr != nil || response.OrderID != orderID {
+				continue
+			}
+			if response.Action == infra.DriverResponseAccept {
+				if _, ok := outcomes[response.DriverID]; ok {
+					outcomes[response.DriverID] = broadcastOutcomeAccepted
+				}
+				d.logger.Info().
+					Str("short_id", order.ShortID).
+					Str("accepting_driver", response.DriverID).
+					Msg("✅ 搶單成功，停止調度")
+				_, status := d.latestOrderResult(ctx, orderID)
+				return response.DriverID, status
+			}
+			if response.Action == infra.DriverResponseReject {
+				if outcome, ok := outcomes[response.DriverID]; ok && outcome == broadcastOutcomePending {
+					outcomes[response.DriverID] = broadcastOutcomeRejected
+				}
+				if allResponded() {
+					d.logger.Info().Str("short_id", order.ShortID).Msg("❌ 所有搶單司機皆已拒單")
+					return d.latestOrderResult(ctx, orderID)
+				}
+			}
+
+		case msg := <-driverStatuses:
+			statusEvent, parseErr := infra.ParseDriverStatusEvent(msg.Payload)
+			if parseErr != nil {
+				continue
+			}
+			// 司機轉為非閒置（例如接了其他訂單）後不再等待該司機
+			if outcome, ok := outcomes[statusEvent.DriverID]; ok && outcome == broadcastOutcomePending &&
+				statusEvent.NewStatus != string(model.DriverStatusIdle) {
+				outcomes[statusEvent.DriverID] = broadcastOutcomeSkipped
+				if allResponded() {
+					return d.latestOrderResult(ctx, orderID)
+				}
+			}
+
+		case <-statusCheckTicker.C:
+			// 定期檢查訂單狀態，防止遺漏接單事件
+			currentOrder, statusErr := d.OrderSvc.GetOrderByID(ctx, orderID)
+			if statusErr != nil {
+				continue
+			}
+			if currentOrder.Status != model.OrderStatusWaiting {
+				return currentOrder.Driver.AssignedDriver, currentOrder.Status
+			}
+
+		case <-lockExtendTicker.C:
+			if extendErr := d.EventManager.ExtendDispatchLock(ctx, orderID, lockValue, lockTTL); extendErr != nil {
+				d.logger.Error().Err(extendErr).
+					Str("short_id", order.ShortID).
+					Msg("⚠️ 調度鎖延長失敗，可能被其他流程搶奪")
+				return d.latestOrderResult(ctx, orderID)
+			}
+
+		case <-ctx.Done():
+			d.logger.Info().Str("short_id", order.ShortID).Msg("🛑 調度上下文取消")
+			return "", model.OrderStatusWaiting
+		}
+	}
+}
+
+// latestOrderResult 查詢訂單最新的接單司機與狀態，查詢失敗時視為仍在等待接單
+func (d *Dispatcher) latestOrderResult(ctx context.Context, orderID string) (string, model.OrderStatus) {
+	currentOrder, err := d.OrderSvc.GetOrderByID(ctx, orderID)
+	if err != nil {
+		d.logger.Error().Err(err).Str("order_id", orderID).Msg("查詢訂單最新狀態失敗")
+		return "", model.OrderStatusWaiting
+	}
+	return currentOrder.Driver.AssignedDriver, currentOrder.Status
+}
+
+// settleBroadcastOutcomes 依搶單結果處理每位被通知的司機：
+// 得標者與拒單者已由接單/拒單 API 記錄日誌；其他司機有人接單時記錄「他人已接」並推送取消通知，無人接單時視為未接
+func (d *Dispatcher) settleBroadcastOutcomes(ctx context.Context, order *model.Order, notified []*model.DriverInfo, outcomes map[string]broadcastOutcome, winnerID string, orderStatus model.OrderStatus) {
+	currentRounds := 1
+	if order.Rounds != nil {
+		currentRounds = *order.Rounds
+	}
+
+	for _, driver := range notified {
+		driverID := driver.ID.Hex()
+		if driverID == winnerID || outcomes[driverID] == broadcastOutcomeRejected {
+			continue
+		}
+
+		switch {
+		case winnerID != "":
+			if err := d.OrderSvc.AddOrderLog(ctx, order.ID.Hex(), model.OrderLogActionDriverLost,
+				string(driver.Fleet), driver.Name, driver.CarPlate, driverID, "搶單失敗，訂單已由其他司機承接", currentRounds); err != nil {
+				d.logger.Error().Err(err).
+					Str("short_id", order.ShortID).
+					Str("driver_id", driverID).
+					Msg("記錄搶單失敗日誌失敗")
+			}
+			d.sendBroadcastCancel(ctx, order, driver, "訂單已由其他司機承接")
+
+		case orderStatus == model.OrderStatusWaiting:
+			if outcomes[driverID] == broadcastOutcomePending {
+				d.handleDriverTimeout(ctx, order, driver)
+			}
+
+		default:
+			// 訂單已取消或失敗，收回司機端的訂單彈窗
+			d.sendBroadcastCancel(ctx, order, driver, "訂單已被取消")
+		}
+	}
+}
+
+// sendBroadcastCancel 推送 cancel_order 通知，收回搶單失敗司機的訂單彈窗
+func (d *Dispatcher) sendBroadcastCancel(ctx context.Context, order *model.Order, driver *model.DriverInfo, message string) {
+	if driver.FcmToken == "" {
+		return
+	}
+
+	cancelData := map[string]interface{}{
+		"notify_order_type": string(model.NotifyTypeCancelOrder),
+		"order_id":          order.ID.Hex(),
+		"message":           message,
+	}
+	notification := map[string]interface{}{
+		"title": "訂單取消通知",
+		"body":  fmt.Sprintf("訂單 %s %s", order.OriText, message),
+		"sound": "cancel_order.wav",
+	}
+
+	if err := d.FcmSvc.Send(ctx, driver.FcmToken, cancelData, notification); err != nil {
+		d.logger.Error().Err(err).
+			Str("short_id", order.ShortID).
+			Str("driver_info", utils.GetDriverInfo(driver)).
+			Msg("推送搶單取消通知失敗")
+	}
+}
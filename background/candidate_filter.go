@@ -0,0 +1,34 @@
+package background
+
+import (
+	"right-backend/model"
+)
+
+// DriverIneligibleReason 檢查司機是否符合訂單的車隊匹配規則、拒絕列表與禁寵/禁五人設定，
+// 符合時回傳空字串，否則回傳過濾原因（派單與離線模擬共用）
+//  1. 本輪未開放跨車隊時只派給本車隊司機
+//  2. 跨車隊時只派給訂單車隊登錄的可派送車隊（dispatch_to_fleets）司機
+func DriverIneligibleReason(order *model.Order, orderFleet *model.Fleet, drv *model.DriverInfo, round model.DispatchRound) string {
+	driverFleet := drv.Fleet
+
+	if !round.CrossFleet && driverFleet != order.Fleet {
+		return "本輪限本車隊"
+	}
+	if !orderFleet.CanDispatchTo(driverFleet) {
+		return "車隊不可跨派給司機所屬車隊"
+	}
+
+	for _, rejectedFleet := range drv.RejectList {
+		if rejectedFleet == string(order.Fleet) {
+			return "拒絕列表包含車隊"
+		}
+	}
+
+	if drv.NoPets && order.HasPets {
+		return "司機設定禁寵，訂單包含寵物"
+	}
+	if drv.NoOverloaded && order.HasOverloaded {
+		return "司機設定禁五人，訂單超載"
+	}
+	return ""
+}
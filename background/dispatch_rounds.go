@@ -0,0 +1,95 @@
+package background
+
+import (
+	"context"
+	"fmt"
+	"right-backend/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// prepareNextRound 進入下一輪派單前：等待輪次間隔並確認訂單仍在等待接單
+// 回傳 false 表示訂單已被處理（接單或取消）或調度中止，不需再派單
+func (d *Dispatcher) prepareNextRound(ctx context.Context, order *model.Order, round model.DispatchRound) bool {
+	if round.DelaySecs > 0 {
+		timer := time.NewTimer(time.Duration(round.DelaySecs) * time.Second)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+
+	currentOrder, err := d.OrderSvc.GetOrderByID(ctx, order.ID.Hex())
+	if err != nil {
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度中心進入下一輪前檢查訂單狀態失敗")
+		return false
+	}
+	if currentOrder.Status != model.OrderStatusWaiting {
+		d.logger.Info().
+			Str("short_id", order.ShortID).
+			Str("status", string(currentOrder.Status)).
+			Msg("調度中心訂單在輪次間已被處理，停止派單")
+		return false
+	}
+	return true
+}
+
+// startDispatchRound 每輪派單開始時遞增派單輪數，讓本輪的司機通知、超時等日誌帶有正確的 Rounds，
+// 流單時 Rounds 即為實際執行的輪數
+func (d *Dispatcher) startDispatchRound(ctx context.Context, order *model.Order) {
+	ordersColl := d.MongoDB.GetCollection("orders")
+	if _, err := ordersColl.UpdateOne(ctx, bson.M{"_id": order.ID}, bson.M{
+		"$inc": bson.M{"rounds": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}); err != nil {
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度中心rounds更新失敗")
+	}
+	order.Rounds = nextRounds(order.Rounds)
+}
+
+// nextRounds 下一輪的派單輪數，未設定時視為 0
+func nextRounds(rounds *int) *int {
+	next := 1
+	if rounds != nil {
+		next = *rounds + 1
+	}
+	return &next
+}
+
+// logDispatchRound 記錄本輪派單的範圍設定到訂單日誌（僅設定多輪派單時記錄）
+func (d *Dispatcher) logDispatchRound(ctx context.Context, order *model.Order, roundIdx, totalRounds int, round model.DispatchRound, zone *model.ZoneRestriction) {
+	if totalRounds <= 1 {
+		return
+	}
+
+	policy := d.policyFor(order.Fleet)
+
+	radius := "不限距離"
+	if enabled, maxKm := MaxDriverDistanceFor(policy, round, zone); enabled {
+		radius = fmt.Sprintf("%.1f公里內", maxKm)
+	}
+	fleetScope := "限本車隊"
+	if round.CrossFleet {
+		fleetScope = "開放跨車隊"
+	}
+	details := fmt.Sprintf("第%d/%d輪派單：%s，%s", roundIdx+1, totalRounds, radius, fleetScope)
+
+	currentRounds := 1
+	if order.Rounds != nil {
+		currentRounds = *order.Rounds
+	}
+
+	if err := d.OrderSvc.AddOrderLog(ctx, order.ID.Hex(), model.OrderLogActionDispatchRound,
+		string(order.Fleet), "", "", "", details, currentRounds); err != nil {
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("記錄派單輪次日誌失敗")
+	}
+
+	d.logger.Info().
+		Str("short_id", order.ShortID).
+		Str("ori_text", order.OriText).
+		Str("details", details).
+		Msg("[調度中心-{short_id}]: ({ori_text}) {details}")
+}
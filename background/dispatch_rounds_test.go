@@ -0,0 +1,49 @@
+package background
+
+import (
+	"right-backend/model"
+	"testing"
+)
+
+func TestMaxDriverDistanceForZone(t *testing.T) {
+	policy := &model.DispatchPolicy{EnableMaxDriverDistance: true, MaxDriverDistanceKm: 15}
+
+	testCases := []struct {
+		name        string
+		round       model.DispatchRound
+		zone        *model.ZoneRestriction
+		wantEnabled bool
+		wantKm      float64
+	}{
+		{name: "無服務區域沿用車隊設定", round: model.DispatchRound{}, wantEnabled: true, wantKm: 15},
+		{name: "服務區域未指定半徑", round: model.DispatchRound{}, zone: &model.ZoneRestriction{}, wantEnabled: true, wantKm: 15},
+		{name: "服務區域半徑覆蓋車隊設定", round: model.DispatchRound{}, zone: &model.ZoneRestriction{MaxDriverDistanceKm: 5}, wantEnabled: true, wantKm: 5},
+		{name: "輪次半徑優先於服務區域", round: model.DispatchRound{MaxDriverDistanceKm: 3}, zone: &model.ZoneRestriction{MaxDriverDistanceKm: 5}, wantEnabled: true, wantKm: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enabled, km := MaxDriverDistanceFor(policy, tc.round, tc.zone)
+			if enabled != tc.wantEnabled || km != tc.wantKm {
+				t.Fatalf("預期 (%v, %.1f)，實際為 (%v, %.1f)", tc.wantEnabled, tc.wantKm, enabled, km)
+			}
+		})
+	}
+}
+
+func TestNextRounds(t *testing.T) {
+	// 新訂單第一輪派單
+	rounds := nextRounds(nil)
+	if *rounds != 1 {
+		t.Fatalf("未設定輪數時預期為 1，實際為 %d", *rounds)
+	}
+
+	// 三輪派單後流單，輪數即為實際執行的輪數
+	rounds = nil
+	for i := 0; i < 3; i++ {
+		rounds = nextRounds(rounds)
+	}
+	if *rounds != 3 {
+		t.Fatalf("執行 3 輪後預期輪數為 3，實際為 %d", *rounds)
+	}
+}
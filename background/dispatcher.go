@@ -10,29 +10,21 @@ import (
 	"right-backend/service"
 	"right-backend/service/interfaces"
 	"right-backend/utils"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	driverModels "right-backend/data-models/driver"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel/trace"
 )
 
-const (
-	haversineCandidatesCount   = 15               // 直線距離計算的候選司機數量上限（用於初步篩選）
-	googleAPICandidatesCount   = 5                // Google Maps API 真實路徑計算的候選司機數量上限（最終派單數）
-	sequentialCallTimeout      = 19 * time.Second // 依序呼叫每位司機的等待時間（前端顯示17秒）
-	enableMaxDriverDistance    = true             // 啟用司機直線距離篩選功能
-	maxDriverDistanceKm        = 15.0             // 司機直線距離超過15公里將被篩選排除
-	enableMaxEstimatedTimeMins = true             // 啟用真實距離預估時間篩選功能
-	maxEstimatedTimeMins       = 20               // 真實距離預估時間超過20分鐘將被篩選排除
-)
-
 type Dispatcher struct {
 	logger              zerolog.Logger
 	MongoDB             *infra.MongoDB
@@ -44,7 +36,11 @@ type Dispatcher struct {
 	BlacklistSvc        *service.DriverBlacklistService
 	EventManager        *infra.RedisEventManager // 新增：事件管理器
 	NotificationService *service.NotificationService
-	dispatcherID        string // 新增：調度器唯一ID
+	PolicySvc           *service.DispatchPolicyService // 車隊派單策略（候選人數、距離、等待時間等）
+	DeadLetterSvc       *service.DeadLetterService     // 多次派單失敗的訂單移入死信隊列
+	ZoneSvc             *service.ServiceZoneService    // 服務區域規則（不服務區域、司機標籤、區域半徑）
+	FleetSvc            *service.FleetService          // 車隊登錄資料（跨車隊派單矩陣、車隊設定）
+	dispatcherID        string                         // 新增：調度器唯一ID
 }
 
 // NewDispatcher 建立新的 Dispatcher
@@ -73,6 +69,24 @@ func NewDispatcher(logger zerolog.Logger, mongoDB *infra.MongoDB, rabbitMQ *infr
 	}
 }
 
+// SetDispatchPolicyService 設定派單策略服務
+func (d *Dispatcher) SetDispatchPolicyService(policySvc *service.DispatchPolicyService) {
+	d.PolicySvc = policySvc
+}
+
+// SetDeadLetterService 設定死信隊列服務
+func (d *Dispatcher) SetDeadLetterService(deadLetterSvc *service.DeadLetterService) {
+	d.DeadLetterSvc = deadLetterSvc
+}
+
+// policyFor 取得車隊目前生效的派單策略
+func (d *Dispatcher) policyFor(fleet model.FleetType) *model.DispatchPolicy {
+	if d.PolicySvc == nil {
+		return model.DefaultDispatchPolicy(fleet, d.fleetFor(fleet).Settings)
+	}
+	return d.PolicySvc.GetPolicy(fleet)
+}
+
 // logTrafficUsage 記錄流量使用到 traffic_usage_log 表
 func (d *Dispatcher) logTrafficUsage(ctx context.Context, service, api string, params map[string]interface{}, fleet string, elements int) {
 	if d.TrafficUsageLogSvc != nil {
@@ -92,24 +106,50 @@ func (d *Dispatcher) logTrafficUsage(ctx context.Context, service, api string, p
 }
 
 func (d *Dispatcher) Start(ctx context.Context) {
-	msgs, err := d.RabbitMQ.Channel.Consume(
-		infra.QueueNameOrders.String(), "", true, false, false, false, nil,
+	workers, prefetch, maxRetries := orderConsumerSettings()
+
+	// 使用獨立 channel，避免 QoS 設定影響其他消費者
+	ch, err := d.RabbitMQ.Connection.Channel()
+	if err != nil {
+		d.logger.Fatal().Err(err).Msg("調度中心無法建立 RabbitMQ channel")
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		d.logger.Fatal().Err(err).Int("prefetch", prefetch).Msg("調度中心設定預取數量失敗")
+	}
+
+	// 手動 ack：訂單派單流程結束（成功、流單或移入死信）後才確認，服務中斷時訊息會重新投遞
+	msgs, err := ch.Consume(
+		infra.QueueNameOrders.String(), "", false, false, false, false, nil,
 	)
 	if err != nil {
 		d.logger.Fatal().Err(err).Str("queue", infra.QueueNameOrders.String()).Msg("調度中心無法消費隊列")
 	}
-	d.logger.Info().Msg("調度中心已啟動，等待訂單...")
-	for msg := range msgs {
-		var order model.Order
-		if err := json.Unmarshal(msg.Body, &order); err != nil {
-			d.logger.Error().Err(err).Msg("調度中心訂單資料解析失敗")
-			continue
-		}
-		go d.handleOrder(ctx, &order)
+	d.logger.Info().
+		Int("workers", workers).
+		Int("prefetch", prefetch).
+		Int("max_retries", maxRetries).
+		Msg("調度中心已啟動，等待訂單...")
+
+	// 啟動中斷訂單恢復掃描（啟動時立即執行一次，之後定期執行）
+	go d.runRecoverySweeper(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				d.processOrderDelivery(ctx, msg, maxRetries)
+			}
+		}()
 	}
+	wg.Wait()
+	d.logger.Warn().Msg("調度中心訂單隊列已關閉，停止消費")
 }
 
-func (d *Dispatcher) handleOrder(ctx context.Context, order *model.Order) {
+func (d *Dispatcher) handleOrder(ctx context.Context, order *model.Order) error {
 	// 獲取當前 span
 	span := trace.SpanFromContext(ctx)
 
@@ -158,114 +198,131 @@ func (d *Dispatcher) handleOrder(ctx context.Context, order *model.Order) {
 			Str("trace_id", span.SpanContext().TraceID().String()).
 			Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
 			Msg("即時單調度器接收到預約單，這不應該發生")
-		return
+		return nil
 	}
 
-	// 記錄每次調度處理（每個訂單經過dispatcher都記錄一次，使用googleAPICandidatesCount作為Elements）
+	// 依上車點所在服務區域套用限制，位於不服務區域的訂單直接流單
+	zone := d.zoneRestrictionFor(dispatchCtx, order)
+	if zone.IsRejected() {
+		infra.AddEvent(dispatchSpan, "order_in_rejected_zone",
+			infra.AttrString("zone", zone.RejectZone),
+		)
+		d.logger.Warn().
+			Str("short_id", order.ShortID).
+			Str("ori_text", order.OriText).
+			Str("zone", zone.RejectZone).
+			Msg("[調度中心-{short_id}]: ({ori_text}) 上車地點位於不服務區域「{zone}」，流單")
+		if err := d.failOrder(ctx, *order.ID, "上車地點位於不服務區域"); err != nil {
+			return fmt.Errorf("更新訂單為失敗狀態時出錯: %w", err)
+		}
+		return nil
+	}
+
+	// 記錄每次調度處理（每個訂單經過dispatcher都記錄一次，使用最終派單人數作為Elements）
 	dispatchParams := map[string]interface{}{
 		"order_id":       order.ID.Hex(),
 		"pickup_address": order.Customer.PickupAddress,
 		"dest_address":   order.Customer.DestAddress,
 	}
-	d.logTrafficUsage(ctx, "google", "dispatch", dispatchParams, string(order.Fleet), googleAPICandidatesCount)
-
-	// 1. Find best candidate drivers
-	infra.AddEvent(dispatchSpan, "finding_candidate_drivers")
-	candidates, distances, durationMins, crawlerCompletedAt, err := d.findCandidateDrivers(dispatchCtx, order)
-	if err != nil {
-		// 記錄錯誤到 span
-		infra.RecordError(dispatchSpan, err, "Find candidate drivers failed",
-			infra.AttrOrderID(order.ID.Hex()),
-			infra.AttrString("error", err.Error()),
-		)
-		infra.AddEvent(dispatchSpan, "find_candidates_failed",
-			infra.AttrString("error", err.Error()),
+	d.logTrafficUsage(ctx, "google", "dispatch", dispatchParams, string(order.Fleet), d.policyFor(order.Fleet).GoogleAPICandidatesCount)
+
+	// 1. 依派單輪次逐輪擴大範圍尋找司機，全部輪次用盡才流單
+	rounds := d.policyFor(order.Fleet).DispatchRounds()
+	orderMatched := false
+	orderProcessed := false
+	failReason := ""
+	for roundIdx, round := range rounds {
+		if roundIdx > 0 {
+			if !d.prepareNextRound(dispatchCtx, order, round) {
+				orderProcessed = true
+				break
+			}
+		}
+		d.startDispatchRound(dispatchCtx, order)
+		d.logDispatchRound(dispatchCtx, order, roundIdx, len(rounds), round, zone)
+		infra.AddEvent(dispatchSpan, "dispatch_round_started",
+			infra.AttrInt("round", roundIdx+1),
+			infra.AttrFloat64("max_driver_distance_km", round.MaxDriverDistanceKm),
+			infra.AttrBool("cross_fleet", round.CrossFleet),
 		)
 
-		d.logger.Error().Err(err).
-			Str("short_id", order.ShortID).
-			Str("order_id", order.ID.Hex()).
-			Str("trace_id", span.SpanContext().TraceID().String()).
-			Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-			Msg("調度中心尋找候選司機失敗")
-		if err := d.failOrder(dispatchCtx, *order.ID, "尋找司機過程出錯"); err != nil {
+		// 1.1 Find best candidate drivers
+		infra.AddEvent(dispatchSpan, "finding_candidate_drivers")
+		candidates, distances, durationMins, crawlerCompletedAt, err := d.findCandidateDrivers(dispatchCtx, order, round, zone)
+		if err != nil {
+			// 記錄錯誤到 span
+			infra.RecordError(dispatchSpan, err, "Find candidate drivers failed",
+				infra.AttrOrderID(order.ID.Hex()),
+				infra.AttrString("error", err.Error()),
+			)
+			infra.AddEvent(dispatchSpan, "find_candidates_failed",
+				infra.AttrString("error", err.Error()),
+			)
+
 			d.logger.Error().Err(err).
 				Str("short_id", order.ShortID).
 				Str("order_id", order.ID.Hex()).
 				Str("trace_id", span.SpanContext().TraceID().String()).
 				Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-				Msg("調度中心更新訂單為失敗狀態時出錯")
+				Msg("調度中心尋找候選司機失敗")
+			// 交由消費者重試，重試次數用盡後才流單並移入死信隊列
+			return fmt.Errorf("尋找司機過程出錯: %w", err)
 		}
-		return
-	}
 
-	if len(candidates) == 0 {
-		infra.AddEvent(dispatchSpan, "no_candidates_found")
-		infra.SetAttributes(dispatchSpan,
-			infra.AttrString("dispatch.failure_reason", "no_candidates"),
-			infra.AttrBool("dispatch.success", false),
-			infra.AttrInt("candidates.count", 0),
-		)
-		d.logger.Warn().
-			Str("short_id", order.ShortID).
-			Str("order_id", order.ID.Hex()).
-			Str("trace_id", span.SpanContext().TraceID().String()).
-			Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-			Msg("調度中心找不到任何可派單的司機")
-		if err := d.failOrder(dispatchCtx, *order.ID, "附近無可用司機"); err != nil {
-			d.logger.Error().Err(err).
+		if len(candidates) == 0 {
+			infra.AddEvent(dispatchSpan, "no_candidates_found",
+				infra.AttrInt("round", roundIdx+1),
+			)
+			d.logger.Warn().
 				Str("short_id", order.ShortID).
 				Str("order_id", order.ID.Hex()).
+				Int("round", roundIdx+1).
 				Str("trace_id", span.SpanContext().TraceID().String()).
 				Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-				Msg("調度中心更新訂單為失敗狀態時出錯")
+				Msg("調度中心本輪找不到任何可派單的司機")
+			failReason = "附近無可用司機"
+			continue
 		}
-		return
-	}
-
-	// 添加候選司機信息到 span
-	infra.AddEvent(dispatchSpan, "candidates_found",
-		infra.AttrInt("candidates_count", len(candidates)),
-	)
-	infra.SetAttributes(dispatchSpan,
-		infra.AttrInt("candidates.count", len(candidates)),
-	)
 
-	// 2. Dispatch order to candidates in parallel
-	infra.AddEvent(dispatchSpan, "dispatching_to_drivers",
-		infra.AttrInt("candidates_count", len(candidates)),
-	)
-	orderMatched, err := d.dispatchOrderToDrivers(dispatchCtx, order, candidates, distances, durationMins, crawlerCompletedAt)
-	if err != nil {
-		// 記錄錯誤到 span
-		infra.RecordError(dispatchSpan, err, "Dispatch to drivers failed",
-			infra.AttrOrderID(order.ID.Hex()),
-			infra.AttrString("error", err.Error()),
+		// 添加候選司機信息到 span
+		infra.AddEvent(dispatchSpan, "candidates_found",
+			infra.AttrInt("candidates_count", len(candidates)),
 		)
-		infra.AddEvent(dispatchSpan, "dispatch_to_drivers_failed",
-			infra.AttrString("error", err.Error()),
+		infra.SetAttributes(dispatchSpan,
+			infra.AttrInt("candidates.count", len(candidates)),
 		)
-		d.logger.Error().Err(err).
-			Str("short_id", order.ShortID).
-			Str("order_id", order.ID.Hex()).
-			Str("trace_id", span.SpanContext().TraceID().String()).
-			Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-			Msg("調度中心派單過程發生錯誤")
-	}
 
-	// 3. Handle dispatch result
-	if !orderMatched {
-		infra.AddEvent(dispatchSpan, "order_not_matched")
-		// 在標記失敗前，再次檢查訂單狀態，以處理邊界情況 (e.g. 司機在超時後一瞬間接單)
+		// 1.2 Dispatch order to candidates
+		infra.AddEvent(dispatchSpan, "dispatching_to_drivers",
+			infra.AttrInt("candidates_count", len(candidates)),
+		)
+		orderMatched, err = d.dispatchOrderToDrivers(dispatchCtx, order, candidates, distances, durationMins, crawlerCompletedAt)
+		if err != nil {
+			// 記錄錯誤到 span
+			infra.RecordError(dispatchSpan, err, "Dispatch to drivers failed",
+				infra.AttrOrderID(order.ID.Hex()),
+				infra.AttrString("error", err.Error()),
+			)
+			infra.AddEvent(dispatchSpan, "dispatch_to_drivers_failed",
+				infra.AttrString("error", err.Error()),
+			)
+			d.logger.Error().Err(err).
+				Str("short_id", order.ShortID).
+				Str("order_id", order.ID.Hex()).
+				Str("trace_id", span.SpanContext().TraceID().String()).
+				Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
+				Msg("調度中心派單過程發生錯誤")
+		}
+		if orderMatched {
+			break
+		}
+
+		// 進入下一輪前，再次檢查訂單狀態，以處理邊界情況 (e.g. 司機在超時後一瞬間接單、訂單已取消)
 		finalOrder, checkErr := d.OrderSvc.GetOrderByID(dispatchCtx, order.ID.Hex())
 		if checkErr == nil && finalOrder.Status != model.OrderStatusWaiting {
 			infra.AddEvent(dispatchSpan, "order_already_processed",
 				infra.AttrString("final_status", string(finalOrder.Status)),
 			)
-			infra.SetAttributes(dispatchSpan,
-				infra.AttrString("dispatch.result", "already_processed"),
-				infra.AttrBool("dispatch.success", true),
-			)
 			d.logger.Info().
 				Str("short_id", order.ShortID).
 				Str("order_id", order.ID.Hex()).
@@ -273,27 +330,14 @@ func (d *Dispatcher) handleOrder(ctx context.Context, order *model.Order) {
 				Str("trace_id", span.SpanContext().TraceID().String()).
 				Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
 				Msg("調度中心訂單在最後確認時已被處理")
-		} else {
-			infra.AddEvent(dispatchSpan, "order_timeout_no_acceptance")
-			infra.SetAttributes(dispatchSpan,
-				infra.AttrString("dispatch.failure_reason", "no_driver_acceptance"),
-				infra.AttrBool("dispatch.success", false),
-			)
-			d.logger.Warn().
-				Str("order_id", order.ID.Hex()).
-				Str("trace_id", span.SpanContext().TraceID().String()).
-				Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-				Msgf("[調度中心-%s]: (%s) 派單時間截止，訂單無人接受", order.ShortID, order.OriText)
-			if err := d.failOrder(dispatchCtx, *order.ID, "無司機接單"); err != nil {
-				d.logger.Error().Err(err).
-					Str("short_id", order.ShortID).
-					Str("order_id", order.ID.Hex()).
-					Str("trace_id", span.SpanContext().TraceID().String()).
-					Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-					Msg("調度中心更新訂單為失敗狀態時出錯")
-			}
+			orderProcessed = true
+			break
 		}
-	} else {
+		failReason = "無司機接單"
+	}
+
+	// 2. Handle dispatch result
+	if orderMatched {
 		infra.AddEvent(dispatchSpan, "order_matched_successfully")
 		infra.MarkSuccess(dispatchSpan,
 			infra.AttrString("dispatch.result", "matched"),
@@ -305,25 +349,36 @@ func (d *Dispatcher) handleOrder(ctx context.Context, order *model.Order) {
 			Str("trace_id", span.SpanContext().TraceID().String()).
 			Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
 			Msg("調度中心訂單已成功匹配司機")
-	}
-
-	// 4. Finalize order processing by incrementing the dispatch round
-	infra.AddEvent(dispatchSpan, "finalizing_dispatch_round")
-	ordersColl := d.MongoDB.GetCollection("orders")
-	_, err = ordersColl.UpdateOne(dispatchCtx, map[string]interface{}{"_id": order.ID}, map[string]interface{}{"$inc": map[string]interface{}{"rounds": 1}, "$set": map[string]interface{}{"updated_at": time.Now()}})
-	if err != nil {
-		infra.AddEvent(dispatchSpan, "rounds_update_failed",
-			infra.AttrString("error", err.Error()),
+	} else if orderProcessed {
+		infra.SetAttributes(dispatchSpan,
+			infra.AttrString("dispatch.result", "already_processed"),
+			infra.AttrBool("dispatch.success", true),
 		)
-		d.logger.Error().Err(err).
-			Str("short_id", order.ShortID).
+	} else {
+		infra.AddEvent(dispatchSpan, "order_not_matched")
+		infra.SetAttributes(dispatchSpan,
+			infra.AttrString("dispatch.failure_reason", failReason),
+			infra.AttrBool("dispatch.success", false),
+			infra.AttrInt("dispatch.rounds", len(rounds)),
+		)
+		d.logger.Warn().
+			Str("order_id", order.ID.Hex()).
+			Int("rounds", len(rounds)).
 			Str("trace_id", span.SpanContext().TraceID().String()).
 			Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
-			Msg("調度中心rounds更新失敗")
-	} else {
-		infra.AddEvent(dispatchSpan, "dispatch_completed")
+			Msgf("[調度中心-%s]: (%s) 派單輪次已用盡(%s)，訂單無人接受", order.ShortID, order.OriText, failReason)
+		if err := d.failOrder(dispatchCtx, *order.ID, failReason); err != nil {
+			d.logger.Error().Err(err).
+				Str("short_id", order.ShortID).
+				Str("order_id", order.ID.Hex()).
+				Str("trace_id", span.SpanContext().TraceID().String()).
+				Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
+				Msg("調度中心更新訂單為失敗狀態時出錯")
+		}
 	}
 
+	infra.AddEvent(dispatchSpan, "dispatch_completed")
+
 	d.logger.Info().
 		Str("short_id", order.ShortID).
 		Str("order_id", order.ID.Hex()).
@@ -331,10 +386,17 @@ func (d *Dispatcher) handleOrder(ctx context.Context, order *model.Order) {
 		Str("trace_id", span.SpanContext().TraceID().String()).
 		Str("span_id", dispatchSpan.SpanContext().SpanID().String()).
 		Msg("[調度中心-{short_id}]: ({ori_text}) 訂單派單流程完成")
+	return nil
+}
+
+// nearbyDriver $geoNear 查詢結果，附帶與上車點的球面距離
+type nearbyDriver struct {
+	model.DriverInfo `bson:",inline"`
+	DistanceM        float64 `bson:"geo_distance_m"`
 }
 
 // 第一步驟 查找並過濾出最佳的候選司機 (上線司機)
-func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Order) ([]*model.DriverInfo, []string, []int, time.Time, error) {
+func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Order, round model.DispatchRound, zone *model.ZoneRestriction) ([]*model.DriverInfo, []string, []int, time.Time, error) {
 	// 獲取當前 span
 	span := trace.SpanFromContext(ctx)
 
@@ -350,7 +412,19 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 	infra.AddEvent(findSpan, "find_candidates_started",
 		infra.AttrOrderID(order.ID.Hex()),
 	)
-	// 1. 查詢所有上線司機
+	policy := d.policyFor(order.Fleet)
+	// 本輪或服務區域有設定半徑時覆蓋車隊直線距離上限
+	enableMaxDriverDistance, maxDriverDistanceKm := MaxDriverDistanceFor(policy, round, zone)
+
+	lat, lng := 0.0, 0.0
+	if order.Customer.PickupLat != nil {
+		lat, _ = strconv.ParseFloat(*order.Customer.PickupLat, 64)
+	}
+	if order.Customer.PickupLng != nil {
+		lng, _ = strconv.ParseFloat(*order.Customer.PickupLng, 64)
+	}
+
+	// 1. 以 $geoNear 查詢上車點附近的上線司機，依距離排序且超出本輪直線距離上限的司機不會回傳
 	infra.AddEvent(findSpan, "querying_online_drivers")
 	driversColl := d.MongoDB.GetCollection("drivers")
 	filter := map[string]interface{}{
@@ -372,7 +446,17 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 		},
 	}
 
-	cursor, err := driversColl.Find(findCtx, filter)
+	geoNear := bson.M{
+		"near":          model.NewGeoPoint(lat, lng),
+		"distanceField": "geo_distance_m",
+		"spherical":     true,
+		"query":         filter,
+	}
+	if enableMaxDriverDistance {
+		geoNear["maxDistance"] = maxDriverDistanceKm * 1000
+	}
+
+	cursor, err := driversColl.Aggregate(findCtx, mongo.Pipeline{{{Key: "$geoNear", Value: geoNear}}})
 	if err != nil {
 		infra.RecordError(findSpan, err, "Database query failed",
 			infra.AttrString("error", err.Error()),
@@ -389,7 +473,7 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 		}
 	}()
 
-	var drivers []*model.DriverInfo
+	var drivers []*nearbyDriver
 	if err := cursor.All(findCtx, &drivers); err != nil {
 		infra.RecordError(findSpan, err, "Driver parsing failed",
 			infra.AttrString("error", err.Error()),
@@ -406,9 +490,12 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 		infra.AttrInt("initial_count", len(drivers)),
 	)
 
-	// 2. 過濾掉黑名單、沒有有效經緯度和正在處理訂單的司機
-	var validDrivers []*model.DriverInfo
-	for _, drv := range drivers {
+	// 2. 過濾掉黑名單和正在處理訂單的司機（結果維持依距離排序）
+	orderFleet := d.fleetFor(order.Fleet)
+	var validDrivers []driverModels.DriverWithDist
+	for _, nearby := range drivers {
+		drv := &nearby.DriverInfo
+
 		// 檢查司機是否在黑名單中
 		if infra.AppConfig.DriverBlacklist.Enabled && d.BlacklistSvc != nil {
 			isBlacklisted, err := d.BlacklistSvc.IsDriverBlacklisted(ctx, drv.ID.Hex(), order.Customer.PickupAddress)
@@ -479,62 +566,30 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 			}
 		}
 
-		// 檢查車隊匹配規則：
-		// 1. WEI 車隊訂單只能派給 WEI 車隊司機
-		// 2. RSK, KD 車隊訂單可以互相派送，但不能派給 WEI 車隊司機
-		orderFleet := order.Fleet
-		driverFleet := drv.Fleet
-
-		if orderFleet == model.FleetTypeWEI && driverFleet != model.FleetTypeWEI {
-			continue
-		}
-
-		if (orderFleet == model.FleetTypeRSK || orderFleet == model.FleetTypeKD) && driverFleet == model.FleetTypeWEI {
-			continue
-		}
-
-		// 檢查司機的拒絕列表，如果該司機的拒絕列表包含此訂單的車隊，則跳過
-		if drv.RejectList != nil {
-			shouldSkip := false
-			for _, rejectedFleet := range drv.RejectList {
-				if rejectedFleet == string(order.Fleet) {
-					shouldSkip = true
-					d.logger.Debug().Str("short_id", order.ShortID).Str("driver_name", drv.Name).Str("car_plate", drv.CarPlate).Str("fleet", string(order.Fleet)).Msg("調度中心司機在拒絕列表中包含車隊，已過濾")
-					break
-				}
-			}
-			if shouldSkip {
-				continue
-			}
-		}
-
-		// 檢查司機的禁寵設定，如果司機設定禁寵且訂單包含寵物，則跳過
-		if drv.NoPets && order.HasPets {
+		// 檢查車隊匹配規則、拒絕列表與禁寵/禁五人設定
+		if reason := DriverIneligibleReason(order, orderFleet, drv, round); reason != "" {
 			d.logger.Debug().
 				Str("short_id", order.ShortID).
 				Str("driver_name", drv.Name).
 				Str("car_plate", drv.CarPlate).
-				Bool("has_pets", order.HasPets).
-				Msg("調度中心司機設定禁寵，訂單包含寵物，已過濾")
+				Str("fleet", string(order.Fleet)).
+				Str("reason", reason).
+				Msg("調度中心司機不符合訂單條件，已過濾")
 			continue
 		}
 
-		// 檢查司機的禁五人設定，如果司機設定禁五人且訂單超載，則跳過
-		if drv.NoOverloaded && order.HasOverloaded {
+		// 服務區域要求特定司機標籤
+		if missingTag := zone.MissingDriverTag(drv); missingTag != "" {
 			d.logger.Debug().
 				Str("short_id", order.ShortID).
 				Str("driver_name", drv.Name).
 				Str("car_plate", drv.CarPlate).
-				Bool("has_overloaded", order.HasOverloaded).
-				Msg("調度中心司機設定禁五人，訂單超載，已過濾")
+				Str("required_tag", missingTag).
+				Msg("調度中心司機缺少服務區域要求的標籤，已過濾")
 			continue
 		}
 
-		lat, _ := strconv.ParseFloat(drv.Lat, 64)
-		lng, _ := strconv.ParseFloat(drv.Lng, 64)
-		if lat != 0 && lng != 0 {
-			validDrivers = append(validDrivers, drv)
-		}
+		validDrivers = append(validDrivers, driverModels.DriverWithDist{Driver: drv, Dist: nearby.DistanceM / 1000})
 	}
 
 	if len(validDrivers) == 0 {
@@ -557,30 +612,8 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 		infra.AttrInt("initial_count", len(drivers)),
 	)
 
-	// 3. Haversine 算法找出距離最近的候選人
-	lat, lng := 0.0, 0.0
-	if order.Customer.PickupLat != nil {
-		lat, _ = strconv.ParseFloat(*order.Customer.PickupLat, 64)
-	}
-	if order.Customer.PickupLng != nil {
-		lng, _ = strconv.ParseFloat(*order.Customer.PickupLng, 64)
-	}
-
-	var driverDists []driverModels.DriverWithDist
-	for _, drv := range validDrivers {
-		driverLat, _ := strconv.ParseFloat(drv.Lat, 64)
-		driverLng, _ := strconv.ParseFloat(drv.Lng, 64)
-		dist := utils.Haversine(lat, lng, driverLat, driverLng)
-
-		// 過濾掉直線距離超過15公里的司機（如果啟用），但 WEI 車隊訂單無視距離限制
-		if enableMaxDriverDistance && dist > maxDriverDistanceKm && order.Fleet != model.FleetTypeWEI {
-			d.logger.Debug().Str("short_id", order.ShortID).Str("driver_name", drv.Name).Str("car_plate", drv.CarPlate).Float64("distance_km", dist).Msg("調度中心司機直線距離超過15公里，已過濾")
-			continue
-		}
-
-		driverDists = append(driverDists, driverModels.DriverWithDist{Driver: drv, Dist: dist})
-	}
-	sort.Slice(driverDists, func(i, j int) bool { return driverDists[i].Dist < driverDists[j].Dist })
+	// 3. 取直線距離最近的候選人（$geoNear 已依距離排序並套用距離上限）
+	driverDists := validDrivers
 
 	// 建立直線距離排名列表
 	var driverHaversineInfos []string
@@ -598,8 +631,8 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 		Str("driver_rankings", strings.Join(driverHaversineInfos, " | ")).
 		Msg("[調度中心-{short_id}]: ({ori_text}) 直線距離排序完成，共{driver_count}名司機，排名: {driver_rankings}")
 
-	if len(driverDists) > haversineCandidatesCount {
-		driverDists = driverDists[:haversineCandidatesCount]
+	if len(driverDists) > policy.HaversineCandidatesCount {
+		driverDists = driverDists[:policy.HaversineCandidatesCount]
 	}
 
 	// 4. 使用 CrawlerService 計算真實路徑，找出最佳候選人
@@ -655,77 +688,70 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 		d.logger.Warn().Int("routes_count", len(routes)).Int("drivers_count", len(origins)).Msg("Crawler返回的路線數量與司機數量不匹配")
 	}
 
-	// 建立結果結構，包含司機索引、距離和時間
-	type DriverRoute struct {
-		Index       int     // 司機在原陣列中的索引
-		Distance    float64 // 距離（公里）
-		Duration    int     // 時間（分鐘）
-		DistanceStr string  // 距離字串
-		DurationStr string  // 時間字串
-	}
-
-	var driverRoutes []DriverRoute
+	// 建立排序用的候選司機快照，並過濾預估時間過長的司機
+	var rankingCandidates []*RankingCandidate
 	for i, route := range routes {
 		if i < len(origins) { // 確保索引不超出範圍
-			// 過濾掉真實距離預估時間超過20分鐘的司機（如果啟用），但 WEI 車隊訂單無視時間限制
-			if enableMaxEstimatedTimeMins && route.TimeInMinutes > maxEstimatedTimeMins && order.Fleet != model.FleetTypeWEI {
+			// 過濾掉真實距離預估時間超過車隊上限的司機（如果啟用）
+			if policy.EnableMaxEstimatedTimeMins && route.TimeInMinutes > policy.MaxEstimatedTimeMins {
 				driverInfo := "未知司機"
 				if i < len(driverDists) {
 					driverInfo = fmt.Sprintf("%s %s", driverDists[i].Driver.Name, driverDists[i].Driver.CarPlate)
 				}
-				d.logger.Debug().Str("short_id", order.ShortID).Str("driver_info", driverInfo).Int("estimated_mins", route.TimeInMinutes).Msg("調度中心司機真實距離預估時間超過20分鐘，已過濾")
+				d.logger.Debug().Str("short_id", order.ShortID).Str("driver_info", driverInfo).Int("estimated_mins", route.TimeInMinutes).Int("max_estimated_mins", policy.MaxEstimatedTimeMins).Msg("調度中心司機真實距離預估時間超過上限，已過濾")
 				continue
 			}
 
-			driverRoutes = append(driverRoutes, DriverRoute{
-				Index:       i,
-				Distance:    route.DistanceKm,
-				Duration:    route.TimeInMinutes,
+			rankingCandidates = append(rankingCandidates, &RankingCandidate{
+				Driver:      driverDists[i].Driver,
+				HaversineKm: driverDists[i].Dist,
+				EtaMins:     route.TimeInMinutes,
+				DistanceKm:  route.DistanceKm,
 				DistanceStr: route.Distance,
-				DurationStr: route.Time,
 			})
 		}
 	}
 
-	// 根據時間排序（時間短的優先）
-	sort.Slice(driverRoutes, func(i, j int) bool {
-		return driverRoutes[i].Duration < driverRoutes[j].Duration
-	})
+	// 依車隊設定的排序策略排序候選司機
+	strategy := d.rankingStrategyFor(policy)
+	if strategy.NeedsDriverStats() {
+		d.loadDriverDailyStats(findCtx, rankingCandidates)
+	}
+	rankingCandidates = strategy.Rank(order, rankingCandidates, time.Now())
+	infra.AddEvent(findSpan, "candidates_ranked",
+		infra.AttrString("ranking_strategy", strategy.Name()),
+		infra.AttrInt("ranked_count", len(rankingCandidates)),
+	)
 
 	// 取前 N 名候選人
-	candidateCount := googleAPICandidatesCount
-	if len(driverRoutes) < candidateCount {
-		candidateCount = len(driverRoutes)
+	candidateCount := policy.GoogleAPICandidatesCount
+	if len(rankingCandidates) < candidateCount {
+		candidateCount = len(rankingCandidates)
 	}
 
 	// 提取結果
-	var idxs []int
+	var finalCandidates []*model.DriverInfo
 	var distances []string
 	var durationMins []int
-	for i := 0; i < candidateCount; i++ {
-		route := driverRoutes[i]
-		idxs = append(idxs, route.Index)
-		distances = append(distances, route.DistanceStr)
-		durationMins = append(durationMins, route.Duration)
-	}
-
-	var finalCandidates []*model.DriverInfo
 	var driverInfos []string
-	for n, i := range idxs {
-		finalCandidates = append(finalCandidates, driverDists[i].Driver)
+	for n, c := range rankingCandidates[:candidateCount] {
+		finalCandidates = append(finalCandidates, c.Driver)
+		distances = append(distances, c.DistanceStr)
+		durationMins = append(durationMins, c.EtaMins)
 		driverInfos = append(driverInfos, fmt.Sprintf("排名%d: %s %s 預估時間:%d分鐘 距離:%s",
-			n+1, driverDists[i].Driver.Name, driverDists[i].Driver.CarPlate, durationMins[n], distances[n]))
+			n+1, c.Driver.Name, c.Driver.CarPlate, c.EtaMins, c.DistanceStr))
 	}
 	d.logger.Warn().
 		Str("short_id", order.ShortID).
 		Str("ori_text", order.OriText).
+		Str("ranking_strategy", strategy.Name()).
 		Int("candidate_count", len(finalCandidates)).
 		Str("final_rankings", strings.Join(driverInfos, " | ")).
-		Msg("[調度中心-{short_id}]: ({ori_text}) 真實距離司機排名完成，共{candidate_count}名司機，依序發送，排名: {final_rankings}")
+		Msg("[調度中心-{short_id}]: ({ori_text}) 真實距離司機排名完成({ranking_strategy})，共{candidate_count}名司機，依序發送，排名: {final_rankings}")
 	// 添加最終結果事件
 	infra.AddEvent(findSpan, "final_candidates_selected",
 		infra.AttrInt("final_count", len(finalCandidates)),
-		infra.AttrInt("google_api_limit", googleAPICandidatesCount),
+		infra.AttrInt("google_api_limit", policy.GoogleAPICandidatesCount),
 	)
 
 	// 設置成功的 span 屬性
@@ -739,6 +765,69 @@ func (d *Dispatcher) findCandidateDrivers(ctx context.Context, order *model.Orde
 	return finalCandidates, distances, durationMins, crawlerCompletedAt, nil
 }
 
+// loadDriverDailyStats 載入候選司機今日的閒置起始時間與完成訂單收入，供公平派單策略使用
+func (d *Dispatcher) loadDriverDailyStats(ctx context.Context, candidates []*RankingCandidate) {
+	if len(candidates) == 0 {
+		return
+	}
+
+	taipeiLocation := time.FixedZone("Asia/Taipei", 8*3600)
+	now := time.Now().In(taipeiLocation)
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, taipeiLocation)
+
+	driverIDs := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		driverIDs = append(driverIDs, c.Driver.ID.Hex())
+		// 預設以上線時間作為閒置起點
+		c.IdleSince = c.Driver.LastOnline
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"driver.assigned_driver": bson.M{"$in": driverIDs},
+			"status":                 model.OrderStatusCompleted,
+			"completion_time":        bson.M{"$gte": todayStart},
+		}},
+		{"$group": bson.M{
+			"_id":             "$driver.assigned_driver",
+			"income":          bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$income", 0}}},
+			"last_completion": bson.M{"$max": "$completion_time"},
+		}},
+	}
+
+	cursor, err := d.MongoDB.GetCollection("orders").Aggregate(ctx, pipeline)
+	if err != nil {
+		d.logger.Error().Err(err).Msg("查詢司機今日統計失敗，排序將不考慮收入與閒置時間")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var stats []struct {
+		DriverID       string    `bson:"_id"`
+		Income         int       `bson:"income"`
+		LastCompletion time.Time `bson:"last_completion"`
+	}
+	if err := cursor.All(ctx, &stats); err != nil {
+		d.logger.Error().Err(err).Msg("解析司機今日統計失敗")
+		return
+	}
+
+	statsByDriver := make(map[string]int, len(stats))
+	for i, s := range stats {
+		statsByDriver[s.DriverID] = i
+	}
+	for _, c := range candidates {
+		i, ok := statsByDriver[c.Driver.ID.Hex()]
+		if !ok {
+			continue
+		}
+		c.TodayIncome = stats[i].Income
+		if stats[i].LastCompletion.After(c.IdleSince) {
+			c.IdleSince = stats[i].LastCompletion
+		}
+	}
+}
+
 // buildOrderInfo 建立要發送給司機的訂單資訊
 func (d *Dispatcher) buildOrderInfo(ctx context.Context, order *model.Order) (*model.OrderInfo, error) {
 	// 預估從上車點到目的地的時間與距離
@@ -797,6 +886,9 @@ func (d *Dispatcher) buildOrderInfo(ctx context.Context, order *model.Order) (*m
 func (d *Dispatcher) dispatchOrderToDrivers(ctx context.Context, order *model.Order, candidates []*model.DriverInfo, distances []string, durationMins []int, crawlerCompletedAt time.Time) (bool, error) {
 	// 僅使用 FCM 推送
 	if d.FcmSvc != nil {
+		if d.policyFor(order.Fleet).IsBroadcast() {
+			return d.sendFcmBroadcast(ctx, order, candidates, distances, durationMins, crawlerCompletedAt)
+		}
 		return d.sendFcm(ctx, order, candidates, distances, durationMins, crawlerCompletedAt)
 	}
 
@@ -893,6 +985,7 @@ func (d *Dispatcher) sendFcm(ctx context.Context, order *model.Order, candidates
 	}
 
 	// 3. 依序發送FCM通知給每位司機
+	callTimeout := d.policyFor(order.Fleet).SequentialCallTimeout()
 	infra.AddEvent(fcmSpan, "starting_sequential_notifications",
 		infra.AttrInt("total_candidates", len(candidates)),
 	)
@@ -966,7 +1059,7 @@ func (d *Dispatcher) sendFcm(ctx context.Context, order *model.Order, candidates
 		// 使用新的原子性檢查並通知司機
 		var driverNotificationRelease func()
 		if d.EventManager != nil {
-			lockTTL := sequentialCallTimeout + 3*time.Second // 比等待時間稍長
+			lockTTL := callTimeout + 3*time.Second // 比等待時間稍長
 			success, reason, atomicErr := d.EventManager.AtomicNotifyDriver(ctx,
 				driver.ID.Hex(),
 				order.ID.Hex(),
@@ -998,108 +1091,16 @@ func (d *Dispatcher) sendFcm(ctx context.Context, order *model.Order, candidates
 			}
 		}
 
-		// 為當前司機客製化預估到達資訊
-		orderInfoForDriver := *baseOrderInfo
-		estPickupMins := durationMins[i]
-
-		// 記錄準備發送 FCM 的時間（發送前記錄）
-		pushTime := time.Now()
-
-		// 動態計算補時：基於 Crawler 完成後到當前 FCM 發送的實際等待時間
-		elapsedSinceCalc := int(pushTime.Sub(crawlerCompletedAt).Seconds())
-		compensationMins := elapsedSinceCalc / 30 // 每30秒補時1分鐘
-		finalEstPickupMins := estPickupMins + compensationMins
-
-		orderInfoForDriver.EstPickUpDist = utils.ParseDistanceToKm(distances[i])
-		orderInfoForDriver.EstPickupMins = finalEstPickupMins
-		// 轉換為台北時間，使用 pushTime 作為基準
-		taipeiLocation := time.FixedZone("Asia/Taipei", 8*3600)
-		orderInfoForDriver.EstPickupTime = pushTime.Add(time.Duration(finalEstPickupMins) * time.Minute).In(taipeiLocation).Format("15:04:05")
-
-		distanceKm := utils.ParseDistanceToKm(distances[i])
-		driverInfo := utils.GetDriverInfo(driver)
-		d.logger.Info().Str("short_id", order.ShortID).Str("ori_text", order.OriText).Int("rank", i+1).Str("driver_info", driverInfo).Int("original_mins", estPickupMins).Int("compensation_mins", compensationMins).Int("final_mins", finalEstPickupMins).Int("elapsed_since_calc_seconds", elapsedSinceCalc).Float64("distance_km", distanceKm).Msg("[調度中心-{short_id}]: ({ori_text}) FCM 第{rank}順位司機: {driver_info} (Crawler完成後經過{elapsed_since_calc_seconds}秒, 原始{original_mins}分鐘+補時{compensation_mins}分鐘=最終{final_mins}分鐘 {distance_km}公里)")
-
-		// 使用統一的推送資料模型
-		pushData := orderInfoForDriver.ToOrderPushData(int(sequentialCallTimeout.Seconds()))
-		pushDataMap := pushData.ToMap()
-
-		// 添加通知類型
-		pushDataMap["notify_order_type"] = string(model.NotifyTypeNewOrder)
-
-		notification := map[string]interface{}{
-			"title": fmt.Sprintf("來自%s的新訂單", string(order.Fleet)),
-			"body":  fmt.Sprintf("%s，預估%d分鐘(%.1f公里)可到達客上地點。", order.OriText, finalEstPickupMins, distanceKm),
-			"sound": "new_order.wav",
-		}
-
-		// 添加FCM發送事件
-		infra.AddEvent(fcmSpan, "sending_fcm_notification",
-			infra.AttrInt("driver_rank", i+1),
-			infra.AttrDriverID(driver.ID.Hex()),
-			infra.AttrString("car_plate", driver.CarPlate),
-			infra.AttrInt("estimated_pickup_mins", finalEstPickupMins),
-			infra.AttrFloat64("distance_km", distanceKm),
-		)
-
-		// 原子性檢查已確保司機和訂單狀態正確，直接發送 FCM
-
-		// 同步發送FCM推送，確保時間準確
-		fcmSendErr := d.FcmSvc.Send(fcmCtx, driver.FcmToken, pushDataMap, notification)
-
-		if fcmSendErr != nil {
-			infra.AddEvent(fcmSpan, "fcm_send_failed",
-				infra.AttrInt("driver_rank", i+1),
-				infra.AttrDriverID(driver.ID.Hex()),
-				infra.AttrString("error", fcmSendErr.Error()),
-			)
-			d.logger.Error().Err(fcmSendErr).
-				Str("short_id", order.ShortID).
-				Str("car_plate", driver.CarPlate).
-				Str("trace_id", span.SpanContext().TraceID().String()).
-				Str("span_id", fcmSpan.SpanContext().SpanID().String()).
-				Msg("調度中心推送通知發送失敗")
+		if pushErr := d.pushOrderToDriver(fcmCtx, fcmSpan, order, baseOrderInfo, driver, i+1, distances[i], durationMins[i], crawlerCompletedAt, callTimeout); pushErr != nil {
 			// FCM 發送失敗時釋放鎖
 			if driverNotificationRelease != nil {
 				driverNotificationRelease()
 			}
 			continue
-		} else {
-			infra.AddEvent(fcmSpan, "fcm_sent_successfully",
-				infra.AttrInt("driver_rank", i+1),
-				infra.AttrDriverID(driver.ID.Hex()),
-				infra.AttrString("car_plate", driver.CarPlate),
-			)
-			// 推送成功後記錄到 Redis（使用發送前記錄的 pushTime）
-			if d.EventManager != nil {
-				d.recordNotifyingOrder(fcmCtx, order, driver, &orderInfoForDriver, pushTime, int(sequentialCallTimeout.Seconds()))
-			}
-
-			// 記錄 FCM 發送到訂單 log
-			taipeiLocation := time.FixedZone("Asia/Taipei", 8*3600)
-			fcmSentTimeUTC8 := pushTime.In(taipeiLocation)
-			logDetails := fmt.Sprintf("FCM發送: %s | 預估: %d分鐘(%.1fkm)",
-				fcmSentTimeUTC8.Format("2006-01-02 15:04:05"),
-				finalEstPickupMins,
-				distanceKm)
-
-			currentRounds := 1
-			if order.Rounds != nil {
-				currentRounds = *order.Rounds
-			}
-
-			if err := d.OrderSvc.AddOrderLog(fcmCtx, order.ID.Hex(), model.OrderLogActionDriverNotified,
-				string(driver.Fleet), driver.Name, driver.CarPlate, driver.ID.Hex(),
-				logDetails, currentRounds); err != nil {
-				d.logger.Error().Err(err).
-					Str("short_id", order.ShortID).
-					Str("driver_id", driver.ID.Hex()).
-					Msg("記錄 FCM 發送日誌失敗")
-			}
 		}
 
 		// 使用事件驅動的等待機制，並傳遞鎖釋放函數
-		accepted, shouldContinue := d.waitForDriverResponseEventDriven(ctx, order, driver)
+		accepted, shouldContinue := d.waitForDriverResponseEventDriven(ctx, order, driver, callTimeout)
 
 		// 等待結束後釋放司機通知鎖
 		if driverNotificationRelease != nil {
@@ -1167,6 +1168,103 @@ func (d *Dispatcher) sendFcm(ctx context.Context, order *model.Order, candidates
 	return false, nil // 未匹配
 }
 
+// pushOrderToDriver 推送新訂單給單一司機，成功後記錄通知中訂單與訂單日誌
+func (d *Dispatcher) pushOrderToDriver(ctx context.Context, fcmSpan trace.Span, order *model.Order, baseOrderInfo *model.OrderInfo, driver *model.DriverInfo, rank int, distance string, estPickupMins int, crawlerCompletedAt time.Time, callTimeout time.Duration) error {
+	// 為當前司機客製化預估到達資訊
+	orderInfoForDriver := *baseOrderInfo
+
+	// 記錄準備發送 FCM 的時間（發送前記錄）
+	pushTime := time.Now()
+
+	// 動態計算補時：基於 Crawler 完成後到當前 FCM 發送的實際等待時間
+	elapsedSinceCalc := int(pushTime.Sub(crawlerCompletedAt).Seconds())
+	compensationMins := elapsedSinceCalc / 30 // 每30秒補時1分鐘
+	finalEstPickupMins := estPickupMins + compensationMins
+
+	orderInfoForDriver.EstPickUpDist = utils.ParseDistanceToKm(distance)
+	orderInfoForDriver.EstPickupMins = finalEstPickupMins
+	// 轉換為台北時間，使用 pushTime 作為基準
+	taipeiLocation := time.FixedZone("Asia/Taipei", 8*3600)
+	orderInfoForDriver.EstPickupTime = pushTime.Add(time.Duration(finalEstPickupMins) * time.Minute).In(taipeiLocation).Format("15:04:05")
+
+	distanceKm := utils.ParseDistanceToKm(distance)
+	driverInfo := utils.GetDriverInfo(driver)
+	d.logger.Info().Str("short_id", order.ShortID).Str("ori_text", order.OriText).Int("rank", rank).Str("driver_info", driverInfo).Int("original_mins", estPickupMins).Int("compensation_mins", compensationMins).Int("final_mins", finalEstPickupMins).Int("elapsed_since_calc_seconds", elapsedSinceCalc).Float64("distance_km", distanceKm).Msg("[調度中心-{short_id}]: ({ori_text}) FCM 第{rank}順位司機: {driver_info} (Crawler完成後經過{elapsed_since_calc_seconds}秒, 原始{original_mins}分鐘+補時{compensation_mins}分鐘=最終{final_mins}分鐘 {distance_km}公里)")
+
+	// 使用統一的推送資料模型
+	pushData := orderInfoForDriver.ToOrderPushData(int(callTimeout.Seconds()))
+	pushDataMap := pushData.ToMap()
+
+	// 添加通知類型
+	pushDataMap["notify_order_type"] = string(model.NotifyTypeNewOrder)
+
+	notification := map[string]interface{}{
+		"title": fmt.Sprintf("來自%s的新訂單", string(order.Fleet)),
+		"body":  fmt.Sprintf("%s，預估%d分鐘(%.1f公里)可到達客上地點。", order.OriText, finalEstPickupMins, distanceKm),
+		"sound": "new_order.wav",
+	}
+
+	// 添加FCM發送事件
+	infra.AddEvent(fcmSpan, "sending_fcm_notification",
+		infra.AttrInt("driver_rank", rank),
+		infra.AttrDriverID(driver.ID.Hex()),
+		infra.AttrString("car_plate", driver.CarPlate),
+		infra.AttrInt("estimated_pickup_mins", finalEstPickupMins),
+		infra.AttrFloat64("distance_km", distanceKm),
+	)
+
+	// 原子性檢查已確保司機和訂單狀態正確，直接發送 FCM
+
+	// 同步發送FCM推送，確保時間準確
+	if fcmSendErr := d.FcmSvc.Send(ctx, driver.FcmToken, pushDataMap, notification); fcmSendErr != nil {
+		infra.AddEvent(fcmSpan, "fcm_send_failed",
+			infra.AttrInt("driver_rank", rank),
+			infra.AttrDriverID(driver.ID.Hex()),
+			infra.AttrString("error", fcmSendErr.Error()),
+		)
+		d.logger.Error().Err(fcmSendErr).
+			Str("short_id", order.ShortID).
+			Str("car_plate", driver.CarPlate).
+			Str("trace_id", fcmSpan.SpanContext().TraceID().String()).
+			Str("span_id", fcmSpan.SpanContext().SpanID().String()).
+			Msg("調度中心推送通知發送失敗")
+		return fcmSendErr
+	}
+
+	infra.AddEvent(fcmSpan, "fcm_sent_successfully",
+		infra.AttrInt("driver_rank", rank),
+		infra.AttrDriverID(driver.ID.Hex()),
+		infra.AttrString("car_plate", driver.CarPlate),
+	)
+	// 推送成功後記錄到 Redis（使用發送前記錄的 pushTime）
+	if d.EventManager != nil {
+		d.recordNotifyingOrder(ctx, order, driver, &orderInfoForDriver, pushTime, int(callTimeout.Seconds()))
+	}
+
+	// 記錄 FCM 發送到訂單 log
+	fcmSentTimeUTC8 := pushTime.In(taipeiLocation)
+	logDetails := fmt.Sprintf("FCM發送: %s | 預估: %d分鐘(%.1fkm)",
+		fcmSentTimeUTC8.Format("2006-01-02 15:04:05"),
+		finalEstPickupMins,
+		distanceKm)
+
+	currentRounds := 1
+	if order.Rounds != nil {
+		currentRounds = *order.Rounds
+	}
+
+	if err := d.OrderSvc.AddOrderLog(ctx, order.ID.Hex(), model.OrderLogActionDriverNotified,
+		string(driver.Fleet), driver.Name, driver.CarPlate, driver.ID.Hex(),
+		logDetails, currentRounds); err != nil {
+		d.logger.Error().Err(err).
+			Str("short_id", order.ShortID).
+			Str("driver_id", driver.ID.Hex()).
+			Msg("記錄 FCM 發送日誌失敗")
+	}
+
+	return nil
+}
+
 func (d *Dispatcher) failOrder(ctx context.Context, orderID primitive.ObjectID, reason string) error {
 	// 獲取當前 span
 	span := trace.SpanFromContext(ctx)
@@ -1200,8 +1298,12 @@ func (d *Dispatcher) failOrder(ctx context.Context, orderID primitive.ObjectID,
 	}
 
 	infra.AddEvent(failSpan, "updating_order_status_to_failed")
-	if err := d.OrderSvc.FailOrder(failCtx, orderID, reason); err != nil {
-		shortID := order.ShortID
+	failed, err := d.OrderSvc.FailOrder(failCtx, orderID, reason)
+	if err != nil {
+		shortID := ""
+		if order != nil {
+			shortID = order.ShortID
+		}
 		infra.RecordError(failSpan, err, "Update order status failed",
 			infra.AttrString("error", err.Error()),
 		)
@@ -1214,6 +1316,16 @@ func (d *Dispatcher) failOrder(ctx context.Context, orderID primitive.ObjectID,
 		return err
 	}
 
+	// 訂單已被接單或取消時不是流單，不發送流單通知
+	if !failed {
+		infra.AddEvent(failSpan, "order_not_in_waiting_status")
+		d.logger.Info().
+			Str("order_id", orderID.Hex()).
+			Str("reason", reason).
+			Msg("調度中心訂單已不在等待接單狀態，略過流單通知")
+		return nil
+	}
+
 	// 添加成功事件
 	infra.AddEvent(failSpan, "order_failed_successfully",
 		infra.AttrString("reason", reason),
@@ -1242,18 +1354,18 @@ func (d *Dispatcher) failOrder(ctx context.Context, orderID primitive.ObjectID,
 }
 
 // 新增：事件驅動的司機回應等待機制
-func (d *Dispatcher) waitForDriverResponseEventDriven(ctx context.Context, order *model.Order, driver *model.DriverInfo) (accepted bool, shouldContinue bool) {
+func (d *Dispatcher) waitForDriverResponseEventDriven(ctx context.Context, order *model.Order, driver *model.DriverInfo, callTimeout time.Duration) (accepted bool, shouldContinue bool) {
 	orderID := order.ID.Hex()
 	driverID := driver.ID.Hex()
 
 	// 檢查事件管理器是否可用
 	if d.EventManager == nil {
 		d.logger.Error().Str("short_id", order.ShortID).Msg("事件管理器未初始化，降級為傳統等待模式")
-		return d.waitForDriverResponseTraditional(ctx, order, driver)
+		return d.waitForDriverResponseTraditional(ctx, order, driver, callTimeout)
 	}
 
 	// 1. 獲取調度鎖，確保調度狀態權威
-	lockTTL := sequentialCallTimeout + 10*time.Second
+	lockTTL := callTimeout + 10*time.Second
 	lockAcquired, lockValue, releaseLock, err := d.EventManager.AcquireDispatchLock(ctx, orderID, d.dispatcherID, lockTTL)
 	if err != nil {
 		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度鎖獲取失敗")
@@ -1291,7 +1403,7 @@ func (d *Dispatcher) waitForDriverResponseEventDriven(ctx context.Context, order
 	statusCheckTicker := time.NewTicker(1 * time.Second) // 每1秒檢查一次訂單狀態
 	defer statusCheckTicker.Stop()
 
-	timeoutTimer := time.NewTimer(sequentialCallTimeout)
+	timeoutTimer := time.NewTimer(callTimeout)
 	defer timeoutTimer.Stop()
 
 	lockExtendTicker := time.NewTicker(5 * time.Second) // 每5秒延長鎖
@@ -1301,7 +1413,7 @@ func (d *Dispatcher) waitForDriverResponseEventDriven(ctx context.Context, order
 	d.logger.Info().
 		Str("short_id", order.ShortID).
 		Str("ori_text", order.OriText).
-		Dur("timeout", sequentialCallTimeout).
+		Dur("timeout", callTimeout).
 		Str("driver_info", driverInfo).
 		Msg("[調度中心-{short_id}]: ({ori_text}) 開始事件驅動等待司機回應: {driver_info}")
 
@@ -1464,14 +1576,14 @@ func (d *Dispatcher) waitForDriverResponseEventDriven(ctx context.Context, order
 }
 
 // 傳統等待模式作為降級方案
-func (d *Dispatcher) waitForDriverResponseTraditional(ctx context.Context, order *model.Order, driver *model.DriverInfo) (accepted bool, shouldContinue bool) {
+func (d *Dispatcher) waitForDriverResponseTraditional(ctx context.Context, order *model.Order, driver *model.DriverInfo, callTimeout time.Duration) (accepted bool, shouldContinue bool) {
 	d.logger.Info().
 		Str("short_id", order.ShortID).
 		Str("car_plate", driver.CarPlate).
 		Msg("⬇️ 使用傳統等待模式")
 
 	// 等待這位司機回應
-	time.Sleep(sequentialCallTimeout)
+	time.Sleep(callTimeout)
 
 	// 檢查司機是否接受了訂單
 	finalOrder, err := d.OrderSvc.GetOrderByID(ctx, order.ID.Hex())
@@ -1712,7 +1824,7 @@ func (d *Dispatcher) recordNotifyingOrder(ctx context.Context, order *model.Orde
 		return
 	}
 
-	// 設定 TTL 為 sequentialCallTimeout + 5秒緩衝
+	// 設定 TTL 為司機等待時間 + 5秒緩衝
 	ttl := time.Duration(timeoutSeconds+5) * time.Second
 	cacheErr := d.EventManager.SetCache(ctx, notifyingOrderKey, string(notifyingOrderJSON), ttl)
 	if cacheErr != nil {
@@ -0,0 +1,19 @@
+package background
+
+import (
+	"right-backend/model"
+	"right-backend/service"
+)
+
+// SetFleetService 設定車隊登錄服務，設定後跨車隊派單規則與車隊設定以 fleets 集合為準
+func (d *Dispatcher) SetFleetService(fleetSvc *service.FleetService) {
+	d.FleetSvc = fleetSvc
+}
+
+// fleetFor 取得訂單車隊的登錄資料，未設定車隊登錄服務時使用預設車隊
+func (d *Dispatcher) fleetFor(code model.FleetType) *model.Fleet {
+	if d.FleetSvc == nil {
+		return model.DefaultFleetFor(code)
+	}
+	return d.FleetSvc.FleetOrUnregistered(code)
+}
@@ -0,0 +1,198 @@
+package background
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	orderRetryHeader        = "x-retry-count" // 訂單訊息已重試次數
+	defaultOrderWorkers     = 10
+	defaultOrderMaxRetries  = 3
+	orderRetryBackoffPerTry = 2 * time.Second // 每次重試前的等待時間（依重試次數遞增）
+)
+
+// orderConsumerSettings 讀取訂單消費者設定，未設定時使用預設值
+func orderConsumerSettings() (workers, prefetch, maxRetries int) {
+	cfg := infra.AppConfig.Dispatcher
+	workers = cfg.Workers
+	if workers <= 0 {
+		workers = defaultOrderWorkers
+	}
+	prefetch = cfg.Prefetch
+	if prefetch <= 0 {
+		prefetch = workers
+	}
+	maxRetries = cfg.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultOrderMaxRetries
+	}
+	return workers, prefetch, maxRetries
+}
+
+// processOrderDelivery 處理單一訂單訊息：解析、派單，並依結果 ack、重試或移入死信隊列
+func (d *Dispatcher) processOrderDelivery(ctx context.Context, msg amqp.Delivery, maxRetries int) {
+	retries := orderRetryCount(msg.Headers)
+
+	var order model.Order
+	if err := json.Unmarshal(msg.Body, &order); err != nil || order.ID == nil {
+		if err == nil {
+			err = errors.New("訂單缺少ID")
+		}
+		d.logger.Error().Err(err).Msg("調度中心訂單資料解析失敗")
+		// 解析失敗重試也不會成功，直接移入死信隊列
+		d.deadLetterOrder(ctx, msg, nil, "訂單資料解析失敗", err, retries+1)
+		return
+	}
+
+	// 重新投遞、重試或重複發布的訊息可能已被處理過，每次派單前都以資料庫狀態為準
+	currentOrder, err := d.OrderSvc.GetOrderByID(ctx, order.ID.Hex())
+	if err != nil {
+		d.retryOrDeadLetter(ctx, msg, &order, retries, maxRetries, fmt.Errorf("查詢訂單狀態失敗: %w", err))
+		return
+	}
+	if currentOrder.Status != model.OrderStatusWaiting {
+		d.logger.Info().
+			Str("short_id", order.ShortID).
+			Str("status", string(currentOrder.Status)).
+			Bool("redelivered", msg.Redelivered).
+			Int("retries", retries).
+			Msg("調度中心訂單已不在等待接單狀態，略過")
+		d.ackDelivery(msg, &order)
+		return
+	}
+	if isSupersededOrderMessage(&order, currentOrder) {
+		d.logger.Info().
+			Str("short_id", order.ShortID).
+			Msg("調度中心訂單已重新排入隊列，略過舊的訊息")
+		d.ackDelivery(msg, &order)
+		return
+	}
+
+	if err := d.handleOrderSafely(ctx, &order); err != nil {
+		d.retryOrDeadLetter(ctx, msg, &order, retries, maxRetries, err)
+		return
+	}
+	d.ackDelivery(msg, &order)
+}
+
+// isSupersededOrderMessage 訂單在此訊息發布後又重新排入隊列（例如中斷訂單恢復），舊訊息交由新的訊息處理
+func isSupersededOrderMessage(message, current *model.Order) bool {
+	if current.EnqueuedAt == nil {
+		return false
+	}
+	return message.EnqueuedAt == nil || message.EnqueuedAt.Before(*current.EnqueuedAt)
+}
+
+// handleOrderSafely 執行派單流程，並將 panic 轉為錯誤，避免單一訂單拖垮整個 worker
+func (d *Dispatcher) handleOrderSafely(ctx context.Context, order *model.Order) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("派單流程 panic: %v", r)
+		}
+	}()
+	return d.handleOrder(ctx, order)
+}
+
+// retryOrDeadLetter 未超過重試次數時帶重試計數重新發布，否則流單並移入死信隊列
+func (d *Dispatcher) retryOrDeadLetter(ctx context.Context, msg amqp.Delivery, order *model.Order, retries, maxRetries int, cause error) {
+	if retries < maxRetries {
+		backoff := time.Duration(retries+1) * orderRetryBackoffPerTry
+		d.logger.Warn().Err(cause).
+			Str("short_id", order.ShortID).
+			Int("retry", retries+1).
+			Int("max_retries", maxRetries).
+			Dur("backoff", backoff).
+			Msg("調度中心派單失敗，稍後重試")
+
+		select {
+		case <-ctx.Done():
+			_ = msg.Nack(false, true)
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := d.RabbitMQ.PublishMessageWithHeaders(infra.QueueNameOrders.String(), msg.Body, orderRetryHeaders(retries+1)); err != nil {
+			d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度中心重新發布訂單失敗，退回隊列")
+			_ = msg.Nack(false, true)
+			return
+		}
+		d.ackDelivery(msg, order)
+		return
+	}
+
+	d.logger.Error().Err(cause).
+		Str("short_id", order.ShortID).
+		Int("retries", retries).
+		Msg("調度中心派單重試次數已用盡，流單並移入死信隊列")
+	if err := d.failOrder(ctx, *order.ID, "尋找司機過程出錯"); err != nil {
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度中心更新訂單為失敗狀態時出錯")
+	}
+	d.deadLetterOrder(ctx, msg, order, "派單失敗", cause, retries+1)
+}
+
+// deadLetterOrder 寫入死信隊列後 ack 原訊息；寫入失敗時退回隊列，避免訊息遺失
+func (d *Dispatcher) deadLetterOrder(ctx context.Context, msg amqp.Delivery, order *model.Order, reason string, cause error, attempts int) {
+	if d.DeadLetterSvc == nil {
+		d.logger.Error().Err(cause).Str("reason", reason).Str("body", string(msg.Body)).Msg("死信隊列服務未初始化，訊息直接丟棄")
+		d.ackDelivery(msg, order)
+		return
+	}
+
+	letter := &model.DeadLetterOrder{
+		Queue:    infra.QueueNameOrders.String(),
+		Body:     string(msg.Body),
+		Reason:   reason,
+		Attempts: attempts,
+	}
+	if cause != nil {
+		letter.Error = cause.Error()
+	}
+	if order != nil && order.ID != nil {
+		letter.OrderID = order.ID.Hex()
+		letter.ShortID = order.ShortID
+	}
+
+	if err := d.DeadLetterSvc.Record(ctx, letter); err != nil {
+		_ = msg.Nack(false, true)
+		return
+	}
+	d.ackDelivery(msg, order)
+}
+
+// ackDelivery 確認訊息已處理完成
+func (d *Dispatcher) ackDelivery(msg amqp.Delivery, order *model.Order) {
+	if err := msg.Ack(false); err != nil {
+		logEvent := d.logger.Error().Err(err)
+		if order != nil {
+			logEvent = logEvent.Str("short_id", order.ShortID)
+		}
+		logEvent.Msg("調度中心訂單訊息 ack 失敗")
+	}
+}
+
+// orderRetryHeaders 重新發布訂單時帶上的重試計數標頭
+func orderRetryHeaders(retries int) amqp.Table {
+	return amqp.Table{orderRetryHeader: int32(retries)}
+}
+
+// orderRetryCount 從訊息標頭讀取已重試次數
+func orderRetryCount(headers amqp.Table) int {
+	switch v := headers[orderRetryHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
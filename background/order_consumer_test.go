@@ -0,0 +1,200 @@
+package background
+
+import (
+	"context"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/service"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// fakeAcknowledger 記錄訊息被 ack 或 nack 的結果
+type fakeAcknowledger struct {
+	acked   bool
+	nacked  bool
+	requeue bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.nacked = true
+	a.requeue = requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func newTestDelivery(body string, headers amqp.Table) (amqp.Delivery, *fakeAcknowledger) {
+	ack := &fakeAcknowledger{}
+	return amqp.Delivery{Acknowledger: ack, Body: []byte(body), Headers: headers}, ack
+}
+
+func TestOrderRetryCount(t *testing.T) {
+	testCases := []struct {
+		name    string
+		headers amqp.Table
+		want    int
+	}{
+		{name: "無標頭", headers: nil, want: 0},
+		{name: "未帶重試標頭", headers: amqp.Table{"other": int32(5)}, want: 0},
+		{name: "int32", headers: amqp.Table{orderRetryHeader: int32(2)}, want: 2},
+		{name: "int64", headers: amqp.Table{orderRetryHeader: int64(3)}, want: 3},
+		{name: "int", headers: amqp.Table{orderRetryHeader: 1}, want: 1},
+		{name: "無法識別的型別", headers: amqp.Table{orderRetryHeader: "2"}, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := orderRetryCount(tc.headers); got != tc.want {
+				t.Fatalf("預期重試次數為 %d，實際為 %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOrderRetryHeadersRoundTrip(t *testing.T) {
+	for retries := 1; retries <= defaultOrderMaxRetries; retries++ {
+		headers := orderRetryHeaders(retries)
+		// 標頭需為 AMQP 支援的型別，否則重新發布會失敗
+		if err := headers.Validate(); err != nil {
+			t.Fatalf("重試標頭不符合 AMQP 格式: %v", err)
+		}
+		if got := orderRetryCount(headers); got != retries {
+			t.Fatalf("預期讀回重試次數 %d，實際為 %d", retries, got)
+		}
+	}
+}
+
+func TestInvalidOrderMessageWithoutDeadLetterService(t *testing.T) {
+	d := &Dispatcher{logger: zerolog.Nop()}
+	msg, ack := newTestDelivery("not-json", nil)
+
+	d.processOrderDelivery(context.Background(), msg, defaultOrderMaxRetries)
+
+	if !ack.acked || ack.nacked {
+		t.Fatalf("死信隊列未初始化時預期直接 ack，實際 acked=%v nacked=%v", ack.acked, ack.nacked)
+	}
+}
+
+func TestInvalidOrderMessageDeadLettered(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("寫入死信隊列後 ack 並記錄嘗試次數", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		d := newDeadLetterTestDispatcher(mt)
+		msg, ack := newTestDelivery(`{"short_id":"A001"}`, orderRetryHeaders(2))
+
+		d.processOrderDelivery(context.Background(), msg, defaultOrderMaxRetries)
+
+		if !ack.acked || ack.nacked {
+			t.Fatalf("預期寫入死信隊列後 ack，實際 acked=%v nacked=%v", ack.acked, ack.nacked)
+		}
+		letter := insertedDocument(mt)
+		if attempts, _ := letter.Lookup("attempts").AsInt64OK(); attempts != 3 {
+			t.Fatalf("已重試 2 次的訊息預期記錄嘗試次數 3，實際為 %d", attempts)
+		}
+		if reason := letter.Lookup("reason").StringValue(); reason != "訂單資料解析失敗" {
+			t.Fatalf("預期死信原因為訂單資料解析失敗，實際為 %s", reason)
+		}
+	})
+
+	mt.Run("寫入死信隊列失敗時退回隊列", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"}))
+		d := newDeadLetterTestDispatcher(mt)
+		msg, ack := newTestDelivery("not-json", nil)
+
+		d.processOrderDelivery(context.Background(), msg, defaultOrderMaxRetries)
+
+		if ack.acked || !ack.nacked || !ack.requeue {
+			t.Fatalf("預期退回隊列，實際 acked=%v nacked=%v requeue=%v", ack.acked, ack.nacked, ack.requeue)
+		}
+	})
+}
+
+func newDeadLetterTestDispatcher(mt *mtest.T) *Dispatcher {
+	mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+	return &Dispatcher{
+		logger:        zerolog.Nop(),
+		DeadLetterSvc: service.NewDeadLetterService(zerolog.Nop(), mongoDB, nil, nil),
+	}
+}
+
+// insertedDocument 取出最後一次 insert 指令寫入的文件
+func insertedDocument(mt *mtest.T) bson.Raw {
+	mt.Helper()
+	started := mt.GetStartedEvent()
+	if started == nil || started.CommandName != "insert" {
+		mt.Fatal("預期寫入死信隊列")
+	}
+	docs, err := started.Command.Lookup("documents").Array().Values()
+	if err != nil || len(docs) == 0 {
+		mt.Fatalf("無法讀取寫入的文件: %v", err)
+	}
+	return docs[0].Document()
+}
+
+func TestOrderMessageSkippedWhenNotWaiting(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("首次投遞但訂單已被接單時直接 ack", func(mt *mtest.T) {
+		orderID := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "right_db.orders", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: orderID},
+			{Key: "short_id", Value: "A001"},
+			{Key: "status", Value: string(model.OrderStatusEnroute)},
+		}))
+		mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+		d := &Dispatcher{logger: zerolog.Nop(), OrderSvc: service.NewOrderService(zerolog.Nop(), mongoDB, nil, nil, nil, nil)}
+		msg, ack := newTestDelivery(`{"_id":"`+orderID.Hex()+`","short_id":"A001"}`, nil)
+
+		d.processOrderDelivery(context.Background(), msg, defaultOrderMaxRetries)
+
+		if !ack.acked || ack.nacked {
+			t.Fatalf("預期略過並 ack，實際 acked=%v nacked=%v", ack.acked, ack.nacked)
+		}
+		if started := mt.GetStartedEvent(); started == nil || started.CommandName != "find" {
+			t.Fatal("預期派單前查詢訂單狀態")
+		}
+		if started := mt.GetStartedEvent(); started != nil {
+			t.Fatalf("訂單不在等待接單狀態時不應派單，實際執行了 %s", started.CommandName)
+		}
+	})
+
+	mt.Run("訂單已重新排入隊列時略過舊訊息", func(mt *mtest.T) {
+		orderID := primitive.NewObjectID()
+		requeuedAt := time.Now().UTC().Truncate(time.Millisecond)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "right_db.orders", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: orderID},
+			{Key: "short_id", Value: "A002"},
+			{Key: "status", Value: string(model.OrderStatusWaiting)},
+			{Key: "enqueued_at", Value: requeuedAt},
+		}))
+		mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+		d := &Dispatcher{logger: zerolog.Nop(), OrderSvc: service.NewOrderService(zerolog.Nop(), mongoDB, nil, nil, nil, nil)}
+		previous := requeuedAt.Add(-10 * time.Minute).Format(time.RFC3339Nano)
+		msg, ack := newTestDelivery(`{"_id":"`+orderID.Hex()+`","short_id":"A002","enqueued_at":"`+previous+`"}`, nil)
+
+		d.processOrderDelivery(context.Background(), msg, defaultOrderMaxRetries)
+
+		if !ack.acked || ack.nacked {
+			t.Fatalf("預期略過並 ack，實際 acked=%v nacked=%v", ack.acked, ack.nacked)
+		}
+		mt.GetStartedEvent()
+		if started := mt.GetStartedEvent(); started != nil {
+			t.Fatalf("舊訊息不應派單，實際執行了 %s", started.CommandName)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package background
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"time"
+
+	driverModels "right-backend/data-models/driver"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultRecoveryInterval    = 60 * time.Second
+	defaultRecoveryMaxOrderAge = 30 * time.Minute
+	defaultRecoveryQueuedGrace = 10 * time.Minute
+	recoveryStaleGrace         = 2 * time.Minute  // 超過最長派單時間後再多等待的緩衝
+	recoveryLockTTL            = 30 * time.Second // 恢復處理期間持有的調度鎖存活時間
+	recoveryBatchSize          = 100
+)
+
+// recoverySettings 讀取中斷訂單恢復設定，未設定時使用預設值
+func recoverySettings() (interval, maxOrderAge time.Duration) {
+	cfg := infra.AppConfig.Dispatcher
+	interval = time.Duration(cfg.RecoveryIntervalSecs) * time.Second
+	if interval <= 0 {
+		interval = defaultRecoveryInterval
+	}
+	maxOrderAge = time.Duration(cfg.RecoveryMaxOrderAgeMins) * time.Minute
+	if maxOrderAge <= 0 {
+		maxOrderAge = defaultRecoveryMaxOrderAge
+	}
+	return interval, maxOrderAge
+}
+
+// recoveryQueuedGrace 排入隊列後尚未開始派單的訂單可能仍在隊列中排隊，超過此時間才視為中斷
+func recoveryQueuedGrace() time.Duration {
+	grace := time.Duration(infra.AppConfig.Dispatcher.RecoveryQueuedGraceMins) * time.Minute
+	if grace <= 0 {
+		return defaultRecoveryQueuedGrace
+	}
+	return grace
+}
+
+// runRecoverySweeper 調度中心啟動時立即掃描一次中斷訂單，之後定期掃描
+func (d *Dispatcher) runRecoverySweeper(ctx context.Context) {
+	interval, maxOrderAge := recoverySettings()
+	d.logger.Info().Dur("interval", interval).Dur("max_order_age", maxOrderAge).Msg("調度中心中斷訂單恢復掃描已啟動")
+
+	d.recoverStuckOrders(ctx, maxOrderAge)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.recoverStuckOrders(ctx, maxOrderAge)
+		}
+	}
+}
+
+// recoverStuckOrders 找出等待接單且已超過最長派單時間未更新、也沒有調度鎖的即時單，重新排入隊列或流單
+func (d *Dispatcher) recoverStuckOrders(ctx context.Context, maxOrderAge time.Duration) {
+	now := time.Now()
+	filter := bson.M{
+		"status":     model.OrderStatusWaiting,
+		"type":       model.OrderTypeInstant,
+		"updated_at": bson.M{"$lt": now.Add(-recoveryStaleGrace)},
+	}
+	opts := options.Find().SetSort(bson.M{"updated_at": 1}).SetLimit(recoveryBatchSize)
+
+	cursor, err := d.MongoDB.GetCollection("orders").Find(ctx, filter, opts)
+	if err != nil {
+		d.logger.Error().Err(err).Msg("調度中心查詢中斷訂單失敗")
+		return
+	}
+	var orders []*model.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		d.logger.Error().Err(err).Msg("調度中心解析中斷訂單失敗")
+		return
+	}
+
+	recovered := 0
+	for _, order := range orders {
+		if d.isOrderStale(order, now) && d.recoverStuckOrder(ctx, order, maxOrderAge) {
+			recovered++
+		}
+	}
+	if recovered > 0 {
+		d.logger.Warn().Int("recovered", recovered).Msg("調度中心已處理中斷訂單")
+	}
+}
+
+// isOrderStale 訂單最後更新時間是否已超過該車隊完整派單所需的最長時間；
+// 排入隊列後尚未開始派單（每輪開始都會更新 updated_at）的訂單改以排入時間與隊列等待緩衝判斷
+func (d *Dispatcher) isOrderStale(order *model.Order, now time.Time) bool {
+	if order.EnqueuedAt != nil && (order.UpdatedAt == nil || !order.EnqueuedAt.Before(*order.UpdatedAt)) {
+		return now.Sub(*order.EnqueuedAt) > recoveryQueuedGrace()
+	}
+	if order.UpdatedAt == nil {
+		return true
+	}
+	staleAfter := d.policyFor(order.Fleet).MaxDispatchDuration() + recoveryStaleGrace
+	return now.Sub(*order.UpdatedAt) > staleAfter
+}
+
+// recoverStuckOrder 取得調度鎖後再次確認訂單狀態，清除殘留的通知狀態，依訂單建立時間重新排入隊列或流單
+func (d *Dispatcher) recoverStuckOrder(ctx context.Context, order *model.Order, maxOrderAge time.Duration) bool {
+	orderID := order.ID.Hex()
+
+	// 仍有調度鎖表示其他調度中心正在派單，不處理
+	if d.EventManager != nil {
+		lockAcquired, _, releaseLock, err := d.EventManager.AcquireDispatchLock(ctx, orderID, d.dispatcherID, recoveryLockTTL)
+		if err != nil || !lockAcquired {
+			return false
+		}
+		defer releaseLock()
+	}
+
+	// 取得鎖後再次確認，避免與其他調度中心重複處理
+	currentOrder, err := d.OrderSvc.GetOrderByID(ctx, orderID)
+	if err != nil || currentOrder.Status != model.OrderStatusWaiting || !d.isOrderStale(currentOrder, time.Now()) {
+		return false
+	}
+
+	d.clearDanglingNotifications(ctx, currentOrder)
+
+	rounds := 1
+	if currentOrder.Rounds != nil {
+		rounds = *currentOrder.Rounds
+	}
+
+	orderAge := time.Duration(0)
+	if currentOrder.CreatedAt != nil {
+		orderAge = time.Since(*currentOrder.CreatedAt)
+	}
+
+	if orderAge > maxOrderAge {
+		details := fmt.Sprintf("派單中斷且訂單已建立%d分鐘，超過%d分鐘上限，自動流單", int(orderAge.Minutes()), int(maxOrderAge.Minutes()))
+		if err := d.OrderSvc.AddOrderLog(ctx, orderID, model.OrderLogActionDispatchResume,
+			string(currentOrder.Fleet), "", "", "", details, rounds); err != nil {
+			d.logger.Error().Err(err).Str("short_id", currentOrder.ShortID).Msg("記錄派單恢復日誌失敗")
+		}
+		if err := d.failOrder(ctx, *currentOrder.ID, "派單中斷逾時"); err != nil {
+			return false
+		}
+		d.logger.Warn().
+			Str("short_id", currentOrder.ShortID).
+			Str("ori_text", currentOrder.OriText).
+			Dur("order_age", orderAge).
+			Msg("[調度中心-{short_id}]: ({ori_text}) 派單中斷逾時，已流單")
+		return true
+	}
+
+	// 先寫入日誌，重新排入時記錄排入時間，避免下一次掃描在訂單仍於隊列中排隊時重複排入
+	details := fmt.Sprintf("派單中斷（最後更新於%s），重新排入派單隊列", currentOrder.UpdatedAt.In(time.FixedZone("Asia/Taipei", 8*3600)).Format("15:04:05"))
+	if err := d.OrderSvc.AddOrderLog(ctx, orderID, model.OrderLogActionDispatchResume,
+		string(currentOrder.Fleet), "", "", "", details, rounds); err != nil {
+		d.logger.Error().Err(err).Str("short_id", currentOrder.ShortID).Msg("記錄派單恢復日誌失敗")
+	}
+	// 持有調度鎖時重新讀取訂單狀態，確認仍在等待接單才重新排入；發布後才釋放調度鎖
+	currentOrder, err = d.OrderSvc.GetOrderByID(ctx, orderID)
+	if err != nil || currentOrder.Status != model.OrderStatusWaiting {
+		return false
+	}
+	if err := d.OrderSvc.RequeueOrder(currentOrder); err != nil {
+		d.logger.Error().Err(err).Str("short_id", currentOrder.ShortID).Msg("調度中心重新排入中斷訂單失敗")
+		return false
+	}
+	d.logger.Warn().
+		Str("short_id", currentOrder.ShortID).
+		Str("ori_text", currentOrder.OriText).
+		Msg("[調度中心-{short_id}]: ({ori_text}) 派單中斷，已重新排入派單隊列")
+	return true
+}
+
+// clearDanglingNotifications 清除曾通知過此訂單的司機殘留的 notifying_order 狀態
+func (d *Dispatcher) clearDanglingNotifications(ctx context.Context, order *model.Order) {
+	if d.EventManager == nil {
+		return
+	}
+
+	orderID := order.ID.Hex()
+	cleared := make(map[string]bool)
+	for _, logEntry := range order.Logs {
+		if logEntry.Action != model.OrderLogActionDriverNotified || logEntry.DriverID == "" || cleared[logEntry.DriverID] {
+			continue
+		}
+		cleared[logEntry.DriverID] = true
+
+		notifyingOrderKey := fmt.Sprintf("notifying_order:%s", logEntry.DriverID)
+		cachedData, err := d.EventManager.GetCache(ctx, notifyingOrderKey)
+		if err != nil || cachedData == "" {
+			continue
+		}
+		var redisNotifyingOrder driverModels.RedisNotifyingOrder
+		if err := json.Unmarshal([]byte(cachedData), &redisNotifyingOrder); err != nil || redisNotifyingOrder.OrderID != orderID {
+			continue
+		}
+		if err := d.EventManager.DeleteCache(ctx, notifyingOrderKey); err != nil {
+			d.logger.Error().Err(err).Str("driver_id", logEntry.DriverID).Msg("清除殘留的通知中訂單失敗")
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package background
+
+import (
+	"right-backend/model"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestIsOrderStale(t *testing.T) {
+	d := &Dispatcher{logger: zerolog.Nop()}
+	now := time.Now()
+	maxDispatch := d.policyFor(model.FleetTypeRSK).MaxDispatchDuration()
+	at := func(ago time.Duration) *time.Time {
+		ts := now.Add(-ago)
+		return &ts
+	}
+
+	testCases := []struct {
+		name       string
+		updatedAt  *time.Time
+		enqueuedAt *time.Time
+		want       bool
+	}{
+		{name: "沒有更新時間", want: true},
+		{name: "派單中仍在最長派單時間內", updatedAt: at(time.Minute), want: false},
+		{name: "派單中超過最長派單時間", updatedAt: at(maxDispatch + recoveryStaleGrace + time.Second), want: true},
+		{name: "排入隊列後仍在隊列等待緩衝內", updatedAt: at(maxDispatch + recoveryStaleGrace + time.Minute), enqueuedAt: at(maxDispatch + recoveryStaleGrace + time.Minute), want: false},
+		{name: "排入隊列後超過隊列等待緩衝", updatedAt: at(defaultRecoveryQueuedGrace + time.Minute), enqueuedAt: at(defaultRecoveryQueuedGrace + time.Second), want: true},
+		{name: "排入隊列後已開始派單改依更新時間判斷", updatedAt: at(time.Minute), enqueuedAt: at(defaultRecoveryQueuedGrace + time.Minute), want: false},
+		{name: "開始派單後中斷", updatedAt: at(maxDispatch + recoveryStaleGrace + time.Second), enqueuedAt: at(maxDispatch + recoveryStaleGrace + time.Minute), want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order := &model.Order{Fleet: model.FleetTypeRSK, UpdatedAt: tc.updatedAt, EnqueuedAt: tc.enqueuedAt}
+			if got := d.isOrderStale(order, now); got != tc.want {
+				t.Fatalf("預期 %v，實際為 %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIsSupersededOrderMessage(t *testing.T) {
+	earlier := time.Now().Add(-time.Minute).UTC().Truncate(time.Millisecond)
+	later := earlier.Add(30 * time.Second)
+
+	testCases := []struct {
+		name    string
+		message *time.Time
+		current *time.Time
+		want    bool
+	}{
+		{name: "都沒有排入時間", want: false},
+		{name: "同一次排入", message: &earlier, current: &earlier, want: false},
+		{name: "訊息排入後訂單又重新排入", message: &earlier, current: &later, want: true},
+		{name: "沒有排入時間的舊訊息已被重新排入取代", message: nil, current: &later, want: true},
+		{name: "訊息較新", message: &later, current: &earlier, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message := &model.Order{EnqueuedAt: tc.message}
+			current := &model.Order{EnqueuedAt: tc.current}
+			if got := isSupersededOrderMessage(message, current); got != tc.want {
+				t.Fatalf("預期 %v，實際為 %v", tc.want, got)
+			}
+		})
+	}
+}
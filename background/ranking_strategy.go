@@ -0,0 +1,149 @@
+package background
+
+import (
+	"fmt"
+	"math"
+	"right-backend/infra"
+	"right-backend/model"
+	"sort"
+	"time"
+)
+
+// 司機排序策略名稱
+const (
+	RankingStrategyNearestETA     = "nearest_eta"     // 依真實路徑預估時間排序（預設）
+	RankingStrategyFairness       = "fairness"        // 兼顧閒置時間與今日收入的公平派單
+	RankingStrategyAcceptanceRate = "acceptance_rate" // 兼顧司機歷史接單率
+)
+
+// RankingCandidate 排序用的候選司機快照
+type RankingCandidate struct {
+	Driver      *model.DriverInfo
+	HaversineKm float64   // 直線距離（公里）
+	EtaMins     int       // 真實路徑預估到達時間（分鐘）
+	DistanceKm  float64   // 真實路徑距離（公里）
+	DistanceStr string    // 真實路徑距離字串（原始格式）
+	IdleSince   time.Time // 開始閒置的時間（零值表示未知）
+	TodayIncome int       // 今日已完成訂單收入
+}
+
+// RankingStrategy 候選司機排序策略
+type RankingStrategy interface {
+	// Name 策略名稱
+	Name() string
+	// NeedsDriverStats 是否需要閒置時間、今日收入等統計資料
+	NeedsDriverStats() bool
+	// Rank 回傳排序後的候選司機（不修改傳入的切片）
+	Rank(order *model.Order, candidates []*RankingCandidate, now time.Time) []*RankingCandidate
+}
+
+// NewRankingStrategy 依名稱建立排序策略
+func NewRankingStrategy(name string) (RankingStrategy, error) {
+	switch name {
+	case "", RankingStrategyNearestETA:
+		return &NearestETAStrategy{}, nil
+	case RankingStrategyFairness:
+		return &FairnessStrategy{IdleBonusPerHour: 6, MaxIdleBonusMins: 10, IncomePenaltyPer1K: 2}, nil
+	case RankingStrategyAcceptanceRate:
+		return &AcceptanceRateStrategy{RejectPenaltyMins: 5}, nil
+	default:
+		return nil, fmt.Errorf("未知的司機排序策略: %s", name)
+	}
+}
+
+// rankingStrategyFor 取得車隊的排序策略：優先使用派單策略設定，其次為 config.yml，皆未設定或設定錯誤時使用 nearest_eta
+func (d *Dispatcher) rankingStrategyFor(policy *model.DispatchPolicy) RankingStrategy {
+	name := policy.RankingStrategy
+	if name == "" {
+		name = infra.AppConfig.Dispatcher.RankingStrategies[string(policy.Fleet)]
+	}
+	strategy, err := NewRankingStrategy(name)
+	if err != nil {
+		d.logger.Warn().Err(err).Str("fleet", string(policy.Fleet)).Msg("車隊排序策略設定錯誤，改用預設策略")
+		return &NearestETAStrategy{}
+	}
+	return strategy
+}
+
+// NearestETAStrategy 依真實路徑預估時間排序，時間相同時以直線距離排序
+type NearestETAStrategy struct{}
+
+func (s *NearestETAStrategy) Name() string           { return RankingStrategyNearestETA }
+func (s *NearestETAStrategy) NeedsDriverStats() bool { return false }
+
+func (s *NearestETAStrategy) Rank(order *model.Order, candidates []*RankingCandidate, now time.Time) []*RankingCandidate {
+	ranked := append([]*RankingCandidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].EtaMins != ranked[j].EtaMins {
+			return ranked[i].EtaMins < ranked[j].EtaMins
+		}
+		return ranked[i].HaversineKm < ranked[j].HaversineKm
+	})
+	return ranked
+}
+
+// FairnessStrategy 公平派單：以預估時間（分鐘）為基礎分數，閒置越久扣分、今日收入越高加分，分數越低越優先
+type FairnessStrategy struct {
+	IdleBonusPerHour   float64 // 每閒置一小時折抵的分鐘數
+	MaxIdleBonusMins   float64 // 閒置折抵上限（分鐘），避免遠距離司機因閒置過久被優先派單
+	IncomePenaltyPer1K float64 // 今日收入每 1000 元加計的分鐘數
+}
+
+func (s *FairnessStrategy) Name() string           { return RankingStrategyFairness }
+func (s *FairnessStrategy) NeedsDriverStats() bool { return true }
+
+func (s *FairnessStrategy) Rank(order *model.Order, candidates []*RankingCandidate, now time.Time) []*RankingCandidate {
+	scores := make(map[*RankingCandidate]float64, len(candidates))
+	for _, c := range candidates {
+		idleBonus := 0.0
+		if !c.IdleSince.IsZero() && now.After(c.IdleSince) {
+			idleBonus = math.Min(now.Sub(c.IdleSince).Hours()*s.IdleBonusPerHour, s.MaxIdleBonusMins)
+		}
+		incomePenalty := float64(c.TodayIncome) / 1000 * s.IncomePenaltyPer1K
+		scores[c] = float64(c.EtaMins) - idleBonus + incomePenalty
+	}
+	return rankByScore(candidates, scores)
+}
+
+// AcceptanceRateStrategy 以預估時間（分鐘）為基礎分數，依拒單比例加計分鐘數，分數越低越優先
+// 接單率採用 (接單+1)/(接單+拒絕+2) 平滑，避免新司機因樣本過少被極端排序
+type AcceptanceRateStrategy struct {
+	RejectPenaltyMins float64 // 接單率為 0 時加計的分鐘數
+}
+
+func (s *AcceptanceRateStrategy) Name() string           { return RankingStrategyAcceptanceRate }
+func (s *AcceptanceRateStrategy) NeedsDriverStats() bool { return false }
+
+func (s *AcceptanceRateStrategy) Rank(order *model.Order, candidates []*RankingCandidate, now time.Time) []*RankingCandidate {
+	scores := make(map[*RankingCandidate]float64, len(candidates))
+	for _, c := range candidates {
+		scores[c] = float64(c.EtaMins) + s.RejectPenaltyMins*(1-acceptanceRate(c.Driver))
+	}
+	return rankByScore(candidates, scores)
+}
+
+// acceptanceRate 計算平滑後的司機接單率
+func acceptanceRate(driver *model.DriverInfo) float64 {
+	if driver == nil {
+		return 0.5
+	}
+	accepted := float64(driver.AcceptedCount)
+	rejected := float64(driver.RejectedCount)
+	return (accepted + 1) / (accepted + rejected + 2)
+}
+
+// rankByScore 依分數由低到高排序，分數相同時以預估時間、直線距離排序
+func rankByScore(candidates []*RankingCandidate, scores map[*RankingCandidate]float64) []*RankingCandidate {
+	ranked := append([]*RankingCandidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		si, sj := scores[ranked[i]], scores[ranked[j]]
+		if si != sj {
+			return si < sj
+		}
+		if ranked[i].EtaMins != ranked[j].EtaMins {
+			return ranked[i].EtaMins < ranked[j].EtaMins
+		}
+		return ranked[i].HaversineKm < ranked[j].HaversineKm
+	})
+	return ranked
+}
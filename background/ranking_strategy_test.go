@@ -0,0 +1,141 @@
+package background
+
+import (
+	"right-backend/model"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var rankingTestNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newRankingCandidate 建立測試用的候選司機快照
+func newRankingCandidate(name string, etaMins int, haversineKm float64, idleMins int, income int, accepted int, rejected int) *RankingCandidate {
+	return &RankingCandidate{
+		Driver: &model.DriverInfo{
+			ID:            primitive.NewObjectID(),
+			Name:          name,
+			AcceptedCount: accepted,
+			RejectedCount: rejected,
+		},
+		EtaMins:     etaMins,
+		HaversineKm: haversineKm,
+		IdleSince:   rankingTestNow.Add(-time.Duration(idleMins) * time.Minute),
+		TodayIncome: income,
+	}
+}
+
+func rankedNames(ranked []*RankingCandidate) []string {
+	names := make([]string, 0, len(ranked))
+	for _, c := range ranked {
+		names = append(names, c.Driver.Name)
+	}
+	return names
+}
+
+func TestRankingStrategies(t *testing.T) {
+	testCases := []struct {
+		name       string
+		strategy   string
+		candidates []*RankingCandidate
+		expected   []string
+	}{
+		{
+			name:     "nearest_eta 依預估時間排序",
+			strategy: RankingStrategyNearestETA,
+			candidates: []*RankingCandidate{
+				newRankingCandidate("A", 12, 3.0, 0, 0, 0, 0),
+				newRankingCandidate("B", 5, 4.0, 0, 0, 0, 0),
+				newRankingCandidate("C", 8, 1.0, 0, 0, 0, 0),
+			},
+			expected: []string{"B", "C", "A"},
+		},
+		{
+			name:     "nearest_eta 預估時間相同時以直線距離排序",
+			strategy: RankingStrategyNearestETA,
+			candidates: []*RankingCandidate{
+				newRankingCandidate("A", 6, 3.0, 0, 0, 0, 0),
+				newRankingCandidate("B", 6, 1.5, 0, 0, 0, 0),
+			},
+			expected: []string{"B", "A"},
+		},
+		{
+			name:     "fairness 閒置較久且收入較少的司機優先",
+			strategy: RankingStrategyFairness,
+			candidates: []*RankingCandidate{
+				newRankingCandidate("忙碌", 5, 1.0, 5, 3000, 0, 0),
+				newRankingCandidate("久候", 7, 2.0, 120, 0, 0, 0),
+				newRankingCandidate("普通", 6, 1.5, 30, 1500, 0, 0),
+			},
+			expected: []string{"久候", "普通", "忙碌"},
+		},
+		{
+			name:     "fairness 其他條件相同時退化為依預估時間排序",
+			strategy: RankingStrategyFairness,
+			candidates: []*RankingCandidate{
+				newRankingCandidate("A", 9, 1.0, 10, 500, 0, 0),
+				newRankingCandidate("B", 4, 1.0, 10, 500, 0, 0),
+			},
+			expected: []string{"B", "A"},
+		},
+		{
+			name:     "acceptance_rate 接單率高的司機優先",
+			strategy: RankingStrategyAcceptanceRate,
+			candidates: []*RankingCandidate{
+				newRankingCandidate("常拒單", 5, 1.0, 0, 0, 2, 18),
+				newRankingCandidate("常接單", 6, 1.2, 0, 0, 18, 2),
+			},
+			expected: []string{"常接單", "常拒單"},
+		},
+		{
+			name:     "acceptance_rate 預估時間差距大時仍以時間為主",
+			strategy: RankingStrategyAcceptanceRate,
+			candidates: []*RankingCandidate{
+				newRankingCandidate("遠", 20, 8.0, 0, 0, 20, 0),
+				newRankingCandidate("近", 3, 1.0, 0, 0, 10, 10),
+			},
+			expected: []string{"近", "遠"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy, err := NewRankingStrategy(tc.strategy)
+			if err != nil {
+				t.Fatalf("建立排序策略失敗: %v", err)
+			}
+			ranked := rankedNames(strategy.Rank(&model.Order{}, tc.candidates, rankingTestNow))
+			if len(ranked) != len(tc.expected) {
+				t.Fatalf("排序結果數量錯誤: got %v, want %v", ranked, tc.expected)
+			}
+			for i := range ranked {
+				if ranked[i] != tc.expected[i] {
+					t.Fatalf("排序結果錯誤: got %v, want %v", ranked, tc.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRankingStrategyDoesNotMutateInput(t *testing.T) {
+	candidates := []*RankingCandidate{
+		newRankingCandidate("A", 10, 1.0, 0, 0, 0, 0),
+		newRankingCandidate("B", 2, 1.0, 0, 0, 0, 0),
+	}
+	strategy := &NearestETAStrategy{}
+	strategy.Rank(&model.Order{}, candidates, rankingTestNow)
+	if candidates[0].Driver.Name != "A" {
+		t.Fatalf("排序策略不應修改傳入的切片")
+	}
+}
+
+func TestNewRankingStrategyUnknown(t *testing.T) {
+	if _, err := NewRankingStrategy("random"); err == nil {
+		t.Fatalf("未知策略應回傳錯誤")
+	}
+	strategy, err := NewRankingStrategy("")
+	if err != nil || strategy.Name() != RankingStrategyNearestETA {
+		t.Fatalf("未設定策略時應使用 nearest_eta")
+	}
+}
@@ -0,0 +1,64 @@
+package background
+
+import (
+	"context"
+	"right-backend/infra"
+	"right-backend/service"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const defaultRecurringGenerateInterval = 10 * time.Minute
+
+// RecurringOrderGenerator 週期性預約產生器，定期將即將到來的單次預約產生為預約單（進入預約單隊列）
+type RecurringOrderGenerator struct {
+	logger        zerolog.Logger
+	RecurringSvc  *service.RecurringOrderService
+	generateEvery time.Duration
+}
+
+func NewRecurringOrderGenerator(logger zerolog.Logger, recurringSvc *service.RecurringOrderService) *RecurringOrderGenerator {
+	interval := time.Duration(infra.AppConfig.RecurringOrders.IntervalMins) * time.Minute
+	if interval <= 0 {
+		interval = defaultRecurringGenerateInterval
+	}
+	return &RecurringOrderGenerator{
+		logger:        logger.With().Str("component", "recurring-order-generator").Logger(),
+		RecurringSvc:  recurringSvc,
+		generateEvery: interval,
+	}
+}
+
+// Start 啟動時立即產生一次，之後依設定間隔定期產生
+func (g *RecurringOrderGenerator) Start(ctx context.Context) {
+	g.logger.Info().
+		Dur("interval", g.generateEvery).
+		Int("days_ahead", g.RecurringSvc.DaysAhead()).
+		Msg("週期性預約產生器已啟動")
+
+	g.generate(ctx)
+
+	ticker := time.NewTicker(g.generateEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.generate(ctx)
+		}
+	}
+}
+
+// generate 產生即將到來的預約單
+func (g *RecurringOrderGenerator) generate(ctx context.Context) {
+	generated, err := g.RecurringSvc.GenerateUpcoming(ctx, time.Now())
+	if err != nil {
+		g.logger.Error().Err(err).Msg("週期性預約產生預約單失敗")
+		return
+	}
+	if generated > 0 {
+		g.logger.Info().Int("generated", generated).Msg("週期性預約已產生預約單")
+	}
+}
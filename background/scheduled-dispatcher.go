@@ -35,6 +35,7 @@ type ScheduledDispatcher struct {
 	TrafficUsageLogSvc *service.TrafficUsageLogService
 	BlacklistSvc       *service.DriverBlacklistService
 	NotificationSvc    *service.NotificationService
+	FleetSvc           *service.FleetService // 車隊登錄資料（跨車隊派單矩陣）
 	dispatcherID       string
 }
 
@@ -63,6 +64,11 @@ func NewScheduledDispatcher(
 	}
 }
 
+// SetFleetService 設定車隊登錄服務，跨車隊派單規則以 fleets 集合為準
+func (sd *ScheduledDispatcher) SetFleetService(fleetSvc *service.FleetService) {
+	sd.FleetSvc = fleetSvc
+}
+
 // Start 啟動預約單調度器，監聽預約單隊列
 func (sd *ScheduledDispatcher) Start(ctx context.Context) {
 	msgs, err := sd.RabbitMQ.Channel.Consume(
@@ -160,17 +166,12 @@ func (sd *ScheduledDispatcher) DispatchScheduledOrder(ctx context.Context, order
 
 // 輔助方法
 
-// isFleetMatching 檢查車隊匹配規則
+// isFleetMatching 檢查車隊匹配規則（依車隊登錄的跨車隊派單設定）
 func (sd *ScheduledDispatcher) isFleetMatching(orderFleet, driverFleet model.FleetType) bool {
-	// WEI 車隊訂單只能派給 WEI 車隊司機
-	if orderFleet == model.FleetTypeWEI && driverFleet != model.FleetTypeWEI {
-		return false
-	}
-	// RSK, KD 車隊訂單不能派給 WEI 車隊司機
-	if (orderFleet == model.FleetTypeRSK || orderFleet == model.FleetTypeKD) && driverFleet == model.FleetTypeWEI {
-		return false
+	if sd.FleetSvc == nil {
+		return model.DefaultFleetFor(orderFleet).CanDispatchTo(driverFleet)
 	}
-	return true
+	return sd.FleetSvc.CanDispatch(orderFleet, driverFleet)
 }
 
 // isDriverRejectingFleet 檢查司機是否拒絕該車隊
@@ -364,6 +365,10 @@ func (sd *ScheduledDispatcher) sendToInstantOrderQueue(ctx context.Context, orde
 		Str("short_id", order.ShortID).
 		Msg("將轉換後的即時單發送到派單隊列")
 
+	if err := sd.OrderSvc.MarkOrderEnqueued(ctx, order); err != nil {
+		sd.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("記錄訂單排入隊列時間失敗")
+	}
+
 	// 將訂單序列化為 JSON
 	orderJSON, err := json.Marshal(order)
 	if err != nil {
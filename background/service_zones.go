@@ -0,0 +1,34 @@
+package background
+
+import (
+	"context"
+	"right-backend/model"
+	"right-backend/service"
+)
+
+// SetServiceZoneService 設定服務區域服務，設定後派單會套用上車點所在區域的規則
+func (d *Dispatcher) SetServiceZoneService(zoneSvc *service.ServiceZoneService) {
+	d.ZoneSvc = zoneSvc
+}
+
+// zoneRestrictionFor 查詢訂單上車點所在服務區域的限制，查詢失敗時不套用限制，避免區域設定問題阻斷派單
+func (d *Dispatcher) zoneRestrictionFor(ctx context.Context, order *model.Order) *model.ZoneRestriction {
+	if d.ZoneSvc == nil {
+		return nil
+	}
+	restriction, err := d.ZoneSvc.ResolveOrderRestriction(ctx, order)
+	if err != nil {
+		d.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("調度中心查詢服務區域限制失敗，不套用區域規則")
+		return nil
+	}
+	return restriction
+}
+
+// MaxDriverDistanceFor 本輪司機直線距離上限：輪次半徑優先，其次為服務區域指定的半徑，最後為車隊設定
+// （派單與離線模擬共用）
+func MaxDriverDistanceFor(policy *model.DispatchPolicy, round model.DispatchRound, zone *model.ZoneRestriction) (bool, float64) {
+	if round.MaxDriverDistanceKm <= 0 && zone != nil && zone.MaxDriverDistanceKm > 0 {
+		return true, zone.MaxDriverDistanceKm
+	}
+	return policy.MaxDriverDistanceFor(round)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const driverLocationsCollection = "driver_locations"
+
+// locationHistory 依 driver_locations 位置歷史重建訂單建立當下的司機位置與狀態
+type locationHistory struct {
+	mongoDB  *infra.MongoDB
+	window   time.Duration                // 訂單建立前多久內的軌跡點視為當下位置
+	profiles map[string]*model.DriverInfo // 司機ID → 司機資料（車隊、拒絕列表、標籤等設定）
+}
+
+func newLocationHistory(mongoDB *infra.MongoDB, window time.Duration, drivers []*model.DriverInfo) *locationHistory {
+	profiles := make(map[string]*model.DriverInfo, len(drivers))
+	for _, drv := range drivers {
+		profiles[drv.ID.Hex()] = drv
+	}
+	return &locationHistory{mongoDB: mongoDB, window: window, profiles: profiles}
+}
+
+// hasPoints 檢查模擬區間內是否有位置歷史，沒有時改用司機位置快照
+func (h *locationHistory) hasPoints(ctx context.Context, start, end time.Time) (bool, error) {
+	count, err := h.mongoDB.GetCollection(driverLocationsCollection).CountDocuments(ctx, bson.M{
+		"recorded_at": bson.M{"$gt": start.Add(-h.window), "$lte": end},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// driversAt 取得各司機在指定時間前最後一筆軌跡點，只保留當下閒置的司機（與調度中心只派閒置司機相同）
+// 司機資料的預約單狀態是備份當下的資料，與歷史訂單當下不同，因此不套用預約單排除規則
+func (h *locationHistory) driversAt(ctx context.Context, at time.Time) ([]*model.DriverInfo, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"recorded_at": bson.M{"$gt": at.Add(-h.window), "$lte": at}}},
+		{"$sort": bson.M{"recorded_at": -1}},
+		{"$group": bson.M{
+			"_id":         "$driver_id",
+			"driver_id":   bson.M{"$first": "$driver_id"},
+			"lat":         bson.M{"$first": "$lat"},
+			"lng":         bson.M{"$first": "$lng"},
+			"status":      bson.M{"$first": "$status"},
+			"recorded_at": bson.M{"$first": "$recorded_at"},
+		}},
+	}
+	cursor, err := h.mongoDB.GetCollection(driverLocationsCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var points []model.DriverLocation
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, err
+	}
+
+	drivers := make([]*model.DriverInfo, 0, len(points))
+	for _, point := range points {
+		profile, ok := h.profiles[point.DriverID]
+		if !ok || point.Status != model.DriverStatusIdle {
+			continue
+		}
+		drv := *profile
+		drv.Lat = strconv.FormatFloat(point.Lat, 'f', -1, 64)
+		drv.Lng = strconv.FormatFloat(point.Lng, 'f', -1, 64)
+		drv.Status = point.Status
+		drv.HasSchedule = false
+		drv.ScheduledTime = nil
+		drivers = append(drivers, &drv)
+	}
+	return drivers, nil
+}
@@ -0,0 +1,348 @@
+// dispatch-sim 派單策略離線模擬工具
+//
+// 從本機 MongoDB 備份載入歷史即時單與司機資料，以直線距離 × 速度係數取代 CrawlerService，
+// 重跑與調度中心相同的候選司機篩選、服務區域規則、輪次擴大半徑與排序邏輯，輸出各派單策略的預估到達時間、候選人數與流單率。
+//
+// 司機位置（-positions）：
+//
+//   - history（預設）：每筆訂單依 driver_locations 位置歷史，取各司機在訂單建立前 -location-window 內最後一筆軌跡點，
+//     只使用當下閒置的司機；司機的車隊、拒絕列表與標籤等設定仍取自備份當下的司機資料，且不套用預約單排除規則
+//   - snapshot：所有訂單共用 -drivers-collection 的司機位置快照，快照是備份當下的位置，與歷史訂單當下不同，結果僅供策略間相對比較
+//
+// 模擬區間內沒有位置歷史時（例如位置歷史已超過保留天數），自動改用司機位置快照。
+//
+// 使用方式：
+//
+//	go run ./cmd/dispatch-sim -db right_db -from 2025-08-01 -to 2025-09-01 -policies scenarios.json
+//
+// scenarios.json 格式（未指定時只模擬備份中 dispatch_policies 的目前設定）：
+//
+//	[{"name": "擴大半徑", "policies": [{"fleet": "RSK", "haversine_candidates_count": 15, ...}]}]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scenario 一組待比較的派單策略，未列出的車隊沿用備份中的目前設定
+type scenario struct {
+	Name     string                 `json:"name"`
+	Policies []model.DispatchPolicy `json:"policies"`
+}
+
+func main() {
+	mongoURI := flag.String("mongo-uri", "mongodb://localhost:27017", "本機 MongoDB 連線字串（載入備份後的資料庫）")
+	database := flag.String("db", "right_db", "資料庫名稱")
+	from := flag.String("from", "", "訂單建立起始日期 (YYYY-MM-DD)，預設為 7 天前")
+	to := flag.String("to", "", "訂單建立結束日期 (YYYY-MM-DD，不含)，預設為今天")
+	fleet := flag.String("fleet", "", "只模擬指定車隊的訂單")
+	limit := flag.Int64("limit", 5000, "最多載入的訂單數量")
+	policiesFile := flag.String("policies", "", "待比較的派單策略 JSON 檔")
+	driversCollection := flag.String("drivers-collection", "drivers", "司機資料與位置快照所在的集合")
+	positions := flag.String("positions", "history", "司機位置來源：history（訂單建立當下的位置歷史）或 snapshot（備份當下的位置快照）")
+	locationWindow := flag.Duration("location-window", 5*time.Minute, "位置歷史模式下，訂單建立前多久內的軌跡點視為司機當下位置")
+	onlineOnly := flag.Bool("online-only", true, "快照模式下只使用快照中上線的司機")
+	speedKmh := flag.Float64("speed-kmh", 25, "模擬路徑計算的平均車速（公里/小時）")
+	roadFactor := flag.Float64("road-factor", 1.4, "直線距離換算道路距離的係數")
+	allowRemote := flag.Bool("allow-remote", false, "允許連線非本機的 MongoDB（預設只允許本機，避免誤連正式環境）")
+	flag.Parse()
+
+	if !*allowRemote && !isLocalMongoURI(*mongoURI) {
+		log.Fatalf("❌ 模擬工具只能連線本機 MongoDB 備份，如確定要連線 %s 請加上 -allow-remote", *mongoURI)
+	}
+	if *speedKmh <= 0 || *roadFactor <= 0 {
+		log.Fatalf("❌ speed-kmh 與 road-factor 必須大於 0")
+	}
+	if *positions != "history" && *positions != "snapshot" {
+		log.Fatalf("❌ positions 只能是 history 或 snapshot")
+	}
+	if *locationWindow <= 0 {
+		log.Fatalf("❌ location-window 必須大於 0")
+	}
+
+	start, end, err := parseDateRange(*from, *to)
+	if err != nil {
+		log.Fatalf("❌ 日期格式錯誤: %v", err)
+	}
+
+	scenarios, err := loadScenarios(*policiesFile)
+	if err != nil {
+		log.Fatalf("❌ 讀取派單策略檔失敗: %v", err)
+	}
+
+	mongoDB, err := infra.NewMongoDB(infra.MongoConfig{URI: *mongoURI, Database: *database})
+	if err != nil {
+		log.Fatalf("❌ 連接 MongoDB 失敗: %v", err)
+	}
+	defer mongoDB.Close(context.Background())
+
+	ctx := context.Background()
+
+	orders, err := loadOrders(ctx, mongoDB, start, end, model.FleetType(*fleet), *limit)
+	if err != nil {
+		log.Fatalf("❌ 載入歷史訂單失敗: %v", err)
+	}
+	// 位置歷史模式以軌跡點的狀態判斷當下是否可接單，不使用備份當下的上線狀態
+	drivers, err := loadDriverSnapshots(ctx, mongoDB, *driversCollection, *onlineOnly && *positions == "snapshot")
+	if err != nil {
+		log.Fatalf("❌ 載入司機資料失敗: %v", err)
+	}
+	var history *locationHistory
+	if *positions == "history" {
+		history = newLocationHistory(mongoDB, *locationWindow, drivers)
+		ok, err := history.hasPoints(ctx, start, end)
+		if err != nil {
+			log.Fatalf("❌ 查詢司機位置歷史失敗: %v", err)
+		}
+		if !ok {
+			fmt.Println("⚠️  模擬區間內沒有司機位置歷史，改用司機位置快照（快照為備份當下的位置，結果僅供策略間相對比較）")
+			history = nil
+			if *onlineOnly {
+				drivers = onlineDrivers(drivers)
+			}
+		}
+	}
+	currentPolicies, err := loadCurrentPolicies(ctx, mongoDB)
+	if err != nil {
+		log.Fatalf("❌ 載入目前派單策略失敗: %v", err)
+	}
+	fleets, err := loadFleets(ctx, mongoDB)
+	if err != nil {
+		log.Fatalf("❌ 載入車隊登錄資料失敗: %v", err)
+	}
+
+	positionSource := "司機位置快照"
+	if history != nil {
+		positionSource = fmt.Sprintf("司機資料（位置取訂單建立前 %v 內的位置歷史）", *locationWindow)
+	}
+	fmt.Printf("✅ 載入 %d 筆即時單（%s ~ %s）、%d 名%s、%d 組車隊派單策略\n",
+		len(orders), start.Format("2006-01-02"), end.Format("2006-01-02"), len(drivers), positionSource, len(currentPolicies))
+	if len(orders) == 0 || len(drivers) == 0 {
+		fmt.Println("⚠️  沒有可模擬的訂單或司機，結束")
+		return
+	}
+
+	sim := &simulator{
+		drivers:   drivers,
+		history:   history,
+		fleets:    fleets,
+		zones:     service.NewServiceZoneService(zerolog.Nop(), mongoDB),
+		estimator: &haversineRouteEstimator{speedKmh: *speedKmh, roadFactor: *roadFactor},
+	}
+
+	fmt.Printf("🚀 開始模擬（車速 %.0f 公里/小時，道路係數 %.2f）\n", *speedKmh, *roadFactor)
+	for _, sc := range scenarios {
+		policies := mergePolicies(currentPolicies, sc.Policies)
+		report := sim.run(ctx, orders, policies)
+		printReport(sc.Name, report)
+	}
+}
+
+// isLocalMongoURI 判斷連線字串是否只指向本機
+func isLocalMongoURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "mongodb+srv" {
+		return false
+	}
+	for _, host := range strings.Split(u.Host, ",") {
+		hostname := host
+		if idx := strings.LastIndex(host, ":"); idx >= 0 && !strings.HasSuffix(host, "]") {
+			hostname = host[:idx]
+		}
+		switch strings.Trim(hostname, "[]") {
+		case "localhost", "127.0.0.1", "::1":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseDateRange 解析模擬的訂單建立時間區間（台北時間）
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	loc := time.FixedZone("Asia/Taipei", 8*3600)
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	end := today.AddDate(0, 0, 1)
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -7)
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("起始日期 %s 必須早於結束日期 %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	return start, end, nil
+}
+
+// loadScenarios 讀取待比較的派單策略，未指定檔案時只模擬目前設定
+func loadScenarios(path string) ([]scenario, error) {
+	if path == "" {
+		return []scenario{{Name: "目前設定"}}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenarios []scenario
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, err
+	}
+	if len(scenarios) == 0 {
+		return nil, fmt.Errorf("%s 未包含任何派單策略", path)
+	}
+	for i := range scenarios {
+		if scenarios[i].Name == "" {
+			scenarios[i].Name = fmt.Sprintf("策略%d", i+1)
+		}
+		// 與後台儲存派單策略相同的驗證，避免模擬線上無法設定的策略
+		for j := range scenarios[i].Policies {
+			if err := scenarios[i].Policies[j].Validate(); err != nil {
+				return nil, fmt.Errorf("%s 的 %s 車隊派單策略無效: %w", scenarios[i].Name, scenarios[i].Policies[j].Fleet, err)
+			}
+		}
+	}
+	return scenarios, nil
+}
+
+// loadOrders 載入區間內有上車點座標的歷史即時單
+func loadOrders(ctx context.Context, mongoDB *infra.MongoDB, start, end time.Time, fleet model.FleetType, limit int64) ([]*model.Order, error) {
+	filter := bson.M{
+		"type":                model.OrderTypeInstant,
+		"created_at":          bson.M{"$gte": start, "$lt": end},
+		"customer.pickup_lat": bson.M{"$exists": true, "$ne": ""},
+		"customer.pickup_lng": bson.M{"$exists": true, "$ne": ""},
+	}
+	if fleet != "" {
+		filter["fleet"] = fleet
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": 1}).SetLimit(limit)
+
+	cursor, err := mongoDB.GetCollection("orders").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var orders []*model.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// loadDriverSnapshots 載入司機位置快照，沿用調度中心的啟用與審核條件
+// 快照時間點的司機狀態與歷史訂單當下不同，因此不過濾閒置狀態
+func loadDriverSnapshots(ctx context.Context, mongoDB *infra.MongoDB, collection string, onlineOnly bool) ([]*model.DriverInfo, error) {
+	filter := bson.M{
+		"$and": []bson.M{
+			{"$or": []bson.M{{"is_active": true}, {"is_active": bson.M{"$exists": false}}}},
+			{"$or": []bson.M{{"is_approved": true}, {"is_approved": bson.M{"$exists": false}}}},
+		},
+	}
+	if onlineOnly {
+		filter["is_online"] = true
+	}
+
+	cursor, err := mongoDB.GetCollection(collection).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var drivers []*model.DriverInfo
+	if err := cursor.All(ctx, &drivers); err != nil {
+		return nil, err
+	}
+	return drivers, nil
+}
+
+// onlineDrivers 只保留快照中上線的司機
+func onlineDrivers(drivers []*model.DriverInfo) []*model.DriverInfo {
+	online := make([]*model.DriverInfo, 0, len(drivers))
+	for _, drv := range drivers {
+		if drv.IsOnline {
+			online = append(online, drv)
+		}
+	}
+	return online
+}
+
+// loadCurrentPolicies 載入備份中各車隊目前的派單策略
+func loadCurrentPolicies(ctx context.Context, mongoDB *infra.MongoDB) (map[model.FleetType]*model.DispatchPolicy, error) {
+	cursor, err := mongoDB.GetCollection("dispatch_policies").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var policies []*model.DispatchPolicy
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+
+	result := make(map[model.FleetType]*model.DispatchPolicy, len(policies))
+	for _, policy := range policies {
+		// 與調度中心載入策略相同，無效的策略改用預設值
+		if err := policy.Validate(); err != nil {
+			fmt.Printf("⚠️  %s 車隊派單策略設定無效，改用預設值: %v\n", policy.Fleet, err)
+			continue
+		}
+		result[policy.Fleet] = policy
+	}
+	return result, nil
+}
+
+// loadFleets 載入備份中的車隊登錄資料，集合為空時使用預設車隊（與服務端一致）
+func loadFleets(ctx context.Context, mongoDB *infra.MongoDB) (map[model.FleetType]*model.Fleet, error) {
+	cursor, err := mongoDB.GetCollection("fleets").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var fleets []*model.Fleet
+	if err := cursor.All(ctx, &fleets); err != nil {
+		return nil, err
+	}
+	if len(fleets) == 0 {
+		fleets = model.DefaultFleets()
+	}
+
+	result := make(map[model.FleetType]*model.Fleet, len(fleets))
+	for _, fleet := range fleets {
+		result[fleet.Code] = fleet
+	}
+	return result, nil
+}
+
+// mergePolicies 以模擬策略覆蓋目前設定
+func mergePolicies(current map[model.FleetType]*model.DispatchPolicy, overrides []model.DispatchPolicy) map[model.FleetType]*model.DispatchPolicy {
+	merged := make(map[model.FleetType]*model.DispatchPolicy, len(current)+len(overrides))
+	for fleet, policy := range current {
+		merged[fleet] = policy
+	}
+	for i := range overrides {
+		merged[overrides[i].Fleet] = &overrides[i]
+	}
+	return merged
+}
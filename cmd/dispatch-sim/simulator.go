@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"right-backend/background"
+	"right-backend/model"
+	"right-backend/service"
+	"right-backend/utils"
+)
+
+// routeEstimator 與 CrawlerService.DirectionsMatrixInverse 相同簽名的路徑計算介面
+type routeEstimator interface {
+	DirectionsMatrixInverse(ctx context.Context, origins []string, destination string) ([]service.RouteInfo, error)
+}
+
+// haversineRouteEstimator 以直線距離 × 道路係數 ÷ 平均車速估算路徑，取代需要瀏覽器的 CrawlerService
+type haversineRouteEstimator struct {
+	speedKmh   float64
+	roadFactor float64
+}
+
+func (e *haversineRouteEstimator) DirectionsMatrixInverse(ctx context.Context, origins []string, destination string) ([]service.RouteInfo, error) {
+	destLat, destLng, err := parseCoord(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]service.RouteInfo, 0, len(origins))
+	for _, origin := range origins {
+		lat, lng, err := parseCoord(origin)
+		if err != nil {
+			return nil, err
+		}
+		distanceKm := utils.Haversine(lat, lng, destLat, destLng) * e.roadFactor
+		mins := int(math.Ceil(distanceKm / e.speedKmh * 60))
+		routes = append(routes, service.RouteInfo{
+			Time:          fmt.Sprintf("%d 分鐘", mins),
+			Distance:      fmt.Sprintf("%.1f 公里", distanceKm),
+			TimeInMinutes: mins,
+			DistanceKm:    distanceKm,
+		})
+	}
+	return routes, nil
+}
+
+func parseCoord(coord string) (float64, float64, error) {
+	parts := strings.Split(coord, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("無效的座標: %s", coord)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lng, nil
+}
+
+// orderOutcome 單筆訂單的模擬結果
+type orderOutcome struct {
+	Fleet               model.FleetType
+	Failed              bool
+	ZoneRejected        bool // 上車點位於不服務區域，調度中心直接流單
+	Round               int  // 找到候選司機的輪次（從 1 開始）
+	HaversineCandidates int
+	FinalCandidates     int
+	EtaMins             int  // 第一順位司機的預估到達時間
+	ActualFailed        bool // 歷史訂單實際是否流單
+	ActualEtaMins       int  // 歷史訂單實際接單司機的預估到達時間（0 表示無資料）
+}
+
+// simulator 以訂單建立當下的司機位置（位置歷史，或固定的司機位置快照）重跑調度中心的候選司機篩選流程
+type simulator struct {
+	drivers   []*model.DriverInfo
+	history   *locationHistory // 未設定時所有訂單都使用司機位置快照
+	fleets    map[model.FleetType]*model.Fleet
+	zones     *service.ServiceZoneService // 備份中的服務區域，與調度中心相同套用區域規則
+	estimator routeEstimator
+
+	orderDrivers map[*model.Order][]*model.DriverInfo // 各訂單當下的司機位置，多組策略共用
+}
+
+// driversFor 取得訂單建立當下的司機，位置歷史只查詢一次並供所有策略共用
+func (s *simulator) driversFor(ctx context.Context, order *model.Order, orderTime time.Time) ([]*model.DriverInfo, error) {
+	if s.history == nil {
+		return s.drivers, nil
+	}
+	if drivers, ok := s.orderDrivers[order]; ok {
+		return drivers, nil
+	}
+	drivers, err := s.history.driversAt(ctx, orderTime)
+	if err != nil {
+		return nil, err
+	}
+	if s.orderDrivers == nil {
+		s.orderDrivers = make(map[*model.Order][]*model.DriverInfo)
+	}
+	s.orderDrivers[order] = drivers
+	return drivers, nil
+}
+
+// fleetFor 取得訂單車隊的登錄資料，備份中未登錄的車隊只派給本車隊司機
+func (s *simulator) fleetFor(code model.FleetType) *model.Fleet {
+	if fleet, ok := s.fleets[code]; ok {
+		return fleet
+	}
+	return model.UnregisteredFleet(code)
+}
+
+// zoneRestrictionFor 查詢訂單上車點所在服務區域的限制，查詢失敗時與調度中心相同不套用限制
+func (s *simulator) zoneRestrictionFor(ctx context.Context, order *model.Order) *model.ZoneRestriction {
+	if s.zones == nil {
+		return nil
+	}
+	restriction, err := s.zones.ResolveOrderRestriction(ctx, order)
+	if err != nil {
+		fmt.Printf("⚠️  訂單 %s 查詢服務區域失敗，不套用區域規則: %v\n", order.ShortID, err)
+		return nil
+	}
+	return restriction
+}
+
+// run 依各車隊派單策略模擬所有訂單
+func (s *simulator) run(ctx context.Context, orders []*model.Order, policies map[model.FleetType]*model.DispatchPolicy) []orderOutcome {
+	outcomes := make([]orderOutcome, 0, len(orders))
+	for _, order := range orders {
+		policy, ok := policies[order.Fleet]
+		if !ok {
+			policy = model.DefaultDispatchPolicy(order.Fleet, s.fleetFor(order.Fleet).Settings)
+		}
+		outcome, err := s.simulateOrder(ctx, order, policy)
+		if err != nil {
+			fmt.Printf("⚠️  訂單 %s 模擬失敗: %v\n", order.ShortID, err)
+			continue
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// simulateOrder 依輪次篩選候選司機，第一個找到候選司機的輪次即視為派出（假設第一順位司機接單）
+func (s *simulator) simulateOrder(ctx context.Context, order *model.Order, policy *model.DispatchPolicy) (orderOutcome, error) {
+	outcome := orderOutcome{
+		Fleet:         order.Fleet,
+		Failed:        true,
+		ActualFailed:  order.Status == model.OrderStatusFailed,
+		ActualEtaMins: order.Driver.EstPickupMins,
+	}
+
+	strategy, err := background.NewRankingStrategy(policy.RankingStrategy)
+	if err != nil {
+		return outcome, err
+	}
+
+	zone := s.zoneRestrictionFor(ctx, order)
+	if zone.IsRejected() {
+		outcome.ZoneRejected = true
+		return outcome, nil
+	}
+
+	orderTime := time.Now()
+	if order.CreatedAt != nil {
+		orderTime = *order.CreatedAt
+	}
+	drivers, err := s.driversFor(ctx, order, orderTime)
+	if err != nil {
+		return outcome, err
+	}
+
+	for roundIdx, round := range policy.DispatchRounds() {
+		haversineCount, candidates, err := s.findCandidates(ctx, order, drivers, policy, round, zone, strategy, orderTime)
+		if err != nil {
+			return outcome, err
+		}
+		outcome.HaversineCandidates = haversineCount
+		outcome.FinalCandidates = len(candidates)
+		if len(candidates) > 0 {
+			outcome.Failed = false
+			outcome.Round = roundIdx + 1
+			outcome.EtaMins = candidates[0].EtaMins
+			break
+		}
+	}
+	return outcome, nil
+}
+
+// findCandidates 與調度中心 findCandidateDrivers 相同的篩選步驟：
+// 車隊規則 → 服務區域標籤 → 直線距離上限 → 直線距離前 N 名 → 路徑預估時間上限 → 排序策略 → 最終派單人數
+func (s *simulator) findCandidates(ctx context.Context, order *model.Order, drivers []*model.DriverInfo, policy *model.DispatchPolicy, round model.DispatchRound,
+	zone *model.ZoneRestriction, strategy background.RankingStrategy, orderTime time.Time) (int, []*background.RankingCandidate, error) {
+	pickupLat, _ := strconv.ParseFloat(*order.Customer.PickupLat, 64)
+	pickupLng, _ := strconv.ParseFloat(*order.Customer.PickupLng, 64)
+	enableMaxDriverDistance, maxDriverDistanceKm := background.MaxDriverDistanceFor(policy, round, zone)
+	orderFleet := s.fleetFor(order.Fleet)
+
+	type driverWithDist struct {
+		driver *model.DriverInfo
+		dist   float64
+	}
+	var driverDists []driverWithDist
+	for _, drv := range drivers {
+		if background.DriverIneligibleReason(order, orderFleet, drv, round) != "" {
+			continue
+		}
+		if zone.MissingDriverTag(drv) != "" {
+			continue
+		}
+		// 即時單不派給 1 小時內有預約單的司機
+		if drv.HasSchedule && drv.ScheduledTime != nil {
+			timeDiff := drv.ScheduledTime.Sub(orderTime)
+			if timeDiff > 0 && timeDiff <= time.Hour {
+				continue
+			}
+		}
+		lat, _ := strconv.ParseFloat(drv.Lat, 64)
+		lng, _ := strconv.ParseFloat(drv.Lng, 64)
+		if lat == 0 || lng == 0 {
+			continue
+		}
+		dist := utils.Haversine(pickupLat, pickupLng, lat, lng)
+		if enableMaxDriverDistance && dist > maxDriverDistanceKm {
+			continue
+		}
+		driverDists = append(driverDists, driverWithDist{driver: drv, dist: dist})
+	}
+	sort.Slice(driverDists, func(i, j int) bool { return driverDists[i].dist < driverDists[j].dist })
+	if len(driverDists) > policy.HaversineCandidatesCount {
+		driverDists = driverDists[:policy.HaversineCandidatesCount]
+	}
+	if len(driverDists) == 0 {
+		return 0, nil, nil
+	}
+
+	origins := make([]string, 0, len(driverDists))
+	for _, dd := range driverDists {
+		origins = append(origins, fmt.Sprintf("%s,%s", dd.driver.Lat, dd.driver.Lng))
+	}
+	routes, err := s.estimator.DirectionsMatrixInverse(ctx, origins, fmt.Sprintf("%f,%f", pickupLat, pickupLng))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var candidates []*background.RankingCandidate
+	for i, route := range routes {
+		if i >= len(driverDists) {
+			break
+		}
+		if policy.EnableMaxEstimatedTimeMins && route.TimeInMinutes > policy.MaxEstimatedTimeMins {
+			continue
+		}
+		candidates = append(candidates, &background.RankingCandidate{
+			Driver:      driverDists[i].driver,
+			HaversineKm: driverDists[i].dist,
+			EtaMins:     route.TimeInMinutes,
+			DistanceKm:  route.DistanceKm,
+			DistanceStr: route.Distance,
+		})
+	}
+
+	// 備份沒有司機當日閒置與收入資料，公平派單策略只依預估時間排序
+	candidates = strategy.Rank(order, candidates, orderTime)
+	if len(candidates) > policy.GoogleAPICandidatesCount {
+		candidates = candidates[:policy.GoogleAPICandidatesCount]
+	}
+	return len(driverDists), candidates, nil
+}
+
+// printReport 依車隊輸出模擬結果與歷史實際結果的對照
+func printReport(name string, outcomes []orderOutcome) {
+	byFleet := make(map[string][]orderOutcome)
+	var fleets []string
+	for _, o := range outcomes {
+		key := string(o.Fleet)
+		if _, ok := byFleet[key]; !ok {
+			fleets = append(fleets, key)
+		}
+		byFleet[key] = append(byFleet[key], o)
+	}
+	sort.Strings(fleets)
+	if len(fleets) > 1 {
+		fleets = append(fleets, "全部")
+		byFleet["全部"] = outcomes
+	}
+
+	fmt.Printf("\n📊 策略：%s\n", name)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "車隊\t訂單數\t模擬流單率\t區域拒絕\t實際流單率\t直線候選\t最終候選\tETA平均\tETA P50\tETA P90\t實際ETA平均\t各輪派出\t")
+	for _, fleet := range fleets {
+		group := byFleet[fleet]
+		var failed, zoneRejected, actualFailed, haversineSum, finalSum int
+		var etas, actualEtas []int
+		roundHits := make(map[int]int)
+		maxRound := 0
+		for _, o := range group {
+			if o.ZoneRejected {
+				zoneRejected++
+			}
+			if o.Failed {
+				failed++
+			} else {
+				etas = append(etas, o.EtaMins)
+				roundHits[o.Round]++
+				if o.Round > maxRound {
+					maxRound = o.Round
+				}
+			}
+			if o.ActualFailed {
+				actualFailed++
+			} else if o.ActualEtaMins > 0 {
+				actualEtas = append(actualEtas, o.ActualEtaMins)
+			}
+			haversineSum += o.HaversineCandidates
+			finalSum += o.FinalCandidates
+		}
+
+		var rounds []string
+		for r := 1; r <= maxRound; r++ {
+			rounds = append(rounds, strconv.Itoa(roundHits[r]))
+		}
+
+		total := len(group)
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%d\t%.1f%%\t%.1f\t%.1f\t%s\t%s\t%s\t%s\t%s\t\n",
+			fleet, total,
+			percent(failed, total), zoneRejected, percent(actualFailed, total),
+			float64(haversineSum)/float64(total), float64(finalSum)/float64(total),
+			formatMins(mean(etas)), formatMins(percentile(etas, 50)), formatMins(percentile(etas, 90)),
+			formatMins(mean(actualEtas)), strings.Join(rounds, "/"))
+	}
+	w.Flush()
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+func mean(values []int) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return float64(sum) / float64(len(values))
+}
+
+// percentile 以最近排名法計算百分位數
+func percentile(values []int, p int) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	idx := int(math.Ceil(float64(p)/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return float64(sorted[idx])
+}
+
+func formatMins(mins float64) string {
+	if math.IsNaN(mins) {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f分", mins)
+}
@@ -6,8 +6,10 @@ import (
 	"io/ioutil"
 	"log"
 	"strings"
+	"time"
 
 	"right-backend/infra"
+	"right-backend/model"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,6 +22,9 @@ type Config struct {
 		URI      string `yaml:"uri"`
 		Database string `yaml:"database"`
 	} `yaml:"mongodb"`
+	DriverLocation struct {
+		RetentionDays int `yaml:"retention_days"`
+	} `yaml:"driver_location"`
 }
 
 func main() {
@@ -74,6 +79,15 @@ func main() {
 	fmt.Println("   • service 層的複合條件過濾")
 	fmt.Println()
 
+	// 司機位置歷史需在寫入前建立為時間序列集合
+	retentionDays := cfg.DriverLocation.RetentionDays
+	if retentionDays <= 0 {
+		retentionDays = model.DefaultDriverLocationRetentionDays
+	}
+	if err := setupDriverLocations(ctx, mongoDB, retentionDays); err != nil {
+		fmt.Printf("⚠️  設定 driver_locations 集合失敗: %v\n", err)
+	}
+
 	// 創建索引
 	if err := createOptimizedIndexes(ctx, mongoDB); err != nil {
 		log.Fatalf("❌ 創建索引失敗: %v", err)
@@ -143,6 +157,44 @@ func createOptimizedIndexes(ctx context.Context, mongoDB *infra.MongoDB) error {
 			Options: options.Index().SetName("customer_group_query"),
 		},
 
+		// 【客群月結請款】- 支援依客群、完成時間彙總完成訂單
+		{
+			Keys: bson.D{
+				{Key: "customer_group", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "completion_time", Value: 1},
+			},
+			Options: options.Index().SetName("customer_group_invoice_query"),
+		},
+
+		// 【乘客訂單歷史】- 支援依乘客或 LINE 用戶ID 查詢最近訂單
+		{
+			Keys: bson.D{
+				{Key: "passenger_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetName("passenger_orders_query"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "customer.line_user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetName("line_user_orders_query"),
+		},
+
+		// 【週期性預約索引】- 同一系列同一天只會產生一張預約單
+		{
+			Keys: bson.D{
+				{Key: "recurring_order_id", Value: 1},
+				{Key: "occurrence_date", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"recurring_order_id": bson.M{"$exists": true}}).
+				SetName("recurring_occurrence_unique"),
+		},
+
 		// 【地理位置索引】- 支援地址相關查詢
 		{
 			Keys: bson.D{
@@ -205,6 +257,12 @@ func createOptimizedIndexes(ctx context.Context, mongoDB *infra.MongoDB) error {
 			Options: options.Index().SetName("driver_location_query"),
 		},
 
+		// 【司機地理位置索引】- 支援 dispatcher 以 $geoNear 依距離查詢候選司機
+		{
+			Keys:    bson.D{{Key: "location", Value: "2dsphere"}},
+			Options: options.Index().SetName("driver_location_2dsphere"),
+		},
+
 		// 【管理查詢索引】- 支援後台司機管理
 		{
 			Keys: bson.D{
@@ -288,6 +346,75 @@ func createIndexesSafely(ctx context.Context, collection *mongo.Collection, inde
 	return nil
 }
 
+// setupDriverLocations 建立司機位置歷史集合並套用保留天數
+// 軌跡只有新增與依司機、時間區間查詢，適合時間序列集合（依 driver_id 分桶壓縮儲存，以 expireAfterSeconds 自動清除）；
+// 已存在的一般集合（舊版以 TTL 索引清除）不會自動轉換，改為更新 TTL 索引的過期秒數
+func setupDriverLocations(ctx context.Context, mongoDB *infra.MongoDB, retentionDays int) error {
+	fmt.Printf("🎯 設定 driver_locations 集合（保留 %d 天）...\n", retentionDays)
+	expireAfterSeconds := int64(retentionDays) * 24 * 3600
+	collection := mongoDB.GetCollection("driver_locations")
+
+	specs, err := mongoDB.Database.ListCollectionSpecifications(ctx, bson.M{"name": "driver_locations"})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(specs) == 0:
+		opts := options.CreateCollection().
+			SetTimeSeriesOptions(options.TimeSeries().
+				SetTimeField("recorded_at").
+				SetMetaField("driver_id").
+				SetGranularity("seconds")).
+			SetExpireAfterSeconds(expireAfterSeconds)
+		if err := mongoDB.Database.CreateCollection(ctx, "driver_locations", opts); err != nil {
+			return err
+		}
+		fmt.Println("   ✅ 時間序列集合 driver_locations 創建成功")
+	case specs[0].Type == "timeseries":
+		if err := mongoDB.Database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: "driver_locations"},
+			{Key: "expireAfterSeconds", Value: expireAfterSeconds},
+		}).Err(); err != nil {
+			return err
+		}
+		fmt.Println("   ✅ 時間序列集合 driver_locations 保留天數已更新")
+	default:
+		fmt.Println("   ⚠️  driver_locations 為一般集合，沿用 TTL 索引清除過期資料（如需轉為時間序列集合請先搬移資料後刪除集合再執行）")
+		ttlIndex := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "recorded_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(int32(expireAfterSeconds)).SetName("idx_driver_locations_ttl"),
+			},
+		}
+		if err := createIndexesSafely(ctx, collection, ttlIndex, "driver_locations"); err != nil {
+			return err
+		}
+		if err := mongoDB.Database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: "driver_locations"},
+			{Key: "index", Value: bson.D{
+				{Key: "name", Value: "idx_driver_locations_ttl"},
+				{Key: "expireAfterSeconds", Value: expireAfterSeconds},
+			}},
+		}).Err(); err != nil {
+			return err
+		}
+	}
+
+	// 司機位置歷史軌跡查詢
+	driverLocationIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "driver_id", Value: 1}, {Key: "recorded_at", Value: 1}},
+			Options: options.Index().SetName("idx_driver_locations_driver_recorded"),
+		},
+	}
+	if err := createIndexesSafely(ctx, collection, driverLocationIndexes, "driver_locations"); err != nil {
+		return err
+	}
+	fmt.Println("✅ driver_locations 集合設定完成")
+	return nil
+}
+
 // createSupportingIndexes 創建其他支援性索引
 func createSupportingIndexes(ctx context.Context, mongoDB *infra.MongoDB) error {
 	fmt.Println("🎯 創建支援性索引...")
@@ -343,12 +470,339 @@ func createSupportingIndexes(ctx context.Context, mongoDB *infra.MongoDB) error
 		fmt.Println("✅ order_logs 集合索引創建完成")
 	}
 
+	// Dispatch Policies 集合索引 - 每個車隊只有一筆派單策略
+	dispatchPoliciesCollection := mongoDB.GetCollection("dispatch_policies")
+	dispatchPolicyIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "fleet", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("unique_dispatch_policy_fleet"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, dispatchPoliciesCollection, dispatchPolicyIndexes, "dispatch_policies"); err != nil {
+		fmt.Printf("⚠️  創建 dispatch_policies 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ dispatch_policies 集合索引創建完成")
+	}
+
+	// Dead Letter Orders 集合索引 - 死信訂單依狀態與時間查詢
+	deadLetterOrdersCollection := mongoDB.GetCollection("dead_letter_orders")
+	deadLetterOrderIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_dead_letter_status_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "order_id", Value: 1}},
+			Options: options.Index().SetName("idx_dead_letter_order_id"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, deadLetterOrdersCollection, deadLetterOrderIndexes, "dead_letter_orders"); err != nil {
+		fmt.Printf("⚠️  創建 dead_letter_orders 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ dead_letter_orders 集合索引創建完成")
+	}
+
+	// Service Zones 集合索引 - 依上車點查詢所在服務區域
+	serviceZonesCollection := mongoDB.GetCollection("service_zones")
+	serviceZoneIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "area", Value: "2dsphere"}},
+			Options: options.Index().SetName("idx_service_zones_area_2dsphere"),
+		},
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetName("idx_service_zones_name"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, serviceZonesCollection, serviceZoneIndexes, "service_zones"); err != nil {
+		fmt.Printf("⚠️  創建 service_zones 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ service_zones 集合索引創建完成")
+	}
+
+	// Fleets 集合索引 - 車隊代碼唯一
+	fleetsCollection := mongoDB.GetCollection("fleets")
+	fleetIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_fleets_code_unique"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, fleetsCollection, fleetIndexes, "fleets"); err != nil {
+		fmt.Printf("⚠️  創建 fleets 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ fleets 集合索引創建完成")
+	}
+
+	// 寫入預設車隊（僅在集合為空時），與登錄機制上線前寫死的車隊規則一致
+	if err := seedDefaultFleets(ctx, fleetsCollection); err != nil {
+		fmt.Printf("⚠️  寫入預設車隊失敗: %v\n", err)
+	}
+
+	// Tariffs 集合索引 - 同一車隊與客群只能有一組費率
+	tariffsCollection := mongoDB.GetCollection("tariffs")
+	tariffIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "fleet", Value: 1}, {Key: "customer_group", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_tariffs_fleet_customer_group_unique"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, tariffsCollection, tariffIndexes, "tariffs"); err != nil {
+		fmt.Printf("⚠️  創建 tariffs 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ tariffs 集合索引創建完成")
+	}
+
+	// Settlements 集合索引 - 同一司機同一期間只能有一張結算單，同一訂單只能列入一張結算單
+	settlementsCollection := mongoDB.GetCollection("settlements")
+	settlementIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "driver_id", Value: 1}, {Key: "period_start", Value: 1}, {Key: "period_end", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_settlements_driver_period_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "period_start", Value: -1}},
+			Options: options.Index().SetName("idx_settlements_status_period"),
+		},
+		{
+			Keys:    bson.D{{Key: "fleet", Value: 1}, {Key: "period_start", Value: -1}},
+			Options: options.Index().SetName("idx_settlements_fleet_period"),
+		},
+		// 同一訂單只能列入一張結算單（多鍵唯一索引只限制不同結算單之間）
+		{
+			Keys: bson.D{{Key: "lines.order_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"lines.order_id": bson.M{"$exists": true}}).
+				SetName("idx_settlements_lines_order_id_unique"),
+		},
+	}
+
+	// 舊版的訂單明細索引不是唯一索引，先移除再建立唯一索引
+	if _, err := settlementsCollection.Indexes().DropOne(ctx, "idx_settlements_lines_order_id"); err == nil {
+		fmt.Println("   ✅ 已移除舊版 settlements 訂單明細索引")
+	}
+
+	if err := createIndexesSafely(ctx, settlementsCollection, settlementIndexes, "settlements"); err != nil {
+		fmt.Printf("⚠️  創建 settlements 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ settlements 集合索引創建完成")
+	}
+
+	// Settlement audit logs 集合索引 - 依結算單查詢稽核紀錄
+	settlementAuditCollection := mongoDB.GetCollection("settlement_audit_logs")
+	settlementAuditIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "settlement_id", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("idx_settlement_audit_logs_settlement_created"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, settlementAuditCollection, settlementAuditIndexes, "settlement_audit_logs"); err != nil {
+		fmt.Printf("⚠️  創建 settlement_audit_logs 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ settlement_audit_logs 集合索引創建完成")
+	}
+
+	// Customer groups 集合索引 - 客群代碼唯一
+	customerGroupsCollection := mongoDB.GetCollection("customer_groups")
+	customerGroupIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_customer_groups_code_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "monthly_billing", Value: 1}},
+			Options: options.Index().SetName("idx_customer_groups_monthly_billing"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, customerGroupsCollection, customerGroupIndexes, "customer_groups"); err != nil {
+		fmt.Printf("⚠️  創建 customer_groups 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ customer_groups 集合索引創建完成")
+	}
+
+	// Passengers 集合索引 - LINE 用戶ID唯一（手動建立的乘客可不填）
+	passengersCollection := mongoDB.GetCollection("passengers")
+	passengerIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "line_user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true).SetName("idx_passengers_line_user_id_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "last_order_at", Value: -1}},
+			Options: options.Index().SetName("idx_passengers_last_order_at"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, passengersCollection, passengerIndexes, "passengers"); err != nil {
+		fmt.Printf("⚠️  創建 passengers 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ passengers 集合索引創建完成")
+	}
+
+	// Recurring Orders 集合索引 - 背景排程依狀態查詢啟用中的系列
+	recurringOrdersCollection := mongoDB.GetCollection("recurring_orders")
+	recurringOrderIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}},
+			Options: options.Index().SetName("idx_recurring_orders_status"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_recurring_orders_created_at"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, recurringOrdersCollection, recurringOrderIndexes, "recurring_orders"); err != nil {
+		fmt.Printf("⚠️  創建 recurring_orders 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ recurring_orders 集合索引創建完成")
+	}
+
+	// Order events 集合索引 - 依訂單查詢時間軸、依追蹤ID查詢同一請求的事件
+	orderEventsCollection := mongoDB.GetCollection("order_events")
+	orderEventIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "order_id", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("idx_order_events_order_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "trace_id", Value: 1}},
+			Options: options.Index().SetName("idx_order_events_trace_id").SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "actor.type", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_order_events_actor_created"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, orderEventsCollection, orderEventIndexes, "order_events"); err != nil {
+		fmt.Printf("⚠️  創建 order_events 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ order_events 集合索引創建完成")
+	}
+
+	// Auth sessions 集合索引 - 依 refresh token 雜湊換發、依登入者列出 session、到期後自動刪除
+	authSessionsCollection := mongoDB.GetCollection("auth_sessions")
+	authSessionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "refresh_token_hash", Value: 1}},
+			Options: options.Index().SetName("idx_auth_sessions_refresh_token_hash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "previous_refresh_token_hash", Value: 1}},
+			Options: options.Index().SetName("idx_auth_sessions_previous_refresh_token_hash").SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "subject_type", Value: 1}, {Key: "subject_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_auth_sessions_subject_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "revoked_at", Value: 1}},
+			Options: options.Index().SetName("idx_auth_sessions_revoked_at").SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("idx_auth_sessions_ttl"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, authSessionsCollection, authSessionIndexes, "auth_sessions"); err != nil {
+		fmt.Printf("⚠️  創建 auth_sessions 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ auth_sessions 集合索引創建完成")
+	}
+
+	// 創建 driver_devices 集合的索引
+	driverDevicesCollection := mongoDB.GetCollection("driver_devices")
+	driverDeviceIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "driver_id", Value: 1}, {Key: "device_id", Value: 1}},
+			Options: options.Index().SetName("idx_driver_devices_driver_device").SetUnique(true),
+		},
+		{
+			// 每位司機同時只能有一台已綁定的設備
+			Keys: bson.D{{Key: "driver_id", Value: 1}},
+			Options: options.Index().SetName("idx_driver_devices_bound_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": model.DriverDeviceStatusBound}),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "fleet", Value: 1}, {Key: "updated_at", Value: -1}},
+			Options: options.Index().SetName("idx_driver_devices_status_fleet"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, driverDevicesCollection, driverDeviceIndexes, "driver_devices"); err != nil {
+		fmt.Printf("⚠️  創建 driver_devices 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ driver_devices 集合索引創建完成")
+	}
+
+	// 創建 login_lockout_events 集合的索引
+	loginLockoutEventsCollection := mongoDB.GetCollection("login_lockout_events")
+	loginLockoutEventIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_login_lockout_events_created_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "account", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_login_lockout_events_account"),
+		},
+		{
+			Keys:    bson.D{{Key: "ip", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_login_lockout_events_ip"),
+		},
+		{
+			Keys:    bson.D{{Key: "scope", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_login_lockout_events_scope"),
+		},
+	}
+
+	if err := createIndexesSafely(ctx, loginLockoutEventsCollection, loginLockoutEventIndexes, "login_lockout_events"); err != nil {
+		fmt.Printf("⚠️  創建 login_lockout_events 索引失敗: %v\n", err)
+	} else {
+		fmt.Println("✅ login_lockout_events 集合索引創建完成")
+	}
+
+	return nil
+}
+
+// seedDefaultFleets fleets 集合為空時寫入預設車隊
+func seedDefaultFleets(ctx context.Context, collection *mongo.Collection) error {
+	count, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		fmt.Printf("ℹ️  fleets 集合已有 %d 個車隊，略過預設車隊\n", count)
+		return nil
+	}
+
+	now := time.Now()
+	var docs []interface{}
+	for _, fleet := range model.DefaultFleets() {
+		fleet.UpdatedBy = "init"
+		fleet.CreatedAt = &now
+		fleet.UpdatedAt = &now
+		docs = append(docs, fleet)
+	}
+	if _, err := collection.InsertMany(ctx, docs); err != nil {
+		return err
+	}
+	fmt.Printf("✅ 已寫入 %d 個預設車隊\n", len(docs))
 	return nil
 }
 
 // printIndexInfo 顯示各集合的索引資訊
 func printIndexInfo(ctx context.Context, mongoDB *infra.MongoDB) error {
-	collections := []string{"orders", "drivers", "users", "order_logs"}
+	collections := []string{"orders", "drivers", "users", "order_logs", "dispatch_policies", "dead_letter_orders", "driver_locations", "service_zones", "fleets", "tariffs", "settlements", "settlement_audit_logs", "customer_groups", "passengers", "recurring_orders", "order_events", "auth_sessions", "driver_devices", "login_lockout_events"}
 
 	fmt.Println("\n📊 索引創建報告:")
 	fmt.Println(strings.Repeat("=", 60))
@@ -0,0 +1,127 @@
+// migrate-driver-location 為既有司機資料補上 GeoJSON location 欄位
+//
+// 調度中心改以 drivers.location 的 2dsphere 索引查詢候選司機，未補上 location 的司機在重新回報位置前不會被派單。
+// 請在部署新版調度中心前執行本工具，再執行 cmd/init 建立索引：
+//
+//	go run ./cmd/migrate-driver-location [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	MongoDB struct {
+		URI      string `yaml:"uri"`
+		Database string `yaml:"database"`
+	} `yaml:"mongodb"`
+}
+
+const batchSize = 500
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只統計需要遷移的司機數量，不寫入資料庫")
+	flag.Parse()
+
+	// 讀取配置 - 自動尋找配置檔位置
+	configPaths := []string{"config.yml", "../config.yml", "../../config.yml"}
+
+	var configData []byte
+	var err error
+	var usedPath string
+	for _, path := range configPaths {
+		configData, err = os.ReadFile(path)
+		if err == nil {
+			usedPath = path
+			break
+		}
+	}
+	if err != nil {
+		log.Fatalf("❌ 無法找到 config.yml 配置檔，已嘗試路徑: %v", configPaths)
+	}
+	fmt.Printf("✅ 找到配置檔: %s\n", usedPath)
+
+	var cfg Config
+	if err := yaml.Unmarshal(configData, &cfg); err != nil {
+		log.Fatalf("❌ 解析 config.yml 失敗: %v", err)
+	}
+
+	mongoDB, err := infra.NewMongoDB(infra.MongoConfig{URI: cfg.MongoDB.URI, Database: cfg.MongoDB.Database})
+	if err != nil {
+		log.Fatalf("❌ 連接 MongoDB 失敗: %v", err)
+	}
+	defer mongoDB.Close(context.Background())
+
+	ctx := context.Background()
+	driversColl := mongoDB.GetCollection("drivers")
+
+	// 只處理尚未有 location 且有經緯度的司機，可重複執行
+	filter := bson.M{
+		"location": bson.M{"$exists": false},
+		"lat":      bson.M{"$nin": bson.A{"", nil}},
+		"lng":      bson.M{"$nin": bson.A{"", nil}},
+	}
+	cursor, err := driversColl.Find(ctx, filter)
+	if err != nil {
+		log.Fatalf("❌ 查詢司機失敗: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var migrated, invalid int
+	var writes []mongo.WriteModel
+	flush := func() {
+		if len(writes) == 0 || *dryRun {
+			writes = writes[:0]
+			return
+		}
+		result, err := driversColl.BulkWrite(ctx, writes)
+		if err != nil {
+			log.Fatalf("❌ 批次更新司機位置失敗: %v", err)
+		}
+		fmt.Printf("  ➡️  已更新 %d 名司機\n", result.ModifiedCount)
+		writes = writes[:0]
+	}
+
+	for cursor.Next(ctx) {
+		var driver model.DriverInfo
+		if err := cursor.Decode(&driver); err != nil {
+			log.Fatalf("❌ 解析司機資料失敗: %v", err)
+		}
+
+		location, ok := model.ParseGeoPoint(driver.Lat, driver.Lng)
+		if !ok {
+			invalid++
+			fmt.Printf("⚠️  司機 %s (%s) 經緯度無效: %s,%s，略過\n", driver.Name, driver.CarPlate, driver.Lat, driver.Lng)
+			continue
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": driver.ID, "location": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"location": location}}))
+		migrated++
+		if len(writes) >= batchSize {
+			flush()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Fatalf("❌ 讀取司機資料失敗: %v", err)
+	}
+	flush()
+
+	if *dryRun {
+		fmt.Printf("🔍 試跑完成：%d 名司機需要補上 location，%d 名經緯度無效\n", migrated, invalid)
+		return
+	}
+	fmt.Printf("🎉 遷移完成：%d 名司機已補上 location，%d 名經緯度無效（重新回報位置後會自動補上）\n", migrated, invalid)
+}
@@ -0,0 +1,141 @@
+// migrate-passwords 將 users 與 drivers 集合中的明文密碼改為 bcrypt 雜湊
+//
+// 新版登入改以雜湊比對密碼，仍為明文的帳號會在下次登入成功後自動升級；本工具一次遷移所有尚未登入的帳號，
+// 遷移後資料庫不再保留任何明文密碼。可重複執行，已雜湊的密碼會略過：
+//
+//	go run ./cmd/migrate-passwords [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"right-backend/auth"
+	"right-backend/infra"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	MongoDB struct {
+		URI      string `yaml:"uri"`
+		Database string `yaml:"database"`
+	} `yaml:"mongodb"`
+}
+
+const batchSize = 100
+
+// account 遷移時只讀取帳號與密碼欄位
+type account struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Account  string             `bson:"account"`
+	Password string             `bson:"password"`
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只統計需要遷移的帳號數量，不寫入資料庫")
+	flag.Parse()
+
+	// 讀取配置 - 自動尋找配置檔位置
+	configPaths := []string{"config.yml", "../config.yml", "../../config.yml"}
+
+	var configData []byte
+	var err error
+	var usedPath string
+	for _, path := range configPaths {
+		configData, err = os.ReadFile(path)
+		if err == nil {
+			usedPath = path
+			break
+		}
+	}
+	if err != nil {
+		log.Fatalf("❌ 無法找到 config.yml 配置檔，已嘗試路徑: %v", configPaths)
+	}
+	fmt.Printf("✅ 找到配置檔: %s\n", usedPath)
+
+	var cfg Config
+	if err := yaml.Unmarshal(configData, &cfg); err != nil {
+		log.Fatalf("❌ 解析 config.yml 失敗: %v", err)
+	}
+
+	mongoDB, err := infra.NewMongoDB(infra.MongoConfig{URI: cfg.MongoDB.URI, Database: cfg.MongoDB.Database})
+	if err != nil {
+		log.Fatalf("❌ 連接 MongoDB 失敗: %v", err)
+	}
+	defer mongoDB.Close(context.Background())
+
+	ctx := context.Background()
+	for _, collName := range []string{"users", "drivers"} {
+		migrated, skipped := migrateCollection(ctx, mongoDB.GetCollection(collName), *dryRun)
+		if *dryRun {
+			fmt.Printf("🔍 %s 試跑完成：%d 個帳號需要雜湊密碼，%d 個已雜湊\n", collName, migrated, skipped)
+			continue
+		}
+		fmt.Printf("🎉 %s 遷移完成：%d 個帳號已雜湊密碼，%d 個原本已雜湊\n", collName, migrated, skipped)
+	}
+}
+
+// migrateCollection 雜湊集合中所有明文密碼，回傳遷移與略過（已雜湊）的帳號數
+func migrateCollection(ctx context.Context, coll *mongo.Collection, dryRun bool) (migrated, skipped int) {
+	filter := bson.M{"password": bson.M{"$nin": bson.A{"", nil}}}
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		log.Fatalf("❌ 查詢 %s 失敗: %v", coll.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var writes []mongo.WriteModel
+	flush := func() {
+		if len(writes) == 0 || dryRun {
+			writes = writes[:0]
+			return
+		}
+		result, err := coll.BulkWrite(ctx, writes)
+		if err != nil {
+			log.Fatalf("❌ 批次更新 %s 密碼失敗: %v", coll.Name(), err)
+		}
+		fmt.Printf("  ➡️  %s 已更新 %d 個帳號\n", coll.Name(), result.ModifiedCount)
+		writes = writes[:0]
+	}
+
+	for cursor.Next(ctx) {
+		var acc account
+		if err := cursor.Decode(&acc); err != nil {
+			log.Fatalf("❌ 解析 %s 資料失敗: %v", coll.Name(), err)
+		}
+		if auth.IsPasswordHash(acc.Password) {
+			skipped++
+			continue
+		}
+
+		migrated++
+		if dryRun {
+			continue
+		}
+
+		hashed, err := auth.HashPassword(acc.Password)
+		if err != nil {
+			log.Fatalf("❌ 帳號 %s 密碼雜湊失敗: %v", acc.Account, err)
+		}
+		// 以原密碼為條件，避免覆蓋執行期間登入升級或修改過的密碼
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": acc.ID, "password": acc.Password}).
+			SetUpdate(bson.M{"$set": bson.M{"password": hashed}}))
+		if len(writes) >= batchSize {
+			flush()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Fatalf("❌ 讀取 %s 資料失敗: %v", coll.Name(), err)
+	}
+	flush()
+
+	return migrated, skipped
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"right-backend/data-models/admin"
 	"right-backend/data-models/common"
+	"right-backend/middleware"
+	"right-backend/model"
 	"right-backend/service"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -11,18 +13,22 @@ import (
 )
 
 type AdminController struct {
-	logger        zerolog.Logger
-	driverService *service.DriverService
+	logger         zerolog.Logger
+	driverService  *service.DriverService
+	authMiddleware *middleware.UserAuthMiddleware
 }
 
-func NewAdminController(logger zerolog.Logger, driverService *service.DriverService) *AdminController {
+func NewAdminController(logger zerolog.Logger, driverService *service.DriverService, authMiddleware *middleware.UserAuthMiddleware) *AdminController {
 	return &AdminController{
-		logger:        logger.With().Str("module", "admin_controller").Logger(),
-		driverService: driverService,
+		logger:         logger.With().Str("module", "admin_controller").Logger(),
+		driverService:  driverService,
+		authMiddleware: authMiddleware,
 	}
 }
 
 func (c *AdminController) RegisterRoutes(api huma.API) {
+	security := []map[string][]string{{"bearerAuth": {}}}
+
 	// 獲取司機審核列表
 	huma.Register(api, huma.Operation{
 		OperationID: "get-approval-driver",
@@ -31,6 +37,9 @@ func (c *AdminController) RegisterRoutes(api huma.API) {
 		Summary:     "獲取司機審核列表",
 		Description: "獲取司機列表，支援車隊過濾、模糊搜尋和審核狀態過濾，帶分頁功能",
 		Tags:        []string{"admin"},
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
 	}, func(ctx context.Context, input *admin.GetApprovalDriverInput) (*admin.PaginatedDriversResponse, error) {
 		drivers, totalCount, err := c.driverService.GetDriversForApproval(
 			ctx,
@@ -66,6 +75,9 @@ func (c *AdminController) RegisterRoutes(api huma.API) {
 		Summary:     "審核司機",
 		Description: "將指定司機的審核狀態設為已審核（is_approved = true），並啟用司機帳號",
 		Tags:        []string{"admin"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
 	}, func(ctx context.Context, input *admin.ApproveDriverInput) (*admin.SimpleResponse, error) {
 		updatedDriver, err := c.driverService.ApproveDriver(ctx, input.DriverID)
 		if err != nil {
@@ -97,6 +109,9 @@ func (c *AdminController) RegisterRoutes(api huma.API) {
 		Summary:     "管理員更新司機個人資料",
 		Description: "管理員更新指定司機的個人資料，包含基本資料、車輛資訊、車隊、狀態等",
 		Tags:        []string{"admin"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
 	}, func(ctx context.Context, input *admin.UpdateDriverProfileInput) (*admin.UpdateDriverProfileResponse, error) {
 		updatedDriver, err := c.driverService.AdminUpdateDriverProfile(ctx, input.DriverID, input)
 		if err != nil {
@@ -127,6 +142,9 @@ func (c *AdminController) RegisterRoutes(api huma.API) {
 		Summary:     "管理員讓司機退出車隊",
 		Description: "將指定司機從車隊中移除，設定車隊欄位為空白",
 		Tags:        []string{"admin"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
 	}, func(ctx context.Context, input *admin.RemoveDriverFromFleetInput) (*admin.UpdateDriverProfileResponse, error) {
 		updatedDriver, err := c.driverService.RemoveDriverFromFleet(ctx, input.DriverID)
 
@@ -2,28 +2,39 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"right-backend/data-models/auth"
 	"right-backend/data-models/common"
 	"right-backend/infra"
+	"right-backend/middleware"
 	"right-backend/model"
 	"right-backend/service"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type AuthController struct {
-	logger        zerolog.Logger
-	userService   *service.UserService
-	driverService *service.DriverService
+	logger              zerolog.Logger
+	userService         *service.UserService
+	driverService       *service.DriverService
+	sessionService      *service.AuthSessionService
+	deviceService       *service.DriverDeviceService
+	loginLimiter        *service.LoginLimiterService
+	rateLimitMiddleware *middleware.LoginRateLimitMiddleware
 }
 
-func NewAuthController(logger zerolog.Logger, userService *service.UserService, driverService *service.DriverService) *AuthController {
+func NewAuthController(logger zerolog.Logger, userService *service.UserService, driverService *service.DriverService, sessionService *service.AuthSessionService, deviceService *service.DriverDeviceService, loginLimiter *service.LoginLimiterService, rateLimitMiddleware *middleware.LoginRateLimitMiddleware) *AuthController {
 	return &AuthController{
-		logger:        logger.With().Str("module", "auth_controller").Logger(),
-		userService:   userService,
-		driverService: driverService,
+		logger:              logger.With().Str("module", "auth_controller").Logger(),
+		userService:         userService,
+		driverService:       driverService,
+		sessionService:      sessionService,
+		deviceService:       deviceService,
+		loginLimiter:        loginLimiter,
+		rateLimitMiddleware: rateLimitMiddleware,
 	}
 }
 
@@ -34,7 +45,9 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 		Method:      "POST",
 		Path:        "/auth/login",
 		Summary:     "用戶登入",
+		Description: "帳號或來源 IP 登入失敗次數過多時暫時鎖定，鎖定期間回傳 429 與 Retry-After",
 		Tags:        []string{"auth"},
+		Middlewares: huma.Middlewares{c.rateLimitMiddleware.Limit(model.TokenTypeUser)},
 	}, func(ctx context.Context, input *auth.LoginInput) (*auth.UserLoginResponse, error) {
 		// 獲取當前 span
 		span := trace.SpanFromContext(ctx)
@@ -51,8 +64,17 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 			infra.AttrString("account", input.Body.Account),
 		)
 
-		user, token, err := c.userService.Login(authCtx, input.Body.Account, input.Body.Password)
+		if err := c.checkAccountLock(authCtx, model.TokenTypeUser, input.Body.Account); err != nil {
+			infra.SetAttributes(authSpan, infra.AttrErrorType("login_locked"))
+			return nil, err
+		}
+
+		user, err := c.userService.Login(authCtx, input.Body.Account, input.Body.Password)
 		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, service.ErrInvalidCredentials) {
+				c.recordLoginFailure(authCtx, model.TokenTypeUser, input.Body.Account)
+			}
+
 			// 記錄錯誤到 span
 			infra.RecordError(authSpan, err, "User login failed",
 				infra.AttrString("account", input.Body.Account),
@@ -72,6 +94,20 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 				Msg("用戶登入失敗 - 帳號或密碼錯誤")
 			return nil, huma.Error401Unauthorized("帳號或密碼錯誤", err)
 		}
+		c.loginLimiter.RecordSuccess(authCtx, model.TokenTypeUser, input.Body.Account)
+
+		tokens, err := c.sessionService.IssueUserSession(authCtx, user, model.SessionDevice{UserAgent: input.UserAgent})
+		if err != nil {
+			infra.RecordError(authSpan, err, "Issue user session failed",
+				infra.AttrUserID(user.ID.Hex()),
+			)
+			c.logger.Error().
+				Str("用戶編號", user.ID.Hex()).
+				Str("錯誤原因", err.Error()).
+				Str("trace_id", span.SpanContext().TraceID().String()).
+				Msg("用戶登入失敗 - 建立登入 session 失敗")
+			return nil, huma.Error500InternalServerError("系統錯誤，請稍後再試", err)
+		}
 
 		// 添加成功事件
 		infra.AddEvent(authSpan, "user_login_success",
@@ -96,7 +132,10 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 
 		resp := &auth.UserLoginResponse{}
 		resp.Body.User = user
-		resp.Body.Token = token
+		resp.Body.Token = tokens.AccessToken
+		resp.Body.ExpiresAt = tokens.ExpiresAt
+		resp.Body.RefreshToken = tokens.RefreshToken
+		resp.Body.SessionID = tokens.SessionID
 		resp.Body.Message = "登入成功"
 
 		return resp, nil
@@ -108,7 +147,9 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 		Method:      "POST",
 		Path:        "/auth/driver-login",
 		Summary:     "司機登入",
+		Description: "帳號或來源 IP 登入失敗次數過多時暫時鎖定，鎖定期間回傳 429 與 Retry-After",
 		Tags:        []string{"auth"},
+		Middlewares: huma.Middlewares{c.rateLimitMiddleware.Limit(model.TokenTypeDriver)},
 	}, func(ctx context.Context, input *auth.DriverLoginInput) (*auth.DriverLoginResponse, error) {
 		// 獲取當前 span
 		span := trace.SpanFromContext(ctx)
@@ -129,7 +170,12 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 			infra.AttrString("device_model", input.Body.DeviceModelName),
 		)
 
-		driver, token, err := c.driverService.Login(authCtx, input.Body.Account, input.Body.Password, input.Body.DeviceModelName, input.Body.DeviceDeviceName, input.Body.DeviceBrand, input.Body.DeviceManufacturer, input.Body.DeviceAppVersion)
+		if err := c.checkAccountLock(authCtx, model.TokenTypeDriver, input.Body.Account); err != nil {
+			infra.SetAttributes(authSpan, infra.AttrErrorType("login_locked"))
+			return nil, err
+		}
+
+		driver, err := c.driverService.Login(authCtx, input.Body.Account, input.Body.Password, input.Body.DeviceModelName, input.Body.DeviceDeviceName, input.Body.DeviceBrand, input.Body.DeviceManufacturer, input.Body.DeviceAppVersion)
 		if err != nil {
 			// 記錄錯誤到 span
 			infra.RecordError(authSpan, err, "Driver login failed",
@@ -152,9 +198,11 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 			switch err.Error() {
 			case "帳號不存在":
 				infra.SetAttributes(authSpan, infra.AttrErrorType("account_not_found"))
+				c.recordLoginFailure(authCtx, model.TokenTypeDriver, input.Body.Account)
 				return nil, huma.Error401Unauthorized("帳號不存在", err)
 			case "密碼錯誤":
 				infra.SetAttributes(authSpan, infra.AttrErrorType("wrong_password"))
+				c.recordLoginFailure(authCtx, model.TokenTypeDriver, input.Body.Account)
 				return nil, huma.Error401Unauthorized("密碼錯誤", err)
 			case "帳號未啟用":
 				infra.SetAttributes(authSpan, infra.AttrErrorType("account_disabled"))
@@ -164,6 +212,7 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 				return nil, huma.Error500InternalServerError("系統錯誤，請稍後再試", err)
 			}
 		}
+		c.loginLimiter.RecordSuccess(authCtx, model.TokenTypeDriver, input.Body.Account)
 
 		// 添加認證成功事件
 		infra.AddEvent(authSpan, "driver_credentials_verified",
@@ -193,6 +242,69 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 			return nil, huma.Error403Forbidden("您的帳號正在等待管理員審核中！")
 		}
 
+		// 檢查設備綁定：首次登入自動綁定，未綁定的新設備需管理員核准
+		device := model.SessionDevice{
+			DeviceID:     input.Body.DeviceID,
+			ModelName:    input.Body.DeviceModelName,
+			DeviceName:   input.Body.DeviceDeviceName,
+			Brand:        input.Body.DeviceBrand,
+			Manufacturer: input.Body.DeviceManufacturer,
+			AppVersion:   input.Body.DeviceAppVersion,
+			UserAgent:    input.UserAgent,
+		}
+		boundDevice, err := c.deviceService.AuthorizeLoginDevice(authCtx, driver, device)
+		if err != nil {
+			if errors.Is(err, service.ErrDeviceApprovalPending) {
+				infra.SetAttributes(authSpan, infra.AttrErrorType("device_not_bound"))
+				c.logger.Debug().
+					Str("司機編號", driver.ID.Hex()).
+					Str("司機帳號", driver.Account).
+					Str("設備型號", input.Body.DeviceModelName).
+					Str("trace_id", span.SpanContext().TraceID().String()).
+					Msg("司機登入失敗 - 設備尚未綁定")
+				return nil, huma.Error403Forbidden("此設備尚未綁定，已送出綁定申請，請等待管理員核准")
+			}
+			infra.RecordError(authSpan, err, "Authorize driver device failed",
+				infra.AttrDriverID(driver.ID.Hex()),
+			)
+			infra.SetAttributes(authSpan, infra.AttrErrorType("device_error"))
+			c.logger.Error().
+				Str("司機編號", driver.ID.Hex()).
+				Str("錯誤原因", err.Error()).
+				Str("trace_id", span.SpanContext().TraceID().String()).
+				Msg("司機登入失敗 - 檢查設備綁定失敗")
+			return nil, huma.Error500InternalServerError("系統錯誤，請稍後再試", err)
+		}
+		device.DeviceID = boundDevice.DeviceID
+
+		// 審核通過且設備已綁定後才建立登入 session
+		tokens, err := c.sessionService.IssueDriverSession(authCtx, driver, device)
+		if err != nil {
+			infra.RecordError(authSpan, err, "Issue driver session failed",
+				infra.AttrDriverID(driver.ID.Hex()),
+			)
+			infra.SetAttributes(authSpan, infra.AttrErrorType("session_error"))
+			c.logger.Error().
+				Str("司機編號", driver.ID.Hex()).
+				Str("錯誤原因", err.Error()).
+				Str("trace_id", span.SpanContext().TraceID().String()).
+				Msg("司機登入失敗 - 建立登入 session 失敗")
+			return nil, huma.Error500InternalServerError("系統錯誤，請稍後再試", err)
+		}
+
+		// 同一時間只允許一台設備登入，撤銷其他 session 並關閉其 WebSocket 連線
+		if revoked, err := c.sessionService.RevokeSubjectSessionsExcept(authCtx, model.TokenTypeDriver, driver.ID.Hex(), tokens.SessionID, model.SessionRevokeNewDevice); err != nil {
+			c.logger.Error().
+				Str("司機編號", driver.ID.Hex()).
+				Str("錯誤原因", err.Error()).
+				Msg("撤銷司機其他設備的登入 session 失敗")
+		} else if revoked > 0 {
+			infra.AddEvent(authSpan, "driver_other_sessions_revoked",
+				infra.AttrDriverID(driver.ID.Hex()),
+				infra.AttrInt("revoked", revoked),
+			)
+		}
+
 		// 添加最終成功事件
 		infra.AddEvent(authSpan, "driver_login_success",
 			infra.AttrDriverID(driver.ID.Hex()),
@@ -223,12 +335,59 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 
 		resp := &auth.DriverLoginResponse{}
 		resp.Body.Driver = driver
-		resp.Body.Token = token
+		resp.Body.Token = tokens.AccessToken
+		resp.Body.ExpiresAt = tokens.ExpiresAt
+		resp.Body.RefreshToken = tokens.RefreshToken
+		resp.Body.SessionID = tokens.SessionID
 		resp.Body.Message = "登入成功"
 
 		return resp, nil
 	})
 
+	// 換發 access token
+	huma.Register(api, huma.Operation{
+		OperationID: "auth-refresh",
+		Method:      "POST",
+		Path:        "/auth/refresh",
+		Summary:     "換發 access token",
+		Description: "以 refresh token 換發新的 access token，同時輪替 refresh token，舊的 refresh token 即失效。已輪替的 refresh token 再次使用時會撤銷整個 session",
+		Tags:        []string{"auth"},
+	}, func(ctx context.Context, input *auth.RefreshTokenInput) (*auth.RefreshTokenResponse, error) {
+		tokens, err := c.sessionService.Refresh(ctx, input.Body.RefreshToken)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrSessionRevoked) {
+				c.logger.Warn().Str("錯誤原因", err.Error()).Msg("換發 access token 失敗")
+				return nil, huma.Error401Unauthorized(err.Error())
+			}
+			c.logger.Error().Err(err).Msg("換發 access token 失敗")
+			return nil, huma.Error500InternalServerError("系統錯誤，請稍後再試", err)
+		}
+
+		return &auth.RefreshTokenResponse{Body: *tokens}, nil
+	})
+
+	// 登出
+	huma.Register(api, huma.Operation{
+		OperationID: "auth-logout",
+		Method:      "POST",
+		Path:        "/auth/logout",
+		Summary:     "登出",
+		Description: "撤銷 refresh token 所屬的登入 session，該 session 簽發的 access token 立即失效",
+		Tags:        []string{"auth"},
+	}, func(ctx context.Context, input *auth.RefreshTokenInput) (*auth.LogoutResponse, error) {
+		if err := c.sessionService.Logout(ctx, input.Body.RefreshToken); err != nil {
+			if errors.Is(err, service.ErrInvalidRefreshToken) {
+				return nil, huma.Error401Unauthorized(err.Error())
+			}
+			c.logger.Error().Err(err).Msg("登出失敗")
+			return nil, huma.Error500InternalServerError("登出失敗", err)
+		}
+
+		resp := &auth.LogoutResponse{}
+		resp.Body.Message = "已登出"
+		return resp, nil
+	})
+
 	// 司機註冊
 	huma.Register(api, huma.Operation{
 		OperationID: "driver-register",
@@ -418,3 +577,26 @@ func (c *AuthController) RegisterRoutes(api huma.API) {
 		}, nil
 	})
 }
+
+// checkAccountLock 帳號鎖定中時回傳 429
+func (c *AuthController) checkAccountLock(ctx context.Context, loginType model.TokenType, account string) error {
+	var throttled *service.LoginThrottleError
+	if err := c.loginLimiter.CheckAccount(ctx, loginType, account); !errors.As(err, &throttled) {
+		return nil
+	}
+
+	middleware.RecordLoginThrottle(string(loginType), string(throttled.Scope), throttled.Reason)
+	c.logger.Warn().
+		Str("登入類型", string(loginType)).
+		Str("帳號", account).
+		Int("剩餘秒數", throttled.RetryAfterSeconds()).
+		Msg("登入失敗 - 帳號鎖定中")
+	return middleware.LoginThrottledError(throttled)
+}
+
+// recordLoginFailure 記錄帳號密碼錯誤，達上限時鎖定帳號或來源 IP
+func (c *AuthController) recordLoginFailure(ctx context.Context, loginType model.TokenType, account string) {
+	for _, lockout := range c.loginLimiter.RecordFailure(ctx, loginType, account, middleware.ClientIPFromContext(ctx)) {
+		middleware.RecordLoginThrottle(string(loginType), string(lockout.Scope), service.LoginThrottleLockout)
+	}
+}
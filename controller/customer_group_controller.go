@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/customer_group"
+	"right-backend/infra"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type CustomerGroupController struct {
+	logger               zerolog.Logger
+	customerGroupService *service.CustomerGroupService
+	authMiddleware       *middleware.UserAuthMiddleware
+}
+
+func NewCustomerGroupController(logger zerolog.Logger, customerGroupService *service.CustomerGroupService, authMiddleware *middleware.UserAuthMiddleware) *CustomerGroupController {
+	return &CustomerGroupController{
+		logger:               logger.With().Str("module", "customer_group_controller").Logger(),
+		customerGroupService: customerGroupService,
+		authMiddleware:       authMiddleware,
+	}
+}
+
+func (c *CustomerGroupController) RegisterRoutes(api huma.API) {
+	// 列出客群
+	huma.Register(api, huma.Operation{
+		OperationID: "get-customer-groups",
+		Method:      "GET",
+		Path:        "/admin/customer-groups",
+		Summary:     "列出客群",
+		Description: "列出登錄的客群（企業客戶）、聯絡資訊、請款條件與下單限制",
+		Tags:        []string{"customer-groups"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch, model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.ListCustomerGroupsInput) (*customer_group.CustomerGroupListResponse, error) {
+		groups, err := c.customerGroupService.ListGroups(ctx, input.MonthlyOnly)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取客群失敗", err)
+		}
+
+		response := &customer_group.CustomerGroupListResponse{}
+		response.Body.CustomerGroups = groups
+		response.Body.StrictMode = infra.AppConfig.CustomerGroups.Strict
+		return response, nil
+	})
+
+	// 獲取單一客群
+	huma.Register(api, huma.Operation{
+		OperationID: "get-customer-group",
+		Method:      "GET",
+		Path:        "/admin/customer-groups/{code}",
+		Summary:     "獲取客群",
+		Description: "獲取單一客群資料",
+		Tags:        []string{"customer-groups"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch, model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.CustomerGroupCodeInput) (*customer_group.CustomerGroupResponse, error) {
+		group, err := c.customerGroupService.GetGroup(ctx, input.Code)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取客群失敗", err)
+		}
+		if group == nil {
+			return nil, huma.Error404NotFound("找不到客群: " + input.Code)
+		}
+		return &customer_group.CustomerGroupResponse{Body: group}, nil
+	})
+
+	// 新增客群
+	huma.Register(api, huma.Operation{
+		OperationID: "create-customer-group",
+		Method:      "POST",
+		Path:        "/admin/customer-groups",
+		Summary:     "新增客群",
+		Description: "登錄客群（企業客戶），客群代碼為訂單文字 / 前的部分。嚴格模式下只有已登錄且啟用的客群可以建單",
+		Tags:        []string{"customer-groups"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.CreateCustomerGroupInput) (*customer_group.CustomerGroupResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		group := customerGroupFromBody(input.Body.CustomerGroupBody)
+		group.Code = input.Body.Code
+		created, err := c.customerGroupService.CreateGroup(ctx, group, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("code", input.Body.Code).Str("用戶帳號", userFromToken.Account).Msg("新增客群失敗")
+			return nil, huma.Error400BadRequest("新增客群失敗", err)
+		}
+		return &customer_group.CustomerGroupResponse{Body: created}, nil
+	})
+
+	// 更新客群
+	huma.Register(api, huma.Operation{
+		OperationID: "update-customer-group",
+		Method:      "PUT",
+		Path:        "/admin/customer-groups/{code}",
+		Summary:     "更新客群",
+		Description: "更新客群資料與下單限制，客群代碼不可修改",
+		Tags:        []string{"customer-groups"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.UpdateCustomerGroupInput) (*customer_group.CustomerGroupResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		updated, err := c.customerGroupService.UpdateGroup(ctx, input.Code, customerGroupFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("code", input.Code).Str("用戶帳號", userFromToken.Account).Msg("更新客群失敗")
+			return nil, huma.Error400BadRequest("更新客群失敗", err)
+		}
+		return &customer_group.CustomerGroupResponse{Body: updated}, nil
+	})
+
+	// 刪除客群
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-customer-group",
+		Method:      "DELETE",
+		Path:        "/admin/customer-groups/{code}",
+		Summary:     "刪除客群",
+		Description: "刪除客群登錄資料，既有訂單不受影響；嚴格模式下刪除後該客群不可再建單",
+		Tags:        []string{"customer-groups"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.CustomerGroupCodeInput) (*customer_group.SimpleResponse, error) {
+		if err := c.customerGroupService.DeleteGroup(ctx, input.Code); err != nil {
+			return nil, huma.Error400BadRequest("刪除客群失敗", err)
+		}
+
+		response := &customer_group.SimpleResponse{}
+		response.Body.Success = true
+		response.Body.Message = "客群已刪除"
+		return response, nil
+	})
+
+	// 月結請款總覽
+	huma.Register(api, huma.Operation{
+		OperationID: "get-customer-invoice-summary",
+		Method:      "GET",
+		Path:        "/admin/customer-invoices",
+		Summary:     "月結請款總覽",
+		Description: "列出所有月結客群指定月份完成的訂單數與請款金額",
+		Tags:        []string{"customer-groups"},
+		Metadata:    middleware.RequirePermissions(model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.InvoiceSummaryInput) (*customer_group.InvoiceSummaryResponse, error) {
+		summaries, err := c.customerGroupService.MonthlyInvoiceSummary(ctx, input.Month)
+		if err != nil {
+			return nil, huma.Error400BadRequest("獲取月結請款總覽失敗", err)
+		}
+
+		response := &customer_group.InvoiceSummaryResponse{}
+		response.Body.Month = input.Month
+		response.Body.Summaries = summaries
+		return response, nil
+	})
+
+	// 客群月結請款單
+	huma.Register(api, huma.Operation{
+		OperationID: "get-customer-group-invoice",
+		Method:      "GET",
+		Path:        "/admin/customer-groups/{code}/invoices/{month}",
+		Summary:     "客群月結請款單",
+		Description: "列出客群指定月份（依完成時間）所有完成的訂單與車資，車資優先使用完成時計算的實際車資，其次為後台填寫的收入",
+		Tags:        []string{"customer-groups"},
+		Metadata:    middleware.RequirePermissions(model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.InvoiceInput) (*customer_group.InvoiceResponse, error) {
+		invoice, err := c.customerGroupService.MonthlyInvoice(ctx, input.Code, input.Month)
+		if err != nil {
+			return nil, huma.Error400BadRequest("獲取請款單失敗", err)
+		}
+		return &customer_group.InvoiceResponse{Body: invoice}, nil
+	})
+
+	// 下載客群月結請款單
+	huma.Register(api, huma.Operation{
+		OperationID:   "export-customer-group-invoice",
+		Method:        "GET",
+		Path:          "/admin/customer-groups/{code}/invoices/{month}/export",
+		Summary:       "下載客群月結請款單",
+		Description:   "下載客群指定月份的請款單 Excel 檔案",
+		Tags:          []string{"customer-groups"},
+		DefaultStatus: 200,
+		Metadata:      middleware.RequirePermissions(model.PermissionOperationReport),
+		Middlewares:   huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *customer_group.InvoiceInput) (*huma.StreamResponse, error) {
+		f, invoice, err := c.customerGroupService.ExportInvoice(ctx, input.Code, input.Month)
+		if err != nil {
+			c.logger.Error().Err(err).Str("code", input.Code).Str("month", input.Month).Msg("匯出請款單失敗")
+			return nil, huma.Error400BadRequest("匯出請款單失敗", err)
+		}
+
+		filename := "invoice_" + invoice.CustomerGroup.Code + "_" + input.Month + ".xlsx"
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				ctx.SetHeader("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+				ctx.SetHeader("Content-Disposition", "attachment; filename="+filename)
+				if err := f.Write(ctx.BodyWriter()); err != nil {
+					c.logger.Error().Err(err).Msg("寫入 Excel 檔案失敗")
+				}
+			},
+		}, nil
+	})
+}
+
+// customerGroupFromBody 將請求內容轉為客群
+func customerGroupFromBody(body customer_group.CustomerGroupBody) *model.CustomerGroup {
+	return &model.CustomerGroup{
+		Name:                 body.Name,
+		Contact:              body.Contact,
+		Billing:              body.Billing,
+		DefaultFleet:         model.FleetType(strings.ToUpper(body.DefaultFleet)),
+		AllowedLineConfigIDs: body.AllowedLineConfigIDs,
+		MonthlyBilling:       body.MonthlyBilling,
+		Enabled:              body.Enabled,
+		Notes:                body.Notes,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"right-backend/data-models/common"
 	"right-backend/data-models/dashboard"
+	"right-backend/middleware"
 	"right-backend/model"
 	"right-backend/service"
 
@@ -14,16 +15,20 @@ import (
 type DashboardController struct {
 	logger           zerolog.Logger
 	dashboardService *service.DashboardService
+	authMiddleware   *middleware.UserAuthMiddleware
 }
 
-func NewDashboardController(logger zerolog.Logger, dashboardService *service.DashboardService) *DashboardController {
+func NewDashboardController(logger zerolog.Logger, dashboardService *service.DashboardService, authMiddleware *middleware.UserAuthMiddleware) *DashboardController {
 	return &DashboardController{
 		logger:           logger.With().Str("module", "dashboard_controller").Logger(),
 		dashboardService: dashboardService,
+		authMiddleware:   authMiddleware,
 	}
 }
 
 func (c *DashboardController) RegisterRoutes(api huma.API) {
+	security := []map[string][]string{{"bearerAuth": {}}}
+
 	huma.Register(api, huma.Operation{
 		OperationID: "get-dashboard-stats",
 		Method:      "GET",
@@ -31,8 +36,11 @@ func (c *DashboardController) RegisterRoutes(api huma.API) {
 		Summary:     "獲取儀表板統計資料",
 		Description: "獲取包含訂單、司機狀態、任務執行等各項統計資料",
 		Tags:        []string{"dashboard"},
-	}, func(ctx context.Context, input *struct{}) (*dashboard.DashboardStatsResponse, error) {
-		stats, err := c.dashboardService.GetDashboardStats(ctx)
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionDashboard),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *dashboard.DashboardFleetInput) (*dashboard.DashboardStatsResponse, error) {
+		stats, err := c.dashboardService.GetDashboardStats(ctx, input.Fleet)
 		if err != nil {
 			c.logger.Error().Err(err).Msg("獲取統計資料失敗")
 			return nil, huma.Error500InternalServerError("獲取統計資料失敗", err)
@@ -48,8 +56,11 @@ func (c *DashboardController) RegisterRoutes(api huma.API) {
 		Summary:     "獲取所有司機資料",
 		Description: "獲取系統中所有司機的詳細資料列表",
 		Tags:        []string{"dashboard"},
-	}, func(ctx context.Context, input *struct{}) (*dashboard.GetAllDriversResponse, error) {
-		drivers, totalCount, err := c.dashboardService.GetAllDrivers(ctx)
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *dashboard.DashboardFleetInput) (*dashboard.GetAllDriversResponse, error) {
+		drivers, totalCount, err := c.dashboardService.GetAllDrivers(ctx, input.Fleet)
 		if err != nil {
 			c.logger.Error().Err(err).Msg("獲取司機資料失敗")
 			return nil, huma.Error500InternalServerError("獲取司機資料失敗", err)
@@ -74,10 +85,13 @@ func (c *DashboardController) RegisterRoutes(api huma.API) {
 		Summary:     "獲取車隊統計資料",
 		Description: "獲取按車隊分組的線上司機統計",
 		Tags:        []string{"dashboard"},
-	}, func(ctx context.Context, input *struct{}) (*struct {
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionDashboard),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *dashboard.DashboardFleetInput) (*struct {
 		Body map[string]int `json:"fleetStats"`
 	}, error) {
-		fleetStats, err := c.dashboardService.GetOnlineDriverStatsByFleet(ctx)
+		fleetStats, err := c.dashboardService.GetOnlineDriverStatsByFleet(ctx, input.Fleet)
 		if err != nil {
 			c.logger.Error().Err(err).Msg("獲取車隊統計失敗")
 			return nil, huma.Error500InternalServerError("獲取車隊統計失敗", err)
@@ -97,8 +111,11 @@ func (c *DashboardController) RegisterRoutes(api huma.API) {
 		Summary:     "獲取司機訂單列表",
 		Description: "以司機為主找訂單，支援狀態過濾和模糊搜尋，帶分頁功能",
 		Tags:        []string{"dashboard"},
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionDashboard),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
 	}, func(ctx context.Context, input *dashboard.GetDriverOrdersInput) (*dashboard.GetDriverOrdersResponse, error) {
-		orders, pagination, err := c.dashboardService.GetDriverOrders(ctx, input.GetPageNum(), input.GetPageSize(), input.DriverStatus, input.GetSearchKeyword())
+		orders, pagination, err := c.dashboardService.GetDriverOrders(ctx, input.GetPageNum(), input.GetPageSize(), input.Fleet, input.DriverStatus, input.GetSearchKeyword())
 		if err != nil {
 			c.logger.Error().Err(err).Str("driver_status", input.DriverStatus).Str("search_keyword", input.GetSearchKeyword()).Msg("獲取司機訂單列表失敗")
 			return nil, huma.Error500InternalServerError("獲取司機訂單列表失敗", err)
@@ -118,9 +135,13 @@ func (c *DashboardController) RegisterRoutes(api huma.API) {
 		Summary:     "獲取司機週接單排行榜",
 		Description: "根據週數偏移獲取司機接單數量排行榜，不包含流單",
 		Tags:        []string{"dashboard"},
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionDashboard),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
 	}, func(ctx context.Context, input *dashboard.GetDriverWeeklyOrderRanksInput) (*dashboard.GetDriverWeeklyOrderRanksResponse, error) {
 		ranks, totalCount, weekStart, weekEnd, err := c.dashboardService.GetDriverWeeklyOrderRanks(
 			ctx,
+			input.Fleet,
 			input.WeekOffset,
 			input.GetPageNum(),
 			input.GetPageSize(),
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/common"
+	"right-backend/data-models/dead_letter"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type DeadLetterController struct {
+	logger            zerolog.Logger
+	deadLetterService *service.DeadLetterService
+	authMiddleware    *middleware.UserAuthMiddleware
+}
+
+func NewDeadLetterController(logger zerolog.Logger, deadLetterService *service.DeadLetterService, authMiddleware *middleware.UserAuthMiddleware) *DeadLetterController {
+	return &DeadLetterController{
+		logger:            logger.With().Str("module", "dead_letter_controller").Logger(),
+		deadLetterService: deadLetterService,
+		authMiddleware:    authMiddleware,
+	}
+}
+
+func (c *DeadLetterController) RegisterRoutes(api huma.API) {
+	// 列出死信訂單
+	huma.Register(api, huma.Operation{
+		OperationID: "get-dead-letter-orders",
+		Method:      "GET",
+		Path:        "/admin/dead-letter-orders",
+		Summary:     "列出死信訂單",
+		Description: "列出多次解析或派單失敗而移入死信隊列的訂單訊息",
+		Tags:        []string{"dead-letter"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *dead_letter.GetDeadLetterOrdersInput) (*dead_letter.DeadLetterOrdersResponse, error) {
+		pageNum, pageSize := input.GetPageNum(), input.GetPageSize()
+		letters, total, err := c.deadLetterService.List(ctx, input.Status, pageNum, pageSize)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取死信訂單失敗", err)
+		}
+
+		response := &dead_letter.DeadLetterOrdersResponse{}
+		response.Body.DeadLetters = letters
+		response.Body.Pagination = common.NewPaginationInfo(pageNum, pageSize, total)
+		return response, nil
+	})
+
+	// 獲取單筆死信訂單
+	huma.Register(api, huma.Operation{
+		OperationID: "get-dead-letter-order",
+		Method:      "GET",
+		Path:        "/admin/dead-letter-orders/{id}",
+		Summary:     "獲取死信訂單",
+		Description: "獲取單筆死信訂單，包含原始訊息內容與最後一次錯誤",
+		Tags:        []string{"dead-letter"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *dead_letter.DeadLetterIDInput) (*dead_letter.DeadLetterOrderResponse, error) {
+		letter, err := c.deadLetterService.GetByID(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到死信訂單", err)
+		}
+		return &dead_letter.DeadLetterOrderResponse{Body: letter}, nil
+	})
+
+	// 重新派送死信訂單
+	huma.Register(api, huma.Operation{
+		OperationID: "replay-dead-letter-order",
+		Method:      "POST",
+		Path:        "/admin/dead-letter-orders/{id}/replay",
+		Summary:     "重新派送死信訂單",
+		Description: "將死信訂單重設為等待接單並重新送入調度隊列",
+		Tags:        []string{"dead-letter"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *dead_letter.DeadLetterIDInput) (*dead_letter.DeadLetterOrderResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		letter, err := c.deadLetterService.Replay(ctx, input.ID, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("重新派送死信訂單失敗")
+			return nil, huma.Error400BadRequest("重新派送死信訂單失敗", err)
+		}
+		return &dead_letter.DeadLetterOrderResponse{Body: letter}, nil
+	})
+}
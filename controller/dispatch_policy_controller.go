@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/dispatch_policy"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type DispatchPolicyController struct {
+	logger                zerolog.Logger
+	dispatchPolicyService *service.DispatchPolicyService
+	authMiddleware        *middleware.UserAuthMiddleware
+}
+
+func NewDispatchPolicyController(logger zerolog.Logger, dispatchPolicyService *service.DispatchPolicyService, authMiddleware *middleware.UserAuthMiddleware) *DispatchPolicyController {
+	return &DispatchPolicyController{
+		logger:                logger.With().Str("module", "dispatch_policy_controller").Logger(),
+		dispatchPolicyService: dispatchPolicyService,
+		authMiddleware:        authMiddleware,
+	}
+}
+
+func (c *DispatchPolicyController) RegisterRoutes(api huma.API) {
+	// 列出已自訂的派單策略
+	huma.Register(api, huma.Operation{
+		OperationID: "get-dispatch-policies",
+		Method:      "GET",
+		Path:        "/admin/dispatch-policies",
+		Summary:     "列出車隊派單策略",
+		Description: "列出所有已自訂的車隊派單策略，未列出的車隊使用系統預設值",
+		Tags:        []string{"dispatch-policy"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *struct{}) (*dispatch_policy.DispatchPolicyListResponse, error) {
+		policies, err := c.dispatchPolicyService.ListPolicies(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取派單策略失敗", err)
+		}
+
+		response := &dispatch_policy.DispatchPolicyListResponse{}
+		response.Body.Policies = policies
+		return response, nil
+	})
+
+	// 獲取單一車隊目前生效的派單策略
+	huma.Register(api, huma.Operation{
+		OperationID: "get-dispatch-policy",
+		Method:      "GET",
+		Path:        "/admin/dispatch-policies/{fleet}",
+		Summary:     "獲取車隊派單策略",
+		Description: "獲取指定車隊目前生效的派單策略，未自訂時回傳系統預設值",
+		Tags:        []string{"dispatch-policy"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *dispatch_policy.FleetPathInput) (*dispatch_policy.DispatchPolicyResponse, error) {
+		policy := c.dispatchPolicyService.GetPolicy(model.FleetType(input.Fleet))
+
+		response := &dispatch_policy.DispatchPolicyResponse{}
+		response.Body.Policy = policy
+		response.Body.IsDefault = policy.ID == nil
+		return response, nil
+	})
+
+	// 新增或更新車隊派單策略
+	huma.Register(api, huma.Operation{
+		OperationID: "upsert-dispatch-policy",
+		Method:      "PUT",
+		Path:        "/admin/dispatch-policies/{fleet}",
+		Summary:     "更新車隊派單策略",
+		Description: "新增或更新指定車隊的派單策略，儲存後所有調度中心即時生效，無需重新部署",
+		Tags:        []string{"dispatch-policy"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *dispatch_policy.UpsertDispatchPolicyInput) (*dispatch_policy.DispatchPolicyResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		policy := &model.DispatchPolicy{
+			Fleet:                      model.FleetType(input.Fleet),
+			HaversineCandidatesCount:   input.Body.HaversineCandidatesCount,
+			GoogleAPICandidatesCount:   input.Body.GoogleAPICandidatesCount,
+			SequentialCallTimeoutSecs:  input.Body.SequentialCallTimeoutSecs,
+			EnableMaxDriverDistance:    input.Body.EnableMaxDriverDistance,
+			MaxDriverDistanceKm:        input.Body.MaxDriverDistanceKm,
+			EnableMaxEstimatedTimeMins: input.Body.EnableMaxEstimatedTimeMins,
+			MaxEstimatedTimeMins:       input.Body.MaxEstimatedTimeMins,
+			RankingStrategy:            input.Body.RankingStrategy,
+			DispatchMode:               model.DispatchMode(input.Body.DispatchMode),
+			BroadcastCount:             input.Body.BroadcastCount,
+			Rounds:                     input.Body.Rounds,
+		}
+
+		saved, err := c.dispatchPolicyService.UpsertPolicy(ctx, policy, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("fleet", input.Fleet).Str("用戶帳號", userFromToken.Account).Msg("更新派單策略失敗")
+			return nil, huma.Error400BadRequest("更新派單策略失敗", err)
+		}
+
+		response := &dispatch_policy.DispatchPolicyResponse{}
+		response.Body.Policy = saved
+		response.Body.IsDefault = false
+		return response, nil
+	})
+
+	// 刪除車隊派單策略（恢復預設值）
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-dispatch-policy",
+		Method:      "DELETE",
+		Path:        "/admin/dispatch-policies/{fleet}",
+		Summary:     "重設車隊派單策略",
+		Description: "刪除指定車隊的自訂派單策略，恢復系統預設值",
+		Tags:        []string{"dispatch-policy"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *dispatch_policy.FleetPathInput) (*dispatch_policy.SimpleResponse, error) {
+		if err := c.dispatchPolicyService.DeletePolicy(ctx, model.FleetType(input.Fleet)); err != nil {
+			return nil, huma.Error400BadRequest("重設派單策略失敗", err)
+		}
+
+		response := &dispatch_policy.SimpleResponse{}
+		response.Body.Success = true
+		response.Body.Message = "車隊 " + input.Fleet + " 派單策略已重設為預設值"
+		return response, nil
+	})
+}
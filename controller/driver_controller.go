@@ -912,6 +912,67 @@ func (c *DriverController) RegisterRoutes(api huma.API) {
 		return resp, nil
 	})
 
+	// 司機回報抵達停靠點
+	huma.Register(api, huma.Operation{
+		OperationID: "arrive-waypoint",
+		Method:      "POST",
+		Path:        "/drivers/orders/{orderId}/waypoints/{seq}/arrive",
+		Summary:     "司機回報抵達停靠點(3.1)",
+		Description: "多點行程中司機回報已抵達指定停靠點，停靠點須依序回報，僅限客上（執行任務）的訂單。",
+		Tags:        []string{"drivers", "flow"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+	}, func(ctx context.Context, input *driver.WaypointActionInput) (*driver.WaypointActionResponse, error) {
+		return c.updateWaypointStatus(ctx, input, model.WaypointStatusArrived)
+	})
+
+	// 司機回報停靠點完成
+	huma.Register(api, huma.Operation{
+		OperationID: "complete-waypoint",
+		Method:      "POST",
+		Path:        "/drivers/orders/{orderId}/waypoints/{seq}/complete",
+		Summary:     "司機回報停靠點完成(3.2)",
+		Description: "多點行程中司機回報指定停靠點已完成，並依各段預估行駛時間重新推算後續停靠點的預估抵達時間。最後一站完成後仍需呼叫完成訂單。",
+		Tags:        []string{"drivers", "flow"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+	}, func(ctx context.Context, input *driver.WaypointActionInput) (*driver.WaypointActionResponse, error) {
+		return c.updateWaypointStatus(ctx, input, model.WaypointStatusCompleted)
+	})
+
+	// 司機回報乘客未到
+	huma.Register(api, huma.Operation{
+		OperationID: "report-passenger-no-show",
+		Method:      "POST",
+		Path:        "/drivers/passenger-no-show",
+		Summary:     "司機回報乘客未到(3.1)",
+		Description: "司機抵達上車點後等候超過車隊取消政策規定的時間乘客仍未出現，以乘客未到完成訂單並記錄乘客未到費用。",
+		Tags:        []string{"drivers", "flow"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+	}, func(ctx context.Context, input *driver.PassengerNoShowInput) (*driver.PassengerNoShowResponse, error) {
+		d, err := auth.GetDriverFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized("無效的司機Auth")
+		}
+
+		c.logger.Info().
+			Str("driver_name", d.Name).
+			Str("car_plate", d.CarPlate).
+			Str("order_id", input.Body.OrderID).
+			Msg("司機回報乘客未到")
+
+		driverStatus, orderStatus, fee, err := c.driverService.ReportPassengerNoShow(ctx, d, input.Body.OrderID, time.Now())
+		if err != nil {
+			return nil, huma.Error400BadRequest("回報乘客未到失敗", err)
+		}
+
+		resp := &driver.PassengerNoShowResponse{}
+		resp.Body.Success = true
+		resp.Body.Message = fmt.Sprintf("已回報乘客未到，乘客未到費用 %d 元", fee.Amount)
+		resp.Body.DriverStatus = driverStatus
+		resp.Body.OrderStatus = orderStatus
+		resp.Body.CancellationFee = fee
+		return resp, nil
+	})
+
 	// 司機完成訂單
 	huma.Register(api, huma.Operation{
 		OperationID: "complete-order",
@@ -1454,3 +1515,36 @@ func (c *DriverController) handleUploadAvatar(ctx context.Context, req *UploadAv
 		},
 	}, nil
 }
+
+// updateWaypointStatus 處理司機回報停靠點抵達或完成
+func (c *DriverController) updateWaypointStatus(ctx context.Context, input *driver.WaypointActionInput, status model.WaypointStatus) (*driver.WaypointActionResponse, error) {
+	d, err := auth.GetDriverFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("無效的司機Auth")
+	}
+
+	c.logger.Info().
+		Str("driver_name", d.Name).
+		Str("car_plate", d.CarPlate).
+		Str("order_id", input.OrderID).
+		Int("seq", input.Seq).
+		Str("status", string(status)).
+		Msg("司機回報停靠點")
+
+	updatedOrder, err := c.driverService.UpdateWaypointStatus(ctx, d, input.OrderID, input.Seq, status, time.Now())
+	if err != nil {
+		return nil, huma.Error400BadRequest("更新停靠點狀態失敗", err)
+	}
+
+	resp := &driver.WaypointActionResponse{}
+	resp.Body.Success = true
+	if status == model.WaypointStatusArrived {
+		resp.Body.Message = fmt.Sprintf("已回報抵達第%d站", input.Seq)
+	} else {
+		resp.Body.Message = fmt.Sprintf("第%d站已完成", input.Seq)
+	}
+	resp.Body.DriverStatus = string(d.Status)
+	resp.Body.OrderStatus = string(updatedOrder.Status)
+	resp.Body.Waypoints = updatedOrder.Waypoints
+	return resp, nil
+}
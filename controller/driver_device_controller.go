@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"right-backend/auth"
+	"right-backend/data-models/admin"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+// DriverDeviceController 司機設備綁定管理（查詢、核准、解除綁定）
+type DriverDeviceController struct {
+	logger         zerolog.Logger
+	deviceService  *service.DriverDeviceService
+	authMiddleware *middleware.UserAuthMiddleware
+}
+
+func NewDriverDeviceController(logger zerolog.Logger, deviceService *service.DriverDeviceService, authMiddleware *middleware.UserAuthMiddleware) *DriverDeviceController {
+	return &DriverDeviceController{
+		logger:         logger.With().Str("module", "driver_device_controller").Logger(),
+		deviceService:  deviceService,
+		authMiddleware: authMiddleware,
+	}
+}
+
+func (c *DriverDeviceController) RegisterRoutes(api huma.API) {
+	security := []map[string][]string{{"bearerAuth": {}}}
+
+	// 查詢司機的設備
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-driver-devices",
+		Method:      "GET",
+		Path:        "/admin/drivers/{driverId}/devices",
+		Summary:     "查詢司機設備",
+		Description: "查詢司機已綁定、待核准與已解除綁定的設備",
+		Tags:        []string{"driver-devices"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *admin.DriverDevicesInput) (*admin.DriverDevicesResponse, error) {
+		devices, err := c.deviceService.ListDriverDevices(ctx, input.DriverID)
+		if err != nil {
+			c.logger.Error().Err(err).Str("driver_id", input.DriverID).Msg("查詢司機設備失敗")
+			return nil, huma.Error400BadRequest("查詢司機設備失敗", err)
+		}
+
+		response := &admin.DriverDevicesResponse{}
+		response.Body.Devices = devices
+		return response, nil
+	})
+
+	// 查詢待核准的設備
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-pending-driver-devices",
+		Method:      "GET",
+		Path:        "/admin/driver-devices/pending",
+		Summary:     "查詢待核准設備",
+		Description: "查詢司機在未綁定設備登入而送出的綁定申請",
+		Tags:        []string{"driver-devices"},
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *admin.PendingDriverDevicesInput) (*admin.DriverDevicesResponse, error) {
+		devices, err := c.deviceService.ListPendingDevices(ctx, input.Fleet)
+		if err != nil {
+			c.logger.Error().Err(err).Str("fleet", input.Fleet).Msg("查詢待核准設備失敗")
+			return nil, huma.Error500InternalServerError("查詢待核准設備失敗", err)
+		}
+
+		response := &admin.DriverDevicesResponse{}
+		response.Body.Devices = devices
+		return response, nil
+	})
+
+	// 核准設備
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-approve-driver-device",
+		Method:      "PUT",
+		Path:        "/admin/drivers/{driverId}/devices/{deviceId}/approve",
+		Summary:     "核准司機設備",
+		Description: "將設備綁定給司機，原本綁定的設備會解除綁定並強制登出",
+		Tags:        []string{"driver-devices"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *admin.DriverDeviceInput) (*admin.DriverDeviceResponse, error) {
+		device, err := c.deviceService.ApproveDevice(ctx, input.DriverID, input.DeviceID, c.reviewer(ctx))
+		if err != nil {
+			return nil, c.deviceError(err, input, "核准司機設備失敗")
+		}
+
+		response := &admin.DriverDeviceResponse{}
+		response.Body.Device = device
+		response.Body.Message = "已核准設備"
+		return response, nil
+	})
+
+	// 解除設備綁定
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-unbind-driver-device",
+		Method:      "DELETE",
+		Path:        "/admin/drivers/{driverId}/devices/{deviceId}",
+		Summary:     "解除司機設備綁定",
+		Description: "解除設備綁定或拒絕待核准的設備，並強制登出該設備；司機下次登入的設備會自動綁定",
+		Tags:        []string{"driver-devices"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *admin.DriverDeviceInput) (*admin.DriverDeviceResponse, error) {
+		device, err := c.deviceService.UnbindDevice(ctx, input.DriverID, input.DeviceID, c.reviewer(ctx))
+		if err != nil {
+			return nil, c.deviceError(err, input, "解除司機設備綁定失敗")
+		}
+
+		response := &admin.DriverDeviceResponse{}
+		response.Body.Device = device
+		response.Body.Message = "已解除設備綁定"
+		return response, nil
+	})
+}
+
+// reviewer 操作的管理員帳號
+func (c *DriverDeviceController) reviewer(ctx context.Context) string {
+	user, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return ""
+	}
+	return user.Account
+}
+
+func (c *DriverDeviceController) deviceError(err error, input *admin.DriverDeviceInput, message string) error {
+	if errors.Is(err, service.ErrDriverDeviceNotFound) {
+		return huma.Error404NotFound("找不到司機設備")
+	}
+	c.logger.Error().Err(err).
+		Str("driver_id", input.DriverID).
+		Str("device_id", input.DeviceID).
+		Msg(message)
+	return huma.Error500InternalServerError(message, err)
+}
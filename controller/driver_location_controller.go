@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"right-backend/data-models/driver_location"
+	"right-backend/middleware"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type DriverLocationController struct {
+	logger                zerolog.Logger
+	driverLocationService *service.DriverLocationService
+	authMiddleware        *middleware.UserAuthMiddleware
+}
+
+func NewDriverLocationController(logger zerolog.Logger, driverLocationService *service.DriverLocationService, authMiddleware *middleware.UserAuthMiddleware) *DriverLocationController {
+	return &DriverLocationController{
+		logger:                logger.With().Str("module", "driver_location_controller").Logger(),
+		driverLocationService: driverLocationService,
+		authMiddleware:        authMiddleware,
+	}
+}
+
+func (c *DriverLocationController) RegisterRoutes(api huma.API) {
+	// 查詢司機時間區間內的軌跡
+	huma.Register(api, huma.Operation{
+		OperationID: "get-driver-track",
+		Method:      "GET",
+		Path:        "/admin/drivers/{id}/locations",
+		Summary:     "查詢司機軌跡",
+		Description: "查詢司機在指定時間區間內的位置歷史軌跡，時間區間不可超過24小時",
+		Tags:        []string{"driver-locations"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *driver_location.GetDriverTrackInput) (*driver_location.DriverTrackResponse, error) {
+		points, err := c.driverLocationService.GetTrack(ctx, input.ID, input.From, input.To)
+		if err != nil {
+			c.logger.Error().Err(err).Str("driver_id", input.ID).Msg("查詢司機軌跡失敗")
+			return nil, huma.Error400BadRequest("查詢司機軌跡失敗", err)
+		}
+
+		response := &driver_location.DriverTrackResponse{}
+		response.Body.DriverID = input.ID
+		response.Body.From = input.From
+		response.Body.To = input.To
+		response.Body.Points = points
+		return response, nil
+	})
+
+	// 查詢訂單執行期間的司機軌跡
+	huma.Register(api, huma.Operation{
+		OperationID: "get-order-driver-track",
+		Method:      "GET",
+		Path:        "/admin/orders/{id}/driver-track",
+		Summary:     "查詢訂單司機軌跡",
+		Description: "查詢訂單從接單到完成期間接單司機的位置軌跡，並附上回報抵達時間與偏差，供處理司機「已抵達」爭議",
+		Tags:        []string{"driver-locations"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *driver_location.OrderTrackInput) (*driver_location.OrderTrackResponse, error) {
+		order, points, err := c.driverLocationService.GetOrderTrack(ctx, input.ID)
+		if err != nil {
+			c.logger.Error().Err(err).Str("order_id", input.ID).Msg("查詢訂單司機軌跡失敗")
+			return nil, huma.Error400BadRequest("查詢訂單司機軌跡失敗", err)
+		}
+
+		response := &driver_location.OrderTrackResponse{}
+		response.Body.OrderID = input.ID
+		response.Body.ShortID = order.ShortID
+		response.Body.DriverID = order.Driver.AssignedDriver
+		response.Body.PickupLat = order.Customer.PickupLat
+		response.Body.PickupLng = order.Customer.PickupLng
+		response.Body.AcceptanceTime = order.AcceptanceTime
+		response.Body.ArrivalTime = order.ArrivalTime
+		response.Body.CompletionTime = order.CompletionTime
+		response.Body.ArrivalDeviationSecs = order.Driver.ArrivalDeviationSecs
+		response.Body.Points = points
+		return response, nil
+	})
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/fleet"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type FleetController struct {
+	logger         zerolog.Logger
+	fleetService   *service.FleetService
+	authMiddleware *middleware.UserAuthMiddleware
+}
+
+func NewFleetController(logger zerolog.Logger, fleetService *service.FleetService, authMiddleware *middleware.UserAuthMiddleware) *FleetController {
+	return &FleetController{
+		logger:         logger.With().Str("module", "fleet_controller").Logger(),
+		fleetService:   fleetService,
+		authMiddleware: authMiddleware,
+	}
+}
+
+func (c *FleetController) RegisterRoutes(api huma.API) {
+	// 列出車隊
+	huma.Register(api, huma.Operation{
+		OperationID: "get-fleets",
+		Method:      "GET",
+		Path:        "/admin/fleets",
+		Summary:     "列出車隊",
+		Description: "列出所有登錄的車隊、客群前綴、跨車隊派單設定與車隊設定，尚未登錄任何車隊時回傳系統預設車隊",
+		Tags:        []string{"fleets"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *struct{}) (*fleet.FleetListResponse, error) {
+		fleets, err := c.fleetService.ListFleets(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取車隊失敗", err)
+		}
+
+		response := &fleet.FleetListResponse{}
+		response.Body.Fleets = fleets
+		if len(fleets) == 0 {
+			response.Body.Fleets = model.DefaultFleets()
+			response.Body.UsingDefaults = true
+		}
+		return response, nil
+	})
+
+	// 獲取單一車隊
+	huma.Register(api, huma.Operation{
+		OperationID: "get-fleet",
+		Method:      "GET",
+		Path:        "/admin/fleets/{code}",
+		Summary:     "獲取車隊",
+		Description: "獲取指定車隊目前生效的登錄資料",
+		Tags:        []string{"fleets"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *fleet.FleetCodeInput) (*fleet.FleetResponse, error) {
+		f := c.fleetService.GetFleet(model.FleetType(strings.ToUpper(input.Code)))
+		if f == nil {
+			return nil, huma.Error404NotFound("找不到車隊: " + input.Code)
+		}
+		return &fleet.FleetResponse{Body: f}, nil
+	})
+
+	// 新增車隊
+	huma.Register(api, huma.Operation{
+		OperationID: "create-fleet",
+		Method:      "POST",
+		Path:        "/admin/fleets",
+		Summary:     "新增車隊",
+		Description: "登錄新的合作車隊，儲存後建單、派單與 Discord 指令即時生效，無需修改程式或重新部署",
+		Tags:        []string{"fleets"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *fleet.CreateFleetInput) (*fleet.FleetResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		f := fleetFromBody(input.Body.FleetBody)
+		f.Code = model.FleetType(input.Body.Code)
+		created, err := c.fleetService.CreateFleet(ctx, f, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("fleet", input.Body.Code).Str("用戶帳號", userFromToken.Account).Msg("新增車隊失敗")
+			return nil, huma.Error400BadRequest("新增車隊失敗", err)
+		}
+		return &fleet.FleetResponse{Body: created}, nil
+	})
+
+	// 更新車隊
+	huma.Register(api, huma.Operation{
+		OperationID: "update-fleet",
+		Method:      "PUT",
+		Path:        "/admin/fleets/{code}",
+		Summary:     "更新車隊",
+		Description: "更新車隊的顯示名稱、客群前綴、跨車隊派單設定、車隊設定與啟用狀態，儲存後所有實例即時生效",
+		Tags:        []string{"fleets"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *fleet.UpdateFleetInput) (*fleet.FleetResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		code := model.FleetType(strings.ToUpper(input.Code))
+		updated, err := c.fleetService.UpdateFleet(ctx, code, fleetFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("fleet", input.Code).Str("用戶帳號", userFromToken.Account).Msg("更新車隊失敗")
+			return nil, huma.Error400BadRequest("更新車隊失敗", err)
+		}
+		return &fleet.FleetResponse{Body: updated}, nil
+	})
+
+	// 刪除車隊
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-fleet",
+		Method:      "DELETE",
+		Path:        "/admin/fleets/{code}",
+		Summary:     "刪除車隊",
+		Description: "刪除車隊登錄資料，該車隊訂單之後只派給本車隊司機；已有司機或訂單的車隊建議改為停用",
+		Tags:        []string{"fleets"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *fleet.FleetCodeInput) (*fleet.SimpleResponse, error) {
+		if err := c.fleetService.DeleteFleet(ctx, model.FleetType(strings.ToUpper(input.Code))); err != nil {
+			return nil, huma.Error400BadRequest("刪除車隊失敗", err)
+		}
+
+		response := &fleet.SimpleResponse{}
+		response.Body.Success = true
+		response.Body.Message = "車隊 " + input.Code + " 已刪除"
+		return response, nil
+	})
+}
+
+// fleetFromBody 將請求內容轉為車隊登錄資料
+func fleetFromBody(body fleet.FleetBody) *model.Fleet {
+	return &model.Fleet{
+		DisplayName:           body.DisplayName,
+		CustomerGroupPrefixes: body.CustomerGroupPrefixes,
+		DispatchToFleets:      body.DispatchToFleets,
+		IsDefault:             body.IsDefault,
+		Settings:              body.Settings,
+		Enabled:               body.Enabled,
+	}
+}
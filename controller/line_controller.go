@@ -203,6 +203,9 @@ func (lc *LineController) handleMessageEvent(ctx context.Context, eventMap map[s
 		return
 	}
 
+	// 發送者的 LINE 用戶ID（群組對話時也會帶入），用於連結乘客資料
+	lineUserID, _ := source["userId"].(string)
+
 	// 獲取 reply token
 	replyToken, _ := eventMap["replyToken"].(string)
 
@@ -232,7 +235,7 @@ func (lc *LineController) handleMessageEvent(ctx context.Context, eventMap map[s
 			Msg("收到 LINE 文字訊息")
 
 		// 處理文字訊息
-		lc.handleTextMessage(ctx, messageText, config.ID, sourceID, replyToken)
+		lc.handleTextMessage(ctx, messageText, config.ID, sourceID, lineUserID, replyToken)
 	} else {
 		messageType, _ := message["type"].(string)
 		lc.logger.Info().
@@ -244,7 +247,7 @@ func (lc *LineController) handleMessageEvent(ctx context.Context, eventMap map[s
 }
 
 // handleTextMessage 處理文字訊息
-func (lc *LineController) handleTextMessage(ctx context.Context, messageText, configID, sourceID, replyToken string) {
+func (lc *LineController) handleTextMessage(ctx context.Context, messageText, configID, sourceID, lineUserID, replyToken string) {
 	// 檢查是否為交互指令
 	if lc.handleInteractiveCommand(ctx, messageText, configID, sourceID, replyToken) {
 		return
@@ -274,7 +277,7 @@ func (lc *LineController) handleTextMessage(ctx context.Context, messageText, co
 	}
 
 	// 創建訂單
-	createdOrder, err := lc.lineService.CreateOrderFromMessage(ctx, messageText, configID, sourceID)
+	createdOrder, err := lc.lineService.CreateOrderFromMessage(ctx, messageText, configID, sourceID, lineUserID)
 	if err != nil {
 		lc.logger.Error().
 			Err(err).
@@ -365,7 +368,8 @@ func (lc *LineController) handleCancelCommand(ctx context.Context, orderID, conf
 		Msg("處理 LINE 取消指令")
 
 	// 使用統一的取消服務（包含所有驗證邏輯）
-	updatedOrder, err := lc.orderSvc.CancelOrder(ctx, orderID, "LINE取消", "LINE用戶")
+	ctx = service.WithOrderEventActor(ctx, model.OrderEventActor{Type: model.OrderEventActorLine, ID: sourceID, Name: "LINE用戶"})
+	updatedOrder, err := lc.orderSvc.CancelOrder(ctx, orderID, model.OrderActorPassenger, "LINE取消", "LINE用戶")
 	if err != nil {
 		lc.logger.Error().Err(err).Str("order_id", orderID).Msg("LINE取消訂單失敗")
 		lc.lineService.ReplyMessage(configID, replyToken, fmt.Sprintf("❌ %s", err.Error()))
@@ -410,7 +414,8 @@ func (lc *LineController) handleRedispatchCommand(ctx context.Context, orderID,
 	}
 
 	// 3. 執行重派操作
-	redispatchedOrder, err := lc.orderSvc.RedispatchOrder(ctx, orderID)
+	ctx = service.WithOrderEventActor(ctx, model.OrderEventActor{Type: model.OrderEventActorLine, ID: sourceID, Name: "LINE用戶"})
+	redispatchedOrder, err := lc.orderSvc.RedispatchOrder(ctx, orderID, model.OrderActorPassenger)
 	if err != nil {
 		lc.logger.Error().Err(err).Str("order_id", orderID).Msg("重派訂單失敗")
 		lc.lineService.ReplyMessage(configID, replyToken, "❌ 重派訂單失敗，請稍後再試")
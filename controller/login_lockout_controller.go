@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"right-backend/auth"
+	"right-backend/data-models/admin"
+	"right-backend/data-models/common"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+// LoginLockoutController 登入鎖定管理（稽核紀錄查詢、解除鎖定），只有系統管理員和版主可以操作
+type LoginLockoutController struct {
+	logger         zerolog.Logger
+	loginLimiter   *service.LoginLimiterService
+	authMiddleware *middleware.UserAuthMiddleware
+}
+
+func NewLoginLockoutController(logger zerolog.Logger, loginLimiter *service.LoginLimiterService, authMiddleware *middleware.UserAuthMiddleware) *LoginLockoutController {
+	return &LoginLockoutController{
+		logger:         logger.With().Str("module", "login_lockout_controller").Logger(),
+		loginLimiter:   loginLimiter,
+		authMiddleware: authMiddleware,
+	}
+}
+
+func (c *LoginLockoutController) RegisterRoutes(api huma.API) {
+	security := []map[string][]string{{"bearerAuth": {}}}
+
+	// 查詢登入鎖定紀錄
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-login-lockouts",
+		Method:      "GET",
+		Path:        "/admin/login-lockouts",
+		Summary:     "查詢登入鎖定紀錄",
+		Description: "查詢帳號或 IP 因登入失敗次數過多而鎖定，以及管理員解除鎖定的稽核紀錄",
+		Tags:        []string{"login-lockouts"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *admin.LoginLockoutEventsInput) (*admin.LoginLockoutEventsResponse, error) {
+		pageNum, pageSize := input.GetPageNum(), input.GetPageSize()
+		events, total, err := c.loginLimiter.ListLockoutEvents(ctx, input.Scope, input.Account, input.IP, pageNum, pageSize)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("查詢登入鎖定紀錄失敗")
+			return nil, huma.Error500InternalServerError("查詢登入鎖定紀錄失敗", err)
+		}
+
+		response := &admin.LoginLockoutEventsResponse{}
+		response.Body.Events = events
+		response.Body.Pagination = common.NewPaginationInfo(pageNum, pageSize, total)
+		return response, nil
+	})
+
+	// 解除帳號登入鎖定
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-unlock-login-account",
+		Method:      "POST",
+		Path:        "/admin/login-lockouts/unlock-account",
+		Summary:     "解除帳號登入鎖定",
+		Description: "解除帳號的登入鎖定，並清除失敗次數與鎖定等級",
+		Tags:        []string{"login-lockouts"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *admin.UnlockLoginAccountInput) (*admin.UnlockLoginResponse, error) {
+		operator, err := c.operator(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		event, err := c.loginLimiter.UnlockAccount(ctx, input.Body.LoginType, input.Body.Account, operator)
+		if err != nil {
+			return nil, c.unlockError(err, "解除帳號登入鎖定失敗")
+		}
+
+		response := &admin.UnlockLoginResponse{}
+		response.Body.Event = event
+		response.Body.Message = "已解除帳號登入鎖定"
+		return response, nil
+	})
+
+	// 解除 IP 登入鎖定
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-unlock-login-ip",
+		Method:      "POST",
+		Path:        "/admin/login-lockouts/unlock-ip",
+		Summary:     "解除 IP 登入鎖定",
+		Description: "解除來源 IP 的登入鎖定，並清除失敗次數與鎖定等級",
+		Tags:        []string{"login-lockouts"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *admin.UnlockLoginIPInput) (*admin.UnlockLoginResponse, error) {
+		operator, err := c.operator(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		event, err := c.loginLimiter.UnlockIP(ctx, input.Body.IP, operator)
+		if err != nil {
+			return nil, c.unlockError(err, "解除 IP 登入鎖定失敗")
+		}
+
+		response := &admin.UnlockLoginResponse{}
+		response.Body.Event = event
+		response.Body.Message = "已解除 IP 登入鎖定"
+		return response, nil
+	})
+}
+
+// operator 操作的管理員帳號，角色限制由操作宣告的 AccessRule 檢查
+func (c *LoginLockoutController) operator(ctx context.Context) (string, error) {
+	user, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+		return "", huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+	}
+	return user.Account, nil
+}
+
+func (c *LoginLockoutController) unlockError(err error, message string) error {
+	switch {
+	case errors.Is(err, service.ErrLoginLockNotFound):
+		return huma.Error404NotFound(err.Error())
+	case errors.Is(err, service.ErrInvalidLoginTarget):
+		return huma.Error400BadRequest(err.Error())
+	}
+	c.logger.Error().Err(err).Msg(message)
+	return huma.Error500InternalServerError(message, err)
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"right-backend/auth"
 	"right-backend/data-models/common"
 	"right-backend/data-models/order"
@@ -20,15 +21,17 @@ type OrderController struct {
 	driverService       *service.DriverService
 	authMiddleware      *middleware.UserAuthMiddleware
 	notificationService *service.NotificationService
+	orderEventService   *service.OrderEventService
 }
 
-func NewOrderController(logger zerolog.Logger, orderService *service.OrderService, driverService *service.DriverService, authMiddleware *middleware.UserAuthMiddleware, notificationService *service.NotificationService) *OrderController {
+func NewOrderController(logger zerolog.Logger, orderService *service.OrderService, driverService *service.DriverService, authMiddleware *middleware.UserAuthMiddleware, notificationService *service.NotificationService, orderEventService *service.OrderEventService) *OrderController {
 	return &OrderController{
 		logger:              logger.With().Str("module", "order_controller").Logger(),
 		orderService:        orderService,
 		driverService:       driverService,
 		authMiddleware:      authMiddleware,
 		notificationService: notificationService,
+		orderEventService:   orderEventService,
 	}
 }
 
@@ -107,6 +110,39 @@ func (c *OrderController) RegisterRoutes(api huma.API) {
 		return &order.OrderResponse{Body: o}, nil
 	})
 
+	// 獲取訂單事件時間軸
+	huma.Register(api, huma.Operation{
+		OperationID: "get-order-timeline",
+		Method:      "GET",
+		Path:        "/orders/{id}/timeline",
+		Summary:     "獲取訂單事件時間軸",
+		Description: "依時間順序列出訂單的所有事件（建立、派單、司機操作、取消、後台編輯、匯入），包含操作者、欄位異動前後的值與追蹤ID",
+		Tags:        []string{"orders"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *order.OrderIDInput) (*order.OrderTimelineResponse, error) {
+		o, err := c.orderService.GetOrderByID(ctx, input.ID)
+		if err != nil {
+			c.logger.Error().Err(err).Str("order_id", input.ID).Msg("訂單不存在")
+			return nil, huma.Error404NotFound("訂單不存在", err)
+		}
+
+		events, err := c.orderEventService.GetOrderTimeline(ctx, input.ID)
+		if err != nil {
+			c.logger.Error().Err(err).Str("order_id", input.ID).Msg("獲取訂單事件失敗")
+			return nil, huma.Error500InternalServerError("獲取訂單事件失敗", err)
+		}
+
+		response := &order.OrderTimelineResponse{}
+		response.Body.OrderID = input.ID
+		response.Body.ShortID = o.ShortID
+		response.Body.Status = o.Status
+		response.Body.Events = events
+		return response, nil
+	})
+
 	// 更新訂單狀態
 	huma.Register(api, huma.Operation{
 		OperationID: "update-order-status",
@@ -115,38 +151,14 @@ func (c *OrderController) RegisterRoutes(api huma.API) {
 		Summary:     "更新訂單狀態",
 		Tags:        []string{"orders"},
 	}, func(ctx context.Context, input *order.UpdateOrderStatusInput) (*order.OrderResponse, error) {
-		o, err := c.orderService.UpdateOrderStatus(ctx, input.ID, input.Body.Status)
+		// 依訂單狀態機檢查並套用副作用（司機狀態、通知）
+		o, err := c.orderService.ChangeOrderStatus(ctx, input.ID, input.Body.Status, model.OrderActorAdmin)
 		if err != nil {
 			c.logger.Error().Err(err).Str("order_id", input.ID).Str("status", string(input.Body.Status)).Msg("更新訂單狀態失敗")
-			return nil, huma.Error400BadRequest("更新訂單狀態失敗", err)
-		}
-
-		// 對於特定狀態變更，觸發通知
-		if c.notificationService != nil && o.Driver.AssignedDriver != "" {
-			// 構建司機資訊
-			driver := &model.DriverInfo{}
-			driver.Name = o.Driver.Name
-			driver.CarPlate = o.Driver.CarNo
-
-			// 根據新狀態決定是否需要通知
-			shouldNotify := input.Body.Status == model.OrderStatusCancelled
-
-			if shouldNotify {
-				go func() {
-					var err error
-					switch input.Body.Status {
-					case model.OrderStatusCancelled:
-						err = c.notificationService.NotifyOrderCancelled(context.Background(), input.ID, driver)
-					}
-
-					if err != nil {
-						c.logger.Error().Err(err).
-							Str("order_id", input.ID).
-							Str("status", string(input.Body.Status)).
-							Msg("管理員訂單狀態更新通知失敗")
-					}
-				}()
+			if errors.Is(err, model.ErrIllegalOrderTransition) {
+				return nil, huma.Error409Conflict("不允許的訂單狀態轉換", err)
 			}
+			return nil, huma.Error400BadRequest("更新訂單狀態失敗", err)
 		}
 
 		return &order.OrderResponse{Body: o}, nil
@@ -279,7 +291,7 @@ func (c *OrderController) RegisterRoutes(api huma.API) {
 			infra.AttrOrderID(input.ID),
 		)
 
-		o, err := c.orderService.RedispatchOrder(ctx, input.ID)
+		o, err := c.orderService.RedispatchOrder(ctx, input.ID, model.OrderActorAdmin)
 		if err != nil {
 			infra.RecordOrderControllerError(span, err, input.ID, "重新派送訂單失敗")
 			c.logger.Error().Err(err).Str("order_id", input.ID).Msg("重新派送訂單失敗")
@@ -346,10 +358,13 @@ func (c *OrderController) RegisterRoutes(api huma.API) {
 		}
 
 		// 使用統一的取消服務（包含所有驗證邏輯）
-		updatedOrder, err := c.orderService.CancelOrder(ctx, input.OrderID, "網頁取消", userName)
+		updatedOrder, err := c.orderService.CancelOrder(ctx, input.OrderID, model.OrderActorAdmin, "網頁取消", userName)
 		if err != nil {
 			infra.RecordOrderControllerError(span, err, input.OrderID, "取消訂單失敗")
 			c.logger.Error().Err(err).Str("order_id", input.OrderID).Msg("取消訂單失敗")
+			if errors.Is(err, model.ErrIllegalOrderTransition) {
+				return nil, huma.Error409Conflict("訂單目前狀態無法取消", err)
+			}
 			return nil, huma.Error500InternalServerError("取消訂單失敗", err)
 		}
 
@@ -2,9 +2,11 @@ package controller
 
 import (
 	"context"
+	"right-backend/auth"
 	"right-backend/data-models/common"
 	"right-backend/data-models/order"
 	"right-backend/middleware"
+	"right-backend/model"
 	"right-backend/service"
 	"strings"
 
@@ -28,6 +30,14 @@ func NewOrderSummaryController(logger zerolog.Logger, orderSummaryService *servi
 	}
 }
 
+// orderReportPermissions 具備任一訂單報表權限即可存取訂單報表
+var orderReportPermissions = []model.Permission{
+	model.PermissionOrderReport,
+	model.PermissionRSKReport,
+	model.PermissionKDReport,
+	model.PermissionWEIReport,
+}
+
 func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 	// 獲取訂單報表列表
 	huma.Register(api, huma.Operation{
@@ -36,6 +46,7 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Path:        "/order-summary",
 		Summary:     "獲取訂單報表列表",
 		Tags:        []string{"order-summary"},
+		Metadata:    middleware.RequireFleetScoped("fleet", orderReportPermissions...),
 		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
@@ -124,6 +135,7 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Path:        "/order-summary/{id}",
 		Summary:     "根據ID獲取訂單詳情",
 		Tags:        []string{"order-summary"},
+		Metadata:    middleware.RequirePermissions(orderReportPermissions...),
 		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
@@ -134,6 +146,9 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 			c.logger.Error().Err(err).Str("order_id", input.ID).Msg("訂單不存在")
 			return nil, huma.Error404NotFound("訂單不存在", err)
 		}
+		if err := c.ensureOrderInScope(ctx, o); err != nil {
+			return nil, err
+		}
 
 		return &order.OrderSummaryResponse{Body: o}, nil
 	})
@@ -145,6 +160,7 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Path:        "/order-summary",
 		Summary:     "創建新訂單",
 		Tags:        []string{"order-summary"},
+		Metadata:    middleware.RequirePermissions(orderReportPermissions...),
 		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
@@ -166,11 +182,16 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Path:        "/order-summary/{id}",
 		Summary:     "更新訂單",
 		Tags:        []string{"order-summary"},
+		Metadata:    middleware.RequirePermissions(orderReportPermissions...),
 		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
 	}, func(ctx context.Context, input *order.UpdateOrderSummaryInput) (*order.OrderSummaryResponse, error) {
+		if err := c.ensureOrderIDInScope(ctx, input.ID); err != nil {
+			return nil, err
+		}
+
 		o, err := c.orderSummaryService.UpdateOrderSummary(ctx, input.ID, &input.Body)
 		if err != nil {
 			c.logger.Error().Err(err).Str("order_id", input.ID).Msg("更新訂單失敗")
@@ -187,11 +208,16 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Path:        "/order-summary/{id}",
 		Summary:     "刪除訂單",
 		Tags:        []string{"order-summary"},
+		Metadata:    middleware.RequirePermissions(orderReportPermissions...),
 		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
 		Security: []map[string][]string{
 			{"bearerAuth": {}},
 		},
 	}, func(ctx context.Context, input *order.OrderSummaryIDInput) (*struct{}, error) {
+		if err := c.ensureOrderIDInScope(ctx, input.ID); err != nil {
+			return nil, err
+		}
+
 		if err := c.orderSummaryService.DeleteOrder(ctx, input.ID); err != nil {
 			c.logger.Error().Err(err).Str("order_id", input.ID).Msg("刪除訂單失敗")
 			return nil, huma.Error400BadRequest("刪除訂單失敗", err)
@@ -207,6 +233,11 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Path:        "/order-summary/batch-edit",
 		Summary:     "批量編輯訂單",
 		Tags:        []string{"order-summary"},
+		Metadata:    middleware.RequirePermissions(orderReportPermissions...),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
 	}, func(ctx context.Context, input *order.BatchEditOrderInput) (*order.BatchEditOrderResponse, error) {
 		// 驗證訂單數量
 		if len(input.Body.Orders) == 0 {
@@ -214,6 +245,13 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 			return nil, huma.Error400BadRequest("訂單列表不能為空")
 		}
 
+		// 只能編輯所屬車隊的訂單
+		for _, item := range input.Body.Orders {
+			if err := c.ensureOrderIDInScope(ctx, item.OrderID); err != nil {
+				return nil, err
+			}
+		}
+
 		// 執行批量編輯
 		results := c.orderSummaryService.BatchEditOrders(ctx, input.Body.Orders)
 
@@ -250,7 +288,12 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Summary:       "匯入訂單檢查",
 		Description:   "檢查 Excel 檔案並返回預計匯入的訂單數量",
 		Tags:          []string{"order-summary"},
+		Metadata:      middleware.RequireFleetScoped("fleet", orderReportPermissions...),
 		DefaultStatus: 200,
+		Middlewares:   huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
 	}, func(ctx context.Context, input *order.ImportOrdersCheckInput) (*order.ImportOrdersCheckResponse, error) {
 		// 驗證車隊
 		if input.Fleet == "" {
@@ -300,7 +343,12 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Summary:       "匯入訂單",
 		Description:   "從 Excel 檔案匯入訂單",
 		Tags:          []string{"order-summary"},
+		Metadata:      middleware.RequireFleetScoped("fleet", orderReportPermissions...),
 		DefaultStatus: 200,
+		Middlewares:   huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
 	}, func(ctx context.Context, input *order.ImportOrdersInput) (*order.ImportOrdersResponse, error) {
 		// 驗證車隊
 		if input.Fleet == "" {
@@ -351,7 +399,12 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		Summary:       "匯出訂單",
 		Description:   "匯出訂單到 Excel 檔案",
 		Tags:          []string{"order-summary"},
+		Metadata:      middleware.RequireFleetScoped("fleet", orderReportPermissions...),
 		DefaultStatus: 200,
+		Middlewares:   huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
 	}, func(ctx context.Context, input *order.ExportOrdersInput) (*huma.StreamResponse, error) {
 		// 匯出訂單
 		f, err := c.orderImportExportService.ExportOrders(ctx, input.Fleet, input.StartDate, input.EndDate, input.HasHeader)
@@ -384,3 +437,42 @@ func (c *OrderSummaryController) RegisterRoutes(api huma.API) {
 		}, nil
 	})
 }
+
+// ensureOrderIDInScope 載入訂單並檢查是否在用戶可檢視的車隊範圍內
+func (c *OrderSummaryController) ensureOrderIDInScope(ctx context.Context, orderID string) error {
+	access, err := auth.GetUserAccessFromContext(ctx)
+	if err != nil {
+		return huma.Error401Unauthorized("未授權", err)
+	}
+	if _, scoped := access.ScopedFleet(); !scoped {
+		return nil
+	}
+
+	o, err := c.orderSummaryService.GetOrderByID(ctx, orderID)
+	if err != nil {
+		c.logger.Error().Err(err).Str("order_id", orderID).Msg("訂單不存在")
+		return huma.Error404NotFound("訂單不存在", err)
+	}
+	return c.ensureOrderInScope(ctx, o)
+}
+
+// ensureOrderInScope 只能檢視自家車隊的用戶不可存取其他車隊的訂單
+func (c *OrderSummaryController) ensureOrderInScope(ctx context.Context, o *model.Order) error {
+	access, err := auth.GetUserAccessFromContext(ctx)
+	if err != nil {
+		return huma.Error401Unauthorized("未授權", err)
+	}
+	fleet, scoped := access.ScopedFleet()
+	if !scoped {
+		return nil
+	}
+	if fleet == "" || o.Fleet != fleet {
+		c.logger.Warn().
+			Str("order_id", o.ID.Hex()).
+			Str("order_fleet", string(o.Fleet)).
+			Str("user_fleet", string(fleet)).
+			Msg("用戶嘗試存取其他車隊的訂單")
+		return huma.Error403Forbidden("權限不足，只能存取所屬車隊的訂單")
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/common"
+	"right-backend/data-models/passenger"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type PassengerController struct {
+	logger           zerolog.Logger
+	passengerService *service.PassengerService
+	orderService     *service.OrderService
+	authMiddleware   *middleware.UserAuthMiddleware
+}
+
+func NewPassengerController(logger zerolog.Logger, passengerService *service.PassengerService, orderService *service.OrderService, authMiddleware *middleware.UserAuthMiddleware) *PassengerController {
+	return &PassengerController{
+		logger:           logger.With().Str("module", "passenger_controller").Logger(),
+		passengerService: passengerService,
+		orderService:     orderService,
+		authMiddleware:   authMiddleware,
+	}
+}
+
+func (c *PassengerController) RegisterRoutes(api huma.API) {
+	// 列出乘客
+	huma.Register(api, huma.Operation{
+		OperationID: "get-passengers",
+		Method:      "GET",
+		Path:        "/admin/passengers",
+		Summary:     "列出乘客",
+		Description: "分頁列出乘客資料，可依稱呼、電話或 LINE 用戶ID 搜尋，依最近下單時間排序",
+		Tags:        []string{"passengers"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *passenger.ListPassengersInput) (*passenger.PassengerListResponse, error) {
+		pageNum, pageSize := input.GetPageNum(), input.GetPageSize()
+		passengers, total, err := c.passengerService.List(ctx, input.GetSearchKeyword(), input.BlockedOnly, pageNum, pageSize)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取乘客失敗", err)
+		}
+
+		response := &passenger.PassengerListResponse{}
+		response.Body.Passengers = passengers
+		response.Body.Pagination = common.NewPaginationInfo(pageNum, pageSize, total)
+		return response, nil
+	})
+
+	// 獲取單一乘客
+	huma.Register(api, huma.Operation{
+		OperationID: "get-passenger",
+		Method:      "GET",
+		Path:        "/admin/passengers/{id}",
+		Summary:     "獲取乘客",
+		Description: "獲取乘客資料、備註、常用地點與常搭乘上車地點",
+		Tags:        []string{"passengers"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *passenger.PassengerIDInput) (*passenger.PassengerResponse, error) {
+		p, err := c.passengerService.Get(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到乘客", err)
+		}
+		return &passenger.PassengerResponse{Body: p}, nil
+	})
+
+	// 新增乘客
+	huma.Register(api, huma.Operation{
+		OperationID: "create-passenger",
+		Method:      "POST",
+		Path:        "/admin/passengers",
+		Summary:     "新增乘客",
+		Description: "手動建立乘客資料；透過 LINE 下單的乘客會依 LINE 用戶ID 自動建立",
+		Tags:        []string{"passengers"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *passenger.CreatePassengerInput) (*passenger.PassengerResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		p := passengerFromBody(input.Body.PassengerBody)
+		p.LineUserID = input.Body.LineUserID
+		created, err := c.passengerService.Create(ctx, p, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("用戶帳號", userFromToken.Account).Msg("新增乘客失敗")
+			return nil, huma.Error400BadRequest("新增乘客失敗", err)
+		}
+		return &passenger.PassengerResponse{Body: created}, nil
+	})
+
+	// 更新乘客
+	huma.Register(api, huma.Operation{
+		OperationID: "update-passenger",
+		Method:      "PUT",
+		Path:        "/admin/passengers/{id}",
+		Summary:     "更新乘客",
+		Description: "更新乘客資料、備註、常用地點與封鎖狀態，封鎖後該乘客無法透過 LINE 下單",
+		Tags:        []string{"passengers"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *passenger.UpdatePassengerInput) (*passenger.PassengerResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		updated, err := c.passengerService.Update(ctx, input.ID, passengerFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("passenger_id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("更新乘客失敗")
+			return nil, huma.Error400BadRequest("更新乘客失敗", err)
+		}
+		return &passenger.PassengerResponse{Body: updated}, nil
+	})
+
+	// 乘客訂單歷史
+	huma.Register(api, huma.Operation{
+		OperationID: "get-passenger-orders",
+		Method:      "GET",
+		Path:        "/admin/passengers/{id}/orders",
+		Summary:     "乘客訂單歷史",
+		Description: "列出乘客最近的訂單（依建立時間新到舊）",
+		Tags:        []string{"passengers"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *passenger.PassengerOrdersInput) (*passenger.PassengerOrdersResponse, error) {
+		p, err := c.passengerService.Get(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到乘客", err)
+		}
+
+		orders, err := c.passengerService.RecentOrders(ctx, p, input.Limit, "")
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取乘客訂單失敗", err)
+		}
+
+		response := &passenger.PassengerOrdersResponse{}
+		response.Body.Orders = orders
+		return response, nil
+	})
+
+	// 訂單乘客歷史（新訂單進來時派單員查看乘客最近行程）
+	huma.Register(api, huma.Operation{
+		OperationID: "get-order-passenger-history",
+		Method:      "GET",
+		Path:        "/orders/{id}/passenger-history",
+		Summary:     "訂單乘客歷史",
+		Description: "依訂單連結的乘客（或訂單的 LINE 用戶ID）回傳乘客資料與最近 N 趟行程，供派單員在新訂單進來時參考",
+		Tags:        []string{"passengers"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *passenger.OrderPassengerHistoryInput) (*passenger.OrderPassengerHistoryResponse, error) {
+		order, err := c.orderService.GetOrderByID(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到訂單", err)
+		}
+
+		response := &passenger.OrderPassengerHistoryResponse{}
+		response.Body.RecentOrders = []*model.Order{}
+
+		p, err := c.passengerService.ForOrder(ctx, order)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取乘客資料失敗", err)
+		}
+		if p == nil {
+			return response, nil
+		}
+
+		orders, err := c.passengerService.RecentOrders(ctx, p, input.Limit, input.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取乘客訂單失敗", err)
+		}
+		response.Body.Passenger = p
+		response.Body.RecentOrders = orders
+		return response, nil
+	})
+}
+
+// passengerFromBody 將請求內容轉為乘客資料
+func passengerFromBody(body passenger.PassengerBody) *model.Passenger {
+	return &model.Passenger{
+		Name:              body.Name,
+		Phone:             body.Phone,
+		Notes:             body.Notes,
+		Tags:              body.Tags,
+		FavoriteAddresses: body.FavoriteAddresses,
+		Blocked:           body.Blocked,
+		BlockedReason:     body.BlockedReason,
+	}
+}
@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/common"
+	"right-backend/data-models/recurring_order"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type RecurringOrderController struct {
+	logger                zerolog.Logger
+	recurringOrderService *service.RecurringOrderService
+	authMiddleware        *middleware.UserAuthMiddleware
+}
+
+func NewRecurringOrderController(logger zerolog.Logger, recurringOrderService *service.RecurringOrderService, authMiddleware *middleware.UserAuthMiddleware) *RecurringOrderController {
+	return &RecurringOrderController{
+		logger:                logger.With().Str("module", "recurring_order_controller").Logger(),
+		recurringOrderService: recurringOrderService,
+		authMiddleware:        authMiddleware,
+	}
+}
+
+func (c *RecurringOrderController) RegisterRoutes(api huma.API) {
+	// 列出週期性預約
+	huma.Register(api, huma.Operation{
+		OperationID: "get-recurring-orders",
+		Method:      "GET",
+		Path:        "/admin/recurring-orders",
+		Summary:     "列出週期性預約",
+		Description: "分頁列出週期性預約（例如醫院、學校每個平日固定接送），可依名稱、訂單文字或客群搜尋",
+		Tags:        []string{"recurring-orders"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *recurring_order.ListRecurringOrdersInput) (*recurring_order.RecurringOrderListResponse, error) {
+		pageNum, pageSize := input.GetPageNum(), input.GetPageSize()
+		recurringOrders, total, err := c.recurringOrderService.List(ctx, input.GetSearchKeyword(), model.RecurringOrderStatus(input.Status), pageNum, pageSize)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取週期性預約失敗", err)
+		}
+
+		response := &recurring_order.RecurringOrderListResponse{}
+		response.Body.RecurringOrders = recurringOrders
+		response.Body.DaysAhead = c.recurringOrderService.DaysAhead()
+		response.Body.Pagination = common.NewPaginationInfo(pageNum, pageSize, total)
+		return response, nil
+	})
+
+	// 獲取單一週期性預約
+	huma.Register(api, huma.Operation{
+		OperationID: "get-recurring-order",
+		Method:      "GET",
+		Path:        "/admin/recurring-orders/{id}",
+		Summary:     "獲取週期性預約",
+		Description: "獲取週期性預約與週期規則",
+		Tags:        []string{"recurring-orders"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *recurring_order.RecurringOrderIDInput) (*recurring_order.RecurringOrderResponse, error) {
+		recurring, err := c.recurringOrderService.Get(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到週期性預約", err)
+		}
+		return &recurring_order.RecurringOrderResponse{Body: recurring}, nil
+	})
+
+	// 新增週期性預約
+	huma.Register(api, huma.Operation{
+		OperationID: "create-recurring-order",
+		Method:      "POST",
+		Path:        "/admin/recurring-orders",
+		Summary:     "新增週期性預約",
+		Description: "新增週期性預約：每週指定星期或每N天重複，可設定結束日期與例外日期。背景排程會提前產生預約單並送入預約單隊列",
+		Tags:        []string{"recurring-orders"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *recurring_order.CreateRecurringOrderInput) (*recurring_order.RecurringOrderResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		created, err := c.recurringOrderService.Create(ctx, recurringOrderFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("用戶帳號", userFromToken.Account).Msg("新增週期性預約失敗")
+			return nil, huma.Error400BadRequest("新增週期性預約失敗", err)
+		}
+		return &recurring_order.RecurringOrderResponse{Body: created}, nil
+	})
+
+	// 更新週期性預約
+	huma.Register(api, huma.Operation{
+		OperationID: "update-recurring-order",
+		Method:      "PUT",
+		Path:        "/admin/recurring-orders/{id}",
+		Summary:     "更新週期性預約",
+		Description: "更新週期性預約的名稱、訂單文字、車隊與規則，只影響尚未產生的預約單",
+		Tags:        []string{"recurring-orders"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *recurring_order.UpdateRecurringOrderInput) (*recurring_order.RecurringOrderResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		updated, err := c.recurringOrderService.Update(ctx, input.ID, recurringOrderFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("recurring_order_id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("更新週期性預約失敗")
+			return nil, huma.Error400BadRequest("更新週期性預約失敗", err)
+		}
+		return &recurring_order.RecurringOrderResponse{Body: updated}, nil
+	})
+
+	// 列出單次預約
+	huma.Register(api, huma.Operation{
+		OperationID: "get-recurring-order-occurrences",
+		Method:      "GET",
+		Path:        "/admin/recurring-orders/{id}/occurrences",
+		Summary:     "列出單次預約",
+		Description: "列出今天起指定天數內的單次預約，包含已略過的日期與已產生的預約單狀態",
+		Tags:        []string{"recurring-orders"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *recurring_order.OccurrencesInput) (*recurring_order.OccurrencesResponse, error) {
+		occurrences, err := c.recurringOrderService.Occurrences(ctx, input.ID, input.Days)
+		if err != nil {
+			return nil, huma.Error400BadRequest("獲取單次預約失敗", err)
+		}
+
+		response := &recurring_order.OccurrencesResponse{}
+		response.Body.Occurrences = occurrences
+		return response, nil
+	})
+
+	// 略過單次預約
+	huma.Register(api, huma.Operation{
+		OperationID: "skip-recurring-order-occurrence",
+		Method:      "POST",
+		Path:        "/admin/recurring-orders/{id}/skip",
+		Summary:     "略過單次預約",
+		Description: "略過指定日期的單次預約（加入例外日期），該日預約單已產生且尚未開始時一併取消，系列其他日期不受影響",
+		Tags:        []string{"recurring-orders"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *recurring_order.SkipOccurrenceInput) (*recurring_order.SkipOccurrenceResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		recurring, cancelled, err := c.recurringOrderService.SkipOccurrence(ctx, input.ID, input.Body.Date, input.Body.Reason, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("recurring_order_id", input.ID).Str("date", input.Body.Date).Str("用戶帳號", userFromToken.Account).Msg("略過單次預約失敗")
+			return nil, huma.Error400BadRequest("略過單次預約失敗", err)
+		}
+
+		response := &recurring_order.SkipOccurrenceResponse{}
+		response.Body.RecurringOrder = recurring
+		response.Body.CancelledOrder = cancelled
+		return response, nil
+	})
+
+	// 取消整個系列
+	huma.Register(api, huma.Operation{
+		OperationID: "cancel-recurring-order",
+		Method:      "POST",
+		Path:        "/admin/recurring-orders/{id}/cancel",
+		Summary:     "取消週期性預約系列",
+		Description: "取消整個週期性預約系列，不再產生預約單，並取消已產生且尚未開始的預約單",
+		Tags:        []string{"recurring-orders"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *recurring_order.CancelSeriesInput) (*recurring_order.CancelSeriesResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		recurring, cancelledCount, err := c.recurringOrderService.CancelSeries(ctx, input.ID, input.Body.Reason, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("recurring_order_id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("取消週期性預約失敗")
+			return nil, huma.Error400BadRequest("取消週期性預約失敗", err)
+		}
+
+		response := &recurring_order.CancelSeriesResponse{}
+		response.Body.RecurringOrder = recurring
+		response.Body.CancelledOrders = cancelledCount
+		return response, nil
+	})
+}
+
+// recurringOrderFromBody 將請求內容轉為週期性預約
+func recurringOrderFromBody(body recurring_order.RecurringOrderBody) *model.RecurringOrder {
+	return &model.RecurringOrder{
+		Name:    body.Name,
+		OriText: body.OriText,
+		Fleet:   model.FleetType(body.Fleet),
+		Rule:    body.Rule,
+	}
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/service_zone"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type ServiceZoneController struct {
+	logger             zerolog.Logger
+	serviceZoneService *service.ServiceZoneService
+	authMiddleware     *middleware.UserAuthMiddleware
+}
+
+func NewServiceZoneController(logger zerolog.Logger, serviceZoneService *service.ServiceZoneService, authMiddleware *middleware.UserAuthMiddleware) *ServiceZoneController {
+	return &ServiceZoneController{
+		logger:             logger.With().Str("module", "service_zone_controller").Logger(),
+		serviceZoneService: serviceZoneService,
+		authMiddleware:     authMiddleware,
+	}
+}
+
+func (c *ServiceZoneController) RegisterRoutes(api huma.API) {
+	// 列出服務區域
+	huma.Register(api, huma.Operation{
+		OperationID: "get-service-zones",
+		Method:      "GET",
+		Path:        "/admin/service-zones",
+		Summary:     "列出服務區域",
+		Description: "列出所有服務區域（機場、偏遠地區、不服務區域等）及各車隊規則",
+		Tags:        []string{"service-zones"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *struct{}) (*service_zone.ServiceZoneListResponse, error) {
+		zones, err := c.serviceZoneService.ListZones(ctx)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取服務區域失敗", err)
+		}
+
+		response := &service_zone.ServiceZoneListResponse{}
+		response.Body.Zones = zones
+		return response, nil
+	})
+
+	// 獲取單一服務區域
+	huma.Register(api, huma.Operation{
+		OperationID: "get-service-zone",
+		Method:      "GET",
+		Path:        "/admin/service-zones/{id}",
+		Summary:     "獲取服務區域",
+		Description: "獲取單一服務區域的範圍與規則",
+		Tags:        []string{"service-zones"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *service_zone.ServiceZoneIDInput) (*service_zone.ServiceZoneResponse, error) {
+		zone, err := c.serviceZoneService.GetZone(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到服務區域", err)
+		}
+		return &service_zone.ServiceZoneResponse{Body: zone}, nil
+	})
+
+	// 新增服務區域
+	huma.Register(api, huma.Operation{
+		OperationID: "create-service-zone",
+		Method:      "POST",
+		Path:        "/admin/service-zones",
+		Summary:     "新增服務區域",
+		Description: "新增多邊形服務區域與各車隊規則：reject 拒絕建單、require_tag 只派給具備標籤的司機、radius 改用不同的司機距離上限",
+		Tags:        []string{"service-zones"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *service_zone.CreateServiceZoneInput) (*service_zone.ServiceZoneResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		zone, err := c.serviceZoneService.CreateZone(ctx, zoneFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("name", input.Body.Name).Str("用戶帳號", userFromToken.Account).Msg("新增服務區域失敗")
+			return nil, huma.Error400BadRequest("新增服務區域失敗", err)
+		}
+		return &service_zone.ServiceZoneResponse{Body: zone}, nil
+	})
+
+	// 更新服務區域
+	huma.Register(api, huma.Operation{
+		OperationID: "update-service-zone",
+		Method:      "PUT",
+		Path:        "/admin/service-zones/{id}",
+		Summary:     "更新服務區域",
+		Description: "更新服務區域的範圍、規則與啟用狀態，儲存後建單與派單立即套用",
+		Tags:        []string{"service-zones"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *service_zone.UpdateServiceZoneInput) (*service_zone.ServiceZoneResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		zone, err := c.serviceZoneService.UpdateZone(ctx, input.ID, zoneFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("zone_id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("更新服務區域失敗")
+			return nil, huma.Error400BadRequest("更新服務區域失敗", err)
+		}
+		return &service_zone.ServiceZoneResponse{Body: zone}, nil
+	})
+
+	// 刪除服務區域
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-service-zone",
+		Method:      "DELETE",
+		Path:        "/admin/service-zones/{id}",
+		Summary:     "刪除服務區域",
+		Description: "刪除服務區域，該區域的規則立即失效",
+		Tags:        []string{"service-zones"},
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *service_zone.ServiceZoneIDInput) (*service_zone.SimpleResponse, error) {
+		if err := c.serviceZoneService.DeleteZone(ctx, input.ID); err != nil {
+			return nil, huma.Error400BadRequest("刪除服務區域失敗", err)
+		}
+
+		response := &service_zone.SimpleResponse{}
+		response.Body.Success = true
+		response.Body.Message = "服務區域已刪除"
+		return response, nil
+	})
+}
+
+// zoneFromBody 將請求內容轉為服務區域
+func zoneFromBody(body service_zone.ServiceZoneBody) *model.ServiceZone {
+	return &model.ServiceZone{
+		Name:        body.Name,
+		Description: body.Description,
+		Area:        body.Area,
+		Rules:       body.Rules,
+		Enabled:     body.Enabled,
+	}
+}
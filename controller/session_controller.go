@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"right-backend/auth"
+	authModels "right-backend/data-models/auth"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+// SessionController 登入 session 查詢與撤銷（強制登出）
+// 用戶 session 只有系統管理員和版主可以管理；司機 session 需具備司機列表權限，且只能管理可檢視車隊的司機
+type SessionController struct {
+	logger               zerolog.Logger
+	sessionService       *service.AuthSessionService
+	driverService        *service.DriverService
+	userAuthMiddleware   *middleware.UserAuthMiddleware
+	driverAuthMiddleware *middleware.DriverAuthMiddleware
+}
+
+func NewSessionController(logger zerolog.Logger, sessionService *service.AuthSessionService, driverService *service.DriverService, userAuthMiddleware *middleware.UserAuthMiddleware, driverAuthMiddleware *middleware.DriverAuthMiddleware) *SessionController {
+	return &SessionController{
+		logger:               logger.With().Str("module", "session_controller").Logger(),
+		sessionService:       sessionService,
+		driverService:        driverService,
+		userAuthMiddleware:   userAuthMiddleware,
+		driverAuthMiddleware: driverAuthMiddleware,
+	}
+}
+
+func (c *SessionController) RegisterRoutes(api huma.API) {
+	security := []map[string][]string{{"bearerAuth": {}}}
+
+	// 用戶查詢自己的登入裝置
+	huma.Register(api, huma.Operation{
+		OperationID: "list-my-user-sessions",
+		Method:      "GET",
+		Path:        "/users/me/sessions",
+		Summary:     "查詢我的登入裝置",
+		Tags:        []string{"sessions"},
+		Middlewares: huma.Middlewares{c.userAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *struct{}) (*authModels.SessionListResponse, error) {
+		user, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized("未授權", err)
+		}
+		return c.listSessions(ctx, model.TokenTypeUser, user.ID.Hex())
+	})
+
+	// 用戶登出指定裝置
+	huma.Register(api, huma.Operation{
+		OperationID: "revoke-my-user-session",
+		Method:      "DELETE",
+		Path:        "/users/me/sessions/{sessionId}",
+		Summary:     "登出我的指定裝置",
+		Tags:        []string{"sessions"},
+		Middlewares: huma.Middlewares{c.userAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *authModels.SessionIDInput) (*authModels.RevokeSessionsResponse, error) {
+		user, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized("未授權", err)
+		}
+		return c.revokeOwnSession(ctx, model.TokenTypeUser, user.ID.Hex(), input.SessionID)
+	})
+
+	// 司機查詢自己的登入裝置
+	huma.Register(api, huma.Operation{
+		OperationID: "list-my-driver-sessions",
+		Method:      "GET",
+		Path:        "/drivers/me/sessions",
+		Summary:     "司機查詢登入裝置",
+		Tags:        []string{"sessions"},
+		Middlewares: huma.Middlewares{c.driverAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *struct{}) (*authModels.SessionListResponse, error) {
+		driver, err := auth.GetDriverFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized("未授權", err)
+		}
+		return c.listSessions(ctx, model.TokenTypeDriver, driver.ID.Hex())
+	})
+
+	// 司機登出指定裝置
+	huma.Register(api, huma.Operation{
+		OperationID: "revoke-my-driver-session",
+		Method:      "DELETE",
+		Path:        "/drivers/me/sessions/{sessionId}",
+		Summary:     "司機登出指定裝置",
+		Tags:        []string{"sessions"},
+		Middlewares: huma.Middlewares{c.driverAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *authModels.SessionIDInput) (*authModels.RevokeSessionsResponse, error) {
+		driver, err := auth.GetDriverFromContext(ctx)
+		if err != nil {
+			return nil, huma.Error401Unauthorized("未授權", err)
+		}
+		return c.revokeOwnSession(ctx, model.TokenTypeDriver, driver.ID.Hex(), input.SessionID)
+	})
+
+	// 管理員查詢用戶的登入 session
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-user-sessions",
+		Method:      "GET",
+		Path:        "/admin/users/{id}/sessions",
+		Summary:     "查詢用戶登入 session",
+		Tags:        []string{"sessions"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.userAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *authModels.SessionSubjectInput) (*authModels.SessionListResponse, error) {
+		return c.listSessions(ctx, model.TokenTypeUser, input.ID)
+	})
+
+	// 管理員強制用戶登出
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-revoke-user-sessions",
+		Method:      "DELETE",
+		Path:        "/admin/users/{id}/sessions",
+		Summary:     "強制用戶登出",
+		Description: "撤銷用戶所有登入 session，已簽發的 access token 立即失效",
+		Tags:        []string{"sessions"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.userAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *authModels.SessionSubjectInput) (*authModels.RevokeSessionsResponse, error) {
+		return c.revokeAllSessions(ctx, model.TokenTypeUser, input.ID)
+	})
+
+	// 管理員查詢司機的登入 session
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-list-driver-sessions",
+		Method:      "GET",
+		Path:        "/admin/drivers/{id}/sessions",
+		Summary:     "查詢司機登入 session",
+		Tags:        []string{"sessions"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.userAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *authModels.SessionSubjectInput) (*authModels.SessionListResponse, error) {
+		if err := c.ensureDriverInScope(ctx, input.ID); err != nil {
+			return nil, err
+		}
+		return c.listSessions(ctx, model.TokenTypeDriver, input.ID)
+	})
+
+	// 管理員強制司機登出
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-revoke-driver-sessions",
+		Method:      "DELETE",
+		Path:        "/admin/drivers/{id}/sessions",
+		Summary:     "強制司機登出",
+		Description: "撤銷司機所有登入 session，已簽發的 access token 與 WebSocket 重新連線立即失效",
+		Tags:        []string{"sessions"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDriverList),
+		Middlewares: huma.Middlewares{c.userAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *authModels.SessionSubjectInput) (*authModels.RevokeSessionsResponse, error) {
+		if err := c.ensureDriverInScope(ctx, input.ID); err != nil {
+			return nil, err
+		}
+		return c.revokeAllSessions(ctx, model.TokenTypeDriver, input.ID)
+	})
+
+	// 管理員撤銷單一 session
+	huma.Register(api, huma.Operation{
+		OperationID: "admin-revoke-session",
+		Method:      "DELETE",
+		Path:        "/admin/sessions/{sessionId}",
+		Summary:     "撤銷登入 session",
+		Tags:        []string{"sessions"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.userAuthMiddleware.Auth()},
+		Security:    security,
+	}, func(ctx context.Context, input *authModels.SessionIDInput) (*authModels.RevokeSessionsResponse, error) {
+		session, err := c.sessionService.GetSession(ctx, input.SessionID)
+		if err != nil {
+			if errors.Is(err, service.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound("找不到登入 session")
+			}
+			c.logger.Error().Err(err).Str("session_id", input.SessionID).Msg("查詢登入 session 失敗")
+			return nil, huma.Error500InternalServerError("查詢登入 session 失敗", err)
+		}
+		if session.SubjectType == model.TokenTypeDriver {
+			if err := c.ensureDriverInScope(ctx, session.SubjectID.Hex()); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := c.sessionService.RevokeSession(ctx, input.SessionID, model.SessionRevokeManual); err != nil {
+			if errors.Is(err, service.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound("找不到登入 session")
+			}
+			c.logger.Error().Err(err).Str("session_id", input.SessionID).Msg("撤銷登入 session 失敗")
+			return nil, huma.Error500InternalServerError("撤銷登入 session 失敗", err)
+		}
+
+		response := &authModels.RevokeSessionsResponse{}
+		response.Body.Revoked = 1
+		response.Body.Message = "已撤銷登入 session"
+		return response, nil
+	})
+}
+
+func (c *SessionController) listSessions(ctx context.Context, subjectType model.TokenType, subjectID string) (*authModels.SessionListResponse, error) {
+	sessions, err := c.sessionService.ListActiveSessions(ctx, subjectType, subjectID)
+	if err != nil {
+		c.logger.Error().Err(err).Str("subject_type", string(subjectType)).Str("subject_id", subjectID).Msg("查詢登入 session 失敗")
+		return nil, huma.Error400BadRequest("查詢登入 session 失敗", err)
+	}
+
+	response := &authModels.SessionListResponse{}
+	response.Body.Sessions = sessions
+	return response, nil
+}
+
+func (c *SessionController) revokeAllSessions(ctx context.Context, subjectType model.TokenType, subjectID string) (*authModels.RevokeSessionsResponse, error) {
+	count, err := c.sessionService.RevokeSubjectSessions(ctx, subjectType, subjectID, model.SessionRevokeManual)
+	if err != nil {
+		c.logger.Error().Err(err).Str("subject_type", string(subjectType)).Str("subject_id", subjectID).Msg("強制登出失敗")
+		return nil, huma.Error400BadRequest("強制登出失敗", err)
+	}
+
+	response := &authModels.RevokeSessionsResponse{}
+	response.Body.Revoked = count
+	response.Body.Message = "已強制登出"
+	return response, nil
+}
+
+// ensureDriverInScope 只能檢視自家車隊的用戶不可管理其他車隊司機的 session
+func (c *SessionController) ensureDriverInScope(ctx context.Context, driverID string) error {
+	access, err := auth.GetUserAccessFromContext(ctx)
+	if err != nil {
+		return huma.Error401Unauthorized("未授權", err)
+	}
+	fleet, scoped := access.ScopedFleet()
+	if !scoped {
+		return nil
+	}
+
+	driver, err := c.driverService.GetDriverByID(ctx, driverID)
+	if err != nil {
+		return huma.Error404NotFound("找不到司機")
+	}
+	if fleet == "" || driver.Fleet != fleet {
+		c.logger.Warn().
+			Str("driver_id", driverID).
+			Str("driver_fleet", string(driver.Fleet)).
+			Str("user_fleet", string(fleet)).
+			Msg("用戶嘗試管理其他車隊司機的登入 session")
+		return huma.Error403Forbidden("權限不足，只能管理所屬車隊司機的登入 session")
+	}
+	return nil
+}
+
+// revokeOwnSession 撤銷自己的 session，不可撤銷其他人的 session
+func (c *SessionController) revokeOwnSession(ctx context.Context, subjectType model.TokenType, subjectID, sessionID string) (*authModels.RevokeSessionsResponse, error) {
+	session, err := c.sessionService.GetSession(ctx, sessionID)
+	if err != nil || session.SubjectType != subjectType || session.SubjectID.Hex() != subjectID {
+		return nil, huma.Error404NotFound("找不到登入 session")
+	}
+
+	if err := c.sessionService.RevokeSession(ctx, sessionID, model.SessionRevokeLogout); err != nil {
+		c.logger.Error().Err(err).Str("session_id", sessionID).Msg("登出裝置失敗")
+		return nil, huma.Error500InternalServerError("登出裝置失敗", err)
+	}
+
+	response := &authModels.RevokeSessionsResponse{}
+	response.Body.Revoked = 1
+	response.Body.Message = "已登出裝置"
+	return response, nil
+}
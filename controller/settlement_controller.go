@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/settlement"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+	"github.com/xuri/excelize/v2"
+)
+
+type SettlementController struct {
+	logger            zerolog.Logger
+	settlementService *service.SettlementService
+	authMiddleware    *middleware.UserAuthMiddleware
+}
+
+func NewSettlementController(logger zerolog.Logger, settlementService *service.SettlementService, authMiddleware *middleware.UserAuthMiddleware) *SettlementController {
+	return &SettlementController{
+		logger:            logger.With().Str("module", "settlement_controller").Logger(),
+		settlementService: settlementService,
+		authMiddleware:    authMiddleware,
+	}
+}
+
+func (c *SettlementController) RegisterRoutes(api huma.API) {
+	// 產生結算單
+	huma.Register(api, huma.Operation{
+		OperationID: "generate-settlements",
+		Method:      "POST",
+		Path:        "/admin/settlements/generate",
+		Summary:     "產生司機結算單",
+		Description: "彙總期間內司機完成的訂單，依司機所屬車隊的抽成規則產生草稿結算單。已有草稿會重新產生，已確認或已撥款的結算單不會變動",
+		Tags:        []string{"settlements"},
+		Metadata:    middleware.RequirePermissions(model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.GenerateSettlementsInput) (*settlement.GenerateSettlementsResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		settlements, skipped, err := c.settlementService.Generate(ctx, strings.ToUpper(input.Body.Fleet), input.Body.DriverID,
+			input.Body.StartDate, input.Body.EndDate, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("用戶帳號", userFromToken.Account).Msg("產生結算單失敗")
+			return nil, huma.Error400BadRequest("產生結算單失敗", err)
+		}
+
+		response := &settlement.GenerateSettlementsResponse{}
+		response.Body.Settlements = settlements
+		response.Body.Skipped = skipped
+		return response, nil
+	})
+
+	// 列出結算單
+	huma.Register(api, huma.Operation{
+		OperationID: "get-settlements",
+		Method:      "GET",
+		Path:        "/admin/settlements",
+		Summary:     "列出結算單",
+		Description: "依車隊、司機、狀態與期間列出結算單（不含訂單明細）",
+		Tags:        []string{"settlements"},
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.ListSettlementsInput) (*settlement.SettlementListResponse, error) {
+		settlements, err := c.settlementService.List(ctx, settlementFilter(input))
+		if err != nil {
+			return nil, huma.Error400BadRequest("獲取結算單失敗", err)
+		}
+
+		response := &settlement.SettlementListResponse{}
+		response.Body.Settlements = settlements
+		return response, nil
+	})
+
+	// 匯出結算總表
+	huma.Register(api, huma.Operation{
+		OperationID:   "export-settlements",
+		Method:        "GET",
+		Path:          "/admin/settlements/export",
+		Summary:       "匯出結算總表",
+		Description:   "依查詢條件匯出結算單總表到 Excel 檔案，一位司機一列",
+		Tags:          []string{"settlements"},
+		DefaultStatus: 200,
+		Metadata:      middleware.RequireFleetScoped("fleet", model.PermissionOperationReport),
+		Middlewares:   huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.ListSettlementsInput) (*huma.StreamResponse, error) {
+		f, err := c.settlementService.ExportList(ctx, settlementFilter(input))
+		if err != nil {
+			c.logger.Error().Err(err).Msg("匯出結算總表失敗")
+			return nil, huma.Error400BadRequest("匯出結算總表失敗", err)
+		}
+
+		filename := "settlements.xlsx"
+		if input.StartDate != "" || input.EndDate != "" {
+			filename = "settlements_" + input.StartDate + "_" + input.EndDate + ".xlsx"
+		}
+		return c.xlsxResponse(f, filename), nil
+	})
+
+	// 獲取單一結算單
+	huma.Register(api, huma.Operation{
+		OperationID: "get-settlement",
+		Method:      "GET",
+		Path:        "/admin/settlements/{id}",
+		Summary:     "獲取結算單",
+		Description: "獲取單一結算單與訂單明細",
+		Tags:        []string{"settlements"},
+		Metadata:    middleware.RequirePermissions(model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.SettlementIDInput) (*settlement.SettlementResponse, error) {
+		s, err := c.settlementService.Get(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到結算單", err)
+		}
+		return &settlement.SettlementResponse{Body: s}, nil
+	})
+
+	// 下載結算單
+	huma.Register(api, huma.Operation{
+		OperationID:   "export-settlement",
+		Method:        "GET",
+		Path:          "/admin/settlements/{id}/export",
+		Summary:       "下載結算單",
+		Description:   "下載單一司機結算單與訂單明細的 Excel 檔案，下載行為會記錄於稽核紀錄",
+		Tags:          []string{"settlements"},
+		DefaultStatus: 200,
+		Metadata:      middleware.RequirePermissions(model.PermissionOperationReport),
+		Middlewares:   huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.SettlementIDInput) (*huma.StreamResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		f, s, err := c.settlementService.ExportStatement(ctx, input.ID, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("settlement_id", input.ID).Msg("匯出結算單失敗")
+			return nil, huma.Error400BadRequest("匯出結算單失敗", err)
+		}
+
+		filename := "settlement_" + s.DriverID + "_" + s.PeriodStart.Format("20060102") + ".xlsx"
+		if s.DriverNo != "" {
+			filename = "settlement_" + s.DriverNo + "_" + s.PeriodStart.Format("20060102") + ".xlsx"
+		}
+		return c.xlsxResponse(f, filename), nil
+	})
+
+	// 確認結算單
+	huma.Register(api, huma.Operation{
+		OperationID: "confirm-settlement",
+		Method:      "POST",
+		Path:        "/admin/settlements/{id}/confirm",
+		Summary:     "確認結算單",
+		Description: "將草稿結算單確認，確認後金額鎖定，不再因重新產生而變動",
+		Tags:        []string{"settlements"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.SettlementIDInput) (*settlement.SettlementResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		s, err := c.settlementService.Confirm(ctx, input.ID, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("settlement_id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("確認結算單失敗")
+			return nil, huma.Error400BadRequest("確認結算單失敗", err)
+		}
+		return &settlement.SettlementResponse{Body: s}, nil
+	})
+
+	// 登記撥款
+	huma.Register(api, huma.Operation{
+		OperationID: "pay-settlement",
+		Method:      "POST",
+		Path:        "/admin/settlements/{id}/pay",
+		Summary:     "登記撥款",
+		Description: "將已確認的結算單登記為已撥款，可附上轉帳序號等撥款參考編號",
+		Tags:        []string{"settlements"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.MarkPaidInput) (*settlement.SettlementResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		s, err := c.settlementService.MarkPaid(ctx, input.ID, input.Body.PaymentRef, userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("settlement_id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("登記撥款失敗")
+			return nil, huma.Error400BadRequest("登記撥款失敗", err)
+		}
+		return &settlement.SettlementResponse{Body: s}, nil
+	})
+
+	// 刪除草稿結算單
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-settlement",
+		Method:      "DELETE",
+		Path:        "/admin/settlements/{id}",
+		Summary:     "刪除草稿結算單",
+		Description: "刪除草稿結算單，已確認或已撥款的結算單不可刪除，稽核紀錄會保留",
+		Tags:        []string{"settlements"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.SettlementIDInput) (*settlement.SimpleResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		if err := c.settlementService.DeleteDraft(ctx, input.ID, userFromToken.Account); err != nil {
+			return nil, huma.Error400BadRequest("刪除結算單失敗", err)
+		}
+
+		response := &settlement.SimpleResponse{}
+		response.Body.Success = true
+		response.Body.Message = "結算單已刪除"
+		return response, nil
+	})
+
+	// 結算單稽核紀錄
+	huma.Register(api, huma.Operation{
+		OperationID: "get-settlement-audit",
+		Method:      "GET",
+		Path:        "/admin/settlements/{id}/audit",
+		Summary:     "結算單稽核紀錄",
+		Description: "列出結算單的產生、確認、撥款、刪除與下載紀錄，紀錄只新增不修改",
+		Tags:        []string{"settlements"},
+		Metadata:    middleware.RequirePermissions(model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *settlement.SettlementIDInput) (*settlement.SettlementAuditResponse, error) {
+		entries, err := c.settlementService.ListAudit(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取稽核紀錄失敗", err)
+		}
+
+		response := &settlement.SettlementAuditResponse{}
+		response.Body.Entries = entries
+		return response, nil
+	})
+}
+
+// xlsxResponse 以 Excel 檔案回應
+func (c *SettlementController) xlsxResponse(f *excelize.File, filename string) *huma.StreamResponse {
+	return &huma.StreamResponse{
+		Body: func(ctx huma.Context) {
+			ctx.SetHeader("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+			ctx.SetHeader("Content-Disposition", "attachment; filename="+filename)
+			if err := f.Write(ctx.BodyWriter()); err != nil {
+				c.logger.Error().Err(err).Msg("寫入 Excel 檔案失敗")
+			}
+		},
+	}
+}
+
+// settlementFilter 將查詢參數轉為結算單查詢條件
+func settlementFilter(input *settlement.ListSettlementsInput) service.SettlementFilter {
+	return service.SettlementFilter{
+		Fleet:     strings.ToUpper(input.Fleet),
+		DriverID:  input.DriverID,
+		Status:    input.Status,
+		StartDate: input.StartDate,
+		EndDate:   input.EndDate,
+	}
+}
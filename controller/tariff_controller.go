@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"context"
+	"right-backend/auth"
+	"right-backend/data-models/tariff"
+	"right-backend/middleware"
+	"right-backend/model"
+	"right-backend/service"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rs/zerolog"
+)
+
+type TariffController struct {
+	logger         zerolog.Logger
+	tariffService  *service.TariffService
+	authMiddleware *middleware.UserAuthMiddleware
+}
+
+func NewTariffController(logger zerolog.Logger, tariffService *service.TariffService, authMiddleware *middleware.UserAuthMiddleware) *TariffController {
+	return &TariffController{
+		logger:         logger.With().Str("module", "tariff_controller").Logger(),
+		tariffService:  tariffService,
+		authMiddleware: authMiddleware,
+	}
+}
+
+func (c *TariffController) RegisterRoutes(api huma.API) {
+	// 列出費率
+	huma.Register(api, huma.Operation{
+		OperationID: "get-tariffs",
+		Method:      "GET",
+		Path:        "/admin/tariffs",
+		Summary:     "列出費率",
+		Description: "列出各車隊與客群的計費規則",
+		Tags:        []string{"tariffs"},
+		Metadata:    middleware.RequireFleetScoped("fleet", model.PermissionOperationReport, model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *tariff.ListTariffsInput) (*tariff.TariffListResponse, error) {
+		tariffs, err := c.tariffService.ListTariffs(ctx, input.Fleet)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("獲取費率失敗", err)
+		}
+
+		response := &tariff.TariffListResponse{}
+		response.Body.Tariffs = tariffs
+		return response, nil
+	})
+
+	// 獲取單一費率
+	huma.Register(api, huma.Operation{
+		OperationID: "get-tariff",
+		Method:      "GET",
+		Path:        "/admin/tariffs/{id}",
+		Summary:     "獲取費率",
+		Description: "獲取單一計費規則",
+		Tags:        []string{"tariffs"},
+		Metadata:    middleware.RequirePermissions(model.PermissionOperationReport, model.PermissionDispatch),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *tariff.TariffIDInput) (*tariff.TariffResponse, error) {
+		t, err := c.tariffService.GetTariff(ctx, input.ID)
+		if err != nil {
+			return nil, huma.Error404NotFound("找不到費率", err)
+		}
+		return &tariff.TariffResponse{Body: t}, nil
+	})
+
+	// 新增費率
+	huma.Register(api, huma.Operation{
+		OperationID: "create-tariff",
+		Method:      "POST",
+		Path:        "/admin/tariffs",
+		Summary:     "新增費率",
+		Description: "新增車隊或客群的計費規則：起跳價、里程、時間、夜間加成、區域加價、寵物與超載加價。客群專屬費率優先於車隊預設費率",
+		Tags:        []string{"tariffs"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *tariff.CreateTariffInput) (*tariff.TariffResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		t, err := c.tariffService.CreateTariff(ctx, tariffFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("fleet", input.Body.Fleet).Str("用戶帳號", userFromToken.Account).Msg("新增費率失敗")
+			return nil, huma.Error400BadRequest("新增費率失敗", err)
+		}
+		return &tariff.TariffResponse{Body: t}, nil
+	})
+
+	// 更新費率
+	huma.Register(api, huma.Operation{
+		OperationID: "update-tariff",
+		Method:      "PUT",
+		Path:        "/admin/tariffs/{id}",
+		Summary:     "更新費率",
+		Description: "更新計費規則，之後建立與完成的訂單立即套用，已計算的車資不會重新計算",
+		Tags:        []string{"tariffs"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *tariff.UpdateTariffInput) (*tariff.TariffResponse, error) {
+		userFromToken, err := auth.GetUserFromContext(ctx)
+		if err != nil {
+			c.logger.Error().Err(err).Msg("無法從token中獲取用戶資訊")
+			return nil, huma.Error500InternalServerError("無法從token中獲取用戶資訊")
+		}
+
+		t, err := c.tariffService.UpdateTariff(ctx, input.ID, tariffFromBody(input.Body), userFromToken.Account)
+		if err != nil {
+			c.logger.Error().Err(err).Str("tariff_id", input.ID).Str("用戶帳號", userFromToken.Account).Msg("更新費率失敗")
+			return nil, huma.Error400BadRequest("更新費率失敗", err)
+		}
+		return &tariff.TariffResponse{Body: t}, nil
+	})
+
+	// 刪除費率
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-tariff",
+		Method:      "DELETE",
+		Path:        "/admin/tariffs/{id}",
+		Summary:     "刪除費率",
+		Description: "刪除計費規則，該客群改用車隊預設費率，車隊沒有費率時不再計算車資",
+		Tags:        []string{"tariffs"},
+		Metadata:    middleware.RequireRoles(model.RoleSystemAdmin, model.RoleModerator),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *tariff.TariffIDInput) (*tariff.SimpleResponse, error) {
+		if err := c.tariffService.DeleteTariff(ctx, input.ID); err != nil {
+			return nil, huma.Error400BadRequest("刪除費率失敗", err)
+		}
+
+		response := &tariff.SimpleResponse{}
+		response.Body.Success = true
+		response.Body.Message = "費率已刪除"
+		return response, nil
+	})
+
+	// 車資試算
+	huma.Register(api, huma.Operation{
+		OperationID: "quote-fare",
+		Method:      "POST",
+		Path:        "/admin/tariffs/quote",
+		Summary:     "車資試算",
+		Description: "以指定車隊、客群、里程與時間試算車資，回傳使用的費率與明細",
+		Tags:        []string{"tariffs"},
+		Metadata:    middleware.RequirePermissions(model.PermissionDispatch, model.PermissionOperationReport),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, func(ctx context.Context, input *tariff.QuoteFareInput) (*tariff.FareResponse, error) {
+		at := time.Now()
+		if input.Body.At != nil {
+			at = *input.Body.At
+		}
+		distanceSource := model.FareDistanceEstimate
+		if input.Body.DistanceKm <= 0 {
+			distanceSource = model.FareDistanceNone
+		}
+
+		fare, err := c.tariffService.Quote(ctx, model.FleetType(strings.ToUpper(input.Body.Fleet)), input.Body.CustomerGroup, model.FareInput{
+			DistanceKm:     input.Body.DistanceKm,
+			DurationMins:   input.Body.DurationMins,
+			DistanceSource: distanceSource,
+			At:             at,
+			Zones:          input.Body.Zones,
+			HasPets:        input.Body.HasPets,
+			HasOverloaded:  input.Body.HasOverloaded,
+		})
+		if err != nil {
+			return nil, huma.Error400BadRequest("車資試算失敗", err)
+		}
+		return &tariff.FareResponse{Body: fare}, nil
+	})
+}
+
+// tariffFromBody 將請求內容轉為費率
+func tariffFromBody(body tariff.TariffBody) *model.Tariff {
+	return &model.Tariff{
+		Fleet:               model.FleetType(strings.ToUpper(body.Fleet)),
+		CustomerGroup:       body.CustomerGroup,
+		Name:                body.Name,
+		FlagFall:            body.FlagFall,
+		FlagFallKm:          body.FlagFallKm,
+		PerKm:               body.PerKm,
+		PerMinute:           body.PerMinute,
+		MinimumFare:         body.MinimumFare,
+		NightSurcharge:      body.NightSurcharge,
+		ZoneSurcharges:      body.ZoneSurcharges,
+		PetSurcharge:        body.PetSurcharge,
+		OverloadedSurcharge: body.OverloadedSurcharge,
+		Enabled:             body.Enabled,
+	}
+}
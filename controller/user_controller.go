@@ -89,6 +89,11 @@ func (c *UserController) RegisterRoutes(api huma.API) {
 		Summary:     "獲取管理員列表（分頁）",
 		Description: "獲取分頁的用戶列表，支援頁碼、每頁數量、車隊篩選和模糊搜尋參數，不返回密碼",
 		Tags:        []string{"users"},
+		Metadata:    middleware.RequireFleetScoped("fleet"),
+		Middlewares: huma.Middlewares{c.authMiddleware.Auth()},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
 	}, func(ctx context.Context, input *user.GetUsersInput) (*user.PaginatedUsersResponse, error) {
 		users, pagination, err := c.userService.GetUsersWithFilterAndPagination(ctx, input.GetPageNum(), input.GetPageSize(), input.Fleet, input.Search)
 		if err != nil {
@@ -213,7 +218,6 @@ func (c *UserController) RegisterRoutes(api huma.API) {
 
 		response := &user.ChangePasswordResponse{}
 		response.Body.Message = "密碼修改成功"
-		response.Body.NewPassword = input.Body.NewPassword
 
 		c.logger.Info().
 			Str("user_id", input.ID).
@@ -28,13 +28,14 @@ type WebSocketController struct {
 	userService      *service.UserService
 	websocketService *service.WebSocketService
 	chatController   *ChatController
+	sessionService   *service.AuthSessionService
 	jwtSecretKey     string
 	upgrader         websocket.Upgrader
 	connections      map[string]*websocketModels.Connection // 統一連接管理
 	connectionsMu    sync.RWMutex
 }
 
-func NewWebSocketController(logger zerolog.Logger, driverService *service.DriverService, userService *service.UserService, chatController *ChatController, jwtSecretKey string) *WebSocketController {
+func NewWebSocketController(logger zerolog.Logger, driverService *service.DriverService, userService *service.UserService, chatController *ChatController, sessionService *service.AuthSessionService, jwtSecretKey string) *WebSocketController {
 	websocketService := service.NewWebSocketService(logger, driverService)
 	wsc := &WebSocketController{
 		logger:           logger.With().Str("module", "websocket_controller").Logger(),
@@ -42,6 +43,7 @@ func NewWebSocketController(logger zerolog.Logger, driverService *service.Driver
 		userService:      userService,
 		websocketService: websocketService,
 		chatController:   chatController,
+		sessionService:   sessionService,
 		jwtSecretKey:     jwtSecretKey,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
@@ -67,7 +69,7 @@ func (wsc *WebSocketController) handleWebSocket(w http.ResponseWriter, r *http.R
 	}
 
 	// 驗證token並獲取用戶信息
-	connInfo, err := wsc.validateToken(token)
+	connInfo, err := wsc.validateToken(r.Context(), token)
 	if err != nil {
 		wsc.logger.Error().Err(err).Msg("token驗證失敗")
 		http.Error(w, fmt.Sprintf("token驗證失敗: %v", err), http.StatusUnauthorized)
@@ -85,6 +87,7 @@ func (wsc *WebSocketController) handleWebSocket(w http.ResponseWriter, r *http.R
 		Type:         connInfo.Type,
 		Conn:         conn,
 		Fleet:        connInfo.Fleet,
+		SessionID:    connInfo.SessionID,
 		LastPing:     time.Now(),
 		SendChannel:  make(chan []byte, 256),
 		CloseChannel: make(chan struct{}),
@@ -103,34 +106,46 @@ func (wsc *WebSocketController) handleWebSocket(w http.ResponseWriter, r *http.R
 
 // ConnectionInfo 連接信息結構
 type ConnectionInfo struct {
-	ID       string
-	Type     websocketModels.ConnectionType
-	Fleet    string
-	UserInfo interface{} // 可以是 *model.DriverInfo 或 *model.User
+	ID        string
+	Type      websocketModels.ConnectionType
+	Fleet     string
+	SessionID string
+	UserInfo  interface{} // 可以是 *model.DriverInfo 或 *model.User
 }
 
 // validateToken 通用token驗證，支援driver和user
-func (wsc *WebSocketController) validateToken(tokenString string) (*ConnectionInfo, error) {
+func (wsc *WebSocketController) validateToken(ctx context.Context, tokenString string) (*ConnectionInfo, error) {
 	// 使用與 REST API 相同的認證邏輯
 	claims, err := auth.ValidateJWTToken(tokenString, wsc.jwtSecretKey)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %v", err)
 	}
 
+	// 檢查登入 session 是否已撤銷
+	if err := wsc.sessionService.CheckAccessClaims(ctx, claims); err != nil {
+		return nil, err
+	}
+
 	// 檢查 token 類型
 	tokenType, ok := claims["type"].(string)
 	if !ok {
 		return nil, fmt.Errorf("missing token type")
 	}
 
+	var connInfo *ConnectionInfo
 	switch tokenType {
 	case string(model.TokenTypeDriver):
-		return wsc.validateDriverTokenClaims(claims)
+		connInfo, err = wsc.validateDriverTokenClaims(claims)
 	case string(model.TokenTypeUser):
-		return wsc.validateUserTokenClaims(claims)
+		connInfo, err = wsc.validateUserTokenClaims(claims)
 	default:
 		return nil, fmt.Errorf("unsupported token type: %s", tokenType)
 	}
+	if err != nil {
+		return nil, err
+	}
+	connInfo.SessionID, _ = claims["sid"].(string)
+	return connInfo, nil
 }
 
 // validateDriverTokenClaims 驗證司機token
@@ -409,6 +424,55 @@ func (wsc *WebSocketController) healthCheck() {
 	}
 }
 
+// StartSessionRevocationWatcher 訂閱 session 撤銷事件，關閉本實例上已撤銷 session 的連線
+func (wsc *WebSocketController) StartSessionRevocationWatcher(ctx context.Context, eventManager *infra.RedisEventManager) {
+	pubsub := eventManager.SubscribeSessionRevocations(ctx)
+	defer pubsub.Close()
+
+	events := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			event, err := infra.ParseSessionRevokedEvent(msg.Payload)
+			if err != nil {
+				wsc.logger.Error().Err(err).Msg("無法解析 session 撤銷事件")
+				continue
+			}
+			wsc.closeRevokedSession(event)
+		}
+	}
+}
+
+// closeRevokedSession 關閉使用已撤銷 session 的連線，同一用戶以新 session 建立的連線不受影響
+func (wsc *WebSocketController) closeRevokedSession(event *infra.SessionRevokedEvent) {
+	wsc.connectionsMu.Lock()
+	conn, exists := wsc.connections[event.SubjectID]
+	if !exists || conn.SessionID != event.SessionID {
+		wsc.connectionsMu.Unlock()
+		return
+	}
+	delete(wsc.connections, event.SubjectID)
+	wsc.connectionsMu.Unlock()
+
+	wsc.logger.Info().
+		Str("user_id", event.SubjectID).
+		Str("session_id", event.SessionID).
+		Str("reason", event.Reason).
+		Msg("登入 session 已撤銷，關閉連線")
+
+	closeMessage := websocket.FormatCloseMessage(websocketModels.CloseCodeSessionRevoked, event.Reason)
+	_ = conn.Conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	conn.CloseOnce.Do(func() {
+		close(conn.CloseChannel)
+	})
+	conn.Conn.Close()
+}
+
 func (wsc *WebSocketController) RegisterRoutes(api huma.API) {}
 
 func (wsc *WebSocketController) GetWebSocketHandler() http.HandlerFunc {
@@ -28,15 +28,16 @@ type ApproveDriverInput struct {
 
 // AdminUpdateDriverProfileInputBody 管理員更新司機個人資料輸入參數 Body
 type AdminUpdateDriverProfileInputBody struct {
-	Name        *string `json:"name,omitempty" example:"王大明" doc:"新的司機姓名"`
-	CarModel    *string `json:"car_model,omitempty" example:"豐田 Camry" doc:"新的車型"`
-	CarAge      *int    `json:"car_age,omitempty" example:"5" doc:"新的車齡"`
-	CarPlate    *string `json:"car_plate,omitempty" example:"ABC-1234" doc:"新的車牌號碼"`
-	CarColor    *string `json:"car_color,omitempty" example:"白色" doc:"新的車輛顏色"`
-	NewPassword *string `json:"newPassword,omitempty" example:"newpassword123" doc:"新的密碼"`
-	Fleet       *string `json:"fleet,omitempty" example:"RSK" doc:"所屬車隊"`
-	IsActive    *bool   `json:"is_active,omitempty" example:"true" doc:"是否啟用"`
-	IsApproved  *bool   `json:"is_approved,omitempty" example:"true" doc:"是否已審核"`
+	Name        *string  `json:"name,omitempty" example:"王大明" doc:"新的司機姓名"`
+	CarModel    *string  `json:"car_model,omitempty" example:"豐田 Camry" doc:"新的車型"`
+	CarAge      *int     `json:"car_age,omitempty" example:"5" doc:"新的車齡"`
+	CarPlate    *string  `json:"car_plate,omitempty" example:"ABC-1234" doc:"新的車牌號碼"`
+	CarColor    *string  `json:"car_color,omitempty" example:"白色" doc:"新的車輛顏色"`
+	NewPassword *string  `json:"newPassword,omitempty" maxLength:"72" example:"newpassword123" doc:"新的密碼"`
+	Fleet       *string  `json:"fleet,omitempty" example:"RSK" doc:"所屬車隊"`
+	IsActive    *bool    `json:"is_active,omitempty" example:"true" doc:"是否啟用"`
+	IsApproved  *bool    `json:"is_approved,omitempty" example:"true" doc:"是否已審核"`
+	Tags        []string `json:"tags,omitempty" example:"[\"airport\"]" doc:"司機標籤（服務區域規則可要求特定標籤），傳入空陣列表示清除"`
 }
 
 // UpdateDriverProfileInput 管理員更新司機個人資料輸入參數
@@ -0,0 +1,34 @@
+package admin
+
+import "right-backend/model"
+
+// DriverDevicesInput 查詢司機設備輸入參數
+type DriverDevicesInput struct {
+	DriverID string `path:"driverId" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"司機ID"`
+}
+
+// PendingDriverDevicesInput 查詢待核准設備輸入參數
+type PendingDriverDevicesInput struct {
+	Fleet string `query:"fleet" example:"RSK" doc:"車隊過濾（選填），不填或全部表示所有車隊"`
+}
+
+// DriverDeviceInput 核准或解除綁定司機設備輸入參數
+type DriverDeviceInput struct {
+	DriverID string `path:"driverId" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"司機ID"`
+	DeviceID string `path:"deviceId" maxLength:"24" minLength:"24" example:"684a73ad0e3a583c37e4b30d" doc:"設備紀錄ID"`
+}
+
+// DriverDevicesResponse 司機設備列表回應
+type DriverDevicesResponse struct {
+	Body struct {
+		Devices []*model.DriverDevice `json:"devices" doc:"設備列表"`
+	} `json:"body"`
+}
+
+// DriverDeviceResponse 司機設備回應
+type DriverDeviceResponse struct {
+	Body struct {
+		Device  *model.DriverDevice `json:"device" doc:"設備"`
+		Message string              `json:"message" example:"已核准設備"`
+	} `json:"body"`
+}
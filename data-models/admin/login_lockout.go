@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"right-backend/data-models/common"
+	"right-backend/model"
+)
+
+// LoginLockoutEventsInput 查詢登入鎖定紀錄輸入參數
+type LoginLockoutEventsInput struct {
+	common.BasePaginationInput
+	Scope   string `query:"scope" enum:"account,ip" example:"account" doc:"鎖定對象過濾（選填）"`
+	Account string `query:"account" example:"driver001@taxi.com" doc:"帳號過濾（選填）"`
+	IP      string `query:"ip" example:"203.0.113.7" doc:"IP 過濾（選填）"`
+}
+
+// LoginLockoutEventsResponse 登入鎖定紀錄列表回應
+type LoginLockoutEventsResponse struct {
+	Body struct {
+		Events     []*model.LoginLockoutEvent `json:"events" doc:"鎖定與解除鎖定紀錄"`
+		Pagination common.PaginationInfo      `json:"pagination" doc:"分頁資訊"`
+	} `json:"body"`
+}
+
+// UnlockLoginAccountInput 解除帳號登入鎖定輸入參數
+type UnlockLoginAccountInput struct {
+	Body struct {
+		LoginType model.TokenType `json:"login_type" enum:"user,driver" example:"driver" doc:"登入類型(user/driver)"`
+		Account   string          `json:"account" minLength:"1" example:"driver001@taxi.com" doc:"登入帳號"`
+	} `json:"body"`
+}
+
+// UnlockLoginIPInput 解除 IP 登入鎖定輸入參數
+type UnlockLoginIPInput struct {
+	Body struct {
+		IP string `json:"ip" minLength:"1" example:"203.0.113.7" doc:"來源 IP"`
+	} `json:"body"`
+}
+
+// UnlockLoginResponse 解除登入鎖定回應
+type UnlockLoginResponse struct {
+	Body struct {
+		Event   *model.LoginLockoutEvent `json:"event" doc:"解除鎖定紀錄"`
+		Message string                   `json:"message" example:"已解除登入鎖定"`
+	} `json:"body"`
+}
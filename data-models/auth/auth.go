@@ -2,18 +2,21 @@ package auth
 
 import (
 	"right-backend/model"
+	"time"
 )
 
 type DriverLoginInput struct {
 	Body struct {
 		Account            string `json:"account" doc:"帳號" example:"driver001@taxi.com"`
 		Password           string `json:"password" doc:"密碼" example:"123456"`
+		DeviceID           string `json:"device_id,omitempty" doc:"設備識別碼，App 安裝後產生並保存（選填，未提供時以設備資訊識別）" example:"8F3C2A1E-6B7D-4E21-9C55-0A1B2C3D4E5F"`
 		DeviceModelName    string `json:"device_model_name,omitempty" doc:"設備型號名稱（選填）" example:"iPhone15,4"`
 		DeviceDeviceName   string `json:"device_device_name,omitempty" doc:"設備名稱（選填）" example:"My iPhone"`
 		DeviceBrand        string `json:"device_brand,omitempty" doc:"設備品牌（選填）" example:"Apple"`
 		DeviceManufacturer string `json:"device_manufacturer,omitempty" doc:"設備製造商（選填）" example:"Apple Inc."`
 		DeviceAppVersion   string `json:"device_app_version,omitempty" doc:"應用程式版本（選填）" example:"1.0.0"`
 	} `json:"body"`
+	UserAgent string `header:"User-Agent"`
 }
 
 type LoginInput struct {
@@ -21,21 +24,67 @@ type LoginInput struct {
 		Account  string `json:"account" doc:"帳號" example:"user001@gmail.com"`
 		Password string `json:"password" doc:"密碼" example:"123456"`
 	} `json:"body"`
+	UserAgent string `header:"User-Agent"`
 }
 
 type UserLoginResponse struct {
 	Body struct {
-		User    *model.User `json:"user"`
-		Token   string      `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-		Message string      `json:"message" example:"登入成功"`
+		User         *model.User `json:"user"`
+		Token        string      `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..." doc:"access token"`
+		ExpiresAt    time.Time   `json:"expires_at" doc:"access token 到期時間"`
+		RefreshToken string      `json:"refresh_token" doc:"refresh token，用於換發 access token"`
+		SessionID    string      `json:"session_id" example:"684a73ad0e3a583c37e4b30d" doc:"登入 session ID"`
+		Message      string      `json:"message" example:"登入成功"`
 	} `json:"body"`
 }
 
 type DriverLoginResponse struct {
 	Body struct {
-		Driver  *model.DriverInfo `json:"driver"`
-		Token   string            `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-		Message string            `json:"message" example:"登入成功"`
+		Driver       *model.DriverInfo `json:"driver"`
+		Token        string            `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..." doc:"access token"`
+		ExpiresAt    time.Time         `json:"expires_at" doc:"access token 到期時間"`
+		RefreshToken string            `json:"refresh_token" doc:"refresh token，用於換發 access token"`
+		SessionID    string            `json:"session_id" example:"684a73ad0e3a583c37e4b30d" doc:"登入 session ID"`
+		Message      string            `json:"message" example:"登入成功"`
+	} `json:"body"`
+}
+
+// RefreshTokenInput 換發 access token 或登出時提供的 refresh token
+type RefreshTokenInput struct {
+	Body struct {
+		RefreshToken string `json:"refresh_token" minLength:"1" doc:"refresh token"`
+	} `json:"body"`
+}
+
+type RefreshTokenResponse struct {
+	Body model.AuthTokens `json:"body"`
+}
+
+type LogoutResponse struct {
+	Body struct {
+		Message string `json:"message" example:"已登出"`
+	} `json:"body"`
+}
+
+// SessionSubjectInput 查詢或撤銷指定用戶或司機的 session
+type SessionSubjectInput struct {
+	ID string `path:"id" doc:"用戶或司機ID"`
+}
+
+type SessionIDInput struct {
+	SessionID string `path:"sessionId" doc:"登入 session ID"`
+}
+
+type SessionListResponse struct {
+	Body struct {
+		Sessions []*model.AuthSession `json:"sessions" doc:"有效的登入 session，最新登入在前"`
+	} `json:"body"`
+}
+
+type RevokeSessionsResponse struct {
+	Body struct {
+		Revoked int    `json:"revoked" example:"2" doc:"撤銷的 session 數量"`
+		Message string `json:"message" example:"已強制登出"`
 	} `json:"body"`
 }
 
@@ -45,7 +94,7 @@ type DriverRegisterInput struct {
 		Nickname   string `json:"nickname" doc:"司機暱稱" example:"小明"`
 		DriverNo   string `json:"driver_no" doc:"司機編號" example:"D001"`
 		Account    string `json:"account" doc:"司機帳號" example:"driver001@taxi.com"`
-		Password   string `json:"password" doc:"密碼" example:"driver123"`
+		Password   string `json:"password" doc:"密碼" maxLength:"72" example:"driver123"`
 		CarPlate   string `json:"car_plate" doc:"車牌號碼" example:"6793黑"`
 		Fleet      string `json:"fleet" doc:"所屬車隊" example:"RSK"`
 		CarModel   string `json:"car_model" doc:"車型" example:"納智捷S5"`
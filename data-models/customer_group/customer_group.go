@@ -0,0 +1,84 @@
+package customer_group
+
+import "right-backend/model"
+
+// CustomerGroupCodeInput 客群代碼路徑參數
+type CustomerGroupCodeInput struct {
+	Code string `path:"code" minLength:"1" maxLength:"20" example:"R1" doc:"客群代碼"`
+}
+
+// ListCustomerGroupsInput 客群列表查詢
+type ListCustomerGroupsInput struct {
+	MonthlyOnly bool `query:"monthly_only" doc:"只列出月結客群"`
+}
+
+// CustomerGroupBody 客群內容
+type CustomerGroupBody struct {
+	Name                 string                `json:"name" minLength:"1" maxLength:"100" example:"遠東飯店" doc:"客戶名稱"`
+	Contact              model.CustomerContact `json:"contact" doc:"聯絡資訊"`
+	Billing              model.BillingTerms    `json:"billing" doc:"請款條件"`
+	DefaultFleet         string                `json:"default_fleet,omitempty" maxLength:"20" example:"RSK" doc:"預設車隊，建單未指定車隊時優先使用，不填表示依客群前綴判斷"`
+	AllowedLineConfigIDs []string              `json:"allowed_line_config_ids,omitempty" maxItems:"20" doc:"允許透過哪些 LINE Bot 配置下單，不填表示不限"`
+	MonthlyBilling       bool                  `json:"monthly_billing" example:"true" doc:"是否月結"`
+	Enabled              bool                  `json:"enabled" example:"true" doc:"是否啟用，停用後不可建立該客群訂單"`
+	Notes                string                `json:"notes,omitempty" maxLength:"500" doc:"備註"`
+}
+
+// CreateCustomerGroupInput 新增客群
+type CreateCustomerGroupInput struct {
+	Body struct {
+		Code string `json:"code" minLength:"1" maxLength:"20" example:"R1" doc:"客群代碼（訂單文字 / 前的部分，建立後不可修改）"`
+		CustomerGroupBody
+	} `json:"body"`
+}
+
+// UpdateCustomerGroupInput 更新客群
+type UpdateCustomerGroupInput struct {
+	Code string            `path:"code" minLength:"1" maxLength:"20" example:"R1" doc:"客群代碼"`
+	Body CustomerGroupBody `json:"body"`
+}
+
+// InvoiceInput 客群月結請款單查詢
+type InvoiceInput struct {
+	Code  string `path:"code" minLength:"1" maxLength:"20" example:"R1" doc:"客群代碼"`
+	Month string `path:"month" pattern:"^[0-9]{4}-[0-9]{2}$" example:"2025-01" doc:"請款月份（YYYY-MM，台北時間）"`
+}
+
+// InvoiceSummaryInput 月結請款總覽查詢
+type InvoiceSummaryInput struct {
+	Month string `query:"month" required:"true" pattern:"^[0-9]{4}-[0-9]{2}$" example:"2025-01" doc:"請款月份（YYYY-MM，台北時間）"`
+}
+
+// CustomerGroupResponse 單一客群回應
+type CustomerGroupResponse struct {
+	Body *model.CustomerGroup `json:"body"`
+}
+
+// CustomerGroupListResponse 客群列表回應
+type CustomerGroupListResponse struct {
+	Body struct {
+		CustomerGroups []*model.CustomerGroup `json:"customer_groups" doc:"客群列表"`
+		StrictMode     bool                   `json:"strict_mode" doc:"是否啟用嚴格模式（未登錄的客群不可建單）"`
+	} `json:"body"`
+}
+
+// InvoiceResponse 客群月結請款單回應
+type InvoiceResponse struct {
+	Body *model.CustomerGroupInvoice `json:"body"`
+}
+
+// InvoiceSummaryResponse 月結請款總覽回應
+type InvoiceSummaryResponse struct {
+	Body struct {
+		Month     string                          `json:"month" example:"2025-01" doc:"請款月份"`
+		Summaries []*model.CustomerInvoiceSummary `json:"summaries" doc:"各月結客群的訂單數與請款金額"`
+	} `json:"body"`
+}
+
+// SimpleResponse 簡單回應
+type SimpleResponse struct {
+	Body struct {
+		Success bool   `json:"success" example:"true"`
+		Message string `json:"message" example:"客群已刪除"`
+	} `json:"body"`
+}
@@ -29,6 +29,11 @@ type DriverStats struct {
 	Count int `json:"count"`
 }
 
+// DashboardFleetInput 儀表板車隊過濾參數，只能檢視自家車隊的用戶固定為所屬車隊
+type DashboardFleetInput struct {
+	Fleet string `query:"fleet" example:"RSK" doc:"車隊過濾（選填），不填或全部表示所有車隊"`
+}
+
 type DashboardStatsResponse struct {
 	Body *DashboardStats `json:"stats"`
 }
@@ -42,6 +47,7 @@ type GetAllDriversResponse struct {
 
 type GetDriverOrdersInput struct {
 	common.BaseSearchPaginationInput
+	Fleet        string `query:"fleet" example:"RSK" doc:"車隊過濾（選填），不填或全部表示所有車隊"`
 	DriverStatus string `query:"driverStatus" doc:"司機狀態過濾：all(全部)/閒置/前往上車點/司機抵達/執行任務，預設為 all"`
 }
 
@@ -90,9 +96,10 @@ type DriverWeeklyOrderRank struct {
 }
 
 type GetDriverWeeklyOrderRanksInput struct {
-	WeekOffset int `query:"week_offset" doc:"週數偏移，0為本週，-1為上週，1為下週" example:"0"`
-	PageNum    int `query:"page_num" doc:"頁碼" example:"1"`
-	PageSize   int `query:"page_size" doc:"每頁筆數" example:"20"`
+	WeekOffset int    `query:"week_offset" doc:"週數偏移，0為本週，-1為上週，1為下週" example:"0"`
+	Fleet      string `query:"fleet" example:"RSK" doc:"車隊過濾（選填），不填或全部表示所有車隊"`
+	PageNum    int    `query:"page_num" doc:"頁碼" example:"1"`
+	PageSize   int    `query:"page_size" doc:"每頁筆數" example:"20"`
 }
 
 func (input *GetDriverWeeklyOrderRanksInput) GetPageNum() int {
@@ -0,0 +1,30 @@
+package dead_letter
+
+import (
+	"right-backend/data-models/common"
+	"right-backend/model"
+)
+
+// GetDeadLetterOrdersInput 死信訂單列表查詢參數
+type GetDeadLetterOrdersInput struct {
+	common.BasePaginationInput
+	Status string `query:"status" doc:"狀態過濾：pending(待處理)/replayed(已重新派送)，不填表示全部"`
+}
+
+// DeadLetterOrdersResponse 死信訂單列表回應
+type DeadLetterOrdersResponse struct {
+	Body struct {
+		DeadLetters []*model.DeadLetterOrder `json:"dead_letters" doc:"死信訂單列表"`
+		Pagination  common.PaginationInfo    `json:"pagination" doc:"分頁資訊"`
+	} `json:"body"`
+}
+
+// DeadLetterIDInput 死信ID路徑參數
+type DeadLetterIDInput struct {
+	ID string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"死信ID"`
+}
+
+// DeadLetterOrderResponse 單筆死信訂單回應
+type DeadLetterOrderResponse struct {
+	Body *model.DeadLetterOrder `json:"body"`
+}
@@ -0,0 +1,49 @@
+package dispatch_policy
+
+import "right-backend/model"
+
+// FleetPathInput 車隊路徑參數
+type FleetPathInput struct {
+	Fleet string `path:"fleet" example:"RSK" doc:"車隊代碼"`
+}
+
+// UpsertDispatchPolicyInput 新增或更新車隊派單策略
+type UpsertDispatchPolicyInput struct {
+	Fleet string `path:"fleet" example:"RSK" doc:"車隊代碼"`
+	Body  struct {
+		HaversineCandidatesCount   int                   `json:"haversine_candidates_count" minimum:"1" maximum:"50" example:"15" doc:"直線距離初步篩選的候選司機數量上限"`
+		GoogleAPICandidatesCount   int                   `json:"google_api_candidates_count" minimum:"1" maximum:"20" example:"5" doc:"真實路徑計算後的最終派單人數"`
+		SequentialCallTimeoutSecs  int                   `json:"sequential_call_timeout_secs" minimum:"5" maximum:"120" example:"19" doc:"依序呼叫每位司機的等待秒數"`
+		EnableMaxDriverDistance    bool                  `json:"enable_max_driver_distance" example:"true" doc:"是否啟用司機直線距離篩選"`
+		MaxDriverDistanceKm        float64               `json:"max_driver_distance_km" example:"15" doc:"司機直線距離上限（公里）"`
+		EnableMaxEstimatedTimeMins bool                  `json:"enable_max_estimated_time_mins" example:"true" doc:"是否啟用真實路徑預估時間篩選"`
+		MaxEstimatedTimeMins       int                   `json:"max_estimated_time_mins" example:"20" doc:"真實路徑預估時間上限（分鐘）"`
+		RankingStrategy            string                `json:"ranking_strategy,omitempty" enum:"nearest_eta,fairness,acceptance_rate" example:"nearest_eta" doc:"司機排序策略，不填表示使用 config.yml 設定"`
+		DispatchMode               string                `json:"dispatch_mode,omitempty" enum:"sequential,broadcast" example:"sequential" doc:"派單模式：sequential 依序呼叫、broadcast 同時推播先搶先贏，不填表示依序呼叫"`
+		BroadcastCount             int                   `json:"broadcast_count,omitempty" minimum:"0" maximum:"20" example:"3" doc:"搶單模式同時推播的司機數量，0 表示推播給所有最終候選司機"`
+		Rounds                     []model.DispatchRound `json:"rounds,omitempty" maxItems:"10" doc:"多輪派單擴大半徑設定，例如 5 公里、10 公里、15 公里並開放跨車隊，不填表示只派一輪"`
+	} `json:"body"`
+}
+
+// DispatchPolicyResponse 單一車隊派單策略回應
+type DispatchPolicyResponse struct {
+	Body struct {
+		Policy    *model.DispatchPolicy `json:"policy" doc:"目前生效的派單策略"`
+		IsDefault bool                  `json:"is_default" doc:"是否為系統預設值（尚未自訂）"`
+	} `json:"body"`
+}
+
+// DispatchPolicyListResponse 派單策略列表回應
+type DispatchPolicyListResponse struct {
+	Body struct {
+		Policies []*model.DispatchPolicy `json:"policies" doc:"已自訂的車隊派單策略"`
+	} `json:"body"`
+}
+
+// SimpleResponse 簡單回應
+type SimpleResponse struct {
+	Body struct {
+		Success bool   `json:"success" example:"true"`
+		Message string `json:"message" example:"派單策略已重設為預設值"`
+	} `json:"body"`
+}
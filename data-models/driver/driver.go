@@ -83,6 +83,31 @@ type CompleteOrderInput struct {
 	} `json:"body"`
 }
 
+type PassengerNoShowInput struct {
+	Body struct {
+		OrderID string `json:"order_id" doc:"訂單ID" example:"664a73ad0e3a583c37e4b30d"`
+	} `json:"body"`
+}
+
+type PassengerNoShowResponse struct {
+	Body struct {
+		BaseDriverActionResponse
+		CancellationFee *model.CancellationFee `json:"cancellation_fee" doc:"乘客未到費用"`
+	} `json:"body"`
+}
+
+type WaypointActionInput struct {
+	OrderID string `path:"orderId" doc:"訂單ID"`
+	Seq     int    `path:"seq" minimum:"1" doc:"停靠順序(從1開始)"`
+}
+
+type WaypointActionResponse struct {
+	Body struct {
+		BaseDriverActionResponse
+		Waypoints []model.Waypoint `json:"waypoints" doc:"更新後的停靠點"`
+	} `json:"body"`
+}
+
 type ArrivePickupLocationInput struct {
 	Body struct {
 		OrderID string `json:"order_id" doc:"訂單ID" example:"664a73ad0e3a583c37e4b30d"`
@@ -261,7 +286,7 @@ type UpdateDriverProfileInputBody struct {
 	CarAge      *int    `json:"car_age,omitempty" example:"5" doc:"新的車齡"`
 	CarPlate    *string `json:"car_plate,omitempty" example:"ABC-1234" doc:"新的車牌號碼"`
 	CarColor    *string `json:"car_color,omitempty" example:"白色" doc:"新的車輛顏色"`
-	NewPassword *string `json:"newPassword,omitempty" example:"newpassword123" doc:"新的密碼"`
+	NewPassword *string `json:"newPassword,omitempty" maxLength:"72" example:"newpassword123" doc:"新的密碼"`
 }
 
 type UpdateDriverProfileInput struct {
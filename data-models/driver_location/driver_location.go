@@ -0,0 +1,44 @@
+package driver_location
+
+import (
+	"right-backend/model"
+	"time"
+)
+
+// GetDriverTrackInput 司機軌跡查詢參數
+type GetDriverTrackInput struct {
+	ID   string    `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"司機ID"`
+	From time.Time `query:"from" required:"true" example:"2025-08-01T08:00:00+08:00" doc:"開始時間 (RFC3339)"`
+	To   time.Time `query:"to" required:"true" example:"2025-08-01T10:00:00+08:00" doc:"結束時間 (RFC3339)，與開始時間相差不可超過24小時"`
+}
+
+// DriverTrackResponse 司機軌跡回應
+type DriverTrackResponse struct {
+	Body struct {
+		DriverID string                  `json:"driver_id" doc:"司機ID"`
+		From     time.Time               `json:"from" doc:"開始時間"`
+		To       time.Time               `json:"to" doc:"結束時間"`
+		Points   []*model.DriverLocation `json:"points" doc:"軌跡點（依時間排序）"`
+	} `json:"body"`
+}
+
+// OrderTrackInput 訂單軌跡查詢參數
+type OrderTrackInput struct {
+	ID string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"訂單ID"`
+}
+
+// OrderTrackResponse 訂單執行期間的司機軌跡回應，附上抵達時間與偏差供爭議稽核
+type OrderTrackResponse struct {
+	Body struct {
+		OrderID              string                  `json:"order_id" doc:"訂單ID"`
+		ShortID              string                  `json:"short_id" doc:"訂單短ID"`
+		DriverID             string                  `json:"driver_id" doc:"接單司機ID"`
+		PickupLat            *string                 `json:"pickup_lat,omitempty" doc:"上車點緯度"`
+		PickupLng            *string                 `json:"pickup_lng,omitempty" doc:"上車點經度"`
+		AcceptanceTime       *time.Time              `json:"acceptance_time,omitempty" doc:"接單時間"`
+		ArrivalTime          *time.Time              `json:"arrival_time,omitempty" doc:"司機回報抵達時間"`
+		CompletionTime       *time.Time              `json:"completion_time,omitempty" doc:"完成時間"`
+		ArrivalDeviationSecs *int                    `json:"arrival_deviation_secs,omitempty" doc:"到達時間偏差(秒)，正值表示遲到"`
+		Points               []*model.DriverLocation `json:"points" doc:"軌跡點（依時間排序）"`
+	} `json:"body"`
+}
@@ -0,0 +1,53 @@
+package fleet
+
+import "right-backend/model"
+
+// FleetCodeInput 車隊代碼路徑參數
+type FleetCodeInput struct {
+	Code string `path:"code" minLength:"1" maxLength:"20" example:"RSK" doc:"車隊代碼"`
+}
+
+// FleetBody 車隊登錄內容
+type FleetBody struct {
+	DisplayName           string              `json:"display_name" maxLength:"50" example:"RSK 車隊" doc:"顯示名稱，不填表示使用車隊代碼"`
+	CustomerGroupPrefixes []string            `json:"customer_group_prefixes" maxItems:"20" example:"[\"R\"]" doc:"客群前綴，建單未指定車隊時依此判斷所屬車隊（最長前綴優先）"`
+	DispatchToFleets      []model.FleetType   `json:"dispatch_to_fleets" maxItems:"20" example:"[\"KD\"]" doc:"本車隊訂單可跨車隊派給哪些車隊的司機，本車隊司機一律可派"`
+	IsDefault             bool                `json:"is_default" example:"false" doc:"客群前綴皆不符合時使用的預設車隊"`
+	Settings              model.FleetSettings `json:"settings" doc:"車隊個別設定"`
+	Enabled               bool                `json:"enabled" example:"true" doc:"是否啟用"`
+}
+
+// CreateFleetInput 新增車隊
+type CreateFleetInput struct {
+	Body struct {
+		Code string `json:"code" minLength:"1" maxLength:"20" example:"TPE" doc:"車隊代碼（唯一，建立後不可修改）"`
+		FleetBody
+	} `json:"body"`
+}
+
+// UpdateFleetInput 更新車隊
+type UpdateFleetInput struct {
+	Code string    `path:"code" minLength:"1" maxLength:"20" example:"RSK" doc:"車隊代碼"`
+	Body FleetBody `json:"body"`
+}
+
+// FleetResponse 單一車隊回應
+type FleetResponse struct {
+	Body *model.Fleet `json:"body"`
+}
+
+// FleetListResponse 車隊列表回應
+type FleetListResponse struct {
+	Body struct {
+		Fleets        []*model.Fleet `json:"fleets" doc:"車隊列表"`
+		UsingDefaults bool           `json:"using_defaults" doc:"fleets 集合尚無資料，目前使用系統預設車隊"`
+	} `json:"body"`
+}
+
+// SimpleResponse 簡單回應
+type SimpleResponse struct {
+	Body struct {
+		Success bool   `json:"success" example:"true"`
+		Message string `json:"message" example:"車隊已刪除"`
+	} `json:"body"`
+}
@@ -66,7 +66,7 @@ type UpdateOrderStatusInput struct {
 type SimpleCreateOrderInput struct {
 	Body struct {
 		OriText string `json:"oriText" huma:"description=A single line of text representing one order" validate:"required"`
-		Fleet   string `json:"fleet" huma:"description=Fleet type for the order" example:"RSK" validate:"required" enum:"RSK,KD,WEI"`
+		Fleet   string `json:"fleet" huma:"description=Fleet type for the order" example:"RSK" validate:"required"`
 	} `json:"body"`
 }
 
@@ -177,3 +177,13 @@ type CurrentOrderWithDriverStatusResponse struct {
 		DriverStatus string       `json:"driver_status" doc:"司機當前狀態" example:"前往中"`
 	} `json:"body"`
 }
+
+// OrderTimelineResponse 訂單事件時間軸回應
+type OrderTimelineResponse struct {
+	Body struct {
+		OrderID string              `json:"order_id" doc:"訂單ID"`
+		ShortID string              `json:"short_id" doc:"訂單短ID"`
+		Status  model.OrderStatus   `json:"status" doc:"訂單目前狀態"`
+		Events  []*model.OrderEvent `json:"events" doc:"訂單事件（依時間排序）"`
+	} `json:"body"`
+}
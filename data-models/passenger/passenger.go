@@ -0,0 +1,82 @@
+package passenger
+
+import (
+	"right-backend/data-models/common"
+	"right-backend/model"
+)
+
+// PassengerIDInput 乘客ID路徑參數
+type PassengerIDInput struct {
+	ID string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"乘客ID"`
+}
+
+// ListPassengersInput 乘客列表查詢
+type ListPassengersInput struct {
+	common.BaseSearchPaginationInput
+	BlockedOnly bool `query:"blocked_only" doc:"只列出已封鎖的乘客"`
+}
+
+// PassengerBody 乘客內容
+type PassengerBody struct {
+	Name              string                  `json:"name,omitempty" maxLength:"50" example:"林先生" doc:"稱呼"`
+	Phone             string                  `json:"phone,omitempty" maxLength:"30" example:"0912345678" doc:"電話"`
+	Notes             string                  `json:"notes,omitempty" maxLength:"500" example:"需要輪椅空間，常帶大型犬" doc:"給派單員與司機的備註"`
+	Tags              []string                `json:"tags,omitempty" maxItems:"20" example:"[\"輪椅\",\"寵物\"]" doc:"標籤"`
+	FavoriteAddresses []model.FavoriteAddress `json:"favorite_addresses,omitempty" maxItems:"20" doc:"常用地點"`
+	Blocked           bool                    `json:"blocked" example:"false" doc:"是否封鎖，封鎖後無法透過 LINE 下單"`
+	BlockedReason     string                  `json:"blocked_reason,omitempty" maxLength:"200" doc:"封鎖原因"`
+}
+
+// CreatePassengerInput 新增乘客
+type CreatePassengerInput struct {
+	Body struct {
+		LineUserID string `json:"line_user_id,omitempty" maxLength:"64" example:"Uxxxxxxxx" doc:"LINE 用戶ID，填寫後該用戶透過 LINE 下單會自動連結"`
+		PassengerBody
+	} `json:"body"`
+}
+
+// UpdatePassengerInput 更新乘客
+type UpdatePassengerInput struct {
+	ID   string        `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"乘客ID"`
+	Body PassengerBody `json:"body"`
+}
+
+// PassengerOrdersInput 乘客訂單歷史查詢
+type PassengerOrdersInput struct {
+	ID    string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"乘客ID"`
+	Limit int    `query:"limit" default:"10" minimum:"1" maximum:"100" doc:"筆數"`
+}
+
+// OrderPassengerHistoryInput 訂單乘客歷史查詢
+type OrderPassengerHistoryInput struct {
+	ID    string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"訂單ID"`
+	Limit int    `query:"limit" default:"5" minimum:"1" maximum:"50" doc:"最近幾趟行程"`
+}
+
+// PassengerResponse 單一乘客回應
+type PassengerResponse struct {
+	Body *model.Passenger `json:"body"`
+}
+
+// PassengerListResponse 乘客列表回應
+type PassengerListResponse struct {
+	Body struct {
+		Passengers []*model.Passenger    `json:"passengers" doc:"乘客列表"`
+		Pagination common.PaginationInfo `json:"pagination" doc:"分頁資訊"`
+	} `json:"body"`
+}
+
+// PassengerOrdersResponse 乘客訂單歷史回應
+type PassengerOrdersResponse struct {
+	Body struct {
+		Orders []*model.Order `json:"orders" doc:"最近的訂單（新到舊）"`
+	} `json:"body"`
+}
+
+// OrderPassengerHistoryResponse 訂單乘客歷史回應
+type OrderPassengerHistoryResponse struct {
+	Body struct {
+		Passenger    *model.Passenger `json:"passenger" doc:"乘客資料，訂單未連結乘客時為 null"`
+		RecentOrders []*model.Order   `json:"recent_orders" doc:"乘客最近的行程（不含本訂單，新到舊）"`
+	} `json:"body"`
+}
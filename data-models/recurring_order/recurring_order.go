@@ -0,0 +1,96 @@
+package recurring_order
+
+import (
+	"right-backend/data-models/common"
+	"right-backend/model"
+)
+
+// RecurringOrderIDInput 週期性預約ID路徑參數
+type RecurringOrderIDInput struct {
+	ID string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"週期性預約ID"`
+}
+
+// ListRecurringOrdersInput 週期性預約列表查詢
+type ListRecurringOrdersInput struct {
+	common.BaseSearchPaginationInput
+	Status string `query:"status" enum:"active,cancelled" doc:"狀態篩選，未指定為全部"`
+}
+
+// RecurringOrderBody 週期性預約內容
+type RecurringOrderBody struct {
+	Name    string               `json:"name" minLength:"1" maxLength:"100" example:"台大醫院 平日洗腎接送" doc:"名稱"`
+	OriText string               `json:"ori_text" minLength:"1" maxLength:"500" example:"A1/台大醫院 洗腎接送" doc:"訂單文字（客群/地址 備註），不需包含時間"`
+	Fleet   string               `json:"fleet,omitempty" maxLength:"20" example:"RSK" doc:"指定車隊，空白時依客群決定"`
+	Rule    model.RecurrenceRule `json:"rule" doc:"週期規則"`
+}
+
+// CreateRecurringOrderInput 新增週期性預約
+type CreateRecurringOrderInput struct {
+	Body RecurringOrderBody `json:"body"`
+}
+
+// UpdateRecurringOrderInput 更新週期性預約
+type UpdateRecurringOrderInput struct {
+	ID   string             `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"週期性預約ID"`
+	Body RecurringOrderBody `json:"body"`
+}
+
+// OccurrencesInput 單次預約查詢
+type OccurrencesInput struct {
+	ID   string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"週期性預約ID"`
+	Days int    `query:"days" default:"14" minimum:"1" maximum:"60" doc:"列出今天起幾天內的單次預約"`
+}
+
+// SkipOccurrenceInput 略過單次預約
+type SkipOccurrenceInput struct {
+	ID   string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"週期性預約ID"`
+	Body struct {
+		Date   string `json:"date" pattern:"^\\d{4}-\\d{2}-\\d{2}$" example:"2025-10-10" doc:"略過的日期（YYYY-MM-DD）"`
+		Reason string `json:"reason,omitempty" maxLength:"200" example:"國慶日停診" doc:"略過原因"`
+	} `json:"body"`
+}
+
+// CancelSeriesInput 取消整個系列
+type CancelSeriesInput struct {
+	ID   string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"週期性預約ID"`
+	Body struct {
+		Reason string `json:"reason,omitempty" maxLength:"200" example:"合約結束" doc:"取消原因"`
+	} `json:"body"`
+}
+
+// RecurringOrderResponse 單一週期性預約回應
+type RecurringOrderResponse struct {
+	Body *model.RecurringOrder `json:"body"`
+}
+
+// RecurringOrderListResponse 週期性預約列表回應
+type RecurringOrderListResponse struct {
+	Body struct {
+		RecurringOrders []*model.RecurringOrder `json:"recurring_orders" doc:"週期性預約列表"`
+		DaysAhead       int                     `json:"days_ahead" doc:"提前產生幾天內的預約單"`
+		Pagination      common.PaginationInfo   `json:"pagination" doc:"分頁資訊"`
+	} `json:"body"`
+}
+
+// OccurrencesResponse 單次預約列表回應
+type OccurrencesResponse struct {
+	Body struct {
+		Occurrences []model.RecurringOccurrence `json:"occurrences" doc:"單次預約（依日期排序）"`
+	} `json:"body"`
+}
+
+// SkipOccurrenceResponse 略過單次預約回應
+type SkipOccurrenceResponse struct {
+	Body struct {
+		RecurringOrder *model.RecurringOrder `json:"recurring_order" doc:"更新後的週期性預約"`
+		CancelledOrder *model.Order          `json:"cancelled_order,omitempty" doc:"一併取消的預約單（該日已產生且尚未開始時）"`
+	} `json:"body"`
+}
+
+// CancelSeriesResponse 取消整個系列回應
+type CancelSeriesResponse struct {
+	Body struct {
+		RecurringOrder  *model.RecurringOrder `json:"recurring_order" doc:"已取消的週期性預約"`
+		CancelledOrders int                   `json:"cancelled_orders" doc:"一併取消的已產生預約單數"`
+	} `json:"body"`
+}
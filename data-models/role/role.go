@@ -38,7 +38,7 @@ type GetRolesInput struct {
 	common.BasePaginationInput
 	IncludeSystem bool   `query:"include_system" example:"true" doc:"是否包含系統角色，預設為 false"`
 	Search        string `query:"search" example:"管理" doc:"模糊搜尋角色名稱"`
-	Fleet         string `query:"fleet" example:"RSK" doc:"根據車隊過濾角色" enum:"RSK,KD,WEI"`
+	Fleet         string `query:"fleet" example:"RSK" doc:"根據車隊過濾角色"`
 }
 
 // RoleIDInput 角色ID輸入
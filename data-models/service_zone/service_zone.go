@@ -0,0 +1,48 @@
+package service_zone
+
+import "right-backend/model"
+
+// ServiceZoneIDInput 服務區域ID路徑參數
+type ServiceZoneIDInput struct {
+	ID string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"區域ID"`
+}
+
+// ServiceZoneBody 服務區域內容
+type ServiceZoneBody struct {
+	Name        string           `json:"name" minLength:"1" maxLength:"50" example:"桃園機場" doc:"區域名稱"`
+	Description string           `json:"description,omitempty" maxLength:"200" doc:"說明"`
+	Area        model.GeoPolygon `json:"area" doc:"區域範圍（GeoJSON Polygon，座標為 [經度, 緯度] 且首尾相同）"`
+	Rules       []model.ZoneRule `json:"rules" maxItems:"20" doc:"各車隊規則"`
+	Enabled     bool             `json:"enabled" example:"true" doc:"是否啟用"`
+}
+
+// CreateServiceZoneInput 新增服務區域
+type CreateServiceZoneInput struct {
+	Body ServiceZoneBody `json:"body"`
+}
+
+// UpdateServiceZoneInput 更新服務區域
+type UpdateServiceZoneInput struct {
+	ID   string          `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"區域ID"`
+	Body ServiceZoneBody `json:"body"`
+}
+
+// ServiceZoneResponse 單一服務區域回應
+type ServiceZoneResponse struct {
+	Body *model.ServiceZone `json:"body"`
+}
+
+// ServiceZoneListResponse 服務區域列表回應
+type ServiceZoneListResponse struct {
+	Body struct {
+		Zones []*model.ServiceZone `json:"zones" doc:"服務區域列表"`
+	} `json:"body"`
+}
+
+// SimpleResponse 簡單回應
+type SimpleResponse struct {
+	Body struct {
+		Success bool   `json:"success" example:"true"`
+		Message string `json:"message" example:"服務區域已刪除"`
+	} `json:"body"`
+}
@@ -0,0 +1,73 @@
+package settlement
+
+import (
+	"right-backend/model"
+	"right-backend/service"
+)
+
+// SettlementIDInput 結算單ID路徑參數
+type SettlementIDInput struct {
+	ID string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"結算單ID"`
+}
+
+// ListSettlementsInput 結算單列表查詢
+type ListSettlementsInput struct {
+	Fleet     string `query:"fleet" example:"RSK" doc:"依司機車隊篩選"`
+	DriverID  string `query:"driver_id" doc:"依司機篩選"`
+	Status    string `query:"status" enum:"draft,confirmed,paid," doc:"依狀態篩選"`
+	StartDate string `query:"start_date" example:"2025-01-01" doc:"結算期間與此日期之後重疊（YYYY-MM-DD，台北時間）"`
+	EndDate   string `query:"end_date" example:"2025-01-31" doc:"結算期間與此日期之前重疊（YYYY-MM-DD，台北時間）"`
+}
+
+// GenerateSettlementsInput 產生結算單
+type GenerateSettlementsInput struct {
+	Body struct {
+		Fleet     string `json:"fleet,omitempty" example:"RSK" doc:"只產生此車隊司機的結算單，不填表示全部車隊"`
+		DriverID  string `json:"driver_id,omitempty" doc:"只產生此司機的結算單"`
+		StartDate string `json:"start_date" example:"2025-01-01" doc:"結算期間開始日（YYYY-MM-DD，台北時間）"`
+		EndDate   string `json:"end_date" example:"2025-01-15" doc:"結算期間結束日（YYYY-MM-DD，台北時間，含當天）"`
+	} `json:"body"`
+}
+
+// MarkPaidInput 登記撥款
+type MarkPaidInput struct {
+	ID   string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"結算單ID"`
+	Body struct {
+		PaymentRef string `json:"payment_ref,omitempty" maxLength:"100" example:"TX20250116001" doc:"撥款參考編號（轉帳序號等）"`
+	} `json:"body"`
+}
+
+// GenerateSettlementsResponse 產生結算單回應
+type GenerateSettlementsResponse struct {
+	Body struct {
+		Settlements []*model.Settlement      `json:"settlements" doc:"產生或重新產生的草稿結算單"`
+		Skipped     []service.SettlementSkip `json:"skipped" doc:"略過的司機與原因"`
+	} `json:"body"`
+}
+
+// SettlementResponse 單一結算單回應
+type SettlementResponse struct {
+	Body *model.Settlement `json:"body"`
+}
+
+// SettlementListResponse 結算單列表回應
+type SettlementListResponse struct {
+	Body struct {
+		Settlements []*model.Settlement `json:"settlements" doc:"結算單列表（不含訂單明細）"`
+	} `json:"body"`
+}
+
+// SettlementAuditResponse 結算單稽核紀錄回應
+type SettlementAuditResponse struct {
+	Body struct {
+		Entries []*model.SettlementAuditEntry `json:"entries" doc:"稽核紀錄"`
+	} `json:"body"`
+}
+
+// SimpleResponse 簡單回應
+type SimpleResponse struct {
+	Body struct {
+		Success bool   `json:"success" example:"true"`
+		Message string `json:"message" example:"結算單已刪除"`
+	} `json:"body"`
+}
@@ -0,0 +1,83 @@
+package tariff
+
+import (
+	"right-backend/model"
+	"time"
+)
+
+// TariffIDInput 費率ID路徑參數
+type TariffIDInput struct {
+	ID string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"費率ID"`
+}
+
+// ListTariffsInput 費率列表查詢
+type ListTariffsInput struct {
+	Fleet string `query:"fleet" example:"RSK" doc:"依車隊篩選"`
+}
+
+// TariffBody 費率內容
+type TariffBody struct {
+	Fleet               string                `json:"fleet" minLength:"1" example:"RSK" doc:"車隊代碼"`
+	CustomerGroup       string                `json:"customer_group,omitempty" maxLength:"20" example:"R1" doc:"客群，不填表示車隊預設費率"`
+	Name                string                `json:"name,omitempty" maxLength:"50" example:"RSK 一般費率" doc:"費率名稱"`
+	FlagFall            int                   `json:"flag_fall" minimum:"0" example:"85" doc:"起跳價（元）"`
+	FlagFallKm          float64               `json:"flag_fall_km" minimum:"0" example:"1.25" doc:"起跳價包含的公里數"`
+	PerKm               float64               `json:"per_km" minimum:"0" example:"20" doc:"超過起跳公里數後每公里費用（元）"`
+	PerMinute           float64               `json:"per_minute" minimum:"0" example:"3" doc:"每分鐘行車時間費用（元）"`
+	MinimumFare         int                   `json:"minimum_fare" minimum:"0" example:"100" doc:"最低車資（元），0 表示不限"`
+	NightSurcharge      model.NightSurcharge  `json:"night_surcharge" doc:"夜間加成，加成百分比與固定加價皆為 0 表示不加成"`
+	ZoneSurcharges      []model.ZoneSurcharge `json:"zone_surcharges,omitempty" maxItems:"50" doc:"服務區域加價，區域名稱需與服務區域設定相同"`
+	PetSurcharge        int                   `json:"pet_surcharge" minimum:"0" example:"50" doc:"攜帶寵物加價（元）"`
+	OverloadedSurcharge int                   `json:"overloaded_surcharge" minimum:"0" example:"100" doc:"超過四人加價（元）"`
+	Enabled             bool                  `json:"enabled" example:"true" doc:"是否啟用"`
+}
+
+// CreateTariffInput 新增費率
+type CreateTariffInput struct {
+	Body TariffBody `json:"body"`
+}
+
+// UpdateTariffInput 更新費率
+type UpdateTariffInput struct {
+	ID   string     `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"費率ID"`
+	Body TariffBody `json:"body"`
+}
+
+// QuoteFareInput 車資試算
+type QuoteFareInput struct {
+	Body struct {
+		Fleet         string     `json:"fleet" minLength:"1" example:"RSK" doc:"車隊代碼"`
+		CustomerGroup string     `json:"customer_group,omitempty" example:"R1" doc:"客群"`
+		DistanceKm    float64    `json:"distance_km" minimum:"0" example:"8.3" doc:"里程（公里）"`
+		DurationMins  float64    `json:"duration_mins" minimum:"0" example:"22" doc:"行車時間（分鐘）"`
+		At            *time.Time `json:"at,omitempty" doc:"上車時間，用於判斷夜間加成，不填表示現在"`
+		Zones         []string   `json:"zones,omitempty" doc:"上車點或目的地所在的服務區域名稱"`
+		HasPets       bool       `json:"has_pets,omitempty" doc:"是否攜帶寵物"`
+		HasOverloaded bool       `json:"has_overloaded,omitempty" doc:"是否超過四人"`
+	} `json:"body"`
+}
+
+// TariffResponse 單一費率回應
+type TariffResponse struct {
+	Body *model.Tariff `json:"body"`
+}
+
+// TariffListResponse 費率列表回應
+type TariffListResponse struct {
+	Body struct {
+		Tariffs []*model.Tariff `json:"tariffs" doc:"費率列表"`
+	} `json:"body"`
+}
+
+// FareResponse 車資試算回應
+type FareResponse struct {
+	Body *model.FareBreakdown `json:"body"`
+}
+
+// SimpleResponse 簡單回應
+type SimpleResponse struct {
+	Body struct {
+		Success bool   `json:"success" example:"true"`
+		Message string `json:"message" example:"費率已刪除"`
+	} `json:"body"`
+}
@@ -25,9 +25,9 @@ type UserWithoutPassword struct {
 type CreateUserInput struct {
 	Body struct {
 		Account  string          `json:"account" minLength:"1" maxLength:"100" example:"admin@taxi.com" doc:"帳號"`
-		Password string          `json:"password" minLength:"1" maxLength:"100" example:"admin123" doc:"密碼"`
+		Password string          `json:"password" minLength:"1" maxLength:"72" example:"admin123" doc:"密碼"`
 		Name     string          `json:"name" minLength:"1" maxLength:"50" example:"管理員" doc:"姓名"`
-		Fleet    model.FleetType `json:"fleet" example:"RSK" doc:"所屬車隊" enum:"RSK,KD,WEI"`
+		Fleet    model.FleetType `json:"fleet" example:"RSK" doc:"所屬車隊（車隊代碼，見 /admin/fleets）"`
 		Role     model.UserRole  `json:"role" example:"管理員" doc:"角色" enum:"系統管理員,版主,管理員,調度"`
 	} `json:"body"`
 }
@@ -65,14 +65,13 @@ type ChangePasswordInput struct {
 	ID   string `path:"id" maxLength:"24" minLength:"24" example:"507f1f77bcf86cd799439011" doc:"用戶ID"`
 	Body struct {
 		OldPassword string `json:"old_password" minLength:"1" maxLength:"100" example:"oldpassword123" doc:"舊密碼"`
-		NewPassword string `json:"new_password" minLength:"1" maxLength:"100" example:"newpassword123" doc:"新密碼"`
+		NewPassword string `json:"new_password" minLength:"1" maxLength:"72" example:"newpassword123" doc:"新密碼"`
 	} `json:"body"`
 }
 
 type ChangePasswordResponse struct {
 	Body struct {
-		Message     string `json:"message" example:"密碼修改成功" doc:"回應訊息"`
-		NewPassword string `json:"new_password" example:"newpassword123" doc:"新密碼"`
+		Message string `json:"message" example:"密碼修改成功" doc:"回應訊息"`
 	} `json:"body"`
 }
 
@@ -82,7 +81,7 @@ type UpdateUserProfileInput struct {
 		Account  string `json:"account,omitempty" maxLength:"100" example:"admin@taxi.com" doc:"帳號"`
 		Role     string `json:"role,omitempty" maxLength:"50" example:"管理員" doc:"角色"`
 		Fleet    string `json:"fleet,omitempty" maxLength:"50" example:"RSK" doc:"所屬車隊"`
-		Password string `json:"password,omitempty" minLength:"1" maxLength:"100" example:"newpassword123" doc:"新密碼（若提供則立即更改密碼）"`
+		Password string `json:"password,omitempty" minLength:"1" maxLength:"72" example:"newpassword123" doc:"新密碼（若提供則立即更改密碼）"`
 	} `json:"body"`
 }
 
@@ -93,7 +92,7 @@ type UpdateUserProfileByIdInput struct {
 		Account  string `json:"account,omitempty" maxLength:"100" example:"admin@taxi.com" doc:"帳號"`
 		Role     string `json:"role,omitempty" maxLength:"50" example:"管理員" doc:"角色"`
 		Fleet    string `json:"fleet,omitempty" maxLength:"50" example:"RSK" doc:"所屬車隊"`
-		Password string `json:"password,omitempty" minLength:"1" maxLength:"100" example:"newpassword123" doc:"新密碼（若提供則立即更改密碼）"`
+		Password string `json:"password,omitempty" minLength:"1" maxLength:"72" example:"newpassword123" doc:"新密碼（若提供則立即更改密碼）"`
 	} `json:"body"`
 }
 
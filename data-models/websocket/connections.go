@@ -21,6 +21,7 @@ type Connection struct {
 	Type         ConnectionType   `json:"type"`                // 連接類型
 	Conn         *websocket.Conn  `json:"-"`                   // WebSocket連接
 	Fleet        string           `json:"fleet"`               // 車隊信息
+	SessionID    string           `json:"session_id"`          // 登入 session ID，session 撤銷時關閉連線
 	LastPing     time.Time        `json:"last_ping"`           // 最後ping時間
 	SendChannel  chan []byte      `json:"-"`                   // 發送通道
 	CloseChannel chan struct{}    `json:"-"`                   // 關閉通道
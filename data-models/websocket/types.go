@@ -37,6 +37,9 @@ const (
 	MessageTypeOrderStatusUpdate = "order_status_update"
 )
 
+// CloseCodeSessionRevoked 登入 session 已撤銷（登出、其他設備登入、設備解除綁定）時關閉連線的代碼
+const CloseCodeSessionRevoked = 4001
+
 // WebSocket 連線狀態
 type ConnectionStatus string
 
@@ -25,6 +25,26 @@ type Config struct {
 		Enabled       bool `yaml:"enabled"`
 		ExpiryMinutes int  `yaml:"expiry_minutes"`
 	} `yaml:"driver_blacklist"`
+	Dispatcher struct {
+		RankingStrategies       map[string]string `yaml:"ranking_strategies"`          // 車隊代碼 -> 司機排序策略 (nearest_eta/fairness/acceptance_rate)
+		Workers                 int               `yaml:"workers"`                     // 同時處理訂單的 worker 數量
+		Prefetch                int               `yaml:"prefetch"`                    // RabbitMQ 預取訊息數量，未設定時等於 workers
+		MaxRetries              int               `yaml:"max_retries"`                 // 訂單派單失敗重試次數，超過後移入死信隊列
+		RecoveryIntervalSecs    int               `yaml:"recovery_interval_secs"`      // 中斷訂單恢復掃描間隔（秒）
+		RecoveryMaxOrderAgeMins int               `yaml:"recovery_max_order_age_mins"` // 中斷訂單建立超過此分鐘數直接流單，否則重新排入隊列
+		RecoveryQueuedGraceMins int               `yaml:"recovery_queued_grace_mins"`  // 排入隊列後尚未開始派單的訂單，超過此分鐘數才視為中斷
+	} `yaml:"dispatcher"`
+	DriverLocation struct {
+		HistoryIntervalSecs int `yaml:"history_interval_secs"` // 位置歷史最短寫入間隔（秒），司機狀態或訂單變更時立即寫入
+		RetentionDays       int `yaml:"retention_days"`        // 位置歷史保留天數，由 cmd/init 設定集合的自動過期時間，未設定時 30 天
+	} `yaml:"driver_location"`
+	CustomerGroups struct {
+		Strict bool `yaml:"strict"` // 嚴格模式：客群必須已登錄且啟用才可建單
+	} `yaml:"customer_groups"`
+	RecurringOrders struct {
+		DaysAhead    int `yaml:"days_ahead"`    // 週期性預約提前產生幾天內的預約單
+		IntervalMins int `yaml:"interval_mins"` // 週期性預約產生排程的執行間隔（分鐘）
+	} `yaml:"recurring_orders"`
 	MongoDB struct {
 		URI      string `yaml:"uri"`
 		Database string `yaml:"database"`
@@ -44,9 +64,20 @@ type Config struct {
 		ServerKey string `yaml:"server_key"`
 	} `yaml:"fcm"`
 	JWT struct {
-		SecretKey    string `yaml:"secret_key"`
-		ExpiresHours int    `yaml:"expires_hours"`
+		SecretKey            string `yaml:"secret_key"`
+		ExpiresHours         int    `yaml:"expires_hours"`          // refresh token（登入 session）有效小時數
+		AccessExpiresMinutes int    `yaml:"access_expires_minutes"` // access token 有效分鐘數
 	} `yaml:"jwt"`
+	LoginLimit struct {
+		AccountMaxFailures     int `yaml:"account_max_failures"`       // 同一帳號在統計期間內失敗幾次後鎖定
+		IPMaxFailures          int `yaml:"ip_max_failures"`            // 同一 IP 在統計期間內失敗幾次後鎖定
+		IPMaxAttemptsPerMinute int `yaml:"ip_max_attempts_per_minute"` // 同一 IP 每分鐘最多登入次數
+		FailureWindowMinutes   int `yaml:"failure_window_minutes"`     // 失敗次數統計期間（分鐘）
+		LockoutMinutes         int `yaml:"lockout_minutes"`            // 第一次鎖定分鐘數，之後每次鎖定加倍
+		MaxLockoutMinutes      int `yaml:"max_lockout_minutes"`        // 鎖定分鐘數上限
+		// TrustedProxies 可信任的反向代理 IP 或 CIDR，只有來自這些位址的請求才採用 X-Forwarded-For / X-Real-IP
+		TrustedProxies []string `yaml:"trusted_proxies"`
+	} `yaml:"login_limit"`
 	Discord struct {
 		BotToken string `yaml:"bot_token"`
 	} `yaml:"discord"`
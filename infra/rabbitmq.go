@@ -85,3 +85,18 @@ func (r *RabbitMQ) PublishMessage(queueName string, body []byte) error {
 			Body:        body,
 		})
 }
+
+// PublishMessageWithHeaders 發送帶有標頭的持久化訊息（用於重試計數等中繼資料）
+func (r *RabbitMQ) PublishMessageWithHeaders(queueName string, body []byte, headers amqp.Table) error {
+	return r.Channel.Publish(
+		"",        // exchange
+		queueName, // routing key
+		false,     // mandatory
+		false,     // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Headers:      headers,
+			Body:         body,
+		})
+}
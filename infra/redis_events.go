@@ -783,12 +783,15 @@ func (rem *RedisEventManager) ReleaseDriverNotification(ctx context.Context, dri
 
 // AtomicAcceptOrder 原子性接單檢查
 // 供司機接單 API 使用，確保司機只能接受正在通知的訂單
+// 搶單模式下多位司機同時收到同一訂單，以 order_accepted_by 決定唯一得標司機
 func (rem *RedisEventManager) AtomicAcceptOrder(ctx context.Context, driverID, orderID string) (success bool, reason string, err error) {
 	script := `
 		local driver_state_key = "driver_state:" .. ARGV[1]
 		local driver_lock_key = "driver_notification_lock:" .. ARGV[1]
 		local order_claim_key = "order_claimed:" .. ARGV[2]
+		local order_winner_key = "order_accepted_by:" .. ARGV[2]
 		local accept_time = ARGV[3]
+		local winner_ttl = tonumber(ARGV[4])
 
 		-- 檢查司機是否正在接收這個訂單的通知
 		local expected_order = redis.call("HGET", driver_state_key, "notification_order_id")
@@ -802,6 +805,13 @@ func (rem *RedisEventManager) AtomicAcceptOrder(ctx context.Context, driverID, o
 			return {0, "already_has_order:" .. current_order}
 		end
 
+		-- 檢查訂單是否已被其他司機搶先接走
+		local winner = redis.call("GET", order_winner_key)
+		if winner and winner ~= ARGV[1] then
+			return {0, "order_taken_by:" .. winner}
+		end
+		redis.call("SETEX", order_winner_key, winner_ttl, ARGV[1])
+
 		-- 原子性設置司機接單狀態
 		redis.call("HSET", driver_state_key,
 			"status", "busy",
@@ -821,8 +831,9 @@ func (rem *RedisEventManager) AtomicAcceptOrder(ctx context.Context, driverID, o
 	`
 
 	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	winnerTTL := 1 * time.Hour
 	result, err := rem.client.Eval(ctx, script, []string{},
-		driverID, orderID, timestamp).Result()
+		driverID, orderID, timestamp, int(winnerTTL.Seconds())).Result()
 
 	if err != nil {
 		rem.logger.Error().Err(err).
@@ -852,6 +863,39 @@ func (rem *RedisEventManager) AtomicAcceptOrder(ctx context.Context, driverID, o
 	return success, reason, nil
 }
 
+// ReleaseOrderWinner 司機搶單成功但訂單未成功寫入資料庫時，釋放該司機的得標紀錄讓其他司機可以接單
+func (rem *RedisEventManager) ReleaseOrderWinner(ctx context.Context, orderID, driverID string) error {
+	script := `
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0
+	`
+	if err := rem.client.Eval(ctx, script, []string{orderWinnerKey(orderID)}, driverID).Err(); err != nil {
+		rem.logger.Error().Err(err).
+			Str("driver_id", driverID).
+			Str("order_id", orderID).
+			Msg("釋放搶單得標紀錄失敗")
+		return err
+	}
+	return nil
+}
+
+// ClearOrderWinner 訂單重新派單、取消或回到等待接單時清除搶單得標紀錄，避免新一輪的司機被擋下
+func (rem *RedisEventManager) ClearOrderWinner(ctx context.Context, orderID string) error {
+	if err := rem.client.Del(ctx, orderWinnerKey(orderID)).Err(); err != nil {
+		rem.logger.Error().Err(err).
+			Str("order_id", orderID).
+			Msg("清除搶單得標紀錄失敗")
+		return err
+	}
+	return nil
+}
+
+func orderWinnerKey(orderID string) string {
+	return "order_accepted_by:" + orderID
+}
+
 // StartCleanupWatcher 啟動自動清理監聽器
 // 監聽 Redis 過期事件並自動清理相關狀態
 func (rem *RedisEventManager) StartCleanupWatcher(ctx context.Context) {
@@ -977,3 +1021,126 @@ func (rem *RedisEventManager) ClearDriverStateAfterComplete(ctx context.Context,
 
 	return nil
 }
+
+// ConfigChangeType 執行期設定變更類型
+type ConfigChangeType string
+
+const (
+	ConfigChangeDispatchPolicy ConfigChangeType = "dispatch_policy" // 車隊派單策略
+	ConfigChangeFleet          ConfigChangeType = "fleet"           // 車隊登錄資料
+)
+
+// ConfigChangeEvent 執行期設定變更事件，通知所有實例重新載入設定
+type ConfigChangeEvent struct {
+	ConfigType ConfigChangeType `json:"config_type"`
+	Key        string           `json:"key,omitempty"` // 變更的設定鍵值（如車隊代碼）
+	Timestamp  time.Time        `json:"timestamp"`
+}
+
+// ToJSON 轉換為 JSON 字串
+func (cce *ConfigChangeEvent) ToJSON() string {
+	data, _ := json.Marshal(cce)
+	return string(data)
+}
+
+// ParseConfigChangeEvent 解析設定變更事件
+func ParseConfigChangeEvent(payload string) (*ConfigChangeEvent, error) {
+	var event ConfigChangeEvent
+	err := json.Unmarshal([]byte(payload), &event)
+	return &event, err
+}
+
+// PublishConfigChangeEvent 發布設定變更事件
+func (rem *RedisEventManager) PublishConfigChangeEvent(ctx context.Context, event *ConfigChangeEvent) error {
+	channel := "config_changes"
+	payload := event.ToJSON()
+
+	err := rem.client.Publish(ctx, channel, payload).Err()
+	if err != nil {
+		rem.logger.Error().Err(err).
+			Str("channel", channel).
+			Str("config_type", string(event.ConfigType)).
+			Str("key", event.Key).
+			Msg("發布設定變更事件失敗")
+		return err
+	}
+
+	rem.logger.Info().
+		Str("channel", channel).
+		Str("config_type", string(event.ConfigType)).
+		Str("key", event.Key).
+		Msg("設定變更事件已發布")
+
+	return nil
+}
+
+// SubscribeConfigChanges 訂閱設定變更事件
+func (rem *RedisEventManager) SubscribeConfigChanges(ctx context.Context) *redis.PubSub {
+	channel := "config_changes"
+	pubsub := rem.client.Subscribe(ctx, channel)
+
+	rem.logger.Info().
+		Str("channel", channel).
+		Msg("開始訂閱設定變更事件")
+
+	return pubsub
+}
+
+// SessionRevokedEvent 登入 session 撤銷事件，通知所有實例關閉該 session 的 WebSocket 連線
+type SessionRevokedEvent struct {
+	SessionID   string    `json:"session_id"`
+	SubjectType string    `json:"subject_type"` // user/driver
+	SubjectID   string    `json:"subject_id"`
+	Reason      string    `json:"reason"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ToJSON 轉換為 JSON 字串
+func (sre *SessionRevokedEvent) ToJSON() string {
+	data, _ := json.Marshal(sre)
+	return string(data)
+}
+
+// ParseSessionRevokedEvent 解析 session 撤銷事件
+func ParseSessionRevokedEvent(payload string) (*SessionRevokedEvent, error) {
+	var event SessionRevokedEvent
+	err := json.Unmarshal([]byte(payload), &event)
+	return &event, err
+}
+
+// PublishSessionRevokedEvent 發布 session 撤銷事件
+func (rem *RedisEventManager) PublishSessionRevokedEvent(ctx context.Context, event *SessionRevokedEvent) error {
+	channel := "session_revocations"
+	payload := event.ToJSON()
+
+	err := rem.client.Publish(ctx, channel, payload).Err()
+	if err != nil {
+		rem.logger.Error().Err(err).
+			Str("channel", channel).
+			Str("session_id", event.SessionID).
+			Str("subject_id", event.SubjectID).
+			Msg("發布 session 撤銷事件失敗")
+		return err
+	}
+
+	rem.logger.Debug().
+		Str("channel", channel).
+		Str("session_id", event.SessionID).
+		Str("subject_id", event.SubjectID).
+		Str("reason", event.Reason).
+		Msg("session 撤銷事件已發布")
+
+	return nil
+}
+
+// SubscribeSessionRevocations 訂閱 session 撤銷事件
+func (rem *RedisEventManager) SubscribeSessionRevocations(ctx context.Context) *redis.PubSub {
+	channel := "session_revocations"
+	pubsub := rem.client.Subscribe(ctx, channel)
+
+	rem.logger.Info().
+		Str("channel", channel).
+		Msg("開始訂閱 session 撤銷事件")
+
+	return pubsub
+}
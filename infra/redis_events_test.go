@@ -0,0 +1,94 @@
+package infra
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+func newTestEventManager(t *testing.T) (*RedisEventManager, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisEventManager(client, zerolog.Nop()), client
+}
+
+// notifyDriver 模擬調度中心推播訂單給司機
+func notifyDriver(t *testing.T, client *redis.Client, driverID, orderID string) {
+	t.Helper()
+	if err := client.HSet(context.Background(), "driver_state:"+driverID, "notification_order_id", orderID).Err(); err != nil {
+		t.Fatalf("設定司機通知狀態失敗: %v", err)
+	}
+}
+
+func acceptOrder(t *testing.T, rem *RedisEventManager, driverID, orderID string) (bool, string) {
+	t.Helper()
+	success, reason, err := rem.AtomicAcceptOrder(context.Background(), driverID, orderID)
+	if err != nil {
+		t.Fatalf("原子性接單檢查失敗: %v", err)
+	}
+	return success, reason
+}
+
+func TestAtomicAcceptOrderAfterRedispatch(t *testing.T) {
+	rem, client := newTestEventManager(t)
+	ctx := context.Background()
+	const orderID = "order-1"
+
+	// 第一輪：司機 A 搶單成功，同時收到推播的司機 B 被擋下
+	notifyDriver(t, client, "driver-a", orderID)
+	notifyDriver(t, client, "driver-b", orderID)
+	if success, reason := acceptOrder(t, rem, "driver-a", orderID); !success {
+		t.Fatalf("預期司機 A 搶單成功，實際失敗: %s", reason)
+	}
+	if success, reason := acceptOrder(t, rem, "driver-b", orderID); success || !strings.HasPrefix(reason, "order_taken_by:driver-a") {
+		t.Fatalf("預期司機 B 因訂單已被司機 A 接走而失敗，實際 success=%v reason=%s", success, reason)
+	}
+
+	// 重新派單：訂單回到等待接單並清除得標紀錄，新一輪推播給司機 B
+	if err := rem.ClearOrderWinner(ctx, orderID); err != nil {
+		t.Fatalf("清除得標紀錄失敗: %v", err)
+	}
+	notifyDriver(t, client, "driver-b", orderID)
+	if success, reason := acceptOrder(t, rem, "driver-b", orderID); !success {
+		t.Fatalf("預期重新派單後司機 B 可以接單，實際失敗: %s", reason)
+	}
+
+	winner, err := client.Get(ctx, "order_accepted_by:"+orderID).Result()
+	if err != nil || winner != "driver-b" {
+		t.Fatalf("預期得標司機為 driver-b，實際為 %q (err=%v)", winner, err)
+	}
+}
+
+func TestReleaseOrderWinnerOnlyReleasesOwnClaim(t *testing.T) {
+	rem, client := newTestEventManager(t)
+	ctx := context.Background()
+	const orderID = "order-2"
+
+	notifyDriver(t, client, "driver-a", orderID)
+	if success, reason := acceptOrder(t, rem, "driver-a", orderID); !success {
+		t.Fatalf("預期司機 A 搶單成功，實際失敗: %s", reason)
+	}
+
+	// 其他司機不可釋放司機 A 的得標紀錄
+	if err := rem.ReleaseOrderWinner(ctx, orderID, "driver-b"); err != nil {
+		t.Fatalf("釋放得標紀錄失敗: %v", err)
+	}
+	if exists := client.Exists(ctx, "order_accepted_by:"+orderID).Val(); exists != 1 {
+		t.Fatal("非得標司機不應釋放得標紀錄")
+	}
+
+	// 司機 A 寫入資料庫失敗後釋放，司機 B 可以接單
+	if err := rem.ReleaseOrderWinner(ctx, orderID, "driver-a"); err != nil {
+		t.Fatalf("釋放得標紀錄失敗: %v", err)
+	}
+	notifyDriver(t, client, "driver-b", orderID)
+	if success, reason := acceptOrder(t, rem, "driver-b", orderID); !success {
+		t.Fatalf("預期司機 A 釋放後司機 B 可以接單，實際失敗: %s", reason)
+	}
+}
@@ -201,6 +201,12 @@ func main() {
 		// 創建 Redis 事件管理器（需要在 OrderService 之前）
 		eventManager := infra.NewRedisEventManager(services.Redis.Client, log.Logger)
 
+		// 創建車隊登錄服務（建單、派單與 Discord 指令共用，需在 OrderService 之前）
+		fleetService := service.NewFleetService(log.Logger, services.MongoDB, eventManager)
+		if err := fleetService.Reload(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("載入車隊登錄資料失敗，暫時使用預設車隊")
+		}
+
 		// 1. 初始化 DiscordService (暫時不傳入 orderService)
 		if infra.AppConfig.Discord.BotToken != "" && infra.AppConfig.Discord.BotToken != "YOUR_DISCORD_BOT_TOKEN" {
 			var err error
@@ -220,6 +226,19 @@ func main() {
 
 		// 3. 初始化 OrderService
 		orderService = service.NewOrderService(log.Logger, services.MongoDB, services.RabbitMQ, googleService, crawlerService, eventManager)
+		orderService.SetFleetService(fleetService)
+
+		// 客群服務（建單檢查客群與預設車隊、LINE 下單限制、月結請款）
+		customerGroupService := service.NewCustomerGroupService(log.Logger, services.MongoDB)
+		customerGroupService.SetFleetService(fleetService)
+		orderService.SetCustomerGroupService(customerGroupService)
+
+		// 訂單事件稽核紀錄（order_events）
+		orderEventService := service.NewOrderEventService(log.Logger, services.MongoDB)
+		orderService.SetOrderEventService(orderEventService)
+
+		// 乘客服務（LINE 下單自動連結乘客資料）
+		passengerService := service.NewPassengerService(log.Logger, services.MongoDB)
 
 		// 4. 創建統一的通知服務（Worker Pool 模式）
 		// 設定 3 個 worker，隊列大小 100
@@ -237,6 +256,7 @@ func main() {
 
 		// 5. 將 orderService 注入回 discordService
 		if discordService != nil {
+			discordService.SetFleetService(fleetService)
 			discordService.SetOrderService(orderService)
 		}
 
@@ -288,6 +308,8 @@ func main() {
 						Err(err).
 						Msg("初始化 LINE 服務失敗")
 				} else {
+					lineService.SetCustomerGroupService(customerGroupService)
+					lineService.SetPassengerService(passengerService)
 					log.Info().
 						Int("configs_count", len(lineConfigs)).
 						Msg("LINE Bot 服務已初始化")
@@ -306,7 +328,35 @@ func main() {
 		}
 
 		trafficUsageLogService := service.NewTrafficUsageLogService(log.Logger, services.MongoDB)
-		userService := service.NewUserService(log.Logger, services.MongoDB, infra.AppConfig.JWT.SecretKey, infra.AppConfig.JWT.ExpiresHours)
+		userService := service.NewUserService(log.Logger, services.MongoDB)
+
+		// 登入 session：短效 access token、可輪替 refresh token 與 Redis 撤銷清單
+		authSessionService := service.NewAuthSessionService(log.Logger, services.MongoDB, services.Redis.Client, infra.AppConfig.JWT.SecretKey)
+		authSessionService.SetEventManager(eventManager)
+		userService.SetAuthSessionService(authSessionService)
+		driverDeviceService := service.NewDriverDeviceService(log.Logger, services.MongoDB, authSessionService)
+		// 登入防暴力破解：帳號與 IP 失敗次數過多時鎖定
+		loginLimiterService := service.NewLoginLimiterService(log.Logger, services.MongoDB, services.Redis.Client)
+		// Redis 已於啟動時清空，由 MongoDB 重建仍在有效期內的撤銷紀錄
+		if restored, err := authSessionService.RestoreRevocations(context.Background()); err != nil {
+			log.Error().
+				Err(err).
+				Msg("重建登入 session 撤銷清單失敗")
+		} else {
+			log.Info().
+				Int("restored", restored).
+				Msg("登入 session 撤銷清單重建完成")
+		}
+		// 由稽核紀錄重建仍在鎖定中的帳號與 IP，避免重新啟動後解除鎖定
+		if restored, err := loginLimiterService.RestoreLockouts(context.Background()); err != nil {
+			log.Error().
+				Err(err).
+				Msg("重建登入鎖定失敗")
+		} else {
+			log.Info().
+				Int("restored", restored).
+				Msg("登入鎖定重建完成")
+		}
 		roleService := service.NewRoleService(log.Logger, services.MongoDB)
 
 		// 初始化系統角色
@@ -330,7 +380,12 @@ func main() {
 		// 初始化司機黑名單服務
 		blacklistService := service.NewDriverBlacklistService(log.Logger, services.Redis.Client)
 
-		driverService := service.NewDriverService(log.Logger, services.MongoDB, infra.AppConfig.JWT.SecretKey, infra.AppConfig.JWT.ExpiresHours, orderService, googleService, crawlerService, trafficUsageLogService, blacklistService, eventManager, notificationService)
+		driverService := service.NewDriverService(log.Logger, services.MongoDB, orderService, googleService, crawlerService, trafficUsageLogService, blacklistService, eventManager, notificationService)
+		driverService.SetAuthSessionService(authSessionService)
+
+		// 司機位置歷史軌跡（位置更新時節流寫入）
+		driverLocationService := service.NewDriverLocationService(log.Logger, services.MongoDB, orderService)
+		driverService.SetLocationService(driverLocationService)
 
 		// 設定司機服務依賴到訂單服務（避免循環依賴）
 		orderService.SetDriverService(driverService)
@@ -365,13 +420,21 @@ func main() {
 		discordService.SetNotificationService(notificationService)
 
 		// WebSocket控制器
-		webSocketController := controller.NewWebSocketController(log.Logger, driverService, userService, chatController, infra.AppConfig.JWT.SecretKey)
+		webSocketController := controller.NewWebSocketController(log.Logger, driverService, userService, chatController, authSessionService, infra.AppConfig.JWT.SecretKey)
+		// 登入 session 撤銷時關閉所有實例上對應的 WebSocket 連線
+		go webSocketController.StartSessionRevocationWatcher(context.Background(), eventManager)
 
 		// Auth Middleware
-		driverAuthMiddleware := authMiddleware.NewDriverAuthMiddleware(driverService, infra.AppConfig.JWT.SecretKey)
-		userAuthMiddleware := authMiddleware.NewUserAuthMiddleware(userService, infra.AppConfig.JWT.SecretKey)
+		driverAuthMiddleware := authMiddleware.NewDriverAuthMiddleware(driverService, authSessionService, infra.AppConfig.JWT.SecretKey)
+		userAuthMiddleware := authMiddleware.NewUserAuthMiddleware(userService, authSessionService, roleService, infra.AppConfig.JWT.SecretKey)
+		loginRateLimitMiddleware, err := authMiddleware.NewLoginRateLimitMiddleware(loginLimiterService, infra.AppConfig.LoginLimit.TrustedProxies)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("初始化登入限流失敗")
+		}
 
-		orderController := controller.NewOrderController(log.Logger, orderService, driverService, userAuthMiddleware, notificationService)
+		orderController := controller.NewOrderController(log.Logger, orderService, driverService, userAuthMiddleware, notificationService, orderEventService)
 
 		// 創建 OrderScheduleService 和 Controller
 		orderScheduleService := service.NewOrderScheduleService(log.Logger, orderService, driverService)
@@ -379,11 +442,16 @@ func main() {
 
 		// 創建 OrderSummaryService 和 OrderImportExportService
 		orderSummaryService := service.NewOrderSummaryService(log.Logger, services.MongoDB)
+		orderSummaryService.SetOrderEventService(orderEventService)
 		orderImportExportService := service.NewOrderImportExportService(log.Logger, services.MongoDB)
+		orderImportExportService.SetOrderEventService(orderEventService)
 		orderSummaryController := controller.NewOrderSummaryController(log.Logger, orderSummaryService, orderImportExportService, userAuthMiddleware)
 		driverController := controller.NewDriverController(log.Logger, driverService, orderService, orderScheduleService, driverAuthMiddleware, fileStorageService, baseURL)
 		userController := controller.NewUserController(log.Logger, userService, orderService, userAuthMiddleware)
-		authController := controller.NewAuthController(log.Logger, userService, driverService)
+		authController := controller.NewAuthController(log.Logger, userService, driverService, authSessionService, driverDeviceService, loginLimiterService, loginRateLimitMiddleware)
+		loginLockoutController := controller.NewLoginLockoutController(log.Logger, loginLimiterService, userAuthMiddleware)
+		sessionController := controller.NewSessionController(log.Logger, authSessionService, driverService, userAuthMiddleware, driverAuthMiddleware)
+		driverDeviceController := controller.NewDriverDeviceController(log.Logger, driverDeviceService, userAuthMiddleware)
 		crawlerController := controller.NewCrawlerController(log.Logger, crawlerService)
 		roleController := controller.NewRoleController(log.Logger, roleService, userAuthMiddleware)
 
@@ -422,6 +490,9 @@ func main() {
 		driverController.RegisterRoutes(api)
 		userController.RegisterRoutes(api)
 		authController.RegisterRoutes(api)
+		loginLockoutController.RegisterRoutes(api)
+		sessionController.RegisterRoutes(api)
+		driverDeviceController.RegisterRoutes(api)
 		crawlerController.RegisterRoutes(api)
 		roleController.RegisterRoutes(api)
 
@@ -463,7 +534,7 @@ func main() {
 
 		// === Dashboard Controller ===
 		dashboardService := service.NewDashboardService(log.Logger, services.MongoDB, driverService)
-		dashboardController := controller.NewDashboardController(log.Logger, dashboardService)
+		dashboardController := controller.NewDashboardController(log.Logger, dashboardService, userAuthMiddleware)
 		dashboardController.RegisterRoutes(api)
 
 		// === Develop Controller ===
@@ -471,11 +542,70 @@ func main() {
 		developController.RegisterRoutes(api)
 
 		// === Admin Controller ===
-		adminController := controller.NewAdminController(log.Logger, driverService)
+		adminController := controller.NewAdminController(log.Logger, driverService, userAuthMiddleware)
 		adminController.RegisterRoutes(api)
 
+		// === Fleet Controller ===
+		fleetController := controller.NewFleetController(log.Logger, fleetService, userAuthMiddleware)
+		fleetController.RegisterRoutes(api)
+
+		// === Dispatch Policy Controller ===
+		dispatchPolicyService := service.NewDispatchPolicyService(log.Logger, services.MongoDB, eventManager)
+		dispatchPolicyService.SetFleetService(fleetService)
+		dispatchPolicyController := controller.NewDispatchPolicyController(log.Logger, dispatchPolicyService, userAuthMiddleware)
+		dispatchPolicyController.RegisterRoutes(api)
+
+		// === Dead Letter Controller ===
+		deadLetterService := service.NewDeadLetterService(log.Logger, services.MongoDB, services.RabbitMQ, orderService)
+		deadLetterController := controller.NewDeadLetterController(log.Logger, deadLetterService, userAuthMiddleware)
+		deadLetterController.RegisterRoutes(api)
+
+		// === Driver Location Controller ===
+		driverLocationController := controller.NewDriverLocationController(log.Logger, driverLocationService, userAuthMiddleware)
+		driverLocationController.RegisterRoutes(api)
+
+		// === Service Zone Controller ===
+		serviceZoneService := service.NewServiceZoneService(log.Logger, services.MongoDB)
+		orderService.SetServiceZoneService(serviceZoneService)
+		serviceZoneController := controller.NewServiceZoneController(log.Logger, serviceZoneService, userAuthMiddleware)
+		serviceZoneController.RegisterRoutes(api)
+
+		// === Tariff Controller ===
+		tariffService := service.NewTariffService(log.Logger, services.MongoDB)
+		tariffService.SetServiceZoneService(serviceZoneService)
+		tariffService.SetLocationService(driverLocationService)
+		orderService.SetTariffService(tariffService)
+		driverService.SetTariffService(tariffService)
+		tariffController := controller.NewTariffController(log.Logger, tariffService, userAuthMiddleware)
+		tariffController.RegisterRoutes(api)
+
+		// === Settlement Controller ===
+		settlementService := service.NewSettlementService(log.Logger, services.MongoDB)
+		settlementService.SetFleetService(fleetService)
+		settlementController := controller.NewSettlementController(log.Logger, settlementService, userAuthMiddleware)
+		settlementController.RegisterRoutes(api)
+
+		// === Customer Group Controller ===
+		customerGroupController := controller.NewCustomerGroupController(log.Logger, customerGroupService, userAuthMiddleware)
+		customerGroupController.RegisterRoutes(api)
+
+		// === Passenger Controller ===
+		passengerController := controller.NewPassengerController(log.Logger, passengerService, orderService, userAuthMiddleware)
+		passengerController.RegisterRoutes(api)
+
+		// === Recurring Order Controller ===
+		recurringOrderService := service.NewRecurringOrderService(log.Logger, services.MongoDB)
+		recurringOrderService.SetOrderService(orderService)
+		recurringOrderService.SetOrderScheduleService(orderScheduleService)
+		recurringOrderController := controller.NewRecurringOrderController(log.Logger, recurringOrderService, userAuthMiddleware)
+		recurringOrderController.RegisterRoutes(api)
+
 		// 啟動 Dispatcher（包含事件驅動調度和黑名單服務）
 		bgDispatcher := background.NewDispatcher(log.Logger, services.MongoDB, services.RabbitMQ, crawlerService, orderService, fcmService, trafficUsageLogService, blacklistService, services.Redis.Client, notificationService)
+		bgDispatcher.SetDispatchPolicyService(dispatchPolicyService)
+		bgDispatcher.SetDeadLetterService(deadLetterService)
+		bgDispatcher.SetServiceZoneService(serviceZoneService)
+		bgDispatcher.SetFleetService(fleetService)
 
 		// 初始化 ScheduledDispatcher（預約單派送器）
 		scheduledDispatcher := background.NewScheduledDispatcher(
@@ -490,6 +620,8 @@ func main() {
 			notificationService,
 		)
 
+		scheduledDispatcher.SetFleetService(fleetService)
+
 		log.Info().Msg("ScheduledDispatcher 已初始化")
 
 		// 設置 Discord 事件處理器到 NotificationService
@@ -498,9 +630,19 @@ func main() {
 			log.Info().Msg("Discord 事件處理器已設置到 NotificationService")
 		}
 
+		// 啟動派單策略熱更新（訂閱設定變更事件）
+		go dispatchPolicyService.StartHotReload(context.Background())
+
+		// 啟動車隊登錄資料熱更新（訂閱設定變更事件）
+		go fleetService.StartHotReload(context.Background())
+
 		go bgDispatcher.Start(context.Background())
 		go scheduledDispatcher.Start(context.Background())
 
+		// 啟動週期性預約產生器（提前產生預約單送入預約單隊列）
+		recurringOrderGenerator := background.NewRecurringOrderGenerator(log.Logger, recurringOrderService)
+		go recurringOrderGenerator.Start(context.Background())
+
 		// 啟動 Redis 自動清理監聽器
 		if bgDispatcher.EventManager != nil {
 			go bgDispatcher.EventManager.StartCleanupWatcher(context.Background())
@@ -541,7 +683,7 @@ func main() {
 				}
 
 				// 更新在線司機統計
-				fleetCounts, err := dashboardService.GetOnlineDriverStatsByFleet(context.Background())
+				fleetCounts, err := dashboardService.GetOnlineDriverStatsByFleet(context.Background(), "")
 				if err == nil && fleetCounts != nil {
 					otelMiddleware.UpdateOnlineDrivers(fleetCounts)
 				} else {
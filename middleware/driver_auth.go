@@ -13,14 +13,16 @@ import (
 )
 
 type DriverAuthMiddleware struct {
-	driverService *service.DriverService
-	jwtSecretKey  string
+	driverService  *service.DriverService
+	sessionService *service.AuthSessionService
+	jwtSecretKey   string
 }
 
-func NewDriverAuthMiddleware(driverService *service.DriverService, jwtSecretKey string) *DriverAuthMiddleware {
+func NewDriverAuthMiddleware(driverService *service.DriverService, sessionService *service.AuthSessionService, jwtSecretKey string) *DriverAuthMiddleware {
 	return &DriverAuthMiddleware{
-		driverService: driverService,
-		jwtSecretKey:  jwtSecretKey,
+		driverService:  driverService,
+		sessionService: sessionService,
+		jwtSecretKey:   jwtSecretKey,
 	}
 }
 
@@ -96,6 +98,14 @@ func (m *DriverAuthMiddleware) Auth() func(huma.Context, func(huma.Context)) {
 			return
 		}
 
+		// 檢查登入 session 是否已撤銷（登出、強制登出）
+		if err := m.sessionService.CheckAccessClaims(ctx.Context(), claims); err != nil {
+			ctx.SetStatus(http.StatusUnauthorized)
+			ctx.SetHeader("Content-Type", "application/json")
+			ctx.BodyWriter().Write([]byte(fmt.Sprintf(`{"code":401,"message":"登入已失效，請重新登入","detail":"%s"}`, err.Error())))
+			return
+		}
+
 		// 從資料庫獲取司機資訊
 		driver, err := m.driverService.GetDriverByID(context.Background(), driverID)
 		if err != nil {
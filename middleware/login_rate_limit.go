@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"right-backend/model"
+	"right-backend/service"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// clientIPKey 登入請求來源 IP 在 context 中的 key
+const clientIPKey = "client_ip"
+
+// LoginRateLimitMiddleware 登入 API 的 IP 限流與鎖定檢查；帳號鎖定在 handler 讀取帳號後檢查
+type LoginRateLimitMiddleware struct {
+	limiter        *service.LoginLimiterService
+	trustedProxies []*net.IPNet
+}
+
+// NewLoginRateLimitMiddleware trustedProxies 為可信任的反向代理 IP 或 CIDR，只有來自這些位址的請求才採用轉發標頭中的來源 IP
+func NewLoginRateLimitMiddleware(limiter *service.LoginLimiterService, trustedProxies []string) (*LoginRateLimitMiddleware, error) {
+	networks, err := parseTrustedProxies(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginRateLimitMiddleware{limiter: limiter, trustedProxies: networks}, nil
+}
+
+// Limit 檢查來源 IP 是否鎖定或超過每分鐘登入次數，並將來源 IP 放入 context 供 handler 記錄失敗
+func (m *LoginRateLimitMiddleware) Limit(loginType model.TokenType) func(huma.Context, func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		ip := clientIP(ctx.RemoteAddr(), ctx.Header("X-Forwarded-For"), ctx.Header("X-Real-IP"), m.trustedProxies)
+
+		var throttled *service.LoginThrottleError
+		if err := m.limiter.CheckIP(ctx.Context(), ip); errors.As(err, &throttled) {
+			RecordLoginThrottle(string(loginType), string(throttled.Scope), throttled.Reason)
+			writeTooManyRequests(ctx, throttled)
+			return
+		}
+
+		next(huma.WithValue(ctx, clientIPKey, ip))
+	}
+}
+
+// ClientIPFromContext 取得 LoginRateLimitMiddleware 解析的來源 IP
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// LoginThrottledError 登入被限制時回傳 429 與 Retry-After，供 handler 使用
+func LoginThrottledError(throttled *service.LoginThrottleError) error {
+	return huma.ErrorWithHeaders(
+		huma.Error429TooManyRequests(service.ErrLoginThrottled.Error(), throttled),
+		http.Header{"Retry-After": []string{strconv.Itoa(throttled.RetryAfterSeconds())}},
+	)
+}
+
+// clientIP 來源 IP：直接連線時取連線位址；來自可信任代理時由 X-Forwarded-For 右邊往左取第一個非代理的位址，
+// 最左邊的位址由用戶端自行填寫，不可直接採用
+func clientIP(remoteAddr, forwardedFor, realIP string, trustedProxies []*net.IPNet) string {
+	remote := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remote = host
+	}
+	if !isTrustedProxy(remote, trustedProxies) {
+		return remote
+	}
+
+	if forwardedFor != "" {
+		hops := strings.Split(forwardedFor, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(hops[i])
+			if net.ParseIP(ip) == nil {
+				return remote
+			}
+			// 整條轉發鏈都是可信任代理時取最左邊的位址
+			if i == 0 || !isTrustedProxy(ip, trustedProxies) {
+				return ip
+			}
+		}
+	}
+	if ip := strings.TrimSpace(realIP); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return remote
+}
+
+func isTrustedProxy(ip string, trustedProxies []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies 解析可信任代理設定，單一 IP 視為 /32（IPv6 為 /128）
+func parseTrustedProxies(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("無效的可信任代理位址: %s", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("無效的可信任代理網段 %s: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// tooManyRequestsBody 登入被限制時回傳的內容
+type tooManyRequestsBody struct {
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	Detail     string `json:"detail"`
+	RetryAfter int    `json:"retry_after"`
+}
+
+func writeTooManyRequests(ctx huma.Context, throttled *service.LoginThrottleError) {
+	body, _ := json.Marshal(tooManyRequestsBody{
+		Code:       http.StatusTooManyRequests,
+		Message:    service.ErrLoginThrottled.Error(),
+		Detail:     string(throttled.Scope) + " " + throttled.Reason,
+		RetryAfter: throttled.RetryAfterSeconds(),
+	})
+	ctx.SetStatus(http.StatusTooManyRequests)
+	ctx.SetHeader("Content-Type", "application/json")
+	ctx.SetHeader("Retry-After", strconv.Itoa(throttled.RetryAfterSeconds()))
+	ctx.BodyWriter().Write(body)
+}
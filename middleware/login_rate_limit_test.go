@@ -0,0 +1,51 @@
+package middleware
+
+import "testing"
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10", "::1"})
+	if err != nil {
+		t.Fatalf("解析可信任代理失敗: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		want         string
+	}{
+		{name: "直接連線使用連線位址", remoteAddr: "203.0.113.5:51234", want: "203.0.113.5"},
+		{name: "非可信任代理忽略轉發標頭", remoteAddr: "203.0.113.5:51234", forwardedFor: "198.51.100.7", realIP: "198.51.100.8", want: "203.0.113.5"},
+		{name: "可信任代理取轉發位址", remoteAddr: "10.1.2.3:443", forwardedFor: "198.51.100.7", want: "198.51.100.7"},
+		{name: "用戶端偽造的最左邊位址不採用", remoteAddr: "10.1.2.3:443", forwardedFor: "1.2.3.4, 198.51.100.7", want: "198.51.100.7"},
+		{name: "略過轉發鏈中的可信任代理", remoteAddr: "10.1.2.3:443", forwardedFor: "198.51.100.7, 192.168.1.10, 10.9.9.9", want: "198.51.100.7"},
+		{name: "整條轉發鏈都是代理時取最左邊", remoteAddr: "10.1.2.3:443", forwardedFor: "10.0.0.8, 10.0.0.9", want: "10.0.0.8"},
+		{name: "轉發位址格式錯誤時使用連線位址", remoteAddr: "10.1.2.3:443", forwardedFor: "198.51.100.7, not-an-ip", want: "10.1.2.3"},
+		{name: "沒有 X-Forwarded-For 時使用 X-Real-IP", remoteAddr: "10.1.2.3:443", realIP: " 198.51.100.8 ", want: "198.51.100.8"},
+		{name: "IPv6 本機代理", remoteAddr: "[::1]:8080", forwardedFor: "2001:db8::1", want: "2001:db8::1"},
+		{name: "連線位址沒有 port", remoteAddr: "203.0.113.5", want: "203.0.113.5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := clientIP(tc.remoteAddr, tc.forwardedFor, tc.realIP, trusted); got != tc.want {
+				t.Fatalf("預期來源 IP 為 %s，實際為 %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	if got := clientIP("10.1.2.3:443", "198.51.100.7", "198.51.100.8", nil); got != "10.1.2.3" {
+		t.Fatalf("未設定可信任代理時預期使用連線位址，實際為 %s", got)
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "proxy.local"} {
+		if _, err := parseTrustedProxies([]string{entry}); err == nil {
+			t.Fatalf("預期 %q 解析失敗", entry)
+		}
+	}
+}
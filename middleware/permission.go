@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"right-backend/model"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// accessRuleKey AccessRule 在 huma.Operation.Metadata 中的 key
+const accessRuleKey = "access_rule"
+
+// AccessRule 操作所需的權限，與 huma 操作一起宣告，由 UserAuthMiddleware 統一檢查
+type AccessRule struct {
+	// Permissions 具備任一權限即可通過，為空時只需登入
+	Permissions []model.Permission
+	// FleetParam 依車隊過濾的 query 參數名稱；只能檢視自家車隊的用戶會被限制在所屬車隊
+	FleetParam string
+	// Roles 限定可操作的角色，為空時不限角色
+	Roles []model.UserRole
+}
+
+// RequirePermissions 宣告操作需要的權限（具備任一即可），用於 huma.Operation.Metadata
+func RequirePermissions(permissions ...model.Permission) map[string]any {
+	return map[string]any{accessRuleKey: AccessRule{Permissions: permissions}}
+}
+
+// RequireFleetScoped 宣告操作需要的權限，並以 fleetParam 查詢參數套用用戶的車隊檢視範圍
+func RequireFleetScoped(fleetParam string, permissions ...model.Permission) map[string]any {
+	return map[string]any{accessRuleKey: AccessRule{Permissions: permissions, FleetParam: fleetParam}}
+}
+
+// RequireRoles 宣告只有指定角色可以操作（系統管理員不受限制），用於 huma.Operation.Metadata
+func RequireRoles(roles ...model.UserRole) map[string]any {
+	return map[string]any{accessRuleKey: AccessRule{Roles: roles}}
+}
+
+func accessRuleOf(op *huma.Operation) (AccessRule, bool) {
+	if op == nil || op.Metadata == nil {
+		return AccessRule{}, false
+	}
+	rule, ok := op.Metadata[accessRuleKey].(AccessRule)
+	return rule, ok
+}
+
+// enforceAccess 檢查用戶是否具備操作所需權限，並將車隊過濾參數限制在用戶可檢視的範圍內
+func enforceAccess(ctx huma.Context, access *model.UserAccess) (huma.Context, bool) {
+	rule, ok := accessRuleOf(ctx.Operation())
+	if !ok {
+		return ctx, true
+	}
+
+	if !access.HasAnyRole(rule.Roles...) {
+		writeForbidden(ctx, "role "+string(access.Role)+" is not allowed", rule)
+		return ctx, false
+	}
+	if !access.HasAnyPermission(rule.Permissions...) {
+		writeForbidden(ctx, "permission denied", rule)
+		return ctx, false
+	}
+
+	if rule.FleetParam == "" {
+		return ctx, true
+	}
+	fleet, scoped := access.ScopedFleet()
+	if !scoped {
+		return ctx, true
+	}
+	if fleet == "" {
+		writeForbidden(ctx, "no fleet assigned to user", rule)
+		return ctx, false
+	}
+
+	// 未指定或查詢全部時改為所屬車隊，指定其他車隊則拒絕
+	requested := ctx.Query(rule.FleetParam)
+	switch requested {
+	case string(fleet):
+		return ctx, true
+	case "", string(model.FleetAccessAll):
+		return &fleetScopedContext{humaContext: ctx, param: rule.FleetParam, fleet: string(fleet)}, true
+	default:
+		writeForbidden(ctx, "fleet "+requested+" is outside user's fleet access", rule)
+		return ctx, false
+	}
+}
+
+// humaContext 別名讓嵌入欄位不與 huma.Context 的 Context() 方法同名
+type humaContext = huma.Context
+
+// fleetScopedContext 覆寫車隊查詢參數，讓 handler 只會查到用戶所屬車隊的資料
+type fleetScopedContext struct {
+	humaContext
+	param string
+	fleet string
+}
+
+func (c *fleetScopedContext) Query(name string) string {
+	if name == c.param {
+		return c.fleet
+	}
+	return c.humaContext.Query(name)
+}
+
+func (c *fleetScopedContext) URL() url.URL {
+	u := c.humaContext.URL()
+	query := u.Query()
+	query.Set(c.param, c.fleet)
+	u.RawQuery = query.Encode()
+	return u
+}
+
+// forbiddenBody 權限不足時統一回傳的內容
+type forbiddenBody struct {
+	Code                int                `json:"code"`
+	Message             string             `json:"message"`
+	Detail              string             `json:"detail"`
+	RequiredPermissions []model.Permission `json:"required_permissions,omitempty"`
+	RequiredRoles       []model.UserRole   `json:"required_roles,omitempty"`
+}
+
+func writeForbidden(ctx huma.Context, detail string, rule AccessRule) {
+	body, _ := json.Marshal(forbiddenBody{
+		Code:                http.StatusForbidden,
+		Message:             "權限不足",
+		Detail:              detail,
+		RequiredPermissions: rule.Permissions,
+		RequiredRoles:       rule.Roles,
+	})
+	ctx.SetStatus(http.StatusForbidden)
+	ctx.SetHeader("Content-Type", "application/json")
+	ctx.BodyWriter().Write(body)
+}
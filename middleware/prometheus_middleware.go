@@ -82,6 +82,15 @@ var (
 		[]string{"service", "component"},
 	)
 
+	// Auth metrics
+	authLoginThrottledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_login_throttled_total",
+			Help: "Total number of login lockouts and throttled login attempts",
+		},
+		[]string{"login_type", "scope", "reason"},
+	)
+
 	// Prometheus registry
 	promRegistry *prometheus.Registry
 )
@@ -124,6 +133,10 @@ func InitPrometheusMetrics(logger zerolog.Logger) error {
 		return fmt.Errorf("failed to register infrastructure_connection_latency_ms: %w", err)
 	}
 
+	if err := promRegistry.Register(authLoginThrottledTotal); err != nil {
+		return fmt.Errorf("failed to register auth_login_throttled_total: %w", err)
+	}
+
 	// 也註冊默認的 Go metrics
 	promRegistry.MustRegister(prometheus.NewGoCollector())
 	promRegistry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
@@ -249,3 +262,10 @@ func UpdateInfrastructureHealth(service, component string, isHealthy bool, laten
 		infraConnectionLatency.WithLabelValues(service, component).Set(latencyMs)
 	}
 }
+
+// RecordLoginThrottle 記錄登入鎖定與被拒絕的登入嘗試 metric
+func RecordLoginThrottle(loginType, scope, reason string) {
+	if promRegistry != nil && authLoginThrottledTotal != nil {
+		authLoginThrottledTotal.WithLabelValues(loginType, scope, reason).Inc()
+	}
+}
@@ -13,14 +13,18 @@ import (
 )
 
 type UserAuthMiddleware struct {
-	userService  *service.UserService
-	jwtSecretKey string
+	userService    *service.UserService
+	sessionService *service.AuthSessionService
+	roleService    *service.RoleService
+	jwtSecretKey   string
 }
 
-func NewUserAuthMiddleware(userService *service.UserService, jwtSecretKey string) *UserAuthMiddleware {
+func NewUserAuthMiddleware(userService *service.UserService, sessionService *service.AuthSessionService, roleService *service.RoleService, jwtSecretKey string) *UserAuthMiddleware {
 	return &UserAuthMiddleware{
-		userService:  userService,
-		jwtSecretKey: jwtSecretKey,
+		userService:    userService,
+		sessionService: sessionService,
+		roleService:    roleService,
+		jwtSecretKey:   jwtSecretKey,
 	}
 }
 
@@ -96,6 +100,14 @@ func (m *UserAuthMiddleware) Auth() func(huma.Context, func(huma.Context)) {
 			return
 		}
 
+		// 檢查登入 session 是否已撤銷（登出、強制登出）
+		if err := m.sessionService.CheckAccessClaims(ctx.Context(), claims); err != nil {
+			ctx.SetStatus(http.StatusUnauthorized)
+			ctx.SetHeader("Content-Type", "application/json")
+			ctx.BodyWriter().Write([]byte(fmt.Sprintf(`{"code":401,"message":"登入已失效，請重新登入","detail":"%s"}`, err.Error())))
+			return
+		}
+
 		// 從資料庫獲取用戶資訊
 		user, err := m.userService.GetUserByID(context.Background(), userID)
 		if err != nil {
@@ -108,9 +120,23 @@ func (m *UserAuthMiddleware) Auth() func(huma.Context, func(huma.Context)) {
 		// 打印用戶操作
 		m.PrintUserOperation(ctx, user)
 
+		// 依角色設定解析生效權限，檢查操作宣告的權限與車隊檢視範圍
+		access, err := m.roleService.ResolveUserAccess(ctx.Context(), user)
+		if err != nil {
+			ctx.SetStatus(http.StatusInternalServerError)
+			ctx.SetHeader("Content-Type", "application/json")
+			ctx.BodyWriter().Write([]byte(fmt.Sprintf(`{"code":500,"message":"無法取得用戶權限","detail":"%s"}`, err.Error())))
+			return
+		}
+		ctx, ok = enforceAccess(ctx, access)
+		if !ok {
+			return
+		}
+
 		// 將用戶資訊添加到 context 中，讓後續的 handler 可以使用
 		ctx = huma.WithValue(ctx, "user", user)
 		ctx = huma.WithValue(ctx, "user_id", userID)
+		ctx = huma.WithValue(ctx, "user_access", access)
 		ctx = huma.WithValue(ctx, "account", claims["account"])
 
 		// 繼續到下一個中間件或 handler
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionRevokeReason 登入 session 撤銷原因
+type SessionRevokeReason string
+
+const (
+	SessionRevokeLogout           SessionRevokeReason = "logout"             // 使用者登出
+	SessionRevokeManual           SessionRevokeReason = "manual"             // 使用者或管理員手動撤銷
+	SessionRevokeRemovedFromFleet SessionRevokeReason = "removed_from_fleet" // 司機被移出車隊
+	SessionRevokePasswordChanged  SessionRevokeReason = "password_changed"   // 密碼變更
+	SessionRevokeAccountDisabled  SessionRevokeReason = "account_disabled"   // 帳號停用或刪除
+	SessionRevokeRefreshReused    SessionRevokeReason = "refresh_reused"     // 已輪替的 refresh token 被重複使用，視為外洩
+	SessionRevokeNewDevice        SessionRevokeReason = "new_device"         // 司機在另一台設備登入
+	SessionRevokeDeviceUnbound    SessionRevokeReason = "device_unbound"     // 管理員解除設備綁定
+)
+
+// SessionDevice 登入時的裝置資訊
+type SessionDevice struct {
+	DeviceID     string `json:"device_id,omitempty" bson:"device_id,omitempty" example:"8F3C2A1E-6B7D-4E21-9C55-0A1B2C3D4E5F" doc:"設備識別碼"`
+	ModelName    string `json:"model_name,omitempty" bson:"model_name,omitempty" example:"iPhone15,4" doc:"設備型號名稱"`
+	DeviceName   string `json:"device_name,omitempty" bson:"device_name,omitempty" example:"My iPhone" doc:"設備名稱"`
+	Brand        string `json:"brand,omitempty" bson:"brand,omitempty" example:"Apple" doc:"設備品牌"`
+	Manufacturer string `json:"manufacturer,omitempty" bson:"manufacturer,omitempty" example:"Apple Inc." doc:"設備製造商"`
+	AppVersion   string `json:"app_version,omitempty" bson:"app_version,omitempty" example:"1.0.0" doc:"應用程式版本"`
+	UserAgent    string `json:"user_agent,omitempty" bson:"user_agent,omitempty" doc:"瀏覽器 User-Agent"`
+}
+
+// AuthSession 登入 session，每次登入建立一筆；refresh token 只保存雜湊，每次換發 access token 時輪替
+type AuthSession struct {
+	ID                       primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	SubjectType              TokenType           `json:"subject_type" bson:"subject_type" example:"driver" doc:"登入者類型(user/driver)"`
+	SubjectID                primitive.ObjectID  `json:"subject_id" bson:"subject_id" doc:"用戶或司機ID"`
+	Account                  string              `json:"account" bson:"account" example:"driver001@taxi.com" doc:"登入帳號"`
+	Device                   SessionDevice       `json:"device" bson:"device" doc:"登入裝置"`
+	RefreshTokenHash         string              `json:"-" bson:"refresh_token_hash"`
+	PreviousRefreshTokenHash string              `json:"-" bson:"previous_refresh_token_hash,omitempty"`
+	CreatedAt                time.Time           `json:"created_at" bson:"created_at" doc:"登入時間"`
+	LastRefreshedAt          *time.Time          `json:"last_refreshed_at,omitempty" bson:"last_refreshed_at,omitempty" doc:"最後換發 access token 時間"`
+	ExpiresAt                time.Time           `json:"expires_at" bson:"expires_at" doc:"refresh token 到期時間"`
+	RevokedAt                *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty" doc:"撤銷時間"`
+	RevokedReason            SessionRevokeReason `json:"revoked_reason,omitempty" bson:"revoked_reason,omitempty" example:"logout" doc:"撤銷原因"`
+}
+
+// IsActive session 是否仍可換發 access token
+func (s *AuthSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// AuthTokens 登入或換發後回傳的 token 組合
+type AuthTokens struct {
+	AccessToken  string    `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..." doc:"access token"`
+	ExpiresAt    time.Time `json:"expires_at" doc:"access token 到期時間"`
+	RefreshToken string    `json:"refresh_token" doc:"refresh token，換發後舊的 refresh token 即失效"`
+	SessionID    string    `json:"session_id" example:"684a73ad0e3a583c37e4b30d" doc:"登入 session ID"`
+}
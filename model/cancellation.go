@@ -0,0 +1,116 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultNoShowWaitMins 車隊未設定等候時間時，司機抵達後需等候多久才可回報乘客未到
+const DefaultNoShowWaitMins = 10
+
+// CancellationPolicy 車隊取消政策：司機接單後的免費取消時段、前往上車點與抵達後的取消費，以及乘客未到的規則
+type CancellationPolicy struct {
+	Enabled                bool `json:"enabled" bson:"enabled" example:"true" doc:"是否收取取消費，停用時取消與乘客未到皆不收費"`
+	FreeMinsAfterAccept    int  `json:"free_mins_after_accept" bson:"free_mins_after_accept" example:"3" doc:"司機接單後幾分鐘內取消免費"`
+	ScheduleFreeBeforeMins int  `json:"schedule_free_before_mins" bson:"schedule_free_before_mins" example:"60" doc:"預約單在預約時間前幾分鐘以前取消免費，0 表示司機出發前一律免費"`
+	EnrouteFee             int  `json:"enroute_fee" bson:"enroute_fee" example:"50" doc:"司機前往上車點後取消的取消費（元），預約單超過免費時段取消亦收此費用"`
+	ArrivedFee             int  `json:"arrived_fee" bson:"arrived_fee" example:"100" doc:"司機抵達上車點後取消的取消費（元）"`
+	NoShowWaitMins         int  `json:"no_show_wait_mins" bson:"no_show_wait_mins" example:"10" doc:"司機抵達後等候幾分鐘乘客仍未出現，可回報乘客未到，0 使用預設 10 分鐘"`
+	NoShowFee              int  `json:"no_show_fee" bson:"no_show_fee" example:"150" doc:"乘客未到的費用（元）"`
+}
+
+// CancellationFeeKind 取消費類型
+type CancellationFeeKind string
+
+const (
+	CancellationFeeFree   CancellationFeeKind = "free"        // 免費取消
+	CancellationFeeLate   CancellationFeeKind = "late_cancel" // 司機出發或抵達後取消
+	CancellationFeeNoShow CancellationFeeKind = "no_show"     // 乘客未到
+)
+
+// CancellationFee 訂單取消或乘客未到時依車隊取消政策計算的費用
+type CancellationFee struct {
+	Kind        CancellationFeeKind `json:"kind" bson:"kind" example:"late_cancel" doc:"類型(free/late_cancel/no_show)"`
+	Amount      int                 `json:"amount" bson:"amount" example:"100" doc:"金額（元）"`
+	Rule        string              `json:"rule" bson:"rule" example:"司機已抵達上車點" doc:"套用的規則說明"`
+	OrderStatus OrderStatus         `json:"order_status" bson:"order_status" example:"司機抵達" doc:"取消當下的訂單狀態"`
+	ChargedBy   string              `json:"charged_by,omitempty" bson:"charged_by,omitempty" doc:"取消者或回報乘客未到的司機"`
+	ChargedAt   time.Time           `json:"charged_at" bson:"charged_at" doc:"計算時間"`
+}
+
+// CancelFee 計算訂單在指定時間取消的費用：尚無司機接單或位於免費時段時免費，
+// 司機前往上車點後收取前往取消費，抵達上車點後收取抵達取消費
+func (p CancellationPolicy) CancelFee(order *Order, now time.Time) *CancellationFee {
+	fee := &CancellationFee{Kind: CancellationFeeFree, OrderStatus: order.Status, ChargedAt: now}
+
+	switch {
+	case order.Status == OrderStatusWaiting:
+		fee.Rule = "尚未有司機接單"
+	case !p.Enabled:
+		fee.Rule = "車隊未啟用取消政策"
+	case order.Status == OrderStatusScheduleAccepted:
+		if p.ScheduleFreeBeforeMins <= 0 || order.ScheduledAt == nil ||
+			now.Before(order.ScheduledAt.Add(-time.Duration(p.ScheduleFreeBeforeMins)*time.Minute)) {
+			fee.Rule = "司機尚未出發"
+			break
+		}
+		fee.Kind, fee.Amount = CancellationFeeLate, p.EnrouteFee
+		fee.Rule = fmt.Sprintf("預約時間前 %d 分鐘內取消", p.ScheduleFreeBeforeMins)
+	case order.Status == OrderStatusEnroute:
+		if order.AcceptanceTime != nil && now.Sub(*order.AcceptanceTime) < time.Duration(p.FreeMinsAfterAccept)*time.Minute {
+			fee.Rule = fmt.Sprintf("司機接單後 %d 分鐘內取消", p.FreeMinsAfterAccept)
+			break
+		}
+		fee.Kind, fee.Amount = CancellationFeeLate, p.EnrouteFee
+		fee.Rule = "司機已前往上車點"
+	case order.Status == OrderStatusDriverArrived:
+		fee.Kind, fee.Amount = CancellationFeeLate, p.ArrivedFee
+		fee.Rule = "司機已抵達上車點"
+	}
+
+	if fee.Amount <= 0 {
+		fee.Kind, fee.Amount = CancellationFeeFree, 0
+	}
+	return fee
+}
+
+// NoShowWait 司機抵達後需等候的時間
+func (p CancellationPolicy) NoShowWait() time.Duration {
+	if p.NoShowWaitMins <= 0 {
+		return DefaultNoShowWaitMins * time.Minute
+	}
+	return time.Duration(p.NoShowWaitMins) * time.Minute
+}
+
+// NoShowFeeFor 計算乘客未到的費用，訂單須為司機抵達狀態且已等候超過規定時間
+func (p CancellationPolicy) NoShowFeeFor(order *Order, now time.Time) (*CancellationFee, error) {
+	if order.Status != OrderStatusDriverArrived || order.ArrivalTime == nil {
+		return nil, fmt.Errorf("訂單狀態為 %s，只有「司機抵達」狀態的訂單可回報乘客未到", order.Status)
+	}
+	if waited := now.Sub(*order.ArrivalTime); waited < p.NoShowWait() {
+		return nil, fmt.Errorf("司機抵達後需等候 %d 分鐘才可回報乘客未到，目前已等候 %d 分鐘",
+			int(p.NoShowWait().Minutes()), int(waited.Minutes()))
+	}
+
+	fee := &CancellationFee{
+		Kind:        CancellationFeeNoShow,
+		Rule:        fmt.Sprintf("司機抵達後等候 %d 分鐘乘客未到", int(p.NoShowWait().Minutes())),
+		OrderStatus: order.Status,
+		ChargedAt:   now,
+	}
+	if p.Enabled {
+		fee.Amount = p.NoShowFee
+	}
+	return fee, nil
+}
+
+// Validate 檢查取消政策的設定值
+func (p CancellationPolicy) Validate() error {
+	if p.FreeMinsAfterAccept < 0 || p.ScheduleFreeBeforeMins < 0 || p.NoShowWaitMins < 0 {
+		return fmt.Errorf("取消政策的分鐘數不可為負數")
+	}
+	if p.EnrouteFee < 0 || p.ArrivedFee < 0 || p.NoShowFee < 0 {
+		return fmt.Errorf("取消政策的費用不可為負數")
+	}
+	return nil
+}
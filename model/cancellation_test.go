@@ -0,0 +1,136 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func testCancellationPolicy() CancellationPolicy {
+	return CancellationPolicy{
+		Enabled:                true,
+		FreeMinsAfterAccept:    3,
+		ScheduleFreeBeforeMins: 60,
+		EnrouteFee:             50,
+		ArrivedFee:             100,
+		NoShowWaitMins:         10,
+		NoShowFee:              150,
+	}
+}
+
+func TestCancellationPolicyCancelFee(t *testing.T) {
+	now := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	later := func(d time.Duration) *time.Time {
+		at := now.Add(d)
+		return &at
+	}
+
+	testCases := []struct {
+		name       string
+		modify     func(p *CancellationPolicy)
+		order      *Order
+		wantKind   CancellationFeeKind
+		wantAmount int
+	}{
+		{name: "尚未有司機接單", order: &Order{Status: OrderStatusWaiting}, wantKind: CancellationFeeFree},
+		{name: "接單後免費時段內", order: &Order{Status: OrderStatusEnroute, AcceptanceTime: ago(2 * time.Minute)}, wantKind: CancellationFeeFree},
+		{name: "接單後超過免費時段", order: &Order{Status: OrderStatusEnroute, AcceptanceTime: ago(5 * time.Minute)}, wantKind: CancellationFeeLate, wantAmount: 50},
+		{name: "前往上車點但無接單時間", order: &Order{Status: OrderStatusEnroute}, wantKind: CancellationFeeLate, wantAmount: 50},
+		{name: "司機已抵達", order: &Order{Status: OrderStatusDriverArrived}, wantKind: CancellationFeeLate, wantAmount: 100},
+		{name: "預約單在免費時段以前", order: &Order{Status: OrderStatusScheduleAccepted, ScheduledAt: later(2 * time.Hour)}, wantKind: CancellationFeeFree},
+		{name: "預約單在預約時間前一小時內", order: &Order{Status: OrderStatusScheduleAccepted, ScheduledAt: later(30 * time.Minute)}, wantKind: CancellationFeeLate, wantAmount: 50},
+		{
+			name:     "預約單未設定免費時段時出發前一律免費",
+			modify:   func(p *CancellationPolicy) { p.ScheduleFreeBeforeMins = 0 },
+			order:    &Order{Status: OrderStatusScheduleAccepted, ScheduledAt: later(5 * time.Minute)},
+			wantKind: CancellationFeeFree,
+		},
+		{
+			name:     "車隊未啟用取消政策",
+			modify:   func(p *CancellationPolicy) { p.Enabled = false },
+			order:    &Order{Status: OrderStatusDriverArrived},
+			wantKind: CancellationFeeFree,
+		},
+		{
+			name:     "取消費設為 0 視為免費",
+			modify:   func(p *CancellationPolicy) { p.ArrivedFee = 0 },
+			order:    &Order{Status: OrderStatusDriverArrived},
+			wantKind: CancellationFeeFree,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := testCancellationPolicy()
+			if tc.modify != nil {
+				tc.modify(&policy)
+			}
+
+			fee := policy.CancelFee(tc.order, now)
+			if fee.Kind != tc.wantKind || fee.Amount != tc.wantAmount {
+				t.Fatalf("預期 %s %d 元，實際為 %s %d 元（%s）", tc.wantKind, tc.wantAmount, fee.Kind, fee.Amount, fee.Rule)
+			}
+			if fee.OrderStatus != tc.order.Status || !fee.ChargedAt.Equal(now) {
+				t.Fatalf("預期記錄取消當下的訂單狀態與時間，實際為 %s %v", fee.OrderStatus, fee.ChargedAt)
+			}
+		})
+	}
+}
+
+func TestCancellationPolicyNoShowFeeFor(t *testing.T) {
+	now := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	arrivedAgo := func(d time.Duration) *Order {
+		at := now.Add(-d)
+		return &Order{Status: OrderStatusDriverArrived, ArrivalTime: &at}
+	}
+
+	testCases := []struct {
+		name       string
+		modify     func(p *CancellationPolicy)
+		order      *Order
+		wantErr    bool
+		wantAmount int
+	}{
+		{name: "等候超過規定時間", order: arrivedAgo(12 * time.Minute), wantAmount: 150},
+		{name: "等候未滿規定時間", order: arrivedAgo(5 * time.Minute), wantErr: true},
+		{name: "非司機抵達狀態", order: &Order{Status: OrderStatusEnroute}, wantErr: true},
+		{name: "司機抵達但缺少抵達時間", order: &Order{Status: OrderStatusDriverArrived}, wantErr: true},
+		{
+			name:    "未設定等候時間使用預設值",
+			modify:  func(p *CancellationPolicy) { p.NoShowWaitMins = 0 },
+			order:   arrivedAgo((DefaultNoShowWaitMins - 1) * time.Minute),
+			wantErr: true,
+		},
+		{
+			name:   "車隊未啟用取消政策時不收費",
+			modify: func(p *CancellationPolicy) { p.Enabled = false },
+			order:  arrivedAgo(12 * time.Minute),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := testCancellationPolicy()
+			if tc.modify != nil {
+				tc.modify(&policy)
+			}
+
+			fee, err := policy.NoShowFeeFor(tc.order, now)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("預期無法回報乘客未到，實際為 %+v", fee)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("預期可回報乘客未到，但失敗: %v", err)
+			}
+			if fee.Kind != CancellationFeeNoShow || fee.Amount != tc.wantAmount {
+				t.Fatalf("預期乘客未到 %d 元，實際為 %s %d 元", tc.wantAmount, fee.Kind, fee.Amount)
+			}
+		})
+	}
+}
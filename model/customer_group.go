@@ -0,0 +1,89 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomerGroup 客群（企業客戶）資料，客群代碼即訂單文字 "/" 前的部分（存放於 customer_groups 集合）
+type CustomerGroup struct {
+	ID                   *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"客群ID"`
+	Code                 string              `json:"code" bson:"code" example:"R1" doc:"客群代碼（唯一，大寫）"`
+	Name                 string              `json:"name" bson:"name" example:"遠東飯店" doc:"客戶名稱"`
+	Contact              CustomerContact     `json:"contact" bson:"contact" doc:"聯絡資訊"`
+	Billing              BillingTerms        `json:"billing" bson:"billing" doc:"請款條件"`
+	DefaultFleet         FleetType           `json:"default_fleet,omitempty" bson:"default_fleet,omitempty" example:"RSK" doc:"預設車隊，建單未指定車隊時優先使用，空白表示依客群前綴判斷"`
+	AllowedLineConfigIDs []string            `json:"allowed_line_config_ids,omitempty" bson:"allowed_line_config_ids,omitempty" example:"[\"line_rsk\"]" doc:"允許透過哪些 LINE Bot 配置下單，空白表示不限"`
+	MonthlyBilling       bool                `json:"monthly_billing" bson:"monthly_billing" example:"true" doc:"是否月結"`
+	Enabled              bool                `json:"enabled" bson:"enabled" example:"true" doc:"是否啟用，停用後不可建立該客群訂單"`
+	Notes                string              `json:"notes,omitempty" bson:"notes,omitempty" doc:"備註"`
+	UpdatedBy            string              `json:"updated_by,omitempty" bson:"updated_by,omitempty" doc:"最後修改者"`
+	CreatedAt            *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt            *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// CustomerContact 客群聯絡資訊
+type CustomerContact struct {
+	Name    string `json:"name,omitempty" bson:"name,omitempty" example:"陳小姐" doc:"聯絡人"`
+	Phone   string `json:"phone,omitempty" bson:"phone,omitempty" example:"02-2345-6789" doc:"電話"`
+	Email   string `json:"email,omitempty" bson:"email,omitempty" example:"billing@example.com" doc:"電子郵件（寄送請款單）"`
+	Address string `json:"address,omitempty" bson:"address,omitempty" doc:"地址"`
+}
+
+// BillingTerms 客群請款條件
+type BillingTerms struct {
+	TaxID          string `json:"tax_id,omitempty" bson:"tax_id,omitempty" example:"12345678" doc:"統一編號"`
+	InvoiceTitle   string `json:"invoice_title,omitempty" bson:"invoice_title,omitempty" doc:"發票抬頭"`
+	PaymentDueDays int    `json:"payment_due_days" bson:"payment_due_days" example:"30" doc:"請款月份結束後幾天內付款"`
+}
+
+// AllowsLineConfig 是否允許透過指定的 LINE Bot 配置下單
+func (g *CustomerGroup) AllowsLineConfig(configID string) bool {
+	if len(g.AllowedLineConfigIDs) == 0 {
+		return true
+	}
+	for _, id := range g.AllowedLineConfigIDs {
+		if id == configID {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerGroupInvoice 客群月結請款單
+type CustomerGroupInvoice struct {
+	CustomerGroup *CustomerGroup        `json:"customer_group" doc:"客群資料"`
+	Month         string                `json:"month" example:"2025-01" doc:"請款月份（台北時間）"`
+	PeriodStart   time.Time             `json:"period_start" doc:"期間開始（含）"`
+	PeriodEnd     time.Time             `json:"period_end" doc:"期間結束（不含）"`
+	DueDate       time.Time             `json:"due_date" doc:"付款期限"`
+	Lines         []CustomerInvoiceLine `json:"lines" doc:"訂單明細"`
+	OrderCount    int                   `json:"order_count" example:"120" doc:"訂單數"`
+	Total         int                   `json:"total" example:"36000" doc:"請款金額合計（元）"`
+	MissingFare   int                   `json:"missing_fare" example:"0" doc:"無車資資料的訂單數"`
+}
+
+// CustomerInvoiceLine 請款單訂單明細
+type CustomerInvoiceLine struct {
+	OrderID        string          `json:"order_id" doc:"訂單ID"`
+	ShortID        string          `json:"short_id" example:"#9011" doc:"訂單短ID"`
+	CompletionTime time.Time       `json:"completion_time" doc:"完成時間"`
+	PassengerID    string          `json:"passenger_id,omitempty" doc:"乘客"`
+	PickupAddress  string          `json:"pickup_address" doc:"上車地點"`
+	DestAddress    string          `json:"dest_address,omitempty" doc:"目的地"`
+	Fleet          FleetType       `json:"fleet" doc:"車隊"`
+	DriverName     string          `json:"driver_name,omitempty" doc:"司機"`
+	CarNo          string          `json:"car_no,omitempty" doc:"車牌"`
+	Fare           int             `json:"fare" example:"300" doc:"車資（元）"`
+	FareSource     OrderFareSource `json:"fare_source" example:"final_fare" doc:"車資來源"`
+	FareItems      []FareItem      `json:"fare_items,omitempty" doc:"車資明細"`
+}
+
+// CustomerInvoiceSummary 月結客群請款總覽
+type CustomerInvoiceSummary struct {
+	Code       string `json:"code" bson:"_id" example:"R1" doc:"客群代碼"`
+	Name       string `json:"name" bson:"-" example:"遠東飯店" doc:"客戶名稱"`
+	OrderCount int    `json:"order_count" bson:"order_count" example:"120" doc:"訂單數"`
+	Total      int    `json:"total" bson:"total" example:"36000" doc:"請款金額合計（元）"`
+}
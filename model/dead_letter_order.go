@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeadLetterStatus 死信訂單狀態
+type DeadLetterStatus string
+
+const (
+	DeadLetterStatusPending  DeadLetterStatus = "pending"  // 待處理
+	DeadLetterStatusReplayed DeadLetterStatus = "replayed" // 已重新派送
+)
+
+// DeadLetterOrder 多次解析或派單失敗而移入死信隊列的訂單訊息（存放於 dead_letter_orders 集合）
+type DeadLetterOrder struct {
+	ID         *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"死信ID"`
+	Queue      string              `json:"queue" bson:"queue" example:"orders_queue" doc:"原始隊列"`
+	OrderID    string              `json:"order_id,omitempty" bson:"order_id,omitempty" doc:"訂單ID（解析失敗時為空）"`
+	ShortID    string              `json:"short_id,omitempty" bson:"short_id,omitempty" doc:"訂單短ID"`
+	Body       string              `json:"body" bson:"body" doc:"原始訊息內容"`
+	Reason     string              `json:"reason" bson:"reason" example:"派單失敗" doc:"移入死信原因"`
+	Error      string              `json:"error,omitempty" bson:"error,omitempty" doc:"最後一次錯誤訊息"`
+	Attempts   int                 `json:"attempts" bson:"attempts" example:"3" doc:"已嘗試次數"`
+	Status     DeadLetterStatus    `json:"status" bson:"status" example:"pending" doc:"狀態"`
+	ReplayedBy string              `json:"replayed_by,omitempty" bson:"replayed_by,omitempty" doc:"重新派送操作者"`
+	ReplayedAt *time.Time          `json:"replayed_at,omitempty" bson:"replayed_at,omitempty" doc:"重新派送時間"`
+	CreatedAt  *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DispatchPolicy 車隊派單策略（存放於 dispatch_policies 集合，調度中心熱更新）
+type DispatchPolicy struct {
+	ID                         *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"策略ID"`
+	Fleet                      FleetType           `json:"fleet" bson:"fleet" example:"RSK" doc:"車隊"`
+	HaversineCandidatesCount   int                 `json:"haversine_candidates_count" bson:"haversine_candidates_count" example:"15" doc:"直線距離初步篩選的候選司機數量上限"`
+	GoogleAPICandidatesCount   int                 `json:"google_api_candidates_count" bson:"google_api_candidates_count" example:"5" doc:"真實路徑計算後的最終派單人數"`
+	SequentialCallTimeoutSecs  int                 `json:"sequential_call_timeout_secs" bson:"sequential_call_timeout_secs" example:"19" doc:"依序呼叫每位司機的等待秒數"`
+	EnableMaxDriverDistance    bool                `json:"enable_max_driver_distance" bson:"enable_max_driver_distance" example:"true" doc:"是否啟用司機直線距離篩選"`
+	MaxDriverDistanceKm        float64             `json:"max_driver_distance_km" bson:"max_driver_distance_km" example:"15" doc:"司機直線距離上限（公里）"`
+	EnableMaxEstimatedTimeMins bool                `json:"enable_max_estimated_time_mins" bson:"enable_max_estimated_time_mins" example:"true" doc:"是否啟用真實路徑預估時間篩選"`
+	MaxEstimatedTimeMins       int                 `json:"max_estimated_time_mins" bson:"max_estimated_time_mins" example:"20" doc:"真實路徑預估時間上限（分鐘）"`
+	RankingStrategy            string              `json:"ranking_strategy,omitempty" bson:"ranking_strategy,omitempty" example:"nearest_eta" doc:"司機排序策略，空白表示使用 config.yml 設定"`
+	DispatchMode               DispatchMode        `json:"dispatch_mode,omitempty" bson:"dispatch_mode,omitempty" example:"sequential" doc:"派單模式：sequential 依序呼叫、broadcast 同時推播先搶先贏"`
+	BroadcastCount             int                 `json:"broadcast_count,omitempty" bson:"broadcast_count,omitempty" example:"3" doc:"搶單模式同時推播的司機數量，0 表示推播給所有最終候選司機"`
+	Rounds                     []DispatchRound     `json:"rounds,omitempty" bson:"rounds,omitempty" doc:"多輪派單擴大半徑設定，未設定時只派一輪"`
+	UpdatedBy                  string              `json:"updated_by,omitempty" bson:"updated_by,omitempty" doc:"最後修改者"`
+	CreatedAt                  *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt                  *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// DispatchRound 單輪派單設定，依序執行直到有司機接單或全部輪次用盡才流單
+type DispatchRound struct {
+	MaxDriverDistanceKm float64 `json:"max_driver_distance_km" bson:"max_driver_distance_km" example:"5" doc:"本輪司機直線距離上限（公里），0 表示沿用車隊設定"`
+	CrossFleet          bool    `json:"cross_fleet" bson:"cross_fleet" example:"false" doc:"本輪是否開放跨車隊派單，否則只派給本車隊司機"`
+	DelaySecs           int     `json:"delay_secs" bson:"delay_secs" example:"10" doc:"上一輪結束後等待秒數（第一輪忽略）"`
+}
+
+// DispatchMode 派單模式
+type DispatchMode string
+
+const (
+	DispatchModeSequential DispatchMode = "sequential" // 依序呼叫，每位司機等待 SequentialCallTimeoutSecs
+	DispatchModeBroadcast  DispatchMode = "broadcast"  // 同時推播給前 N 位司機，先接單者得
+)
+
+// IsBroadcast 是否為搶單模式（未設定時視為依序呼叫）
+func (p *DispatchPolicy) IsBroadcast() bool {
+	return p.DispatchMode == DispatchModeBroadcast
+}
+
+// DispatchRounds 取得派單輪次，未設定時以車隊設定派單一輪（可跨車隊，與舊版行為一致）
+func (p *DispatchPolicy) DispatchRounds() []DispatchRound {
+	if len(p.Rounds) == 0 {
+		return []DispatchRound{{CrossFleet: true}}
+	}
+	return p.Rounds
+}
+
+// MaxDriverDistanceFor 取得該輪的司機直線距離上限，本輪有設定半徑時覆蓋車隊設定
+func (p *DispatchPolicy) MaxDriverDistanceFor(round DispatchRound) (enabled bool, maxKm float64) {
+	if round.MaxDriverDistanceKm > 0 {
+		return true, round.MaxDriverDistanceKm
+	}
+	return p.EnableMaxDriverDistance, p.MaxDriverDistanceKm
+}
+
+// Validate 驗證派單策略數值，未設定派單模式時補上依序呼叫
+func (p *DispatchPolicy) Validate() error {
+	if p.Fleet == "" {
+		return errors.New("車隊不可為空")
+	}
+	if p.HaversineCandidatesCount <= 0 {
+		return errors.New("直線距離候選司機數量必須大於 0")
+	}
+	if p.GoogleAPICandidatesCount <= 0 || p.GoogleAPICandidatesCount > p.HaversineCandidatesCount {
+		return errors.New("最終派單人數必須大於 0 且不可超過直線距離候選司機數量")
+	}
+	if p.SequentialCallTimeoutSecs < 5 {
+		return errors.New("每位司機的等待秒數不可少於 5 秒")
+	}
+	if p.EnableMaxDriverDistance && p.MaxDriverDistanceKm <= 0 {
+		return errors.New("啟用距離篩選時，直線距離上限必須大於 0")
+	}
+	if p.EnableMaxEstimatedTimeMins && p.MaxEstimatedTimeMins <= 0 {
+		return errors.New("啟用預估時間篩選時，預估時間上限必須大於 0")
+	}
+	switch p.DispatchMode {
+	case "":
+		p.DispatchMode = DispatchModeSequential
+	case DispatchModeSequential, DispatchModeBroadcast:
+	default:
+		return fmt.Errorf("未知的派單模式: %s", p.DispatchMode)
+	}
+	if p.BroadcastCount < 0 || p.BroadcastCount > p.GoogleAPICandidatesCount {
+		return errors.New("搶單推播人數不可小於 0 且不可超過最終派單人數")
+	}
+	if len(p.Rounds) > 10 {
+		return errors.New("派單輪次不可超過 10 輪")
+	}
+	for i, round := range p.Rounds {
+		if round.MaxDriverDistanceKm < 0 {
+			return fmt.Errorf("第 %d 輪直線距離上限不可小於 0", i+1)
+		}
+		if round.DelaySecs < 0 || round.DelaySecs > 600 {
+			return fmt.Errorf("第 %d 輪等待秒數必須介於 0 到 600 秒", i+1)
+		}
+	}
+	return nil
+}
+
+// SequentialCallTimeout 依序呼叫每位司機的等待時間
+func (p *DispatchPolicy) SequentialCallTimeout() time.Duration {
+	return time.Duration(p.SequentialCallTimeoutSecs) * time.Second
+}
+
+// MaxDispatchDuration 完整跑完所有派單輪次的最長時間（含輪次間隔與路徑計算緩衝），供中斷訂單恢復判斷使用
+func (p *DispatchPolicy) MaxDispatchDuration() time.Duration {
+	const roundOverhead = 30 * time.Second // 查詢司機與路徑計算的緩衝時間
+
+	perRound := time.Duration(p.GoogleAPICandidatesCount) * p.SequentialCallTimeout()
+	if p.IsBroadcast() {
+		perRound = p.SequentialCallTimeout()
+	}
+
+	var total time.Duration
+	for _, round := range p.DispatchRounds() {
+		total += time.Duration(round.DelaySecs)*time.Second + perRound + roundOverhead
+	}
+	return total
+}
+
+// DefaultDispatchPolicy 車隊未設定派單策略時使用的預設值
+// 車隊設定不限派單距離時（例如 WEI）不受距離與預估時間限制
+func DefaultDispatchPolicy(fleet FleetType, settings FleetSettings) *DispatchPolicy {
+	policy := &DispatchPolicy{
+		Fleet:                      fleet,
+		HaversineCandidatesCount:   15,
+		GoogleAPICandidatesCount:   5,
+		SequentialCallTimeoutSecs:  19, // 前端顯示17秒
+		EnableMaxDriverDistance:    true,
+		MaxDriverDistanceKm:        15.0,
+		EnableMaxEstimatedTimeMins: true,
+		MaxEstimatedTimeMins:       20,
+		DispatchMode:               DispatchModeSequential,
+	}
+	if settings.UnlimitedDispatchRange {
+		policy.EnableMaxDriverDistance = false
+		policy.EnableMaxEstimatedTimeMins = false
+	}
+	return policy
+}
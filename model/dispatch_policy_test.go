@@ -0,0 +1,110 @@
+package model
+
+import "testing"
+
+func validDispatchPolicy() *DispatchPolicy {
+	return &DispatchPolicy{
+		Fleet:                      FleetTypeRSK,
+		HaversineCandidatesCount:   15,
+		GoogleAPICandidatesCount:   5,
+		SequentialCallTimeoutSecs:  19,
+		EnableMaxDriverDistance:    true,
+		MaxDriverDistanceKm:        15,
+		EnableMaxEstimatedTimeMins: true,
+		MaxEstimatedTimeMins:       20,
+	}
+}
+
+func TestDispatchPolicyValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(p *DispatchPolicy)
+		valid  bool
+	}{
+		{name: "預設值", modify: func(p *DispatchPolicy) {}, valid: true},
+		{name: "搶單模式推播全部候選", modify: func(p *DispatchPolicy) { p.DispatchMode = DispatchModeBroadcast }, valid: true},
+		{name: "搶單推播人數等於最終派單人數", modify: func(p *DispatchPolicy) { p.DispatchMode = DispatchModeBroadcast; p.BroadcastCount = 5 }, valid: true},
+		{name: "停用距離篩選時不檢查距離上限", modify: func(p *DispatchPolicy) { p.EnableMaxDriverDistance = false; p.MaxDriverDistanceKm = 0 }, valid: true},
+		{name: "多輪擴大半徑", modify: func(p *DispatchPolicy) {
+			p.Rounds = []DispatchRound{{MaxDriverDistanceKm: 3}, {MaxDriverDistanceKm: 8, DelaySecs: 10}, {CrossFleet: true, DelaySecs: 600}}
+		}, valid: true},
+
+		{name: "車隊為空", modify: func(p *DispatchPolicy) { p.Fleet = "" }},
+		{name: "候選司機數量為 0", modify: func(p *DispatchPolicy) { p.HaversineCandidatesCount = 0 }},
+		{name: "最終派單人數超過候選司機數量", modify: func(p *DispatchPolicy) { p.GoogleAPICandidatesCount = 16 }},
+		{name: "最終派單人數為 0", modify: func(p *DispatchPolicy) { p.GoogleAPICandidatesCount = 0 }},
+		{name: "等待秒數少於 5 秒", modify: func(p *DispatchPolicy) { p.SequentialCallTimeoutSecs = 4 }},
+		{name: "啟用距離篩選但上限為 0", modify: func(p *DispatchPolicy) { p.MaxDriverDistanceKm = 0 }},
+		{name: "啟用預估時間篩選但上限為 0", modify: func(p *DispatchPolicy) { p.MaxEstimatedTimeMins = 0 }},
+		{name: "未知的派單模式", modify: func(p *DispatchPolicy) { p.DispatchMode = "lottery" }},
+		{name: "搶單推播人數為負數", modify: func(p *DispatchPolicy) { p.BroadcastCount = -1 }},
+		{name: "搶單推播人數超過最終派單人數", modify: func(p *DispatchPolicy) { p.BroadcastCount = 6 }},
+		{name: "派單輪次超過 10 輪", modify: func(p *DispatchPolicy) { p.Rounds = make([]DispatchRound, 11) }},
+		{name: "輪次半徑為負數", modify: func(p *DispatchPolicy) { p.Rounds = []DispatchRound{{MaxDriverDistanceKm: -1}} }},
+		{name: "輪次等待秒數超過 600 秒", modify: func(p *DispatchPolicy) { p.Rounds = []DispatchRound{{}, {DelaySecs: 601}} }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := validDispatchPolicy()
+			tc.modify(policy)
+
+			err := policy.Validate()
+			if tc.valid && err != nil {
+				t.Fatalf("預期策略有效，但驗證失敗: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("預期策略無效，但驗證通過")
+			}
+		})
+	}
+}
+
+func TestDispatchPolicyValidateDefaultsMode(t *testing.T) {
+	policy := validDispatchPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("驗證失敗: %v", err)
+	}
+	if policy.DispatchMode != DispatchModeSequential {
+		t.Fatalf("未設定派單模式時預期為 %s，實際為 %s", DispatchModeSequential, policy.DispatchMode)
+	}
+}
+
+func TestDispatchPolicyDispatchRounds(t *testing.T) {
+	policy := validDispatchPolicy()
+	rounds := policy.DispatchRounds()
+	if len(rounds) != 1 || !rounds[0].CrossFleet {
+		t.Fatalf("未設定輪次時預期單輪開放跨車隊，實際為 %+v", rounds)
+	}
+
+	policy.Rounds = []DispatchRound{{MaxDriverDistanceKm: 3}, {MaxDriverDistanceKm: 8, DelaySecs: 10}, {CrossFleet: true}}
+	if rounds := policy.DispatchRounds(); len(rounds) != 3 {
+		t.Fatalf("預期 3 輪派單，實際為 %d 輪", len(rounds))
+	}
+}
+
+func TestDispatchPolicyMaxDriverDistanceFor(t *testing.T) {
+	testCases := []struct {
+		name        string
+		enabled     bool
+		round       DispatchRound
+		wantEnabled bool
+		wantKm      float64
+	}{
+		{name: "沿用車隊設定", enabled: true, round: DispatchRound{}, wantEnabled: true, wantKm: 15},
+		{name: "輪次半徑覆蓋車隊設定", enabled: true, round: DispatchRound{MaxDriverDistanceKm: 3}, wantEnabled: true, wantKm: 3},
+		{name: "車隊停用距離篩選時輪次半徑仍生效", enabled: false, round: DispatchRound{MaxDriverDistanceKm: 8}, wantEnabled: true, wantKm: 8},
+		{name: "車隊停用距離篩選且輪次未設定", enabled: false, round: DispatchRound{}, wantEnabled: false, wantKm: 15},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := validDispatchPolicy()
+			policy.EnableMaxDriverDistance = tc.enabled
+			enabled, km := policy.MaxDriverDistanceFor(tc.round)
+			if enabled != tc.wantEnabled || km != tc.wantKm {
+				t.Fatalf("預期 (%v, %.1f)，實際為 (%v, %.1f)", tc.wantEnabled, tc.wantKm, enabled, km)
+			}
+		})
+	}
+}
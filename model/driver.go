@@ -12,7 +12,7 @@ type DriverInfo struct {
 	Nickname               string             `json:"nickname" bson:"nickname" example:"小明" doc:"司機暱稱"`
 	DriverNo               string             `json:"driver_no" bson:"driver_no" example:"D001" doc:"司機編號"`
 	Account                string             `json:"account" bson:"account" example:"driver001@taxi.com" doc:"司機帳號"`
-	Password               string             `json:"password" bson:"password" example:"driver123" doc:"密碼"`
+	Password               string             `json:"-" bson:"password"` // bcrypt 雜湊，不輸出於 API 回應
 	CarPlate               string             `json:"car_plate" bson:"car_plate" example:"6793黑" doc:"車牌號碼"`
 	Fleet                  FleetType          `json:"fleet" bson:"fleet" example:"RSK" doc:"所屬車隊"`
 	CarModel               string             `json:"car_model" bson:"car_model" example:"納智捷S5" doc:"車型"`
@@ -27,6 +27,7 @@ type DriverInfo struct {
 	AcceptedCount          int                `json:"accepted_count" bson:"accepted_count" example:"0" doc:"接單次數"`
 	Lat                    string             `json:"lat" bson:"lat" example:"\"25.0675657\"" doc:"當前緯度"`
 	Lng                    string             `json:"lng" bson:"lng" example:"\"121.5526993\"" doc:"當前經度"`
+	Location               *GeoPoint          `json:"location,omitempty" bson:"location,omitempty" doc:"當前位置 GeoJSON（由經緯度同步維護，供地理查詢使用）"`
 	FcmToken               string             `json:"fcm_token" bson:"fcm_token" example:"token123" doc:"FCM推播令牌"`
 	FCMType                string             `json:"fcm_type,omitempty" bson:"fcm_type,omitempty" example:"web" doc:"FCM令牌類型 (web/mobile)"`
 	LineUID                string             `json:"line_uid" bson:"line_uid" example:"Uxxxxxxxx" doc:"Line用戶ID"`
@@ -47,6 +48,7 @@ type DriverInfo struct {
 	CurrentOrderId         *string            `json:"current_order_id,omitempty" bson:"current_order_id,omitempty" doc:"當前即時訂單ID"`
 	NoPets                 bool               `json:"no_pets" bson:"no_pets" example:"false" doc:"禁止寵物訂單（不接有寵物的單）"`
 	NoOverloaded           bool               `json:"no_overloaded" bson:"no_overloaded" example:"false" doc:"禁止超載訂單（不接5人以上的單）"`
+	Tags                   []string           `json:"tags,omitempty" bson:"tags,omitempty" example:"[\"airport\"]" doc:"司機標籤，服務區域規則可要求具備特定標籤才可派單"`
 	CreatedAt              time.Time          `json:"created_at" bson:"created_at" doc:"建立時間"`
 	UpdatedAt              time.Time          `json:"updated_at" bson:"updated_at" doc:"更新時間"`
 }
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DriverDeviceStatus 司機設備綁定狀態
+type DriverDeviceStatus string
+
+const (
+	DriverDeviceStatusBound   DriverDeviceStatus = "bound"   // 已綁定，可登入
+	DriverDeviceStatusPending DriverDeviceStatus = "pending" // 新設備等待管理員核准
+	DriverDeviceStatusUnbound DriverDeviceStatus = "unbound" // 已解除綁定或核准遭拒
+)
+
+// DriverDevice 司機登入設備，每位司機同時只有一台已綁定的設備；首次登入自動綁定，之後的新設備需管理員核准
+type DriverDevice struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	DriverID      primitive.ObjectID `json:"driver_id" bson:"driver_id" doc:"司機ID"`
+	DriverAccount string             `json:"driver_account" bson:"driver_account" example:"driver001@taxi.com" doc:"司機帳號"`
+	Fleet         FleetType          `json:"fleet" bson:"fleet" example:"RSK" doc:"司機所屬車隊"`
+	DeviceID      string             `json:"device_id" bson:"device_id" example:"8F3C2A1E-6B7D-4E21-9C55-0A1B2C3D4E5F" doc:"設備識別碼"`
+	ModelName     string             `json:"model_name,omitempty" bson:"model_name,omitempty" example:"iPhone15,4" doc:"設備型號名稱"`
+	DeviceName    string             `json:"device_name,omitempty" bson:"device_name,omitempty" example:"My iPhone" doc:"設備名稱"`
+	Brand         string             `json:"brand,omitempty" bson:"brand,omitempty" example:"Apple" doc:"設備品牌"`
+	Manufacturer  string             `json:"manufacturer,omitempty" bson:"manufacturer,omitempty" example:"Apple Inc." doc:"設備製造商"`
+	AppVersion    string             `json:"app_version,omitempty" bson:"app_version,omitempty" example:"1.0.0" doc:"應用程式版本"`
+	Status        DriverDeviceStatus `json:"status" bson:"status" example:"bound" doc:"綁定狀態(bound/pending/unbound)"`
+	RequestedAt   time.Time          `json:"requested_at" bson:"requested_at" doc:"首次登入或申請綁定時間"`
+	BoundAt       *time.Time         `json:"bound_at,omitempty" bson:"bound_at,omitempty" doc:"綁定時間"`
+	UnboundAt     *time.Time         `json:"unbound_at,omitempty" bson:"unbound_at,omitempty" doc:"解除綁定時間"`
+	ReviewedBy    string             `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty" example:"admin001" doc:"核准或解除綁定的管理員帳號"`
+	LastLoginAt   *time.Time         `json:"last_login_at,omitempty" bson:"last_login_at,omitempty" doc:"最後登入時間"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at" doc:"更新時間"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultDriverLocationRetentionDays 位置歷史預設保留天數
+const DefaultDriverLocationRetentionDays = 30
+
+// DriverLocation 司機位置歷史軌跡點（存放於 driver_locations 時間序列集合，超過保留天數自動清除）
+type DriverLocation struct {
+	ID         *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"軌跡點ID"`
+	DriverID   string              `json:"driver_id" bson:"driver_id" example:"684a73ad0e3a583c37e4b30d" doc:"司機ID"`
+	Lat        float64             `json:"lat" bson:"lat" example:"25.0675657" doc:"緯度"`
+	Lng        float64             `json:"lng" bson:"lng" example:"121.5526993" doc:"經度"`
+	Status     DriverStatus        `json:"status,omitempty" bson:"status,omitempty" doc:"記錄當下的司機狀態"`
+	OrderID    string              `json:"order_id,omitempty" bson:"order_id,omitempty" doc:"記錄當下執行中的訂單ID"`
+	RecordedAt time.Time           `json:"recorded_at" bson:"recorded_at" doc:"記錄時間"`
+}
@@ -45,9 +45,10 @@ const (
 	TokenTypeUser   TokenType = "user"   // 用戶 token
 )
 
-// FleetType 車隊類型
+// FleetType 車隊代碼，可用車隊與派單規則以 fleets 集合登錄為準（見 Fleet）
 type FleetType string
 
+// 初始車隊代碼，僅作為預設登錄資料與 WEI 專用指令使用
 const (
 	FleetTypeRSK FleetType = "RSK" // RSK 車隊
 	FleetTypeKD  FleetType = "KD"  // KD 車隊
@@ -92,6 +93,7 @@ const (
 	EventScheduledWaiting   EventType = "scheduled_waiting"     // 預約單等待接單
 	EventOrderConverted     EventType = "order_converted"       // 預約單轉換為即時單
 	EventChat               EventType = "chat"                  // 聊天消息
+	EventWaypointUpdated    EventType = "waypoint_updated"      // 停靠點狀態更新
 )
 
 // CreatedBy 訂單建立者類型
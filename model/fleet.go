@@ -0,0 +1,108 @@
+package model
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Fleet 車隊登錄資料（存放於 fleets 集合，新增合作車隊不需修改程式）
+type Fleet struct {
+	ID                    *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"車隊ID"`
+	Code                  FleetType           `json:"code" bson:"code" example:"RSK" doc:"車隊代碼（唯一）"`
+	DisplayName           string              `json:"display_name" bson:"display_name" example:"RSK 車隊" doc:"顯示名稱"`
+	CustomerGroupPrefixes []string            `json:"customer_group_prefixes" bson:"customer_group_prefixes" example:"[\"R\"]" doc:"客群前綴，建單未指定車隊時依此判斷所屬車隊"`
+	DispatchToFleets      []FleetType         `json:"dispatch_to_fleets" bson:"dispatch_to_fleets" example:"[\"KD\"]" doc:"本車隊訂單可跨車隊派給哪些車隊的司機（本車隊司機一律可派）"`
+	IsDefault             bool                `json:"is_default" bson:"is_default" example:"false" doc:"客群前綴皆不符合時使用的預設車隊"`
+	Settings              FleetSettings       `json:"settings" bson:"settings" doc:"車隊個別設定"`
+	Enabled               bool                `json:"enabled" bson:"enabled" example:"true" doc:"是否啟用，停用後不可建立該車隊訂單"`
+	UpdatedBy             string              `json:"updated_by,omitempty" bson:"updated_by,omitempty" doc:"最後修改者"`
+	CreatedAt             *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt             *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// FleetSettings 車隊個別設定
+type FleetSettings struct {
+	UnlimitedDispatchRange bool               `json:"unlimited_dispatch_range" bson:"unlimited_dispatch_range" example:"false" doc:"預設派單策略不限制司機距離與預估時間"`
+	CommissionPercent      float64            `json:"commission_percent" bson:"commission_percent" example:"10" doc:"司機結算時依車資抽成的百分比"`
+	CommissionPerOrder     int                `json:"commission_per_order" bson:"commission_per_order" example:"0" doc:"司機結算時每筆訂單固定抽成（元）"`
+	Cancellation           CancellationPolicy `json:"cancellation" bson:"cancellation" doc:"取消政策與乘客未到規則"`
+}
+
+// Commission 依車隊抽成規則計算單筆訂單的抽成金額，無車資時不抽成，抽成不超過車資（司機實得不為負數）
+func (s FleetSettings) Commission(fare int) int {
+	if fare <= 0 {
+		return 0
+	}
+	commission := int(math.Round(float64(fare)*s.CommissionPercent/100)) + s.CommissionPerOrder
+	return min(commission, fare)
+}
+
+// CanDispatchTo 本車隊訂單是否可派給指定車隊的司機
+func (f *Fleet) CanDispatchTo(driverFleet FleetType) bool {
+	if driverFleet == f.Code {
+		return true
+	}
+	for _, fleet := range f.DispatchToFleets {
+		if fleet == driverFleet {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchCustomerGroup 客群是否符合本車隊的客群前綴，回傳符合的前綴長度（0 表示不符合）
+func (f *Fleet) MatchCustomerGroup(customerGroup string) int {
+	longest := 0
+	for _, prefix := range f.CustomerGroupPrefixes {
+		if prefix != "" && strings.HasPrefix(customerGroup, strings.ToUpper(prefix)) && len(prefix) > longest {
+			longest = len(prefix)
+		}
+	}
+	return longest
+}
+
+// UnregisteredFleet 未登錄車隊的替代設定：只派給本車隊司機，不套用任何車隊設定
+func UnregisteredFleet(code FleetType) *Fleet {
+	return &Fleet{Code: code, DisplayName: string(code)}
+}
+
+// DefaultFleetFor 從預設車隊取得車隊設定，供未接上車隊登錄服務時使用
+func DefaultFleetFor(code FleetType) *Fleet {
+	for _, fleet := range DefaultFleets() {
+		if fleet.Code == code {
+			return fleet
+		}
+	}
+	return UnregisteredFleet(code)
+}
+
+// DefaultFleets fleets 集合為空時使用的預設車隊，與登錄機制上線前的規則一致：
+// RSK、KD 互相派送，WEI 獨立且不限派單距離，客群前綴不符合時歸屬 WEI
+func DefaultFleets() []*Fleet {
+	return []*Fleet{
+		{
+			Code:                  FleetTypeRSK,
+			DisplayName:           "RSK",
+			CustomerGroupPrefixes: []string{"R"},
+			DispatchToFleets:      []FleetType{FleetTypeKD},
+			Enabled:               true,
+		},
+		{
+			Code:                  FleetTypeKD,
+			DisplayName:           "KD",
+			CustomerGroupPrefixes: []string{"E"},
+			DispatchToFleets:      []FleetType{FleetTypeRSK},
+			Enabled:               true,
+		},
+		{
+			Code:        FleetTypeWEI,
+			DisplayName: "WEI",
+			IsDefault:   true,
+			Settings:    FleetSettings{UnlimitedDispatchRange: true},
+			Enabled:     true,
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GeoPoint GeoJSON 點座標，供 MongoDB 2dsphere 索引與 $geoNear 查詢使用
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type" example:"Point" doc:"GeoJSON 類型"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates" doc:"座標 [經度, 緯度]"`
+}
+
+// NewGeoPoint 以緯度、經度建立 GeoJSON 點（GeoJSON 座標順序為經度在前）
+func NewGeoPoint(lat, lng float64) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
+// ParseGeoPoint 將字串經緯度轉為 GeoJSON 點，無法解析、超出範圍或為 0,0 時回傳 false
+func ParseGeoPoint(lat, lng string) (*GeoPoint, bool) {
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	if err != nil {
+		return nil, false
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if err != nil {
+		return nil, false
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 || (latitude == 0 && longitude == 0) {
+		return nil, false
+	}
+	return NewGeoPoint(latitude, longitude), true
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginLockoutScope 登入鎖定對象
+type LoginLockoutScope string
+
+const (
+	LoginLockoutScopeAccount LoginLockoutScope = "account" // 帳號連續登入失敗
+	LoginLockoutScopeIP      LoginLockoutScope = "ip"      // 同一 IP 大量登入失敗
+)
+
+// LoginLockoutAction 登入鎖定稽核動作
+type LoginLockoutAction string
+
+const (
+	LoginLockoutActionLocked   LoginLockoutAction = "locked"   // 失敗次數達上限而鎖定
+	LoginLockoutActionUnlocked LoginLockoutAction = "unlocked" // 管理員解除鎖定
+)
+
+// LoginLockoutEvent 登入鎖定稽核紀錄，只新增不修改
+type LoginLockoutEvent struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Action      LoginLockoutAction `json:"action" bson:"action" example:"locked" doc:"動作(locked/unlocked)"`
+	Scope       LoginLockoutScope  `json:"scope" bson:"scope" example:"account" doc:"鎖定對象(account/ip)"`
+	LoginType   TokenType          `json:"login_type,omitempty" bson:"login_type,omitempty" example:"driver" doc:"登入類型(user/driver)，鎖定 IP 時為空"`
+	Account     string             `json:"account,omitempty" bson:"account,omitempty" example:"driver001@taxi.com" doc:"登入帳號"`
+	IP          string             `json:"ip,omitempty" bson:"ip,omitempty" example:"203.0.113.7" doc:"來源 IP"`
+	Failures    int64              `json:"failures,omitempty" bson:"failures,omitempty" example:"5" doc:"鎖定時統計期間內的失敗次數"`
+	Level       int64              `json:"level,omitempty" bson:"level,omitempty" example:"1" doc:"第幾次鎖定，每次鎖定時間加倍"`
+	LockedUntil *time.Time         `json:"locked_until,omitempty" bson:"locked_until,omitempty" doc:"鎖定到期時間"`
+	Operator    string             `json:"operator,omitempty" bson:"operator,omitempty" example:"admin001" doc:"解除鎖定的管理員帳號"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at" doc:"紀錄時間"`
+}
@@ -63,8 +63,13 @@ type Order struct {
 	AmountNote           string              `json:"amount_note,omitempty" bson:"amount_note,omitempty" doc:"金額備註"`
 	Income               *int                `json:"income,omitempty" bson:"income,omitempty" example:"100" doc:"收入"`
 	Expense              *int                `json:"expense,omitempty" bson:"expense,omitempty" example:"50" doc:"支出"`
+	FareEstimate         *FareBreakdown      `json:"fare_estimate,omitempty" bson:"fare_estimate,omitempty" doc:"建單時的預估車資"`
+	FinalFare            *FareBreakdown      `json:"final_fare,omitempty" bson:"final_fare,omitempty" doc:"完成訂單時計算的實際車資"`
+	CancellationFee      *CancellationFee    `json:"cancellation_fee,omitempty" bson:"cancellation_fee,omitempty" doc:"取消或乘客未到時依車隊取消政策計算的費用"`
 	PassengerID          string              `json:"passenger_id,omitempty" bson:"passenger_id,omitempty" doc:"乘客ID"`
 	Customer             Customer            `json:"customer" bson:"customer"`
+	Waypoints            []Waypoint          `json:"waypoints,omitempty" bson:"waypoints,omitempty" doc:"多點行程的停靠點（依序，最後一站為目的地）"`
+	IsRoundTrip          bool                `json:"is_round_trip,omitempty" bson:"is_round_trip,omitempty" doc:"是否為來回行程（最後一站為返回上車點）"`
 	CustomerGroup        string              `json:"customer_group" bson:"customer_group"`
 	Fleet                FleetType           `json:"fleet" bson:"fleet" example:"RSK" doc:"車隊"`
 	Rounds               *int                `json:"rounds,omitempty" bson:"rounds,omitempty" example:"1" doc:"派單輪數"`
@@ -73,10 +78,13 @@ type Order struct {
 	HasMeterJump         bool                `json:"has_meter_jump,omitempty" bson:"has_meter_jump,omitempty" doc:"是否跳表"`
 	IsErrand             bool                `json:"is_errand,omitempty" bson:"is_errand,omitempty" doc:"是否為跑腿"`
 	IsScheduled          bool                `json:"is_scheduled,omitempty" bson:"is_scheduled,omitempty" doc:"是否為預約單"`
+	RecurringOrderID     string              `json:"recurring_order_id,omitempty" bson:"recurring_order_id,omitempty" doc:"來源週期性預約ID"`
+	OccurrenceDate       string              `json:"occurrence_date,omitempty" bson:"occurrence_date,omitempty" example:"2025-10-06" doc:"週期性預約的日期"`
 	CreatedBy            string              `json:"created_by,omitempty" bson:"created_by,omitempty" doc:"建立者姓名"`
 	CreatedType          string              `json:"created_type,omitempty" bson:"created_type,omitempty" doc:"建立者類型(discord/line/system)"`
 	CreatedAt            *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
 	UpdatedAt            *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+	EnqueuedAt           *time.Time          `json:"enqueued_at,omitempty" bson:"enqueued_at,omitempty" doc:"最後一次排入即時單派單隊列的時間"`
 	AcceptanceTime       *time.Time          `json:"acceptance_time,omitempty" bson:"acceptance_time,omitempty" doc:"接單時間"`
 	ArrivalTime          *time.Time          `json:"arrival_time,omitempty" bson:"arrival_time,omitempty" doc:"到達時間"`
 	PickUpTime           *time.Time          `json:"pickup_time,omitempty" bson:"pickup_time,omitempty" doc:"客人上車時間"`
@@ -109,6 +117,12 @@ const (
 	OrderLogActionCustomerPickup OrderLogAction = "執行任務"
 	OrderLogActionOrderCompleted OrderLogAction = "司機完成"
 	OrderLogActionDispatchCancel OrderLogAction = "調度取消"
+	OrderLogActionDriverLost     OrderLogAction = "他人已接"
+	OrderLogActionDispatchRound  OrderLogAction = "派單輪次"
+	OrderLogActionDispatchResume OrderLogAction = "派單恢復"
+	OrderLogActionWaypointArrive OrderLogAction = "抵達停靠點"
+	OrderLogActionWaypointDone   OrderLogAction = "完成停靠點"
+	OrderLogActionNoShow         OrderLogAction = "乘客未到"
 )
 
 type OrderLogEntry struct {
@@ -262,3 +276,26 @@ func formatTimeInTaipeiHMS(t time.Time) string {
 	taipei := time.FixedZone("Asia/Taipei", 8*3600)
 	return t.In(taipei).Format("15:04:05")
 }
+
+// OrderFareSource 請款與結算使用的車資來源
+type OrderFareSource string
+
+const (
+	OrderFareFinal  OrderFareSource = "final_fare"       // 完成訂單時依費率計算的實際車資
+	OrderFareCancel OrderFareSource = "cancellation_fee" // 乘客未到或取消時的取消費
+	OrderFareIncome OrderFareSource = "income"           // 後台手動填寫的收入
+	OrderFareNone   OrderFareSource = "none"             // 無車資資料
+)
+
+// BilledFare 訂單的計費車資：優先使用完成時計算的實際車資，其次為取消費，最後為後台填寫的收入
+func (o *Order) BilledFare() (int, OrderFareSource) {
+	switch {
+	case o.FinalFare != nil:
+		return o.FinalFare.Total, OrderFareFinal
+	case o.CancellationFee != nil && o.CancellationFee.Amount > 0:
+		return o.CancellationFee.Amount, OrderFareCancel
+	case o.Income != nil:
+		return *o.Income, OrderFareIncome
+	}
+	return 0, OrderFareNone
+}
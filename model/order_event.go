@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrderEventActorType 訂單事件的操作者類型
+type OrderEventActorType string
+
+const (
+	OrderEventActorUser    OrderEventActorType = "user"    // 後台用戶
+	OrderEventActorDriver  OrderEventActorType = "driver"  // 司機
+	OrderEventActorSystem  OrderEventActorType = "system"  // 系統（派單、排程）
+	OrderEventActorDiscord OrderEventActorType = "discord" // Discord 用戶
+	OrderEventActorLine    OrderEventActorType = "line"    // LINE 用戶
+)
+
+// OrderEventActor 訂單事件的操作者
+type OrderEventActor struct {
+	Type OrderEventActorType `json:"type" bson:"type" example:"user" doc:"操作者類型(user/driver/system/discord/line)"`
+	ID   string              `json:"id,omitempty" bson:"id,omitempty" example:"684a73ad0e3a583c37e4b30d" doc:"操作者ID（用戶、司機或 LINE/Discord 帳號）"`
+	Name string              `json:"name,omitempty" bson:"name,omitempty" example:"Admin" doc:"操作者名稱"`
+}
+
+// SystemEventActor 系統操作者
+var SystemEventActor = OrderEventActor{Type: OrderEventActorSystem, Name: "系統"}
+
+// OrderEventAction 訂單事件動作
+type OrderEventAction string
+
+const (
+	OrderEventLog           OrderEventAction = "log"            // 訂單日誌（建立、派單、司機操作、取消），動作類型見 log_action
+	OrderEventStatusChanged OrderEventAction = "status_changed" // 後台修改訂單狀態
+	OrderEventRedispatched  OrderEventAction = "redispatched"   // 重新派單
+	OrderEventEdited        OrderEventAction = "edited"         // 訂單報表編輯
+	OrderEventBatchEdited   OrderEventAction = "batch_edited"   // 訂單報表批量編輯
+	OrderEventImported      OrderEventAction = "imported"       // Excel 匯入新增
+	OrderEventImportUpdated OrderEventAction = "import_updated" // Excel 匯入更新
+)
+
+// OrderFieldChange 訂單欄位異動，Before 與 After 為顯示用的字串值
+type OrderFieldChange struct {
+	Field  string `json:"field" bson:"field" example:"status" doc:"欄位（巢狀欄位以 . 分隔）"`
+	Before string `json:"before" bson:"before" example:"流單" doc:"異動前的值"`
+	After  string `json:"after" bson:"after" example:"完成" doc:"異動後的值"`
+}
+
+// OrderEvent 訂單事件，存放於只新增不修改的 order_events 集合，作為訂單的完整稽核紀錄
+type OrderEvent struct {
+	ID        *primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	OrderID   primitive.ObjectID  `json:"order_id" bson:"order_id" doc:"訂單ID"`
+	Action    OrderEventAction    `json:"action" bson:"action" example:"edited" doc:"事件動作"`
+	LogAction OrderLogAction      `json:"log_action,omitempty" bson:"log_action,omitempty" example:"司機接單" doc:"訂單日誌動作類型（action 為 log 時）"`
+	Actor     OrderEventActor     `json:"actor" bson:"actor" doc:"操作者"`
+	Changes   []OrderFieldChange  `json:"changes,omitempty" bson:"changes,omitempty" doc:"欄位異動"`
+	Details   string              `json:"details,omitempty" bson:"details,omitempty" doc:"額外詳情"`
+	TraceID   string              `json:"trace_id,omitempty" bson:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736" doc:"追蹤ID（OpenTelemetry trace）"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at" doc:"事件時間"`
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// OrderActor 觸發訂單狀態轉換的角色
+type OrderActor string
+
+const (
+	OrderActorDriver    OrderActor = "driver"    // 司機 App（接單、抵達、上車、完成、回報乘客未到）
+	OrderActorPassenger OrderActor = "passenger" // 乘客或客群（LINE、Discord 取消與重新派單）
+	OrderActorAdmin     OrderActor = "admin"     // 後台人員（網頁取消、修改狀態、報表編輯、匯入、重新派單）
+	OrderActorSystem    OrderActor = "system"    // 系統（派單流單、建單失敗、死信重送）
+)
+
+// OrderNotification 狀態轉換後應發送的通知（Discord、LINE、SSE）
+type OrderNotification string
+
+const (
+	OrderNotifyNone      OrderNotification = ""
+	OrderNotifyAccepted  OrderNotification = "accepted"
+	OrderNotifyArrived   OrderNotification = "arrived"
+	OrderNotifyOnBoard   OrderNotification = "on_board"
+	OrderNotifyCompleted OrderNotification = "completed"
+	OrderNotifyCancelled OrderNotification = "cancelled"
+	OrderNotifyFailed    OrderNotification = "failed"
+)
+
+// OrderTransition 訂單狀態轉換規則：允許觸發的角色，以及轉換後的副作用
+type OrderTransition struct {
+	From         OrderStatus
+	To           OrderStatus
+	Actors       []OrderActor
+	DriverStatus DriverStatus      // 轉換後指派司機應處於的狀態，空白表示不變
+	Notify       OrderNotification // 轉換後應發送的通知
+}
+
+// orderTransitions 訂單狀態機，未列出的轉換一律不允許。
+// 訂單建立時為等待接單（上車地點查詢失敗為系統失敗，匯入歷史訂單為完成），之後的狀態變更都必須符合此表
+var orderTransitions = []OrderTransition{
+	// 派單
+	{From: OrderStatusWaiting, To: OrderStatusEnroute, Actors: []OrderActor{OrderActorDriver}, DriverStatus: DriverStatusEnroute, Notify: OrderNotifyAccepted},
+	{From: OrderStatusWaiting, To: OrderStatusScheduleAccepted, Actors: []OrderActor{OrderActorDriver}, Notify: OrderNotifyAccepted},
+	{From: OrderStatusWaiting, To: OrderStatusFailed, Actors: []OrderActor{OrderActorSystem}, Notify: OrderNotifyFailed},
+	{From: OrderStatusScheduleAccepted, To: OrderStatusEnroute, Actors: []OrderActor{OrderActorDriver}, DriverStatus: DriverStatusEnroute, Notify: OrderNotifyAccepted},
+
+	// 司機執行
+	{From: OrderStatusEnroute, To: OrderStatusDriverArrived, Actors: []OrderActor{OrderActorDriver}, DriverStatus: DriverStatusArrived, Notify: OrderNotifyArrived},
+	{From: OrderStatusDriverArrived, To: OrderStatusExecuting, Actors: []OrderActor{OrderActorDriver}, DriverStatus: DriverStatusExecuting, Notify: OrderNotifyOnBoard},
+	{From: OrderStatusExecuting, To: OrderStatusCompleted, Actors: []OrderActor{OrderActorDriver, OrderActorAdmin}, DriverStatus: DriverStatusIdle, Notify: OrderNotifyCompleted},
+	{From: OrderStatusDriverArrived, To: OrderStatusCompleted, Actors: []OrderActor{OrderActorDriver, OrderActorAdmin}, DriverStatus: DriverStatusIdle, Notify: OrderNotifyCompleted}, // 乘客未到
+
+	// 取消
+	{From: OrderStatusWaiting, To: OrderStatusCancelled, Actors: []OrderActor{OrderActorPassenger, OrderActorAdmin}, Notify: OrderNotifyCancelled},
+	{From: OrderStatusScheduleAccepted, To: OrderStatusCancelled, Actors: []OrderActor{OrderActorPassenger, OrderActorAdmin}, Notify: OrderNotifyCancelled},
+	{From: OrderStatusEnroute, To: OrderStatusCancelled, Actors: []OrderActor{OrderActorPassenger, OrderActorAdmin}, DriverStatus: DriverStatusIdle, Notify: OrderNotifyCancelled},
+	{From: OrderStatusDriverArrived, To: OrderStatusCancelled, Actors: []OrderActor{OrderActorPassenger, OrderActorAdmin}, DriverStatus: DriverStatusIdle, Notify: OrderNotifyCancelled},
+
+	// 重新派單（重設為等待接單並清除司機）
+	{From: OrderStatusWaiting, To: OrderStatusWaiting, Actors: []OrderActor{OrderActorPassenger, OrderActorAdmin, OrderActorSystem}},
+	{From: OrderStatusFailed, To: OrderStatusWaiting, Actors: []OrderActor{OrderActorPassenger, OrderActorAdmin, OrderActorSystem}},
+	{From: OrderStatusSystemFailed, To: OrderStatusWaiting, Actors: []OrderActor{OrderActorAdmin, OrderActorSystem}},
+	{From: OrderStatusCancelled, To: OrderStatusWaiting, Actors: []OrderActor{OrderActorAdmin, OrderActorSystem}},
+
+	// 後台報表更正（線下完成補登、誤建單取消）
+	{From: OrderStatusFailed, To: OrderStatusCompleted, Actors: []OrderActor{OrderActorAdmin}},
+	{From: OrderStatusSystemFailed, To: OrderStatusCompleted, Actors: []OrderActor{OrderActorAdmin}},
+	{From: OrderStatusCancelled, To: OrderStatusCompleted, Actors: []OrderActor{OrderActorAdmin}},
+	{From: OrderStatusCompleted, To: OrderStatusCancelled, Actors: []OrderActor{OrderActorAdmin}},
+	{From: OrderStatusFailed, To: OrderStatusCancelled, Actors: []OrderActor{OrderActorAdmin}},
+}
+
+// ErrIllegalOrderTransition 不允許的訂單狀態轉換，可用 errors.Is 判斷
+var ErrIllegalOrderTransition = errors.New("不允許的訂單狀態轉換")
+
+// IllegalTransitionError 不允許的訂單狀態轉換
+type IllegalTransitionError struct {
+	From  OrderStatus
+	To    OrderStatus
+	Actor OrderActor
+}
+
+func (e *IllegalTransitionError) Error() string {
+	if FindOrderTransition(e.From, e.To) != nil {
+		return fmt.Sprintf("%s: %s 無法將訂單從「%s」轉為「%s」", ErrIllegalOrderTransition, e.Actor, e.From, e.To)
+	}
+	return fmt.Sprintf("%s: 訂單無法從「%s」轉為「%s」", ErrIllegalOrderTransition, e.From, e.To)
+}
+
+func (e *IllegalTransitionError) Is(target error) bool {
+	return target == ErrIllegalOrderTransition
+}
+
+// FindOrderTransition 查詢狀態轉換規則，未定義時回傳 nil
+func FindOrderTransition(from, to OrderStatus) *OrderTransition {
+	for i := range orderTransitions {
+		if orderTransitions[i].From == from && orderTransitions[i].To == to {
+			transition := orderTransitions[i]
+			return &transition
+		}
+	}
+	return nil
+}
+
+// CheckOrderTransition 檢查角色是否可將訂單從 from 轉為 to，不允許時回傳 *IllegalTransitionError
+func CheckOrderTransition(from, to OrderStatus, actor OrderActor) (*OrderTransition, error) {
+	transition := FindOrderTransition(from, to)
+	if transition == nil || !slices.Contains(transition.Actors, actor) {
+		return nil, &IllegalTransitionError{From: from, To: to, Actor: actor}
+	}
+	return transition, nil
+}
+
+// OrderTransitionSources 角色可轉為指定狀態的所有來源狀態，供資料庫原子更新作為條件
+func OrderTransitionSources(to OrderStatus, actor OrderActor) []OrderStatus {
+	var sources []OrderStatus
+	for _, transition := range orderTransitions {
+		if transition.To == to && slices.Contains(transition.Actors, actor) {
+			sources = append(sources, transition.From)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+var allOrderStatuses = []OrderStatus{
+	OrderStatusWaiting,
+	OrderStatusScheduleAccepted,
+	OrderStatusEnroute,
+	OrderStatusDriverArrived,
+	OrderStatusExecuting,
+	OrderStatusCompleted,
+	OrderStatusFailed,
+	OrderStatusSystemFailed,
+	OrderStatusCancelled,
+}
+
+func TestCheckOrderTransition(t *testing.T) {
+	testCases := []struct {
+		name         string
+		from         OrderStatus
+		to           OrderStatus
+		actor        OrderActor
+		allowed      bool
+		driverStatus DriverStatus
+		notify       OrderNotification
+	}{
+		{name: "司機接即時單", from: OrderStatusWaiting, to: OrderStatusEnroute, actor: OrderActorDriver, allowed: true, driverStatus: DriverStatusEnroute, notify: OrderNotifyAccepted},
+		{name: "司機接預約單", from: OrderStatusWaiting, to: OrderStatusScheduleAccepted, actor: OrderActorDriver, allowed: true, notify: OrderNotifyAccepted},
+		{name: "司機啟動預約單", from: OrderStatusScheduleAccepted, to: OrderStatusEnroute, actor: OrderActorDriver, allowed: true, driverStatus: DriverStatusEnroute, notify: OrderNotifyAccepted},
+		{name: "司機抵達", from: OrderStatusEnroute, to: OrderStatusDriverArrived, actor: OrderActorDriver, allowed: true, driverStatus: DriverStatusArrived, notify: OrderNotifyArrived},
+		{name: "乘客上車", from: OrderStatusDriverArrived, to: OrderStatusExecuting, actor: OrderActorDriver, allowed: true, driverStatus: DriverStatusExecuting, notify: OrderNotifyOnBoard},
+		{name: "司機完成訂單", from: OrderStatusExecuting, to: OrderStatusCompleted, actor: OrderActorDriver, allowed: true, driverStatus: DriverStatusIdle, notify: OrderNotifyCompleted},
+		{name: "司機回報乘客未到", from: OrderStatusDriverArrived, to: OrderStatusCompleted, actor: OrderActorDriver, allowed: true, driverStatus: DriverStatusIdle, notify: OrderNotifyCompleted},
+		{name: "系統流單", from: OrderStatusWaiting, to: OrderStatusFailed, actor: OrderActorSystem, allowed: true, notify: OrderNotifyFailed},
+		{name: "乘客取消等待中訂單", from: OrderStatusWaiting, to: OrderStatusCancelled, actor: OrderActorPassenger, allowed: true, notify: OrderNotifyCancelled},
+		{name: "後台取消前往中訂單需釋放司機", from: OrderStatusEnroute, to: OrderStatusCancelled, actor: OrderActorAdmin, allowed: true, driverStatus: DriverStatusIdle, notify: OrderNotifyCancelled},
+		{name: "乘客重新派單流單訂單", from: OrderStatusFailed, to: OrderStatusWaiting, actor: OrderActorPassenger, allowed: true},
+		{name: "後台補登已完成", from: OrderStatusFailed, to: OrderStatusCompleted, actor: OrderActorAdmin, allowed: true},
+
+		{name: "已完成訂單不可回到前往上車點", from: OrderStatusCompleted, to: OrderStatusEnroute, actor: OrderActorDriver},
+		{name: "已取消訂單不可由司機接單", from: OrderStatusCancelled, to: OrderStatusEnroute, actor: OrderActorDriver},
+		{name: "不可跳過抵達直接上車", from: OrderStatusEnroute, to: OrderStatusExecuting, actor: OrderActorDriver},
+		{name: "執行中訂單不可取消", from: OrderStatusExecuting, to: OrderStatusCancelled, actor: OrderActorPassenger},
+		{name: "乘客不可完成訂單", from: OrderStatusExecuting, to: OrderStatusCompleted, actor: OrderActorPassenger},
+		{name: "司機不可取消訂單", from: OrderStatusEnroute, to: OrderStatusCancelled, actor: OrderActorDriver},
+		{name: "乘客不可重新派單已取消訂單", from: OrderStatusCancelled, to: OrderStatusWaiting, actor: OrderActorPassenger},
+		{name: "後台不可將訂單標為流單", from: OrderStatusWaiting, to: OrderStatusFailed, actor: OrderActorAdmin},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			transition, err := CheckOrderTransition(tc.from, tc.to, tc.actor)
+			if !tc.allowed {
+				if err == nil {
+					t.Fatalf("預期 %s 不可將訂單從 %s 轉為 %s", tc.actor, tc.from, tc.to)
+				}
+				if !errors.Is(err, ErrIllegalOrderTransition) {
+					t.Fatalf("錯誤應可用 errors.Is 判斷為 ErrIllegalOrderTransition，實際為 %v", err)
+				}
+				var illegal *IllegalTransitionError
+				if !errors.As(err, &illegal) || illegal.From != tc.from || illegal.To != tc.to || illegal.Actor != tc.actor {
+					t.Fatalf("錯誤應包含轉換資訊，實際為 %#v", err)
+				}
+				if transition != nil {
+					t.Fatalf("不允許的轉換不應回傳規則")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("預期允許轉換，實際錯誤 %v", err)
+			}
+			if transition.DriverStatus != tc.driverStatus {
+				t.Errorf("司機狀態應為 %q，實際為 %q", tc.driverStatus, transition.DriverStatus)
+			}
+			if transition.Notify != tc.notify {
+				t.Errorf("通知應為 %q，實際為 %q", tc.notify, transition.Notify)
+			}
+		})
+	}
+}
+
+func TestIllegalTransitionErrorMessage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      *IllegalTransitionError
+		expected string
+	}{
+		{
+			name:     "未定義的轉換",
+			err:      &IllegalTransitionError{From: OrderStatusCompleted, To: OrderStatusEnroute, Actor: OrderActorDriver},
+			expected: "不允許的訂單狀態轉換: 訂單無法從「完成」轉為「前往上車點」",
+		},
+		{
+			name:     "角色不允許",
+			err:      &IllegalTransitionError{From: OrderStatusEnroute, To: OrderStatusCancelled, Actor: OrderActorDriver},
+			expected: "不允許的訂單狀態轉換: driver 無法將訂單從「前往上車點」轉為「乘客取消」",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.err.Error(); got != tc.expected {
+				t.Errorf("錯誤訊息應為 %q，實際為 %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestOrderTransitionSources(t *testing.T) {
+	testCases := []struct {
+		name     string
+		to       OrderStatus
+		actor    OrderActor
+		expected []OrderStatus
+	}{
+		{name: "司機完成", to: OrderStatusCompleted, actor: OrderActorDriver, expected: []OrderStatus{OrderStatusExecuting, OrderStatusDriverArrived}},
+		{name: "乘客取消", to: OrderStatusCancelled, actor: OrderActorPassenger, expected: []OrderStatus{OrderStatusWaiting, OrderStatusScheduleAccepted, OrderStatusEnroute, OrderStatusDriverArrived}},
+		{name: "乘客重新派單", to: OrderStatusWaiting, actor: OrderActorPassenger, expected: []OrderStatus{OrderStatusWaiting, OrderStatusFailed}},
+		{name: "司機前往上車點", to: OrderStatusEnroute, actor: OrderActorDriver, expected: []OrderStatus{OrderStatusWaiting, OrderStatusScheduleAccepted}},
+		{name: "乘客無法讓訂單抵達", to: OrderStatusDriverArrived, actor: OrderActorPassenger, expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := OrderTransitionSources(tc.to, tc.actor)
+			if !slices.Equal(got, tc.expected) {
+				t.Errorf("來源狀態應為 %v，實際為 %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestOrderTransitionTable 狀態機本身的一致性：不重複定義，且每條規則都有角色
+func TestOrderTransitionTable(t *testing.T) {
+	seen := map[[2]OrderStatus]bool{}
+	for _, transition := range orderTransitions {
+		key := [2]OrderStatus{transition.From, transition.To}
+		if seen[key] {
+			t.Errorf("重複定義轉換 %s -> %s", transition.From, transition.To)
+		}
+		seen[key] = true
+		if len(transition.Actors) == 0 {
+			t.Errorf("轉換 %s -> %s 沒有允許的角色", transition.From, transition.To)
+		}
+		if !slices.Contains(allOrderStatuses, transition.From) || !slices.Contains(allOrderStatuses, transition.To) {
+			t.Errorf("轉換 %s -> %s 使用未知的狀態", transition.From, transition.To)
+		}
+	}
+
+	// 終止狀態只有後台可以更正
+	for _, from := range []OrderStatus{OrderStatusCompleted, OrderStatusCancelled, OrderStatusSystemFailed} {
+		for _, to := range allOrderStatuses {
+			for _, actor := range []OrderActor{OrderActorDriver, OrderActorPassenger} {
+				if _, err := CheckOrderTransition(from, to, actor); err == nil {
+					t.Errorf("%s 不應可將訂單從 %s 轉為 %s", actor, from, to)
+				}
+			}
+		}
+	}
+
+	// 司機可轉換的目標只限派單與執行流程
+	driverTargets := []OrderStatus{OrderStatusEnroute, OrderStatusScheduleAccepted, OrderStatusDriverArrived, OrderStatusExecuting, OrderStatusCompleted}
+	for _, from := range allOrderStatuses {
+		for _, to := range allOrderStatuses {
+			if _, err := CheckOrderTransition(from, to, OrderActorDriver); err == nil && !slices.Contains(driverTargets, to) {
+				t.Errorf("司機不應可將訂單從 %s 轉為 %s", from, to)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Passenger 乘客資料（存放於 passengers 集合），LINE 下單時依 LINE 用戶ID 自動建立並連結訂單
+type Passenger struct {
+	ID                *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"乘客ID"`
+	LineUserID        string              `json:"line_user_id,omitempty" bson:"line_user_id,omitempty" example:"Uxxxxxxxx" doc:"LINE 用戶ID（唯一）"`
+	Name              string              `json:"name,omitempty" bson:"name,omitempty" example:"林先生" doc:"稱呼"`
+	Phone             string              `json:"phone,omitempty" bson:"phone,omitempty" example:"0912345678" doc:"電話"`
+	CustomerGroup     string              `json:"customer_group,omitempty" bson:"customer_group,omitempty" example:"R1" doc:"最近一次下單的客群"`
+	Notes             string              `json:"notes,omitempty" bson:"notes,omitempty" example:"需要輪椅空間，常帶大型犬" doc:"給派單員與司機的備註"`
+	Tags              []string            `json:"tags,omitempty" bson:"tags,omitempty" example:"[\"輪椅\",\"寵物\"]" doc:"標籤"`
+	FavoriteAddresses []FavoriteAddress   `json:"favorite_addresses,omitempty" bson:"favorite_addresses,omitempty" doc:"常用地點（手動維護）"`
+	FrequentAddresses []FrequentAddress   `json:"frequent_addresses,omitempty" bson:"frequent_addresses,omitempty" doc:"常搭乘上車地點（依訂單自動統計，最多保留 20 筆）"`
+	Blocked           bool                `json:"blocked" bson:"blocked" example:"false" doc:"是否封鎖，封鎖後無法透過 LINE 下單"`
+	BlockedReason     string              `json:"blocked_reason,omitempty" bson:"blocked_reason,omitempty" doc:"封鎖原因"`
+	OrderCount        int                 `json:"order_count" bson:"order_count" example:"12" doc:"連結的訂單數"`
+	LastOrderID       string              `json:"last_order_id,omitempty" bson:"last_order_id,omitempty" doc:"最近一筆訂單ID"`
+	LastOrderAt       *time.Time          `json:"last_order_at,omitempty" bson:"last_order_at,omitempty" doc:"最近下單時間"`
+	UpdatedBy         string              `json:"updated_by,omitempty" bson:"updated_by,omitempty" doc:"最後修改者"`
+	CreatedAt         *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt         *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// FavoriteAddress 乘客常用地點
+type FavoriteAddress struct {
+	Label   string `json:"label" bson:"label" example:"家" doc:"名稱"`
+	Address string `json:"address" bson:"address" example:"台北市信義區市府路1號" doc:"地址"`
+}
+
+// FrequentAddress 乘客常搭乘上車地點
+type FrequentAddress struct {
+	Address    string    `json:"address" bson:"address" example:"台北車站" doc:"上車地點（客戶輸入文字）"`
+	Count      int       `json:"count" bson:"count" example:"5" doc:"使用次數"`
+	LastUsedAt time.Time `json:"last_used_at" bson:"last_used_at" doc:"最近使用時間"`
+}
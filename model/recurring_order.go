@@ -0,0 +1,101 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecurrenceDateLayout 週期規則使用的日期格式（台北時間的日曆日）
+const RecurrenceDateLayout = "2006-01-02"
+
+// RecurrenceFrequency 重複頻率
+type RecurrenceFrequency string
+
+const (
+	RecurrenceWeekly   RecurrenceFrequency = "weekly"   // 每週指定星期
+	RecurrenceInterval RecurrenceFrequency = "interval" // 每 N 天（從開始日期起算）
+)
+
+// RecurringOrderStatus 週期性預約狀態
+type RecurringOrderStatus string
+
+const (
+	RecurringOrderActive    RecurringOrderStatus = "active"    // 啟用中，背景排程會持續產生預約單
+	RecurringOrderCancelled RecurringOrderStatus = "cancelled" // 整個系列已取消
+)
+
+// RecurrenceRule 週期規則，日期皆為台北時間的日曆日（YYYY-MM-DD）
+type RecurrenceRule struct {
+	Frequency      RecurrenceFrequency `json:"frequency" bson:"frequency" enum:"weekly,interval" example:"weekly" doc:"重複頻率(weekly: 每週指定星期, interval: 每N天)"`
+	Weekdays       []int               `json:"weekdays,omitempty" bson:"weekdays,omitempty" example:"[1,2,3,4,5]" doc:"每週的星期幾（0=週日 ... 6=週六），weekly 使用"`
+	IntervalDays   int                 `json:"interval_days,omitempty" bson:"interval_days,omitempty" example:"2" doc:"每幾天一次，interval 使用"`
+	TimeOfDay      string              `json:"time_of_day" bson:"time_of_day" example:"08:30" doc:"預約時間（台北時間 HH:MM）"`
+	StartDate      string              `json:"start_date" bson:"start_date" example:"2025-10-01" doc:"開始日期（含）"`
+	EndDate        string              `json:"end_date,omitempty" bson:"end_date,omitempty" example:"2025-12-31" doc:"結束日期（含），空白表示不結束"`
+	ExceptionDates []string            `json:"exception_dates,omitempty" bson:"exception_dates,omitempty" example:"[\"2025-10-10\"]" doc:"不產生預約單的日期（例如國定假日或已略過的單次）"`
+}
+
+// OccursOn 指定日期（YYYY-MM-DD）是否符合週期規則
+func (r *RecurrenceRule) OccursOn(date string) bool {
+	day, err := time.Parse(RecurrenceDateLayout, date)
+	if err != nil {
+		return false
+	}
+	start, err := time.Parse(RecurrenceDateLayout, r.StartDate)
+	if err != nil || day.Before(start) {
+		return false
+	}
+	if r.EndDate != "" {
+		end, err := time.Parse(RecurrenceDateLayout, r.EndDate)
+		if err != nil || day.After(end) {
+			return false
+		}
+	}
+	if slices.Contains(r.ExceptionDates, date) {
+		return false
+	}
+
+	switch r.Frequency {
+	case RecurrenceWeekly:
+		return slices.Contains(r.Weekdays, int(day.Weekday()))
+	case RecurrenceInterval:
+		if r.IntervalDays <= 0 {
+			return false
+		}
+		days := int(day.Sub(start).Hours() / 24)
+		return days%r.IntervalDays == 0
+	default:
+		return false
+	}
+}
+
+// RecurringOrder 週期性預約（例如醫院、學校每個平日固定接送），背景排程依規則提前產生預約單
+type RecurringOrder struct {
+	ID              *primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty" doc:"週期性預約ID"`
+	Name            string               `json:"name" bson:"name" example:"台大醫院 平日洗腎接送" doc:"名稱"`
+	OriText         string               `json:"ori_text" bson:"ori_text" example:"A1/台大醫院 洗腎接送" doc:"訂單文字（客群/地址 備註），不需包含時間"`
+	CustomerGroup   string               `json:"customer_group" bson:"customer_group" example:"A1" doc:"客群（由訂單文字解析）"`
+	Fleet           FleetType            `json:"fleet,omitempty" bson:"fleet,omitempty" example:"RSK" doc:"指定車隊，空白時依客群決定"`
+	Rule            RecurrenceRule       `json:"rule" bson:"rule" doc:"週期規則"`
+	Status          RecurringOrderStatus `json:"status" bson:"status" example:"active" doc:"狀態(active/cancelled)"`
+	CancelReason    string               `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty" doc:"取消原因"`
+	CancelledBy     string               `json:"cancelled_by,omitempty" bson:"cancelled_by,omitempty" doc:"取消者"`
+	CancelledAt     *time.Time           `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty" doc:"取消時間"`
+	LastGeneratedAt *time.Time           `json:"last_generated_at,omitempty" bson:"last_generated_at,omitempty" doc:"最後一次產生預約單的時間"`
+	CreatedBy       string               `json:"created_by,omitempty" bson:"created_by,omitempty" doc:"建立者"`
+	UpdatedBy       string               `json:"updated_by,omitempty" bson:"updated_by,omitempty" doc:"最後修改者"`
+	CreatedAt       *time.Time           `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt       *time.Time           `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// RecurringOccurrence 週期性預約的單次預約，尚未產生訂單時 OrderID 為空
+type RecurringOccurrence struct {
+	Date        string      `json:"date" bson:"date" example:"2025-10-06" doc:"日期"`
+	ScheduledAt time.Time   `json:"scheduled_at" bson:"scheduled_at" doc:"預約時間"`
+	Skipped     bool        `json:"skipped" bson:"skipped" doc:"是否已略過（列於例外日期）"`
+	OrderID     string      `json:"order_id,omitempty" bson:"order_id,omitempty" doc:"已產生的訂單ID"`
+	ShortID     string      `json:"short_id,omitempty" bson:"short_id,omitempty" doc:"已產生的訂單短ID"`
+	OrderStatus OrderStatus `json:"order_status,omitempty" bson:"order_status,omitempty" doc:"已產生的訂單狀態"`
+}
@@ -74,3 +74,47 @@ func getDefaultTagColor(role UserRole) string {
 		return "#722ED1" // 紫色
 	}
 }
+
+// UserAccess 用戶實際生效的權限與車隊檢視範圍，依用戶角色在 roles 集合的設定解析
+type UserAccess struct {
+	Role        UserRole     `json:"role" example:"管理員" doc:"角色"`
+	Permissions []Permission `json:"permissions" doc:"生效的權限列表"`
+	FleetAccess FleetAccess  `json:"fleet_access" example:"自家" doc:"可檢視的車隊"`
+	Fleet       FleetType    `json:"fleet" example:"RSK" doc:"所屬車隊，可檢視的車隊為自家時只能查詢此車隊"`
+}
+
+// HasAnyPermission 是否具備任一權限；系統管理員不受權限限制，未指定權限時只需登入
+func (a *UserAccess) HasAnyPermission(permissions ...Permission) bool {
+	if a.Role == RoleSystemAdmin || len(permissions) == 0 {
+		return true
+	}
+	for _, required := range permissions {
+		for _, granted := range a.Permissions {
+			if granted == required {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasAnyRole 是否為任一指定角色；系統管理員不受角色限制，未指定角色時不限角色
+func (a *UserAccess) HasAnyRole(roles ...UserRole) bool {
+	if a.Role == RoleSystemAdmin || len(roles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if a.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopedFleet 可檢視所有車隊時回傳 false；只能檢視自家車隊時回傳所屬車隊
+func (a *UserAccess) ScopedFleet() (FleetType, bool) {
+	if a.Role == RoleSystemAdmin || a.FleetAccess == FleetAccessAll {
+		return "", false
+	}
+	return a.Fleet, true
+}
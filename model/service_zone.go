@@ -0,0 +1,83 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ZoneRuleAction 服務區域規則動作
+type ZoneRuleAction string
+
+const (
+	ZoneRuleActionReject     ZoneRuleAction = "reject"      // 拒絕建立訂單（不服務區域）
+	ZoneRuleActionRequireTag ZoneRuleAction = "require_tag" // 只派給具備指定標籤的司機
+	ZoneRuleActionRadius     ZoneRuleAction = "radius"      // 改用不同的司機直線距離上限
+)
+
+// GeoPolygon GeoJSON 多邊形，座標為 [外環, 內環...]，每個環的點為 [經度, 緯度] 且首尾相同
+type GeoPolygon struct {
+	Type        string        `json:"type" bson:"type" example:"Polygon" doc:"GeoJSON 類型"`
+	Coordinates [][][]float64 `json:"coordinates" bson:"coordinates" doc:"多邊形座標 [[[經度, 緯度], ...]]"`
+}
+
+// ZoneRule 服務區域對車隊的規則，Fleet 為空表示套用所有車隊
+type ZoneRule struct {
+	Fleet               FleetType      `json:"fleet,omitempty" bson:"fleet,omitempty" example:"RSK" doc:"套用車隊，空白表示所有車隊"`
+	Action              ZoneRuleAction `json:"action" bson:"action" enum:"reject,require_tag,radius" example:"require_tag" doc:"規則動作：reject 拒絕建單、require_tag 要求司機標籤、radius 改用不同距離上限"`
+	DriverTag           string         `json:"driver_tag,omitempty" bson:"driver_tag,omitempty" example:"airport" doc:"require_tag 要求的司機標籤"`
+	MaxDriverDistanceKm float64        `json:"max_driver_distance_km,omitempty" bson:"max_driver_distance_km,omitempty" example:"3" doc:"radius 的司機直線距離上限（公里）"`
+	Message             string         `json:"message,omitempty" bson:"message,omitempty" example:"此區域暫不提供服務" doc:"reject 時回覆給建單者的訊息"`
+}
+
+// AppliesTo 規則是否套用於指定車隊
+func (r ZoneRule) AppliesTo(fleet FleetType) bool {
+	return r.Fleet == "" || r.Fleet == fleet
+}
+
+// ServiceZone 管理員維護的服務區域（存放於 service_zones 集合，area 建有 2dsphere 索引）
+type ServiceZone struct {
+	ID          *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"區域ID"`
+	Name        string              `json:"name" bson:"name" example:"桃園機場" doc:"區域名稱"`
+	Description string              `json:"description,omitempty" bson:"description,omitempty" doc:"說明"`
+	Area        GeoPolygon          `json:"area" bson:"area" doc:"區域範圍"`
+	Rules       []ZoneRule          `json:"rules" bson:"rules" doc:"各車隊規則"`
+	Enabled     bool                `json:"enabled" bson:"enabled" example:"true" doc:"是否啟用"`
+	UpdatedBy   string              `json:"updated_by,omitempty" bson:"updated_by,omitempty" doc:"最後修改者"`
+	CreatedAt   *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt   *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// ZoneRestriction 上車點所在服務區域對某車隊訂單的彙總限制
+type ZoneRestriction struct {
+	Zones               []string // 上車點所在的啟用區域名稱
+	RejectZone          string   // 拒絕建單的區域名稱，空白表示不拒絕
+	RejectMessage       string
+	RequiredDriverTags  []string // 司機必須具備的所有標籤
+	MaxDriverDistanceKm float64  // 區域指定的司機直線距離上限，0 表示沿用車隊設定（多個區域取最小值）
+}
+
+// IsRejected 是否拒絕此訂單
+func (r *ZoneRestriction) IsRejected() bool {
+	return r != nil && r.RejectZone != ""
+}
+
+// MissingDriverTag 回傳司機缺少的第一個必要標籤，具備所有標籤時回傳空字串
+func (r *ZoneRestriction) MissingDriverTag(driver *DriverInfo) string {
+	if r == nil {
+		return ""
+	}
+	for _, required := range r.RequiredDriverTags {
+		found := false
+		for _, tag := range driver.Tags {
+			if tag == required {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return required
+		}
+	}
+	return ""
+}
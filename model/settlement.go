@@ -0,0 +1,99 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SettlementStatus 司機結算單狀態
+type SettlementStatus string
+
+const (
+	SettlementStatusDraft     SettlementStatus = "draft"     // 草稿，可重新產生或刪除
+	SettlementStatusConfirmed SettlementStatus = "confirmed" // 已確認，金額鎖定等待撥款
+	SettlementStatusPaid      SettlementStatus = "paid"      // 已撥款
+)
+
+// Settlement 司機期間結算單（存放於 settlements 集合）
+type Settlement struct {
+	ID                 *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"結算單ID"`
+	DriverID           string              `json:"driver_id" bson:"driver_id" doc:"司機ID"`
+	DriverName         string              `json:"driver_name" bson:"driver_name" example:"王小明" doc:"司機姓名"`
+	DriverNo           string              `json:"driver_no,omitempty" bson:"driver_no,omitempty" example:"D001" doc:"司機編號"`
+	CarPlate           string              `json:"car_plate,omitempty" bson:"car_plate,omitempty" example:"6793黑" doc:"車牌號碼"`
+	JkoAccount         string              `json:"jko_account,omitempty" bson:"jko_account,omitempty" example:"jko001" doc:"司機街口帳號（撥款帳號）"`
+	Fleet              FleetType           `json:"fleet" bson:"fleet" example:"RSK" doc:"司機所屬車隊（抽成規則依此車隊）"`
+	PeriodStart        time.Time           `json:"period_start" bson:"period_start" doc:"結算期間開始（含）"`
+	PeriodEnd          time.Time           `json:"period_end" bson:"period_end" doc:"結算期間結束（不含）"`
+	Status             SettlementStatus    `json:"status" bson:"status" example:"draft" doc:"狀態：draft 草稿、confirmed 已確認、paid 已撥款"`
+	CommissionPercent  float64             `json:"commission_percent" bson:"commission_percent" example:"10" doc:"產生時套用的抽成百分比"`
+	CommissionPerOrder int                 `json:"commission_per_order" bson:"commission_per_order" example:"0" doc:"產生時套用的每筆固定抽成（元）"`
+	Lines              []SettlementLine    `json:"lines" bson:"lines" doc:"訂單明細"`
+	OrderCount         int                 `json:"order_count" bson:"order_count" example:"42" doc:"訂單數"`
+	GrossFare          int                 `json:"gross_fare" bson:"gross_fare" example:"12600" doc:"車資合計（元）"`
+	Commission         int                 `json:"commission" bson:"commission" example:"1260" doc:"抽成合計（元）"`
+	Payout             int                 `json:"payout" bson:"payout" example:"11340" doc:"應撥款金額（元），負數表示司機應補繳"`
+	PaymentRef         string              `json:"payment_ref,omitempty" bson:"payment_ref,omitempty" doc:"撥款參考編號"`
+	CreatedBy          string              `json:"created_by,omitempty" bson:"created_by,omitempty" doc:"產生者"`
+	ConfirmedBy        string              `json:"confirmed_by,omitempty" bson:"confirmed_by,omitempty" doc:"確認者"`
+	ConfirmedAt        *time.Time          `json:"confirmed_at,omitempty" bson:"confirmed_at,omitempty" doc:"確認時間"`
+	PaidBy             string              `json:"paid_by,omitempty" bson:"paid_by,omitempty" doc:"撥款登記者"`
+	PaidAt             *time.Time          `json:"paid_at,omitempty" bson:"paid_at,omitempty" doc:"撥款時間"`
+	CreatedAt          *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt          *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// SettlementLine 結算單訂單明細
+type SettlementLine struct {
+	OrderID        string          `json:"order_id" bson:"order_id" doc:"訂單ID"`
+	ShortID        string          `json:"short_id" bson:"short_id" example:"#9011" doc:"訂單短ID"`
+	OrderFleet     FleetType       `json:"order_fleet" bson:"order_fleet" example:"KD" doc:"訂單車隊"`
+	CustomerGroup  string          `json:"customer_group" bson:"customer_group" doc:"客群"`
+	PickupAddress  string          `json:"pickup_address,omitempty" bson:"pickup_address,omitempty" doc:"上車地點"`
+	CompletionTime time.Time       `json:"completion_time" bson:"completion_time" doc:"完成時間"`
+	Fare           int             `json:"fare" bson:"fare" example:"300" doc:"車資（元）"`
+	FareSource     OrderFareSource `json:"fare_source" bson:"fare_source" example:"final_fare" doc:"車資來源"`
+	Commission     int             `json:"commission" bson:"commission" example:"30" doc:"抽成（元）"`
+	Payout         int             `json:"payout" bson:"payout" example:"270" doc:"司機實得（元）"`
+}
+
+// SettlementAuditAction 結算單稽核動作
+type SettlementAuditAction string
+
+const (
+	SettlementAuditGenerated   SettlementAuditAction = "generated"   // 產生結算單
+	SettlementAuditRegenerated SettlementAuditAction = "regenerated" // 重新產生草稿
+	SettlementAuditConfirmed   SettlementAuditAction = "confirmed"   // 確認
+	SettlementAuditPaid        SettlementAuditAction = "paid"        // 登記撥款
+	SettlementAuditDeleted     SettlementAuditAction = "deleted"     // 刪除草稿
+	SettlementAuditExported    SettlementAuditAction = "exported"    // 下載 XLSX
+)
+
+// SettlementAuditEntry 結算單稽核紀錄（存放於 settlement_audit_logs 集合，只新增不修改）
+type SettlementAuditEntry struct {
+	ID           *primitive.ObjectID   `json:"_id,omitempty" bson:"_id,omitempty" doc:"紀錄ID"`
+	SettlementID string                `json:"settlement_id" bson:"settlement_id" doc:"結算單ID"`
+	DriverID     string                `json:"driver_id" bson:"driver_id" doc:"司機ID"`
+	Action       SettlementAuditAction `json:"action" bson:"action" example:"confirmed" doc:"動作"`
+	FromStatus   SettlementStatus      `json:"from_status,omitempty" bson:"from_status,omitempty" doc:"變更前狀態"`
+	ToStatus     SettlementStatus      `json:"to_status,omitempty" bson:"to_status,omitempty" doc:"變更後狀態"`
+	Payout       int                   `json:"payout" bson:"payout" doc:"當時的應撥款金額（元）"`
+	Actor        string                `json:"actor" bson:"actor" doc:"操作者"`
+	Details      string                `json:"details,omitempty" bson:"details,omitempty" doc:"說明"`
+	CreatedAt    time.Time             `json:"created_at" bson:"created_at" doc:"紀錄時間"`
+}
+
+// Recalculate 依訂單明細重新計算結算單合計
+func (s *Settlement) Recalculate() {
+	s.OrderCount = len(s.Lines)
+	s.GrossFare, s.Commission, s.Payout = 0, 0, 0
+	for _, line := range s.Lines {
+		s.GrossFare += line.Fare
+		s.Commission += line.Commission
+		s.Payout += line.Payout
+	}
+	if s.Lines == nil {
+		s.Lines = []SettlementLine{}
+	}
+}
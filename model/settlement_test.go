@@ -0,0 +1,55 @@
+package model
+
+import "testing"
+
+func TestFleetSettingsCommission(t *testing.T) {
+	testCases := []struct {
+		name     string
+		settings FleetSettings
+		fare     int
+		want     int
+	}{
+		{name: "百分比抽成", settings: FleetSettings{CommissionPercent: 10}, fare: 300, want: 30},
+		{name: "百分比抽成四捨五入", settings: FleetSettings{CommissionPercent: 10}, fare: 245, want: 25},
+		{name: "百分比抽成捨去", settings: FleetSettings{CommissionPercent: 10}, fare: 244, want: 24},
+		{name: "小數百分比", settings: FleetSettings{CommissionPercent: 12.5}, fare: 333, want: 42},
+		{name: "每筆固定抽成", settings: FleetSettings{CommissionPerOrder: 20}, fare: 300, want: 20},
+		{name: "百分比加每筆固定抽成", settings: FleetSettings{CommissionPercent: 10, CommissionPerOrder: 20}, fare: 305, want: 51},
+		{name: "未設定抽成", settings: FleetSettings{}, fare: 300, want: 0},
+		{name: "車資為 0 不抽成", settings: FleetSettings{CommissionPercent: 10, CommissionPerOrder: 20}, fare: 0, want: 0},
+		{name: "抽成不超過車資", settings: FleetSettings{CommissionPercent: 10, CommissionPerOrder: 50}, fare: 40, want: 40},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.settings.Commission(tc.fare); got != tc.want {
+				t.Fatalf("車資 %d 預期抽成 %d，實際為 %d", tc.fare, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSettlementRecalculate(t *testing.T) {
+	settlement := &Settlement{
+		Lines: []SettlementLine{
+			{OrderID: "a", Fare: 300, FareSource: OrderFareFinal, Commission: 30, Payout: 270},
+			{OrderID: "b", Fare: 150, FareSource: OrderFareCancel, Commission: 15, Payout: 135},
+			{OrderID: "c", FareSource: OrderFareNone},
+		},
+		OrderCount: 99,
+		GrossFare:  99,
+		Commission: 99,
+		Payout:     99,
+	}
+
+	settlement.Recalculate()
+	if settlement.OrderCount != 3 || settlement.GrossFare != 450 || settlement.Commission != 45 || settlement.Payout != 405 {
+		t.Fatalf("合計不符: 筆數 %d 車資 %d 抽成 %d 實得 %d", settlement.OrderCount, settlement.GrossFare, settlement.Commission, settlement.Payout)
+	}
+
+	empty := &Settlement{GrossFare: 100}
+	empty.Recalculate()
+	if empty.Lines == nil || empty.OrderCount != 0 || empty.GrossFare != 0 {
+		t.Fatalf("沒有明細時預期合計歸零且明細為空陣列，實際為 %+v", empty)
+	}
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tariff 車隊與客群的計費規則（存放於 tariffs 集合），客群空白表示車隊預設費率
+type Tariff struct {
+	ID                  *primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" doc:"費率ID"`
+	Fleet               FleetType           `json:"fleet" bson:"fleet" example:"RSK" doc:"車隊"`
+	CustomerGroup       string              `json:"customer_group" bson:"customer_group" example:"R1" doc:"客群，空白表示車隊預設費率"`
+	Name                string              `json:"name" bson:"name" example:"RSK 一般費率" doc:"費率名稱"`
+	FlagFall            int                 `json:"flag_fall" bson:"flag_fall" example:"85" doc:"起跳價（元）"`
+	FlagFallKm          float64             `json:"flag_fall_km" bson:"flag_fall_km" example:"1.25" doc:"起跳價包含的公里數"`
+	PerKm               float64             `json:"per_km" bson:"per_km" example:"20" doc:"超過起跳公里數後每公里費用（元）"`
+	PerMinute           float64             `json:"per_minute" bson:"per_minute" example:"3" doc:"每分鐘行車時間費用（元）"`
+	MinimumFare         int                 `json:"minimum_fare" bson:"minimum_fare" example:"100" doc:"最低車資（元），0 表示不限"`
+	NightSurcharge      NightSurcharge      `json:"night_surcharge" bson:"night_surcharge" doc:"夜間加成"`
+	ZoneSurcharges      []ZoneSurcharge     `json:"zone_surcharges,omitempty" bson:"zone_surcharges,omitempty" doc:"服務區域加價（例如機場），上車點或目的地位於該區域時加收"`
+	PetSurcharge        int                 `json:"pet_surcharge" bson:"pet_surcharge" example:"50" doc:"攜帶寵物加價（元）"`
+	OverloadedSurcharge int                 `json:"overloaded_surcharge" bson:"overloaded_surcharge" example:"100" doc:"超過四人加價（元）"`
+	Enabled             bool                `json:"enabled" bson:"enabled" example:"true" doc:"是否啟用"`
+	UpdatedBy           string              `json:"updated_by,omitempty" bson:"updated_by,omitempty" doc:"最後修改者"`
+	CreatedAt           *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty" doc:"建立時間"`
+	UpdatedAt           *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty" doc:"更新時間"`
+}
+
+// NightSurcharge 夜間加成，時段可跨午夜（例如 23 點到 6 點），依台北時間判斷
+type NightSurcharge struct {
+	StartHour int     `json:"start_hour" bson:"start_hour" minimum:"0" maximum:"23" example:"23" doc:"開始時間（時）"`
+	EndHour   int     `json:"end_hour" bson:"end_hour" minimum:"0" maximum:"23" example:"6" doc:"結束時間（時，不含）"`
+	Percent   float64 `json:"percent" bson:"percent" example:"20" doc:"跳表金額加成百分比"`
+	Amount    int     `json:"amount" bson:"amount" example:"0" doc:"固定加價（元）"`
+}
+
+// ZoneSurcharge 服務區域加價
+type ZoneSurcharge struct {
+	Zone   string `json:"zone" bson:"zone" example:"桃園機場" doc:"服務區域名稱"`
+	Amount int    `json:"amount" bson:"amount" example:"100" doc:"加價（元）"`
+}
+
+// FareItemCode 車資明細項目
+type FareItemCode string
+
+const (
+	FareItemFlagFall   FareItemCode = "flag_fall"   // 起跳價
+	FareItemDistance   FareItemCode = "distance"    // 里程
+	FareItemTime       FareItemCode = "time"        // 行車時間
+	FareItemNight      FareItemCode = "night"       // 夜間加成
+	FareItemZone       FareItemCode = "zone"        // 區域加價
+	FareItemPet        FareItemCode = "pet"         // 寵物加價
+	FareItemOverloaded FareItemCode = "overloaded"  // 超載加價
+	FareItemMinimum    FareItemCode = "minimum_fee" // 最低車資補差
+)
+
+// FareDistanceSource 計費里程來源
+type FareDistanceSource string
+
+const (
+	FareDistanceEstimate FareDistanceSource = "estimate" // 建單時預估的上車點到目的地距離
+	FareDistanceTrack    FareDistanceSource = "track"    // 司機位置軌跡累計距離
+	FareDistanceNone     FareDistanceSource = "none"     // 無里程資料，只計起跳價與加價
+)
+
+// FareItem 車資明細
+type FareItem struct {
+	Code   FareItemCode `json:"code" bson:"code" example:"distance" doc:"項目代碼"`
+	Name   string       `json:"name" bson:"name" example:"里程" doc:"項目名稱"`
+	Amount int          `json:"amount" bson:"amount" example:"120" doc:"金額（元）"`
+}
+
+// FareBreakdown 車資計算結果（預估或實收）
+type FareBreakdown struct {
+	TariffID       *primitive.ObjectID `json:"tariff_id,omitempty" bson:"tariff_id,omitempty" doc:"使用的費率ID"`
+	TariffName     string              `json:"tariff_name,omitempty" bson:"tariff_name,omitempty" doc:"使用的費率名稱"`
+	DistanceKm     float64             `json:"distance_km" bson:"distance_km" example:"8.3" doc:"計費里程（公里）"`
+	DurationMins   float64             `json:"duration_mins" bson:"duration_mins" example:"22" doc:"計費時間（分鐘）"`
+	DistanceSource FareDistanceSource  `json:"distance_source" bson:"distance_source" example:"track" doc:"計費里程來源"`
+	Items          []FareItem          `json:"items" bson:"items" doc:"車資明細"`
+	Total          int                 `json:"total" bson:"total" example:"320" doc:"車資合計（元）"`
+	CalculatedAt   time.Time           `json:"calculated_at" bson:"calculated_at" doc:"計算時間"`
+}
+
+// FareInput 車資計算條件
+type FareInput struct {
+	DistanceKm     float64
+	DurationMins   float64
+	DistanceSource FareDistanceSource
+	At             time.Time // 計算夜間加成的時間（上車時間或建單時間）
+	Zones          []string  // 上車點與目的地所在的服務區域名稱
+	HasPets        bool
+	HasOverloaded  bool
+}
+
+// IsNight 指定時間是否位於夜間加成時段
+func (n NightSurcharge) IsNight(t time.Time) bool {
+	if n.Percent <= 0 && n.Amount <= 0 {
+		return false
+	}
+	hour := t.In(time.FixedZone("Asia/Taipei", 8*3600)).Hour()
+	if n.StartHour == n.EndHour {
+		return false
+	}
+	if n.StartHour < n.EndHour {
+		return hour >= n.StartHour && hour < n.EndHour
+	}
+	return hour >= n.StartHour || hour < n.EndHour
+}
+
+// Calculate 依費率計算車資：起跳價 + 超過起跳公里數的里程 + 行車時間，夜間加成以跳表金額計算，
+// 再加上區域、寵物、超載加價，合計低於最低車資時補差
+func (t *Tariff) Calculate(input FareInput) *FareBreakdown {
+	fare := &FareBreakdown{
+		TariffID:       t.ID,
+		TariffName:     t.Name,
+		DistanceKm:     math.Round(input.DistanceKm*10) / 10,
+		DurationMins:   math.Round(input.DurationMins),
+		DistanceSource: input.DistanceSource,
+		CalculatedAt:   time.Now(),
+	}
+	add := func(code FareItemCode, name string, amount int) {
+		if amount > 0 {
+			fare.Items = append(fare.Items, FareItem{Code: code, Name: name, Amount: amount})
+			fare.Total += amount
+		}
+	}
+
+	add(FareItemFlagFall, "起跳價", t.FlagFall)
+	if extraKm := input.DistanceKm - t.FlagFallKm; extraKm > 0 {
+		add(FareItemDistance, "里程", int(math.Round(extraKm*t.PerKm)))
+	}
+	add(FareItemTime, "行車時間", int(math.Round(input.DurationMins*t.PerMinute)))
+
+	if t.NightSurcharge.IsNight(input.At) {
+		meter := fare.Total
+		add(FareItemNight, "夜間加成", int(math.Round(float64(meter)*t.NightSurcharge.Percent/100))+t.NightSurcharge.Amount)
+	}
+
+	charged := make(map[string]bool)
+	for _, zone := range input.Zones {
+		for _, surcharge := range t.ZoneSurcharges {
+			if surcharge.Zone == zone && !charged[zone] {
+				charged[zone] = true
+				add(FareItemZone, zone+"加價", surcharge.Amount)
+			}
+		}
+	}
+
+	if input.HasPets {
+		add(FareItemPet, "寵物加價", t.PetSurcharge)
+	}
+	if input.HasOverloaded {
+		add(FareItemOverloaded, "超載加價", t.OverloadedSurcharge)
+	}
+
+	if fare.Total < t.MinimumFare {
+		add(FareItemMinimum, "最低車資補差", t.MinimumFare-fare.Total)
+	}
+	if fare.Items == nil {
+		fare.Items = []FareItem{}
+	}
+	return fare
+}
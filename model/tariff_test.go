@@ -0,0 +1,116 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+var taipei = time.FixedZone("Asia/Taipei", 8*3600)
+
+func testTariff() *Tariff {
+	return &Tariff{
+		Name:                "RSK 一般費率",
+		FlagFall:            85,
+		FlagFallKm:          1.25,
+		PerKm:               20,
+		PerMinute:           3,
+		MinimumFare:         100,
+		NightSurcharge:      NightSurcharge{StartHour: 23, EndHour: 6, Percent: 20},
+		ZoneSurcharges:      []ZoneSurcharge{{Zone: "桃園機場", Amount: 100}},
+		PetSurcharge:        50,
+		OverloadedSurcharge: 100,
+	}
+}
+
+func fareItemAmount(fare *FareBreakdown, code FareItemCode) int {
+	for _, item := range fare.Items {
+		if item.Code == code {
+			return item.Amount
+		}
+	}
+	return 0
+}
+
+func TestTariffCalculate(t *testing.T) {
+	day := time.Date(2025, 8, 1, 10, 0, 0, 0, taipei)
+	night := time.Date(2025, 8, 1, 0, 30, 0, 0, taipei)
+
+	testCases := []struct {
+		name      string
+		input     FareInput
+		wantTotal int
+		wantItems map[FareItemCode]int
+	}{
+		{
+			name:      "日間跳表",
+			input:     FareInput{DistanceKm: 10, DurationMins: 20, At: day},
+			wantTotal: 320,
+			wantItems: map[FareItemCode]int{FareItemFlagFall: 85, FareItemDistance: 175, FareItemTime: 60},
+		},
+		{
+			name:      "未超過起跳公里數不計里程並補足最低車資",
+			input:     FareInput{DistanceKm: 0.5, DurationMins: 1, At: day},
+			wantTotal: 100,
+			wantItems: map[FareItemCode]int{FareItemFlagFall: 85, FareItemDistance: 0, FareItemTime: 3, FareItemMinimum: 12},
+		},
+		{
+			name:      "跨午夜夜間加成以跳表金額計算",
+			input:     FareInput{DistanceKm: 10, DurationMins: 20, At: night},
+			wantTotal: 384,
+			wantItems: map[FareItemCode]int{FareItemNight: 64},
+		},
+		{
+			name:      "區域加價同一區域只收一次並加收寵物與超載",
+			input:     FareInput{DistanceKm: 10, DurationMins: 20, At: day, Zones: []string{"桃園機場", "桃園機場", "台北市"}, HasPets: true, HasOverloaded: true},
+			wantTotal: 570,
+			wantItems: map[FareItemCode]int{FareItemZone: 100, FareItemPet: 50, FareItemOverloaded: 100},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fare := testTariff().Calculate(tc.input)
+			if fare.Total != tc.wantTotal {
+				t.Fatalf("預期車資 %d，實際為 %d（明細 %+v）", tc.wantTotal, fare.Total, fare.Items)
+			}
+			for code, want := range tc.wantItems {
+				if got := fareItemAmount(fare, code); got != want {
+					t.Fatalf("預期 %s 金額 %d，實際為 %d", code, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTariffCalculateWithoutCharges(t *testing.T) {
+	fare := (&Tariff{}).Calculate(FareInput{DistanceSource: FareDistanceNone})
+	if fare.Total != 0 || fare.Items == nil || len(fare.Items) != 0 {
+		t.Fatalf("預期沒有任何車資項目，實際為 %+v", fare)
+	}
+}
+
+func TestNightSurchargeIsNight(t *testing.T) {
+	testCases := []struct {
+		name      string
+		surcharge NightSurcharge
+		hour      int
+		want      bool
+	}{
+		{name: "跨午夜時段內", surcharge: NightSurcharge{StartHour: 23, EndHour: 6, Percent: 20}, hour: 2, want: true},
+		{name: "跨午夜開始時間", surcharge: NightSurcharge{StartHour: 23, EndHour: 6, Percent: 20}, hour: 23, want: true},
+		{name: "跨午夜結束時間不含", surcharge: NightSurcharge{StartHour: 23, EndHour: 6, Percent: 20}, hour: 6, want: false},
+		{name: "同日時段內", surcharge: NightSurcharge{StartHour: 20, EndHour: 23, Amount: 50}, hour: 21, want: true},
+		{name: "同日時段外", surcharge: NightSurcharge{StartHour: 20, EndHour: 23, Amount: 50}, hour: 23, want: false},
+		{name: "未設定加成", surcharge: NightSurcharge{StartHour: 23, EndHour: 6}, hour: 2, want: false},
+		{name: "開始與結束相同", surcharge: NightSurcharge{StartHour: 6, EndHour: 6, Percent: 20}, hour: 6, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			at := time.Date(2025, 8, 1, tc.hour, 0, 0, 0, taipei)
+			if got := tc.surcharge.IsNight(at); got != tc.want {
+				t.Fatalf("預期 %v，實際為 %v", tc.want, got)
+			}
+		})
+	}
+}
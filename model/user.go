@@ -43,7 +43,7 @@ type User struct {
 	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty" example:"684a73ad0e3a583c37e4b30d" doc:"用戶ID"`
 	Name        string             `json:"name" bson:"name" example:"Admin" doc:"姓名"`
 	Account     string             `json:"account" bson:"account" example:"admin@taxi.com" doc:"帳號"`
-	Password    string             `json:"password" bson:"password" example:"admin123" doc:"密碼"`
+	Password    string             `json:"-" bson:"password"` // bcrypt 雜湊，不輸出於 API 回應
 	Role        UserRole           `json:"role" bson:"role" example:"系統管理員" doc:"角色"`
 	Fleet       FleetType          `json:"fleet" bson:"fleet" example:"RSK" doc:"所屬車隊"`
 	Permissions []Permission       `json:"permissions" bson:"permissions" doc:"權限列表"`
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// WaypointStatus 停靠點狀態
+type WaypointStatus string
+
+const (
+	WaypointStatusPending   WaypointStatus = "pending"   // 尚未抵達
+	WaypointStatusArrived   WaypointStatus = "arrived"   // 已抵達停靠點
+	WaypointStatusCompleted WaypointStatus = "completed" // 停靠點已完成（乘客下車/上車完畢）
+)
+
+// Waypoint 多點行程的停靠點，依 Seq 順序行駛，最後一站即為目的地
+type Waypoint struct {
+	Seq               int            `json:"seq" bson:"seq" example:"1" doc:"停靠順序(從1開始)"`
+	InputAddress      string         `json:"input_address" bson:"input_address" doc:"客戶輸入的停靠點"`
+	Address           string         `json:"address,omitempty" bson:"address,omitempty" doc:"實際停靠點(經 Google 解析)"`
+	Lat               *string        `json:"lat,omitempty" bson:"lat,omitempty" doc:"停靠點緯度"`
+	Lng               *string        `json:"lng,omitempty" bson:"lng,omitempty" doc:"停靠點經度"`
+	IsReturn          bool           `json:"is_return,omitempty" bson:"is_return,omitempty" doc:"是否為來回行程的回程（返回上車點）"`
+	Status            WaypointStatus `json:"status" bson:"status" example:"pending" doc:"停靠點狀態(pending/arrived/completed)"`
+	EstMinsFromPrev   *int           `json:"est_mins_from_prev,omitempty" bson:"est_mins_from_prev,omitempty" doc:"預估從上一站行駛時間(分鐘)"`
+	EstDistKmFromPrev *float64       `json:"est_dist_km_from_prev,omitempty" bson:"est_dist_km_from_prev,omitempty" doc:"預估從上一站行駛距離(公里)"`
+	EstArrivalAt      *time.Time     `json:"est_arrival_at,omitempty" bson:"est_arrival_at,omitempty" doc:"預估抵達時間（客人上車後依各段行駛時間推算）"`
+	ArrivedAt         *time.Time     `json:"arrived_at,omitempty" bson:"arrived_at,omitempty" doc:"抵達時間"`
+	CompletedAt       *time.Time     `json:"completed_at,omitempty" bson:"completed_at,omitempty" doc:"完成時間"`
+}
+
+// HasLocation 停靠點是否已解析出座標
+func (w *Waypoint) HasLocation() bool {
+	return w.Lat != nil && w.Lng != nil
+}
+
+// DisplayAddress 停靠點顯示用地址，優先使用 Google 解析後的地址
+func (w *Waypoint) DisplayAddress() string {
+	if w.Address != "" {
+		return w.Address
+	}
+	return w.InputAddress
+}
+
+// CurrentWaypoint 目前要前往（或已抵達但尚未完成）的停靠點，全部完成或無停靠點時回傳 nil
+func (o *Order) CurrentWaypoint() *Waypoint {
+	for i := range o.Waypoints {
+		if o.Waypoints[i].Status != WaypointStatusCompleted {
+			return &o.Waypoints[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,523 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	authSessionsCollection = "auth_sessions"
+
+	// defaultAccessTokenMinutes 未設定時 access token 的有效分鐘數
+	defaultAccessTokenMinutes = 15
+	// defaultSessionHours 未設定時 refresh token（登入 session）的有效小時數
+	defaultSessionHours = 30 * 24
+	// refreshReuseGrace 輪替後短時間內重送舊 refresh token 視為客戶端重試，不撤銷 session
+	refreshReuseGrace = 30 * time.Second
+
+	revokedSessionKeyPrefix = "auth:revoked_session:"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("無效的 refresh token")
+	ErrSessionRevoked      = errors.New("登入已失效，請重新登入")
+	ErrSessionNotFound     = errors.New("找不到登入 session")
+	ErrMissingSessionID    = errors.New("token 缺少 session，請重新登入")
+)
+
+// AuthSessionService 登入 session 管理：簽發短效 access token 與可輪替的 refresh token，
+// 撤銷的 session 記錄於 Redis，供 API 與 WebSocket 驗證 access token 時檢查
+type AuthSessionService struct {
+	logger       zerolog.Logger
+	mongoDB      *infra.MongoDB
+	redisClient  *redis.Client
+	eventManager *infra.RedisEventManager
+	jwtSecretKey string
+}
+
+func NewAuthSessionService(logger zerolog.Logger, mongoDB *infra.MongoDB, redisClient *redis.Client, jwtSecretKey string) *AuthSessionService {
+	return &AuthSessionService{
+		logger:       logger.With().Str("module", "auth_session_service").Logger(),
+		mongoDB:      mongoDB,
+		redisClient:  redisClient,
+		jwtSecretKey: jwtSecretKey,
+	}
+}
+
+// SetEventManager 設定事件管理器，撤銷 session 時通知所有實例關閉對應的 WebSocket 連線
+func (s *AuthSessionService) SetEventManager(eventManager *infra.RedisEventManager) {
+	s.eventManager = eventManager
+}
+
+// AccessTokenTTL access token 有效時間
+func (s *AuthSessionService) AccessTokenTTL() time.Duration {
+	minutes := infra.AppConfig.JWT.AccessExpiresMinutes
+	if minutes <= 0 {
+		minutes = defaultAccessTokenMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// SessionTTL refresh token（登入 session）有效時間
+func (s *AuthSessionService) SessionTTL() time.Duration {
+	hours := infra.AppConfig.JWT.ExpiresHours
+	if hours <= 0 {
+		hours = defaultSessionHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// IssueUserSession 用戶登入成功後建立 session 並簽發 token
+func (s *AuthSessionService) IssueUserSession(ctx context.Context, user *model.User, device model.SessionDevice) (*model.AuthTokens, error) {
+	return s.issue(ctx, model.TokenTypeUser, user.ID, user.Account, userAccessClaims(user), device)
+}
+
+// IssueDriverSession 司機登入成功後建立 session 並簽發 token
+func (s *AuthSessionService) IssueDriverSession(ctx context.Context, driver *model.DriverInfo, device model.SessionDevice) (*model.AuthTokens, error) {
+	return s.issue(ctx, model.TokenTypeDriver, driver.ID, driver.Account, driverAccessClaims(driver), device)
+}
+
+func (s *AuthSessionService) issue(ctx context.Context, subjectType model.TokenType, subjectID primitive.ObjectID, account string, claims jwt.MapClaims, device model.SessionDevice) (*model.AuthTokens, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := &model.AuthSession{
+		ID:               primitive.NewObjectID(),
+		SubjectType:      subjectType,
+		SubjectID:        subjectID,
+		Account:          account,
+		Device:           device,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(s.SessionTTL()),
+	}
+	if _, err := s.mongoDB.GetCollection(authSessionsCollection).InsertOne(ctx, session); err != nil {
+		s.logger.Error().Err(err).Str("subject_type", string(subjectType)).Str("subject_id", subjectID.Hex()).Msg("建立登入 session 失敗")
+		return nil, fmt.Errorf("建立登入 session 失敗: %w", err)
+	}
+
+	return s.signTokens(session, claims, refreshToken, now)
+}
+
+// Refresh 以 refresh token 換發新的 access token，並輪替 refresh token；
+// 已輪替的舊 refresh token 再次被使用時視為外洩，撤銷整個 session
+func (s *AuthSessionService) Refresh(ctx context.Context, refreshToken string) (*model.AuthTokens, error) {
+	collection := s.mongoDB.GetCollection(authSessionsCollection)
+	tokenHash := hashRefreshToken(refreshToken)
+	now := time.Now().UTC()
+
+	var session model.AuthSession
+	err := collection.FindOne(ctx, bson.M{"refresh_token_hash": tokenHash}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, s.handleStaleRefreshToken(ctx, tokenHash, now)
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢登入 session 失敗")
+		return nil, err
+	}
+	if !session.IsActive(now) {
+		return nil, ErrSessionRevoked
+	}
+
+	claims, err := s.currentClaims(ctx, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	newToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	// 以目前的 refresh token 為條件更新，同一個 refresh token 只能成功換發一次
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": session.ID, "refresh_token_hash": tokenHash, "revoked_at": nil},
+		bson.M{"$set": bson.M{
+			"refresh_token_hash":          hashRefreshToken(newToken),
+			"previous_refresh_token_hash": tokenHash,
+			"last_refreshed_at":           now,
+		}},
+	)
+	if err != nil {
+		s.logger.Error().Err(err).Str("session_id", session.ID.Hex()).Msg("輪替 refresh token 失敗")
+		return nil, err
+	}
+	if result.ModifiedCount == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.signTokens(&session, claims, newToken, now)
+}
+
+// handleStaleRefreshToken 處理找不到的 refresh token：若為已輪替的舊 token 且超過重試寬限時間，撤銷該 session
+func (s *AuthSessionService) handleStaleRefreshToken(ctx context.Context, tokenHash string, now time.Time) error {
+	var session model.AuthSession
+	err := s.mongoDB.GetCollection(authSessionsCollection).FindOne(ctx, bson.M{"previous_refresh_token_hash": tokenHash}).Decode(&session)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error().Err(err).Msg("查詢已輪替的 refresh token 失敗")
+		}
+		return ErrInvalidRefreshToken
+	}
+	if !session.IsActive(now) {
+		return ErrSessionRevoked
+	}
+	if session.LastRefreshedAt != nil && now.Sub(*session.LastRefreshedAt) < refreshReuseGrace {
+		return ErrInvalidRefreshToken
+	}
+
+	s.logger.Warn().
+		Str("session_id", session.ID.Hex()).
+		Str("subject_type", string(session.SubjectType)).
+		Str("subject_id", session.SubjectID.Hex()).
+		Msg("已輪替的 refresh token 被重複使用，撤銷 session")
+	if _, err := s.revoke(ctx, bson.M{"_id": session.ID}, model.SessionRevokeRefreshReused); err != nil {
+		return err
+	}
+	return ErrSessionRevoked
+}
+
+// currentClaims 以最新的用戶或司機資料產生 access token claims，帳號已停用時撤銷 session
+func (s *AuthSessionService) currentClaims(ctx context.Context, session *model.AuthSession) (jwt.MapClaims, error) {
+	switch session.SubjectType {
+	case model.TokenTypeUser:
+		var user model.User
+		err := s.mongoDB.GetCollection("users").FindOne(ctx, bson.M{"_id": session.SubjectID}).Decode(&user)
+		if err == nil && user.IsActive {
+			return userAccessClaims(&user), nil
+		}
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	case model.TokenTypeDriver:
+		var driver model.DriverInfo
+		err := s.mongoDB.GetCollection("drivers").FindOne(ctx, bson.M{"_id": session.SubjectID}).Decode(&driver)
+		if err == nil && driver.IsActive && driver.IsApproved {
+			return driverAccessClaims(&driver), nil
+		}
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if _, err := s.revoke(ctx, bson.M{"_id": session.ID}, model.SessionRevokeAccountDisabled); err != nil {
+		return nil, err
+	}
+	return nil, ErrSessionRevoked
+}
+
+// Logout 撤銷 refresh token 所屬的 session
+func (s *AuthSessionService) Logout(ctx context.Context, refreshToken string) error {
+	var session model.AuthSession
+	err := s.mongoDB.GetCollection(authSessionsCollection).FindOne(ctx, bson.M{"refresh_token_hash": hashRefreshToken(refreshToken)}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.revoke(ctx, bson.M{"_id": session.ID}, model.SessionRevokeLogout)
+	return err
+}
+
+// CheckAccessClaims 檢查 access token 所屬的 session 是否已撤銷；Redis 無法使用時改查 MongoDB
+func (s *AuthSessionService) CheckAccessClaims(ctx context.Context, claims map[string]interface{}) error {
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		return ErrMissingSessionID
+	}
+
+	revoked, err := s.redisClient.Exists(ctx, revokedSessionKeyPrefix+sessionID).Result()
+	if err == nil {
+		if revoked > 0 {
+			return ErrSessionRevoked
+		}
+		return nil
+	}
+
+	s.logger.Warn().Err(err).Str("session_id", sessionID).Msg("Redis 查詢撤銷狀態失敗，改查 MongoDB")
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return ErrSessionRevoked
+	}
+	if session.RevokedAt != nil {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// GetSession 取得登入 session
+func (s *AuthSessionService) GetSession(ctx context.Context, sessionID string) (*model.AuthSession, error) {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	var session model.AuthSession
+	err = s.mongoDB.GetCollection(authSessionsCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveSessions 列出用戶或司機尚未撤銷且未到期的 session，最新登入在前
+func (s *AuthSessionService) ListActiveSessions(ctx context.Context, subjectType model.TokenType, subjectID string) ([]*model.AuthSession, error) {
+	objectID, err := primitive.ObjectIDFromHex(subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的ID: %w", err)
+	}
+
+	filter := bson.M{
+		"subject_type": subjectType,
+		"subject_id":   objectID,
+		"revoked_at":   nil,
+		"expires_at":   bson.M{"$gt": time.Now().UTC()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := s.mongoDB.GetCollection(authSessionsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Str("subject_id", subjectID).Msg("查詢登入 session 失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []*model.AuthSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession 撤銷單一 session
+func (s *AuthSessionService) RevokeSession(ctx context.Context, sessionID string, reason model.SessionRevokeReason) error {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	_, err = s.revoke(ctx, bson.M{"_id": objectID}, reason)
+	return err
+}
+
+// RevokeSubjectSessions 撤銷用戶或司機所有有效的 session（強制登出），回傳撤銷數量
+func (s *AuthSessionService) RevokeSubjectSessions(ctx context.Context, subjectType model.TokenType, subjectID string, reason model.SessionRevokeReason) (int, error) {
+	objectID, err := primitive.ObjectIDFromHex(subjectID)
+	if err != nil {
+		return 0, fmt.Errorf("無效的ID: %w", err)
+	}
+
+	count, err := s.revoke(ctx, bson.M{
+		"subject_type": subjectType,
+		"subject_id":   objectID,
+		"expires_at":   bson.M{"$gt": time.Now().UTC()},
+	}, reason)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	s.logger.Info().
+		Str("subject_type", string(subjectType)).
+		Str("subject_id", subjectID).
+		Str("reason", string(reason)).
+		Int("sessions", count).
+		Msg("已強制登出所有 session")
+	return count, nil
+}
+
+// RevokeSubjectSessionsExcept 撤銷用戶或司機除了 keepSessionID 以外的所有 session（單一設備登入），回傳撤銷數量
+func (s *AuthSessionService) RevokeSubjectSessionsExcept(ctx context.Context, subjectType model.TokenType, subjectID, keepSessionID string, reason model.SessionRevokeReason) (int, error) {
+	objectID, err := primitive.ObjectIDFromHex(subjectID)
+	if err != nil {
+		return 0, fmt.Errorf("無效的ID: %w", err)
+	}
+	keepID, err := primitive.ObjectIDFromHex(keepSessionID)
+	if err != nil {
+		return 0, ErrSessionNotFound
+	}
+
+	return s.revoke(ctx, bson.M{
+		"_id":          bson.M{"$ne": keepID},
+		"subject_type": subjectType,
+		"subject_id":   objectID,
+		"expires_at":   bson.M{"$gt": time.Now().UTC()},
+	}, reason)
+}
+
+// RevokeDeviceSessions 撤銷用戶或司機在指定設備上的 session，回傳撤銷數量
+func (s *AuthSessionService) RevokeDeviceSessions(ctx context.Context, subjectType model.TokenType, subjectID, deviceID string, reason model.SessionRevokeReason) (int, error) {
+	objectID, err := primitive.ObjectIDFromHex(subjectID)
+	if err != nil {
+		return 0, fmt.Errorf("無效的ID: %w", err)
+	}
+
+	return s.revoke(ctx, bson.M{
+		"subject_type":     subjectType,
+		"subject_id":       objectID,
+		"device.device_id": deviceID,
+		"expires_at":       bson.M{"$gt": time.Now().UTC()},
+	}, reason)
+}
+
+// revoke 將符合條件且尚未撤銷的 session 標記為撤銷，並寫入 Redis 撤銷清單直到已簽發的 access token 到期，回傳撤銷數量
+func (s *AuthSessionService) revoke(ctx context.Context, filter bson.M, reason model.SessionRevokeReason) (int, error) {
+	collection := s.mongoDB.GetCollection(authSessionsCollection)
+	filter["revoked_at"] = nil
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "subject_type": 1, "subject_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var sessions []model.AuthSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	now := time.Now().UTC()
+	if _, err := collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now, "revoked_reason": reason}},
+	); err != nil {
+		s.logger.Error().Err(err).Str("reason", string(reason)).Msg("撤銷登入 session 失敗")
+		return 0, fmt.Errorf("撤銷登入 session 失敗: %w", err)
+	}
+
+	pipe := s.redisClient.Pipeline()
+	for _, id := range ids {
+		pipe.Set(ctx, revokedSessionKeyPrefix+id.Hex(), string(reason), s.AccessTokenTTL())
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		// MongoDB 已標記撤銷，Redis 失敗時驗證會改查 MongoDB
+		s.logger.Error().Err(err).Str("reason", string(reason)).Msg("寫入 Redis 撤銷清單失敗")
+	}
+
+	// 通知所有實例關閉已撤銷 session 的 WebSocket 連線
+	if s.eventManager != nil {
+		for _, session := range sessions {
+			_ = s.eventManager.PublishSessionRevokedEvent(ctx, &infra.SessionRevokedEvent{
+				SessionID:   session.ID.Hex(),
+				SubjectType: string(session.SubjectType),
+				SubjectID:   session.SubjectID.Hex(),
+				Reason:      string(reason),
+				Timestamp:   now,
+			})
+		}
+	}
+	return len(ids), nil
+}
+
+// RestoreRevocations 由 MongoDB 重建 Redis 撤銷清單（服務啟動時 Redis 會被清空）
+func (s *AuthSessionService) RestoreRevocations(ctx context.Context) (int, error) {
+	ttl := s.AccessTokenTTL()
+	now := time.Now().UTC()
+	cursor, err := s.mongoDB.GetCollection(authSessionsCollection).Find(ctx, bson.M{
+		"revoked_at": bson.M{"$gt": now.Add(-ttl)},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var sessions []model.AuthSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+
+	pipe := s.redisClient.Pipeline()
+	for _, session := range sessions {
+		pipe.Set(ctx, revokedSessionKeyPrefix+session.ID.Hex(), string(session.RevokedReason), session.RevokedAt.Add(ttl).Sub(now))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return len(sessions), nil
+}
+
+// signTokens 簽發帶有 session ID 的 access token
+func (s *AuthSessionService) signTokens(session *model.AuthSession, claims jwt.MapClaims, refreshToken string, now time.Time) (*model.AuthTokens, error) {
+	expiresAt := now.Add(s.AccessTokenTTL())
+	claims["sid"] = session.ID.Hex()
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtSecretKey))
+	if err != nil {
+		s.logger.Error().Err(err).Str("session_id", session.ID.Hex()).Msg("JWT 令牌生成失敗")
+		return nil, err
+	}
+
+	return &model.AuthTokens{
+		AccessToken:  accessToken,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		SessionID:    session.ID.Hex(),
+	}, nil
+}
+
+// userAccessClaims 用戶 access token 的 claims，包含完整的用戶資訊以減少資料庫查詢
+func userAccessClaims(user *model.User) jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": user.ID.Hex(),
+		"account": user.Account,
+		"role":    user.Role,
+		"fleet":   string(user.Fleet),
+		"type":    string(model.TokenTypeUser),
+	}
+}
+
+// driverAccessClaims 司機 access token 的 claims
+func driverAccessClaims(driver *model.DriverInfo) jwt.MapClaims {
+	return jwt.MapClaims{
+		"driver_id": driver.ID.Hex(),
+		"account":   driver.Account,
+		"name":      driver.Name,
+		"car_plate": driver.CarPlate,
+		"fleet":     string(driver.Fleet),
+		"car_model": driver.CarModel,
+		"type":      string(model.TokenTypeDriver),
+	}
+}
+
+// newRefreshToken 產生隨機 refresh token
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("產生 refresh token 失敗: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashRefreshToken refresh token 只以 SHA-256 雜湊保存
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// mockCursor 模擬查詢結果，文件依 bson 標籤轉換
+func mockCursor(mt *mtest.T, collection string, docs ...interface{}) bson.D {
+	mt.Helper()
+	batch := make([]bson.D, 0, len(docs))
+	for _, v := range docs {
+		raw, err := bson.Marshal(v)
+		if err != nil {
+			mt.Fatalf("序列化測試文件失敗: %v", err)
+		}
+		var doc bson.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			mt.Fatalf("反序列化測試文件失敗: %v", err)
+		}
+		batch = append(batch, doc)
+	}
+	return mtest.CreateCursorResponse(0, "right_db."+collection, mtest.FirstBatch, batch...)
+}
+
+func newTestAuthSessionService(mt *mtest.T) (*AuthSessionService, *redis.Client) {
+	server := miniredis.RunT(mt.T)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	mt.Cleanup(func() { client.Close() })
+	mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+	return NewAuthSessionService(zerolog.Nop(), mongoDB, client, "test-secret"), client
+}
+
+// testUserSession 建立已輪替過一次的用戶 session
+func testUserSession(refreshToken, previousToken string, lastRefreshedAt time.Time) (*model.AuthSession, *model.User) {
+	user := &model.User{ID: primitive.NewObjectID(), Account: "admin", Role: model.RoleAdmin, Fleet: model.FleetTypeRSK, IsActive: true}
+	now := time.Now().UTC()
+	session := &model.AuthSession{
+		ID:                       primitive.NewObjectID(),
+		SubjectType:              model.TokenTypeUser,
+		SubjectID:                user.ID,
+		Account:                  user.Account,
+		RefreshTokenHash:         hashRefreshToken(refreshToken),
+		PreviousRefreshTokenHash: hashRefreshToken(previousToken),
+		CreatedAt:                now.Add(-time.Hour),
+		LastRefreshedAt:          &lastRefreshedAt,
+		ExpiresAt:                now.Add(24 * time.Hour),
+	}
+	return session, user
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("換發後輪替 refresh token", func(mt *mtest.T) {
+		svc, _ := newTestAuthSessionService(mt)
+		session, user := testUserSession("token-2", "token-1", time.Now().Add(-time.Hour))
+		mt.AddMockResponses(
+			mockCursor(mt, authSessionsCollection, session),
+			mockCursor(mt, "users", user),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		tokens, err := svc.Refresh(context.Background(), "token-2")
+		if err != nil {
+			mt.Fatalf("換發失敗: %v", err)
+		}
+		if tokens.RefreshToken == "" || tokens.RefreshToken == "token-2" || tokens.SessionID != session.ID.Hex() {
+			mt.Fatalf("預期換發新的 refresh token 並沿用 session，實際為 %+v", tokens)
+		}
+
+		// 以舊 token 為條件更新，並記錄為上一個 refresh token
+		var update *bson.Raw
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			if event.CommandName == "update" {
+				update = &event.Command
+			}
+		}
+		if update == nil {
+			mt.Fatal("預期更新 refresh token")
+		}
+		stmt := update.Lookup("updates").Array().Index(0).Value().Document()
+		if got := stmt.Lookup("q", "refresh_token_hash").StringValue(); got != hashRefreshToken("token-2") {
+			mt.Fatalf("預期以目前的 refresh token 為更新條件，實際為 %s", got)
+		}
+		set := stmt.Lookup("u", "$set")
+		if got := set.Document().Lookup("refresh_token_hash").StringValue(); got != hashRefreshToken(tokens.RefreshToken) {
+			mt.Fatal("預期儲存新 refresh token 的雜湊")
+		}
+		if got := set.Document().Lookup("previous_refresh_token_hash").StringValue(); got != hashRefreshToken("token-2") {
+			mt.Fatal("預期將舊 refresh token 記錄為上一個 refresh token")
+		}
+	})
+
+	mt.Run("同一個 refresh token 並行換發只有一次成功", func(mt *mtest.T) {
+		svc, _ := newTestAuthSessionService(mt)
+		session, user := testUserSession("token-2", "token-1", time.Now().Add(-time.Hour))
+		mt.AddMockResponses(
+			mockCursor(mt, authSessionsCollection, session),
+			mockCursor(mt, "users", user),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+
+		if _, err := svc.Refresh(context.Background(), "token-2"); !errors.Is(err, ErrInvalidRefreshToken) {
+			mt.Fatalf("預期 ErrInvalidRefreshToken，實際為 %v", err)
+		}
+	})
+}
+
+func TestRefreshReuseDetection(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("重複使用已輪替的 refresh token 撤銷 session", func(mt *mtest.T) {
+		svc, client := newTestAuthSessionService(mt)
+		session, _ := testUserSession("token-2", "token-1", time.Now().Add(-5*time.Minute))
+		mt.AddMockResponses(
+			mockCursor(mt, authSessionsCollection),
+			mockCursor(mt, authSessionsCollection, session),
+			mockCursor(mt, authSessionsCollection, session),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		if _, err := svc.Refresh(context.Background(), "token-1"); !errors.Is(err, ErrSessionRevoked) {
+			mt.Fatalf("預期 ErrSessionRevoked，實際為 %v", err)
+		}
+
+		reason, err := client.Get(context.Background(), revokedSessionKeyPrefix+session.ID.Hex()).Result()
+		if err != nil || reason != string(model.SessionRevokeRefreshReused) {
+			mt.Fatalf("預期 Redis 記錄撤銷原因 %s，實際為 %q (err=%v)", model.SessionRevokeRefreshReused, reason, err)
+		}
+		// 已簽發的 access token 立即失效
+		claims := map[string]interface{}{"sid": session.ID.Hex()}
+		if err := svc.CheckAccessClaims(context.Background(), claims); !errors.Is(err, ErrSessionRevoked) {
+			mt.Fatalf("預期 access token 失效，實際為 %v", err)
+		}
+	})
+
+	mt.Run("輪替後寬限時間內重送視為客戶端重試", func(mt *mtest.T) {
+		svc, client := newTestAuthSessionService(mt)
+		session, _ := testUserSession("token-2", "token-1", time.Now().Add(-5*time.Second))
+		mt.AddMockResponses(
+			mockCursor(mt, authSessionsCollection),
+			mockCursor(mt, authSessionsCollection, session),
+		)
+
+		if _, err := svc.Refresh(context.Background(), "token-1"); !errors.Is(err, ErrInvalidRefreshToken) {
+			mt.Fatalf("預期 ErrInvalidRefreshToken，實際為 %v", err)
+		}
+		if exists := client.Exists(context.Background(), revokedSessionKeyPrefix+session.ID.Hex()).Val(); exists != 0 {
+			mt.Fatal("寬限時間內重送不應撤銷 session")
+		}
+	})
+
+	mt.Run("不存在的 refresh token", func(mt *mtest.T) {
+		svc, _ := newTestAuthSessionService(mt)
+		mt.AddMockResponses(
+			mockCursor(mt, authSessionsCollection),
+			mockCursor(mt, authSessionsCollection),
+		)
+
+		if _, err := svc.Refresh(context.Background(), "unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+			mt.Fatalf("預期 ErrInvalidRefreshToken，實際為 %v", err)
+		}
+	})
+}
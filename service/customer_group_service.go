@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const customerGroupCollection = "customer_groups"
+
+// CustomerGroupService 管理客群（企業客戶）資料、建單檢查與月結請款單
+type CustomerGroupService struct {
+	logger       zerolog.Logger
+	mongoDB      *infra.MongoDB
+	fleetService *FleetService
+}
+
+func NewCustomerGroupService(logger zerolog.Logger, mongoDB *infra.MongoDB) *CustomerGroupService {
+	return &CustomerGroupService{
+		logger:  logger.With().Str("module", "customer_group_service").Logger(),
+		mongoDB: mongoDB,
+	}
+}
+
+// SetFleetService 設定車隊登錄服務，用於檢查客群預設車隊
+func (s *CustomerGroupService) SetFleetService(fleetService *FleetService) {
+	s.fleetService = fleetService
+}
+
+// ListGroups 列出客群，可只列出月結客群
+func (s *CustomerGroupService) ListGroups(ctx context.Context, monthlyOnly bool) ([]*model.CustomerGroup, error) {
+	filter := bson.M{}
+	if monthlyOnly {
+		filter["monthly_billing"] = true
+	}
+	opts := options.Find().SetSort(bson.M{"code": 1})
+	cursor, err := s.mongoDB.GetCollection(customerGroupCollection).Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢客群失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := []*model.CustomerGroup{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		s.logger.Error().Err(err).Msg("解析客群失敗")
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetGroup 依客群代碼取得客群，找不到時回傳 nil
+func (s *CustomerGroupService) GetGroup(ctx context.Context, code string) (*model.CustomerGroup, error) {
+	var group model.CustomerGroup
+	err := s.mongoDB.GetCollection(customerGroupCollection).FindOne(ctx, bson.M{"code": strings.ToUpper(code)}).Decode(&group)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// CreateGroup 新增客群
+func (s *CustomerGroupService) CreateGroup(ctx context.Context, group *model.CustomerGroup, createdBy string) (*model.CustomerGroup, error) {
+	if err := s.validateGroup(group); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := primitive.NewObjectID()
+	group.ID = &id
+	group.UpdatedBy = createdBy
+	group.CreatedAt = &now
+	group.UpdatedAt = &now
+
+	if _, err := s.mongoDB.GetCollection(customerGroupCollection).InsertOne(ctx, group); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("客群代碼已存在: %s", group.Code)
+		}
+		s.logger.Error().Err(err).Str("code", group.Code).Msg("新增客群失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("code", group.Code).Str("name", group.Name).Str("created_by", createdBy).Msg("客群已新增")
+	return group, nil
+}
+
+// UpdateGroup 更新客群資料，客群代碼不可變更
+func (s *CustomerGroupService) UpdateGroup(ctx context.Context, code string, group *model.CustomerGroup, updatedBy string) (*model.CustomerGroup, error) {
+	group.Code = strings.ToUpper(code)
+	if err := s.validateGroup(group); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":                    group.Name,
+		"contact":                 group.Contact,
+		"billing":                 group.Billing,
+		"default_fleet":           group.DefaultFleet,
+		"allowed_line_config_ids": group.AllowedLineConfigIDs,
+		"monthly_billing":         group.MonthlyBilling,
+		"enabled":                 group.Enabled,
+		"notes":                   group.Notes,
+		"updated_by":              updatedBy,
+		"updated_at":              time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.CustomerGroup
+	err := s.mongoDB.GetCollection(customerGroupCollection).FindOneAndUpdate(ctx, bson.M{"code": group.Code}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到客群: %s", group.Code)
+		}
+		s.logger.Error().Err(err).Str("code", group.Code).Msg("更新客群失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("code", updated.Code).Str("updated_by", updatedBy).Msg("客群已更新")
+	return &updated, nil
+}
+
+// DeleteGroup 刪除客群，既有訂單不受影響
+func (s *CustomerGroupService) DeleteGroup(ctx context.Context, code string) error {
+	code = strings.ToUpper(code)
+	result, err := s.mongoDB.GetCollection(customerGroupCollection).DeleteOne(ctx, bson.M{"code": code})
+	if err != nil {
+		s.logger.Error().Err(err).Str("code", code).Msg("刪除客群失敗")
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("找不到客群: %s", code)
+	}
+
+	s.logger.Info().Str("code", code).Msg("客群已刪除")
+	return nil
+}
+
+// CheckOrderAllowed 建單前檢查客群：已停用的客群一律拒絕，嚴格模式下未登錄的客群也拒絕。
+// 回傳登錄的客群資料（未登錄時為 nil）
+func (s *CustomerGroupService) CheckOrderAllowed(ctx context.Context, code string) (*model.CustomerGroup, error) {
+	group, err := s.GetGroup(ctx, code)
+	if err != nil {
+		s.logger.Error().Err(err).Str("customer_group", code).Msg("查詢客群失敗")
+		if infra.AppConfig.CustomerGroups.Strict {
+			return nil, fmt.Errorf("無法確認客群 %s，請稍後再試", code)
+		}
+		return nil, nil
+	}
+	if group == nil {
+		if infra.AppConfig.CustomerGroups.Strict {
+			return nil, fmt.Errorf("客群 %s 尚未登錄，無法建立訂單", code)
+		}
+		return nil, nil
+	}
+	if !group.Enabled {
+		return nil, fmt.Errorf("客群 %s 已停用，無法建立訂單", code)
+	}
+	return group, nil
+}
+
+// CheckLineConfig 檢查客群是否允許透過指定的 LINE Bot 配置下單，未登錄的客群交由建單流程判斷
+func (s *CustomerGroupService) CheckLineConfig(ctx context.Context, code, configID string) error {
+	group, err := s.GetGroup(ctx, code)
+	if err != nil || group == nil {
+		return nil
+	}
+	if !group.AllowsLineConfig(configID) {
+		return fmt.Errorf("客群 %s 不允許透過此 LINE 帳號下單", group.Code)
+	}
+	return nil
+}
+
+// MonthlyInvoice 產生客群指定月份（台北時間 YYYY-MM）的請款單，列出所有完成訂單與車資
+func (s *CustomerGroupService) MonthlyInvoice(ctx context.Context, code, month string) (*model.CustomerGroupInvoice, error) {
+	group, err := s.GetGroup(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("找不到客群: %s", code)
+	}
+	periodStart, periodEnd, err := parseInvoiceMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"customer_group":  group.Code,
+		"status":          model.OrderStatusCompleted,
+		"completion_time": bson.M{"$gte": periodStart, "$lt": periodEnd},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "completion_time", Value: 1}})
+	cursor, err := s.mongoDB.GetCollection("orders").Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Str("customer_group", group.Code).Str("month", month).Msg("查詢請款訂單失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []*model.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	invoice := &model.CustomerGroupInvoice{
+		CustomerGroup: group,
+		Month:         month,
+		PeriodStart:   periodStart,
+		PeriodEnd:     periodEnd,
+		DueDate:       periodEnd.AddDate(0, 0, group.Billing.PaymentDueDays),
+		Lines:         make([]model.CustomerInvoiceLine, 0, len(orders)),
+	}
+	for _, order := range orders {
+		line := model.CustomerInvoiceLine{
+			OrderID:       order.ID.Hex(),
+			ShortID:       order.ShortID,
+			PassengerID:   order.PassengerID,
+			PickupAddress: order.Customer.PickupAddress,
+			DestAddress:   order.Customer.DestAddress,
+			Fleet:         order.Fleet,
+			DriverName:    order.Driver.Name,
+			CarNo:         order.Driver.CarNo,
+		}
+		if line.PickupAddress == "" {
+			line.PickupAddress = order.Customer.InputPickupAddress
+		}
+		if order.CompletionTime != nil {
+			line.CompletionTime = *order.CompletionTime
+		}
+		line.Fare, line.FareSource = order.BilledFare()
+		if order.FinalFare != nil {
+			line.FareItems = order.FinalFare.Items
+		}
+		if line.FareSource == model.OrderFareNone {
+			invoice.MissingFare++
+		}
+		invoice.Total += line.Fare
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	invoice.OrderCount = len(invoice.Lines)
+	return invoice, nil
+}
+
+// MonthlyInvoiceSummary 列出所有月結客群指定月份的訂單數與請款金額
+func (s *CustomerGroupService) MonthlyInvoiceSummary(ctx context.Context, month string) ([]*model.CustomerInvoiceSummary, error) {
+	periodStart, periodEnd, err := parseInvoiceMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.ListGroups(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*model.CustomerInvoiceSummary, 0, len(groups))
+	if len(groups) == 0 {
+		return summaries, nil
+	}
+	codes := make([]string, 0, len(groups))
+	for _, group := range groups {
+		codes = append(codes, group.Code)
+	}
+
+	// 車資優先使用 final_fare.total，其次為 income，與請款單明細一致
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"customer_group":  bson.M{"$in": codes},
+			"status":          model.OrderStatusCompleted,
+			"completion_time": bson.M{"$gte": periodStart, "$lt": periodEnd},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$customer_group",
+			"order_count": bson.M{"$sum": 1},
+			"total": bson.M{"$sum": bson.M{"$ifNull": bson.A{
+				"$final_fare.total",
+				bson.M{"$ifNull": bson.A{"$income", 0}},
+			}}},
+		}}},
+	}
+	cursor, err := s.mongoDB.GetCollection("orders").Aggregate(ctx, pipeline)
+	if err != nil {
+		s.logger.Error().Err(err).Str("month", month).Msg("彙總月結請款失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []*model.CustomerInvoiceSummary
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]*model.CustomerInvoiceSummary, len(results))
+	for _, result := range results {
+		byCode[result.Code] = result
+	}
+
+	for _, group := range groups {
+		summary := &model.CustomerInvoiceSummary{Code: group.Code, Name: group.Name}
+		if result, ok := byCode[group.Code]; ok {
+			summary.OrderCount = result.OrderCount
+			summary.Total = result.Total
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// ExportInvoice 匯出客群月結請款單到 Excel
+func (s *CustomerGroupService) ExportInvoice(ctx context.Context, code, month string) (*excelize.File, *model.CustomerGroupInvoice, error) {
+	invoice, err := s.MonthlyInvoice(ctx, code, month)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc := utils.GetTaipeiLocation()
+	group := invoice.CustomerGroup
+	f := excelize.NewFile()
+	sheetName := "請款單"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("建立工作表失敗: %w", err)
+	}
+	f.SetActiveSheet(index)
+
+	summary := [][]interface{}{
+		{"客群", group.Code},
+		{"客戶名稱", group.Name},
+		{"發票抬頭", group.Billing.InvoiceTitle},
+		{"統一編號", group.Billing.TaxID},
+		{"聯絡人", group.Contact.Name},
+		{"電話", group.Contact.Phone},
+		{"電子郵件", group.Contact.Email},
+		{"請款月份", invoice.Month},
+		{"付款期限", invoice.DueDate.In(loc).Format("2006-01-02")},
+		{"訂單數", invoice.OrderCount},
+		{"請款金額", invoice.Total},
+	}
+	if invoice.MissingFare > 0 {
+		summary = append(summary, []interface{}{"無車資資料訂單數", invoice.MissingFare})
+	}
+
+	rowNum := 1
+	for _, row := range summary {
+		setExcelRow(f, sheetName, rowNum, row)
+		rowNum++
+	}
+	rowNum++
+
+	setExcelRow(f, sheetName, rowNum, []interface{}{"訂單編號", "完成時間", "乘客", "上車地點", "目的地", "車隊", "司機", "車牌", "車資", "車資來源", "系統編號"})
+	rowNum++
+	for _, line := range invoice.Lines {
+		setExcelRow(f, sheetName, rowNum, []interface{}{
+			line.ShortID,
+			line.CompletionTime.In(loc).Format("2006-01-02 15:04"),
+			line.PassengerID,
+			line.PickupAddress,
+			line.DestAddress,
+			string(line.Fleet),
+			line.DriverName,
+			line.CarNo,
+			line.Fare,
+			fareSourceName(line.FareSource),
+			line.OrderID,
+		})
+		rowNum++
+	}
+
+	if f.GetSheetName(0) == "Sheet1" {
+		f.DeleteSheet("Sheet1")
+	}
+	return f, invoice, nil
+}
+
+// parseInvoiceMonth 解析台北時間的請款月份（YYYY-MM）
+func parseInvoiceMonth(month string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, utils.GetTaipeiLocation())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("月份格式錯誤，應為 YYYY-MM: %w", err)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// validateGroup 驗證客群資料並統一代碼大小寫
+func (s *CustomerGroupService) validateGroup(group *model.CustomerGroup) error {
+	group.Code = strings.ToUpper(strings.TrimSpace(group.Code))
+	if group.Code == "" {
+		return errors.New("客群代碼不可為空")
+	}
+	if strings.ContainsAny(group.Code, "/ ") {
+		return errors.New("客群代碼不可包含空白或 /")
+	}
+	if group.Name == "" {
+		return errors.New("客戶名稱不可為空")
+	}
+	if group.Billing.PaymentDueDays < 0 {
+		return errors.New("付款天數不可為負數")
+	}
+
+	group.DefaultFleet = model.FleetType(strings.ToUpper(string(group.DefaultFleet)))
+	if group.DefaultFleet != "" && s.fleetService != nil && s.fleetService.GetFleet(group.DefaultFleet) == nil {
+		return fmt.Errorf("預設車隊未登錄: %s", group.DefaultFleet)
+	}
+	return nil
+}
@@ -32,46 +32,47 @@ func NewDashboardService(logger zerolog.Logger, mongoDB *infra.MongoDB, driverSe
 	}
 }
 
-func (s *DashboardService) GetDashboardStats(ctx context.Context) (*dashboard.DashboardStats, error) {
+// GetDashboardStats 取得儀表板統計，fleet 為空時統計所有車隊
+func (s *DashboardService) GetDashboardStats(ctx context.Context, fleet string) (*dashboard.DashboardStats, error) {
 	stats := &dashboard.DashboardStats{}
 
-	todayStats, err := s.getTodayOrderStats(ctx)
+	todayStats, err := s.getTodayOrderStats(ctx, fleet)
 	if err != nil {
 		return nil, err
 	}
 	stats.TodayOrders = *todayStats
 
-	reservationStats, err := s.getReservationOrderStats(ctx)
+	reservationStats, err := s.getReservationOrderStats(ctx, fleet)
 	if err != nil {
 		return nil, err
 	}
 	stats.ReservationOrders = *reservationStats
 
-	onlineDriverStats, err := s.getOnlineDriverStats(ctx)
+	onlineDriverStats, err := s.getOnlineDriverStats(ctx, fleet)
 	if err != nil {
 		return nil, err
 	}
 	stats.OnlineDrivers = *onlineDriverStats
 
-	offlineDriverStats, err := s.getOfflineDriverStats(ctx)
+	offlineDriverStats, err := s.getOfflineDriverStats(ctx, fleet)
 	if err != nil {
 		return nil, err
 	}
 	stats.OfflineDrivers = *offlineDriverStats
 
-	idleDriverStats, err := s.getIdleDriverStats(ctx)
+	idleDriverStats, err := s.getIdleDriverStats(ctx, fleet)
 	if err != nil {
 		return nil, err
 	}
 	stats.IdleDrivers = *idleDriverStats
 
-	pickingUpCount, err := s.getPickingUpDriversCount(ctx)
+	pickingUpCount, err := s.getPickingUpDriversCount(ctx, fleet)
 	if err != nil {
 		return nil, err
 	}
 	stats.PickingUpDrivers = pickingUpCount
 
-	executingCount, err := s.getExecutingTaskDriversCount(ctx)
+	executingCount, err := s.getExecutingTaskDriversCount(ctx, fleet)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +81,7 @@ func (s *DashboardService) GetDashboardStats(ctx context.Context) (*dashboard.Da
 	return stats, nil
 }
 
-func (s *DashboardService) getTodayOrderStats(ctx context.Context) (*dashboard.OrderStats, error) {
+func (s *DashboardService) getTodayOrderStats(ctx context.Context, fleet string) (*dashboard.OrderStats, error) {
 	collection := s.mongoDB.GetCollection("orders")
 
 	todayStart := time.Now().Truncate(24 * time.Hour)
@@ -93,7 +94,7 @@ func (s *DashboardService) getTodayOrderStats(ctx context.Context) (*dashboard.O
 		},
 	}
 
-	totalCount, err := collection.CountDocuments(ctx, todayFilter)
+	totalCount, err := collection.CountDocuments(ctx, withFleetFilter(todayFilter, fleet))
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +107,7 @@ func (s *DashboardService) getTodayOrderStats(ctx context.Context) (*dashboard.O
 		"status": model.OrderStatusCompleted,
 	}
 
-	successCount, err := collection.CountDocuments(ctx, successFilter)
+	successCount, err := collection.CountDocuments(ctx, withFleetFilter(successFilter, fleet))
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +118,7 @@ func (s *DashboardService) getTodayOrderStats(ctx context.Context) (*dashboard.O
 	}, nil
 }
 
-func (s *DashboardService) getReservationOrderStats(ctx context.Context) (*dashboard.ReservationStats, error) {
+func (s *DashboardService) getReservationOrderStats(ctx context.Context, fleet string) (*dashboard.ReservationStats, error) {
 	collection := s.mongoDB.GetCollection("orders")
 
 	filter := bson.M{
@@ -132,7 +133,7 @@ func (s *DashboardService) getReservationOrderStats(ctx context.Context) (*dashb
 		},
 	}
 
-	count, err := collection.CountDocuments(ctx, filter)
+	count, err := collection.CountDocuments(ctx, withFleetFilter(filter, fleet))
 	if err != nil {
 		return nil, err
 	}
@@ -142,13 +143,13 @@ func (s *DashboardService) getReservationOrderStats(ctx context.Context) (*dashb
 	}, nil
 }
 
-func (s *DashboardService) getOnlineDriverStats(ctx context.Context) (*dashboard.DriverStats, error) {
+func (s *DashboardService) getOnlineDriverStats(ctx context.Context, fleet string) (*dashboard.DriverStats, error) {
 	collection := s.mongoDB.GetCollection("drivers")
 
-	onlineDrivers, err := collection.CountDocuments(ctx, bson.M{
+	onlineDrivers, err := collection.CountDocuments(ctx, withFleetFilter(bson.M{
 		"is_active": true,
 		"is_online": true,
-	})
+	}, fleet))
 	if err != nil {
 		return nil, err
 	}
@@ -158,16 +159,16 @@ func (s *DashboardService) getOnlineDriverStats(ctx context.Context) (*dashboard
 	}, nil
 }
 
-// GetOnlineDriverStatsByFleet 取得按車隊分組的線上司機統計
-func (s *DashboardService) GetOnlineDriverStatsByFleet(ctx context.Context) (map[string]int, error) {
+// GetOnlineDriverStatsByFleet 取得按車隊分組的線上司機統計，fleet 不為空時只統計該車隊
+func (s *DashboardService) GetOnlineDriverStatsByFleet(ctx context.Context, fleet string) (map[string]int, error) {
 	collection := s.mongoDB.GetCollection("drivers")
 
 	pipeline := []bson.M{
 		{
-			"$match": bson.M{
+			"$match": withFleetFilter(bson.M{
 				"is_active": true,
 				"is_online": true,
-			},
+			}, fleet),
 		},
 		{
 			"$group": bson.M{
@@ -196,12 +197,12 @@ func (s *DashboardService) GetOnlineDriverStatsByFleet(ctx context.Context) (map
 		}
 
 		// 處理空白車隊名稱
-		fleet := result.Fleet
-		if fleet == "" {
-			fleet = "未分配"
+		fleetName := result.Fleet
+		if fleetName == "" {
+			fleetName = "未分配"
 		}
 
-		fleetCounts[fleet] = result.Count
+		fleetCounts[fleetName] = result.Count
 		totalOnline += result.Count
 	}
 
@@ -215,14 +216,14 @@ func (s *DashboardService) GetOnlineDriverStatsByFleet(ctx context.Context) (map
 	return fleetCounts, nil
 }
 
-func (s *DashboardService) getIdleDriverStats(ctx context.Context) (*dashboard.DriverStats, error) {
+func (s *DashboardService) getIdleDriverStats(ctx context.Context, fleet string) (*dashboard.DriverStats, error) {
 	collection := s.mongoDB.GetCollection("drivers")
 
-	idleDrivers, err := collection.CountDocuments(ctx, bson.M{
+	idleDrivers, err := collection.CountDocuments(ctx, withFleetFilter(bson.M{
 		"is_active": true,
 		"is_online": true,
 		"status":    model.DriverStatusIdle,
-	})
+	}, fleet))
 	if err != nil {
 		return nil, err
 	}
@@ -232,13 +233,13 @@ func (s *DashboardService) getIdleDriverStats(ctx context.Context) (*dashboard.D
 	}, nil
 }
 
-func (s *DashboardService) getOfflineDriverStats(ctx context.Context) (*dashboard.DriverStats, error) {
+func (s *DashboardService) getOfflineDriverStats(ctx context.Context, fleet string) (*dashboard.DriverStats, error) {
 	collection := s.mongoDB.GetCollection("drivers")
 
-	offlineDrivers, err := collection.CountDocuments(ctx, bson.M{
+	offlineDrivers, err := collection.CountDocuments(ctx, withFleetFilter(bson.M{
 		"is_active": true,
 		"is_online": false,
-	})
+	}, fleet))
 	if err != nil {
 		return nil, err
 	}
@@ -248,10 +249,10 @@ func (s *DashboardService) getOfflineDriverStats(ctx context.Context) (*dashboar
 	}, nil
 }
 
-func (s *DashboardService) getPickingUpDriversCount(ctx context.Context) (int, error) {
+func (s *DashboardService) getPickingUpDriversCount(ctx context.Context, fleet string) (int, error) {
 	collection := s.mongoDB.GetCollection("drivers")
 
-	count, err := collection.CountDocuments(ctx, bson.M{
+	count, err := collection.CountDocuments(ctx, withFleetFilter(bson.M{
 		"is_active": true,
 		"is_online": true,
 		"status": bson.M{
@@ -260,7 +261,7 @@ func (s *DashboardService) getPickingUpDriversCount(ctx context.Context) (int, e
 				model.DriverStatusArrived,
 			},
 		},
-	})
+	}, fleet))
 	if err != nil {
 		return 0, err
 	}
@@ -268,14 +269,14 @@ func (s *DashboardService) getPickingUpDriversCount(ctx context.Context) (int, e
 	return int(count), nil
 }
 
-func (s *DashboardService) getExecutingTaskDriversCount(ctx context.Context) (int, error) {
+func (s *DashboardService) getExecutingTaskDriversCount(ctx context.Context, fleet string) (int, error) {
 	collection := s.mongoDB.GetCollection("drivers")
 
-	count, err := collection.CountDocuments(ctx, bson.M{
+	count, err := collection.CountDocuments(ctx, withFleetFilter(bson.M{
 		"is_active": true,
 		"is_online": true,
 		"status":    model.DriverStatusExecuting,
-	})
+	}, fleet))
 	if err != nil {
 		return 0, err
 	}
@@ -283,12 +284,14 @@ func (s *DashboardService) getExecutingTaskDriversCount(ctx context.Context) (in
 	return int(count), nil
 }
 
-func (s *DashboardService) GetAllDrivers(ctx context.Context) ([]model.DriverInfo, int, error) {
+// GetAllDrivers 取得司機列表，fleet 為空時回傳所有車隊的司機
+func (s *DashboardService) GetAllDrivers(ctx context.Context, fleet string) ([]model.DriverInfo, int, error) {
 	collection := s.mongoDB.GetCollection("drivers")
 
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	filter := withFleetFilter(bson.M{}, fleet)
 
-	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -299,7 +302,7 @@ func (s *DashboardService) GetAllDrivers(ctx context.Context) ([]model.DriverInf
 		return nil, 0, err
 	}
 
-	totalCount, err := collection.CountDocuments(ctx, bson.M{})
+	totalCount, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -307,7 +310,7 @@ func (s *DashboardService) GetAllDrivers(ctx context.Context) ([]model.DriverInf
 	return drivers, int(totalCount), nil
 }
 
-func (s *DashboardService) GetDriverOrders(ctx context.Context, pageNum, pageSize int, driverStatus, searchKeyword string) ([]dashboard.DriverOrderItem, common.PaginationInfo, error) {
+func (s *DashboardService) GetDriverOrders(ctx context.Context, pageNum, pageSize int, fleet, driverStatus, searchKeyword string) ([]dashboard.DriverOrderItem, common.PaginationInfo, error) {
 	// 設定默認分頁參數
 	if pageNum <= 0 {
 		pageNum = 1
@@ -325,6 +328,8 @@ func (s *DashboardService) GetDriverOrders(ctx context.Context, pageNum, pageSiz
 		"is_online": true,
 	}
 
+	withFleetFilter(driverFilter, fleet)
+
 	// 根據司機狀態過濾
 	if driverStatus != "" && driverStatus != "all" {
 		driverFilter["status"] = driverStatus
@@ -477,8 +482,8 @@ func (s *DashboardService) filterDriverOrderItems(items []dashboard.DriverOrderI
 	return filtered
 }
 
-// GetDriverWeeklyOrderRanks 獲取司機週接單排行榜
-func (s *DashboardService) GetDriverWeeklyOrderRanks(ctx context.Context, weekOffset, pageNum, pageSize int) ([]dashboard.DriverWeeklyOrderRank, int64, string, string, error) {
+// GetDriverWeeklyOrderRanks 獲取司機週接單排行榜，fleet 不為空時只統計該車隊的訂單
+func (s *DashboardService) GetDriverWeeklyOrderRanks(ctx context.Context, fleet string, weekOffset, pageNum, pageSize int) ([]dashboard.DriverWeeklyOrderRank, int64, string, string, error) {
 	// 計算目標週的開始和結束時間
 	now := time.Now()
 
@@ -499,21 +504,23 @@ func (s *DashboardService) GetDriverWeeklyOrderRanks(ctx context.Context, weekOf
 	// MongoDB聚合查詢
 	ordersColl := s.mongoDB.GetCollection("orders")
 
+	match := map[string]interface{}{
+		"status": map[string]interface{}{
+			"$ne": model.OrderStatusFailed, // 不包含流單
+		},
+		"driver.assigned_driver": map[string]interface{}{
+			"$ne": "", // 必須有指派司機
+		},
+		"created_at": map[string]interface{}{
+			"$gte": weekStart,
+			"$lte": weekEnd,
+		},
+	}
+
 	pipeline := []interface{}{
 		// 第一階段：過濾條件
 		map[string]interface{}{
-			"$match": map[string]interface{}{
-				"status": map[string]interface{}{
-					"$ne": model.OrderStatusFailed, // 不包含流單
-				},
-				"driver.assigned_driver": map[string]interface{}{
-					"$ne": "", // 必須有指派司機
-				},
-				"created_at": map[string]interface{}{
-					"$gte": weekStart,
-					"$lte": weekEnd,
-				},
-			},
+			"$match": withFleetFilter(match, fleet),
 		},
 		// 第二階段：按司機ID分組並計算接單數
 		map[string]interface{}{
@@ -598,3 +605,11 @@ func (s *DashboardService) GetDriverWeeklyOrderRanks(ctx context.Context, weekOf
 
 	return ranks, totalCount, weekStartStr, weekEndStr, nil
 }
+
+// withFleetFilter 指定車隊時在查詢條件加上車隊過濾，空值或「全部」表示不過濾
+func withFleetFilter(filter bson.M, fleet string) bson.M {
+	if fleet != "" && fleet != string(model.FleetAccessAll) {
+		filter["fleet"] = fleet
+	}
+	return filter
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const deadLetterOrderCollection = "dead_letter_orders"
+
+// DeadLetterService 管理多次解析或派單失敗的訂單訊息，提供查詢與重新派送
+type DeadLetterService struct {
+	logger       zerolog.Logger
+	mongoDB      *infra.MongoDB
+	rabbitMQ     *infra.RabbitMQ
+	orderService *OrderService
+}
+
+func NewDeadLetterService(logger zerolog.Logger, mongoDB *infra.MongoDB, rabbitMQ *infra.RabbitMQ, orderService *OrderService) *DeadLetterService {
+	return &DeadLetterService{
+		logger:       logger.With().Str("module", "dead_letter_service").Logger(),
+		mongoDB:      mongoDB,
+		rabbitMQ:     rabbitMQ,
+		orderService: orderService,
+	}
+}
+
+// Record 將訊息移入死信隊列
+func (s *DeadLetterService) Record(ctx context.Context, letter *model.DeadLetterOrder) error {
+	now := time.Now()
+	letter.ID = nil
+	letter.Status = model.DeadLetterStatusPending
+	letter.CreatedAt = &now
+
+	result, err := s.mongoDB.GetCollection(deadLetterOrderCollection).InsertOne(ctx, letter)
+	if err != nil {
+		s.logger.Error().Err(err).
+			Str("queue", letter.Queue).
+			Str("order_id", letter.OrderID).
+			Msg("寫入死信隊列失敗")
+		return err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		letter.ID = &oid
+	}
+
+	s.logger.Warn().
+		Str("queue", letter.Queue).
+		Str("order_id", letter.OrderID).
+		Str("short_id", letter.ShortID).
+		Str("reason", letter.Reason).
+		Str("error", letter.Error).
+		Int("attempts", letter.Attempts).
+		Msg("訂單訊息已移入死信隊列")
+	return nil
+}
+
+// List 分頁查詢死信訂單，status 為空時查詢全部
+func (s *DeadLetterService) List(ctx context.Context, status string, pageNum, pageSize int) ([]*model.DeadLetterOrder, int64, error) {
+	collection := s.mongoDB.GetCollection(deadLetterOrderCollection)
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("計算死信訂單總數失敗")
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64((pageNum - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢死信訂單失敗")
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var letters []*model.DeadLetterOrder
+	if err := cursor.All(ctx, &letters); err != nil {
+		s.logger.Error().Err(err).Msg("解析死信訂單失敗")
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
+
+// GetByID 取得單筆死信訂單
+func (s *DeadLetterService) GetByID(ctx context.Context, id string) (*model.DeadLetterOrder, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的死信ID: %s", id)
+	}
+
+	var letter model.DeadLetterOrder
+	err = s.mongoDB.GetCollection(deadLetterOrderCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&letter)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到死信訂單: %s", id)
+		}
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// Replay 重新派送死信訂單：可識別訂單時走重新派單流程（重設為等待接單並增加輪數），否則將原始訊息送回原始隊列
+func (s *DeadLetterService) Replay(ctx context.Context, id string, replayedBy string) (*model.DeadLetterOrder, error) {
+	if s.rabbitMQ == nil {
+		return nil, errors.New("RabbitMQ 未連接，無法重新派送")
+	}
+
+	letter, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter.Status == model.DeadLetterStatusReplayed {
+		return nil, fmt.Errorf("死信訂單 %s 已於 %s 重新派送", id, letter.ReplayedAt.Format(time.RFC3339))
+	}
+
+	if letter.OrderID != "" && s.orderService != nil {
+		if _, err := s.orderService.RedispatchOrder(ctx, letter.OrderID, model.OrderActorAdmin); err != nil {
+			s.logger.Error().Err(err).Str("id", id).Str("order_id", letter.OrderID).Msg("重新派送死信訂單失敗")
+			return nil, err
+		}
+	} else if err := s.rabbitMQ.PublishMessage(letter.Queue, []byte(letter.Body)); err != nil {
+		s.logger.Error().Err(err).Str("id", id).Str("queue", letter.Queue).Msg("重新派送死信訂單失敗")
+		return nil, err
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":      model.DeadLetterStatusReplayed,
+		"replayed_by": replayedBy,
+		"replayed_at": now,
+	}}
+	if _, err := s.mongoDB.GetCollection(deadLetterOrderCollection).UpdateOne(ctx, bson.M{"_id": letter.ID}, update); err != nil {
+		s.logger.Error().Err(err).Str("id", id).Msg("更新死信訂單狀態失敗")
+		return nil, err
+	}
+
+	letter.Status = model.DeadLetterStatusReplayed
+	letter.ReplayedBy = replayedBy
+	letter.ReplayedAt = &now
+
+	s.logger.Info().
+		Str("id", id).
+		Str("order_id", letter.OrderID).
+		Str("queue", letter.Queue).
+		Str("replayed_by", replayedBy).
+		Msg("死信訂單已重新派送")
+	return letter, nil
+}
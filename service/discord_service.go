@@ -27,6 +27,7 @@ type DiscordService struct {
 	chatService         *ChatService
 	fcmService          interfaces.FCMService
 	notificationService *NotificationService
+	fleetService        *FleetService
 }
 
 // NewDiscordService creates and initializes a new DiscordService.
@@ -128,20 +129,7 @@ func (s *DiscordService) registerSlashCommands() {
 					Name:        "fleet",
 					Description: "選擇要清理流單的車隊",
 					Required:    true,
-					Choices: []*discordgo.ApplicationCommandOptionChoice{
-						{
-							Name:  "RSK",
-							Value: "RSK",
-						},
-						{
-							Name:  "KD",
-							Value: "KD",
-						},
-						{
-							Name:  "WEI",
-							Value: "WEI",
-						},
-					},
+					Choices:     s.fleetChoices(),
 				},
 			},
 		},
@@ -176,20 +164,7 @@ func (s *DiscordService) registerSlashCommands() {
 					Name:        "fleet",
 					Description: "篩選特定車隊的司機（可選）",
 					Required:    false,
-					Choices: []*discordgo.ApplicationCommandOptionChoice{
-						{
-							Name:  "RSK",
-							Value: "RSK",
-						},
-						{
-							Name:  "KD",
-							Value: "KD",
-						},
-						{
-							Name:  "WEI",
-							Value: "WEI",
-						},
-					},
+					Choices:     s.fleetChoices(),
 				},
 			},
 		},
@@ -331,6 +306,52 @@ func (s *DiscordService) SetNotificationService(notificationService *Notificatio
 	s.notificationService = notificationService
 }
 
+// SetFleetService 設定車隊登錄服務，slash command 的車隊選項依登錄的車隊產生（需在 SetOrderService 之前設定）
+func (s *DiscordService) SetFleetService(fleetService *FleetService) {
+	s.fleetService = fleetService
+}
+
+// enabledFleets 取得啟用中的車隊，未設定車隊登錄服務時使用預設車隊
+func (s *DiscordService) enabledFleets() []*model.Fleet {
+	if s.fleetService == nil {
+		return model.DefaultFleets()
+	}
+	return s.fleetService.EnabledFleets()
+}
+
+// fleetChoices 產生 slash command 的車隊選項（Discord 上限 25 個）
+func (s *DiscordService) fleetChoices() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, fleet := range s.enabledFleets() {
+		if len(choices) >= 25 {
+			break
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fleet.DisplayName,
+			Value: string(fleet.Code),
+		})
+	}
+	return choices
+}
+
+// testOrderCustomerGroup 測試指令建立訂單使用的客群
+const testOrderCustomerGroup = "W0"
+
+// testOrderFleet 測試指令操作的車隊，與建立測試訂單相同依客群判斷（預設車隊設定下為 WEI）
+func (s *DiscordService) testOrderFleet() (model.FleetType, error) {
+	return resolveFleetFrom(s.enabledFleets(), "", testOrderCustomerGroup)
+}
+
+// isEnabledFleet 車隊是否已登錄且啟用
+func (s *DiscordService) isEnabledFleet(code model.FleetType) bool {
+	for _, fleet := range s.enabledFleets() {
+		if fleet.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
 // SendMessage sends a message to a specific Discord channel.
 func (s *DiscordService) SendMessage(channelID, message string) (*discordgo.Message, error) {
 	return s.session.ChannelMessageSend(channelID, message)
@@ -854,6 +875,11 @@ func (s *DiscordService) UpdateOrderCard(order *model.Order) {
 		}
 	}
 
+	// 多點行程：依序列出各停靠點狀態
+	if waypointsField := createWaypointsField(order); waypointsField != nil {
+		embed.Fields = append(embed.Fields, waypointsField)
+	}
+
 	// 明确设置 Content 为空字符串指针，以清除旧的文字内容
 	emptyContent := ""
 	edit := &discordgo.MessageEdit{
@@ -1263,7 +1289,7 @@ func (s *DiscordService) handleCleanFailedOrdersCommand(sess *discordgo.Session,
 		Msg("處理清理流單指令")
 
 	// 驗證車隊
-	if fleet != model.FleetTypeRSK && fleet != model.FleetTypeKD && fleet != model.FleetTypeWEI {
+	if !s.isEnabledFleet(fleet) {
 		err := sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
@@ -1564,7 +1590,15 @@ func (s *DiscordService) handleRedispatch(i *discordgo.InteractionCreate) {
 	customID := i.MessageComponentData().CustomID
 	originalOrderID := strings.TrimPrefix(customID, "redispatch_")
 
-	_, err := s.orderService.RedispatchOrder(context.Background(), originalOrderID)
+	userName := "Discord用戶"
+	if i.Member != nil && i.Member.User != nil {
+		userName = i.Member.User.Username
+	} else if i.User != nil {
+		userName = i.User.Username
+	}
+	ctx := WithOrderEventActor(context.Background(), model.OrderEventActor{Type: model.OrderEventActorDiscord, Name: userName})
+
+	_, err := s.orderService.RedispatchOrder(ctx, originalOrderID, model.OrderActorPassenger)
 	if err != nil {
 		s.logger.Error().
 			Err(err).
@@ -1630,7 +1664,8 @@ func (s *DiscordService) processCancelCommand(ctx context.Context, orderID, chan
 	}
 
 	// 使用統一的取消服務（內部會判斷是否為預約單並處理司機狀態）
-	updatedOrder, err := s.orderService.CancelOrder(ctx, currentOrder.ID.Hex(), "Discord取消", userName)
+	ctx = WithOrderEventActor(ctx, model.OrderEventActor{Type: model.OrderEventActorDiscord, Name: userName})
+	updatedOrder, err := s.orderService.CancelOrder(ctx, currentOrder.ID.Hex(), model.OrderActorPassenger, "Discord取消", userName)
 	if err != nil {
 		s.logger.Error().Err(err).Str("order_id", orderID).Msg("Discord取消訂單失敗")
 		if _, sendErr := s.SendMessage(channelID, fmt.Sprintf("❌ %s", err.Error())); sendErr != nil {
@@ -2292,6 +2327,34 @@ func createScheduledOrderField(order *model.Order) *discordgo.MessageEmbedField
 	return nil
 }
 
+// createWaypointsField 創建多點行程停靠點欄位（如果訂單有停靠點）
+func createWaypointsField(order *model.Order) *discordgo.MessageEmbedField {
+	if len(order.Waypoints) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(order.Waypoints))
+	for _, wp := range order.Waypoints {
+		lines = append(lines, formatWaypointLine(wp))
+	}
+
+	// Discord 欄位內容上限 1024 字
+	value := []rune(strings.Join(lines, "\n"))
+	if len(value) > 1024 {
+		value = append(value[:1021], []rune("...")...)
+	}
+
+	name := "停靠點"
+	if order.IsRoundTrip {
+		name = "停靠點（來回）"
+	}
+	return &discordgo.MessageEmbedField{
+		Name:   name,
+		Value:  string(value),
+		Inline: false,
+	}
+}
+
 // handleOnlineDriversCommand 處理查詢在線司機指令
 func (s *DiscordService) handleOnlineDriversCommand(sess *discordgo.Session, i *discordgo.InteractionCreate) {
 	s.logger.Info().Msg("處理查詢在線司機指令")
@@ -2425,11 +2488,27 @@ func (s *DiscordService) handleWeiEmptyOrderAndDriverCommand(sess *discordgo.Ses
 		userName = i.User.Username
 	}
 
+	fleet, err := s.testOrderFleet()
+	if err != nil {
+		s.logger.Error().Err(err).Str("user", userName).Msg("判斷測試車隊失敗")
+		err = sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("❌ 無法判斷測試車隊：%v", err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			s.logger.Error().Err(err).Msg("回應測試車隊錯誤失敗")
+		}
+		return
+	}
+
 	// 先回應用戶，表示正在處理
-	err := sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	err = sess.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: "⏳ 正在清空 WEI 車隊的所有訂單並重置司機狀態...",
+			Content: fmt.Sprintf("⏳ 正在清空 %s 車隊的所有訂單並重置司機狀態...", fleet),
 		},
 	})
 	if err != nil {
@@ -2438,45 +2517,46 @@ func (s *DiscordService) handleWeiEmptyOrderAndDriverCommand(sess *discordgo.Ses
 	}
 
 	// 在背景執行清空操作
-	go s.processWeiEmptyOrderAndDriver(context.Background(), i, userName)
+	go s.processWeiEmptyOrderAndDriver(context.Background(), i, userName, fleet)
 }
 
-// processWeiEmptyOrderAndDriver 執行清空 WEI 車隊訂單和司機狀態的背景任務
-func (s *DiscordService) processWeiEmptyOrderAndDriver(ctx context.Context, i *discordgo.InteractionCreate, userName string) {
+// processWeiEmptyOrderAndDriver 執行清空測試車隊訂單和司機狀態的背景任務
+func (s *DiscordService) processWeiEmptyOrderAndDriver(ctx context.Context, i *discordgo.InteractionCreate, userName string, fleet model.FleetType) {
 	s.logger.Info().
 		Str("user", userName).
-		Msg("開始清空WEI車隊訂單和司機狀態")
+		Str("fleet", string(fleet)).
+		Msg("開始清空測試車隊訂單和司機狀態")
 
 	var successMessages []string
 	var errorMessages []string
 
-	// 步驟1: 刪除 WEI 車隊的所有訂單
+	// 步驟1: 刪除測試車隊的所有訂單
 	if s.orderService != nil {
-		deletedCount, err := s.orderService.DeleteAllOrdersByFleet(ctx, model.FleetTypeWEI)
+		deletedCount, err := s.orderService.DeleteAllOrdersByFleet(ctx, fleet)
 		if err != nil {
-			errorMsg := fmt.Sprintf("刪除WEI車隊訂單失敗：%v", err)
+			errorMsg := fmt.Sprintf("刪除%s車隊訂單失敗：%v", fleet, err)
 			errorMessages = append(errorMessages, errorMsg)
 			s.logger.Error().Err(err).Msg(errorMsg)
 		} else {
-			successMsg := fmt.Sprintf("✅ 成功刪除 %d 個WEI車隊訂單", deletedCount)
+			successMsg := fmt.Sprintf("✅ 成功刪除 %d 個%s車隊訂單", deletedCount, fleet)
 			successMessages = append(successMessages, successMsg)
-			s.logger.Info().Int("deleted_count", deletedCount).Msg("成功刪除WEI車隊訂單")
+			s.logger.Info().Int("deleted_count", deletedCount).Str("fleet", string(fleet)).Msg("成功刪除測試車隊訂單")
 		}
 	} else {
 		errorMessages = append(errorMessages, "❌ OrderService 未初始化，無法刪除訂單")
 	}
 
-	// 步驟2: 重置 WEI 車隊司機狀態
+	// 步驟2: 重置測試車隊司機狀態
 	if s.driverService != nil {
-		resetCount, err := s.resetWeiDriversStatus(ctx)
+		resetCount, err := s.resetWeiDriversStatus(ctx, fleet)
 		if err != nil {
-			errorMsg := fmt.Sprintf("重置WEI車隊司機狀態失敗：%v", err)
+			errorMsg := fmt.Sprintf("重置%s車隊司機狀態失敗：%v", fleet, err)
 			errorMessages = append(errorMessages, errorMsg)
 			s.logger.Error().Err(err).Msg(errorMsg)
 		} else {
-			successMsg := fmt.Sprintf("✅ 成功重置 %d 個WEI車隊司機狀態", resetCount)
+			successMsg := fmt.Sprintf("✅ 成功重置 %d 個%s車隊司機狀態", resetCount, fleet)
 			successMessages = append(successMessages, successMsg)
-			s.logger.Info().Int("reset_count", resetCount).Msg("成功重置WEI車隊司機狀態")
+			s.logger.Info().Int("reset_count", resetCount).Str("fleet", string(fleet)).Msg("成功重置測試車隊司機狀態")
 		}
 	} else {
 		errorMessages = append(errorMessages, "❌ DriverService 未初始化，無法重置司機狀態")
@@ -2484,7 +2564,7 @@ func (s *DiscordService) processWeiEmptyOrderAndDriver(ctx context.Context, i *d
 
 	// 構建回應訊息
 	var responseContent strings.Builder
-	responseContent.WriteString("🔧 **WEI車隊清空操作完成**\n\n")
+	responseContent.WriteString(fmt.Sprintf("🔧 **%s車隊清空操作完成**\n\n", fleet))
 
 	if len(successMessages) > 0 {
 		responseContent.WriteString("**成功操作：**\n")
@@ -2512,12 +2592,12 @@ func (s *DiscordService) processWeiEmptyOrderAndDriver(ctx context.Context, i *d
 	}
 }
 
-// resetWeiDriversStatus 重置所有 WEI 車隊司機的狀態
-func (s *DiscordService) resetWeiDriversStatus(ctx context.Context) (int, error) {
-	// 獲取所有 WEI 車隊的司機
-	drivers, err := s.driverService.GetDriversByFleet(ctx, model.FleetTypeWEI)
+// resetWeiDriversStatus 重置測試車隊所有司機的狀態
+func (s *DiscordService) resetWeiDriversStatus(ctx context.Context, fleet model.FleetType) (int, error) {
+	// 獲取測試車隊的所有司機
+	drivers, err := s.driverService.GetDriversByFleet(ctx, fleet)
 	if err != nil {
-		return 0, fmt.Errorf("獲取WEI車隊司機失敗: %w", err)
+		return 0, fmt.Errorf("獲取%s車隊司機失敗: %w", fleet, err)
 	}
 
 	resetCount := 0
@@ -2591,10 +2671,10 @@ func (s *DiscordService) processWeiCreateExampleOrder(ctx context.Context, i *di
 		// 預約單：當前時間 +1小時05分
 		scheduledTime := time.Now().Add(1*time.Hour + 5*time.Minute)
 		timeStr := scheduledTime.Format("15:04")
-		content = fmt.Sprintf("W0/638台灣雲林縣麥寮鄉中山路%d號 %s", randomNum, timeStr)
+		content = fmt.Sprintf("%s/638台灣雲林縣麥寮鄉中山路%d號 %s", testOrderCustomerGroup, randomNum, timeStr)
 	} else {
 		// 即時單
-		content = fmt.Sprintf("W0/638台灣雲林縣麥寮鄉中山路%d號 測試即時單", randomNum)
+		content = fmt.Sprintf("%s/638台灣雲林縣麥寮鄉中山路%d號 測試即時單", testOrderCustomerGroup, randomNum)
 	}
 
 	s.logger.Info().
@@ -2801,6 +2881,11 @@ func (s *DiscordService) updateInteractionResponseOrderCard(i *discordgo.Interac
 		embed.Fields = defaultFields
 	}
 
+	// 多點行程：依序列出各停靠點狀態
+	if waypointsField := createWaypointsField(order); waypointsField != nil {
+		embed.Fields = append(embed.Fields, waypointsField)
+	}
+
 	// 更新 interaction response
 	_, err := s.session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Embeds:     &[]*discordgo.MessageEmbed{embed},
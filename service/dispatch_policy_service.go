@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	dispatchPolicyCollection     = "dispatch_policies"
+	dispatchPolicyReloadInterval = 1 * time.Minute // Redis 事件遺失時的定期重新載入間隔
+)
+
+// DispatchPolicyService 管理各車隊派單策略，並在記憶體中快取供調度中心即時讀取
+type DispatchPolicyService struct {
+	logger       zerolog.Logger
+	mongoDB      *infra.MongoDB
+	eventManager *infra.RedisEventManager
+	fleetService *FleetService
+
+	mu       sync.RWMutex
+	policies map[model.FleetType]*model.DispatchPolicy
+}
+
+func NewDispatchPolicyService(logger zerolog.Logger, mongoDB *infra.MongoDB, eventManager *infra.RedisEventManager) *DispatchPolicyService {
+	return &DispatchPolicyService{
+		logger:       logger.With().Str("module", "dispatch_policy_service").Logger(),
+		mongoDB:      mongoDB,
+		eventManager: eventManager,
+		policies:     make(map[model.FleetType]*model.DispatchPolicy),
+	}
+}
+
+// SetFleetService 設定車隊登錄服務，預設派單策略依車隊設定決定是否限制派單距離
+func (s *DispatchPolicyService) SetFleetService(fleetService *FleetService) {
+	s.fleetService = fleetService
+}
+
+// GetPolicy 取得車隊派單策略（讀取快取），未設定時回傳預設值
+func (s *DispatchPolicyService) GetPolicy(fleet model.FleetType) *model.DispatchPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if policy, ok := s.policies[fleet]; ok {
+		copied := *policy
+		return &copied
+	}
+	return model.DefaultDispatchPolicy(fleet, s.fleetSettings(fleet))
+}
+
+// fleetSettings 取得車隊設定，未設定車隊登錄服務時使用預設車隊
+func (s *DispatchPolicyService) fleetSettings(fleet model.FleetType) model.FleetSettings {
+	if s.fleetService != nil {
+		return s.fleetService.FleetOrUnregistered(fleet).Settings
+	}
+	return model.DefaultFleetFor(fleet).Settings
+}
+
+// ListPolicies 列出所有已設定的車隊派單策略
+func (s *DispatchPolicyService) ListPolicies(ctx context.Context) ([]*model.DispatchPolicy, error) {
+	cursor, err := s.mongoDB.GetCollection(dispatchPolicyCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"fleet": 1}))
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢派單策略失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var policies []*model.DispatchPolicy
+	if err := cursor.All(ctx, &policies); err != nil {
+		s.logger.Error().Err(err).Msg("解析派單策略失敗")
+		return nil, err
+	}
+	return policies, nil
+}
+
+// UpsertPolicy 新增或更新車隊派單策略，並通知所有調度中心重新載入
+func (s *DispatchPolicyService) UpsertPolicy(ctx context.Context, policy *model.DispatchPolicy, updatedBy string) (*model.DispatchPolicy, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	policy.ID = nil
+	policy.UpdatedBy = updatedBy
+	policy.UpdatedAt = &now
+	policy.CreatedAt = nil
+
+	update := bson.M{
+		"$set":         policy,
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved model.DispatchPolicy
+	err := s.mongoDB.GetCollection(dispatchPolicyCollection).
+		FindOneAndUpdate(ctx, bson.M{"fleet": policy.Fleet}, update, opts).
+		Decode(&saved)
+	if err != nil {
+		s.logger.Error().Err(err).Str("fleet", string(policy.Fleet)).Msg("更新派單策略失敗")
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("fleet", string(saved.Fleet)).
+		Str("updated_by", updatedBy).
+		Int("haversine_candidates_count", saved.HaversineCandidatesCount).
+		Int("google_api_candidates_count", saved.GoogleAPICandidatesCount).
+		Int("sequential_call_timeout_secs", saved.SequentialCallTimeoutSecs).
+		Float64("max_driver_distance_km", saved.MaxDriverDistanceKm).
+		Int("max_estimated_time_mins", saved.MaxEstimatedTimeMins).
+		Str("dispatch_mode", string(saved.DispatchMode)).
+		Int("broadcast_count", saved.BroadcastCount).
+		Int("rounds", len(saved.Rounds)).
+		Msg("派單策略已更新")
+
+	s.notifyChanged(ctx, saved.Fleet)
+	return &saved, nil
+}
+
+// DeletePolicy 刪除車隊派單策略，使該車隊恢復預設值
+func (s *DispatchPolicyService) DeletePolicy(ctx context.Context, fleet model.FleetType) error {
+	result, err := s.mongoDB.GetCollection(dispatchPolicyCollection).DeleteOne(ctx, bson.M{"fleet": fleet})
+	if err != nil {
+		s.logger.Error().Err(err).Str("fleet", string(fleet)).Msg("刪除派單策略失敗")
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("車隊 %s 沒有自訂派單策略", fleet)
+	}
+
+	s.logger.Info().Str("fleet", string(fleet)).Msg("派單策略已刪除，恢復預設值")
+	s.notifyChanged(ctx, fleet)
+	return nil
+}
+
+// Reload 從 MongoDB 重新載入所有派單策略到快取
+func (s *DispatchPolicyService) Reload(ctx context.Context) error {
+	policies, err := s.ListPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[model.FleetType]*model.DispatchPolicy, len(policies))
+	for _, policy := range policies {
+		// 直接修改資料庫造成的無效策略不載入，該車隊沿用預設值
+		if err := policy.Validate(); err != nil {
+			s.logger.Warn().Err(err).Str("fleet", string(policy.Fleet)).Msg("派單策略設定無效，改用預設值")
+			continue
+		}
+		loaded[policy.Fleet] = policy
+	}
+
+	s.mu.Lock()
+	s.policies = loaded
+	s.mu.Unlock()
+
+	s.logger.Debug().Int("policy_count", len(loaded)).Msg("派單策略已重新載入")
+	return nil
+}
+
+// StartHotReload 啟動派單策略熱更新：訂閱 Redis 設定變更事件，並定期重新載入作為備援
+func (s *DispatchPolicyService) StartHotReload(ctx context.Context) {
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("初始載入派單策略失敗，暫時使用預設值")
+	}
+
+	var events <-chan *redis.Message
+	if s.eventManager != nil {
+		pubsub := s.eventManager.SubscribeConfigChanges(ctx)
+		defer pubsub.Close()
+		events = pubsub.Channel()
+	}
+
+	ticker := time.NewTicker(dispatchPolicyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			event, err := infra.ParseConfigChangeEvent(msg.Payload)
+			if err != nil || event.ConfigType != infra.ConfigChangeDispatchPolicy {
+				continue
+			}
+			if err := s.Reload(ctx); err != nil {
+				s.logger.Error().Err(err).Str("fleet", event.Key).Msg("收到派單策略變更事件，但重新載入失敗")
+			} else {
+				s.logger.Info().Str("fleet", event.Key).Msg("收到派單策略變更事件，已重新載入")
+			}
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				s.logger.Error().Err(err).Msg("定期重新載入派單策略失敗")
+			}
+		}
+	}
+}
+
+// notifyChanged 更新本地快取並發布設定變更事件
+func (s *DispatchPolicyService) notifyChanged(ctx context.Context, fleet model.FleetType) {
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("重新載入派單策略失敗")
+	}
+	if s.eventManager != nil {
+		_ = s.eventManager.PublishConfigChangeEvent(ctx, &infra.ConfigChangeEvent{
+			ConfigType: infra.ConfigChangeDispatchPolicy,
+			Key:        string(fleet),
+			Timestamp:  time.Now(),
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const driverDevicesCollection = "driver_devices"
+
+var (
+	ErrDeviceApprovalPending = errors.New("新設備等待管理員核准")
+	ErrDriverDeviceNotFound  = errors.New("找不到司機設備")
+)
+
+// DriverDeviceService 司機設備綁定：首次登入自動綁定設備，之後在其他設備登入需管理員核准；
+// 每位司機同時只有一台已綁定的設備，設備解除綁定時撤銷該設備的登入 session
+type DriverDeviceService struct {
+	logger         zerolog.Logger
+	mongoDB        *infra.MongoDB
+	sessionService *AuthSessionService
+}
+
+func NewDriverDeviceService(logger zerolog.Logger, mongoDB *infra.MongoDB, sessionService *AuthSessionService) *DriverDeviceService {
+	return &DriverDeviceService{
+		logger:         logger.With().Str("module", "driver_device_service").Logger(),
+		mongoDB:        mongoDB,
+		sessionService: sessionService,
+	}
+}
+
+// AuthorizeLoginDevice 檢查司機是否可以在此設備登入：已綁定的設備直接通過，司機尚未綁定任何設備時自動綁定，
+// 其他設備建立待核准申請並回傳 ErrDeviceApprovalPending
+func (s *DriverDeviceService) AuthorizeLoginDevice(ctx context.Context, driver *model.DriverInfo, device model.SessionDevice) (*model.DriverDevice, error) {
+	collection := s.mongoDB.GetCollection(driverDevicesCollection)
+	deviceID := loginDeviceID(device)
+	now := time.Now().UTC()
+	filter := bson.M{"driver_id": driver.ID, "device_id": deviceID}
+
+	var existing model.DriverDevice
+	err := collection.FindOne(ctx, filter).Decode(&existing)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("查詢司機設備失敗: %w", err)
+	}
+	found := err == nil
+
+	fields := deviceFields(driver, device, now)
+	if found && existing.Status == model.DriverDeviceStatusBound {
+		fields["last_login_at"] = now
+		return s.updateDevice(ctx, filter, bson.M{"$set": fields}, false)
+	}
+
+	// 尚未綁定任何設備（首次登入或管理員解除綁定後）時直接綁定此設備
+	boundCount, err := collection.CountDocuments(ctx, bson.M{"driver_id": driver.ID, "status": model.DriverDeviceStatusBound})
+	if err != nil {
+		return nil, fmt.Errorf("查詢司機已綁定設備失敗: %w", err)
+	}
+	if boundCount == 0 {
+		fields["status"] = model.DriverDeviceStatusBound
+		fields["bound_at"] = now
+		fields["last_login_at"] = now
+		bound, err := s.updateDevice(ctx, filter, bson.M{
+			"$set":         fields,
+			"$setOnInsert": bson.M{"requested_at": now},
+			"$unset":       bson.M{"unbound_at": "", "reviewed_by": ""},
+		}, true)
+		if err == nil {
+			s.logger.Info().
+				Str("driver_id", driver.ID.Hex()).
+				Str("device_id", deviceID).
+				Str("device_model", device.ModelName).
+				Msg("司機首次登入，已自動綁定設備")
+			return bound, nil
+		}
+		// 同時在兩台設備首次登入時，唯一索引只允許一台綁定，另一台改為待核准
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+	}
+
+	fields["status"] = model.DriverDeviceStatusPending
+	if !found || existing.Status != model.DriverDeviceStatusPending {
+		fields["requested_at"] = now
+	}
+	// 避免併發登入時把剛綁定的設備改回待核准
+	pendingFilter := bson.M{"driver_id": driver.ID, "device_id": deviceID, "status": bson.M{"$ne": model.DriverDeviceStatusBound}}
+	if _, err := s.updateDevice(ctx, pendingFilter, bson.M{"$set": fields}, true); err != nil {
+		return nil, err
+	}
+
+	s.logger.Warn().
+		Str("driver_id", driver.ID.Hex()).
+		Str("driver_account", driver.Account).
+		Str("device_id", deviceID).
+		Str("device_model", device.ModelName).
+		Msg("司機在未綁定的設備登入，等待管理員核准")
+	return nil, ErrDeviceApprovalPending
+}
+
+// ListDriverDevices 取得司機所有設備（含待核准與已解除綁定）
+func (s *DriverDeviceService) ListDriverDevices(ctx context.Context, driverID string) ([]*model.DriverDevice, error) {
+	objectID, err := primitive.ObjectIDFromHex(driverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的司機ID: %w", err)
+	}
+	return s.findDevices(ctx, bson.M{"driver_id": objectID})
+}
+
+// ListPendingDevices 取得待核准的設備，fleet 為空或「全部」時回傳所有車隊
+func (s *DriverDeviceService) ListPendingDevices(ctx context.Context, fleet string) ([]*model.DriverDevice, error) {
+	filter := bson.M{"status": model.DriverDeviceStatusPending}
+	if fleet != "" && fleet != string(model.FleetAccessAll) {
+		filter["fleet"] = fleet
+	}
+	return s.findDevices(ctx, filter)
+}
+
+// ApproveDevice 核准司機的設備，原本綁定的設備會解除綁定並撤銷其登入 session
+func (s *DriverDeviceService) ApproveDevice(ctx context.Context, driverID, id, reviewer string) (*model.DriverDevice, error) {
+	device, err := s.getDevice(ctx, driverID, id)
+	if err != nil {
+		return nil, err
+	}
+	if device.Status == model.DriverDeviceStatusBound {
+		return device, nil
+	}
+
+	var current model.DriverDevice
+	err = s.mongoDB.GetCollection(driverDevicesCollection).FindOne(ctx, bson.M{
+		"driver_id": device.DriverID,
+		"status":    model.DriverDeviceStatusBound,
+	}).Decode(&current)
+	switch {
+	case err == nil:
+		if _, err := s.unbind(ctx, &current, reviewer); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return nil, fmt.Errorf("查詢司機已綁定設備失敗: %w", err)
+	}
+
+	now := time.Now().UTC()
+	approved, err := s.updateDevice(ctx, bson.M{"_id": device.ID}, bson.M{
+		"$set": bson.M{
+			"status":      model.DriverDeviceStatusBound,
+			"bound_at":    now,
+			"reviewed_by": reviewer,
+			"updated_at":  now,
+		},
+		"$unset": bson.M{"unbound_at": ""},
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("driver_id", driverID).
+		Str("device_id", approved.DeviceID).
+		Str("reviewer", reviewer).
+		Msg("管理員已核准司機設備")
+	return approved, nil
+}
+
+// UnbindDevice 解除司機設備綁定（或拒絕待核准的設備），並撤銷該設備的登入 session；司機下次登入的設備會自動綁定
+func (s *DriverDeviceService) UnbindDevice(ctx context.Context, driverID, id, reviewer string) (*model.DriverDevice, error) {
+	device, err := s.getDevice(ctx, driverID, id)
+	if err != nil {
+		return nil, err
+	}
+	if device.Status == model.DriverDeviceStatusUnbound {
+		return device, nil
+	}
+
+	unbound, err := s.unbind(ctx, device, reviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("driver_id", driverID).
+		Str("device_id", unbound.DeviceID).
+		Str("reviewer", reviewer).
+		Msg("管理員已解除司機設備綁定")
+	return unbound, nil
+}
+
+func (s *DriverDeviceService) unbind(ctx context.Context, device *model.DriverDevice, reviewer string) (*model.DriverDevice, error) {
+	now := time.Now().UTC()
+	unbound, err := s.updateDevice(ctx, bson.M{"_id": device.ID}, bson.M{"$set": bson.M{
+		"status":      model.DriverDeviceStatusUnbound,
+		"unbound_at":  now,
+		"reviewed_by": reviewer,
+		"updated_at":  now,
+	}}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sessionService.RevokeDeviceSessions(ctx, model.TokenTypeDriver, device.DriverID.Hex(), device.DeviceID, model.SessionRevokeDeviceUnbound); err != nil {
+		s.logger.Error().Err(err).
+			Str("driver_id", device.DriverID.Hex()).
+			Str("device_id", device.DeviceID).
+			Msg("撤銷解除綁定設備的登入 session 失敗")
+	}
+	return unbound, nil
+}
+
+func (s *DriverDeviceService) getDevice(ctx context.Context, driverID, id string) (*model.DriverDevice, error) {
+	driverObjectID, err := primitive.ObjectIDFromHex(driverID)
+	if err != nil {
+		return nil, ErrDriverDeviceNotFound
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrDriverDeviceNotFound
+	}
+
+	var device model.DriverDevice
+	err = s.mongoDB.GetCollection(driverDevicesCollection).FindOne(ctx, bson.M{"_id": objectID, "driver_id": driverObjectID}).Decode(&device)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDriverDeviceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查詢司機設備失敗: %w", err)
+	}
+	return &device, nil
+}
+
+func (s *DriverDeviceService) findDevices(ctx context.Context, filter bson.M) ([]*model.DriverDevice, error) {
+	cursor, err := s.mongoDB.GetCollection(driverDevicesCollection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("查詢司機設備失敗: %w", err)
+	}
+	devices := []*model.DriverDevice{}
+	if err := cursor.All(ctx, &devices); err != nil {
+		return nil, fmt.Errorf("解析司機設備失敗: %w", err)
+	}
+	return devices, nil
+}
+
+func (s *DriverDeviceService) updateDevice(ctx context.Context, filter, update bson.M, upsert bool) (*model.DriverDevice, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetUpsert(upsert)
+	var device model.DriverDevice
+	err := s.mongoDB.GetCollection(driverDevicesCollection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&device)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDriverDeviceNotFound
+	}
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("更新司機設備失敗: %w", err)
+	}
+	return &device, nil
+}
+
+// deviceFields 每次登入時更新的設備描述欄位
+func deviceFields(driver *model.DriverInfo, device model.SessionDevice, now time.Time) bson.M {
+	return bson.M{
+		"driver_account": driver.Account,
+		"fleet":          driver.Fleet,
+		"model_name":     device.ModelName,
+		"device_name":    device.DeviceName,
+		"brand":          device.Brand,
+		"manufacturer":   device.Manufacturer,
+		"app_version":    device.AppVersion,
+		"updated_at":     now,
+	}
+}
+
+// loginDeviceID 設備識別碼；舊版 App 未提供時以設備型號、名稱、品牌與製造商產生指紋
+func loginDeviceID(device model.SessionDevice) string {
+	if id := strings.TrimSpace(device.DeviceID); id != "" {
+		return id
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{device.ModelName, device.DeviceName, device.Brand, device.Manufacturer}, "|")))
+	return "fp:" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestLoginDeviceID(t *testing.T) {
+	device := model.SessionDevice{ModelName: "iPhone15,4", DeviceName: "My iPhone", Brand: "Apple", Manufacturer: "Apple Inc."}
+
+	if id := loginDeviceID(model.SessionDevice{DeviceID: "  device-1 ", ModelName: "iPhone15,4"}); id != "device-1" {
+		t.Fatalf("有設備識別碼時預期使用去除空白的識別碼，實際為 %q", id)
+	}
+
+	fingerprint := loginDeviceID(device)
+	if len(fingerprint) != len("fp:")+32 || fingerprint[:3] != "fp:" {
+		t.Fatalf("未提供設備識別碼時預期產生 fp: 開頭的 32 字元指紋，實際為 %q", fingerprint)
+	}
+	if id := loginDeviceID(model.SessionDevice{DeviceID: "   ", ModelName: "iPhone15,4", DeviceName: "My iPhone", Brand: "Apple", Manufacturer: "Apple Inc."}); id != fingerprint {
+		t.Fatalf("識別碼只有空白時預期改用指紋，實際為 %q", id)
+	}
+
+	// App 版本與 User-Agent 不影響指紋，避免更新 App 後被視為新設備
+	upgraded := device
+	upgraded.AppVersion = "2.0.0"
+	upgraded.UserAgent = "okhttp/4.12.0"
+	if id := loginDeviceID(upgraded); id != fingerprint {
+		t.Fatalf("App 版本變更後預期指紋不變，實際為 %q", id)
+	}
+
+	renamed := device
+	renamed.DeviceName = "Other iPhone"
+	if id := loginDeviceID(renamed); id == fingerprint {
+		t.Fatal("設備名稱不同時預期產生不同指紋")
+	}
+}
+
+func newTestDriverDeviceService(mt *mtest.T) *DriverDeviceService {
+	mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+	return NewDriverDeviceService(zerolog.Nop(), mongoDB, nil)
+}
+
+// findAndModifyResponse 模擬 FindOneAndUpdate 回傳更新後的設備
+func findAndModifyResponse(device *model.DriverDevice) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: device})
+}
+
+// countResponse 模擬 CountDocuments 的 aggregate 結果
+func countResponse(n int) bson.D {
+	if n == 0 {
+		return mtest.CreateCursorResponse(0, "right_db."+driverDevicesCollection, mtest.FirstBatch)
+	}
+	return mtest.CreateCursorResponse(0, "right_db."+driverDevicesCollection, mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+}
+
+// findAndModifyCommand 依序找出下一個 findAndModify 指令
+func findAndModifyCommand(mt *mtest.T) bson.Raw {
+	mt.Helper()
+	for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+		if event.CommandName == "findAndModify" {
+			return event.Command
+		}
+	}
+	mt.Fatal("預期執行 findAndModify，但沒有找到")
+	return nil
+}
+
+func TestAuthorizeLoginDevice(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	driver := &model.DriverInfo{ID: primitive.NewObjectID(), Account: "driver001", Fleet: model.FleetTypeRSK}
+	device := model.SessionDevice{DeviceID: "device-1", ModelName: "iPhone15,4"}
+
+	testDevice := func(status model.DriverDeviceStatus) *model.DriverDevice {
+		now := time.Now().UTC()
+		return &model.DriverDevice{
+			ID:          primitive.NewObjectID(),
+			DriverID:    driver.ID,
+			DeviceID:    device.DeviceID,
+			Status:      status,
+			RequestedAt: now,
+			UpdatedAt:   now,
+		}
+	}
+
+	mt.Run("已綁定的設備直接通過", func(mt *mtest.T) {
+		svc := newTestDriverDeviceService(mt)
+		bound := testDevice(model.DriverDeviceStatusBound)
+		mt.AddMockResponses(
+			mockCursor(mt, driverDevicesCollection, bound),
+			findAndModifyResponse(bound),
+		)
+
+		got, err := svc.AuthorizeLoginDevice(context.Background(), driver, device)
+		if err != nil {
+			mt.Fatalf("預期已綁定設備可以登入，實際失敗: %v", err)
+		}
+		if got.Status != model.DriverDeviceStatusBound {
+			mt.Fatalf("預期設備狀態為 bound，實際為 %s", got.Status)
+		}
+
+		cmd := findAndModifyCommand(mt)
+		if upsert, ok := cmd.Lookup("upsert").BooleanOK(); ok && upsert {
+			mt.Fatal("已綁定設備只更新登入時間，不應 upsert")
+		}
+		if _, err := cmd.Lookup("update", "$set").Document().LookupErr("last_login_at"); err != nil {
+			mt.Fatal("預期更新最後登入時間")
+		}
+	})
+
+	mt.Run("尚未綁定任何設備時自動綁定", func(mt *mtest.T) {
+		svc := newTestDriverDeviceService(mt)
+		mt.AddMockResponses(
+			mockCursor(mt, driverDevicesCollection),
+			countResponse(0),
+			findAndModifyResponse(testDevice(model.DriverDeviceStatusBound)),
+		)
+
+		got, err := svc.AuthorizeLoginDevice(context.Background(), driver, device)
+		if err != nil {
+			mt.Fatalf("預期首次登入自動綁定，實際失敗: %v", err)
+		}
+		if got.Status != model.DriverDeviceStatusBound {
+			mt.Fatalf("預期設備狀態為 bound，實際為 %s", got.Status)
+		}
+
+		cmd := findAndModifyCommand(mt)
+		if status := cmd.Lookup("update", "$set", "status").StringValue(); status != string(model.DriverDeviceStatusBound) {
+			mt.Fatalf("預期寫入 bound 狀態，實際為 %s", status)
+		}
+		if !cmd.Lookup("upsert").Boolean() {
+			mt.Fatal("首次登入的設備預期以 upsert 建立")
+		}
+	})
+
+	mt.Run("已有其他綁定設備時改為待核准", func(mt *mtest.T) {
+		svc := newTestDriverDeviceService(mt)
+		mt.AddMockResponses(
+			mockCursor(mt, driverDevicesCollection),
+			countResponse(1),
+			findAndModifyResponse(testDevice(model.DriverDeviceStatusPending)),
+		)
+
+		got, err := svc.AuthorizeLoginDevice(context.Background(), driver, device)
+		if !errors.Is(err, ErrDeviceApprovalPending) || got != nil {
+			mt.Fatalf("預期回傳 ErrDeviceApprovalPending，實際為 device=%v err=%v", got, err)
+		}
+
+		cmd := findAndModifyCommand(mt)
+		if status := cmd.Lookup("update", "$set", "status").StringValue(); status != string(model.DriverDeviceStatusPending) {
+			mt.Fatalf("預期寫入 pending 狀態，實際為 %s", status)
+		}
+		if _, err := cmd.Lookup("update", "$set").Document().LookupErr("requested_at"); err != nil {
+			mt.Fatal("新設備預期記錄申請時間")
+		}
+		// 不可把併發登入時剛綁定的設備改回待核准
+		if ne := cmd.Lookup("query", "status", "$ne").StringValue(); ne != string(model.DriverDeviceStatusBound) {
+			mt.Fatalf("預期待核准更新排除已綁定設備，實際條件為 %s", ne)
+		}
+	})
+
+	mt.Run("重複登入待核准設備時保留原申請時間", func(mt *mtest.T) {
+		svc := newTestDriverDeviceService(mt)
+		pending := testDevice(model.DriverDeviceStatusPending)
+		mt.AddMockResponses(
+			mockCursor(mt, driverDevicesCollection, pending),
+			countResponse(1),
+			findAndModifyResponse(pending),
+		)
+
+		if _, err := svc.AuthorizeLoginDevice(context.Background(), driver, device); !errors.Is(err, ErrDeviceApprovalPending) {
+			mt.Fatalf("預期回傳 ErrDeviceApprovalPending，實際為 %v", err)
+		}
+
+		cmd := findAndModifyCommand(mt)
+		if _, err := cmd.Lookup("update", "$set").Document().LookupErr("requested_at"); err == nil {
+			mt.Fatal("已在待核准的設備不應更新申請時間")
+		}
+	})
+
+	mt.Run("兩台設備同時首次登入時另一台改為待核准", func(mt *mtest.T) {
+		svc := newTestDriverDeviceService(mt)
+		mt.AddMockResponses(
+			mockCursor(mt, driverDevicesCollection),
+			countResponse(0),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Name: "DuplicateKey", Message: "E11000 duplicate key error"}),
+			findAndModifyResponse(testDevice(model.DriverDeviceStatusPending)),
+		)
+
+		if _, err := svc.AuthorizeLoginDevice(context.Background(), driver, device); !errors.Is(err, ErrDeviceApprovalPending) {
+			mt.Fatalf("預期綁定衝突時回傳 ErrDeviceApprovalPending，實際為 %v", err)
+		}
+
+		findAndModifyCommand(mt)
+		cmd := findAndModifyCommand(mt)
+		if status := cmd.Lookup("update", "$set", "status").StringValue(); status != string(model.DriverDeviceStatusPending) {
+			mt.Fatalf("預期綁定衝突後寫入 pending 狀態，實際為 %s", status)
+		}
+	})
+
+	mt.Run("查詢設備失敗時不放行", func(mt *mtest.T) {
+		svc := newTestDriverDeviceService(mt)
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+
+		if _, err := svc.AuthorizeLoginDevice(context.Background(), driver, device); err == nil || errors.Is(err, ErrDeviceApprovalPending) {
+			mt.Fatalf("預期回傳查詢錯誤，實際為 %v", err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	driverLocationCollection       = "driver_locations"
+	defaultLocationHistoryInterval = 15 * time.Second
+	maxDriverTrackWindow           = 24 * time.Hour // 單次查詢軌跡的最長時間範圍
+	maxDriverTrackPoints           = 10000
+)
+
+// locationMark 司機最後一次寫入軌跡的時間與狀態，用於節流
+type locationMark struct {
+	at      time.Time
+	status  model.DriverStatus
+	orderID string
+}
+
+// DriverLocationService 司機位置歷史軌跡，節流寫入 driver_locations 並提供時間區間與訂單期間的軌跡查詢
+type DriverLocationService struct {
+	logger       zerolog.Logger
+	mongoDB      *infra.MongoDB
+	orderService *OrderService
+
+	mu           sync.Mutex
+	lastRecorded map[string]locationMark
+}
+
+func NewDriverLocationService(logger zerolog.Logger, mongoDB *infra.MongoDB, orderService *OrderService) *DriverLocationService {
+	return &DriverLocationService{
+		logger:       logger.With().Str("module", "driver_location_service").Logger(),
+		mongoDB:      mongoDB,
+		orderService: orderService,
+		lastRecorded: make(map[string]locationMark),
+	}
+}
+
+// historyInterval 位置歷史最短寫入間隔，未設定時使用預設值
+func historyInterval() time.Duration {
+	interval := time.Duration(infra.AppConfig.DriverLocation.HistoryIntervalSecs) * time.Second
+	if interval <= 0 {
+		return defaultLocationHistoryInterval
+	}
+	return interval
+}
+
+// Record 節流寫入司機位置軌跡：距上次寫入超過最短間隔，或司機狀態、執行中訂單有變更時才寫入
+// 寫入失敗只記錄日誌，不影響位置更新
+func (s *DriverLocationService) Record(ctx context.Context, driver *model.DriverInfo, lat, lng string) {
+	latitude, errLat := strconv.ParseFloat(lat, 64)
+	longitude, errLng := strconv.ParseFloat(lng, 64)
+	if errLat != nil || errLng != nil || (latitude == 0 && longitude == 0) {
+		return
+	}
+
+	driverID := driver.ID.Hex()
+	orderID := ""
+	if driver.CurrentOrderId != nil {
+		orderID = *driver.CurrentOrderId
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	last, ok := s.lastRecorded[driverID]
+	if ok && now.Sub(last.at) < historyInterval() && last.status == driver.Status && last.orderID == orderID {
+		s.mu.Unlock()
+		return
+	}
+	s.lastRecorded[driverID] = locationMark{at: now, status: driver.Status, orderID: orderID}
+	s.mu.Unlock()
+
+	point := &model.DriverLocation{
+		DriverID:   driverID,
+		Lat:        latitude,
+		Lng:        longitude,
+		Status:     driver.Status,
+		OrderID:    orderID,
+		RecordedAt: now,
+	}
+	if _, err := s.mongoDB.GetCollection(driverLocationCollection).InsertOne(ctx, point); err != nil {
+		s.logger.Error().Err(err).Str("driver_id", driverID).Msg("寫入司機位置歷史失敗")
+		// 寫入失敗時清除節流記錄，下次位置更新再嘗試
+		s.mu.Lock()
+		delete(s.lastRecorded, driverID)
+		s.mu.Unlock()
+	}
+}
+
+// GetTrack 查詢司機在時間區間內的軌跡，依記錄時間排序
+func (s *DriverLocationService) GetTrack(ctx context.Context, driverID string, from, to time.Time) ([]*model.DriverLocation, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("開始時間必須早於結束時間")
+	}
+	if to.Sub(from) > maxDriverTrackWindow {
+		return nil, fmt.Errorf("查詢時間範圍不可超過%d小時", int(maxDriverTrackWindow.Hours()))
+	}
+
+	filter := bson.M{
+		"driver_id":   driverID,
+		"recorded_at": bson.M{"$gte": from, "$lte": to},
+	}
+	opts := options.Find().SetSort(bson.M{"recorded_at": 1}).SetLimit(maxDriverTrackPoints)
+
+	cursor, err := s.mongoDB.GetCollection(driverLocationCollection).Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Str("driver_id", driverID).Msg("查詢司機位置歷史失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	points := []*model.DriverLocation{}
+	if err := cursor.All(ctx, &points); err != nil {
+		s.logger.Error().Err(err).Str("driver_id", driverID).Msg("解析司機位置歷史失敗")
+		return nil, err
+	}
+	return points, nil
+}
+
+// GetOrderTrack 查詢訂單執行期間（接單到完成，未完成時到目前為止）接單司機的軌跡
+func (s *DriverLocationService) GetOrderTrack(ctx context.Context, orderID string) (*model.Order, []*model.DriverLocation, error) {
+	order, err := s.orderService.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.Driver.AssignedDriver == "" {
+		return nil, nil, fmt.Errorf("訂單 %s 尚未有司機接單", order.ShortID)
+	}
+
+	from := order.AcceptanceTime
+	if from == nil {
+		from = order.CreatedAt
+	}
+	if from == nil {
+		return nil, nil, fmt.Errorf("訂單 %s 缺少接單時間", order.ShortID)
+	}
+
+	to := time.Now()
+	if order.CompletionTime != nil {
+		to = *order.CompletionTime
+	}
+	// 長時間未完成的訂單只取接單後的最長查詢範圍
+	if to.Sub(*from) > maxDriverTrackWindow {
+		to = from.Add(maxDriverTrackWindow)
+	}
+
+	points, err := s.GetTrack(ctx, order.Driver.AssignedDriver, *from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, points, nil
+}
+
+// TripDistanceKm 以軌跡點累計司機在時間區間內的行駛距離（直線段加總），軌跡點少於兩點時 ok 為 false
+func (s *DriverLocationService) TripDistanceKm(ctx context.Context, driverID string, from, to time.Time) (distanceKm float64, ok bool, err error) {
+	if to.Sub(from) > maxDriverTrackWindow {
+		from = to.Add(-maxDriverTrackWindow)
+	}
+	points, err := s.GetTrack(ctx, driverID, from, to)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(points) < 2 {
+		return 0, false, nil
+	}
+	for i := 1; i < len(points); i++ {
+		distanceKm += utils.Haversine(points[i-1].Lat, points[i-1].Lng, points[i].Lat, points[i].Lng)
+	}
+	return distanceKm, true, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"right-backend/auth"
 	"right-backend/data-models/admin"
 	driverModels "right-backend/data-models/driver"
 	"right-backend/infra"
@@ -15,7 +16,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -27,8 +27,6 @@ import (
 type DriverService struct {
 	logger                 zerolog.Logger
 	mongoDB                *infra.MongoDB
-	jwtSecretKey           string
-	jwtExpiresHours        int
 	orderService           *OrderService
 	googleService          *GoogleMapService
 	crawlerService         *CrawlerService
@@ -36,13 +34,14 @@ type DriverService struct {
 	blacklistService       *DriverBlacklistService
 	eventManager           *infra.RedisEventManager // 事件管理器
 	notificationService    *NotificationService     // 統一通知服務
+	locationService        *DriverLocationService   // 位置歷史軌跡
+	tariffService          *TariffService           // 車資計算
+	sessionService         *AuthSessionService      // 登入 session
 }
 
 func NewDriverService(
 	logger zerolog.Logger,
 	mongoDB *infra.MongoDB,
-	jwtSecretKey string,
-	jwtExpiresHours int,
 	orderService *OrderService,
 	googleService *GoogleMapService,
 	crawlerService *CrawlerService,
@@ -54,8 +53,6 @@ func NewDriverService(
 	return &DriverService{
 		logger:                 logger.With().Str("module", "driver_service").Logger(),
 		mongoDB:                mongoDB,
-		jwtSecretKey:           jwtSecretKey,
-		jwtExpiresHours:        jwtExpiresHours,
 		orderService:           orderService,
 		googleService:          googleService,
 		crawlerService:         crawlerService,
@@ -66,6 +63,31 @@ func NewDriverService(
 	}
 }
 
+// SetTariffService 設定車資計算服務，設定後完成訂單時依費率計算實際車資
+func (s *DriverService) SetTariffService(tariffService *TariffService) {
+	s.tariffService = tariffService
+}
+
+// SetAuthSessionService 設定登入 session 服務，設定後移出車隊或管理員重設密碼時強制司機登出
+func (s *DriverService) SetAuthSessionService(sessionService *AuthSessionService) {
+	s.sessionService = sessionService
+}
+
+// revokeDriverSessions 強制司機登出所有裝置，失敗時只記錄錯誤
+func (s *DriverService) revokeDriverSessions(ctx context.Context, driverID string, reason model.SessionRevokeReason) {
+	if s.sessionService == nil {
+		return
+	}
+	if _, err := s.sessionService.RevokeSubjectSessions(ctx, model.TokenTypeDriver, driverID, reason); err != nil {
+		s.logger.Error().Str("driver_id", driverID).Str("reason", string(reason)).Err(err).Msg("強制司機登出失敗")
+	}
+}
+
+// SetLocationService 設定位置歷史軌跡服務，設定後位置更新會節流寫入軌跡
+func (s *DriverService) SetLocationService(locationService *DriverLocationService) {
+	s.locationService = locationService
+}
+
 func (s *DriverService) CreateDriver(ctx context.Context, driver *model.DriverInfo) (*model.DriverInfo, error) {
 	driver.ID = primitive.NewObjectID()
 	now := utils.NowUTC()
@@ -84,8 +106,14 @@ func (s *DriverService) CreateDriver(ctx context.Context, driver *model.DriverIn
 	}
 	driver.IsOnline = false // 初始為離線，需司機手動上線
 
+	hashed, err := auth.HashPassword(driver.Password)
+	if err != nil {
+		return nil, fmt.Errorf("密碼雜湊失敗: %w", err)
+	}
+	driver.Password = hashed
+
 	collection := s.mongoDB.GetCollection("drivers")
-	_, err := collection.InsertOne(ctx, driver)
+	_, err = collection.InsertOne(ctx, driver)
 	if err != nil {
 		s.logger.Error().
 			Str("司機帳號", driver.Account).
@@ -191,13 +219,18 @@ func (s *DriverService) UpdateDriverLocation(ctx context.Context, id string, lat
 		infra.AddEvent(span, "preparing_update_data")
 		collection := s.mongoDB.GetCollection("drivers")
 		filter := bson.M{"_id": objectID}
-		update := bson.M{
-			"$set": bson.M{
-				"lat":         lat,
-				"lng":         lng,
-				"last_online": time.Now(),
-				"updated_at":  time.Now(),
-			},
+		setFields := bson.M{
+			"lat":         lat,
+			"lng":         lng,
+			"last_online": time.Now(),
+			"updated_at":  time.Now(),
+		}
+		// 同步維護 GeoJSON 位置供 2dsphere 查詢；座標無效時移除，避免以舊位置派單
+		update := bson.M{"$set": setFields}
+		if location, ok := model.ParseGeoPoint(lat, lng); ok {
+			setFields["location"] = location
+		} else {
+			update["$unset"] = bson.M{"location": ""}
 		}
 
 		// 執行數據庫更新
@@ -217,6 +250,9 @@ func (s *DriverService) UpdateDriverLocation(ctx context.Context, id string, lat
 		}
 
 		infra.AddEvent(span, "driver_location_updated_successfully")
+		if s.locationService != nil {
+			s.locationService.Record(ctx, &driverInfo, lat, lng)
+		}
 		infra.SetAttributes(span,
 			infra.AttrString("driver.name", driverInfo.Name),
 			infra.AttrString("driver.car_plate", driverInfo.CarPlate),
@@ -473,9 +509,9 @@ func (s *DriverService) UpdateFCMToken(ctx context.Context, driverID string, fcm
 	return &updatedDriver, nil
 }
 
-func (s *DriverService) Login(ctx context.Context, account, password, deviceModelName, deviceDeviceName, deviceBrand, deviceManufacturer, deviceAppVersion string) (*model.DriverInfo, string, error) {
+// Login 驗證帳號密碼並更新設備資訊，token 由 AuthSessionService 簽發
+func (s *DriverService) Login(ctx context.Context, account, password, deviceModelName, deviceDeviceName, deviceBrand, deviceManufacturer, deviceAppVersion string) (*model.DriverInfo, error) {
 	var driver *model.DriverInfo
-	var tokenString string
 
 	err := infra.WithSpan(ctx, "driver_service.login", func(ctx context.Context, span trace.Span) error {
 		// 設置基本屬性
@@ -520,7 +556,8 @@ func (s *DriverService) Login(ctx context.Context, account, password, deviceMode
 
 		// 檢查密碼是否正確
 		infra.AddEvent(span, "validating_password")
-		if driverInfo.Password != password {
+		passwordOK, needsRehash := auth.VerifyPassword(driverInfo.Password, password)
+		if !passwordOK {
 			infra.AddEvent(span, "password_validation_failed")
 			infra.SetAttributes(span, infra.AttrErrorType("invalid_password"))
 			s.logger.Warn().
@@ -540,36 +577,6 @@ func (s *DriverService) Login(ctx context.Context, account, password, deviceMode
 			return fmt.Errorf("帳號未啟用")
 		}
 
-		// 生成 JWT token
-		infra.AddEvent(span, "generating_jwt_token")
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"driver_id": driverInfo.ID.Hex(),
-			"account":   driverInfo.Account,
-			"name":      driverInfo.Name,
-			"car_plate": driverInfo.CarPlate,
-			"fleet":     string(driverInfo.Fleet),
-			"car_model": driverInfo.CarModel,
-			"type":      string(model.TokenTypeDriver),
-			"exp":       time.Now().Add(time.Hour * time.Duration(s.jwtExpiresHours)).Unix(),
-		})
-
-		var tokenErr error
-		tokenString, tokenErr = token.SignedString([]byte(s.jwtSecretKey))
-		if tokenErr != nil {
-			infra.AddEvent(span, "jwt_generation_failed")
-			infra.SetAttributes(span, infra.AttrErrorType("jwt_generation_error"))
-			s.logger.Error().
-				Str("司機編號", driverInfo.ID.Hex()).
-				Str("司機帳號", driverInfo.Account).
-				Str("車隊名稱", string(driverInfo.Fleet)).
-				Str("車牌號碼", driverInfo.CarPlate).
-				Str("錯誤原因", tokenErr.Error()).
-				Msg("司機 JWT 令牌生成失敗")
-			return tokenErr
-		}
-
-		infra.AddEvent(span, "jwt_token_generated")
-
 		// 更新設備資訊（如果提供的話）
 		infra.AddEvent(span, "updating_device_info")
 		updateFields := bson.M{
@@ -592,6 +599,18 @@ func (s *DriverService) Login(ctx context.Context, account, password, deviceMode
 			updateFields["device_app_version"] = deviceAppVersion
 		}
 
+		// 舊資料為明文或雜湊強度不足時，登入成功後升級為目前的雜湊
+		if needsRehash {
+			if hashed, hashErr := auth.HashPassword(password); hashErr == nil {
+				updateFields["password"] = hashed
+			} else {
+				s.logger.Warn().
+					Str("司機編號", driverInfo.ID.Hex()).
+					Str("錯誤原因", hashErr.Error()).
+					Msg("司機密碼雜湊升級失敗")
+			}
+		}
+
 		// 更新司機資料
 		_, updateErr := collection.UpdateOne(ctx,
 			bson.M{"_id": driverInfo.ID},
@@ -632,7 +651,7 @@ func (s *DriverService) Login(ctx context.Context, account, password, deviceMode
 	)
 
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	s.logger.Debug().
@@ -643,7 +662,7 @@ func (s *DriverService) Login(ctx context.Context, account, password, deviceMode
 		Str("車牌號碼", driver.CarPlate).
 		Msg("司機登入成功 - 服務層驗證完成")
 
-	return driver, tokenString, nil
+	return driver, nil
 }
 
 // getPreRedisData 從 Redis 獲取預計算的距離時間數據
@@ -815,14 +834,35 @@ func (s *DriverService) AcceptOrder(ctx context.Context, driver *model.DriverInf
 	// 步驟3: 構建司機物件（包含 FCM 發送時間）
 	driverInfoForOrder := s.buildDriverObject(driver, adjustMins, distanceKm, estPickupMins, estPickupTimeStr, fcmSentTime)
 
-	// 步驟4: 原子性訂單更新（CAS操作）
-	matched, err := s.orderService.AcceptOrderAction(ctx, orderID, driverInfoForOrder, model.OrderStatusEnroute, &requestTime)
-	if err != nil {
-		s.logger.Error().Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Err(err).Msg("司機接單資料庫操作失敗")
-		return 0, 0, "", "", "", fmt.Errorf("接單失敗")
+	// 步驟3.5: Redis 原子性搶單（搶單模式下多位司機同時收到通知，由 Redis 決定唯一得標者）
+	redisClaimed := false
+	if s.eventManager != nil {
+		success, reason, atomicErr := s.eventManager.AtomicAcceptOrder(ctx, driver.ID.Hex(), orderID)
+		if atomicErr == nil {
+			if !success && strings.HasPrefix(reason, "order_taken_by:") {
+				s.logger.Info().
+					Str("order_id", orderID).
+					Str("driver_id", driver.ID.Hex()).
+					Str("reason", reason).
+					Msg("訂單已被其他司機搶先接走")
+				return 0, 0, "", "", "", fmt.Errorf("接單失敗，訂單已被其他司機接走")
+			}
+			redisClaimed = success
+		}
+		// Redis 失敗或司機非通知中（例如超時後接單、手動派單）時，仍以 MongoDB CAS 為準
 	}
 
-	if !matched {
+	// 步驟4: 原子性訂單更新（CAS操作）
+	matched, err := s.orderService.AcceptOrderAction(ctx, orderID, driverInfoForOrder, model.OrderStatusEnroute, &requestTime)
+	if err != nil || !matched {
+		if err != nil {
+			s.logger.Error().Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Err(err).Msg("司機接單資料庫操作失敗")
+		}
+		// Redis 已標記司機接單但資料庫更新失敗，還原司機狀態避免卡在忙碌，並釋放得標紀錄讓其他司機可以接單
+		if redisClaimed {
+			_ = s.eventManager.ClearDriverStateAfterComplete(ctx, driver.ID.Hex())
+			_ = s.eventManager.ReleaseOrderWinner(ctx, orderID, driver.ID.Hex())
+		}
 		return 0, 0, "", "", "", fmt.Errorf("接單失敗")
 	}
 
@@ -1322,28 +1362,107 @@ func (s *DriverService) CompleteOrder(ctx context.Context, driver *model.DriverI
 		s.logger.Error().Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Err(err).Msg("完成訂單失敗，訂單不存在")
 		return "", "", fmt.Errorf("訂單不存在")
 	}
+	if _, err := model.CheckOrderTransition(order.Status, model.OrderStatusCompleted, model.OrderActorDriver); err != nil {
+		s.logger.Warn().Err(err).Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Msg("完成訂單失敗，訂單狀態不允許")
+		return "", "", err
+	}
 
 	// 步驟2: 原子性更新訂單狀態和完成信息
 	order.Driver.Duration = duration
 	order.CompletionTime = &requestTime
+	if s.tariffService != nil {
+		order.FinalFare = s.tariffService.FinalizeFare(ctx, order, requestTime, duration)
+	}
 	_, err = s.orderService.UpdateOrder(ctx, order)
 	if err != nil {
 		s.logger.Error().Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Err(err).Msg("更新訂單資訊失敗")
 		return "", "", fmt.Errorf("更新訂單失敗")
 	}
 
-	// 步驟3: 優先更新司機狀態（關鍵，影響派單邏輯，防止被分派新單）
+	// 步驟3: 優先釋放司機（關鍵，影響派單邏輯，防止被分派新單）
+	if err := s.ReleaseDriverAfterOrder(ctx, driver, order); err != nil {
+		return "", "", err
+	}
+
+	// 步驟4: 更新訂單狀態為完成（不會觸發舊通知機制，統一由NotificationService處理）
+	_, err = s.orderService.UpdateOrderStatus(ctx, orderID, model.OrderStatusCompleted, model.OrderActorDriver)
+	if err != nil {
+		s.logger.Error().Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Err(err).Msg("更新完成訂單狀態失敗")
+		return "", "", fmt.Errorf("更新訂單狀態失敗")
+	}
+
+	// 步驟5: 異步更新司機完成訂單數（優化效能，不阻塞主流程）
+	go func() {
+		if err := s.IncrementCompletedCount(context.Background(), driver.ID.Hex()); err != nil {
+			s.logger.Error().Err(err).
+				Str("order_id", orderID).
+				Str("driver_id", driver.ID.Hex()).
+				Msg("更新司機完成訂單數失敗")
+		}
+	}()
+
+	// 步驟6: 異步處理所有通知（使用 NotificationService）
+	if s.notificationService != nil {
+		go func() {
+			if notifyErr := s.notificationService.NotifyOrderCompleted(context.Background(), orderID, driver); notifyErr != nil {
+				s.logger.Error().Err(notifyErr).
+					Str("order_id", orderID).
+					Str("driver_id", driver.ID.Hex()).
+					Msg("統一通知服務處理失敗")
+			}
+		}()
+	}
+
+	// 步驟7: 異步處理非關鍵操作
+	go func() {
+		bgCtx := context.Background()
+
+		// 添加完成訂單日誌（需要查詢 rounds，但這是異步的）
+		currentRounds := 1
+		if order.Rounds != nil {
+			currentRounds = *order.Rounds
+		}
+		if err := s.orderService.AddOrderLog(bgCtx, orderID, model.OrderLogActionOrderCompleted,
+			string(driver.Fleet), driver.Name, driver.CarPlate, driver.ID.Hex(),
+			fmt.Sprintf("訂單完成，用時: %d秒", duration), currentRounds); err != nil {
+			s.logger.Error().
+				Str("訂單編號", orderID).
+				Str("錯誤原因", err.Error()).
+				Msg("新增完成訂單記錄失敗")
+		}
+	}()
+
+	s.logger.Info().
+		Str("司機編號", driver.ID.Hex()).
+		Str("司機姓名", driver.Name).
+		Str("車牌號碼", driver.CarPlate).
+		Str("訂單編號", orderID).
+		Int("用時_秒", duration).
+		Msg("司機已完成訂單")
+
+	// 返回最終狀態值
+	driverStatus := string(model.DriverStatusIdle)
+	orderStatus := string(model.OrderStatusCompleted)
+
+	return driverStatus, orderStatus, nil
+}
+
+// ReleaseDriverAfterOrder 訂單結束後將司機重置為閒置，清除目前訂單與預約狀態，讓司機可以接新單
+func (s *DriverService) ReleaseDriverAfterOrder(ctx context.Context, driver *model.DriverInfo, order *model.Order) error {
+	orderID := order.ID.Hex()
+
+	// 優先更新司機狀態（關鍵，影響派單邏輯，防止被分派新單）
 	if err := s.UpdateDriverStatusType(ctx, driver.ID.Hex(), model.DriverStatusIdle); err != nil {
 		s.logger.Error().Err(err).
 			Str("order_id", orderID).
 			Str("driver_id", driver.ID.Hex()).
 			Msg("更新司機狀態失敗")
-		return "", "", fmt.Errorf("更新司機狀態失敗: %w", err)
+		return fmt.Errorf("更新司機狀態失敗: %w", err)
 	}
 
-	// 步驟3.2: 根據訂單類型清除對應的訂單ID
+	// 根據訂單類型清除對應的訂單ID
 	if order.Type == model.OrderTypeScheduled {
-		// 預約單：清除 CurrentOrderScheduleId (在步驟3.5中處理)
+		// 預約單：清除 CurrentOrderScheduleId (於下方清理預約狀態時處理)
 	} else {
 		// 即時單：清除 CurrentOrderId
 		if err := s.UpdateDriverCurrentOrderId(ctx, driver.ID.Hex(), ""); err != nil {
@@ -1360,7 +1479,7 @@ func (s *DriverService) CompleteOrder(ctx context.Context, driver *model.DriverI
 		}
 	}
 
-	// 步驟3.3: 同步清除 Redis driver_state 的 current_order_id，讓司機可以接新單
+	// 同步清除 Redis driver_state 的 current_order_id，讓司機可以接新單
 	if s.eventManager != nil {
 		if err := s.eventManager.ClearDriverStateAfterComplete(ctx, driver.ID.Hex()); err != nil {
 			s.logger.Error().Err(err).
@@ -1371,7 +1490,7 @@ func (s *DriverService) CompleteOrder(ctx context.Context, driver *model.DriverI
 		}
 	}
 
-	// 步驟3.5: 如果完成的是預約單，清理司機的預約狀態
+	// 如果完成的是預約單，清理司機的預約狀態
 	if order.Type == model.OrderTypeScheduled {
 		if err := s.ResetDriverScheduledOrder(ctx, driver.ID.Hex()); err != nil {
 			s.logger.Error().Err(err).
@@ -1386,52 +1505,62 @@ func (s *DriverService) CompleteOrder(ctx context.Context, driver *model.DriverI
 				Msg("預約單完成，已清理司機預約狀態")
 		}
 	}
+	return nil
+}
+
+// ReportPassengerNoShow 司機抵達後等候超過車隊規定時間乘客仍未出現，以乘客未到完成訂單並套用乘客未到費用
+func (s *DriverService) ReportPassengerNoShow(ctx context.Context, driver *model.DriverInfo, orderID string, requestTime time.Time) (string, string, *model.CancellationFee, error) {
+	order, err := s.orderService.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("訂單不存在")
+	}
+	if order.Driver.AssignedDriver != driver.ID.Hex() {
+		return "", "", nil, fmt.Errorf("此訂單不屬於該司機")
+	}
 
-	// 步驟4: 更新訂單狀態為完成（不會觸發舊通知機制，統一由NotificationService處理）
-	_, err = s.orderService.UpdateOrderStatus(ctx, orderID, model.OrderStatusCompleted)
+	// 步驟1: 依車隊取消政策檢查等候時間並計算費用
+	fee, err := s.orderService.PassengerNoShowFee(order, requestTime)
 	if err != nil {
-		s.logger.Error().Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Err(err).Msg("更新完成訂單狀態失敗")
-		return "", "", fmt.Errorf("更新訂單狀態失敗")
+		return "", "", nil, err
 	}
+	fee.ChargedBy = driver.Name
 
-	// 步驟5: 異步更新司機完成訂單數（優化效能，不阻塞主流程）
+	// 步驟2: 原子性更新訂單為完成（僅限司機抵達狀態，避免與乘客取消同時發生）
+	updatedOrder, err := s.orderService.CompleteAsNoShow(ctx, orderID, driver.ID.Hex(), fee, requestTime)
+	if err != nil {
+		s.logger.Error().Err(err).Str("order_id", orderID).Str("driver_id", driver.ID.Hex()).Msg("乘客未到更新訂單失敗")
+		return "", "", nil, err
+	}
+
+	// 步驟3: 釋放司機
+	if err := s.ReleaseDriverAfterOrder(ctx, driver, updatedOrder); err != nil {
+		return "", "", nil, err
+	}
+
+	// 步驟4: 異步處理通知與訂單日誌
 	go func() {
-		if err := s.IncrementCompletedCount(context.Background(), driver.ID.Hex()); err != nil {
-			s.logger.Error().Err(err).
-				Str("order_id", orderID).
-				Str("driver_id", driver.ID.Hex()).
-				Msg("更新司機完成訂單數失敗")
-		}
-	}()
+		bgCtx := context.Background()
 
-	// 步驟6: 異步處理所有通知（使用 NotificationService）
-	if s.notificationService != nil {
-		go func() {
-			if notifyErr := s.notificationService.NotifyOrderCompleted(context.Background(), orderID, driver); notifyErr != nil {
+		if s.notificationService != nil {
+			if notifyErr := s.notificationService.NotifyOrderCompleted(bgCtx, orderID, driver); notifyErr != nil {
 				s.logger.Error().Err(notifyErr).
 					Str("order_id", orderID).
 					Str("driver_id", driver.ID.Hex()).
 					Msg("統一通知服務處理失敗")
 			}
-		}()
-	}
-
-	// 步驟7: 異步處理非關鍵操作
-	go func() {
-		bgCtx := context.Background()
+		}
 
-		// 添加完成訂單日誌（需要查詢 rounds，但這是異步的）
 		currentRounds := 1
-		if order.Rounds != nil {
-			currentRounds = *order.Rounds
+		if updatedOrder.Rounds != nil {
+			currentRounds = *updatedOrder.Rounds
 		}
-		if err := s.orderService.AddOrderLog(bgCtx, orderID, model.OrderLogActionOrderCompleted,
-			string(driver.Fleet), driver.Name, driver.CarPlate, driver.ID.Hex(),
-			fmt.Sprintf("訂單完成，用時: %d秒", duration), currentRounds); err != nil {
+		details := fmt.Sprintf("%s，乘客未到費用 %d 元", fee.Rule, fee.Amount)
+		if err := s.orderService.AddOrderLog(bgCtx, orderID, model.OrderLogActionNoShow,
+			string(driver.Fleet), driver.Name, driver.CarPlate, driver.ID.Hex(), details, currentRounds); err != nil {
 			s.logger.Error().
 				Str("訂單編號", orderID).
 				Str("錯誤原因", err.Error()).
-				Msg("新增完成訂單記錄失敗")
+				Msg("新增乘客未到記錄失敗")
 		}
 	}()
 
@@ -1440,14 +1569,10 @@ func (s *DriverService) CompleteOrder(ctx context.Context, driver *model.DriverI
 		Str("司機姓名", driver.Name).
 		Str("車牌號碼", driver.CarPlate).
 		Str("訂單編號", orderID).
-		Int("用時_秒", duration).
-		Msg("司機已完成訂單")
+		Int("乘客未到費用", fee.Amount).
+		Msg("司機回報乘客未到，訂單已完成")
 
-	// 返回最終狀態值
-	driverStatus := string(model.DriverStatusIdle)
-	orderStatus := string(model.OrderStatusCompleted)
-
-	return driverStatus, orderStatus, nil
+	return string(model.DriverStatusIdle), string(model.OrderStatusCompleted), fee, nil
 }
 
 func (s *DriverService) PickUpCustomer(ctx context.Context, driver *model.DriverInfo, orderID string, hasMeterJump bool, requestTime time.Time) (string, string, error) {
@@ -1473,6 +1598,13 @@ func (s *DriverService) PickUpCustomer(ctx context.Context, driver *model.Driver
 		return "", "", fmt.Errorf("更新訂單狀態失敗: %w", err)
 	}
 
+	// 多點行程：依上車時間推算各停靠點的預估抵達時間（需在通知前完成，卡片才會顯示）
+	if len(updatedOrder.Waypoints) > 0 {
+		if err := s.orderService.ScheduleWaypointETAs(ctx, updatedOrder, requestTime); err != nil {
+			s.logger.Warn().Err(err).Str("order_id", orderID).Msg("推算停靠點預估抵達時間失敗")
+		}
+	}
+
 	// 步驟3: 異步處理所有通知（使用 NotificationService）
 	if s.notificationService != nil {
 		go func() {
@@ -1523,6 +1655,52 @@ func (s *DriverService) PickUpCustomer(ctx context.Context, driver *model.Driver
 	return driverStatus, orderStatus, nil
 }
 
+// UpdateWaypointStatus 司機回報多點行程的停靠點抵達或完成，並更新 Discord/LINE 訂單卡片
+func (s *DriverService) UpdateWaypointStatus(ctx context.Context, driver *model.DriverInfo, orderID string, seq int, status model.WaypointStatus, requestTime time.Time) (*model.Order, error) {
+	updatedOrder, err := s.orderService.UpdateWaypointStatus(ctx, orderID, driver.ID.Hex(), seq, status, requestTime)
+	if err != nil {
+		s.logger.Warn().Err(err).
+			Str("order_id", orderID).
+			Str("driver_id", driver.ID.Hex()).
+			Int("seq", seq).
+			Str("status", string(status)).
+			Msg("更新停靠點狀態失敗")
+		return nil, err
+	}
+
+	if s.notificationService != nil {
+		go func() {
+			if notifyErr := s.notificationService.NotifyWaypointUpdated(context.Background(), orderID, driver); notifyErr != nil {
+				s.logger.Error().Err(notifyErr).Str("order_id", orderID).Msg("停靠點通知處理失敗")
+			}
+		}()
+	}
+
+	go func() {
+		action := model.OrderLogActionWaypointArrive
+		if status == model.WaypointStatusCompleted {
+			action = model.OrderLogActionWaypointDone
+		}
+		currentRounds := 1
+		if updatedOrder.Rounds != nil {
+			currentRounds = *updatedOrder.Rounds
+		}
+		address := updatedOrder.Waypoints[seq-1].DisplayAddress()
+		if err := s.orderService.AddOrderLog(context.Background(), orderID, action,
+			string(driver.Fleet), driver.Name, driver.CarPlate, driver.ID.Hex(), fmt.Sprintf("第%d站 %s", seq, address), currentRounds); err != nil {
+			s.logger.Error().Err(err).Str("訂單編號", orderID).Msg("新增停靠點記錄失敗")
+		}
+	}()
+
+	s.logger.Info().
+		Str("訂單編號", orderID).
+		Str("司機姓名", driver.Name).
+		Int("停靠點", seq).
+		Str("狀態", string(status)).
+		Msg("停靠點狀態已回報")
+	return updatedOrder, nil
+}
+
 func (s *DriverService) GetOrdersByDriverID(ctx context.Context, driverID string, pageNum, pageSize int) ([]*model.Order, int64, error) {
 	orders, total, err := s.orderService.GetOrdersByDriverID(ctx, driverID, pageNum, pageSize)
 	if err != nil {
@@ -1561,7 +1739,11 @@ func (s *DriverService) UpdateDriverProfile(ctx context.Context, driverID string
 		updateFields["car_color"] = *updateData.CarColor
 	}
 	if updateData.NewPassword != nil {
-		updateFields["password"] = *updateData.NewPassword
+		hashed, err := auth.HashPassword(*updateData.NewPassword)
+		if err != nil {
+			return nil, fmt.Errorf("密碼雜湊失敗: %w", err)
+		}
+		updateFields["password"] = hashed
 	}
 
 	if len(updateFields) == 0 {
@@ -1609,7 +1791,11 @@ func (s *DriverService) AdminUpdateDriverProfile(ctx context.Context, driverID s
 		updateFields["car_color"] = *updateData.Body.CarColor
 	}
 	if updateData.Body.NewPassword != nil {
-		updateFields["password"] = *updateData.Body.NewPassword
+		hashed, err := auth.HashPassword(*updateData.Body.NewPassword)
+		if err != nil {
+			return nil, fmt.Errorf("密碼雜湊失敗: %w", err)
+		}
+		updateFields["password"] = hashed
 	}
 	if updateData.Body.Fleet != nil {
 		updateFields["fleet"] = *updateData.Body.Fleet
@@ -1620,6 +1806,9 @@ func (s *DriverService) AdminUpdateDriverProfile(ctx context.Context, driverID s
 	if updateData.Body.IsApproved != nil {
 		updateFields["is_approved"] = *updateData.Body.IsApproved
 	}
+	if updateData.Body.Tags != nil {
+		updateFields["tags"] = updateData.Body.Tags
+	}
 
 	if len(updateFields) == 0 {
 		return s.GetDriverByID(ctx, driverID)
@@ -1639,6 +1828,11 @@ func (s *DriverService) AdminUpdateDriverProfile(ctx context.Context, driverID s
 		return nil, fmt.Errorf("管理員更新司機資料失敗 (Admin update driver profile failed): %w", err)
 	}
 
+	// 管理員重設密碼後，已登入的裝置需以新密碼重新登入
+	if updateData.Body.NewPassword != nil {
+		s.revokeDriverSessions(ctx, driverID, model.SessionRevokePasswordChanged)
+	}
+
 	return &updatedDriver, nil
 }
 
@@ -1666,6 +1860,8 @@ func (s *DriverService) RemoveDriverFromFleet(ctx context.Context, driverID stri
 		return nil, fmt.Errorf("移除司機車隊失敗 (Remove driver from fleet failed): %w", err)
 	}
 
+	s.revokeDriverSessions(ctx, driverID, model.SessionRevokeRemovedFromFleet)
+
 	return &updatedDriver, nil
 }
 
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	fleetCollection     = "fleets"
+	fleetReloadInterval = 1 * time.Minute // Redis 事件遺失時的定期重新載入間隔
+)
+
+// FleetService 管理車隊登錄資料（客群前綴、跨車隊派單矩陣、車隊設定），並在記憶體中快取供建單與派單即時讀取
+// fleets 集合為空時使用 model.DefaultFleets
+type FleetService struct {
+	logger       zerolog.Logger
+	mongoDB      *infra.MongoDB
+	eventManager *infra.RedisEventManager
+
+	mu     sync.RWMutex
+	fleets []*model.Fleet
+}
+
+func NewFleetService(logger zerolog.Logger, mongoDB *infra.MongoDB, eventManager *infra.RedisEventManager) *FleetService {
+	return &FleetService{
+		logger:       logger.With().Str("module", "fleet_service").Logger(),
+		mongoDB:      mongoDB,
+		eventManager: eventManager,
+		fleets:       model.DefaultFleets(),
+	}
+}
+
+// GetFleet 取得車隊登錄資料（讀取快取），未登錄時回傳 nil
+func (s *FleetService) GetFleet(code model.FleetType) *model.Fleet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, fleet := range s.fleets {
+		if fleet.Code == code {
+			copied := *fleet
+			return &copied
+		}
+	}
+	return nil
+}
+
+// FleetOrUnregistered 取得車隊登錄資料，未登錄時回傳只派給本車隊司機的替代設定
+func (s *FleetService) FleetOrUnregistered(code model.FleetType) *model.Fleet {
+	if fleet := s.GetFleet(code); fleet != nil {
+		return fleet
+	}
+	return model.UnregisteredFleet(code)
+}
+
+// EnabledFleets 列出所有啟用中的車隊（讀取快取）
+func (s *FleetService) EnabledFleets() []*model.Fleet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fleets := make([]*model.Fleet, 0, len(s.fleets))
+	for _, fleet := range s.fleets {
+		if fleet.Enabled {
+			copied := *fleet
+			fleets = append(fleets, &copied)
+		}
+	}
+	return fleets
+}
+
+// ResolveFleet 決定訂單所屬車隊：有指定車隊時驗證是否啟用，
+// 否則依客群前綴（最長前綴優先）判斷，都不符合時使用預設車隊
+func (s *FleetService) ResolveFleet(fleetCode, customerGroup string) (model.FleetType, error) {
+	return resolveFleetFrom(s.EnabledFleets(), fleetCode, customerGroup)
+}
+
+// resolveFleetFrom 從啟用中的車隊決定訂單所屬車隊
+func resolveFleetFrom(fleets []*model.Fleet, fleetCode, customerGroup string) (model.FleetType, error) {
+	if fleetCode != "" {
+		code := model.FleetType(strings.ToUpper(fleetCode))
+		for _, fleet := range fleets {
+			if fleet.Code == code && fleet.Enabled {
+				return code, nil
+			}
+		}
+		return "", fmt.Errorf("無效的車隊類型: %s", fleetCode)
+	}
+
+	customerGroup = strings.ToUpper(customerGroup)
+	var matched, defaultFleet *model.Fleet
+	longest := 0
+	for _, fleet := range fleets {
+		if !fleet.Enabled {
+			continue
+		}
+		if n := fleet.MatchCustomerGroup(customerGroup); n > longest {
+			matched, longest = fleet, n
+		}
+		if fleet.IsDefault && defaultFleet == nil {
+			defaultFleet = fleet
+		}
+	}
+	if matched != nil {
+		return matched.Code, nil
+	}
+	if defaultFleet != nil {
+		return defaultFleet.Code, nil
+	}
+	return "", fmt.Errorf("客群 %s 無對應車隊，且未設定預設車隊", customerGroup)
+}
+
+// CanDispatch 訂單車隊是否可派給司機所屬車隊
+func (s *FleetService) CanDispatch(orderFleet, driverFleet model.FleetType) bool {
+	return s.FleetOrUnregistered(orderFleet).CanDispatchTo(driverFleet)
+}
+
+// ListFleets 列出 fleets 集合中所有車隊
+func (s *FleetService) ListFleets(ctx context.Context) ([]*model.Fleet, error) {
+	cursor, err := s.mongoDB.GetCollection(fleetCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"code": 1}))
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢車隊失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	fleets := []*model.Fleet{}
+	if err := cursor.All(ctx, &fleets); err != nil {
+		s.logger.Error().Err(err).Msg("解析車隊失敗")
+		return nil, err
+	}
+	return fleets, nil
+}
+
+// CreateFleet 登錄新車隊
+func (s *FleetService) CreateFleet(ctx context.Context, fleet *model.Fleet, createdBy string) (*model.Fleet, error) {
+	if err := validateFleet(fleet); err != nil {
+		return nil, err
+	}
+
+	if err := s.seedDefaults(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := primitive.NewObjectID()
+	fleet.ID = &id
+	fleet.UpdatedBy = createdBy
+	fleet.CreatedAt = &now
+	fleet.UpdatedAt = &now
+
+	if _, err := s.mongoDB.GetCollection(fleetCollection).InsertOne(ctx, fleet); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("車隊 %s 已存在", fleet.Code)
+		}
+		s.logger.Error().Err(err).Str("fleet", string(fleet.Code)).Msg("新增車隊失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("fleet_id", id.Hex()).Str("fleet", string(fleet.Code)).Str("created_by", createdBy).Msg("車隊已新增")
+	s.notifyChanged(ctx, fleet.Code)
+	return fleet, nil
+}
+
+// UpdateFleet 更新車隊設定，車隊代碼不可修改
+func (s *FleetService) UpdateFleet(ctx context.Context, code model.FleetType, fleet *model.Fleet, updatedBy string) (*model.Fleet, error) {
+	fleet.Code = code
+	if err := validateFleet(fleet); err != nil {
+		return nil, err
+	}
+	if err := s.seedDefaults(ctx); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"display_name":            fleet.DisplayName,
+		"customer_group_prefixes": fleet.CustomerGroupPrefixes,
+		"dispatch_to_fleets":      fleet.DispatchToFleets,
+		"is_default":              fleet.IsDefault,
+		"settings":                fleet.Settings,
+		"enabled":                 fleet.Enabled,
+		"updated_by":              updatedBy,
+		"updated_at":              time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Fleet
+	err := s.mongoDB.GetCollection(fleetCollection).FindOneAndUpdate(ctx, bson.M{"code": code}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到車隊: %s", code)
+		}
+		s.logger.Error().Err(err).Str("fleet", string(code)).Msg("更新車隊失敗")
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("fleet", string(code)).
+		Str("updated_by", updatedBy).
+		Strs("customer_group_prefixes", updated.CustomerGroupPrefixes).
+		Bool("enabled", updated.Enabled).
+		Msg("車隊已更新")
+
+	s.notifyChanged(ctx, code)
+	return &updated, nil
+}
+
+// DeleteFleet 刪除車隊登錄資料，已有司機或訂單使用的車隊建議改為停用
+func (s *FleetService) DeleteFleet(ctx context.Context, code model.FleetType) error {
+	result, err := s.mongoDB.GetCollection(fleetCollection).DeleteOne(ctx, bson.M{"code": code})
+	if err != nil {
+		s.logger.Error().Err(err).Str("fleet", string(code)).Msg("刪除車隊失敗")
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("找不到車隊: %s", code)
+	}
+
+	s.logger.Info().Str("fleet", string(code)).Msg("車隊已刪除")
+	s.notifyChanged(ctx, code)
+	return nil
+}
+
+// seedDefaults fleets 集合為空時先寫入預設車隊，避免第一次新增或修改車隊後其餘預設車隊規則消失
+func (s *FleetService) seedDefaults(ctx context.Context) error {
+	collection := s.mongoDB.GetCollection(fleetCollection)
+	count, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢車隊數量失敗")
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	defaults := model.DefaultFleets()
+	docs := make([]interface{}, 0, len(defaults))
+	for _, fleet := range defaults {
+		fleet.CreatedAt = &now
+		fleet.UpdatedAt = &now
+		docs = append(docs, fleet)
+	}
+	if _, err := collection.InsertMany(ctx, docs); err != nil && !mongo.IsDuplicateKeyError(err) {
+		s.logger.Error().Err(err).Msg("寫入預設車隊失敗")
+		return err
+	}
+	s.logger.Info().Int("fleet_count", len(docs)).Msg("已寫入預設車隊")
+	return nil
+}
+
+// Reload 從 MongoDB 重新載入所有車隊到快取，集合為空時使用預設車隊
+func (s *FleetService) Reload(ctx context.Context) error {
+	fleets, err := s.ListFleets(ctx)
+	if err != nil {
+		return err
+	}
+	if len(fleets) == 0 {
+		fleets = model.DefaultFleets()
+	}
+
+	s.mu.Lock()
+	s.fleets = fleets
+	s.mu.Unlock()
+
+	s.logger.Debug().Int("fleet_count", len(fleets)).Msg("車隊登錄資料已重新載入")
+	return nil
+}
+
+// StartHotReload 啟動車隊登錄資料熱更新：訂閱 Redis 設定變更事件，並定期重新載入作為備援
+func (s *FleetService) StartHotReload(ctx context.Context) {
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("初始載入車隊登錄資料失敗，暫時使用預設車隊")
+	}
+
+	var events <-chan *redis.Message
+	if s.eventManager != nil {
+		pubsub := s.eventManager.SubscribeConfigChanges(ctx)
+		defer pubsub.Close()
+		events = pubsub.Channel()
+	}
+
+	ticker := time.NewTicker(fleetReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			event, err := infra.ParseConfigChangeEvent(msg.Payload)
+			if err != nil || event.ConfigType != infra.ConfigChangeFleet {
+				continue
+			}
+			if err := s.Reload(ctx); err != nil {
+				s.logger.Error().Err(err).Str("fleet", event.Key).Msg("收到車隊變更事件，但重新載入失敗")
+			} else {
+				s.logger.Info().Str("fleet", event.Key).Msg("收到車隊變更事件，已重新載入")
+			}
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				s.logger.Error().Err(err).Msg("定期重新載入車隊登錄資料失敗")
+			}
+		}
+	}
+}
+
+// notifyChanged 更新本地快取並發布設定變更事件
+func (s *FleetService) notifyChanged(ctx context.Context, code model.FleetType) {
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("重新載入車隊登錄資料失敗")
+	}
+	if s.eventManager != nil {
+		_ = s.eventManager.PublishConfigChangeEvent(ctx, &infra.ConfigChangeEvent{
+			ConfigType: infra.ConfigChangeFleet,
+			Key:        string(code),
+			Timestamp:  time.Now(),
+		})
+	}
+}
+
+// validateFleet 驗證車隊代碼與客群前綴，並將代碼與前綴統一為大寫
+func validateFleet(fleet *model.Fleet) error {
+	fleet.Code = model.FleetType(strings.ToUpper(strings.TrimSpace(string(fleet.Code))))
+	if fleet.Code == "" {
+		return errors.New("車隊代碼不可為空")
+	}
+	if strings.ContainsAny(string(fleet.Code), " /") {
+		return errors.New("車隊代碼不可包含空白或斜線")
+	}
+	if fleet.DisplayName == "" {
+		fleet.DisplayName = string(fleet.Code)
+	}
+	if fleet.Settings.CommissionPercent < 0 || fleet.Settings.CommissionPercent > 100 {
+		return errors.New("抽成百分比必須介於 0 到 100")
+	}
+	if fleet.Settings.CommissionPerOrder < 0 {
+		return errors.New("每筆訂單固定抽成不可為負數")
+	}
+	if err := fleet.Settings.Cancellation.Validate(); err != nil {
+		return err
+	}
+
+	prefixes := make([]string, 0, len(fleet.CustomerGroupPrefixes))
+	for _, prefix := range fleet.CustomerGroupPrefixes {
+		prefix = strings.ToUpper(strings.TrimSpace(prefix))
+		if prefix == "" {
+			return errors.New("客群前綴不可為空白")
+		}
+		prefixes = appendUnique(prefixes, prefix)
+	}
+	fleet.CustomerGroupPrefixes = prefixes
+
+	dispatchTo := make([]model.FleetType, 0, len(fleet.DispatchToFleets))
+	for _, code := range fleet.DispatchToFleets {
+		code = model.FleetType(strings.ToUpper(strings.TrimSpace(string(code))))
+		if code == "" || code == fleet.Code {
+			continue
+		}
+		dispatchTo = append(dispatchTo, code)
+	}
+	fleet.DispatchToFleets = dispatchTo
+	return nil
+}
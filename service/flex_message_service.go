@@ -24,24 +24,28 @@ func NewFlexMessageService(logger zerolog.Logger) *FlexMessageService {
 
 // GetFlexMessage 根據訂單狀態取得對應的 Flex Message
 func (s *FlexMessageService) GetFlexMessage(order *model.Order) messaging_api.MessageInterface {
+	var message *messaging_api.FlexMessage
 	switch order.Status {
 	case model.OrderStatusWaiting:
-		return s.createWaitingMessage(order)
+		message = s.createWaitingMessage(order)
 	case model.OrderStatusEnroute:
-		return s.createEnrouteMessage(order)
+		message = s.createEnrouteMessage(order)
 	case model.OrderStatusDriverArrived:
-		return s.createDriverArrivedMessage(order)
+		message = s.createDriverArrivedMessage(order)
 	case model.OrderStatusExecuting:
-		return s.createExecutingMessage(order)
+		message = s.createExecutingMessage(order)
 	case model.OrderStatusCompleted:
-		return s.createCompletedMessage(order)
+		message = s.createCompletedMessage(order)
 	case model.OrderStatusFailed:
-		return s.createFailedMessage(order)
+		message = s.createFailedMessage(order)
 	case model.OrderStatusCancelled:
-		return s.createCancelledMessage(order)
+		message = s.createCancelledMessage(order)
 	default:
-		return s.createWaitingMessage(order)
+		message = s.createWaitingMessage(order)
 	}
+
+	s.addWaypointRows(message, order)
+	return message
 }
 
 // GetCreatingFlexMessage 取得建立中的 Flex Message
@@ -664,6 +668,49 @@ func (s *FlexMessageService) createOrderNumberRow(oriText string) *messaging_api
 	}
 }
 
+// addWaypointRows 多點行程在訊息內容最後依序列出各停靠點狀態
+func (s *FlexMessageService) addWaypointRows(message *messaging_api.FlexMessage, order *model.Order) {
+	if len(order.Waypoints) == 0 {
+		return
+	}
+	bubble, ok := message.Contents.(*messaging_api.FlexBubble)
+	if !ok || bubble.Body == nil {
+		return
+	}
+
+	title := "停靠點"
+	if order.IsRoundTrip {
+		title = "停靠點（來回）"
+	}
+	rows := []messaging_api.FlexComponentInterface{
+		&messaging_api.FlexText{
+			Text:  title,
+			Color: "#6B7280",
+			Size:  "md",
+		},
+	}
+	for _, wp := range order.Waypoints {
+		rows = append(rows, &messaging_api.FlexText{
+			Text:  formatWaypointLine(wp),
+			Color: "#111827",
+			Size:  "sm",
+			Wrap:  true,
+		})
+	}
+
+	message.AltText += fmt.Sprintf("（%d個停靠點）", len(order.Waypoints))
+	bubble.Body.Contents = append(bubble.Body.Contents,
+		&messaging_api.FlexSeparator{
+			Margin: "md",
+		},
+		&messaging_api.FlexBox{
+			Layout:   "vertical",
+			Spacing:  "sm",
+			Contents: rows,
+		},
+	)
+}
+
 // 創建資訊行
 func (s *FlexMessageService) createInfoRow(label, value string, isBold bool) *messaging_api.FlexBox {
 	var weight messaging_api.FlexTextWEIGHT
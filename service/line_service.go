@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"right-backend/model"
+	"right-backend/utils"
 	"strings"
 	"time"
 
@@ -34,11 +35,13 @@ type LineConfig struct {
 
 // LineService handles interactions with LINE Messaging API.
 type LineService struct {
-	logger             zerolog.Logger
-	configs            map[string]*LineConfig
-	clients            map[string]*messaging_api.MessagingApiAPI
-	orderService       *OrderService
-	flexMessageService *FlexMessageService
+	logger               zerolog.Logger
+	configs              map[string]*LineConfig
+	clients              map[string]*messaging_api.MessagingApiAPI
+	orderService         *OrderService
+	flexMessageService   *FlexMessageService
+	customerGroupService *CustomerGroupService
+	passengerService     *PassengerService
 }
 
 // NewLineService creates and initializes a new LineService.
@@ -434,12 +437,55 @@ func (s *LineService) formatDefaultMessage(order *model.Order) string {
 	return msg.String()
 }
 
+// SetCustomerGroupService sets the customer group service used to check which LINE configs a group may order from.
+func (s *LineService) SetCustomerGroupService(customerGroupService *CustomerGroupService) {
+	s.customerGroupService = customerGroupService
+}
+
+// SetPassengerService sets the passenger service used to link LINE orders to passenger profiles.
+func (s *LineService) SetPassengerService(passengerService *PassengerService) {
+	s.passengerService = passengerService
+}
+
 // CreateOrderFromMessage parses user input and creates an order.
-func (s *LineService) CreateOrderFromMessage(ctx context.Context, message, configID, sourceID string) (*model.Order, error) {
+// lineUserID is the sender's LINE user ID (also available in group chats) and links the order to a passenger profile.
+func (s *LineService) CreateOrderFromMessage(ctx context.Context, message, configID, sourceID, lineUserID string) (*model.Order, error) {
 	if s.orderService == nil {
 		return nil, fmt.Errorf("orderService not initialized")
 	}
 
+	// 檢查客群是否允許透過此 LINE Bot 配置下單
+	if s.customerGroupService != nil {
+		customerGroup, _, _, _, _ := utils.ExOriText(strings.TrimSpace(message))
+		if customerGroup != "" {
+			if err := s.customerGroupService.CheckLineConfig(ctx, customerGroup, configID); err != nil {
+				s.logger.Warn().
+					Err(err).
+					Str("config_id", configID).
+					Str("customer_group", customerGroup).
+					Msg("Customer group is not allowed to order from this LINE config")
+				return nil, err
+			}
+		}
+	}
+
+	// 取得或建立乘客資料，已封鎖的乘客不可下單
+	var passenger *model.Passenger
+	if s.passengerService != nil && lineUserID != "" {
+		var err error
+		passenger, err = s.passengerService.EnsureByLineUserID(ctx, lineUserID)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("line_user_id", lineUserID).Msg("Failed to load passenger profile, order will not be linked")
+		} else if passenger.Blocked {
+			s.logger.Warn().
+				Str("passenger_id", passenger.ID.Hex()).
+				Str("line_user_id", lineUserID).
+				Str("config_id", configID).
+				Msg("Blocked passenger tried to create an order")
+			return nil, fmt.Errorf("此帳號已暫停叫車服務，請聯繫客服")
+		}
+	}
+
 	// 使用 SimpleCreateOrder 處理用戶輸入（使用 sourceID 作為建立者名稱）
 	result, err := s.orderService.SimpleCreateOrder(ctx, message, "", model.CreatedByLine, sourceID)
 	if err != nil {
@@ -463,6 +509,14 @@ func (s *LineService) CreateOrderFromMessage(ctx context.Context, message, confi
 	}
 	createdOrder.LineMessages = append(createdOrder.LineMessages, lineMessage)
 
+	// 連結乘客資料
+	if lineUserID != "" {
+		createdOrder.Customer.LineUserID = lineUserID
+	}
+	if passenger != nil {
+		createdOrder.PassengerID = passenger.ID.Hex()
+	}
+
 	// 更新訂單以保存 LINE 資訊
 	updatedOrder, err := s.orderService.UpdateOrder(ctx, createdOrder)
 	if err != nil {
@@ -473,6 +527,10 @@ func (s *LineService) CreateOrderFromMessage(ctx context.Context, message, confi
 		return createdOrder, nil // 繼續執行，不阻止訂單創建
 	}
 
+	if passenger != nil {
+		s.passengerService.RecordOrder(ctx, passenger, updatedOrder)
+	}
+
 	return updatedOrder, nil
 }
 
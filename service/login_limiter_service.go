@@ -0,0 +1,444 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	loginLockoutEventsCollection = "login_lockout_events"
+
+	// 未設定時的登入限制
+	defaultLoginAccountMaxFailures     = 5
+	defaultLoginIPMaxFailures          = 20
+	defaultLoginIPMaxAttemptsPerMinute = 30
+	defaultLoginFailureWindowMinutes   = 15
+	defaultLoginLockoutMinutes         = 5
+	defaultLoginMaxLockoutMinutes      = 24 * 60
+
+	// loginLockoutLevelTTL 鎖定到期後保留鎖定等級的時間，期間內再次鎖定時鎖定時間加倍
+	loginLockoutLevelTTL = 24 * time.Hour
+
+	loginLimitKeyPrefix = "auth:login_limit:"
+)
+
+// LoginThrottleError.Reason：登入被拒絕的原因
+const (
+	LoginThrottleLocked      = "locked"       // 帳號或 IP 鎖定中
+	LoginThrottleRateLimited = "rate_limited" // IP 每分鐘登入次數超過上限
+	LoginThrottleLockout     = "lockout"      // 失敗次數達上限而鎖定，只用於 metric
+)
+
+var (
+	ErrLoginThrottled     = errors.New("登入嘗試次數過多，請稍後再試")
+	ErrLoginLockNotFound  = errors.New("沒有鎖定中的登入限制")
+	ErrInvalidLoginTarget = errors.New("無效的登入類型或帳號")
+)
+
+// incrWindowScript 計數加一，新建立的計數設定統計期間為過期時間
+const incrWindowScript = `
+local count = redis.call("INCR", KEYS[1])
+if count == 1 or redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`
+
+// LoginThrottleError 登入被限制時的詳細資訊
+type LoginThrottleError struct {
+	Scope      model.LoginLockoutScope
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottleError) Error() string {
+	return fmt.Sprintf("%s: %s %s，%d 秒後再試", ErrLoginThrottled.Error(), e.Scope, e.Reason, e.RetryAfterSeconds())
+}
+
+func (e *LoginThrottleError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+// RetryAfterSeconds 可再次嘗試的秒數，無條件進位
+func (e *LoginThrottleError) RetryAfterSeconds() int {
+	seconds := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// LoginLimiterService 登入防暴力破解：以 Redis 統計帳號與 IP 的登入失敗次數，達上限時鎖定，
+// 同一對象再次鎖定時鎖定時間加倍；鎖定與管理員解除鎖定都記錄到稽核紀錄。
+// Redis 無法使用時不阻擋登入，只記錄錯誤
+type LoginLimiterService struct {
+	logger      zerolog.Logger
+	mongoDB     *infra.MongoDB
+	redisClient *redis.Client
+}
+
+func NewLoginLimiterService(logger zerolog.Logger, mongoDB *infra.MongoDB, redisClient *redis.Client) *LoginLimiterService {
+	return &LoginLimiterService{
+		logger:      logger.With().Str("module", "login_limiter_service").Logger(),
+		mongoDB:     mongoDB,
+		redisClient: redisClient,
+	}
+}
+
+// CheckIP 檢查 IP 是否鎖定中，並統計每分鐘登入次數；每次登入請求呼叫一次
+func (s *LoginLimiterService) CheckIP(ctx context.Context, ip string) error {
+	if ip == "" {
+		return nil
+	}
+	if err := s.checkLock(ctx, model.LoginLockoutScopeIP, ipSubject(ip)); err != nil {
+		return err
+	}
+
+	rateKey := loginLimitKeyPrefix + "rate:ip:" + ip
+	attempts, err := s.incrWindow(ctx, rateKey, time.Minute)
+	if err != nil {
+		s.logger.Error().Err(err).Str("ip", ip).Msg("統計 IP 登入次數失敗")
+		return nil
+	}
+	if attempts <= int64(positiveOr(infra.AppConfig.LoginLimit.IPMaxAttemptsPerMinute, defaultLoginIPMaxAttemptsPerMinute)) {
+		return nil
+	}
+
+	retryAfter, err := s.redisClient.PTTL(ctx, rateKey).Result()
+	if err != nil || retryAfter <= 0 {
+		retryAfter = time.Minute
+	}
+	return &LoginThrottleError{Scope: model.LoginLockoutScopeIP, Reason: LoginThrottleRateLimited, RetryAfter: retryAfter}
+}
+
+// CheckAccount 檢查帳號是否鎖定中
+func (s *LoginLimiterService) CheckAccount(ctx context.Context, loginType model.TokenType, account string) error {
+	subject, ok := accountSubject(loginType, account)
+	if !ok {
+		return nil
+	}
+	return s.checkLock(ctx, model.LoginLockoutScopeAccount, subject)
+}
+
+// RecordFailure 記錄帳號密碼錯誤，回傳因此次失敗而觸發的鎖定
+func (s *LoginLimiterService) RecordFailure(ctx context.Context, loginType model.TokenType, account, ip string) []*model.LoginLockoutEvent {
+	var lockouts []*model.LoginLockoutEvent
+
+	if subject, ok := accountSubject(loginType, account); ok {
+		event := &model.LoginLockoutEvent{Scope: model.LoginLockoutScopeAccount, LoginType: loginType, Account: normalizeLoginAccount(account), IP: ip}
+		if s.recordSubjectFailure(ctx, subject, positiveOr(infra.AppConfig.LoginLimit.AccountMaxFailures, defaultLoginAccountMaxFailures), event) {
+			lockouts = append(lockouts, event)
+		}
+	}
+
+	if ip != "" {
+		event := &model.LoginLockoutEvent{Scope: model.LoginLockoutScopeIP, IP: ip}
+		if s.recordSubjectFailure(ctx, ipSubject(ip), positiveOr(infra.AppConfig.LoginLimit.IPMaxFailures, defaultLoginIPMaxFailures), event) {
+			lockouts = append(lockouts, event)
+		}
+	}
+
+	return lockouts
+}
+
+// RecordSuccess 登入成功後清除帳號的失敗次數與鎖定等級；IP 可能為多人共用，不清除
+func (s *LoginLimiterService) RecordSuccess(ctx context.Context, loginType model.TokenType, account string) {
+	subject, ok := accountSubject(loginType, account)
+	if !ok {
+		return
+	}
+	if err := s.redisClient.Del(ctx, failuresKey(subject), levelKey(subject)).Err(); err != nil {
+		s.logger.Error().Err(err).Str("login_type", string(loginType)).Str("account", account).Msg("清除帳號登入失敗次數失敗")
+	}
+}
+
+// UnlockAccount 管理員解除帳號鎖定，並清除失敗次數與鎖定等級
+func (s *LoginLimiterService) UnlockAccount(ctx context.Context, loginType model.TokenType, account, operator string) (*model.LoginLockoutEvent, error) {
+	subject, ok := accountSubject(loginType, account)
+	if !ok {
+		return nil, ErrInvalidLoginTarget
+	}
+	return s.unlock(ctx, subject, &model.LoginLockoutEvent{
+		Scope:     model.LoginLockoutScopeAccount,
+		LoginType: loginType,
+		Account:   normalizeLoginAccount(account),
+		Operator:  operator,
+	})
+}
+
+// UnlockIP 管理員解除 IP 鎖定，並清除失敗次數與鎖定等級
+func (s *LoginLimiterService) UnlockIP(ctx context.Context, ip, operator string) (*model.LoginLockoutEvent, error) {
+	ip = strings.TrimSpace(ip)
+	if ip == "" {
+		return nil, ErrInvalidLoginTarget
+	}
+	return s.unlock(ctx, ipSubject(ip), &model.LoginLockoutEvent{
+		Scope:    model.LoginLockoutScopeIP,
+		IP:       ip,
+		Operator: operator,
+	})
+}
+
+// ListLockoutEvents 分頁查詢登入鎖定稽核紀錄，依時間新到舊排序
+func (s *LoginLimiterService) ListLockoutEvents(ctx context.Context, scope, account, ip string, pageNum, pageSize int) ([]*model.LoginLockoutEvent, int64, error) {
+	filter := bson.M{}
+	if scope != "" {
+		filter["scope"] = scope
+	}
+	if account != "" {
+		filter["account"] = normalizeLoginAccount(account)
+	}
+	if ip != "" {
+		filter["ip"] = ip
+	}
+
+	collection := s.mongoDB.GetCollection(loginLockoutEventsCollection)
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查詢登入鎖定紀錄數量失敗: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((pageNum - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查詢登入鎖定紀錄失敗: %w", err)
+	}
+	events := []*model.LoginLockoutEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, fmt.Errorf("解析登入鎖定紀錄失敗: %w", err)
+	}
+	return events, total, nil
+}
+
+// RestoreLockouts 由稽核紀錄重建 Redis 中的登入鎖定與鎖定等級（服務啟動時 Redis 會被清空），回傳重建的鎖定數量。
+// 同一對象以最後一筆紀錄為準，管理員已解除鎖定的對象不重建；已到期的鎖定只重建鎖定等級
+func (s *LoginLimiterService) RestoreLockouts(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	maxDuration := time.Duration(positiveOr(infra.AppConfig.LoginLimit.MaxLockoutMinutes, defaultLoginMaxLockoutMinutes)) * time.Minute
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := s.mongoDB.GetCollection(loginLockoutEventsCollection).Find(ctx, bson.M{
+		"created_at": bson.M{"$gt": now.Add(-maxDuration - loginLockoutLevelTTL)},
+	}, opts)
+	if err != nil {
+		return 0, err
+	}
+	var events []*model.LoginLockoutEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return 0, err
+	}
+
+	latest := make(map[string]*model.LoginLockoutEvent)
+	for _, event := range events {
+		subject, ok := lockoutEventSubject(event)
+		if !ok {
+			continue
+		}
+		switch event.Action {
+		case model.LoginLockoutActionLocked:
+			latest[subject] = event
+		case model.LoginLockoutActionUnlocked:
+			delete(latest, subject)
+		}
+	}
+
+	restored := 0
+	pipe := s.redisClient.Pipeline()
+	for subject, event := range latest {
+		if event.LockedUntil == nil {
+			continue
+		}
+		remaining := event.LockedUntil.Sub(now)
+		if remaining+loginLockoutLevelTTL <= 0 {
+			continue
+		}
+		pipe.Set(ctx, levelKey(subject), event.Level, remaining+loginLockoutLevelTTL)
+		if remaining > 0 {
+			pipe.Set(ctx, lockKey(subject), event.Level, remaining)
+			restored++
+		}
+	}
+	if pipe.Len() == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+func (s *LoginLimiterService) checkLock(ctx context.Context, scope model.LoginLockoutScope, subject string) error {
+	ttl, err := s.redisClient.PTTL(ctx, lockKey(subject)).Result()
+	if err != nil {
+		s.logger.Error().Err(err).Str("subject", subject).Msg("查詢登入鎖定狀態失敗")
+		return nil
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return &LoginThrottleError{Scope: scope, Reason: LoginThrottleLocked, RetryAfter: ttl}
+}
+
+// recordSubjectFailure 失敗次數剛好達到上限時鎖定，同時到達的其他失敗不會重複鎖定
+func (s *LoginLimiterService) recordSubjectFailure(ctx context.Context, subject string, maxFailures int, event *model.LoginLockoutEvent) bool {
+	window := time.Duration(positiveOr(infra.AppConfig.LoginLimit.FailureWindowMinutes, defaultLoginFailureWindowMinutes)) * time.Minute
+	failures, err := s.incrWindow(ctx, failuresKey(subject), window)
+	if err != nil {
+		s.logger.Error().Err(err).Str("subject", subject).Msg("記錄登入失敗次數失敗")
+		return false
+	}
+	if failures != int64(maxFailures) {
+		return false
+	}
+
+	level, err := s.redisClient.Incr(ctx, levelKey(subject)).Result()
+	if err != nil {
+		s.logger.Error().Err(err).Str("subject", subject).Msg("更新登入鎖定等級失敗")
+		level = 1
+	}
+	duration := lockoutDuration(level)
+	now := time.Now().UTC()
+	lockedUntil := now.Add(duration)
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, lockKey(subject), level, duration)
+	pipe.Expire(ctx, levelKey(subject), duration+loginLockoutLevelTTL)
+	pipe.Del(ctx, failuresKey(subject))
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error().Err(err).Str("subject", subject).Msg("設定登入鎖定失敗")
+		return false
+	}
+
+	event.Action = model.LoginLockoutActionLocked
+	event.Failures = failures
+	event.Level = level
+	event.LockedUntil = &lockedUntil
+	event.CreatedAt = now
+	s.insertEvent(ctx, event)
+
+	s.logger.Warn().
+		Str("scope", string(event.Scope)).
+		Str("login_type", string(event.LoginType)).
+		Str("account", event.Account).
+		Str("ip", event.IP).
+		Int64("failures", failures).
+		Int64("level", level).
+		Dur("duration", duration).
+		Msg("登入失敗次數過多，已鎖定")
+	return true
+}
+
+func (s *LoginLimiterService) unlock(ctx context.Context, subject string, event *model.LoginLockoutEvent) (*model.LoginLockoutEvent, error) {
+	pipe := s.redisClient.TxPipeline()
+	locked := pipe.Del(ctx, lockKey(subject))
+	pipe.Del(ctx, failuresKey(subject), levelKey(subject))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("解除登入鎖定失敗: %w", err)
+	}
+	if locked.Val() == 0 {
+		return nil, ErrLoginLockNotFound
+	}
+
+	event.Action = model.LoginLockoutActionUnlocked
+	event.CreatedAt = time.Now().UTC()
+	s.insertEvent(ctx, event)
+
+	s.logger.Info().
+		Str("scope", string(event.Scope)).
+		Str("login_type", string(event.LoginType)).
+		Str("account", event.Account).
+		Str("ip", event.IP).
+		Str("operator", event.Operator).
+		Msg("管理員已解除登入鎖定")
+	return event, nil
+}
+
+// insertEvent 寫入稽核紀錄，失敗時只記錄錯誤，不影響登入流程
+func (s *LoginLimiterService) insertEvent(ctx context.Context, event *model.LoginLockoutEvent) {
+	event.ID = primitive.NewObjectID()
+	if _, err := s.mongoDB.GetCollection(loginLockoutEventsCollection).InsertOne(ctx, event); err != nil {
+		s.logger.Error().Err(err).
+			Str("action", string(event.Action)).
+			Str("scope", string(event.Scope)).
+			Str("account", event.Account).
+			Str("ip", event.IP).
+			Msg("寫入登入鎖定紀錄失敗")
+	}
+}
+
+func (s *LoginLimiterService) incrWindow(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return s.redisClient.Eval(ctx, incrWindowScript, []string{key}, window.Milliseconds()).Int64()
+}
+
+// lockoutDuration 第 level 次鎖定的時間：基本鎖定時間每次加倍，不超過上限
+func lockoutDuration(level int64) time.Duration {
+	base := time.Duration(positiveOr(infra.AppConfig.LoginLimit.LockoutMinutes, defaultLoginLockoutMinutes)) * time.Minute
+	maxDuration := time.Duration(positiveOr(infra.AppConfig.LoginLimit.MaxLockoutMinutes, defaultLoginMaxLockoutMinutes)) * time.Minute
+	duration := base
+	for i := int64(1); i < level && duration < maxDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxDuration {
+		return maxDuration
+	}
+	return duration
+}
+
+func accountSubject(loginType model.TokenType, account string) (string, bool) {
+	account = normalizeLoginAccount(account)
+	if account == "" || (loginType != model.TokenTypeUser && loginType != model.TokenTypeDriver) {
+		return "", false
+	}
+	return "account:" + string(loginType) + ":" + account, true
+}
+
+// lockoutEventSubject 稽核紀錄對應的鎖定對象
+func lockoutEventSubject(event *model.LoginLockoutEvent) (string, bool) {
+	switch event.Scope {
+	case model.LoginLockoutScopeAccount:
+		return accountSubject(event.LoginType, event.Account)
+	case model.LoginLockoutScopeIP:
+		return ipSubject(event.IP), event.IP != ""
+	}
+	return "", false
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+func normalizeLoginAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+func failuresKey(subject string) string {
+	return loginLimitKeyPrefix + "failures:" + subject
+}
+
+func lockKey(subject string) string {
+	return loginLimitKeyPrefix + "lock:" + subject
+}
+
+func levelKey(subject string) string {
+	return loginLimitKeyPrefix + "level:" + subject
+}
+
+func positiveOr(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// setLoginLimitConfig 測試期間替換登入限制設定
+func setLoginLimitConfig(t *testing.T, accountMaxFailures, lockoutMinutes, maxLockoutMinutes int) {
+	t.Helper()
+	original := infra.AppConfig.LoginLimit
+	t.Cleanup(func() { infra.AppConfig.LoginLimit = original })
+	infra.AppConfig.LoginLimit.AccountMaxFailures = accountMaxFailures
+	infra.AppConfig.LoginLimit.LockoutMinutes = lockoutMinutes
+	infra.AppConfig.LoginLimit.MaxLockoutMinutes = maxLockoutMinutes
+}
+
+func TestLockoutDuration(t *testing.T) {
+	setLoginLimitConfig(t, 0, 5, 60)
+
+	testCases := []struct {
+		level int64
+		want  time.Duration
+	}{
+		{level: 0, want: 5 * time.Minute},
+		{level: 1, want: 5 * time.Minute},
+		{level: 2, want: 10 * time.Minute},
+		{level: 3, want: 20 * time.Minute},
+		{level: 4, want: 40 * time.Minute},
+		{level: 5, want: 60 * time.Minute},
+		{level: 100, want: 60 * time.Minute},
+	}
+	for _, tc := range testCases {
+		if got := lockoutDuration(tc.level); got != tc.want {
+			t.Fatalf("第 %d 次鎖定預期 %v，實際為 %v", tc.level, tc.want, got)
+		}
+	}
+}
+
+func TestLockoutDurationDefaults(t *testing.T) {
+	setLoginLimitConfig(t, 0, 0, 0)
+
+	if got := lockoutDuration(1); got != defaultLoginLockoutMinutes*time.Minute {
+		t.Fatalf("未設定時預期鎖定 %d 分鐘，實際為 %v", defaultLoginLockoutMinutes, got)
+	}
+	if got := lockoutDuration(20); got != defaultLoginMaxLockoutMinutes*time.Minute {
+		t.Fatalf("未設定時預期鎖定上限 %d 分鐘，實際為 %v", defaultLoginMaxLockoutMinutes, got)
+	}
+}
+
+func newTestLoginLimiterService(mt *mtest.T) (*LoginLimiterService, *miniredis.Miniredis) {
+	server := miniredis.RunT(mt.T)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	mt.Cleanup(func() { client.Close() })
+	mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+	return NewLoginLimiterService(zerolog.Nop(), mongoDB, client), server
+}
+
+// failLogin 模擬帳號密碼錯誤，回傳帳號鎖定紀錄
+func failLogin(mt *mtest.T, svc *LoginLimiterService, account string) *model.LoginLockoutEvent {
+	mt.Helper()
+	for _, event := range svc.RecordFailure(context.Background(), model.TokenTypeDriver, account, "203.0.113.7") {
+		if event.Scope == model.LoginLockoutScopeAccount {
+			return event
+		}
+	}
+	return nil
+}
+
+func TestRecordFailureLocksAccount(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	setLoginLimitConfig(t, 3, 5, 60)
+
+	mt.Run("失敗次數達上限後鎖定，再次鎖定時間加倍", func(mt *mtest.T) {
+		svc, server := newTestLoginLimiterService(mt)
+		ctx := context.Background()
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		for i := 0; i < 2; i++ {
+			if event := failLogin(mt, svc, "Driver001"); event != nil {
+				mt.Fatalf("第 %d 次失敗不應鎖定", i+1)
+			}
+		}
+		if err := svc.CheckAccount(ctx, model.TokenTypeDriver, "driver001"); err != nil {
+			mt.Fatalf("未達失敗上限前不應鎖定，實際為 %v", err)
+		}
+
+		event := failLogin(mt, svc, " driver001 ")
+		if event == nil {
+			mt.Fatal("第 3 次失敗預期鎖定帳號")
+		}
+		if event.Action != model.LoginLockoutActionLocked || event.Account != "driver001" || event.Failures != 3 || event.Level != 1 {
+			mt.Fatalf("鎖定紀錄不符: %+v", event)
+		}
+		if insert := mt.GetStartedEvent(); insert == nil || insert.CommandName != "insert" {
+			mt.Fatal("預期寫入鎖定稽核紀錄")
+		}
+
+		var throttled *LoginThrottleError
+		err := svc.CheckAccount(ctx, model.TokenTypeDriver, "DRIVER001")
+		if !errors.As(err, &throttled) || throttled.Reason != LoginThrottleLocked || throttled.RetryAfter > 5*time.Minute {
+			mt.Fatalf("預期帳號鎖定 5 分鐘，實際為 %v", err)
+		}
+		// 同一帳號的其他登入類型不受影響
+		if err := svc.CheckAccount(ctx, model.TokenTypeUser, "driver001"); err != nil {
+			mt.Fatalf("用戶登入不應受司機帳號鎖定影響，實際為 %v", err)
+		}
+
+		// 鎖定到期後失敗次數重新計算，再次鎖定時間加倍
+		server.FastForward(5*time.Minute + time.Second)
+		if err := svc.CheckAccount(ctx, model.TokenTypeDriver, "driver001"); err != nil {
+			mt.Fatalf("鎖定到期後應可登入，實際為 %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if event := failLogin(mt, svc, "driver001"); event != nil {
+				mt.Fatal("鎖定後失敗次數應重新計算")
+			}
+		}
+		event = failLogin(mt, svc, "driver001")
+		if event == nil || event.Level != 2 {
+			mt.Fatalf("預期第二次鎖定，實際為 %+v", event)
+		}
+		if got := event.LockedUntil.Sub(event.CreatedAt); got != 10*time.Minute {
+			mt.Fatalf("第二次鎖定預期 10 分鐘，實際為 %v", got)
+		}
+	})
+
+	mt.Run("登入成功清除失敗次數", func(mt *mtest.T) {
+		svc, _ := newTestLoginLimiterService(mt)
+
+		failLogin(mt, svc, "driver002")
+		failLogin(mt, svc, "driver002")
+		svc.RecordSuccess(context.Background(), model.TokenTypeDriver, "driver002")
+		for i := 0; i < 2; i++ {
+			if event := failLogin(mt, svc, "driver002"); event != nil {
+				mt.Fatal("登入成功後失敗次數應重新計算")
+			}
+		}
+	})
+
+	mt.Run("寫入稽核紀錄失敗時仍鎖定", func(mt *mtest.T) {
+		svc, _ := newTestLoginLimiterService(mt)
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+
+		for i := 0; i < 3; i++ {
+			failLogin(mt, svc, "driver003")
+		}
+		if err := svc.CheckAccount(context.Background(), model.TokenTypeDriver, "driver003"); !errors.Is(err, ErrLoginThrottled) {
+			mt.Fatalf("預期帳號鎖定，實際為 %v", err)
+		}
+	})
+}
+
+func TestRestoreLockouts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	setLoginLimitConfig(t, 3, 5, 60)
+
+	mt.Run("由稽核紀錄重建鎖定與鎖定等級", func(mt *mtest.T) {
+		svc, server := newTestLoginLimiterService(mt)
+		ctx := context.Background()
+		now := time.Now().UTC()
+		lockedEvent := func(scope model.LoginLockoutScope, account, ip string, level int64, createdAt time.Time, duration time.Duration) *model.LoginLockoutEvent {
+			lockedUntil := createdAt.Add(duration)
+			return &model.LoginLockoutEvent{Action: model.LoginLockoutActionLocked, Scope: scope, LoginType: model.TokenTypeDriver,
+				Account: account, IP: ip, Level: level, LockedUntil: &lockedUntil, CreatedAt: createdAt}
+		}
+		unlockedEvent := &model.LoginLockoutEvent{Action: model.LoginLockoutActionUnlocked, Scope: model.LoginLockoutScopeAccount,
+			LoginType: model.TokenTypeDriver, Account: "driver002", CreatedAt: now.Add(-time.Minute)}
+		ipEvent := lockedEvent(model.LoginLockoutScopeIP, "", "203.0.113.7", 1, now.Add(-time.Hour), 5*time.Minute)
+		ipEvent.LoginType = ""
+
+		mt.AddMockResponses(mockCursor(mt, loginLockoutEventsCollection,
+			lockedEvent(model.LoginLockoutScopeAccount, "driver003", "", 1, now.Add(-30*time.Minute), 5*time.Minute),
+			ipEvent,
+			lockedEvent(model.LoginLockoutScopeAccount, "driver001", "", 2, now.Add(-2*time.Minute), 10*time.Minute),
+			lockedEvent(model.LoginLockoutScopeAccount, "driver002", "", 1, now.Add(-3*time.Minute), 5*time.Minute),
+			unlockedEvent,
+			lockedEvent(model.LoginLockoutScopeAccount, "driver003", "", 2, now.Add(-time.Minute), 10*time.Minute),
+		))
+
+		restored, err := svc.RestoreLockouts(ctx)
+		if err != nil {
+			mt.Fatalf("重建登入鎖定失敗: %v", err)
+		}
+		if restored != 2 {
+			mt.Fatalf("預期重建 2 筆鎖定，實際為 %d", restored)
+		}
+
+		var throttled *LoginThrottleError
+		err = svc.CheckAccount(ctx, model.TokenTypeDriver, "driver001")
+		if !errors.As(err, &throttled) || throttled.RetryAfter > 8*time.Minute || throttled.RetryAfter < 7*time.Minute {
+			mt.Fatalf("預期帳號剩餘約 8 分鐘鎖定，實際為 %v", err)
+		}
+		if level, _ := server.Get(levelKey("account:driver:driver003")); level != "2" {
+			mt.Fatalf("同一帳號以最後一次鎖定為準，預期鎖定等級 2，實際為 %q", level)
+		}
+		if err := svc.CheckAccount(ctx, model.TokenTypeDriver, "driver002"); err != nil {
+			mt.Fatalf("管理員已解除的鎖定不應重建，實際為 %v", err)
+		}
+		if server.Exists(levelKey("account:driver:driver002")) {
+			mt.Fatal("管理員已解除的鎖定不應重建鎖定等級")
+		}
+
+		// 已到期的 IP 鎖定只重建鎖定等級，再次鎖定時鎖定時間仍會加倍
+		if err := svc.CheckIP(ctx, "203.0.113.7"); err != nil {
+			mt.Fatalf("已到期的鎖定不應重建，實際為 %v", err)
+		}
+		if level, _ := server.Get(levelKey(ipSubject("203.0.113.7"))); level != "1" {
+			mt.Fatalf("預期重建 IP 鎖定等級 1，實際為 %q", level)
+		}
+		if ttl := server.TTL(levelKey(ipSubject("203.0.113.7"))); ttl <= 23*time.Hour || ttl > loginLockoutLevelTTL {
+			mt.Fatalf("鎖定等級預期保留到鎖定到期後 %v，實際剩餘 %v", loginLockoutLevelTTL, ttl)
+		}
+	})
+
+	mt.Run("沒有稽核紀錄時不寫入 Redis", func(mt *mtest.T) {
+		svc, server := newTestLoginLimiterService(mt)
+		mt.AddMockResponses(mockCursor(mt, loginLockoutEventsCollection))
+
+		restored, err := svc.RestoreLockouts(context.Background())
+		if err != nil || restored != 0 {
+			mt.Fatalf("預期沒有重建任何鎖定，實際為 %d, %v", restored, err)
+		}
+		if keys := server.Keys(); len(keys) != 0 {
+			mt.Fatalf("預期 Redis 沒有任何鍵，實際為 %v", keys)
+		}
+	})
+}
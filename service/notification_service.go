@@ -672,6 +672,33 @@ func (ns *NotificationService) NotifyCustomerOnBoard(ctx context.Context, orderI
 	return nil
 }
 
+// NotifyWaypointUpdated 多點行程停靠點狀態更新，重新渲染 Discord 與 LINE 訂單卡片
+func (ns *NotificationService) NotifyWaypointUpdated(ctx context.Context, orderID string, driver *model.DriverInfo) error {
+	order, err := ns.orderService.GetOrderByID(ctx, orderID)
+	if err != nil {
+		ns.logger.Error().Err(err).Str("order_id", orderID).Msg("獲取訂單失敗")
+		return err
+	}
+
+	notifications := []NotificationTask{
+		{Type: model.NotificationDiscord, OrderID: orderID, Driver: driver, Order: order, EventType: model.EventWaypointUpdated},
+		{Type: model.NotificationLine, OrderID: orderID, Driver: driver, Order: order, EventType: model.EventWaypointUpdated},
+	}
+
+	for _, notification := range notifications {
+		select {
+		case ns.notificationQueue <- notification:
+		default:
+			ns.logger.Warn().
+				Str("type", string(notification.Type)).
+				Str("order_id", orderID).
+				Msg("通知隊列已滿，跳過停靠點卡片更新")
+		}
+	}
+
+	return nil
+}
+
 // GetQueueLength 獲取當前隊列長度（用於監控）
 func (ns *NotificationService) GetQueueLength() int {
 	return len(ns.notificationQueue)
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"right-backend/auth"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const orderEventsCollection = "order_events"
+
+type orderEventActorKey struct{}
+
+// WithOrderEventActor 在 context 中指定訂單事件的操作者（Discord、LINE 等沒有登入用戶的來源使用）
+func WithOrderEventActor(ctx context.Context, actor model.OrderEventActor) context.Context {
+	return context.WithValue(ctx, orderEventActorKey{}, actor)
+}
+
+// OrderEventActorFromContext 取得 context 中的操作者：明確指定的操作者、登入的後台用戶、登入的司機，都沒有時為系統
+func OrderEventActorFromContext(ctx context.Context) model.OrderEventActor {
+	if actor, ok := orderEventActorFromContext(ctx); ok {
+		return actor
+	}
+	return model.SystemEventActor
+}
+
+func orderEventActorFromContext(ctx context.Context) (model.OrderEventActor, bool) {
+	if actor, ok := ctx.Value(orderEventActorKey{}).(model.OrderEventActor); ok {
+		return actor, true
+	}
+	if user, err := auth.GetUserFromContext(ctx); err == nil {
+		return model.OrderEventActor{Type: model.OrderEventActorUser, ID: user.ID.Hex(), Name: user.Name}, true
+	}
+	if driver, err := auth.GetDriverFromContext(ctx); err == nil {
+		return model.OrderEventActor{Type: model.OrderEventActorDriver, ID: driver.ID.Hex(), Name: driver.Name}, true
+	}
+	return model.OrderEventActor{}, false
+}
+
+// createdByEventActor 依訂單建立者類型決定建立事件的操作者，Discord 與 LINE 以外的來源回傳 false
+func createdByEventActor(order *model.Order) (model.OrderEventActor, bool) {
+	switch model.CreatedBy(order.CreatedType) {
+	case model.CreatedByDiscord:
+		return model.OrderEventActor{Type: model.OrderEventActorDiscord, Name: order.CreatedBy}, true
+	case model.CreatedByLine:
+		return model.OrderEventActor{Type: model.OrderEventActorLine, Name: order.CreatedBy}, true
+	}
+	return model.OrderEventActor{}, false
+}
+
+// OrderEventService 訂單事件稽核紀錄，order_events 集合只新增不修改
+type OrderEventService struct {
+	logger  zerolog.Logger
+	mongoDB *infra.MongoDB
+}
+
+func NewOrderEventService(logger zerolog.Logger, mongoDB *infra.MongoDB) *OrderEventService {
+	return &OrderEventService{
+		logger:  logger.With().Str("module", "order_event_service").Logger(),
+		mongoDB: mongoDB,
+	}
+}
+
+// Record 新增訂單事件，未指定操作者時由 context 取得，並帶入目前的 trace ID
+func (s *OrderEventService) Record(ctx context.Context, event *model.OrderEvent) error {
+	if event.Actor.Type == "" {
+		event.Actor = OrderEventActorFromContext(ctx)
+	}
+	if event.TraceID == "" {
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+			event.TraceID = spanContext.TraceID().String()
+		}
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = utils.NowUTC()
+	}
+
+	if _, err := s.mongoDB.GetCollection(orderEventsCollection).InsertOne(ctx, event); err != nil {
+		return fmt.Errorf("記錄訂單事件失敗: %w", err)
+	}
+	return nil
+}
+
+// RecordChanges 比對訂單異動前的文件與更新欄位並記錄事件，沒有任何欄位變更時不記錄
+func (s *OrderEventService) RecordChanges(ctx context.Context, orderID primitive.ObjectID, action model.OrderEventAction, before bson.M, set bson.M, details string) error {
+	changes := diffOrderFields(before, set)
+	if len(changes) == 0 {
+		return nil
+	}
+	return s.Record(ctx, &model.OrderEvent{
+		OrderID: orderID,
+		Action:  action,
+		Changes: changes,
+		Details: details,
+	})
+}
+
+// GetOrderTimeline 依時間順序取得訂單的所有事件
+func (s *OrderEventService) GetOrderTimeline(ctx context.Context, orderID string) ([]*model.OrderEvent, error) {
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的訂單ID: %w", err)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.mongoDB.GetCollection(orderEventsCollection).Find(ctx, bson.M{"order_id": objectID}, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Str("order_id", orderID).Msg("查詢訂單事件失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []*model.OrderEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		s.logger.Error().Err(err).Str("order_id", orderID).Msg("讀取訂單事件失敗")
+		return nil, err
+	}
+	return events, nil
+}
+
+// diffOrderFields 比對異動前的訂單文件與 $set 欄位，回傳實際變更的欄位（依欄位名稱排序，忽略 updated_at）
+func diffOrderFields(before bson.M, set bson.M) []model.OrderFieldChange {
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		if field != "updated_at" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	var changes []model.OrderFieldChange
+	for _, field := range fields {
+		beforeValue := auditValue(lookupField(before, field))
+		afterValue := auditValue(set[field])
+		if beforeValue != afterValue {
+			changes = append(changes, model.OrderFieldChange{Field: field, Before: beforeValue, After: afterValue})
+		}
+	}
+	return changes
+}
+
+// lookupField 取得文件中的欄位值，支援以 . 分隔的巢狀欄位
+func lookupField(doc bson.M, field string) interface{} {
+	var value interface{} = doc
+	for _, key := range strings.Split(field, ".") {
+		current, ok := value.(bson.M)
+		if !ok {
+			return nil
+		}
+		value = current[key]
+	}
+	return value
+}
+
+// auditValue 將欄位值轉為顯示用字串；先經過 BSON 編解碼，讓寫入值與資料庫讀出的值有相同表示方式
+func auditValue(value interface{}) string {
+	raw, err := bson.Marshal(bson.M{"v": value})
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return fmt.Sprint(value)
+	}
+
+	switch v := doc["v"].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case primitive.DateTime:
+		return v.Time().UTC().Format(time.RFC3339)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
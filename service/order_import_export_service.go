@@ -20,8 +20,9 @@ import (
 )
 
 type OrderImportExportService struct {
-	logger  zerolog.Logger
-	mongoDB *infra.MongoDB
+	logger            zerolog.Logger
+	mongoDB           *infra.MongoDB
+	orderEventService *OrderEventService // 訂單事件稽核紀錄
 }
 
 func NewOrderImportExportService(logger zerolog.Logger, mongoDB *infra.MongoDB) *OrderImportExportService {
@@ -31,6 +32,21 @@ func NewOrderImportExportService(logger zerolog.Logger, mongoDB *infra.MongoDB)
 	}
 }
 
+// SetOrderEventService 設定訂單事件服務，設定後匯入新增與更新的訂單會記錄訂單事件
+func (s *OrderImportExportService) SetOrderEventService(orderEventService *OrderEventService) {
+	s.orderEventService = orderEventService
+}
+
+// recordOrderEvent 記錄匯入的訂單事件，寫入失敗只記錄錯誤
+func (s *OrderImportExportService) recordOrderEvent(ctx context.Context, event *model.OrderEvent) {
+	if s.orderEventService == nil {
+		return
+	}
+	if err := s.orderEventService.Record(ctx, event); err != nil {
+		s.logger.Error().Err(err).Str("order_id", event.OrderID.Hex()).Str("action", string(event.Action)).Msg("記錄訂單事件失敗")
+	}
+}
+
 // CheckImportOrders 檢查匯入訂單並返回預覽資訊
 func (s *OrderImportExportService) CheckImportOrders(ctx context.Context, fleet string, hasHeader bool, file multipart.File) (int, int, error) {
 	// 讀取 Excel 檔案
@@ -363,8 +379,19 @@ func (s *OrderImportExportService) updateExistingOrder(ctx context.Context, coll
 	filter := bson.M{"_id": orderID}
 	update := bson.M{"$set": updateFields}
 
-	_, err := collection.UpdateOne(ctx, filter, update)
-	return err
+	var previous bson.M
+	if err := collection.FindOneAndUpdate(ctx, filter, update).Decode(&previous); err != nil {
+		return err
+	}
+
+	if changes := diffOrderFields(previous, updateFields); len(changes) > 0 {
+		s.recordOrderEvent(ctx, &model.OrderEvent{
+			OrderID: orderID,
+			Action:  model.OrderEventImportUpdated,
+			Changes: changes,
+		})
+	}
+	return nil
 }
 
 // createNewOrder 建立新訂單
@@ -408,8 +435,19 @@ func (s *OrderImportExportService) createNewOrder(ctx context.Context, collectio
 		Customer: model.Customer{},
 	}
 
-	_, err := collection.InsertOne(ctx, order)
-	return err
+	result, err := collection.InsertOne(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	if orderID, ok := result.InsertedID.(primitive.ObjectID); ok {
+		s.recordOrderEvent(ctx, &model.OrderEvent{
+			OrderID: orderID,
+			Action:  model.OrderEventImported,
+			Details: fmt.Sprintf("匯入訂單 %s - 車隊: %s", shortID, order.Fleet),
+		})
+	}
+	return nil
 }
 
 // convertOrderToExcelRow 將訂單轉換為 Excel 行資料
@@ -122,10 +122,10 @@ func (s *OrderScheduleService) CalcDistanceAndMins(ctx context.Context, driverIn
 	return distanceKm, estPickupMins, nil
 }
 
-// CancelScheduledOrder 取消預約訂單，並重置司機的預約單狀態
+// CancelScheduledOrder 後台取消預約訂單，並重置司機的預約單狀態
 func (s *OrderScheduleService) CancelScheduledOrder(ctx context.Context, orderID string, cancelReason string, cancelledBy string) (*model.Order, error) {
 	// 1. 使用 OrderService 的統一取消服務
-	updatedOrder, err := s.orderService.CancelOrder(ctx, orderID, cancelReason, cancelledBy)
+	updatedOrder, err := s.orderService.CancelOrder(ctx, orderID, model.OrderActorAdmin, cancelReason, cancelledBy)
 	if err != nil {
 		return nil, err
 	}
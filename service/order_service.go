@@ -23,6 +23,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// minScheduleLeadTime 預約時間距今至少需要的時間，不足時視為即時單
+const minScheduleLeadTime = 30 * time.Minute
+
 // CurrentOrderInfo 包含當前訂單及相關狀態信息
 type CurrentOrderInfo struct {
 	Order        *model.Order       `json:"order"`
@@ -31,15 +34,20 @@ type CurrentOrderInfo struct {
 }
 
 type OrderService struct {
-	logger              zerolog.Logger
-	mongoDB             *infra.MongoDB
-	rabbitMQ            *infra.RabbitMQ
-	googleService       *GoogleMapService
-	crawlerService      *CrawlerService
-	driverService       *DriverService
-	eventManager        *infra.RedisEventManager // 事件管理器
-	fcmService          interfaces.FCMService    // FCM 推送服務
-	notificationService *NotificationService     // 統一通知服務
+	logger               zerolog.Logger
+	mongoDB              *infra.MongoDB
+	rabbitMQ             *infra.RabbitMQ
+	googleService        *GoogleMapService
+	crawlerService       *CrawlerService
+	driverService        *DriverService
+	eventManager         *infra.RedisEventManager // 事件管理器
+	fcmService           interfaces.FCMService    // FCM 推送服務
+	notificationService  *NotificationService     // 統一通知服務
+	serviceZoneService   *ServiceZoneService      // 服務區域規則
+	fleetService         *FleetService            // 車隊登錄資料
+	tariffService        *TariffService           // 車資計算
+	customerGroupService *CustomerGroupService    // 客群資料
+	orderEventService    *OrderEventService       // 訂單事件稽核紀錄
 }
 
 func NewOrderService(logger zerolog.Logger, mongoDB *infra.MongoDB, rabbitMQ *infra.RabbitMQ, googleService *GoogleMapService, crawlerService *CrawlerService, eventManager *infra.RedisEventManager) *OrderService {
@@ -68,6 +76,52 @@ func (s *OrderService) SetNotificationService(notificationService *NotificationS
 	s.notificationService = notificationService
 }
 
+// SetServiceZoneService 設定服務區域服務，設定後建單時會檢查上車點是否位於不服務區域
+func (s *OrderService) SetServiceZoneService(serviceZoneService *ServiceZoneService) {
+	s.serviceZoneService = serviceZoneService
+}
+
+// SetFleetService 設定車隊登錄服務，建單時依登錄的車隊與客群前綴決定訂單車隊
+func (s *OrderService) SetFleetService(fleetService *FleetService) {
+	s.fleetService = fleetService
+}
+
+// SetTariffService 設定車資計算服務，設定後建單時依費率估算車資
+func (s *OrderService) SetTariffService(tariffService *TariffService) {
+	s.tariffService = tariffService
+}
+
+// SetCustomerGroupService 設定客群服務，建單時檢查客群是否可下單並套用客群預設車隊
+func (s *OrderService) SetCustomerGroupService(customerGroupService *CustomerGroupService) {
+	s.customerGroupService = customerGroupService
+}
+
+// SetOrderEventService 設定訂單事件服務，設定後訂單日誌與狀態變更同步寫入 order_events
+func (s *OrderService) SetOrderEventService(orderEventService *OrderEventService) {
+	s.orderEventService = orderEventService
+}
+
+// resolveFleet 決定訂單車隊，未設定車隊登錄服務時使用預設車隊
+func (s *OrderService) resolveFleet(fleet, customerGroup string) (model.FleetType, error) {
+	if s.fleetService == nil {
+		return resolveFleetFrom(model.DefaultFleets(), fleet, customerGroup)
+	}
+	return s.fleetService.ResolveFleet(fleet, customerGroup)
+}
+
+// cancellationPolicy 取得訂單車隊的取消政策，未設定車隊登錄服務時使用預設車隊
+func (s *OrderService) cancellationPolicy(fleet model.FleetType) model.CancellationPolicy {
+	if s.fleetService != nil {
+		return s.fleetService.FleetOrUnregistered(fleet).Settings.Cancellation
+	}
+	return model.DefaultFleetFor(fleet).Settings.Cancellation
+}
+
+// PassengerNoShowFee 依車隊取消政策計算乘客未到的費用，尚未達到等候時間時回傳錯誤
+func (s *OrderService) PassengerNoShowFee(order *model.Order, now time.Time) (*model.CancellationFee, error) {
+	return s.cancellationPolicy(order.Fleet).NoShowFeeFor(order, now)
+}
+
 // GetDriverService 獲取司機服務實例
 func (s *OrderService) GetDriverService() *DriverService {
 	return s.driverService
@@ -319,16 +373,34 @@ func (s *OrderService) CreateOrder(ctx context.Context, order *model.Order) (*mo
 			return order, fmt.Errorf("上車地點查詢失敗 (Pickup address query failed): %w", err)
 		}
 	}
-	//if order.Customer.InputDestAddress != "" {
-	//	resolved, lat, lng, err := s.resolveAddress(ctx, order.Customer.InputDestAddress, string(order.Fleet), "")
-	//	if err == nil {
-	//		order.Customer.DestAddress = resolved
-	//		latStr := fmt.Sprintf("%.6f", lat)
-	//		lngStr := fmt.Sprintf("%.6f", lng)
-	//		order.Customer.DestLat = &latStr
-	//		order.Customer.DestLng = &lngStr
-	//	}
-	//}
+
+	// 檢查上車點是否位於拒絕建單的服務區域
+	if s.serviceZoneService != nil {
+		restriction, err := s.serviceZoneService.ResolveOrderRestriction(ctx, order)
+		if err != nil {
+			s.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("查詢服務區域限制失敗，略過區域檢查")
+		} else if restriction.IsRejected() {
+			s.logger.Warn().
+				Str("short_id", order.ShortID).
+				Str("pickup_address", order.Customer.PickupAddress).
+				Str("zone", restriction.RejectZone).
+				Msg("上車地點位於不服務區域，拒絕建立訂單")
+			return nil, &ZoneRejectError{Zone: restriction.RejectZone, Message: restriction.RejectMessage}
+		}
+	}
+
+	// 多點行程：解析各停靠點並估算各段行駛時間，全程距離與時間供車資估算
+	// 單一目的地：解析目的地並估算上車點到目的地的距離與時間
+	if len(order.Waypoints) > 0 {
+		s.resolveWaypoints(ctx, order)
+	} else if order.Customer.InputDestAddress != "" {
+		s.resolveDestination(ctx, order)
+	}
+
+	// 依費率估算車資（無預估距離或無費率時不估算）
+	if s.tariffService != nil {
+		order.FareEstimate = s.tariffService.EstimateFare(ctx, order)
+	}
 
 	collection := s.mongoDB.GetCollection("orders")
 	_, err := collection.InsertOne(ctx, order)
@@ -341,7 +413,11 @@ func (s *OrderService) CreateOrder(ctx context.Context, order *model.Order) (*mo
 	if order.Rounds != nil {
 		currentRounds = *order.Rounds
 	}
-	if err := s.AddOrderLog(ctx, order.ID.Hex(), model.OrderLogActionCreated, "", "", "", "", fmt.Sprintf("訂單建立 - 車隊: %s", order.Fleet), currentRounds); err != nil {
+	logCtx := ctx
+	if actor, ok := createdByEventActor(order); ok {
+		logCtx = WithOrderEventActor(ctx, actor)
+	}
+	if err := s.AddOrderLog(logCtx, order.ID.Hex(), model.OrderLogActionCreated, "", "", "", "", fmt.Sprintf("訂單建立 - 車隊: %s", order.Fleet), currentRounds); err != nil {
 		s.logger.Error().Err(err).Msg("添加訂單建立日誌失敗 (Failed to add order creation log)")
 	}
 
@@ -371,67 +447,18 @@ func (s *OrderService) SimpleCreateOrder(ctx context.Context, orderText string,
 		return nil, fmt.Errorf("輸入文字為空 (Input text is empty)")
 	}
 
-	// 使用改進的 ExOriText 解析完整的文字輸入
-	customerGroup, address, remarks, scheduledTime, isErrand := utils.ExOriText(line)
-
-	// 檢查是否成功解析出客群
-	if customerGroup == "" {
-		status = metrics.StatusError
-		s.logger.Warn().Str("input", line).Msg("無效格式 (Invalid format)")
-		return nil, fmt.Errorf("無效格式：期望 'CustomerGroup / Details' (Invalid format: expected 'CustomerGroup / Details')")
-	}
-
 	// 設置建立者名稱，如果提供了 createdByName 參數則使用，否則使用類型作為默認值
 	createdByUserName := string(createdBy)
 	if len(createdByName) > 0 && createdByName[0] != "" {
 		createdByUserName = createdByName[0]
 	}
 
-	// 偵測訂單中的關鍵字
-	hasPets := strings.ContainsAny(orderText, "狗貓寵籠")
-	hasOverloaded := strings.Contains(orderText, "5人") || strings.Contains(orderText, "五人") ||
-		strings.Contains(orderText, "6人") || strings.Contains(orderText, "六人")
-
-	order := &model.Order{
-		OriText:        orderText,                     // 保存原始輸入文字
-		OriTextDisplay: customerGroup + "/" + address, // 保存客群/地址部分，不含hint內容
-		Hints:          remarks,                       // hints 統一設為空白
-		CreatedBy:      createdByUserName,             // 設置建立者姓名
-		CreatedType:    string(createdBy),             // 設置建立者類型
-		IsErrand:       isErrand,                      // 設置跑腿標記
-		HasPets:        hasPets,                       // 設置寵物標記
-		HasOverloaded:  hasOverloaded,                 // 設置超載標記
-	}
-
-	// 設置客群和車隊
-	order.CustomerGroup = customerGroup
-
-	// 使用傳入的 fleet 參數設置車隊，如果未指定則根據客群前綴自動判斷
-	if fleet != "" {
-		switch strings.ToUpper(fleet) {
-		case "RSK":
-			order.Fleet = model.FleetTypeRSK
-		case "KD":
-			order.Fleet = model.FleetTypeKD
-		case "WEI":
-			order.Fleet = model.FleetTypeWEI
-		default:
-			return nil, fmt.Errorf("無效的車隊類型: %s", fleet)
-		}
-	} else {
-		// 如果沒有指定 fleet，則根據客群前綴自動判斷 (customerGroup已轉為大寫)
-		if strings.HasPrefix(customerGroup, "R") {
-			order.Fleet = model.FleetTypeRSK
-		} else if strings.HasPrefix(customerGroup, "E") {
-			order.Fleet = model.FleetTypeKD
-		} else {
-			order.Fleet = model.FleetTypeWEI
-		}
+	order, scheduledTime, err := s.newOrderFromText(ctx, orderText, fleet, createdBy, createdByUserName)
+	if err != nil {
+		status = metrics.StatusError
+		return nil, err
 	}
 
-	// 直接使用解析出的地址，讓 CreateOrder 中的 resolveAddress 處理地址解析和快取
-	processedAddress := address
-
 	// 預設為即時單
 	order.Type = model.OrderTypeInstant
 	order.IsScheduled = false
@@ -441,7 +468,7 @@ func (s *OrderService) SimpleCreateOrder(ctx context.Context, orderText string,
 		// 檢查預約時間是否超過30分鐘
 		now := utils.NowUTC()
 		timeDiff := scheduledTime.Sub(now)
-		minScheduleThreshold := 30 * time.Minute
+		minScheduleThreshold := minScheduleLeadTime
 
 		if timeDiff >= minScheduleThreshold {
 			// 預約單（時間足夠）
@@ -451,9 +478,6 @@ func (s *OrderService) SimpleCreateOrder(ctx context.Context, orderText string,
 		}
 	}
 
-	order.Customer.InputPickupAddress = processedAddress
-	order.Customer.Remarks = remarks
-
 	// 呼叫現有的 CreateOrder 函數來處理單一訂單的創建
 	createdOrder, err := s.CreateOrder(ctx, order)
 	if err != nil {
@@ -476,12 +500,102 @@ func (s *OrderService) SimpleCreateOrder(ctx context.Context, orderText string,
 	}, nil
 }
 
+// newOrderFromText 解析訂單文字（客群/地址 備註 時間）建立尚未寫入的訂單，並檢查客群、決定車隊與多點行程停靠點
+// 回傳文字中解析出的預約時間，由呼叫端決定訂單類型
+func (s *OrderService) newOrderFromText(ctx context.Context, orderText string, fleet string, createdBy model.CreatedBy, createdByUserName string) (*model.Order, *time.Time, error) {
+	// 使用改進的 ExOriText 解析完整的文字輸入
+	line := strings.TrimSpace(orderText)
+	customerGroup, address, remarks, scheduledTime, isErrand := utils.ExOriText(line)
+
+	// 檢查是否成功解析出客群
+	if customerGroup == "" {
+		s.logger.Warn().Str("input", line).Msg("無效格式 (Invalid format)")
+		return nil, nil, fmt.Errorf("無效格式：期望 'CustomerGroup / Details' (Invalid format: expected 'CustomerGroup / Details')")
+	}
+
+	// 偵測訂單中的關鍵字
+	hasPets := strings.ContainsAny(orderText, "狗貓寵籠")
+	hasOverloaded := strings.Contains(orderText, "5人") || strings.Contains(orderText, "五人") ||
+		strings.Contains(orderText, "6人") || strings.Contains(orderText, "六人")
+
+	order := &model.Order{
+		OriText:        orderText,                     // 保存原始輸入文字
+		OriTextDisplay: customerGroup + "/" + address, // 保存客群/地址部分，不含hint內容
+		Hints:          remarks,                       // hints 統一設為空白
+		CreatedBy:      createdByUserName,             // 設置建立者姓名
+		CreatedType:    string(createdBy),             // 設置建立者類型
+		IsErrand:       isErrand,                      // 設置跑腿標記
+		HasPets:        hasPets,                       // 設置寵物標記
+		HasOverloaded:  hasOverloaded,                 // 設置超載標記
+	}
+
+	// 設置客群和車隊
+	order.CustomerGroup = customerGroup
+
+	// 檢查客群是否可下單（停用或嚴格模式下未登錄的客群會被拒絕），客群有預設車隊時優先使用
+	if s.customerGroupService != nil {
+		group, err := s.customerGroupService.CheckOrderAllowed(ctx, customerGroup)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("customer_group", customerGroup).Msg("客群不可建立訂單")
+			return nil, nil, err
+		}
+		if fleet == "" && group != nil && group.DefaultFleet != "" {
+			fleet = string(group.DefaultFleet)
+		}
+	}
+
+	// 使用傳入的 fleet 參數設置車隊，如果未指定則依車隊登錄的客群前綴自動判斷 (customerGroup已轉為大寫)
+	resolvedFleet, err := s.resolveFleet(fleet, customerGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	order.Fleet = resolvedFleet
+
+	// 直接使用解析出的地址，讓 CreateOrder 中的 resolveAddress 處理地址解析和快取
+	processedAddress := address
+
+	// 多點行程：地址以 > 分隔停靠點，來回行程最後返回上車點
+	if pickupAddress, stops, isRoundTrip := utils.SplitWaypoints(address, remarks); len(stops) > 0 {
+		processedAddress = pickupAddress
+		order.Waypoints = buildWaypoints(pickupAddress, stops, isRoundTrip)
+		order.IsRoundTrip = isRoundTrip
+	}
+
+	order.Customer.InputPickupAddress = processedAddress
+	order.Customer.Remarks = remarks
+
+	return order, scheduledTime, nil
+}
+
+// CreateRecurringOccurrence 依週期性預約的訂單文字建立指定日期的預約單
+func (s *OrderService) CreateRecurringOccurrence(ctx context.Context, recurring *model.RecurringOrder, occurrenceDate string, scheduledAt time.Time) (*model.Order, error) {
+	order, _, err := s.newOrderFromText(ctx, recurring.OriText, string(recurring.Fleet), model.CreatedBySystem, recurring.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := scheduledAt.UTC()
+	order.Type = model.OrderTypeScheduled
+	order.ScheduledAt = &scheduled
+	order.IsScheduled = true
+	order.RecurringOrderID = recurring.ID.Hex()
+	order.OccurrenceDate = occurrenceDate
+
+	return s.CreateOrder(ctx, order)
+}
+
 func (s *OrderService) publishOrderToQueue(order *model.Order) error {
 	if s.rabbitMQ == nil {
 		s.logger.Warn().Msg("RabbitMQ服務不可用，跳過隊列發布 (RabbitMQ service not available, skipping queue publish)")
 		return nil
 	}
 
+	if order.Type != model.OrderTypeScheduled {
+		if err := s.MarkOrderEnqueued(context.Background(), order); err != nil {
+			s.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("記錄訂單排入隊列時間失敗")
+		}
+	}
+
 	b, err := json.Marshal(order)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("序列化訂單失敗 (Failed to marshal order for queue)")
@@ -515,7 +629,30 @@ func (s *OrderService) publishOrderToQueue(order *model.Order) error {
 	)
 }
 
-func (s *OrderService) RedispatchOrder(ctx context.Context, orderID string) (*model.Order, error) {
+// MarkOrderEnqueued 記錄即時單排入派單隊列的時間，並隨訊息一起發布：中斷訂單恢復依此判斷訂單是否仍在隊列中排隊，
+// 調度中心收到排入時間較舊的訊息時，表示訂單已重新排入，舊訊息直接略過。寫入失敗時訊息仍帶有排入時間
+func (s *OrderService) MarkOrderEnqueued(ctx context.Context, order *model.Order) error {
+	// 資料庫只保存到毫秒，訊息中的時間需一致才能比對
+	now := utils.NowUTC().Truncate(time.Millisecond)
+	order.EnqueuedAt = &now
+	if order.ID == nil {
+		return nil
+	}
+
+	_, err := s.mongoDB.GetCollection("orders").UpdateOne(ctx, bson.M{"_id": order.ID}, bson.M{"$set": bson.M{"enqueued_at": now}})
+	if err != nil {
+		return fmt.Errorf("更新訂單排入隊列時間失敗: %w", err)
+	}
+	return nil
+}
+
+// RequeueOrder 將等待接單中的訂單重新發布到派單隊列（不重設狀態與輪數），供中斷訂單恢復使用
+func (s *OrderService) RequeueOrder(order *model.Order) error {
+	return s.publishOrderToQueue(order)
+}
+
+// RedispatchOrder 重新派單：依訂單狀態機將訂單重設為等待接單、清除司機並增加派單輪數
+func (s *OrderService) RedispatchOrder(ctx context.Context, orderID string, actor model.OrderActor) (*model.Order, error) {
 	startTime := time.Now()
 	status := metrics.StatusSuccess
 
@@ -533,6 +670,11 @@ func (s *OrderService) RedispatchOrder(ctx context.Context, orderID string) (*mo
 	}
 
 	// 2. Reset status and clear previous dispatch information
+	previousStatus := order.Status
+	if _, err := model.CheckOrderTransition(previousStatus, model.OrderStatusWaiting, actor); err != nil {
+		status = metrics.StatusError
+		return nil, err
+	}
 	order.Status = model.OrderStatusWaiting
 	order.Driver = model.Driver{}
 
@@ -549,12 +691,22 @@ func (s *OrderService) RedispatchOrder(ctx context.Context, orderID string) (*mo
 	order.CreatedAt = &now
 
 	// 3. Save the updated order
-	updatedOrder, err := s.UpdateOrder(ctx, order)
+	updatedOrder, err := s.replaceOrder(ctx, order, previousStatus)
 	if err != nil {
 		s.logger.Error().Str("order_id", orderID).Err(err).Msg("重新派單失敗，無法更新訂單 (Redispatch failed, cannot update order)")
 		return nil, fmt.Errorf("重新派單失敗，無法更新訂單 (Redispatch failed, cannot update order): %w", err)
 	}
 
+	// 清除上一輪的搶單得標紀錄，否則新一輪的司機會被判定為訂單已被接走
+	s.clearOrderWinner(ctx, orderID)
+
+	s.recordOrderEvent(ctx, &model.OrderEvent{
+		OrderID: *order.ID,
+		Action:  model.OrderEventRedispatched,
+		Changes: []model.OrderFieldChange{{Field: "status", Before: string(previousStatus), After: string(model.OrderStatusWaiting)}},
+		Details: fmt.Sprintf("第 %d 輪派單", *order.Rounds),
+	})
+
 	// 4. Publish to the queue to trigger dispatcher
 	if err := s.publishOrderToQueue(updatedOrder); err != nil {
 		// Even if publishing fails, the order is already updated in DB.
@@ -568,6 +720,16 @@ func (s *OrderService) RedispatchOrder(ctx context.Context, orderID string) (*mo
 	return updatedOrder, nil
 }
 
+// clearOrderWinner 清除搶單得標紀錄，訂單回到等待接單或取消後讓下一位接單的司機不會被舊紀錄擋下
+func (s *OrderService) clearOrderWinner(ctx context.Context, orderID string) {
+	if s.eventManager == nil {
+		return
+	}
+	if err := s.eventManager.ClearOrderWinner(ctx, orderID); err != nil {
+		s.logger.Error().Err(err).Str("order_id", orderID).Msg("清除搶單得標紀錄失敗")
+	}
+}
+
 func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*model.Order, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -858,14 +1020,55 @@ func (s *OrderService) GetDispatchOrdersWithFilterAndSort(ctx context.Context, p
 	return orders, total, nil
 }
 
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, id string, status model.OrderStatus) (*model.Order, error) {
+// transitionFilter 依訂單狀態機組出原子更新條件：訂單目前狀態必須可由 actor 轉為 to
+func transitionFilter(objectID primitive.ObjectID, to model.OrderStatus, actor model.OrderActor) bson.M {
+	return bson.M{
+		"_id":    objectID,
+		"status": bson.M{"$in": model.OrderTransitionSources(to, actor)},
+	}
+}
+
+// transitionFromFilter 指定來源狀態的原子更新條件（接單、激活等 CAS 操作），狀態機不允許時回傳錯誤
+func transitionFromFilter(objectID primitive.ObjectID, from, to model.OrderStatus, actor model.OrderActor) (bson.M, error) {
+	if _, err := model.CheckOrderTransition(from, to, actor); err != nil {
+		return nil, err
+	}
+	return bson.M{"_id": objectID, "status": from}, nil
+}
+
+// statusEditFilter 後台編輯訂單時的更新條件：狀態未變更，或可由 actor 轉為新狀態
+func statusEditFilter(objectID primitive.ObjectID, to model.OrderStatus, actor model.OrderActor) bson.M {
+	return bson.M{
+		"_id":    objectID,
+		"status": bson.M{"$in": append(model.OrderTransitionSources(to, actor), to)},
+	}
+}
+
+// orderTransitionFailure 原子更新未命中時判斷原因：訂單不存在時回傳 mongo.ErrNoDocuments，
+// 狀態機不允許時回傳 *model.IllegalTransitionError
+func orderTransitionFailure(ctx context.Context, collection *mongo.Collection, objectID primitive.ObjectID, to model.OrderStatus, actor model.OrderActor) error {
+	var current model.Order
+	opts := options.FindOne().SetProjection(bson.M{"status": 1})
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&current); err != nil {
+		return err
+	}
+	if current.Status != to {
+		if _, err := model.CheckOrderTransition(current.Status, to, actor); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("訂單狀態已變更為「%s」，請重新操作", current.Status)
+}
+
+// UpdateOrderStatus 依訂單狀態機更新訂單狀態，不允許的轉換回傳 *model.IllegalTransitionError（不套用副作用）
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, id string, status model.OrderStatus, actor model.OrderActor) (*model.Order, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
 	collection := s.mongoDB.GetCollection("orders")
-	filter := bson.M{"_id": objectID}
+	filter := transitionFilter(objectID, status, actor)
 	update := bson.M{
 		"$set": bson.M{
 			"status":     status,
@@ -876,6 +1079,9 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, id string, status
 
 	var updatedOrder model.Order
 	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedOrder)
+	if err == mongo.ErrNoDocuments {
+		return nil, orderTransitionFailure(ctx, collection, objectID, status, actor)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -883,7 +1089,109 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, id string, status
 	return &updatedOrder, nil
 }
 
-// UpdateOrderStatusAndCertificate 更新訂單狀態、證明照片和拍照狀態，但不觸發事件（避免重複通知）
+// ChangeOrderStatus 後台修改訂單狀態：依訂單狀態機檢查後更新，並套用該轉換宣告的司機狀態與通知
+func (s *OrderService) ChangeOrderStatus(ctx context.Context, id string, status model.OrderStatus, actor model.OrderActor) (*model.Order, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := s.mongoDB.GetCollection("orders")
+	update := bson.M{
+		"$set": bson.M{
+			"status":     status,
+			"updated_at": utils.NowUTC(),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var previousOrder model.Order
+	err = collection.FindOneAndUpdate(ctx, transitionFilter(objectID, status, actor), update, opts).Decode(&previousOrder)
+	if err == mongo.ErrNoDocuments {
+		return nil, orderTransitionFailure(ctx, collection, objectID, status, actor)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updatedOrder, err := s.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == model.OrderStatusWaiting || status == model.OrderStatusCancelled {
+		s.clearOrderWinner(ctx, id)
+	}
+
+	if transition := model.FindOrderTransition(previousOrder.Status, status); transition != nil {
+		s.applyTransitionEffects(ctx, updatedOrder, transition)
+	}
+
+	s.recordOrderEvent(ctx, &model.OrderEvent{
+		OrderID: objectID,
+		Action:  model.OrderEventStatusChanged,
+		Changes: []model.OrderFieldChange{{Field: "status", Before: string(previousOrder.Status), After: string(status)}},
+	})
+
+	s.logger.Info().
+		Str("order_id", id).
+		Str("from", string(previousOrder.Status)).
+		Str("to", string(status)).
+		Str("actor", string(actor)).
+		Msg("訂單狀態已修改")
+
+	return updatedOrder, nil
+}
+
+// applyTransitionEffects 套用狀態轉換宣告的副作用：調整指派司機狀態並發送通知
+func (s *OrderService) applyTransitionEffects(ctx context.Context, order *model.Order, transition *model.OrderTransition) {
+	orderID := order.ID.Hex()
+	driver := &model.DriverInfo{Name: order.Driver.Name, CarPlate: order.Driver.CarNo}
+
+	if driverID := order.Driver.AssignedDriver; driverID != "" && s.driverService != nil {
+		if info, err := s.driverService.GetDriverByID(ctx, driverID); err == nil && info != nil {
+			driver = info
+		}
+
+		if transition.DriverStatus == model.DriverStatusIdle && !driver.ID.IsZero() {
+			if err := s.driverService.ReleaseDriverAfterOrder(ctx, driver, order); err != nil {
+				s.logger.Error().Err(err).Str("order_id", orderID).Str("driver_id", driverID).Msg("狀態轉換後釋放司機失敗")
+			}
+		} else if transition.DriverStatus != "" {
+			if err := s.driverService.UpdateDriverStatusType(ctx, driverID, transition.DriverStatus, "後台修改訂單狀態", orderID); err != nil {
+				s.logger.Error().Err(err).Str("order_id", orderID).Str("driver_id", driverID).Msg("狀態轉換後更新司機狀態失敗")
+			}
+		}
+	}
+
+	if s.notificationService == nil {
+		return
+	}
+
+	var err error
+	switch transition.Notify {
+	case model.OrderNotifyAccepted:
+		err = s.notificationService.NotifyOrderAccepted(ctx, orderID, driver)
+	case model.OrderNotifyArrived:
+		err = s.notificationService.NotifyDriverArrived(ctx, orderID, driver, false)
+	case model.OrderNotifyOnBoard:
+		err = s.notificationService.NotifyCustomerOnBoard(ctx, orderID, driver)
+	case model.OrderNotifyCompleted:
+		err = s.notificationService.NotifyOrderCompleted(ctx, orderID, driver)
+	case model.OrderNotifyCancelled:
+		err = s.notificationService.NotifyOrderCancelled(ctx, orderID, driver)
+	case model.OrderNotifyFailed:
+		err = s.notificationService.NotifyOrderFailed(ctx, orderID, "後台修改訂單狀態")
+	}
+	if err != nil {
+		s.logger.Error().Err(err).
+			Str("order_id", orderID).
+			Str("notify", string(transition.Notify)).
+			Msg("狀態轉換通知發送失敗")
+	}
+}
+
+// UpdateOrderStatusAndCertificate 司機更新訂單狀態、證明照片和拍照狀態，但不觸發事件（避免重複通知）
 func (s *OrderService) UpdateOrderStatusAndCertificate(ctx context.Context, id string, status model.OrderStatus, certURL string, isPhotoTaken ...bool) (*model.Order, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -891,7 +1199,7 @@ func (s *OrderService) UpdateOrderStatusAndCertificate(ctx context.Context, id s
 	}
 
 	collection := s.mongoDB.GetCollection("orders")
-	filter := bson.M{"_id": objectID}
+	filter := transitionFilter(objectID, status, model.OrderActorDriver)
 	updateFields := bson.M{
 		"status":                 status,
 		"pickup_certificate_url": certURL,
@@ -908,6 +1216,9 @@ func (s *OrderService) UpdateOrderStatusAndCertificate(ctx context.Context, id s
 
 	var updatedOrder model.Order
 	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedOrder)
+	if err == mongo.ErrNoDocuments {
+		return nil, orderTransitionFailure(ctx, collection, objectID, status, model.OrderActorDriver)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -915,7 +1226,7 @@ func (s *OrderService) UpdateOrderStatusAndCertificate(ctx context.Context, id s
 	return &updatedOrder, nil
 }
 
-// UpdateOrderStatusWithPickupTime 更新訂單狀態並設置客人上車時間，但不觸發事件（避免重複通知）
+// UpdateOrderStatusWithPickupTime 司機更新訂單狀態並設置客人上車時間，但不觸發事件（避免重複通知）
 func (s *OrderService) UpdateOrderStatusWithPickupTime(ctx context.Context, id string, status model.OrderStatus, hasMeterJump bool, requestTime time.Time) (*model.Order, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -923,7 +1234,7 @@ func (s *OrderService) UpdateOrderStatusWithPickupTime(ctx context.Context, id s
 	}
 
 	collection := s.mongoDB.GetCollection("orders")
-	filter := bson.M{"_id": objectID}
+	filter := transitionFilter(objectID, status, model.OrderActorDriver)
 	update := bson.M{
 		"$set": bson.M{
 			"status":         status,
@@ -936,6 +1247,9 @@ func (s *OrderService) UpdateOrderStatusWithPickupTime(ctx context.Context, id s
 
 	var updatedOrder model.Order
 	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedOrder)
+	if err == mongo.ErrNoDocuments {
+		return nil, orderTransitionFailure(ctx, collection, objectID, status, model.OrderActorDriver)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1084,7 +1398,7 @@ func (s *OrderService) UpdateOrderPhotoTakenStatus(ctx context.Context, id strin
 }
 
 // CancelOrder 統一的訂單取消服務，處理所有通知和狀態更新
-func (s *OrderService) CancelOrder(ctx context.Context, orderID string, cancelReason string, cancelledBy string) (*model.Order, error) {
+func (s *OrderService) CancelOrder(ctx context.Context, orderID string, actor model.OrderActor, cancelReason string, cancelledBy string) (*model.Order, error) {
 	startTime := time.Now()
 	status := metrics.StatusSuccess
 	source := metrics.DetermineSourceFromCreatedBy(cancelledBy)
@@ -1102,32 +1416,44 @@ func (s *OrderService) CancelOrder(ctx context.Context, orderID string, cancelRe
 		return nil, fmt.Errorf("訂單不存在: %w", err)
 	}
 
-	// 2. 驗證訂單狀態 - 允許在 Waiting、Enroute 或 DriverArrived 狀態下取消
-	if currentOrder.Status != model.OrderStatusWaiting &&
-		currentOrder.Status != model.OrderStatusScheduleAccepted &&
-		currentOrder.Status != model.OrderStatusEnroute &&
-		currentOrder.Status != model.OrderStatusDriverArrived {
-		return nil, fmt.Errorf("訂單狀態為 %s，無法取消。只有「等待接單」、「前往上車點」或「司機抵達」狀態的訂單可以取消", currentOrder.Status)
+	// 2. 驗證訂單狀態 - 依訂單狀態機，允許在 Waiting、ScheduleAccepted、Enroute 或 DriverArrived 狀態下取消
+	if _, err := model.CheckOrderTransition(currentOrder.Status, model.OrderStatusCancelled, actor); err != nil {
+		status = metrics.StatusError
+		return nil, err
 	}
 
 	// 3. 同步更新訂單狀態為已取消並通知 dispatcher
 	// 統一記錄為「乘客取消」
 	logReason := "乘客取消"
-	if err := s.UpdateOrderStatusWithEvent(ctx, orderID, model.OrderStatusCancelled, logReason, "", nil); err != nil {
+	if err := s.UpdateOrderStatusWithEvent(ctx, orderID, model.OrderStatusCancelled, actor, logReason, "", nil); err != nil {
 		s.logger.Error().Err(err).Str("order_id", orderID).Msg("更新訂單狀態為取消失敗")
 		return nil, fmt.Errorf("更新訂單狀態失敗: %w", err)
 	}
 
+	s.clearOrderWinner(ctx, orderID)
+
+	// 依車隊取消政策計算取消費並記錄在訂單上
+	cancellationFee := s.cancellationPolicy(currentOrder.Fleet).CancelFee(currentOrder, startTime)
+	cancellationFee.ChargedBy = cancelledBy
+	if err := s.setCancellationFee(ctx, orderID, cancellationFee); err != nil {
+		s.logger.Error().Err(err).Str("order_id", orderID).Int("fee", cancellationFee.Amount).Msg("記錄取消費失敗")
+	}
+	logDetails := logReason
+	if cancellationFee.Amount > 0 {
+		logDetails = fmt.Sprintf("%s，取消費 %d 元（%s）", logReason, cancellationFee.Amount, cancellationFee.Rule)
+	}
+
 	// 獲取更新後的訂單
 	updatedOrder, err := s.GetOrderByID(ctx, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("獲取更新後訂單失敗: %w", err)
 	}
 
-	// 4. 異步處理所有後續操作
+	// 4. 異步處理所有後續操作（保留取消者作為訂單事件的操作者）
+	logCtx := WithOrderEventActor(context.Background(), OrderEventActorFromContext(ctx))
 	go func() {
 		// 記錄 order log
-		if logErr := s.AddOrderLog(context.Background(), orderID, model.OrderLogActionDispatchCancel, "", "", "", "", logReason, 0); logErr != nil {
+		if logErr := s.AddOrderLog(logCtx, orderID, model.OrderLogActionDispatchCancel, "", "", "", "", logDetails, 0); logErr != nil {
 			s.logger.Error().Err(logErr).
 				Str("order_id", orderID).
 				Str("reason", logReason).
@@ -1224,24 +1550,90 @@ func (s *OrderService) CancelOrder(ctx context.Context, orderID string, cancelRe
 		Str("short_id", updatedOrder.ShortID).
 		Str("previous_status", string(currentOrder.Status)).
 		Str("cancelled_by", cancelledBy).
+		Int("cancellation_fee", cancellationFee.Amount).
 		Msg("訂單已成功取消")
 
 	return updatedOrder, nil
 }
 
+// setCancellationFee 記錄訂單的取消費
+func (s *OrderService) setCancellationFee(ctx context.Context, orderID string, fee *model.CancellationFee) error {
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return err
+	}
+
+	collection := s.mongoDB.GetCollection("orders")
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"cancellation_fee": fee,
+			"updated_at":       utils.NowUTC(),
+		},
+	})
+	return err
+}
+
+// CompleteAsNoShow 將司機抵達狀態的訂單以乘客未到完成，並記錄乘客未到費用
+func (s *OrderService) CompleteAsNoShow(ctx context.Context, orderID string, driverID string, fee *model.CancellationFee, completionTime time.Time) (*model.Order, error) {
+	objectID, err := primitive.ObjectIDFromHex(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := transitionFromFilter(objectID, model.OrderStatusDriverArrived, model.OrderStatusCompleted, model.OrderActorDriver)
+	if err != nil {
+		return nil, err
+	}
+	filter["driver.assigned_driver"] = driverID
+
+	collection := s.mongoDB.GetCollection("orders")
+	update := bson.M{
+		"$set": bson.M{
+			"status":           model.OrderStatusCompleted,
+			"completion_time":  completionTime,
+			"cancellation_fee": fee,
+			"updated_at":       utils.NowUTC(),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updatedOrder model.Order
+	if err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedOrder); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("訂單狀態已變更，無法回報乘客未到")
+		}
+		return nil, err
+	}
+	return &updatedOrder, nil
+}
+
+// UpdateOrder 以整份文件覆寫訂單（不可變更狀態），訂單狀態已被其他流程變更時拒絕覆寫，避免以過期資料倒退狀態
 func (s *OrderService) UpdateOrder(ctx context.Context, order *model.Order) (*model.Order, error) {
+	return s.replaceOrder(ctx, order, order.Status)
+}
+
+// replaceOrder 以整份文件覆寫訂單，資料庫中的訂單狀態必須仍為 expectedStatus
+func (s *OrderService) replaceOrder(ctx context.Context, order *model.Order, expectedStatus model.OrderStatus) (*model.Order, error) {
 	if order.ID == nil {
 		s.logger.Error().Msg("訂單ID為空 (Order ID is empty)")
 		return nil, fmt.Errorf("訂單ID為空 (Order ID is empty)")
 	}
 	collection := s.mongoDB.GetCollection("orders")
-	filter := bson.M{"_id": *order.ID}
+	filter := bson.M{"_id": *order.ID, "status": expectedStatus}
 	now := utils.NowUTC()
 	order.UpdatedAt = &now
-	_, err := collection.ReplaceOne(ctx, filter, order)
+	result, err := collection.ReplaceOne(ctx, filter, order)
 	if err != nil {
 		return nil, err
 	}
+	if result.MatchedCount == 0 {
+		var current model.Order
+		if err := collection.FindOne(ctx, bson.M{"_id": *order.ID}).Decode(&current); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: 訂單狀態已變更為「%s」，無法覆寫為「%s」",
+			model.ErrIllegalOrderTransition, current.Status, order.Status)
+	}
 	return order, nil
 }
 
@@ -1286,8 +1678,11 @@ func (s *OrderService) AcceptOrderAction(ctx context.Context, orderID string, dr
 	}
 
 	// 直接執行原子性更新（CAS操作），不做額外查詢
+	filter, err := transitionFromFilter(objectID, model.OrderStatusWaiting, matchedStatus, model.OrderActorDriver)
+	if err != nil {
+		return false, err
+	}
 	collection := s.mongoDB.GetCollection("orders")
-	filter := bson.M{"_id": objectID, "status": model.OrderStatusWaiting}
 	update := bson.M{
 		"$set": bson.M{
 			"driver":          driver,
@@ -1313,8 +1708,11 @@ func (s *OrderService) AcceptScheduledOrderWithCondition(ctx context.Context, or
 	}
 
 	// 預約單接受，分配司機並設置為已接受狀態
+	filter, err := transitionFromFilter(objectID, matchedStatus, model.OrderStatusScheduleAccepted, model.OrderActorDriver)
+	if err != nil {
+		return false, err
+	}
 	collection := s.mongoDB.GetCollection("orders")
-	filter := bson.M{"_id": objectID, "status": model.OrderStatusWaiting}
 	update := bson.M{
 		"$set": bson.M{
 			"driver":     driver,
@@ -1339,8 +1737,11 @@ func (s *OrderService) ActivateScheduledOrderWithCondition(ctx context.Context,
 	}
 
 	// 預約單激活後設置為前往上車點狀態
+	filter, err := transitionFromFilter(objectID, matchedStatus, model.OrderStatusEnroute, model.OrderActorDriver)
+	if err != nil {
+		return false, err
+	}
 	collection := s.mongoDB.GetCollection("orders")
-	filter := bson.M{"_id": objectID, "status": model.OrderStatusScheduleAccepted}
 	update := bson.M{
 		"$set": bson.M{
 			"driver":          driver,
@@ -1437,8 +1838,8 @@ func (s *OrderService) GetUnassignedScheduledOrders(ctx context.Context) ([]*mod
 	return orders, nil
 }
 
-// 新增：統一的訂單狀態更新方法(帶事件發佈)
-func (s *OrderService) UpdateOrderStatusWithEvent(ctx context.Context, orderID string, newStatus model.OrderStatus, reason string, driverID string, details map[string]interface{}) error {
+// 新增：統一的訂單狀態更新方法(帶事件發佈)，依訂單狀態機檢查 actor 是否可進行此轉換
+func (s *OrderService) UpdateOrderStatusWithEvent(ctx context.Context, orderID string, newStatus model.OrderStatus, actor model.OrderActor, reason string, driverID string, details map[string]interface{}) error {
 	objectID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
 		s.logger.Error().Str("order_id", orderID).Err(err).Msg("無效的訂單ID格式")
@@ -1468,8 +1869,11 @@ func (s *OrderService) UpdateOrderStatusWithEvent(ctx context.Context, orderID s
 		return nil
 	}
 
-	// 更新訂單狀態
-	filter := bson.M{"_id": objectID}
+	// 更新訂單狀態（以讀取到的狀態作為條件，避免與其他流程同時轉換）
+	filter, err := transitionFromFilter(objectID, oldStatus, newStatus, actor)
+	if err != nil {
+		return err
+	}
 	update := bson.M{
 		"$set": bson.M{
 			"status":     newStatus,
@@ -1477,7 +1881,7 @@ func (s *OrderService) UpdateOrderStatusWithEvent(ctx context.Context, orderID s
 		},
 	}
 
-	_, err = collection.UpdateOne(ctx, filter, update)
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		s.logger.Error().
 			Str("order_id", orderID).
@@ -1487,6 +1891,9 @@ func (s *OrderService) UpdateOrderStatusWithEvent(ctx context.Context, orderID s
 			Msg("更新訂單狀態失敗")
 		return err
 	}
+	if result.MatchedCount == 0 {
+		return orderTransitionFailure(ctx, collection, objectID, newStatus, actor)
+	}
 
 	// 發布訂單狀態變更事件
 	if s.eventManager != nil {
@@ -1576,9 +1983,9 @@ func (s *OrderService) MatchOrder(ctx context.Context, orderID primitive.ObjectI
 	}
 
 	// 條件：訂單必須是 "等待接單" 狀態
-	filter := bson.M{
-		"_id":    orderID,
-		"status": model.OrderStatusWaiting,
+	filter, err := transitionFromFilter(orderID, model.OrderStatusWaiting, model.OrderStatusEnroute, model.OrderActorDriver)
+	if err != nil {
+		return err
 	}
 
 	result, err := orders.UpdateOne(ctx, filter, update)
@@ -1597,15 +2004,16 @@ func (s *OrderService) MatchOrder(ctx context.Context, orderID primitive.ObjectI
 }
 
 // FailOrder marks an order as failed, usually when no driver accepts it.
-func (s *OrderService) FailOrder(ctx context.Context, orderID primitive.ObjectID, reason string) error {
+// It reports whether the order was transitioned; false means the order had already left the waiting status.
+func (s *OrderService) FailOrder(ctx context.Context, orderID primitive.ObjectID, reason string) (bool, error) {
 	//s.logger.Info().Str("order_id", orderID.Hex()).Str("reason", reason).Msg("嘗試將訂單設為流單 (Attempting to fail order)")
 	collection := s.mongoDB.GetCollection("orders")
 
 	// Atomically find an order that is in "Waiting" status and update it to "Failed".
 	// This prevents a race condition where a driver accepts the order just as the dispatcher tries to fail it.
-	filter := bson.M{
-		"_id":    orderID,
-		"status": model.OrderStatusWaiting,
+	filter, err := transitionFromFilter(orderID, model.OrderStatusWaiting, model.OrderStatusFailed, model.OrderActorSystem)
+	if err != nil {
+		return false, err
 	}
 
 	update := bson.M{
@@ -1618,13 +2026,13 @@ func (s *OrderService) FailOrder(ctx context.Context, orderID primitive.ObjectID
 	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		s.logger.Error().Str("order_id", orderID.Hex()).Err(err).Msg("執行流單更新失敗 (Failed to execute update for failing order)")
-		return err
+		return false, err
 	}
 
 	if result.MatchedCount == 0 {
 		s.logger.Info().Str("order_id", orderID.Hex()).Msg("訂單未能設為流單，可能已被接單或取消 (Order was not failed, likely already accepted or cancelled)")
 		// Returning nil because this is not a system error. The order was already in a final state.
-		return nil
+		return false, nil
 	}
 
 	// 成功更新後，發布訂單失敗事件
@@ -1654,7 +2062,7 @@ func (s *OrderService) FailOrder(ctx context.Context, orderID primitive.ObjectID
 	}
 
 	//s.logger.Info().Str("order_id", orderID.Hex()).Msg("訂單成功設為流單 (Order successfully failed)")
-	return nil
+	return true, nil
 }
 
 // UpdateOrderTripDetails 更新訂單中從上車點到目的地的行程預估資訊
@@ -1920,10 +2328,35 @@ func (s *OrderService) AddOrderLog(ctx context.Context, orderID string, action m
 		return fmt.Errorf("添加訂單日誌失敗 (Failed to add order log): %w", err)
 	}
 
+	// 同步寫入訂單事件，context 沒有操作者時以日誌的司機為操作者
+	event := &model.OrderEvent{OrderID: objectID, Action: model.OrderEventLog, LogAction: action, Details: details}
+	if actor, ok := orderEventActorFromContext(ctx); ok {
+		event.Actor = actor
+	} else if driverID != "" {
+		event.Actor = model.OrderEventActor{Type: model.OrderEventActorDriver, ID: driverID, Name: driverName}
+	}
+	if rounds > 0 {
+		event.Details = strings.TrimSpace(fmt.Sprintf("%s（第 %d 輪）", details, rounds))
+	}
+	s.recordOrderEvent(ctx, event)
+
 	//s.logger.Info().Str("order_id", orderID).Str("action", string(action)).Str("driver_name", driverName).Str("car_plate", carPlate).Msg("新增訂單日誌 (Added order log)")
 	return nil
 }
 
+// recordOrderEvent 寫入訂單事件稽核紀錄，未設定訂單事件服務時略過；寫入失敗只記錄錯誤，不影響訂單流程
+func (s *OrderService) recordOrderEvent(ctx context.Context, event *model.OrderEvent) {
+	if s.orderEventService == nil {
+		return
+	}
+	if err := s.orderEventService.Record(ctx, event); err != nil {
+		s.logger.Error().Err(err).
+			Str("order_id", event.OrderID.Hex()).
+			Str("action", string(event.Action)).
+			Msg("記錄訂單事件失敗")
+	}
+}
+
 // RecordCancelingOrder 記錄取消中訂單到 Redis，供司機查詢
 func (s *OrderService) RecordCancelingOrder(ctx context.Context, order *model.Order, cancelReason string, timeoutSeconds int) error {
 	// 檢查訂單是否有分配的司機
@@ -2160,6 +2593,9 @@ func (s *OrderService) UpdateOrderSummary(ctx context.Context, id string, update
 
 	collection := s.mongoDB.GetCollection("orders")
 	filter := bson.M{"_id": objectID}
+	if updateData.Status != nil {
+		filter = statusEditFilter(objectID, *updateData.Status, model.OrderActorAdmin)
+	}
 
 	updateFields := bson.M{
 		"updated_at": utils.NowUTC(),
@@ -2193,6 +2629,9 @@ func (s *OrderService) UpdateOrderSummary(ctx context.Context, id string, update
 
 	var updatedOrder model.Order
 	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedOrder)
+	if err == mongo.ErrNoDocuments && updateData.Status != nil {
+		return nil, orderTransitionFailure(ctx, collection, objectID, *updateData.Status, model.OrderActorAdmin)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -2804,3 +3243,316 @@ func (s *OrderService) GetFailedOrders(ctx context.Context, driver *model.Driver
 
 	return result, int(total), nil
 }
+
+// buildWaypoints 依解析出的停靠點建立訂單停靠點，來回行程最後加上返回上車點
+func buildWaypoints(pickup string, stops []string, isRoundTrip bool) []model.Waypoint {
+	waypoints := make([]model.Waypoint, 0, len(stops)+1)
+	for _, stop := range stops {
+		waypoints = append(waypoints, model.Waypoint{InputAddress: stop})
+	}
+	if isRoundTrip {
+		waypoints = append(waypoints, model.Waypoint{InputAddress: pickup, IsReturn: true})
+	}
+	return waypoints
+}
+
+// resolveWaypoints 解析各停靠點地址並估算各段行駛距離與時間，最後一站作為訂單目的地
+// 停靠點解析失敗不影響建單，僅該站與其後的預估無法計算
+func (s *OrderService) resolveWaypoints(ctx context.Context, order *model.Order) {
+	prevLat, prevLng := order.Customer.PickupLat, order.Customer.PickupLng
+	totalKm, totalMins := 0.0, 0
+	allLegsKnown := true
+
+	for i := range order.Waypoints {
+		wp := &order.Waypoints[i]
+		wp.Seq = i + 1
+		wp.Status = model.WaypointStatusPending
+		wp.Address, wp.Lat, wp.Lng = "", nil, nil
+		wp.EstMinsFromPrev, wp.EstDistKmFromPrev, wp.EstArrivalAt = nil, nil, nil
+		wp.ArrivedAt, wp.CompletedAt = nil, nil
+
+		if wp.IsReturn {
+			// 回程直接使用上車點的解析結果
+			wp.Address = order.Customer.PickupAddress
+			wp.Lat, wp.Lng = order.Customer.PickupLat, order.Customer.PickupLng
+		} else if resolved, lat, lng, err := s.resolveAddress(ctx, wp.InputAddress, string(order.Fleet), ""); err == nil && resolved != "" {
+			latStr := fmt.Sprintf("%.6f", lat)
+			lngStr := fmt.Sprintf("%.6f", lng)
+			wp.Address = resolved
+			wp.Lat, wp.Lng = &latStr, &lngStr
+		} else {
+			s.logger.Warn().Err(err).
+				Str("short_id", order.ShortID).
+				Int("seq", wp.Seq).
+				Str("input_address", wp.InputAddress).
+				Msg("停靠點地址解析失敗，略過該站預估")
+		}
+
+		if prevLat == nil || prevLng == nil || !wp.HasLocation() {
+			allLegsKnown = false
+			prevLat, prevLng = wp.Lat, wp.Lng
+			continue
+		}
+
+		km, mins, err := s.estimateLeg(ctx, *prevLat+","+*prevLng, *wp.Lat+","+*wp.Lng)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("short_id", order.ShortID).Int("seq", wp.Seq).Msg("停靠點行駛時間預估失敗")
+			allLegsKnown = false
+		} else {
+			wp.EstDistKmFromPrev = &km
+			wp.EstMinsFromPrev = &mins
+			totalKm += km
+			totalMins += mins
+		}
+		prevLat, prevLng = wp.Lat, wp.Lng
+	}
+
+	// 最後一站即為目的地
+	last := order.Waypoints[len(order.Waypoints)-1]
+	order.Customer.InputDestAddress = last.InputAddress
+	order.Customer.DestAddress = last.Address
+	order.Customer.DestLat, order.Customer.DestLng = last.Lat, last.Lng
+
+	// 所有路段都有預估時才填入全程距離與時間，避免以部分路段估算車資
+	if allLegsKnown {
+		dist := fmt.Sprintf("%.1f", totalKm)
+		order.Customer.EstPickToDestDist = &dist
+		order.Customer.EstPickToDestMins = &totalMins
+	}
+
+	s.logger.Info().
+		Str("short_id", order.ShortID).
+		Int("waypoints", len(order.Waypoints)).
+		Bool("round_trip", order.IsRoundTrip).
+		Bool("all_legs_known", allLegsKnown).
+		Float64("total_km", totalKm).
+		Int("total_mins", totalMins).
+		Msg("多點行程停靠點解析完成")
+}
+
+// resolveDestination 解析單一目的地地址並估算上車點到目的地的行駛距離與時間
+// 目的地解析或路線預估失敗不影響建單，僅不估算車資
+func (s *OrderService) resolveDestination(ctx context.Context, order *model.Order) {
+	resolved, lat, lng, err := s.resolveAddress(ctx, order.Customer.InputDestAddress, string(order.Fleet), "")
+	if err != nil || resolved == "" {
+		s.logger.Warn().Err(err).
+			Str("short_id", order.ShortID).
+			Str("input_dest_address", order.Customer.InputDestAddress).
+			Msg("目的地地址解析失敗，略過行程預估")
+		return
+	}
+	latStr := fmt.Sprintf("%.6f", lat)
+	lngStr := fmt.Sprintf("%.6f", lng)
+	order.Customer.DestAddress = resolved
+	order.Customer.DestLat = &latStr
+	order.Customer.DestLng = &lngStr
+
+	if order.Customer.PickupLat == nil || order.Customer.PickupLng == nil {
+		return
+	}
+	km, mins, err := s.estimateLeg(ctx, *order.Customer.PickupLat+","+*order.Customer.PickupLng, latStr+","+lngStr)
+	if err != nil {
+		s.logger.Warn().Err(err).Str("short_id", order.ShortID).Msg("上車點到目的地行駛時間預估失敗")
+		return
+	}
+	dist := fmt.Sprintf("%.1f", km)
+	order.Customer.EstPickToDestDist = &dist
+	order.Customer.EstPickToDestMins = &mins
+
+	s.logger.Info().
+		Str("short_id", order.ShortID).
+		Str("dest_address", resolved).
+		Float64("km", km).
+		Int("mins", mins).
+		Msg("目的地解析完成")
+}
+
+// estimateLeg 估算兩點間的行駛距離與時間，優先使用爬蟲，失敗時改用 Google Distance Matrix
+func (s *OrderService) estimateLeg(ctx context.Context, origin, destination string) (float64, int, error) {
+	if s.crawlerService != nil {
+		routes, err := s.crawlerService.GetGoogleMapsDirections(ctx, origin, destination)
+		if err == nil && len(routes) > 0 {
+			km := routes[0].DistanceKm
+			if km == 0 {
+				km = utils.ParseDistanceToKm(routes[0].Distance)
+			}
+			return km, routes[0].TimeInMinutes, nil
+		}
+		s.logger.Debug().Err(err).Str("origin", origin).Str("destination", destination).Msg("爬蟲路線查詢失敗，改用 Google Distance Matrix")
+	}
+
+	if s.googleService == nil {
+		return 0, 0, fmt.Errorf("無可用的路線服務")
+	}
+	km, mins, err := s.googleService.DistanceMatrix(ctx, origin, destination)
+	if err != nil {
+		return 0, 0, err
+	}
+	if km == 0 && mins == 0 {
+		return 0, 0, fmt.Errorf("無法取得路線資訊: %s -> %s", origin, destination)
+	}
+	return km, mins, nil
+}
+
+// scheduleWaypointETAs 從指定時間起依各段預估行駛時間推算尚未抵達停靠點的預估抵達時間
+// 某段沒有預估時，該站與其後各站的預估抵達時間皆清空
+func scheduleWaypointETAs(waypoints []model.Waypoint, from time.Time) {
+	eta := from
+	known := true
+	for i := range waypoints {
+		wp := &waypoints[i]
+		if wp.Status != model.WaypointStatusPending {
+			continue
+		}
+		if !known || wp.EstMinsFromPrev == nil {
+			known = false
+			wp.EstArrivalAt = nil
+			continue
+		}
+		eta = eta.Add(time.Duration(*wp.EstMinsFromPrev) * time.Minute)
+		arrival := eta
+		wp.EstArrivalAt = &arrival
+	}
+}
+
+// waypointETAUpdate 產生更新停靠點預估抵達時間的欄位
+func waypointETAUpdate(waypoints []model.Waypoint, fields bson.M) {
+	for i, wp := range waypoints {
+		if wp.Status != model.WaypointStatusPending {
+			continue
+		}
+		fields[fmt.Sprintf("waypoints.%d.est_arrival_at", i)] = wp.EstArrivalAt
+	}
+}
+
+// ScheduleWaypointETAs 客人上車後依各段預估行駛時間推算停靠點的預估抵達時間
+func (s *OrderService) ScheduleWaypointETAs(ctx context.Context, order *model.Order, pickupTime time.Time) error {
+	if len(order.Waypoints) == 0 {
+		return nil
+	}
+
+	scheduleWaypointETAs(order.Waypoints, pickupTime)
+	fields := bson.M{}
+	waypointETAUpdate(order.Waypoints, fields)
+
+	_, err := s.mongoDB.GetCollection("orders").UpdateOne(ctx, bson.M{"_id": order.ID}, bson.M{"$set": fields})
+	if err != nil {
+		s.logger.Error().Err(err).Str("order_id", order.ID.Hex()).Msg("更新停靠點預估抵達時間失敗")
+		return err
+	}
+	return nil
+}
+
+// UpdateWaypointStatus 司機回報停靠點抵達或完成，停靠點須依序處理，完成後重新推算後續各站的預估抵達時間
+func (s *OrderService) UpdateWaypointStatus(ctx context.Context, orderID, driverID string, seq int, status model.WaypointStatus, requestTime time.Time) (*model.Order, error) {
+	if status != model.WaypointStatusArrived && status != model.WaypointStatusCompleted {
+		return nil, fmt.Errorf("無效的停靠點狀態: %s", status)
+	}
+
+	order, err := s.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Driver.AssignedDriver != driverID {
+		return nil, fmt.Errorf("訂單未指派給此司機")
+	}
+	if order.Status != model.OrderStatusExecuting {
+		return nil, fmt.Errorf("訂單狀態為%s，客人上車後才能回報停靠點", order.Status)
+	}
+
+	idx := -1
+	for i, wp := range order.Waypoints {
+		if wp.Seq == seq {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return nil, fmt.Errorf("找不到停靠點: %d", seq)
+	}
+	current := order.CurrentWaypoint()
+	if current == nil {
+		return nil, fmt.Errorf("所有停靠點皆已完成")
+	}
+	if current.Seq != seq {
+		return nil, fmt.Errorf("停靠點須依序回報，目前應處理第%d站", current.Seq)
+	}
+
+	wp := &order.Waypoints[idx]
+	prevStatus := wp.Status
+	fields := bson.M{"updated_at": requestTime}
+	fields[fmt.Sprintf("waypoints.%d.status", idx)] = status
+
+	switch status {
+	case model.WaypointStatusArrived:
+		if prevStatus != model.WaypointStatusPending {
+			return nil, fmt.Errorf("第%d站已回報抵達", seq)
+		}
+		fields[fmt.Sprintf("waypoints.%d.arrived_at", idx)] = requestTime
+	case model.WaypointStatusCompleted:
+		// 未回報抵達直接完成時，抵達時間同完成時間
+		if prevStatus == model.WaypointStatusPending {
+			fields[fmt.Sprintf("waypoints.%d.arrived_at", idx)] = requestTime
+		}
+		fields[fmt.Sprintf("waypoints.%d.completed_at", idx)] = requestTime
+		wp.Status = status
+		scheduleWaypointETAs(order.Waypoints, requestTime)
+		waypointETAUpdate(order.Waypoints, fields)
+	}
+
+	// 以原狀態作為條件，避免重複回報或同時操作
+	filter := bson.M{
+		"_id":                    order.ID,
+		"status":                 model.OrderStatusExecuting,
+		"driver.assigned_driver": driverID,
+	}
+	filter[fmt.Sprintf("waypoints.%d.status", idx)] = prevStatus
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Order
+	err = s.mongoDB.GetCollection("orders").FindOneAndUpdate(ctx, filter, bson.M{"$set": fields}, opts).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("停靠點狀態已變更，請重新整理")
+		}
+		s.logger.Error().Err(err).Str("order_id", orderID).Int("seq", seq).Msg("更新停靠點狀態失敗")
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("order_id", orderID).
+		Str("driver_id", driverID).
+		Int("seq", seq).
+		Str("status", string(status)).
+		Msg("停靠點狀態已更新")
+	return &updated, nil
+}
+
+// formatWaypointLine 停靠點顯示文字（狀態、順序、地址與時間），供 Discord 卡片與 LINE 訊息使用
+func formatWaypointLine(wp model.Waypoint) string {
+	icon := "⬜"
+	switch wp.Status {
+	case model.WaypointStatusArrived:
+		icon = "📍"
+	case model.WaypointStatusCompleted:
+		icon = "✅"
+	}
+
+	line := fmt.Sprintf("%s %d. %s", icon, wp.Seq, wp.DisplayAddress())
+	if wp.IsReturn {
+		line += "（回程）"
+	}
+
+	taipeiLocation := utils.GetTaipeiLocation()
+	switch {
+	case wp.CompletedAt != nil:
+		line += " 完成 " + wp.CompletedAt.In(taipeiLocation).Format("15:04")
+	case wp.ArrivedAt != nil:
+		line += " 抵達 " + wp.ArrivedAt.In(taipeiLocation).Format("15:04")
+	case wp.EstArrivalAt != nil:
+		line += " 預計 " + wp.EstArrivalAt.In(taipeiLocation).Format("15:04")
+	case wp.EstMinsFromPrev != nil:
+		line += fmt.Sprintf(" 約%d分鐘", *wp.EstMinsFromPrev)
+	}
+	return line
+}
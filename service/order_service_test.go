@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// fakeGoogleTransport 依 Google API 路徑回傳固定的回應
+type fakeGoogleTransport map[string]string
+
+func (f fakeGoogleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for path, body := range f {
+		if strings.Contains(req.URL.Path, path) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		}
+	}
+	return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+}
+
+func newTestGoogleMapService(responses fakeGoogleTransport) *GoogleMapService {
+	client := &infra.GoogleClient{HTTPClient: &http.Client{Transport: responses}}
+	return NewGoogleMapService(zerolog.Nop(), client, nil, nil, nil)
+}
+
+func singleDestinationOrder() *model.Order {
+	pickupLat, pickupLng := "24.147700", "120.673600"
+	order := &model.Order{Fleet: model.FleetTypeRSK, ShortID: "#TEST"}
+	order.Customer.PickupAddress = "台中火車站"
+	order.Customer.PickupLat = &pickupLat
+	order.Customer.PickupLng = &pickupLng
+	order.Customer.InputDestAddress = "台中高鐵站"
+	return order
+}
+
+func TestResolveDestinationEstimatesFare(t *testing.T) {
+	google := newTestGoogleMapService(fakeGoogleTransport{
+		"findplacefromtext": `{"candidates":[{"name":"台中高鐵站","formatted_address":"台中市烏日區站區二路8號","geometry":{"location":{"lat":24.1120,"lng":120.6160}}}]}`,
+		"distancematrix":    `{"rows":[{"elements":[{"distance":{"value":10000},"duration":{"value":1200}}]}]}`,
+	})
+	orderService := NewOrderService(zerolog.Nop(), nil, nil, google, nil, nil)
+
+	order := singleDestinationOrder()
+	orderService.resolveDestination(context.Background(), order)
+
+	if order.Customer.DestLat == nil || *order.Customer.DestLat != "24.112000" || *order.Customer.DestLng != "120.616000" {
+		t.Fatalf("預期解析出目的地座標，實際為 %v,%v", order.Customer.DestLat, order.Customer.DestLng)
+	}
+	if order.Customer.EstPickToDestDist == nil || *order.Customer.EstPickToDestDist != "10.0" {
+		t.Fatalf("預期上車點到目的地距離 10.0 公里，實際為 %v", order.Customer.EstPickToDestDist)
+	}
+	if order.Customer.EstPickToDestMins == nil || *order.Customer.EstPickToDestMins != 20 {
+		t.Fatalf("預期上車點到目的地 20 分鐘，實際為 %v", order.Customer.EstPickToDestMins)
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("單一目的地訂單估算車資", func(mt *mtest.T) {
+		mt.AddMockResponses(tariffCursorResponse(mt, testFleetTariff()))
+
+		fare := newTestTariffService(mt).EstimateFare(context.Background(), order)
+		if fare == nil || fare.Total != 320 {
+			mt.Fatalf("預期單一目的地訂單預估車資 320 元，實際為 %+v", fare)
+		}
+	})
+}
+
+func TestResolveDestinationWithoutRoute(t *testing.T) {
+	google := newTestGoogleMapService(fakeGoogleTransport{
+		"findplacefromtext": `{"candidates":[{"name":"台中高鐵站","formatted_address":"台中市烏日區站區二路8號","geometry":{"location":{"lat":24.1120,"lng":120.6160}}}]}`,
+	})
+	orderService := NewOrderService(zerolog.Nop(), nil, nil, google, nil, nil)
+
+	order := singleDestinationOrder()
+	orderService.resolveDestination(context.Background(), order)
+
+	if order.Customer.DestLat == nil {
+		t.Fatal("路線預估失敗時仍應保留目的地座標")
+	}
+	if order.Customer.EstPickToDestDist != nil {
+		t.Fatalf("路線預估失敗時不應填入預估距離，實際為 %s", *order.Customer.EstPickToDestDist)
+	}
+}
+
+func TestFailOrderReportsTransition(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	orderID := primitive.NewObjectID()
+
+	mt.Run("等待接單的訂單設為流單", func(mt *mtest.T) {
+		mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		failed, err := NewOrderService(zerolog.Nop(), mongoDB, nil, nil, nil, nil).FailOrder(context.Background(), orderID, "無司機接單")
+		if err != nil || !failed {
+			mt.Fatalf("預期訂單設為流單，實際 failed=%v err=%v", failed, err)
+		}
+		if status := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q", "status").StringValue(); status != string(model.OrderStatusWaiting) {
+			mt.Fatalf("預期只更新等待接單的訂單，實際條件為 %s", status)
+		}
+	})
+
+	mt.Run("已被接單或取消的訂單不設為流單", func(mt *mtest.T) {
+		mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		failed, err := NewOrderService(zerolog.Nop(), mongoDB, nil, nil, nil, nil).FailOrder(context.Background(), orderID, "無司機接單")
+		if err != nil || failed {
+			mt.Fatalf("預期訂單未轉為流單，實際 failed=%v err=%v", failed, err)
+		}
+	})
+}
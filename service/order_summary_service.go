@@ -11,12 +11,14 @@ import (
 	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OrderSummaryService struct {
-	logger  zerolog.Logger
-	mongoDB *infra.MongoDB
+	logger            zerolog.Logger
+	mongoDB           *infra.MongoDB
+	orderEventService *OrderEventService // 訂單事件稽核紀錄
 }
 
 func NewOrderSummaryService(logger zerolog.Logger, mongoDB *infra.MongoDB) *OrderSummaryService {
@@ -26,6 +28,21 @@ func NewOrderSummaryService(logger zerolog.Logger, mongoDB *infra.MongoDB) *Orde
 	}
 }
 
+// SetOrderEventService 設定訂單事件服務，設定後報表編輯與批量編輯會記錄異動前後的欄位
+func (s *OrderSummaryService) SetOrderEventService(orderEventService *OrderEventService) {
+	s.orderEventService = orderEventService
+}
+
+// recordChanges 記錄報表編輯的欄位異動，寫入失敗只記錄錯誤
+func (s *OrderSummaryService) recordChanges(ctx context.Context, orderID primitive.ObjectID, action model.OrderEventAction, before bson.M, set bson.M) {
+	if s.orderEventService == nil {
+		return
+	}
+	if err := s.orderEventService.RecordChanges(ctx, orderID, action, before, set, ""); err != nil {
+		s.logger.Error().Err(err).Str("order_id", orderID.Hex()).Str("action", string(action)).Msg("記錄訂單事件失敗")
+	}
+}
+
 // OrderSummaryFilter 訂單報表過濾器
 type OrderSummaryFilter struct {
 	StartDate     string
@@ -290,6 +307,9 @@ func (s *OrderSummaryService) UpdateOrderSummary(ctx context.Context, id string,
 
 	collection := s.mongoDB.GetCollection("orders")
 	filter := bson.M{"_id": objectID}
+	if updateData.Status != nil {
+		filter = statusEditFilter(objectID, *updateData.Status, model.OrderActorAdmin)
+	}
 
 	updateFields := bson.M{
 		"updated_at": time.Now(),
@@ -319,15 +339,20 @@ func (s *OrderSummaryService) UpdateOrderSummary(ctx context.Context, id string,
 	}
 
 	update := bson.M{"$set": updateFields}
-	options := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	options := options.FindOneAndUpdate().SetReturnDocument(options.Before)
 
-	var updatedOrder model.Order
-	err = collection.FindOneAndUpdate(ctx, filter, update, options).Decode(&updatedOrder)
+	// 取得異動前的文件以記錄欄位異動
+	var previous bson.M
+	err = collection.FindOneAndUpdate(ctx, filter, update, options).Decode(&previous)
+	if err == mongo.ErrNoDocuments && updateData.Status != nil {
+		return nil, orderTransitionFailure(ctx, collection, objectID, *updateData.Status, model.OrderActorAdmin)
+	}
 	if err != nil {
 		return nil, err
 	}
+	s.recordChanges(ctx, objectID, model.OrderEventEdited, previous, updateFields)
 
-	return &updatedOrder, nil
+	return s.GetOrderByID(ctx, id)
 }
 
 // DeleteOrder 刪除訂單
@@ -418,25 +443,36 @@ func (s *OrderSummaryService) BatchEditOrders(ctx context.Context, orders []orde
 			updateFields["passenger_id"] = *order.Fields.PassengerID
 		}
 
-		// 執行更新
+		// 執行更新（修改狀態時需符合訂單狀態機）
 		filter := bson.M{"_id": objectID}
+		if order.Fields.Status != nil {
+			filter = statusEditFilter(objectID, *order.Fields.Status, model.OrderActorAdmin)
+		}
 		update := bson.M{"$set": updateFields}
 
-		updateResult, err := collection.UpdateOne(ctx, filter, update)
-		if err != nil {
+		var previous bson.M
+		err = collection.FindOneAndUpdate(ctx, filter, update).Decode(&previous)
+		if err != nil && err != mongo.ErrNoDocuments {
 			result.Error = "更新訂單失敗: " + err.Error()
 			results[i] = result
 			s.logger.Error().Str("order_id", order.OrderID).Err(err).Msg("更新訂單失敗")
 			continue
 		}
 
-		if updateResult.MatchedCount == 0 {
+		if err == mongo.ErrNoDocuments {
 			result.Error = "訂單不存在"
+			if order.Fields.Status != nil {
+				if transitionErr := orderTransitionFailure(ctx, collection, objectID, *order.Fields.Status, model.OrderActorAdmin); transitionErr != mongo.ErrNoDocuments {
+					result.Error = transitionErr.Error()
+				}
+			}
 			results[i] = result
-			s.logger.Warn().Str("order_id", order.OrderID).Msg("訂單不存在")
+			s.logger.Warn().Str("order_id", order.OrderID).Str("error", result.Error).Msg("更新訂單失敗")
 			continue
 		}
 
+		s.recordChanges(ctx, objectID, model.OrderEventBatchEdited, previous, updateFields)
+
 		// 成功
 		result.Success = true
 		results[i] = result
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"right-backend/infra"
+	"right-backend/model"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	passengerCollection = "passengers"
+
+	// maxFrequentAddresses 每位乘客保留的常搭乘上車地點數
+	maxFrequentAddresses = 20
+)
+
+// PassengerService 管理乘客資料、LINE 下單時的乘客連結與乘客訂單歷史
+type PassengerService struct {
+	logger  zerolog.Logger
+	mongoDB *infra.MongoDB
+}
+
+func NewPassengerService(logger zerolog.Logger, mongoDB *infra.MongoDB) *PassengerService {
+	return &PassengerService{
+		logger:  logger.With().Str("module", "passenger_service").Logger(),
+		mongoDB: mongoDB,
+	}
+}
+
+// List 分頁列出乘客，關鍵字比對稱呼、電話與 LINE 用戶ID
+func (s *PassengerService) List(ctx context.Context, keyword string, blockedOnly bool, pageNum, pageSize int) ([]*model.Passenger, int64, error) {
+	collection := s.mongoDB.GetCollection(passengerCollection)
+
+	filter := bson.M{}
+	if keyword != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(keyword), "$options": "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"phone": pattern},
+			bson.M{"line_user_id": pattern},
+		}
+	}
+	if blockedOnly {
+		filter["blocked"] = true
+	}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("計算乘客總數失敗")
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"last_order_at": -1}).
+		SetSkip(int64((pageNum - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢乘客失敗")
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	passengers := []*model.Passenger{}
+	if err := cursor.All(ctx, &passengers); err != nil {
+		s.logger.Error().Err(err).Msg("解析乘客失敗")
+		return nil, 0, err
+	}
+	return passengers, total, nil
+}
+
+// Get 取得單一乘客
+func (s *PassengerService) Get(ctx context.Context, id string) (*model.Passenger, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的乘客ID: %s", id)
+	}
+
+	var passenger model.Passenger
+	err = s.mongoDB.GetCollection(passengerCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&passenger)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到乘客: %s", id)
+		}
+		return nil, err
+	}
+	return &passenger, nil
+}
+
+// Create 手動新增乘客
+func (s *PassengerService) Create(ctx context.Context, passenger *model.Passenger, createdBy string) (*model.Passenger, error) {
+	if err := validatePassenger(passenger); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := primitive.NewObjectID()
+	passenger.ID = &id
+	passenger.OrderCount = 0
+	passenger.UpdatedBy = createdBy
+	passenger.CreatedAt = &now
+	passenger.UpdatedAt = &now
+
+	if _, err := s.mongoDB.GetCollection(passengerCollection).InsertOne(ctx, passenger); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("LINE 用戶ID已有乘客資料: %s", passenger.LineUserID)
+		}
+		s.logger.Error().Err(err).Msg("新增乘客失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("passenger_id", id.Hex()).Str("created_by", createdBy).Msg("乘客已新增")
+	return passenger, nil
+}
+
+// Update 更新乘客資料、備註、常用地點與封鎖狀態，LINE 用戶ID與下單統計不可修改
+func (s *PassengerService) Update(ctx context.Context, id string, passenger *model.Passenger, updatedBy string) (*model.Passenger, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的乘客ID: %s", id)
+	}
+	if err := validatePassenger(passenger); err != nil {
+		return nil, err
+	}
+	if !passenger.Blocked {
+		passenger.BlockedReason = ""
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":               passenger.Name,
+		"phone":              passenger.Phone,
+		"notes":              passenger.Notes,
+		"tags":               passenger.Tags,
+		"favorite_addresses": passenger.FavoriteAddresses,
+		"blocked":            passenger.Blocked,
+		"blocked_reason":     passenger.BlockedReason,
+		"updated_by":         updatedBy,
+		"updated_at":         time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Passenger
+	err = s.mongoDB.GetCollection(passengerCollection).FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到乘客: %s", id)
+		}
+		s.logger.Error().Err(err).Str("passenger_id", id).Msg("更新乘客失敗")
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("passenger_id", id).
+		Bool("blocked", updated.Blocked).
+		Str("updated_by", updatedBy).
+		Msg("乘客已更新")
+	return &updated, nil
+}
+
+// EnsureByLineUserID 依 LINE 用戶ID 取得乘客，不存在時自動建立
+func (s *PassengerService) EnsureByLineUserID(ctx context.Context, lineUserID string) (*model.Passenger, error) {
+	if lineUserID == "" {
+		return nil, errors.New("LINE 用戶ID不可為空")
+	}
+
+	now := time.Now()
+	update := bson.M{"$setOnInsert": bson.M{
+		"line_user_id": lineUserID,
+		"blocked":      false,
+		"order_count":  0,
+		"created_at":   now,
+		"updated_at":   now,
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var passenger model.Passenger
+	err := s.mongoDB.GetCollection(passengerCollection).FindOneAndUpdate(ctx, bson.M{"line_user_id": lineUserID}, update, opts).Decode(&passenger)
+	if err != nil {
+		s.logger.Error().Err(err).Str("line_user_id", lineUserID).Msg("取得或建立乘客失敗")
+		return nil, err
+	}
+	return &passenger, nil
+}
+
+// RecordOrder 訂單連結乘客後更新下單統計與常搭乘上車地點
+func (s *PassengerService) RecordOrder(ctx context.Context, passenger *model.Passenger, order *model.Order) {
+	collection := s.mongoDB.GetCollection(passengerCollection)
+	now := time.Now()
+	logger := s.logger.With().Str("passenger_id", passenger.ID.Hex()).Str("order_id", order.ID.Hex()).Logger()
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": passenger.ID}, bson.M{
+		"$inc": bson.M{"order_count": 1},
+		"$set": bson.M{
+			"customer_group": order.CustomerGroup,
+			"last_order_id":  order.ID.Hex(),
+			"last_order_at":  now,
+			"updated_at":     now,
+		},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("更新乘客下單統計失敗")
+		return
+	}
+
+	address := strings.TrimSpace(order.Customer.InputPickupAddress)
+	if address == "" {
+		return
+	}
+
+	// 已有的地點累加次數，否則加入並依次數排序、只保留前幾筆
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": passenger.ID, "frequent_addresses.address": address},
+		bson.M{
+			"$inc": bson.M{"frequent_addresses.$.count": 1},
+			"$set": bson.M{"frequent_addresses.$.last_used_at": now},
+		})
+	if err != nil {
+		logger.Error().Err(err).Msg("更新乘客常搭乘地點失敗")
+		return
+	}
+	if result.MatchedCount > 0 {
+		return
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": passenger.ID}, bson.M{
+		"$push": bson.M{"frequent_addresses": bson.M{
+			"$each":  bson.A{model.FrequentAddress{Address: address, Count: 1, LastUsedAt: now}},
+			"$sort":  bson.D{{Key: "count", Value: -1}, {Key: "last_used_at", Value: -1}},
+			"$slice": maxFrequentAddresses,
+		}},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("新增乘客常搭乘地點失敗")
+	}
+}
+
+// RecentOrders 列出乘客最近的訂單（依建立時間新到舊），可排除指定訂單
+func (s *PassengerService) RecentOrders(ctx context.Context, passenger *model.Passenger, limit int, excludeOrderID string) ([]*model.Order, error) {
+	conditions := bson.A{bson.M{"passenger_id": passenger.ID.Hex()}}
+	if passenger.LineUserID != "" {
+		conditions = append(conditions, bson.M{"customer.line_user_id": passenger.LineUserID})
+	}
+	filter := bson.M{"$or": conditions}
+	if excludeOrderID != "" {
+		if objectID, err := primitive.ObjectIDFromHex(excludeOrderID); err == nil {
+			filter["_id"] = bson.M{"$ne": objectID}
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"logs": 0, "line_messages": 0})
+	cursor, err := s.mongoDB.GetCollection("orders").Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Str("passenger_id", passenger.ID.Hex()).Msg("查詢乘客訂單失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []*model.Order{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ForOrder 取得訂單連結的乘客，未連結時依訂單的 LINE 用戶ID 查詢，都沒有時回傳 nil
+func (s *PassengerService) ForOrder(ctx context.Context, order *model.Order) (*model.Passenger, error) {
+	collection := s.mongoDB.GetCollection(passengerCollection)
+
+	var filter bson.M
+	if objectID, err := primitive.ObjectIDFromHex(order.PassengerID); err == nil {
+		filter = bson.M{"_id": objectID}
+	} else if order.Customer.LineUserID != "" {
+		filter = bson.M{"line_user_id": order.Customer.LineUserID}
+	} else {
+		return nil, nil
+	}
+
+	var passenger model.Passenger
+	if err := collection.FindOne(ctx, filter).Decode(&passenger); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &passenger, nil
+}
+
+// validatePassenger 驗證乘客資料
+func validatePassenger(passenger *model.Passenger) error {
+	passenger.LineUserID = strings.TrimSpace(passenger.LineUserID)
+	for i, favorite := range passenger.FavoriteAddresses {
+		if strings.TrimSpace(favorite.Address) == "" {
+			return fmt.Errorf("第%d個常用地點的地址不可為空", i+1)
+		}
+	}
+	return nil
+}
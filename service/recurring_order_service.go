@@ -0,0 +1,553 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	recurringOrderCollection = "recurring_orders"
+
+	// defaultRecurringDaysAhead 未設定時提前產生幾天內的預約單
+	defaultRecurringDaysAhead = 3
+	// maxRecurringDaysAhead 提前產生與查詢單次預約的最大天數
+	maxRecurringDaysAhead = 60
+)
+
+// RecurringOrderService 管理週期性預約，並依規則提前產生預約單
+type RecurringOrderService struct {
+	logger               zerolog.Logger
+	mongoDB              *infra.MongoDB
+	orderService         *OrderService
+	orderScheduleService *OrderScheduleService
+}
+
+func NewRecurringOrderService(logger zerolog.Logger, mongoDB *infra.MongoDB) *RecurringOrderService {
+	return &RecurringOrderService{
+		logger:  logger.With().Str("module", "recurring_order_service").Logger(),
+		mongoDB: mongoDB,
+	}
+}
+
+// SetOrderService 設定訂單服務，產生預約單時使用
+func (s *RecurringOrderService) SetOrderService(orderService *OrderService) {
+	s.orderService = orderService
+}
+
+// SetOrderScheduleService 設定預約單服務，略過單次或取消系列時取消已產生的預約單
+func (s *RecurringOrderService) SetOrderScheduleService(orderScheduleService *OrderScheduleService) {
+	s.orderScheduleService = orderScheduleService
+}
+
+// DaysAhead 提前產生幾天內的預約單
+func (s *RecurringOrderService) DaysAhead() int {
+	days := infra.AppConfig.RecurringOrders.DaysAhead
+	if days <= 0 {
+		return defaultRecurringDaysAhead
+	}
+	return min(days, maxRecurringDaysAhead)
+}
+
+// List 分頁列出週期性預約，關鍵字比對名稱、訂單文字與客群
+func (s *RecurringOrderService) List(ctx context.Context, keyword string, status model.RecurringOrderStatus, pageNum, pageSize int) ([]*model.RecurringOrder, int64, error) {
+	collection := s.mongoDB.GetCollection(recurringOrderCollection)
+
+	filter := bson.M{}
+	if keyword != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(keyword), "$options": "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"ori_text": pattern},
+			bson.M{"customer_group": pattern},
+		}
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("計算週期性預約總數失敗")
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64((pageNum - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢週期性預約失敗")
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	recurringOrders := []*model.RecurringOrder{}
+	if err := cursor.All(ctx, &recurringOrders); err != nil {
+		s.logger.Error().Err(err).Msg("解析週期性預約失敗")
+		return nil, 0, err
+	}
+	return recurringOrders, total, nil
+}
+
+// Get 取得單一週期性預約
+func (s *RecurringOrderService) Get(ctx context.Context, id string) (*model.RecurringOrder, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的週期性預約ID: %s", id)
+	}
+
+	var recurring model.RecurringOrder
+	err = s.mongoDB.GetCollection(recurringOrderCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&recurring)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到週期性預約: %s", id)
+		}
+		return nil, err
+	}
+	return &recurring, nil
+}
+
+// Create 新增週期性預約，下次排程執行時開始產生預約單
+func (s *RecurringOrderService) Create(ctx context.Context, recurring *model.RecurringOrder, createdBy string) (*model.RecurringOrder, error) {
+	if err := validateRecurringOrder(recurring); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := primitive.NewObjectID()
+	recurring.ID = &id
+	recurring.Status = model.RecurringOrderActive
+	recurring.CreatedBy = createdBy
+	recurring.UpdatedBy = createdBy
+	recurring.CreatedAt = &now
+	recurring.UpdatedAt = &now
+
+	if _, err := s.mongoDB.GetCollection(recurringOrderCollection).InsertOne(ctx, recurring); err != nil {
+		s.logger.Error().Err(err).Msg("新增週期性預約失敗")
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("recurring_order_id", id.Hex()).
+		Str("name", recurring.Name).
+		Str("frequency", string(recurring.Rule.Frequency)).
+		Str("created_by", createdBy).
+		Msg("週期性預約已新增")
+	return recurring, nil
+}
+
+// Update 更新週期性預約的名稱、訂單文字、車隊與規則，已產生的預約單不受影響
+func (s *RecurringOrderService) Update(ctx context.Context, id string, recurring *model.RecurringOrder, updatedBy string) (*model.RecurringOrder, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的週期性預約ID: %s", id)
+	}
+	if err := validateRecurringOrder(recurring); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":           recurring.Name,
+		"ori_text":       recurring.OriText,
+		"customer_group": recurring.CustomerGroup,
+		"fleet":          recurring.Fleet,
+		"rule":           recurring.Rule,
+		"updated_by":     updatedBy,
+		"updated_at":     time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.RecurringOrder
+	err = s.mongoDB.GetCollection(recurringOrderCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "status": model.RecurringOrderActive}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到啟用中的週期性預約: %s", id)
+		}
+		s.logger.Error().Err(err).Str("recurring_order_id", id).Msg("更新週期性預約失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("recurring_order_id", id).Str("updated_by", updatedBy).Msg("週期性預約已更新")
+	return &updated, nil
+}
+
+// SkipOccurrence 略過單次預約：將日期加入例外日期，若該日預約單已產生且尚未開始則一併取消
+func (s *RecurringOrderService) SkipOccurrence(ctx context.Context, id, date, reason, actor string) (*model.RecurringOrder, *model.Order, error) {
+	recurring, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if recurring.Status != model.RecurringOrderActive {
+		return nil, nil, fmt.Errorf("週期性預約已取消")
+	}
+	if slices.Contains(recurring.Rule.ExceptionDates, date) {
+		return nil, nil, fmt.Errorf("%s 已略過", date)
+	}
+	if !recurring.Rule.OccursOn(date) {
+		return nil, nil, fmt.Errorf("%s 不在週期規則內", date)
+	}
+
+	_, err = s.mongoDB.GetCollection(recurringOrderCollection).UpdateOne(ctx,
+		bson.M{"_id": recurring.ID},
+		bson.M{
+			"$addToSet": bson.M{"rule.exception_dates": date},
+			"$set":      bson.M{"updated_by": actor, "updated_at": time.Now()},
+		})
+	if err != nil {
+		s.logger.Error().Err(err).Str("recurring_order_id", id).Str("date", date).Msg("略過單次預約失敗")
+		return nil, nil, err
+	}
+	recurring.Rule.ExceptionDates = append(recurring.Rule.ExceptionDates, date)
+
+	// 該日預約單已產生時取消
+	var cancelled *model.Order
+	orders, err := s.generatedOrders(ctx, id, bson.M{"occurrence_date": date})
+	if err != nil {
+		return recurring, nil, err
+	}
+	if order, ok := orders[date]; ok {
+		cancelled, err = s.cancelOccurrenceOrder(ctx, order, "略過單次週期預約 "+reason, actor)
+		if err != nil {
+			return recurring, nil, fmt.Errorf("已略過 %s，但取消已產生的預約單失敗: %w", date, err)
+		}
+	}
+
+	s.logger.Info().
+		Str("recurring_order_id", id).
+		Str("date", date).
+		Bool("order_cancelled", cancelled != nil).
+		Str("actor", actor).
+		Msg("已略過單次週期預約")
+	return recurring, cancelled, nil
+}
+
+// CancelSeries 取消整個週期性預約系列，並取消已產生且尚未開始的預約單，回傳取消的預約單數
+func (s *RecurringOrderService) CancelSeries(ctx context.Context, id, reason, actor string) (*model.RecurringOrder, int, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, 0, fmt.Errorf("無效的週期性預約ID: %s", id)
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":        model.RecurringOrderCancelled,
+		"cancel_reason": reason,
+		"cancelled_by":  actor,
+		"cancelled_at":  now,
+		"updated_by":    actor,
+		"updated_at":    now,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var recurring model.RecurringOrder
+	err = s.mongoDB.GetCollection(recurringOrderCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "status": model.RecurringOrderActive}, update, opts).Decode(&recurring)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, 0, fmt.Errorf("找不到啟用中的週期性預約: %s", id)
+		}
+		return nil, 0, err
+	}
+
+	orders, err := s.generatedOrders(ctx, id, bson.M{"scheduled_at": bson.M{"$gt": now}})
+	if err != nil {
+		return &recurring, 0, err
+	}
+
+	cancelledCount := 0
+	for _, order := range orders {
+		if _, err := s.cancelOccurrenceOrder(ctx, order, "取消週期預約系列 "+reason, actor); err != nil {
+			s.logger.Error().Err(err).Str("order_id", order.ID.Hex()).Msg("取消週期預約產生的預約單失敗")
+			continue
+		}
+		cancelledCount++
+	}
+
+	s.logger.Info().
+		Str("recurring_order_id", id).
+		Int("cancelled_orders", cancelledCount).
+		Str("actor", actor).
+		Msg("週期性預約系列已取消")
+	return &recurring, cancelledCount, nil
+}
+
+// Occurrences 列出今天起指定天數內的單次預約（含已略過的日期）與已產生的預約單
+func (s *RecurringOrderService) Occurrences(ctx context.Context, id string, days int) ([]model.RecurringOccurrence, error) {
+	recurring, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 列出時包含已略過的日期
+	rule := recurring.Rule
+	rule.ExceptionDates = nil
+	dates := occurrenceDates(&rule, time.Now(), min(days, maxRecurringDaysAhead))
+	if len(dates) == 0 {
+		return []model.RecurringOccurrence{}, nil
+	}
+
+	orders, err := s.generatedOrders(ctx, id, bson.M{"occurrence_date": bson.M{"$in": dates}})
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]model.RecurringOccurrence, 0, len(dates))
+	for _, date := range dates {
+		scheduledAt, err := occurrenceTime(date, recurring.Rule.TimeOfDay)
+		if err != nil {
+			return nil, err
+		}
+		occurrence := model.RecurringOccurrence{
+			Date:        date,
+			ScheduledAt: scheduledAt,
+			Skipped:     slices.Contains(recurring.Rule.ExceptionDates, date),
+		}
+		if order, ok := orders[date]; ok {
+			occurrence.OrderID = order.ID.Hex()
+			occurrence.ShortID = order.ShortID
+			occurrence.OrderStatus = order.Status
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// GenerateUpcoming 依所有啟用中的週期性預約，產生今天起 DaysAhead 天內尚未產生的預約單，回傳產生的數量
+// 預約時間距今不足預約單最短前置時間的單次會略過；同一日期只會產生一次（orders 有唯一索引）
+func (s *RecurringOrderService) GenerateUpcoming(ctx context.Context, now time.Time) (int, error) {
+	if s.orderService == nil {
+		return 0, errors.New("OrderService 未初始化")
+	}
+
+	cursor, err := s.mongoDB.GetCollection(recurringOrderCollection).Find(ctx, bson.M{"status": model.RecurringOrderActive})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢啟用中的週期性預約失敗")
+		return 0, err
+	}
+	var recurringOrders []*model.RecurringOrder
+	if err := cursor.All(ctx, &recurringOrders); err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for _, recurring := range recurringOrders {
+		generated += s.generateForRecurring(ctx, recurring, now)
+	}
+	return generated, nil
+}
+
+// generateForRecurring 產生單一週期性預約尚未產生的預約單
+func (s *RecurringOrderService) generateForRecurring(ctx context.Context, recurring *model.RecurringOrder, now time.Time) int {
+	id := recurring.ID.Hex()
+	logger := s.logger.With().Str("recurring_order_id", id).Str("name", recurring.Name).Logger()
+
+	occurrences, err := upcomingOccurrences(&recurring.Rule, now, s.DaysAhead())
+	if err != nil {
+		logger.Error().Err(err).Msg("解析預約時間失敗")
+		return 0
+	}
+	if len(occurrences) == 0 {
+		return 0
+	}
+	dates := make([]string, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		dates = append(dates, occurrence.date)
+	}
+
+	existing, err := s.generatedOrders(ctx, id, bson.M{"occurrence_date": bson.M{"$in": dates}})
+	if err != nil {
+		logger.Error().Err(err).Msg("查詢已產生的預約單失敗")
+		return 0
+	}
+
+	generated := 0
+	for _, occurrence := range occurrences {
+		date, scheduledAt := occurrence.date, occurrence.scheduledAt
+		if _, ok := existing[date]; ok {
+			continue
+		}
+
+		order, err := s.orderService.CreateRecurringOccurrence(ctx, recurring, date, scheduledAt)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				// 其他實例已產生
+				continue
+			}
+			logger.Error().Err(err).Str("date", date).Msg("產生週期預約的預約單失敗")
+			continue
+		}
+		generated++
+		logger.Info().
+			Str("date", date).
+			Str("order_id", order.ID.Hex()).
+			Str("short_id", order.ShortID).
+			Time("scheduled_at", scheduledAt).
+			Msg("已產生週期預約的預約單")
+	}
+
+	if generated > 0 {
+		_, err := s.mongoDB.GetCollection(recurringOrderCollection).UpdateOne(ctx,
+			bson.M{"_id": recurring.ID}, bson.M{"$set": bson.M{"last_generated_at": now}})
+		if err != nil {
+			logger.Error().Err(err).Msg("更新週期性預約產生時間失敗")
+		}
+	}
+	return generated
+}
+
+// generatedOrders 查詢週期性預約已產生的預約單，以日期為鍵
+func (s *RecurringOrderService) generatedOrders(ctx context.Context, id string, extra bson.M) (map[string]*model.Order, error) {
+	filter := bson.M{"recurring_order_id": id}
+	for key, value := range extra {
+		filter[key] = value
+	}
+	opts := options.Find().SetProjection(bson.M{"logs": 0, "line_messages": 0})
+
+	cursor, err := s.mongoDB.GetCollection("orders").Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Str("recurring_order_id", id).Msg("查詢週期預約的預約單失敗")
+		return nil, err
+	}
+	var orders []*model.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*model.Order, len(orders))
+	for _, order := range orders {
+		byDate[order.OccurrenceDate] = order
+	}
+	return byDate, nil
+}
+
+// cancelOccurrenceOrder 取消週期預約產生且尚未開始的預約單，已開始或已結束的預約單不處理並回傳 nil
+func (s *RecurringOrderService) cancelOccurrenceOrder(ctx context.Context, order *model.Order, reason, actor string) (*model.Order, error) {
+	if order.Status != model.OrderStatusWaiting && order.Status != model.OrderStatusScheduleAccepted {
+		return nil, nil
+	}
+	if s.orderScheduleService == nil {
+		return nil, errors.New("OrderScheduleService 未初始化")
+	}
+	return s.orderScheduleService.CancelScheduledOrder(ctx, order.ID.Hex(), strings.TrimSpace(reason), actor)
+}
+
+// occurrenceDates 列出從指定時間當天（台北時間）起 days 天內符合規則的日期
+func occurrenceDates(rule *model.RecurrenceRule, from time.Time, days int) []string {
+	today := from.In(utils.GetTaipeiLocation())
+	dates := []string{}
+	for i := 0; i <= days; i++ {
+		date := today.AddDate(0, 0, i).Format(model.RecurrenceDateLayout)
+		if rule.OccursOn(date) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// scheduledOccurrence 待產生預約單的單次預約
+type scheduledOccurrence struct {
+	date        string
+	scheduledAt time.Time
+}
+
+// upcomingOccurrences 列出從指定時間起 days 天內符合規則的單次預約，略過預約時間距今不足預約單最短前置時間的單次
+func upcomingOccurrences(rule *model.RecurrenceRule, now time.Time, days int) ([]scheduledOccurrence, error) {
+	var occurrences []scheduledOccurrence
+	for _, date := range occurrenceDates(rule, now, days) {
+		scheduledAt, err := occurrenceTime(date, rule.TimeOfDay)
+		if err != nil {
+			return nil, err
+		}
+		if scheduledAt.Sub(now) < minScheduleLeadTime {
+			continue
+		}
+		occurrences = append(occurrences, scheduledOccurrence{date: date, scheduledAt: scheduledAt})
+	}
+	return occurrences, nil
+}
+
+// occurrenceTime 單次預約的預約時間（台北時間的日期與時間）
+func occurrenceTime(date, timeOfDay string) (time.Time, error) {
+	return time.ParseInLocation(model.RecurrenceDateLayout+" 15:04", date+" "+timeOfDay, utils.GetTaipeiLocation())
+}
+
+// validateRecurringOrder 驗證週期性預約並解析客群
+func validateRecurringOrder(recurring *model.RecurringOrder) error {
+	recurring.Name = strings.TrimSpace(recurring.Name)
+	recurring.OriText = strings.TrimSpace(recurring.OriText)
+	if recurring.Name == "" {
+		return errors.New("名稱不可為空")
+	}
+
+	customerGroup, address, _, _, _ := utils.ExOriText(recurring.OriText)
+	if customerGroup == "" || address == "" {
+		return errors.New("訂單文字格式錯誤，應為「客群/地址 備註」")
+	}
+	recurring.CustomerGroup = customerGroup
+	recurring.Fleet = model.FleetType(strings.ToUpper(string(recurring.Fleet)))
+
+	rule := &recurring.Rule
+	switch rule.Frequency {
+	case model.RecurrenceWeekly:
+		if len(rule.Weekdays) == 0 {
+			return errors.New("每週重複需指定星期")
+		}
+		for _, weekday := range rule.Weekdays {
+			if weekday < 0 || weekday > 6 {
+				return fmt.Errorf("無效的星期: %d（0=週日 ... 6=週六）", weekday)
+			}
+		}
+		slices.Sort(rule.Weekdays)
+		rule.Weekdays = slices.Compact(rule.Weekdays)
+		rule.IntervalDays = 0
+	case model.RecurrenceInterval:
+		if rule.IntervalDays < 1 {
+			return errors.New("每N天重複的天數需大於0")
+		}
+		rule.Weekdays = nil
+	default:
+		return fmt.Errorf("無效的重複頻率: %s", rule.Frequency)
+	}
+
+	if _, err := time.Parse("15:04", rule.TimeOfDay); err != nil {
+		return fmt.Errorf("無效的預約時間: %s，應為 HH:MM", rule.TimeOfDay)
+	}
+	start, err := time.Parse(model.RecurrenceDateLayout, rule.StartDate)
+	if err != nil {
+		return fmt.Errorf("無效的開始日期: %s，應為 YYYY-MM-DD", rule.StartDate)
+	}
+	if rule.EndDate != "" {
+		end, err := time.Parse(model.RecurrenceDateLayout, rule.EndDate)
+		if err != nil {
+			return fmt.Errorf("無效的結束日期: %s，應為 YYYY-MM-DD", rule.EndDate)
+		}
+		if end.Before(start) {
+			return errors.New("結束日期不可早於開始日期")
+		}
+	}
+	for _, date := range rule.ExceptionDates {
+		if _, err := time.Parse(model.RecurrenceDateLayout, date); err != nil {
+			return fmt.Errorf("無效的例外日期: %s，應為 YYYY-MM-DD", date)
+		}
+	}
+	slices.Sort(rule.ExceptionDates)
+	rule.ExceptionDates = slices.Compact(rule.ExceptionDates)
+	return nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestOccurrenceDates(t *testing.T) {
+	taipei := utils.GetTaipeiLocation()
+	friday := time.Date(2025, 10, 3, 10, 0, 0, 0, taipei)
+	weekdays := model.RecurrenceRule{Frequency: model.RecurrenceWeekly, Weekdays: []int{1, 2, 3, 4, 5}, TimeOfDay: "08:30", StartDate: "2025-10-01"}
+	withException := weekdays
+	withException.ExceptionDates = []string{"2025-10-06"}
+
+	testCases := []struct {
+		name string
+		rule model.RecurrenceRule
+		from time.Time
+		days int
+		want []string
+	}{
+		{name: "平日規則略過週末", rule: weekdays, from: friday, days: 4, want: []string{"2025-10-03", "2025-10-06", "2025-10-07"}},
+		{name: "略過例外日期", rule: withException, from: friday, days: 4, want: []string{"2025-10-03", "2025-10-07"}},
+		{
+			name: "依台北時間判斷當天（UTC 仍是前一天）",
+			rule: model.RecurrenceRule{Frequency: model.RecurrenceWeekly, Weekdays: []int{1}, TimeOfDay: "08:30", StartDate: "2025-10-01"},
+			from: time.Date(2025, 10, 5, 17, 0, 0, 0, time.UTC),
+			days: 0,
+			want: []string{"2025-10-06"},
+		},
+		{
+			name: "台北時間深夜不跨到隔天",
+			rule: weekdays,
+			from: time.Date(2025, 10, 6, 15, 59, 0, 0, time.UTC),
+			days: 1,
+			want: []string{"2025-10-06", "2025-10-07"},
+		},
+		{
+			name: "只產生開始與結束日期之間",
+			rule: model.RecurrenceRule{Frequency: model.RecurrenceWeekly, Weekdays: []int{0, 1, 2, 3, 4, 5, 6}, TimeOfDay: "08:30", StartDate: "2025-10-06", EndDate: "2025-10-07"},
+			from: time.Date(2025, 10, 5, 10, 0, 0, 0, taipei),
+			days: 3,
+			want: []string{"2025-10-06", "2025-10-07"},
+		},
+		{
+			name: "每 N 天從開始日期起算",
+			rule: model.RecurrenceRule{Frequency: model.RecurrenceInterval, IntervalDays: 2, TimeOfDay: "08:30", StartDate: "2025-10-01"},
+			from: friday,
+			days: 4,
+			want: []string{"2025-10-03", "2025-10-05", "2025-10-07"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := occurrenceDates(&tc.rule, tc.from, tc.days); !slices.Equal(got, tc.want) {
+				t.Fatalf("預期日期 %v，實際為 %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOccurrenceTime(t *testing.T) {
+	testCases := []struct {
+		date      string
+		timeOfDay string
+		want      time.Time
+	}{
+		{date: "2025-10-06", timeOfDay: "08:30", want: time.Date(2025, 10, 6, 0, 30, 0, 0, time.UTC)},
+		{date: "2025-10-06", timeOfDay: "00:15", want: time.Date(2025, 10, 5, 16, 15, 0, 0, time.UTC)},
+		{date: "2025-10-06", timeOfDay: "23:45", want: time.Date(2025, 10, 6, 15, 45, 0, 0, time.UTC)},
+	}
+	for _, tc := range testCases {
+		got, err := occurrenceTime(tc.date, tc.timeOfDay)
+		if err != nil {
+			t.Fatalf("解析 %s %s 失敗: %v", tc.date, tc.timeOfDay, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("%s %s（台北時間）預期為 %v，實際為 %v", tc.date, tc.timeOfDay, tc.want, got.UTC())
+		}
+	}
+
+	if _, err := occurrenceTime("2025-10-06", "8點半"); err == nil {
+		t.Fatal("預期時間格式錯誤")
+	}
+}
+
+func TestUpcomingOccurrences(t *testing.T) {
+	taipei := utils.GetTaipeiLocation()
+	daily := model.RecurrenceRule{Frequency: model.RecurrenceWeekly, Weekdays: []int{0, 1, 2, 3, 4, 5, 6}, TimeOfDay: "08:30", StartDate: "2025-10-01"}
+
+	testCases := []struct {
+		name string
+		now  time.Time
+		want []string
+	}{
+		{name: "距預約時間剛好為最短前置時間", now: time.Date(2025, 10, 6, 8, 0, 0, 0, taipei), want: []string{"2025-10-06", "2025-10-07"}},
+		{name: "距預約時間不足最短前置時間時略過", now: time.Date(2025, 10, 6, 8, 10, 0, 0, taipei), want: []string{"2025-10-07"}},
+		{name: "預約時間已過時略過", now: time.Date(2025, 10, 6, 9, 0, 0, 0, taipei), want: []string{"2025-10-07"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			occurrences, err := upcomingOccurrences(&daily, tc.now, 1)
+			if err != nil {
+				t.Fatalf("列出單次預約失敗: %v", err)
+			}
+			var dates []string
+			for _, occurrence := range occurrences {
+				dates = append(dates, occurrence.date)
+				if occurrence.scheduledAt.Sub(tc.now) < minScheduleLeadTime {
+					t.Fatalf("%s 距今不足最短前置時間，不應產生", occurrence.date)
+				}
+			}
+			if !slices.Equal(dates, tc.want) {
+				t.Fatalf("預期日期 %v，實際為 %v", tc.want, dates)
+			}
+		})
+	}
+
+	invalid := daily
+	invalid.TimeOfDay = "8點半"
+	if _, err := upcomingOccurrences(&invalid, time.Now(), 1); err == nil {
+		t.Fatal("預期時間格式錯誤")
+	}
+}
+
+func TestGenerateUpcomingSkipsGeneratedDates(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	original := infra.AppConfig.RecurringOrders.DaysAhead
+	t.Cleanup(func() { infra.AppConfig.RecurringOrders.DaysAhead = original })
+	infra.AppConfig.RecurringOrders.DaysAhead = 1
+
+	mt.Run("不足前置時間的日期不查詢，已產生的日期不重複產生", func(mt *mtest.T) {
+		mongoDB := &infra.MongoDB{Client: mt.Client, Database: mt.DB}
+		svc := NewRecurringOrderService(zerolog.Nop(), mongoDB)
+		svc.SetOrderService(NewOrderService(zerolog.Nop(), mongoDB, nil, nil, nil, nil))
+
+		id := primitive.NewObjectID()
+		recurring := &model.RecurringOrder{
+			ID:     &id,
+			Name:   "平日洗腎接送",
+			Status: model.RecurringOrderActive,
+			Rule:   model.RecurrenceRule{Frequency: model.RecurrenceWeekly, Weekdays: []int{0, 1, 2, 3, 4, 5, 6}, TimeOfDay: "08:30", StartDate: "2025-10-01"},
+		}
+		generated := &model.Order{RecurringOrderID: id.Hex(), OccurrenceDate: "2025-10-07"}
+		mt.AddMockResponses(
+			mockCursor(mt, recurringOrderCollection, recurring),
+			mockCursor(mt, "orders", generated),
+		)
+
+		now := time.Date(2025, 10, 6, 8, 10, 0, 0, utils.GetTaipeiLocation())
+		count, err := svc.GenerateUpcoming(context.Background(), now)
+		if err != nil {
+			mt.Fatalf("產生預約單失敗: %v", err)
+		}
+		if count != 0 {
+			mt.Fatalf("預期沒有需要產生的預約單，實際產生 %d 張", count)
+		}
+
+		mt.GetStartedEvent()
+		query := mt.GetStartedEvent()
+		if query == nil || query.CommandName != "find" {
+			mt.Fatal("預期查詢已產生的預約單")
+		}
+		values, err := query.Command.Lookup("filter", "occurrence_date", "$in").Array().Values()
+		if err != nil || len(values) != 1 || values[0].StringValue() != "2025-10-07" {
+			mt.Fatalf("預期只查詢 2025-10-07（當天距預約時間不足最短前置時間），實際為 %v", values)
+		}
+		if extra := mt.GetStartedEvent(); extra != nil {
+			mt.Fatalf("已產生的日期不應再寫入，實際執行 %s", extra.CommandName)
+		}
+	})
+}
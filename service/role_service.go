@@ -7,24 +7,100 @@ import (
 	"right-backend/data-models/role"
 	"right-backend/infra"
 	"right-backend/model"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// roleCacheTTL 角色權限快取時間，其他實例修改角色後最多延遲此時間生效
+const roleCacheTTL = time.Minute
+
+type cachedRole struct {
+	role     *model.Role // nil 表示 roles 集合沒有此角色
+	loadedAt time.Time
+}
+
 type RoleService struct {
-	logger  zerolog.Logger
-	mongoDB *infra.MongoDB
+	logger    zerolog.Logger
+	mongoDB   *infra.MongoDB
+	roleCache map[model.UserRole]cachedRole
+	cacheMu   sync.RWMutex
 }
 
 func NewRoleService(logger zerolog.Logger, mongoDB *infra.MongoDB) *RoleService {
 	return &RoleService{
-		logger:  logger.With().Str("module", "role_service").Logger(),
-		mongoDB: mongoDB,
+		logger:    logger.With().Str("module", "role_service").Logger(),
+		mongoDB:   mongoDB,
+		roleCache: make(map[model.UserRole]cachedRole),
+	}
+}
+
+// ResolveUserAccess 解析用戶實際生效的權限：以 roles 集合中的角色設定為準，角色未登錄時沿用用戶本身的權限，角色停用時沒有任何權限
+func (s *RoleService) ResolveUserAccess(ctx context.Context, user *model.User) (*model.UserAccess, error) {
+	access := &model.UserAccess{
+		Role:        user.Role,
+		Permissions: user.Permissions,
+		FleetAccess: user.FleetAccess,
+		Fleet:       user.Fleet,
+	}
+
+	role, err := s.cachedRoleByName(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role != nil {
+		access.Permissions = role.Permissions
+		access.FleetAccess = role.FleetAccess
+		if !role.IsActive {
+			access.Permissions = nil
+			access.FleetAccess = model.FleetAccessOwn
+		}
+	}
+	if access.FleetAccess == "" {
+		access.FleetAccess = model.GetDefaultFleetAccess(user.Role)
+	}
+	return access, nil
+}
+
+func (s *RoleService) cachedRoleByName(ctx context.Context, name model.UserRole) (*model.Role, error) {
+	s.cacheMu.RLock()
+	entry, ok := s.roleCache[name]
+	s.cacheMu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < roleCacheTTL {
+		return entry.role, nil
+	}
+
+	var role model.Role
+	err := s.mongoDB.GetCollection("roles").FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	entry = cachedRole{loadedAt: time.Now()}
+	switch {
+	case err == nil:
+		entry.role = &role
+	case errors.Is(err, mongo.ErrNoDocuments):
+	default:
+		s.logger.Error().
+			Str("角色名稱", string(name)).
+			Str("錯誤原因", err.Error()).
+			Msg("查詢角色權限失敗")
+		return nil, err
 	}
+
+	s.cacheMu.Lock()
+	s.roleCache[name] = entry
+	s.cacheMu.Unlock()
+	return entry.role, nil
+}
+
+// invalidateRoleCache 角色異動後清除權限快取
+func (s *RoleService) invalidateRoleCache() {
+	s.cacheMu.Lock()
+	s.roleCache = make(map[model.UserRole]cachedRole)
+	s.cacheMu.Unlock()
 }
 
 // CreateRole 創建角色
@@ -60,6 +136,8 @@ func (s *RoleService) CreateRole(ctx context.Context, input *role.CreateRoleInpu
 		return nil, err
 	}
 
+	s.invalidateRoleCache()
+
 	s.logger.Info().
 		Str("角色ID", newRole.ID.Hex()).
 		Str("角色名稱", input.Body.Name).
@@ -273,6 +351,8 @@ func (s *RoleService) UpdateRole(ctx context.Context, input *role.UpdateRoleInpu
 		return nil, err
 	}
 
+	s.invalidateRoleCache()
+
 	s.logger.Info().
 		Str("角色ID", updatedRole.ID.Hex()).
 		Str("角色名稱", string(updatedRole.Name)).
@@ -348,6 +428,8 @@ func (s *RoleService) DeleteRole(ctx context.Context, roleID string) error {
 		return errors.New("找不到要刪除的角色")
 	}
 
+	s.invalidateRoleCache()
+
 	s.logger.Info().
 		Str("角色ID", roleID).
 		Str("角色名稱", string(existingRole.Name)).
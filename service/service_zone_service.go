@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const serviceZoneCollection = "service_zones"
+
+// ServiceZoneService 管理服務區域（機場、偏遠地區、不服務區域等）並依上車點彙總各車隊的限制規則
+type ServiceZoneService struct {
+	logger  zerolog.Logger
+	mongoDB *infra.MongoDB
+}
+
+func NewServiceZoneService(logger zerolog.Logger, mongoDB *infra.MongoDB) *ServiceZoneService {
+	return &ServiceZoneService{
+		logger:  logger.With().Str("module", "service_zone_service").Logger(),
+		mongoDB: mongoDB,
+	}
+}
+
+// ListZones 列出所有服務區域
+func (s *ServiceZoneService) ListZones(ctx context.Context) ([]*model.ServiceZone, error) {
+	opts := options.Find().SetSort(bson.M{"name": 1})
+	cursor, err := s.mongoDB.GetCollection(serviceZoneCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢服務區域失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	zones := []*model.ServiceZone{}
+	if err := cursor.All(ctx, &zones); err != nil {
+		s.logger.Error().Err(err).Msg("解析服務區域失敗")
+		return nil, err
+	}
+	return zones, nil
+}
+
+// GetZone 取得單一服務區域
+func (s *ServiceZoneService) GetZone(ctx context.Context, id string) (*model.ServiceZone, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的區域ID: %s", id)
+	}
+
+	var zone model.ServiceZone
+	err = s.mongoDB.GetCollection(serviceZoneCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&zone)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到服務區域: %s", id)
+		}
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// CreateZone 新增服務區域
+func (s *ServiceZoneService) CreateZone(ctx context.Context, zone *model.ServiceZone, createdBy string) (*model.ServiceZone, error) {
+	if err := validateServiceZone(zone); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := primitive.NewObjectID()
+	zone.ID = &id
+	zone.UpdatedBy = createdBy
+	zone.CreatedAt = &now
+	zone.UpdatedAt = &now
+
+	if _, err := s.mongoDB.GetCollection(serviceZoneCollection).InsertOne(ctx, zone); err != nil {
+		s.logger.Error().Err(err).Str("name", zone.Name).Msg("新增服務區域失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("zone_id", id.Hex()).Str("name", zone.Name).Str("created_by", createdBy).Msg("服務區域已新增")
+	return zone, nil
+}
+
+// UpdateZone 更新服務區域範圍與規則
+func (s *ServiceZoneService) UpdateZone(ctx context.Context, id string, zone *model.ServiceZone, updatedBy string) (*model.ServiceZone, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的區域ID: %s", id)
+	}
+	if err := validateServiceZone(zone); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":        zone.Name,
+		"description": zone.Description,
+		"area":        zone.Area,
+		"rules":       zone.Rules,
+		"enabled":     zone.Enabled,
+		"updated_by":  updatedBy,
+		"updated_at":  time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.ServiceZone
+	err = s.mongoDB.GetCollection(serviceZoneCollection).FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到服務區域: %s", id)
+		}
+		s.logger.Error().Err(err).Str("zone_id", id).Msg("更新服務區域失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("zone_id", id).Str("name", updated.Name).Str("updated_by", updatedBy).Msg("服務區域已更新")
+	return &updated, nil
+}
+
+// DeleteZone 刪除服務區域
+func (s *ServiceZoneService) DeleteZone(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("無效的區域ID: %s", id)
+	}
+
+	result, err := s.mongoDB.GetCollection(serviceZoneCollection).DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		s.logger.Error().Err(err).Str("zone_id", id).Msg("刪除服務區域失敗")
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("找不到服務區域: %s", id)
+	}
+
+	s.logger.Info().Str("zone_id", id).Msg("服務區域已刪除")
+	return nil
+}
+
+// ResolveRestriction 查詢座標所在的啟用區域，彙總套用於該車隊的規則
+func (s *ServiceZoneService) ResolveRestriction(ctx context.Context, fleet model.FleetType, lat, lng float64) (*model.ZoneRestriction, error) {
+	filter := bson.M{
+		"enabled": true,
+		"area": bson.M{"$geoIntersects": bson.M{
+			"$geometry": model.NewGeoPoint(lat, lng),
+		}},
+	}
+	cursor, err := s.mongoDB.GetCollection(serviceZoneCollection).Find(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Float64("lat", lat).Float64("lng", lng).Msg("查詢座標所在服務區域失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var zones []*model.ServiceZone
+	if err := cursor.All(ctx, &zones); err != nil {
+		return nil, err
+	}
+
+	restriction := &model.ZoneRestriction{}
+	for _, zone := range zones {
+		restriction.Zones = append(restriction.Zones, zone.Name)
+		for _, rule := range zone.Rules {
+			if !rule.AppliesTo(fleet) {
+				continue
+			}
+			switch rule.Action {
+			case model.ZoneRuleActionReject:
+				if restriction.RejectZone == "" {
+					restriction.RejectZone = zone.Name
+					restriction.RejectMessage = rule.Message
+				}
+			case model.ZoneRuleActionRequireTag:
+				restriction.RequiredDriverTags = appendUnique(restriction.RequiredDriverTags, rule.DriverTag)
+			case model.ZoneRuleActionRadius:
+				if restriction.MaxDriverDistanceKm == 0 || rule.MaxDriverDistanceKm < restriction.MaxDriverDistanceKm {
+					restriction.MaxDriverDistanceKm = rule.MaxDriverDistanceKm
+				}
+			}
+		}
+	}
+	return restriction, nil
+}
+
+// ResolveOrderRestriction 依訂單上車點與車隊查詢區域限制，上車點無座標時回傳 nil
+func (s *ServiceZoneService) ResolveOrderRestriction(ctx context.Context, order *model.Order) (*model.ZoneRestriction, error) {
+	if order.Customer.PickupLat == nil || order.Customer.PickupLng == nil {
+		return nil, nil
+	}
+	lat, errLat := strconv.ParseFloat(*order.Customer.PickupLat, 64)
+	lng, errLng := strconv.ParseFloat(*order.Customer.PickupLng, 64)
+	if errLat != nil || errLng != nil {
+		return nil, nil
+	}
+	return s.ResolveRestriction(ctx, order.Fleet, lat, lng)
+}
+
+// ZoneRejectError 上車點位於拒絕建單的服務區域
+type ZoneRejectError struct {
+	Zone    string
+	Message string
+}
+
+func (e *ZoneRejectError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("上車地點位於「%s」：%s", e.Zone, e.Message)
+	}
+	return fmt.Sprintf("上車地點位於「%s」，此區域不提供服務", e.Zone)
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// validateServiceZone 驗證區域範圍與規則
+func validateServiceZone(zone *model.ServiceZone) error {
+	if zone.Name == "" {
+		return errors.New("區域名稱不可為空")
+	}
+
+	zone.Area.Type = "Polygon"
+	if len(zone.Area.Coordinates) == 0 {
+		return errors.New("區域範圍不可為空")
+	}
+	for i, ring := range zone.Area.Coordinates {
+		if len(ring) < 4 {
+			return fmt.Errorf("區域範圍第%d個環至少需要4個點（首尾相同）", i+1)
+		}
+		for _, point := range ring {
+			if len(point) != 2 || point[0] < -180 || point[0] > 180 || point[1] < -90 || point[1] > 90 {
+				return fmt.Errorf("區域範圍第%d個環包含無效座標，格式應為 [經度, 緯度]", i+1)
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("區域範圍第%d個環首尾座標必須相同", i+1)
+		}
+	}
+
+	for i, rule := range zone.Rules {
+		switch rule.Action {
+		case model.ZoneRuleActionReject:
+		case model.ZoneRuleActionRequireTag:
+			if rule.DriverTag == "" {
+				return fmt.Errorf("第%d條規則要求司機標籤但未指定 driver_tag", i+1)
+			}
+		case model.ZoneRuleActionRadius:
+			if rule.MaxDriverDistanceKm <= 0 {
+				return fmt.Errorf("第%d條規則的 max_driver_distance_km 必須大於 0", i+1)
+			}
+		default:
+			return fmt.Errorf("第%d條規則動作無效: %s", i+1, rule.Action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,708 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	settlementCollection         = "settlements"
+	settlementAuditLogCollection = "settlement_audit_logs"
+	maxSettlementDraftAttempts   = 3 // 訂單同時被其他結算單列入時，重新排除已結算訂單的次數上限
+)
+
+// SettlementService 依期間彙總司機完成的訂單，套用車隊抽成規則產生結算單，
+// 結算單依 草稿 → 已確認 → 已撥款 流轉，每次異動寫入只新增不修改的稽核紀錄
+type SettlementService struct {
+	logger       zerolog.Logger
+	mongoDB      *infra.MongoDB
+	fleetService *FleetService
+}
+
+func NewSettlementService(logger zerolog.Logger, mongoDB *infra.MongoDB) *SettlementService {
+	return &SettlementService{
+		logger:  logger.With().Str("module", "settlement_service").Logger(),
+		mongoDB: mongoDB,
+	}
+}
+
+// SetFleetService 設定車隊登錄服務，用於取得司機所屬車隊的抽成規則
+func (s *SettlementService) SetFleetService(fleetService *FleetService) {
+	s.fleetService = fleetService
+}
+
+// SettlementFilter 結算單查詢條件，日期為台北時間 YYYY-MM-DD
+type SettlementFilter struct {
+	Fleet     string
+	DriverID  string
+	Status    string
+	StartDate string
+	EndDate   string
+}
+
+// SettlementSkip 產生結算單時略過的司機與原因
+type SettlementSkip struct {
+	DriverID   string `json:"driver_id" doc:"司機ID"`
+	DriverName string `json:"driver_name" doc:"司機姓名"`
+	Reason     string `json:"reason" doc:"略過原因"`
+}
+
+// Generate 彙總期間內（台北時間，含結束日）完成的訂單產生各司機結算單。
+// 已有草稿的司機會重新產生，已確認或已撥款的結算單不會變動；已列入其他結算單的訂單不重複結算
+func (s *SettlementService) Generate(ctx context.Context, fleet, driverID, startDate, endDate, actor string) ([]*model.Settlement, []SettlementSkip, error) {
+	periodStart, periodEnd, err := parseSettlementPeriod(startDate, endDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ordersByDriver, err := s.completedOrdersByDriver(ctx, driverID, periodStart, periodEnd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	settlements := []*model.Settlement{}
+	skipped := []SettlementSkip{}
+	for _, id := range sortedDriverIDs(ordersByDriver) {
+		driver, err := s.getDriver(ctx, id)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("driver_id", id).Msg("結算時找不到司機資料，略過")
+			skipped = append(skipped, SettlementSkip{DriverID: id, Reason: "找不到司機資料"})
+			continue
+		}
+		if fleet != "" && string(driver.Fleet) != fleet {
+			continue
+		}
+
+		settlement, err := s.upsertDraft(ctx, driver, ordersByDriver[id], periodStart, periodEnd, actor)
+		if err != nil {
+			var skip *settlementSkipError
+			if errors.As(err, &skip) {
+				skipped = append(skipped, SettlementSkip{DriverID: id, DriverName: driver.Name, Reason: skip.reason})
+				continue
+			}
+			return nil, nil, err
+		}
+		settlements = append(settlements, settlement)
+	}
+
+	s.logger.Info().
+		Str("fleet", fleet).
+		Str("start_date", startDate).
+		Str("end_date", endDate).
+		Int("generated", len(settlements)).
+		Int("skipped", len(skipped)).
+		Str("actor", actor).
+		Msg("司機結算單產生完成")
+	return settlements, skipped, nil
+}
+
+// settlementSkipError 單一司機不需產生結算單的原因，不中斷整批產生
+type settlementSkipError struct {
+	reason string
+}
+
+func (e *settlementSkipError) Error() string {
+	return e.reason
+}
+
+// completedOrdersByDriver 查詢期間內完成的訂單並依司機分組
+func (s *SettlementService) completedOrdersByDriver(ctx context.Context, driverID string, periodStart, periodEnd time.Time) (map[string][]*model.Order, error) {
+	filter := bson.M{
+		"status":                 model.OrderStatusCompleted,
+		"completion_time":        bson.M{"$gte": periodStart, "$lt": periodEnd},
+		"driver.assigned_driver": bson.M{"$nin": bson.A{"", nil}},
+	}
+	if driverID != "" {
+		filter["driver.assigned_driver"] = driverID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "completion_time", Value: 1}})
+	cursor, err := s.mongoDB.GetCollection("orders").Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢結算期間完成訂單失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []*model.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+
+	ordersByDriver := make(map[string][]*model.Order)
+	for _, order := range orders {
+		ordersByDriver[order.Driver.AssignedDriver] = append(ordersByDriver[order.Driver.AssignedDriver], order)
+	}
+	return ordersByDriver, nil
+}
+
+// upsertDraft 產生或重新產生司機的草稿結算單
+func (s *SettlementService) upsertDraft(ctx context.Context, driver *model.DriverInfo, orders []*model.Order, periodStart, periodEnd time.Time, actor string) (*model.Settlement, error) {
+	driverID := driver.ID.Hex()
+
+	var existing model.Settlement
+	err := s.mongoDB.GetCollection(settlementCollection).FindOne(ctx, bson.M{
+		"driver_id":    driverID,
+		"period_start": periodStart,
+		"period_end":   periodEnd,
+	}).Decode(&existing)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	hasExisting := err == nil
+	if hasExisting && existing.Status != model.SettlementStatusDraft {
+		return nil, &settlementSkipError{reason: fmt.Sprintf("結算單已%s，不重新產生", settlementStatusName(existing.Status))}
+	}
+
+	for attempt := 1; attempt <= maxSettlementDraftAttempts; attempt++ {
+		remaining, err := s.excludeSettledOrders(ctx, orders, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(remaining) == 0 {
+			return nil, &settlementSkipError{reason: "期間內訂單皆已列入其他結算單"}
+		}
+		if attempt > 1 && !hasExisting && len(remaining) == len(orders) {
+			// 沒有訂單被其他結算單列入，衝突來自同期間同時產生的結算單
+			return nil, &settlementSkipError{reason: "同期間結算單正在產生中"}
+		}
+		orders = remaining
+
+		settlement := newDraftSettlement(driver, orders, s.fleetSettings(driver.Fleet), periodStart, periodEnd, actor)
+		if hasExisting {
+			settlement.ID = existing.ID
+			settlement.CreatedBy = existing.CreatedBy
+			settlement.CreatedAt = existing.CreatedAt
+		}
+		err = s.saveDraft(ctx, settlement, hasExisting, actor)
+		if !mongo.IsDuplicateKeyError(err) {
+			if err != nil {
+				return nil, err
+			}
+			return settlement, nil
+		}
+		// 訂單明細的唯一索引確保同一訂單只列入一張結算單，其他結算單同時列入時重新排除後再試
+		s.logger.Warn().Err(err).Str("driver_id", driverID).Int("attempt", attempt).Msg("結算單訂單已被其他結算單列入，重新排除後再試")
+	}
+	return nil, &settlementSkipError{reason: "訂單同時列入其他結算單，請稍後重新產生"}
+}
+
+// newDraftSettlement 依車隊抽成規則建立草稿結算單
+func newDraftSettlement(driver *model.DriverInfo, orders []*model.Order, settings model.FleetSettings, periodStart, periodEnd time.Time, actor string) *model.Settlement {
+	now := time.Now()
+	settlement := &model.Settlement{
+		DriverID:           driver.ID.Hex(),
+		DriverName:         driver.Name,
+		DriverNo:           driver.DriverNo,
+		CarPlate:           driver.CarPlate,
+		JkoAccount:         driver.JkoAccount,
+		Fleet:              driver.Fleet,
+		PeriodStart:        periodStart,
+		PeriodEnd:          periodEnd,
+		Status:             model.SettlementStatusDraft,
+		CommissionPercent:  settings.CommissionPercent,
+		CommissionPerOrder: settings.CommissionPerOrder,
+		CreatedBy:          actor,
+		CreatedAt:          &now,
+		UpdatedAt:          &now,
+	}
+	for _, order := range orders {
+		settlement.Lines = append(settlement.Lines, settlementLine(order, settings))
+	}
+	settlement.Recalculate()
+	return settlement
+}
+
+// saveDraft 寫入草稿結算單，重複鍵錯誤原樣回傳由呼叫端重新排除已結算訂單
+func (s *SettlementService) saveDraft(ctx context.Context, settlement *model.Settlement, hasExisting bool, actor string) error {
+	collection := s.mongoDB.GetCollection(settlementCollection)
+
+	if hasExisting {
+		result, err := collection.ReplaceOne(ctx, bson.M{"_id": settlement.ID, "status": model.SettlementStatusDraft}, settlement)
+		if err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				s.logger.Error().Err(err).Str("driver_id", settlement.DriverID).Msg("重新產生結算單失敗")
+			}
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return &settlementSkipError{reason: "結算單狀態已變更，不重新產生"}
+		}
+		s.writeAudit(ctx, settlement, model.SettlementAuditRegenerated, model.SettlementStatusDraft, model.SettlementStatusDraft, actor,
+			fmt.Sprintf("重新產生，%d 筆訂單", settlement.OrderCount))
+		return nil
+	}
+
+	id := primitive.NewObjectID()
+	settlement.ID = &id
+	if _, err := collection.InsertOne(ctx, settlement); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			s.logger.Error().Err(err).Str("driver_id", settlement.DriverID).Msg("新增結算單失敗")
+		}
+		return err
+	}
+	s.writeAudit(ctx, settlement, model.SettlementAuditGenerated, "", model.SettlementStatusDraft, actor,
+		fmt.Sprintf("產生結算單，%d 筆訂單", settlement.OrderCount))
+	return nil
+}
+
+// excludeSettledOrders 排除已列入其他結算單的訂單（重新產生時忽略本身的草稿）
+func (s *SettlementService) excludeSettledOrders(ctx context.Context, orders []*model.Order, selfID *primitive.ObjectID) ([]*model.Order, error) {
+	orderIDs := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.ID.Hex())
+	}
+
+	filter := bson.M{"lines.order_id": bson.M{"$in": orderIDs}}
+	if selfID != nil {
+		filter["_id"] = bson.M{"$ne": *selfID}
+	}
+	opts := options.Find().SetProjection(bson.M{"lines.order_id": 1})
+	cursor, err := s.mongoDB.GetCollection(settlementCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var others []model.Settlement
+	if err := cursor.All(ctx, &others); err != nil {
+		return nil, err
+	}
+	settled := make(map[string]bool)
+	for _, other := range others {
+		for _, line := range other.Lines {
+			settled[line.OrderID] = true
+		}
+	}
+
+	remaining := make([]*model.Order, 0, len(orders))
+	for _, order := range orders {
+		if !settled[order.ID.Hex()] {
+			remaining = append(remaining, order)
+		}
+	}
+	return remaining, nil
+}
+
+// settlementLine 訂單結算明細：優先使用完成時計算的實際車資，其次為後台填寫的收入；無車資資料時不抽成
+func settlementLine(order *model.Order, settings model.FleetSettings) model.SettlementLine {
+	line := model.SettlementLine{
+		OrderID:       order.ID.Hex(),
+		ShortID:       order.ShortID,
+		OrderFleet:    order.Fleet,
+		CustomerGroup: order.CustomerGroup,
+		PickupAddress: order.Customer.PickupAddress,
+	}
+	if line.PickupAddress == "" {
+		line.PickupAddress = order.Customer.InputPickupAddress
+	}
+	if order.CompletionTime != nil {
+		line.CompletionTime = *order.CompletionTime
+	}
+
+	line.Fare, line.FareSource = order.BilledFare()
+	if line.FareSource != model.OrderFareNone {
+		line.Commission = settings.Commission(line.Fare)
+	}
+	line.Payout = line.Fare - line.Commission
+	return line
+}
+
+// fleetSettings 取得車隊設定，未接上車隊登錄服務時使用預設車隊
+func (s *SettlementService) fleetSettings(fleet model.FleetType) model.FleetSettings {
+	if s.fleetService != nil {
+		return s.fleetService.FleetOrUnregistered(fleet).Settings
+	}
+	return model.DefaultFleetFor(fleet).Settings
+}
+
+func (s *SettlementService) getDriver(ctx context.Context, driverID string) (*model.DriverInfo, error) {
+	objectID, err := primitive.ObjectIDFromHex(driverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的司機ID: %s", driverID)
+	}
+	var driver model.DriverInfo
+	if err := s.mongoDB.GetCollection("drivers").FindOne(ctx, bson.M{"_id": objectID}).Decode(&driver); err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
+// List 列出結算單（不含訂單明細），依期間新到舊排序
+func (s *SettlementService) List(ctx context.Context, filter SettlementFilter) ([]*model.Settlement, error) {
+	query, err := settlementQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "period_start", Value: -1}, {Key: "driver_name", Value: 1}}).
+		SetProjection(bson.M{"lines": 0})
+	cursor, err := s.mongoDB.GetCollection(settlementCollection).Find(ctx, query, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢結算單失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	settlements := []*model.Settlement{}
+	if err := cursor.All(ctx, &settlements); err != nil {
+		s.logger.Error().Err(err).Msg("解析結算單失敗")
+		return nil, err
+	}
+	return settlements, nil
+}
+
+// Get 取得單一結算單（含訂單明細）
+func (s *SettlementService) Get(ctx context.Context, id string) (*model.Settlement, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的結算單ID: %s", id)
+	}
+
+	var settlement model.Settlement
+	err = s.mongoDB.GetCollection(settlementCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&settlement)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到結算單: %s", id)
+		}
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+// Confirm 確認草稿結算單，確認後金額鎖定不再重新產生
+func (s *SettlementService) Confirm(ctx context.Context, id, actor string) (*model.Settlement, error) {
+	now := time.Now()
+	return s.transition(ctx, id, model.SettlementStatusDraft, model.SettlementStatusConfirmed, bson.M{
+		"confirmed_by": actor,
+		"confirmed_at": now,
+	}, model.SettlementAuditConfirmed, actor, "")
+}
+
+// MarkPaid 登記已確認的結算單已撥款
+func (s *SettlementService) MarkPaid(ctx context.Context, id, paymentRef, actor string) (*model.Settlement, error) {
+	now := time.Now()
+	details := ""
+	if paymentRef != "" {
+		details = "撥款參考編號：" + paymentRef
+	}
+	return s.transition(ctx, id, model.SettlementStatusConfirmed, model.SettlementStatusPaid, bson.M{
+		"paid_by":     actor,
+		"paid_at":     now,
+		"payment_ref": paymentRef,
+	}, model.SettlementAuditPaid, actor, details)
+}
+
+// transition 以狀態為條件原子更新結算單，避免重複確認或撥款
+func (s *SettlementService) transition(ctx context.Context, id string, from, to model.SettlementStatus, fields bson.M, action model.SettlementAuditAction, actor, details string) (*model.Settlement, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的結算單ID: %s", id)
+	}
+
+	fields["status"] = to
+	fields["updated_at"] = time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Settlement
+	err = s.mongoDB.GetCollection(settlementCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "status": from},
+		bson.M{"$set": fields}, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			current, getErr := s.Get(ctx, id)
+			if getErr != nil {
+				return nil, getErr
+			}
+			return nil, fmt.Errorf("結算單目前為%s，無法變更為%s", settlementStatusName(current.Status), settlementStatusName(to))
+		}
+		s.logger.Error().Err(err).Str("settlement_id", id).Msg("更新結算單狀態失敗")
+		return nil, err
+	}
+
+	s.writeAudit(ctx, &updated, action, from, to, actor, details)
+	s.logger.Info().
+		Str("settlement_id", id).
+		Str("driver_id", updated.DriverID).
+		Str("from", string(from)).
+		Str("to", string(to)).
+		Int("payout", updated.Payout).
+		Str("actor", actor).
+		Msg("結算單狀態已更新")
+	return &updated, nil
+}
+
+// DeleteDraft 刪除草稿結算單，已確認或已撥款的結算單不可刪除；稽核紀錄保留
+func (s *SettlementService) DeleteDraft(ctx context.Context, id, actor string) error {
+	settlement, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if settlement.Status != model.SettlementStatusDraft {
+		return fmt.Errorf("結算單目前為%s，只能刪除草稿", settlementStatusName(settlement.Status))
+	}
+
+	result, err := s.mongoDB.GetCollection(settlementCollection).DeleteOne(ctx, bson.M{"_id": settlement.ID, "status": model.SettlementStatusDraft})
+	if err != nil {
+		s.logger.Error().Err(err).Str("settlement_id", id).Msg("刪除結算單失敗")
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("結算單狀態已變更，無法刪除")
+	}
+
+	s.writeAudit(ctx, settlement, model.SettlementAuditDeleted, model.SettlementStatusDraft, "", actor, "")
+	s.logger.Info().Str("settlement_id", id).Str("actor", actor).Msg("草稿結算單已刪除")
+	return nil
+}
+
+// ListAudit 列出結算單的稽核紀錄（依時間排序）
+func (s *SettlementService) ListAudit(ctx context.Context, id string) ([]*model.SettlementAuditEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := s.mongoDB.GetCollection(settlementAuditLogCollection).Find(ctx, bson.M{"settlement_id": id}, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Str("settlement_id", id).Msg("查詢結算單稽核紀錄失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []*model.SettlementAuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// writeAudit 新增稽核紀錄，失敗只記錄錯誤不影響結算單操作
+func (s *SettlementService) writeAudit(ctx context.Context, settlement *model.Settlement, action model.SettlementAuditAction, from, to model.SettlementStatus, actor, details string) {
+	entry := &model.SettlementAuditEntry{
+		SettlementID: settlement.ID.Hex(),
+		DriverID:     settlement.DriverID,
+		Action:       action,
+		FromStatus:   from,
+		ToStatus:     to,
+		Payout:       settlement.Payout,
+		Actor:        actor,
+		Details:      details,
+		CreatedAt:    time.Now(),
+	}
+	if _, err := s.mongoDB.GetCollection(settlementAuditLogCollection).InsertOne(ctx, entry); err != nil {
+		s.logger.Error().Err(err).
+			Str("settlement_id", entry.SettlementID).
+			Str("action", string(action)).
+			Msg("寫入結算單稽核紀錄失敗")
+	}
+}
+
+// ExportStatement 匯出單一司機結算單到 Excel
+func (s *SettlementService) ExportStatement(ctx context.Context, id, actor string) (*excelize.File, *model.Settlement, error) {
+	settlement, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc := utils.GetTaipeiLocation()
+	f := excelize.NewFile()
+	sheetName := "結算單"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("建立工作表失敗: %w", err)
+	}
+	f.SetActiveSheet(index)
+
+	summary := [][]interface{}{
+		{"司機", settlement.DriverName},
+		{"司機編號", settlement.DriverNo},
+		{"車牌", settlement.CarPlate},
+		{"車隊", string(settlement.Fleet)},
+		{"街口帳號", settlement.JkoAccount},
+		{"結算期間", settlementPeriodText(settlement)},
+		{"狀態", settlementStatusName(settlement.Status)},
+		{"抽成規則", fmt.Sprintf("%g%% + 每筆 %d 元", settlement.CommissionPercent, settlement.CommissionPerOrder)},
+		{"訂單數", settlement.OrderCount},
+		{"車資合計", settlement.GrossFare},
+		{"抽成合計", settlement.Commission},
+		{"應撥款金額", settlement.Payout},
+	}
+	if settlement.PaymentRef != "" {
+		summary = append(summary, []interface{}{"撥款參考編號", settlement.PaymentRef})
+	}
+
+	rowNum := 1
+	for _, row := range summary {
+		setExcelRow(f, sheetName, rowNum, row)
+		rowNum++
+	}
+	rowNum++
+
+	setExcelRow(f, sheetName, rowNum, []interface{}{"訂單編號", "完成時間", "訂單車隊", "客群", "上車地點", "車資", "車資來源", "抽成", "司機實得", "系統編號"})
+	rowNum++
+	for _, line := range settlement.Lines {
+		setExcelRow(f, sheetName, rowNum, []interface{}{
+			line.ShortID,
+			line.CompletionTime.In(loc).Format("2006-01-02 15:04"),
+			string(line.OrderFleet),
+			line.CustomerGroup,
+			line.PickupAddress,
+			line.Fare,
+			fareSourceName(line.FareSource),
+			line.Commission,
+			line.Payout,
+			line.OrderID,
+		})
+		rowNum++
+	}
+
+	if f.GetSheetName(0) == "Sheet1" {
+		f.DeleteSheet("Sheet1")
+	}
+
+	s.writeAudit(ctx, settlement, model.SettlementAuditExported, "", "", actor, "")
+	return f, settlement, nil
+}
+
+// ExportList 匯出結算單總表到 Excel，一位司機一列
+func (s *SettlementService) ExportList(ctx context.Context, filter SettlementFilter) (*excelize.File, error) {
+	settlements, err := s.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	sheetName := "結算總表"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("建立工作表失敗: %w", err)
+	}
+	f.SetActiveSheet(index)
+
+	setExcelRow(f, sheetName, 1, []interface{}{"結算期間", "司機", "司機編號", "車牌", "車隊", "街口帳號", "狀態", "訂單數", "車資合計", "抽成合計", "應撥款金額", "撥款參考編號", "結算單ID"})
+	for i, settlement := range settlements {
+		setExcelRow(f, sheetName, i+2, []interface{}{
+			settlementPeriodText(settlement),
+			settlement.DriverName,
+			settlement.DriverNo,
+			settlement.CarPlate,
+			string(settlement.Fleet),
+			settlement.JkoAccount,
+			settlementStatusName(settlement.Status),
+			settlement.OrderCount,
+			settlement.GrossFare,
+			settlement.Commission,
+			settlement.Payout,
+			settlement.PaymentRef,
+			settlement.ID.Hex(),
+		})
+	}
+
+	if f.GetSheetName(0) == "Sheet1" {
+		f.DeleteSheet("Sheet1")
+	}
+	return f, nil
+}
+
+func setExcelRow(f *excelize.File, sheetName string, rowNum int, values []interface{}) {
+	for col, value := range values {
+		cell, _ := excelize.CoordinatesToCellName(col+1, rowNum)
+		f.SetCellValue(sheetName, cell, value)
+	}
+}
+
+// settlementQuery 將查詢條件轉為 Mongo 篩選，日期條件比對結算期間
+func settlementQuery(filter SettlementFilter) (bson.M, error) {
+	query := bson.M{}
+	if filter.Fleet != "" {
+		query["fleet"] = filter.Fleet
+	}
+	if filter.DriverID != "" {
+		query["driver_id"] = filter.DriverID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	loc := utils.GetTaipeiLocation()
+	if filter.StartDate != "" {
+		start, err := time.ParseInLocation("2006-01-02", filter.StartDate, loc)
+		if err != nil {
+			return nil, fmt.Errorf("開始日期格式錯誤: %w", err)
+		}
+		query["period_end"] = bson.M{"$gt": start}
+	}
+	if filter.EndDate != "" {
+		end, err := time.ParseInLocation("2006-01-02", filter.EndDate, loc)
+		if err != nil {
+			return nil, fmt.Errorf("結束日期格式錯誤: %w", err)
+		}
+		query["period_start"] = bson.M{"$lt": end.AddDate(0, 0, 1)}
+	}
+	return query, nil
+}
+
+// parseSettlementPeriod 解析台北時間的結算期間，結束日當天包含在內
+func parseSettlementPeriod(startDate, endDate string) (time.Time, time.Time, error) {
+	loc := utils.GetTaipeiLocation()
+	start, err := time.ParseInLocation("2006-01-02", startDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("開始日期格式錯誤: %w", err)
+	}
+	end, err := time.ParseInLocation("2006-01-02", endDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("結束日期格式錯誤: %w", err)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.New("結束日期不可早於開始日期")
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+func settlementPeriodText(settlement *model.Settlement) string {
+	loc := utils.GetTaipeiLocation()
+	return settlement.PeriodStart.In(loc).Format("2006-01-02") + " ~ " + settlement.PeriodEnd.In(loc).AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+func settlementStatusName(status model.SettlementStatus) string {
+	switch status {
+	case model.SettlementStatusDraft:
+		return "草稿"
+	case model.SettlementStatusConfirmed:
+		return "已確認"
+	case model.SettlementStatusPaid:
+		return "已撥款"
+	}
+	return string(status)
+}
+
+func fareSourceName(source model.OrderFareSource) string {
+	switch source {
+	case model.OrderFareFinal:
+		return "計費"
+	case model.OrderFareCancel:
+		return "取消費"
+	case model.OrderFareIncome:
+		return "收入"
+	}
+	return "無"
+}
+
+func sortedDriverIDs(m map[string][]*model.Order) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testSettlementOrder() *model.Order {
+	id := primitive.NewObjectID()
+	completedAt := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	return &model.Order{
+		ID:             &id,
+		ShortID:        "#9011",
+		Fleet:          model.FleetTypeRSK,
+		CustomerGroup:  "R1",
+		Customer:       model.Customer{InputPickupAddress: "台北車站"},
+		CompletionTime: &completedAt,
+	}
+}
+
+func TestSettlementLine(t *testing.T) {
+	income := 200
+	zero := 0
+	settings := model.FleetSettings{CommissionPercent: 10, CommissionPerOrder: 5}
+
+	testCases := []struct {
+		name           string
+		setup          func(order *model.Order)
+		wantFare       int
+		wantSource     model.OrderFareSource
+		wantCommission int
+		wantPayout     int
+	}{
+		{name: "實際車資", setup: func(o *model.Order) { o.FinalFare = &model.FareBreakdown{Total: 305} }, wantFare: 305, wantSource: model.OrderFareFinal, wantCommission: 36, wantPayout: 269},
+		{name: "取消費", setup: func(o *model.Order) { o.CancellationFee = &model.CancellationFee{Amount: 100} }, wantFare: 100, wantSource: model.OrderFareCancel, wantCommission: 15, wantPayout: 85},
+		{name: "後台填寫收入", setup: func(o *model.Order) { o.Income = &income }, wantFare: 200, wantSource: model.OrderFareIncome, wantCommission: 25, wantPayout: 175},
+		{name: "無車資資料不抽成", setup: func(o *model.Order) {}, wantFare: 0, wantSource: model.OrderFareNone, wantCommission: 0, wantPayout: 0},
+		{name: "收入為 0 不抽成", setup: func(o *model.Order) { o.Income = &zero }, wantFare: 0, wantSource: model.OrderFareIncome, wantCommission: 0, wantPayout: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order := testSettlementOrder()
+			tc.setup(order)
+
+			line := settlementLine(order, settings)
+			if line.Fare != tc.wantFare || line.FareSource != tc.wantSource || line.Commission != tc.wantCommission || line.Payout != tc.wantPayout {
+				t.Fatalf("預期車資 %d（%s）抽成 %d 實得 %d，實際為 %d（%s）抽成 %d 實得 %d",
+					tc.wantFare, tc.wantSource, tc.wantCommission, tc.wantPayout,
+					line.Fare, line.FareSource, line.Commission, line.Payout)
+			}
+			if line.OrderID != order.ID.Hex() || line.PickupAddress != "台北車站" || !line.CompletionTime.Equal(*order.CompletionTime) {
+				t.Fatalf("明細訂單資料不符: %+v", line)
+			}
+		})
+	}
+}
+
+func newTestSettlementService(mt *mtest.T) *SettlementService {
+	return NewSettlementService(zerolog.Nop(), &infra.MongoDB{Client: mt.Client, Database: mt.DB})
+}
+
+// settledBy 模擬已列入指定訂單的其他結算單
+func settledBy(orders ...*model.Order) *model.Settlement {
+	id := primitive.NewObjectID()
+	settlement := &model.Settlement{ID: &id, Status: model.SettlementStatusConfirmed}
+	for _, order := range orders {
+		settlement.Lines = append(settlement.Lines, model.SettlementLine{OrderID: order.ID.Hex()})
+	}
+	return settlement
+}
+
+func TestExcludeSettledOrders(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	orders := []*model.Order{testSettlementOrder(), testSettlementOrder(), testSettlementOrder()}
+
+	mt.Run("排除已列入其他結算單的訂單", func(mt *mtest.T) {
+		svc := newTestSettlementService(mt)
+		mt.AddMockResponses(mockCursor(mt, settlementCollection, settledBy(orders[1])))
+
+		remaining, err := svc.excludeSettledOrders(context.Background(), orders, nil)
+		if err != nil {
+			mt.Fatalf("排除已結算訂單失敗: %v", err)
+		}
+		if len(remaining) != 2 || remaining[0] != orders[0] || remaining[1] != orders[2] {
+			mt.Fatalf("預期保留第 1、3 筆訂單，實際保留 %d 筆", len(remaining))
+		}
+		if _, err := mt.GetStartedEvent().Command.LookupErr("filter", "_id"); err == nil {
+			mt.Fatal("新產生結算單時不應排除任何結算單")
+		}
+	})
+
+	mt.Run("重新產生時忽略本身的草稿", func(mt *mtest.T) {
+		svc := newTestSettlementService(mt)
+		selfID := primitive.NewObjectID()
+		mt.AddMockResponses(mockCursor(mt, settlementCollection))
+
+		remaining, err := svc.excludeSettledOrders(context.Background(), orders, &selfID)
+		if err != nil {
+			mt.Fatalf("排除已結算訂單失敗: %v", err)
+		}
+		if len(remaining) != len(orders) {
+			mt.Fatalf("預期保留全部訂單，實際保留 %d 筆", len(remaining))
+		}
+		ne, ok := mt.GetStartedEvent().Command.Lookup("filter", "_id", "$ne").ObjectIDOK()
+		if !ok || ne != selfID {
+			mt.Fatal("預期查詢條件排除本身的草稿結算單")
+		}
+	})
+}
+
+func TestUpsertDraftOrderConflict(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	driver := &model.DriverInfo{ID: primitive.NewObjectID(), Name: "王小明", Fleet: model.FleetTypeRSK}
+	periodStart := time.Date(2025, 7, 31, 16, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	duplicateKey := mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Name: "DuplicateKey", Message: "E11000 duplicate key error"})
+
+	mt.Run("訂單同時列入其他結算單時重新排除後產生", func(mt *mtest.T) {
+		svc := newTestSettlementService(mt)
+		orders := []*model.Order{testSettlementOrder(), testSettlementOrder()}
+		mt.AddMockResponses(
+			mockCursor(mt, settlementCollection),
+			mockCursor(mt, settlementCollection),
+			duplicateKey,
+			mockCursor(mt, settlementCollection, settledBy(orders[0])),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		settlement, err := svc.upsertDraft(context.Background(), driver, orders, periodStart, periodEnd, "admin")
+		if err != nil {
+			mt.Fatalf("預期重新排除後產生結算單，實際失敗: %v", err)
+		}
+		if settlement.OrderCount != 1 || settlement.Lines[0].OrderID != orders[1].ID.Hex() {
+			mt.Fatalf("預期只列入未被其他結算單列入的訂單，實際為 %+v", settlement.Lines)
+		}
+	})
+
+	mt.Run("同期間結算單同時產生時略過", func(mt *mtest.T) {
+		svc := newTestSettlementService(mt)
+		orders := []*model.Order{testSettlementOrder()}
+		mt.AddMockResponses(
+			mockCursor(mt, settlementCollection),
+			mockCursor(mt, settlementCollection),
+			duplicateKey,
+			mockCursor(mt, settlementCollection),
+		)
+
+		_, err := svc.upsertDraft(context.Background(), driver, orders, periodStart, periodEnd, "admin")
+		var skip *settlementSkipError
+		if !errors.As(err, &skip) || skip.reason != "同期間結算單正在產生中" {
+			mt.Fatalf("預期略過同期間結算單，實際為 %v", err)
+		}
+	})
+
+	mt.Run("訂單皆已列入其他結算單時略過", func(mt *mtest.T) {
+		svc := newTestSettlementService(mt)
+		orders := []*model.Order{testSettlementOrder()}
+		mt.AddMockResponses(
+			mockCursor(mt, settlementCollection),
+			mockCursor(mt, settlementCollection),
+			duplicateKey,
+			mockCursor(mt, settlementCollection, settledBy(orders[0])),
+		)
+
+		_, err := svc.upsertDraft(context.Background(), driver, orders, periodStart, periodEnd, "admin")
+		var skip *settlementSkipError
+		if !errors.As(err, &skip) || skip.reason != "期間內訂單皆已列入其他結算單" {
+			mt.Fatalf("預期略過已結算訂單，實際為 %v", err)
+		}
+	})
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"right-backend/infra"
+	"right-backend/model"
+	"right-backend/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const tariffCollection = "tariffs"
+
+// TariffService 管理車隊與客群的計費規則，建單時估算車資、完成訂單時計算實際車資
+type TariffService struct {
+	logger             zerolog.Logger
+	mongoDB            *infra.MongoDB
+	serviceZoneService *ServiceZoneService
+	locationService    *DriverLocationService
+}
+
+func NewTariffService(logger zerolog.Logger, mongoDB *infra.MongoDB) *TariffService {
+	return &TariffService{
+		logger:  logger.With().Str("module", "tariff_service").Logger(),
+		mongoDB: mongoDB,
+	}
+}
+
+// SetServiceZoneService 設定服務區域服務，設定後依上車點與目的地所在區域加收區域加價
+func (s *TariffService) SetServiceZoneService(serviceZoneService *ServiceZoneService) {
+	s.serviceZoneService = serviceZoneService
+}
+
+// SetLocationService 設定位置歷史軌跡服務，設定後實際車資優先以司機軌跡累計里程計算
+func (s *TariffService) SetLocationService(locationService *DriverLocationService) {
+	s.locationService = locationService
+}
+
+// ListTariffs 列出費率，可依車隊篩選
+func (s *TariffService) ListTariffs(ctx context.Context, fleet string) ([]*model.Tariff, error) {
+	filter := bson.M{}
+	if fleet != "" {
+		filter["fleet"] = fleet
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "fleet", Value: 1}, {Key: "customer_group", Value: 1}})
+	cursor, err := s.mongoDB.GetCollection(tariffCollection).Find(ctx, filter, opts)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("查詢費率失敗")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tariffs := []*model.Tariff{}
+	if err := cursor.All(ctx, &tariffs); err != nil {
+		s.logger.Error().Err(err).Msg("解析費率失敗")
+		return nil, err
+	}
+	return tariffs, nil
+}
+
+// GetTariff 取得單一費率
+func (s *TariffService) GetTariff(ctx context.Context, id string) (*model.Tariff, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的費率ID: %s", id)
+	}
+
+	var tariff model.Tariff
+	err = s.mongoDB.GetCollection(tariffCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&tariff)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到費率: %s", id)
+		}
+		return nil, err
+	}
+	return &tariff, nil
+}
+
+// CreateTariff 新增費率，同一車隊與客群只能有一組費率
+func (s *TariffService) CreateTariff(ctx context.Context, tariff *model.Tariff, createdBy string) (*model.Tariff, error) {
+	if err := validateTariff(tariff); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := primitive.NewObjectID()
+	tariff.ID = &id
+	tariff.UpdatedBy = createdBy
+	tariff.CreatedAt = &now
+	tariff.UpdatedAt = &now
+
+	if _, err := s.mongoDB.GetCollection(tariffCollection).InsertOne(ctx, tariff); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("車隊 %s 客群「%s」已有費率", tariff.Fleet, tariff.CustomerGroup)
+		}
+		s.logger.Error().Err(err).Str("fleet", string(tariff.Fleet)).Str("customer_group", tariff.CustomerGroup).Msg("新增費率失敗")
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("tariff_id", id.Hex()).
+		Str("fleet", string(tariff.Fleet)).
+		Str("customer_group", tariff.CustomerGroup).
+		Str("created_by", createdBy).
+		Msg("費率已新增")
+	return tariff, nil
+}
+
+// UpdateTariff 更新費率內容
+func (s *TariffService) UpdateTariff(ctx context.Context, id string, tariff *model.Tariff, updatedBy string) (*model.Tariff, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("無效的費率ID: %s", id)
+	}
+	if err := validateTariff(tariff); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"fleet":                tariff.Fleet,
+		"customer_group":       tariff.CustomerGroup,
+		"name":                 tariff.Name,
+		"flag_fall":            tariff.FlagFall,
+		"flag_fall_km":         tariff.FlagFallKm,
+		"per_km":               tariff.PerKm,
+		"per_minute":           tariff.PerMinute,
+		"minimum_fare":         tariff.MinimumFare,
+		"night_surcharge":      tariff.NightSurcharge,
+		"zone_surcharges":      tariff.ZoneSurcharges,
+		"pet_surcharge":        tariff.PetSurcharge,
+		"overloaded_surcharge": tariff.OverloadedSurcharge,
+		"enabled":              tariff.Enabled,
+		"updated_by":           updatedBy,
+		"updated_at":           time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Tariff
+	err = s.mongoDB.GetCollection(tariffCollection).FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("找不到費率: %s", id)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("車隊 %s 客群「%s」已有費率", tariff.Fleet, tariff.CustomerGroup)
+		}
+		s.logger.Error().Err(err).Str("tariff_id", id).Msg("更新費率失敗")
+		return nil, err
+	}
+
+	s.logger.Info().Str("tariff_id", id).Str("fleet", string(updated.Fleet)).Str("updated_by", updatedBy).Msg("費率已更新")
+	return &updated, nil
+}
+
+// DeleteTariff 刪除費率
+func (s *TariffService) DeleteTariff(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("無效的費率ID: %s", id)
+	}
+
+	result, err := s.mongoDB.GetCollection(tariffCollection).DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		s.logger.Error().Err(err).Str("tariff_id", id).Msg("刪除費率失敗")
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("找不到費率: %s", id)
+	}
+
+	s.logger.Info().Str("tariff_id", id).Msg("費率已刪除")
+	return nil
+}
+
+// ResolveTariff 取得訂單適用的費率：客群專屬費率優先，其次為車隊預設費率，都沒有時回傳 nil
+func (s *TariffService) ResolveTariff(ctx context.Context, fleet model.FleetType, customerGroup string) (*model.Tariff, error) {
+	filter := bson.M{
+		"fleet":          fleet,
+		"enabled":        true,
+		"customer_group": bson.M{"$in": []string{strings.ToUpper(customerGroup), ""}},
+	}
+	// 客群專屬費率排序在車隊預設費率（空字串）之前
+	opts := options.FindOne().SetSort(bson.M{"customer_group": -1})
+
+	var tariff model.Tariff
+	err := s.mongoDB.GetCollection(tariffCollection).FindOne(ctx, filter, opts).Decode(&tariff)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tariff, nil
+}
+
+// EstimateFare 依建單時預估的上車點到目的地距離與時間估算車資，沒有預估距離或沒有費率時回傳 nil
+func (s *TariffService) EstimateFare(ctx context.Context, order *model.Order) *model.FareBreakdown {
+	if order.Customer.EstPickToDestDist == nil {
+		return nil
+	}
+	distanceKm := utils.ParseDistanceToKm(*order.Customer.EstPickToDestDist)
+	if distanceKm <= 0 {
+		return nil
+	}
+
+	tariff, err := s.ResolveTariff(ctx, order.Fleet, order.CustomerGroup)
+	if err != nil {
+		s.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("查詢費率失敗，略過預估車資")
+		return nil
+	}
+	if tariff == nil {
+		return nil
+	}
+
+	durationMins := 0.0
+	if order.Customer.EstPickToDestMins != nil {
+		durationMins = float64(*order.Customer.EstPickToDestMins)
+	}
+	at := time.Now()
+	if order.ScheduledAt != nil {
+		at = *order.ScheduledAt
+	}
+
+	fare := tariff.Calculate(model.FareInput{
+		DistanceKm:     distanceKm,
+		DurationMins:   durationMins,
+		DistanceSource: model.FareDistanceEstimate,
+		At:             at,
+		Zones:          s.orderZones(ctx, order),
+		HasPets:        order.HasPets,
+		HasOverloaded:  order.HasOverloaded,
+	})
+	s.logger.Debug().Str("short_id", order.ShortID).Str("tariff", tariff.Name).Int("total", fare.Total).Msg("預估車資已計算")
+	return fare
+}
+
+// FinalizeFare 完成訂單時計算實際車資：里程優先使用上車到完成期間的司機軌跡，沒有軌跡時使用建單時的預估距離；
+// 行車時間為上車到完成的時間，沒有上車時間時使用司機回報的用時。沒有費率時回傳 nil
+func (s *TariffService) FinalizeFare(ctx context.Context, order *model.Order, completedAt time.Time, durationSecs int) *model.FareBreakdown {
+	tariff, err := s.ResolveTariff(ctx, order.Fleet, order.CustomerGroup)
+	if err != nil {
+		s.logger.Error().Err(err).Str("short_id", order.ShortID).Msg("查詢費率失敗，略過實際車資計算")
+		return nil
+	}
+	if tariff == nil {
+		return nil
+	}
+
+	start := order.PickUpTime
+	durationMins := float64(durationSecs) / 60
+	if start != nil && completedAt.After(*start) {
+		durationMins = completedAt.Sub(*start).Minutes()
+	}
+
+	input := model.FareInput{
+		DurationMins:   durationMins,
+		DistanceSource: model.FareDistanceNone,
+		At:             completedAt,
+		Zones:          s.orderZones(ctx, order),
+		HasPets:        order.HasPets,
+		HasOverloaded:  order.HasOverloaded,
+	}
+	if start != nil {
+		input.At = *start
+	}
+
+	if s.locationService != nil && start != nil && order.Driver.AssignedDriver != "" {
+		distanceKm, ok, err := s.locationService.TripDistanceKm(ctx, order.Driver.AssignedDriver, *start, completedAt)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("short_id", order.ShortID).Msg("查詢司機軌跡里程失敗，改用預估距離")
+		} else if ok {
+			input.DistanceKm = distanceKm
+			input.DistanceSource = model.FareDistanceTrack
+		}
+	}
+	if input.DistanceSource == model.FareDistanceNone && order.Customer.EstPickToDestDist != nil {
+		if distanceKm := utils.ParseDistanceToKm(*order.Customer.EstPickToDestDist); distanceKm > 0 {
+			input.DistanceKm = distanceKm
+			input.DistanceSource = model.FareDistanceEstimate
+		}
+	}
+
+	fare := tariff.Calculate(input)
+	s.logger.Info().
+		Str("short_id", order.ShortID).
+		Str("tariff", tariff.Name).
+		Float64("distance_km", fare.DistanceKm).
+		Str("distance_source", string(fare.DistanceSource)).
+		Float64("duration_mins", fare.DurationMins).
+		Int("total", fare.Total).
+		Msg("實際車資已計算")
+	return fare
+}
+
+// Quote 以指定條件試算車資，供後台調整費率時使用
+func (s *TariffService) Quote(ctx context.Context, fleet model.FleetType, customerGroup string, input model.FareInput) (*model.FareBreakdown, error) {
+	tariff, err := s.ResolveTariff(ctx, fleet, customerGroup)
+	if err != nil {
+		return nil, err
+	}
+	if tariff == nil {
+		return nil, fmt.Errorf("車隊 %s 客群「%s」沒有啟用中的費率", fleet, customerGroup)
+	}
+	return tariff.Calculate(input), nil
+}
+
+// orderZones 查詢訂單上車點與目的地所在的服務區域名稱，查詢失敗時不加收區域加價
+func (s *TariffService) orderZones(ctx context.Context, order *model.Order) []string {
+	if s.serviceZoneService == nil {
+		return nil
+	}
+
+	var zones []string
+	if restriction, err := s.serviceZoneService.ResolveOrderRestriction(ctx, order); err != nil {
+		s.logger.Warn().Err(err).Str("short_id", order.ShortID).Msg("查詢上車點服務區域失敗，不加收區域加價")
+	} else if restriction != nil {
+		zones = append(zones, restriction.Zones...)
+	}
+
+	if order.Customer.DestLat != nil && order.Customer.DestLng != nil {
+		lat, errLat := strconv.ParseFloat(*order.Customer.DestLat, 64)
+		lng, errLng := strconv.ParseFloat(*order.Customer.DestLng, 64)
+		if errLat == nil && errLng == nil {
+			if restriction, err := s.serviceZoneService.ResolveRestriction(ctx, order.Fleet, lat, lng); err != nil {
+				s.logger.Warn().Err(err).Str("short_id", order.ShortID).Msg("查詢目的地服務區域失敗，不加收區域加價")
+			} else {
+				for _, zone := range restriction.Zones {
+					zones = appendUnique(zones, zone)
+				}
+			}
+		}
+	}
+	return zones
+}
+
+// validateTariff 驗證費率數值，並將客群統一為大寫
+func validateTariff(tariff *model.Tariff) error {
+	if tariff.Fleet == "" {
+		return errors.New("車隊不可為空")
+	}
+	tariff.CustomerGroup = strings.ToUpper(strings.TrimSpace(tariff.CustomerGroup))
+	if tariff.Name == "" {
+		tariff.Name = string(tariff.Fleet)
+		if tariff.CustomerGroup != "" {
+			tariff.Name += " " + tariff.CustomerGroup
+		}
+	}
+	if tariff.FlagFall < 0 || tariff.FlagFallKm < 0 || tariff.PerKm < 0 || tariff.PerMinute < 0 || tariff.MinimumFare < 0 {
+		return errors.New("起跳價、里程、時間費用與最低車資不可為負數")
+	}
+	if tariff.PetSurcharge < 0 || tariff.OverloadedSurcharge < 0 {
+		return errors.New("加價金額不可為負數")
+	}
+
+	night := tariff.NightSurcharge
+	if night.StartHour < 0 || night.StartHour > 23 || night.EndHour < 0 || night.EndHour > 23 {
+		return errors.New("夜間加成時段必須介於 0 到 23 點")
+	}
+	if night.Percent < 0 || night.Amount < 0 {
+		return errors.New("夜間加成不可為負數")
+	}
+	if (night.Percent > 0 || night.Amount > 0) && night.StartHour == night.EndHour {
+		return errors.New("夜間加成的開始與結束時間不可相同")
+	}
+
+	for i, surcharge := range tariff.ZoneSurcharges {
+		if surcharge.Zone == "" {
+			return fmt.Errorf("第%d個區域加價未指定區域名稱", i+1)
+		}
+		if surcharge.Amount < 0 {
+			return fmt.Errorf("第%d個區域加價金額不可為負數", i+1)
+		}
+	}
+	return nil
+}
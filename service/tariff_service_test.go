@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"right-backend/infra"
+	"right-backend/model"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// tariffCursorResponse 模擬費率查詢結果
+func tariffCursorResponse(mt *mtest.T, tariffs ...*model.Tariff) bson.D {
+	mt.Helper()
+	docs := make([]bson.D, 0, len(tariffs))
+	for _, tariff := range tariffs {
+		raw, err := bson.Marshal(tariff)
+		if err != nil {
+			mt.Fatalf("序列化費率失敗: %v", err)
+		}
+		var doc bson.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			mt.Fatalf("反序列化費率失敗: %v", err)
+		}
+		docs = append(docs, doc)
+	}
+	return mtest.CreateCursorResponse(0, "right_db."+tariffCollection, mtest.FirstBatch, docs...)
+}
+
+func newTestTariffService(mt *mtest.T) *TariffService {
+	return NewTariffService(zerolog.Nop(), &infra.MongoDB{Client: mt.Client, Database: mt.DB})
+}
+
+func testFleetTariff() *model.Tariff {
+	return &model.Tariff{
+		Fleet:       model.FleetTypeRSK,
+		Name:        "RSK 一般費率",
+		FlagFall:    85,
+		FlagFallKm:  1.25,
+		PerKm:       20,
+		PerMinute:   3,
+		MinimumFare: 100,
+		Enabled:     true,
+	}
+}
+
+func estimatedOrder(dist string, mins int) *model.Order {
+	order := &model.Order{Fleet: model.FleetTypeRSK, ShortID: "#TEST"}
+	order.Customer.EstPickToDestDist = &dist
+	order.Customer.EstPickToDestMins = &mins
+	return order
+}
+
+func TestEstimateFare(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("依預估距離與時間估算車資", func(mt *mtest.T) {
+		mt.AddMockResponses(tariffCursorResponse(mt, testFleetTariff()))
+
+		fare := newTestTariffService(mt).EstimateFare(context.Background(), estimatedOrder("10.0", 20))
+		if fare == nil {
+			mt.Fatal("預期估算出車資")
+		}
+		if fare.Total != 320 || fare.DistanceSource != model.FareDistanceEstimate {
+			mt.Fatalf("預期預估車資 320 元，實際為 %d 元（來源 %s）", fare.Total, fare.DistanceSource)
+		}
+	})
+
+	mt.Run("沒有預估距離時不估算", func(mt *mtest.T) {
+		order := &model.Order{Fleet: model.FleetTypeRSK}
+		if fare := newTestTariffService(mt).EstimateFare(context.Background(), order); fare != nil {
+			mt.Fatalf("預期不估算車資，實際為 %+v", fare)
+		}
+		if started := mt.GetStartedEvent(); started != nil {
+			mt.Fatalf("沒有預估距離時不應查詢費率，實際執行 %s", started.CommandName)
+		}
+	})
+
+	mt.Run("沒有適用費率時不估算", func(mt *mtest.T) {
+		mt.AddMockResponses(tariffCursorResponse(mt))
+
+		if fare := newTestTariffService(mt).EstimateFare(context.Background(), estimatedOrder("10.0", 20)); fare != nil {
+			mt.Fatalf("預期不估算車資，實際為 %+v", fare)
+		}
+	})
+}
@@ -3,13 +3,13 @@ package service
 import (
 	"context"
 	"errors"
+	"right-backend/auth"
 	"right-backend/data-models/common"
 	"right-backend/data-models/user"
 	"right-backend/infra"
 	"right-backend/model"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,21 +17,26 @@ import (
 )
 
 type UserService struct {
-	logger          zerolog.Logger
-	mongoDB         *infra.MongoDB
-	jwtSecretKey    string
-	jwtExpiresHours int
+	logger         zerolog.Logger
+	mongoDB        *infra.MongoDB
+	sessionService *AuthSessionService
 }
 
-func NewUserService(logger zerolog.Logger, mongoDB *infra.MongoDB, jwtSecretKey string, jwtExpiresHours int) *UserService {
+// ErrInvalidCredentials 帳號或密碼錯誤
+var ErrInvalidCredentials = errors.New("帳號或密碼錯誤")
+
+func NewUserService(logger zerolog.Logger, mongoDB *infra.MongoDB) *UserService {
 	return &UserService{
-		logger:          logger.With().Str("module", "user_service").Logger(),
-		mongoDB:         mongoDB,
-		jwtSecretKey:    jwtSecretKey,
-		jwtExpiresHours: jwtExpiresHours,
+		logger:  logger.With().Str("module", "user_service").Logger(),
+		mongoDB: mongoDB,
 	}
 }
 
+// SetAuthSessionService 設定登入 session 服務，設定後修改密碼時強制用戶登出
+func (s *UserService) SetAuthSessionService(sessionService *AuthSessionService) {
+	s.sessionService = sessionService
+}
+
 func (s *UserService) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
 	user.ID = primitive.NewObjectID()
 	user.CreatedAt = time.Now()
@@ -42,8 +47,14 @@ func (s *UserService) CreateUser(ctx context.Context, user *model.User) (*model.
 	user.FleetAccess = model.GetDefaultFleetAccess(user.Role)
 	user.IsActive = true
 
+	hashed, err := auth.HashPassword(user.Password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = hashed
+
 	collection := s.mongoDB.GetCollection("users")
-	_, err := collection.InsertOne(ctx, user)
+	_, err = collection.InsertOne(ctx, user)
 	if err != nil {
 		s.logger.Error().
 			Str("用戶帳號", user.Account).
@@ -303,14 +314,7 @@ func (s *UserService) GetUsersWithFilterAndPagination(ctx context.Context, pageN
 
 	// 車隊篩選
 	if fleet != "" && fleet != "全部" {
-		switch fleet {
-		case "RSK":
-			filter["fleet"] = model.FleetTypeRSK
-		case "KD":
-			filter["fleet"] = model.FleetTypeKD
-		case "WEI":
-			filter["fleet"] = model.FleetTypeWEI
-		}
+		filter["fleet"] = model.FleetType(fleet)
 	}
 
 	// 模糊搜尋（姓名或帳號）
@@ -413,6 +417,13 @@ func (s *UserService) UpdateUser(ctx context.Context, id string, updates bson.M)
 		return nil, err
 	}
 
+	if password, ok := updates["password"].(string); ok {
+		hashed, err := auth.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		updates["password"] = hashed
+	}
 	updates["updated_at"] = time.Now()
 
 	collection := s.mongoDB.GetCollection("users")
@@ -437,12 +448,12 @@ func (s *UserService) UpdateUser(ctx context.Context, id string, updates bson.M)
 	return &updatedUser, nil
 }
 
-func (s *UserService) Login(ctx context.Context, account, password string) (*model.User, string, error) {
+// Login 驗證帳號密碼，token 由 AuthSessionService 簽發
+func (s *UserService) Login(ctx context.Context, account, password string) (*model.User, error) {
 	collection := s.mongoDB.GetCollection("users")
 	var user model.User
 	err := collection.FindOne(ctx, bson.M{
 		"account":   account,
-		"password":  password,
 		"is_active": true,
 	}).Decode(&user)
 	if err != nil {
@@ -450,28 +461,20 @@ func (s *UserService) Login(ctx context.Context, account, password string) (*mod
 			Str("用戶帳號", account).
 			Str("錯誤原因", err.Error()).
 			Msg("用戶登入失敗 - 帳號或密碼錯誤或帳號未啟用")
-		return nil, "", err
+		return nil, err
 	}
 
-	// 生成 JWT token，包含完整的用戶資訊以減少資料庫查詢
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID.Hex(),
-		"account": user.Account,
-		"role":    user.Role,
-		"fleet":   string(user.Fleet),
-		"type":    string(model.TokenTypeUser),
-		"exp":     time.Now().Add(time.Hour * time.Duration(s.jwtExpiresHours)).Unix(),
-	})
+	passwordOK, needsRehash := auth.VerifyPassword(user.Password, password)
+	if !passwordOK {
+		s.logger.Warn().
+			Str("用戶帳號", account).
+			Msg("用戶登入失敗 - 帳號或密碼錯誤或帳號未啟用")
+		return nil, ErrInvalidCredentials
+	}
 
-	tokenString, err := token.SignedString([]byte(s.jwtSecretKey)) // 使用配置的密鑰
-	if err != nil {
-		s.logger.Error().
-			Str("用戶編號", user.ID.Hex()).
-			Str("用戶帳號", user.Account).
-			Str("用戶角色", string(user.Role)).
-			Str("錯誤原因", err.Error()).
-			Msg("用戶 JWT 令牌生成失敗")
-		return nil, "", err
+	// 舊資料為明文或雜湊強度不足時，登入成功後升級為目前的雜湊
+	if needsRehash {
+		s.upgradePasswordHash(ctx, user.ID, password)
 	}
 
 	s.logger.Debug().
@@ -480,7 +483,7 @@ func (s *UserService) Login(ctx context.Context, account, password string) (*mod
 		Str("用戶角色", string(user.Role)).
 		Msg("用戶登入成功 - 服務層驗證完成")
 
-	return &user, tokenString, nil
+	return &user, nil
 }
 
 func (s *UserService) ChangePassword(ctx context.Context, userID, oldPassword, newPassword string) error {
@@ -497,10 +500,7 @@ func (s *UserService) ChangePassword(ctx context.Context, userID, oldPassword, n
 
 	// 先驗證舊密碼是否正確
 	var user model.User
-	err = collection.FindOne(ctx, bson.M{
-		"_id":      objectID,
-		"password": oldPassword,
-	}).Decode(&user)
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
 	if err != nil {
 		s.logger.Warn().
 			Str("用戶編號", userID).
@@ -508,11 +508,22 @@ func (s *UserService) ChangePassword(ctx context.Context, userID, oldPassword, n
 			Msg("舊密碼驗證失敗")
 		return err
 	}
+	if ok, _ := auth.VerifyPassword(user.Password, oldPassword); !ok {
+		s.logger.Warn().
+			Str("用戶編號", userID).
+			Msg("舊密碼驗證失敗")
+		return ErrInvalidCredentials
+	}
+
+	hashed, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
 
 	// 更新密碼
 	filter := bson.M{"_id": objectID}
 	update := bson.M{"$set": bson.M{
-		"password":   newPassword,
+		"password":   hashed,
 		"updated_at": time.Now(),
 	}}
 
@@ -530,5 +541,32 @@ func (s *UserService) ChangePassword(ctx context.Context, userID, oldPassword, n
 		Str("用戶帳號", user.Account).
 		Msg("用戶密碼修改成功")
 
+	// 密碼變更後，已登入的裝置需以新密碼重新登入
+	if s.sessionService != nil {
+		if _, err := s.sessionService.RevokeSubjectSessions(ctx, model.TokenTypeUser, userID, model.SessionRevokePasswordChanged); err != nil {
+			s.logger.Error().
+				Str("用戶編號", userID).
+				Str("錯誤原因", err.Error()).
+				Msg("修改密碼後強制登出失敗")
+		}
+	}
+
 	return nil
 }
+
+// upgradePasswordHash 將明文或強度不足的密碼更新為目前的雜湊，失敗時只記錄警告，不影響登入
+func (s *UserService) upgradePasswordHash(ctx context.Context, userID primitive.ObjectID, password string) {
+	hashed, err := auth.HashPassword(password)
+	if err == nil {
+		_, err = s.mongoDB.GetCollection("users").UpdateOne(ctx,
+			bson.M{"_id": userID},
+			bson.M{"$set": bson.M{"password": hashed}},
+		)
+	}
+	if err != nil {
+		s.logger.Warn().
+			Str("用戶編號", userID.Hex()).
+			Str("錯誤原因", err.Error()).
+			Msg("用戶密碼雜湊升級失敗")
+	}
+}
@@ -80,3 +80,34 @@ func parseDetailsInternal(input string) (address, remarks string, scheduledTime
 
 	return address, remarks, scheduledTime
 }
+
+// waypointSeparatorReplacer 將全形與箭頭分隔符號統一為 ">"
+var waypointSeparatorReplacer = strings.NewReplacer("＞", ">", "→", ">")
+
+// SplitWaypoints 將地址拆成上車點與依序的停靠點
+// 格式: "上車點>停靠點1>停靠點2"（分隔符號支援 > ＞ →，前後不可有空白）
+// 停靠點為「來回」或備註含「來回」且有停靠點時視為來回行程，最後返回上車點
+func SplitWaypoints(address, remarks string) (pickup string, stops []string, isRoundTrip bool) {
+	parts := strings.Split(waypointSeparatorReplacer.Replace(address), ">")
+	pickup = strings.TrimSpace(parts[0])
+
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+			continue
+		case "來回":
+			isRoundTrip = true
+		default:
+			stops = append(stops, part)
+		}
+	}
+
+	if len(stops) == 0 {
+		return pickup, nil, false
+	}
+	if strings.Contains(remarks, "來回") {
+		isRoundTrip = true
+	}
+	return pickup, stops, isRoundTrip
+}